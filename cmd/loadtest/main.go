@@ -9,6 +9,9 @@ import (
 	"io"
 	"math/rand"
 	"net/http"
+	"os"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -26,14 +29,15 @@ var (
 	numUsers    = flag.Int("users", 100, "Number of concurrent users")
 	duration    = flag.Duration("duration", 30*time.Second, "Test duration")
 	requestRate = flag.Int("rate", 10, "Requests per second per user (approx)")
+	scenario    = flag.String("scenario", "feed", "Workload: feed (reads only), write (location, messages, stories) or mixed (70% feed, 15% location, 10% message, 5% story)")
 )
 
 // Global Stats
 var (
-	totalRequests  int64
-	successfulReqs int64
-	failedReqs     int64
-	totalLatency   int64 // Microseconds
+	// results gathers every user's stats per action once they finish
+	results    = make(map[string]*runStats)
+	resultsMu  sync.Mutex
+	sawFailure atomic.Bool
 )
 
 type LoginResponse struct {
@@ -50,7 +54,12 @@ type FeedResponse struct {
 
 func main() {
 	flag.Parse()
-	fmt.Printf("🚀 Starting Load Test with %d users for %v...\n", *numUsers, *duration)
+	actions, ok := scenarios[*scenario]
+	if !ok {
+		fmt.Printf("Unknown scenario %q; use one of %v\n", *scenario, scenarioNames())
+		os.Exit(2)
+	}
+	fmt.Printf("🚀 Starting %s Load Test with %d users for %v...\n", *scenario, *numUsers, *duration)
 
 	rand.Seed(time.Now().UnixNano())
 
@@ -65,7 +74,7 @@ func main() {
 		wg.Add(1)
 		go func(id int) {
 			defer wg.Done()
-			runUser(id)
+			runUser(id, actions)
 		}(i)
 		time.Sleep(200 * time.Millisecond) // Stagger login to avoid 429
 	}
@@ -76,7 +85,7 @@ func main() {
 	printStats(elapsed)
 }
 
-func runUser(id int) {
+func runUser(id int, actions []weightedAction) {
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
@@ -120,33 +129,46 @@ func runUser(id int) {
 
 	var loginResp LoginResponse
 	json.Unmarshal(respBody, &loginResp)
-	token := loginResp.AccessToken
+	user := &virtualUser{
+		client: client,
+		token:  loginResp.AccessToken,
+		id:     loginResp.User.ID,
+	}
+	if scenarioWrites(actions) {
+		user.connect()
+	}
+
+	stats := make(map[string]*runStats)
+	defer func() {
+		resultsMu.Lock()
+		defer resultsMu.Unlock()
+		for action, s := range stats {
+			if results[action] == nil {
+				results[action] = newRunStats()
+			}
+			results[action].merge(s)
+		}
+	}()
 
 	// Main Loop
 	endTime := time.Now().Add(*duration)
 	for time.Now().Before(endTime) {
-		// Action: Get Feed
-		// Simulate random location around a center point (e.g. San Francisco)
-		// Lat: 37.7749, Lng: -122.4194
-		lat := 37.7749 + (rand.Float64()-0.5)*0.1 // +/- 0.05 degrees (~5km)
-		lng := -122.4194 + (rand.Float64()-0.5)*0.1
-
-		url := fmt.Sprintf("/feed?latitude=%f&longitude=%f", lat, lng)
+		action := pickAction(actions)
+		s := stats[action]
+		if s == nil {
+			s = newRunStats()
+			stats[action] = s
+		}
 
 		reqStart := time.Now()
-		_, code, err := get(client, url, token)
-		latency := time.Since(reqStart).Microseconds()
-
-		atomic.AddInt64(&totalRequests, 1)
-		atomic.AddInt64(&totalLatency, latency)
-
-		if err == nil && code == 200 {
-			atomic.AddInt64(&successfulReqs, 1)
+		code, ok, err := user.do(action)
+		if !ok {
+			s.skipped++
 		} else {
-			if atomic.LoadInt64(&failedReqs) == 0 {
-				fmt.Printf("First failure: Code=%d, Err=%v\n", code, err)
+			s.record(code, time.Since(reqStart))
+			if (err != nil || code < 200 || code >= 300) && sawFailure.CompareAndSwap(false, true) {
+				fmt.Printf("First failure: Action=%s, Code=%d, Err=%v\n", action, code, err)
 			}
-			atomic.AddInt64(&failedReqs, 1)
 		}
 
 		// Sleep a bit to match rate
@@ -189,19 +211,68 @@ func get(client *http.Client, path string, token string) ([]byte, int, error) {
 }
 
 func printStats(elapsed time.Duration) {
-	total := atomic.LoadInt64(&totalRequests)
-	success := atomic.LoadInt64(&successfulReqs)
-	failed := atomic.LoadInt64(&failedReqs)
-	totalLat := atomic.LoadInt64(&totalLatency)
+	resultsMu.Lock()
+	defer resultsMu.Unlock()
+
+	overall := newRunStats()
+	actions := make([]string, 0, len(results))
+	for action, s := range results {
+		overall.merge(s)
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+
+	total := overall.latency.total
+	success := overall.succeeded()
 
 	fmt.Println("\n📊 Load Test Results")
 	fmt.Println("====================")
 	fmt.Printf("Duration:    %v\n", elapsed)
 	fmt.Printf("Total Reqs:  %d\n", total)
 	fmt.Printf("Success:     %d\n", success)
-	fmt.Printf("Failed:      %d\n", failed)
-	if total > 0 {
-		fmt.Printf("Avg Latency: %.2f ms\n", float64(totalLat)/float64(total)/1000.0)
-		fmt.Printf("RPS:         %.2f\n", float64(total)/elapsed.Seconds())
+	fmt.Printf("Failed:      %d\n", total-success)
+	if total == 0 {
+		return
+	}
+	fmt.Printf("RPS:         %.2f\n", float64(total)/elapsed.Seconds())
+
+	printRunStats("All", overall)
+	if len(actions) > 1 {
+		for _, action := range actions {
+			printRunStats(action, results[action])
+		}
+	}
+}
+
+// printRunStats prints the status breakdown and latency percentiles of s
+func printRunStats(title string, s *runStats) {
+	fmt.Printf("\n%s (%d reqs", title, s.latency.total)
+	if s.skipped > 0 {
+		fmt.Printf(", %d skipped", s.skipped)
+	}
+	fmt.Println(")")
+
+	var statuses []string
+	for _, code := range s.statusCodes() {
+		label := fmt.Sprint(code)
+		if code == 0 {
+			label = "error"
+		}
+		statuses = append(statuses, fmt.Sprintf("%s=%d", label, s.byStatus[code]))
 	}
+	fmt.Printf("  status  %s\n", strings.Join(statuses, " "))
+
+	h := &s.latency
+	fmt.Printf("  avg %s  p50 %s  p90 %s  p95 %s  p99 %s  max %s\n",
+		formatLatency(h.mean()),
+		formatLatency(h.percentile(50)),
+		formatLatency(h.percentile(90)),
+		formatLatency(h.percentile(95)),
+		formatLatency(h.percentile(99)),
+		formatLatency(h.max),
+	)
+}
+
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d.Microseconds())/1000.0)
 }
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	actionFeed     = "feed"
+	actionLocation = "location"
+	actionMessage  = "message"
+	actionStory    = "story"
+
+	// connectionsPerUser is how many earlier users each writer asks to connect with
+	connectionsPerUser = 3
+	// connectionRefresh is how often a writer accepts requests and reloads its connections
+	connectionRefresh = 10 * time.Second
+)
+
+// Simulate random locations around a center point (e.g. San Francisco)
+const centerLat, centerLng = 37.7749, -122.4194
+
+type weightedAction struct {
+	name   string
+	weight int
+}
+
+// scenarios are the workloads -scenario picks from. write keeps the mix of
+// writes in mixed, without the feed reads. Load test users never verify a
+// phone, so messages and stories need REQUIRE_PHONE_VERIFICATION off on the
+// server under test; rate limits it hits show up as 429s per action.
+var scenarios = map[string][]weightedAction{
+	"feed": {
+		{actionFeed, 100},
+	},
+	"write": {
+		{actionLocation, 15},
+		{actionMessage, 10},
+		{actionStory, 5},
+	},
+	"mixed": {
+		{actionFeed, 70},
+		{actionLocation, 15},
+		{actionMessage, 10},
+		{actionStory, 5},
+	},
+}
+
+func scenarioNames() []string {
+	names := make([]string, 0, len(scenarios))
+	for name := range scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pickAction chooses an action at random in proportion to the weights
+func pickAction(actions []weightedAction) string {
+	total := 0
+	for _, a := range actions {
+		total += a.weight
+	}
+	n := rand.Intn(total)
+	for _, a := range actions {
+		if n < a.weight {
+			return a.name
+		}
+		n -= a.weight
+	}
+	return actions[len(actions)-1].name
+}
+
+func scenarioWrites(actions []weightedAction) bool {
+	for _, a := range actions {
+		if a.name != actionFeed {
+			return true
+		}
+	}
+	return false
+}
+
+// userPool holds the ids of logged-in users so writers can connect with each other
+var userPool struct {
+	sync.Mutex
+	ids []uuid.UUID
+}
+
+// joinUserPool adds id to the pool and returns up to n users who joined before it
+func joinUserPool(id uuid.UUID, n int) []uuid.UUID {
+	userPool.Lock()
+	defer userPool.Unlock()
+
+	earlier := append([]uuid.UUID(nil), userPool.ids...)
+	userPool.ids = append(userPool.ids, id)
+	rand.Shuffle(len(earlier), func(i, j int) { earlier[i], earlier[j] = earlier[j], earlier[i] })
+	if len(earlier) > n {
+		earlier = earlier[:n]
+	}
+	return earlier
+}
+
+// virtualUser is one simulated client
+type virtualUser struct {
+	client *http.Client
+	token  string
+	id     uuid.UUID
+
+	connections        []uuid.UUID
+	connectionsFetched time.Time
+}
+
+func randomPoint() (float64, float64) {
+	lat := centerLat + (rand.Float64()-0.5)*0.1 // +/- 0.05 degrees (~5km)
+	lng := centerLng + (rand.Float64()-0.5)*0.1
+	return lat, lng
+}
+
+// connect asks earlier users to connect, so messages have someone to go to.
+// Setup traffic isn't counted in the results.
+func (u *virtualUser) connect() {
+	for _, target := range joinUserPool(u.id, connectionsPerUser) {
+		postJSON(u.client, "/connections/request", map[string]string{"target_user_id": target.String()}, u.token)
+	}
+}
+
+// refreshConnections accepts pending requests and reloads who u can message,
+// at most every connectionRefresh. Not counted in the results.
+func (u *virtualUser) refreshConnections() {
+	if time.Since(u.connectionsFetched) < connectionRefresh {
+		return
+	}
+	u.connectionsFetched = time.Now()
+
+	body, code, err := get(u.client, "/connections/requests", u.token)
+	if err == nil && code == http.StatusOK {
+		var pending []struct {
+			RequesterID uuid.UUID `json:"requester_id"`
+		}
+		json.Unmarshal(body, &pending)
+		for _, p := range pending {
+			postJSON(u.client, "/connections/update", map[string]string{
+				"requester_id": p.RequesterID.String(),
+				"status":       "accepted",
+			}, u.token)
+		}
+	}
+
+	body, code, err = get(u.client, "/connections", u.token)
+	if err != nil || code != http.StatusOK {
+		return
+	}
+	var friends []struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if json.Unmarshal(body, &friends) != nil {
+		return
+	}
+	u.connections = u.connections[:0]
+	for _, f := range friends {
+		u.connections = append(u.connections, f.ID)
+	}
+}
+
+// do runs one action and returns its status code. ok is false when the action
+// couldn't be attempted, e.g. a message with no one connected to send it to.
+func (u *virtualUser) do(action string) (code int, ok bool, err error) {
+	lat, lng := randomPoint()
+	switch action {
+	case actionLocation:
+		_, code, err = postJSON(u.client, "/location/ping", map[string]float64{
+			"latitude":  lat,
+			"longitude": lng,
+		}, u.token)
+	case actionMessage:
+		u.refreshConnections()
+		if len(u.connections) == 0 {
+			return 0, false, nil
+		}
+		to := u.connections[rand.Intn(len(u.connections))]
+		_, code, err = postJSON(u.client, "/messages", map[string]any{
+			"receiver_id": to,
+			"content":     fmt.Sprintf("load test message %d", rand.Intn(1000000)),
+		}, u.token)
+	case actionStory:
+		_, code, err = postJSON(u.client, "/stories", map[string]any{
+			"media_url":  "https://example.com/loadtest.jpg",
+			"media_type": "text",
+			"latitude":   lat,
+			"longitude":  lng,
+			"caption":    "load test",
+		}, u.token)
+	default:
+		_, code, err = get(u.client, fmt.Sprintf("/feed?latitude=%f&longitude=%f", lat, lng), u.token)
+	}
+	return code, true, err
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPickAction(t *testing.T) {
+	const picks = 100000
+	counts := make(map[string]int)
+	for i := 0; i < picks; i++ {
+		counts[pickAction(scenarios["mixed"])]++
+	}
+
+	require.Len(t, counts, 4)
+	require.InDelta(t, 0.70, float64(counts[actionFeed])/picks, 0.02)
+	require.InDelta(t, 0.15, float64(counts[actionLocation])/picks, 0.02)
+	require.InDelta(t, 0.10, float64(counts[actionMessage])/picks, 0.02)
+	require.InDelta(t, 0.05, float64(counts[actionStory])/picks, 0.02)
+
+	require.False(t, scenarioWrites(scenarios["feed"]))
+	require.True(t, scenarioWrites(scenarios["write"]))
+}
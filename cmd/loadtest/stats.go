@@ -0,0 +1,128 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// latencyPrecision is the relative width of a histogram bucket: reported
+// percentiles are at most 1% above the true value
+const latencyPrecision = 0.01
+
+var latencyBucketBase = math.Log1p(latencyPrecision)
+
+// latencyHistogram counts latencies in buckets of a fixed relative width,
+// HdrHistogram-style, so memory stays the same however many requests are
+// recorded: under 2k buckets covers a microsecond to the client timeout.
+// It isn't safe for concurrent use; each user keeps its own and they're merged.
+type latencyHistogram struct {
+	counts []int64
+	total  int64
+	sum    time.Duration
+	max    time.Duration
+}
+
+func latencyBucket(d time.Duration) int {
+	us := max(d.Microseconds(), 1)
+	return int(math.Log(float64(us)) / latencyBucketBase)
+}
+
+// latencyBucketUpper is the largest latency that falls into bucket i
+func latencyBucketUpper(i int) time.Duration {
+	return time.Duration(math.Exp(float64(i+1)*latencyBucketBase)) * time.Microsecond
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	i := latencyBucket(d)
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]int64, i+1-len(h.counts))...)
+	}
+	h.counts[i]++
+	h.total++
+	h.sum += d
+	h.max = max(h.max, d)
+}
+
+func (h *latencyHistogram) merge(other *latencyHistogram) {
+	if len(other.counts) > len(h.counts) {
+		h.counts = append(h.counts, make([]int64, len(other.counts)-len(h.counts))...)
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.total += other.total
+	h.sum += other.sum
+	h.max = max(h.max, other.max)
+}
+
+// percentile returns the latency p percent of requests came in under (0 < p <= 100)
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(p / 100 * float64(h.total)))
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return min(latencyBucketUpper(i), h.max)
+		}
+	}
+	return h.max
+}
+
+func (h *latencyHistogram) mean() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.total)
+}
+
+// runStats is what one user saw of one action; users merge theirs into the
+// global results when done
+type runStats struct {
+	latency latencyHistogram
+	// byStatus counts responses per HTTP status code; 0 is a request that got no response
+	byStatus map[int]int64
+	// skipped counts picks of the action that couldn't be attempted
+	skipped int64
+}
+
+func newRunStats() *runStats {
+	return &runStats{byStatus: make(map[int]int64)}
+}
+
+func (s *runStats) record(code int, latency time.Duration) {
+	s.latency.record(latency)
+	s.byStatus[code]++
+}
+
+func (s *runStats) merge(other *runStats) {
+	s.latency.merge(&other.latency)
+	s.skipped += other.skipped
+	for code, n := range other.byStatus {
+		s.byStatus[code] += n
+	}
+}
+
+// succeeded counts 2xx responses
+func (s *runStats) succeeded() int64 {
+	var n int64
+	for code, count := range s.byStatus {
+		if code >= 200 && code < 300 {
+			n += count
+		}
+	}
+	return n
+}
+
+// statusCodes returns the status codes seen, in order
+func (s *runStats) statusCodes() []int {
+	codes := make([]int, 0, len(s.byStatus))
+	for code := range s.byStatus {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	return codes
+}
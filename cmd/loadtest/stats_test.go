@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyHistogramPercentiles(t *testing.T) {
+	// Two users whose latencies are 1ms..1000ms between them
+	a, b := newRunStats(), newRunStats()
+	for i := 1; i <= 1000; i++ {
+		s := a
+		if i%2 == 0 {
+			s = b
+		}
+		s.record(200, time.Duration(i)*time.Millisecond)
+	}
+	b.record(0, 5*time.Second)
+	a.merge(b)
+
+	h := &a.latency
+	require.Equal(t, int64(1001), h.total)
+	require.Equal(t, 5*time.Second, h.max)
+	for p, want := range map[float64]time.Duration{
+		50: 501 * time.Millisecond,
+		90: 901 * time.Millisecond,
+		99: 991 * time.Millisecond,
+	} {
+		got := h.percentile(p)
+		require.GreaterOrEqual(t, got, want, "p%v", p)
+		require.LessOrEqual(t, float64(got), float64(want)*(1+latencyPrecision), "p%v", p)
+	}
+	require.Equal(t, 5*time.Second, h.percentile(100))
+
+	require.Equal(t, []int{0, 200}, a.statusCodes())
+	require.Equal(t, int64(1000), a.byStatus[200])
+	require.Equal(t, int64(1), a.byStatus[0])
+}
@@ -16,6 +16,7 @@ import (
 	"privacy-social-backend/internal/config"
 	"privacy-social-backend/internal/repository"
 	"privacy-social-backend/internal/service/storage"
+	"privacy-social-backend/internal/util"
 	"privacy-social-backend/internal/worker"
 )
 
@@ -28,8 +29,11 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot load config")
 	}
+	if config.LogFormat == "json" {
+		log.Logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
+	}
 
-	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	conn, err := sql.Open(config.DBDriver, util.WithUTCSession(config.DBSource))
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot connect to db")
 	}
@@ -52,7 +56,9 @@ func main() {
 	// Start background workers
 	cleanupWorker := worker.NewCleanupWorker(store)
 	cleanupWorker.Start()
-	// cleanupWorker.StartCrossingDetector() // Disabled: Switched to Redis-based Realtime Detection
+	// Crossings are detected on every location ping by the Redis location service,
+	// so the polling StartCrossingDetector isn't started
+	worker.NewRecommendationWorker(store).Start()
 
 	// Initialize Storage Service (R2)
 	// For local dev without keys, this might fail or we can make it optional/mock
@@ -63,16 +69,29 @@ func main() {
 		config.R2AccessKey,
 		config.R2SecretKey,
 		config.R2BucketName,
+		config.R2PublicBaseURL,
+		config.R2PrivateBucket,
+		config.UploadURLExpiry,
 	)
 	if storageErr != nil {
 		// Log warning instead of fatal if we want to allow local dev without R2
 		log.Warn().Err(storageErr).Msg("failed to initialize S3 storage service (uploads may fail)")
+	} else {
+		worker.NewUploadCleanupWorker(store, storageService, config.MediaRetention()).Start()
 	}
 
 	server, err := api.NewServer(config, store, storageService)
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot create server")
 	}
+	if err := server.SeedAdmin(context.Background()); err != nil {
+		log.Fatal().Err(err).Msg("cannot seed admin")
+	}
+	if err := server.RestoreLockouts(context.Background()); err != nil {
+		log.Error().Err(err).Msg("cannot restore ban lockouts")
+	}
+	server.StartScheduledMessageDispatcher()
+	server.StartAnalyticsIngest()
 
 	// Graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -93,8 +112,8 @@ func main() {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Here you would call server.Shutdown(shutdownCtx) if Gin supported it
-	// For now, just wait for the timeout
-	<-shutdownCtx.Done()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Graceful shutdown did not finish")
+	}
 	log.Info().Msg("Server stopped")
 }
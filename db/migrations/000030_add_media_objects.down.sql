@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS messages_media_refs ON messages;
+DROP TRIGGER IF EXISTS archived_stories_media_refs ON archived_stories;
+DROP TRIGGER IF EXISTS stories_media_refs ON stories;
+DROP FUNCTION IF EXISTS media_objects_track_refs();
+DROP TABLE IF EXISTS media_objects;
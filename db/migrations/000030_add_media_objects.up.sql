@@ -0,0 +1,41 @@
+-- Content-addressed media: one row per distinct uploaded object, keyed by SHA-256
+CREATE TABLE media_objects (
+    hash TEXT PRIMARY KEY,
+    object_key TEXT NOT NULL UNIQUE,
+    url TEXT NOT NULL UNIQUE,
+    size_bytes BIGINT NOT NULL,
+    content_type TEXT NOT NULL,
+    ref_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_media_objects_unreferenced ON media_objects(updated_at) WHERE ref_count <= 0;
+
+-- Keep ref_count in step with every row that points at a media URL.
+-- Triggers (rather than application code) also cover the bulk expiry deletes.
+CREATE OR REPLACE FUNCTION media_objects_track_refs() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.media_url IS NOT NULL THEN
+        UPDATE media_objects SET ref_count = ref_count - 1, updated_at = NOW()
+        WHERE url = OLD.media_url;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.media_url IS NOT NULL THEN
+        UPDATE media_objects SET ref_count = ref_count + 1, updated_at = NOW()
+        WHERE url = NEW.media_url;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER stories_media_refs
+AFTER INSERT OR DELETE OR UPDATE OF media_url ON stories
+FOR EACH ROW EXECUTE FUNCTION media_objects_track_refs();
+
+CREATE TRIGGER archived_stories_media_refs
+AFTER INSERT OR DELETE OR UPDATE OF media_url ON archived_stories
+FOR EACH ROW EXECUTE FUNCTION media_objects_track_refs();
+
+CREATE TRIGGER messages_media_refs
+AFTER INSERT OR DELETE OR UPDATE OF media_url ON messages
+FOR EACH ROW EXECUTE FUNCTION media_objects_track_refs();
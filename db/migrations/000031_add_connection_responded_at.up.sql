@@ -0,0 +1,4 @@
+ALTER TABLE connections ADD COLUMN responded_at TIMESTAMPTZ;
+
+-- Best guess for relationships answered before the column existed
+UPDATE connections SET responded_at = updated_at WHERE status != 'pending';
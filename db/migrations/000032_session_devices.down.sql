@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_sessions_user_active;
+ALTER TABLE sessions DROP COLUMN IF EXISTS platform;
+ALTER TABLE sessions DROP COLUMN IF EXISTS device_name;
@@ -0,0 +1,5 @@
+ALTER TABLE sessions ADD COLUMN device_name VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN platform VARCHAR NOT NULL DEFAULT '';
+
+-- Serves the per-user active session list and the concurrent-session cap
+CREATE INDEX IF NOT EXISTS idx_sessions_user_active ON sessions(user_id, created_at DESC) WHERE is_blocked = false;
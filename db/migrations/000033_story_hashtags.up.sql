@@ -0,0 +1,9 @@
+-- Hashtags parsed from story captions, for topical discovery
+CREATE TABLE IF NOT EXISTS story_hashtags (
+  story_id UUID NOT NULL REFERENCES stories(id) ON DELETE CASCADE,
+  tag VARCHAR(50) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (story_id, tag)
+);
+
+CREATE INDEX idx_story_hashtags_tag ON story_hashtags(tag, created_at DESC);
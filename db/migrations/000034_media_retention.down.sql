@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_media_objects_unreferenced;
+CREATE INDEX idx_media_objects_unreferenced ON media_objects(updated_at) WHERE ref_count <= 0;
+
+ALTER TABLE media_objects DROP COLUMN IF EXISTS on_hold;
+ALTER TABLE media_objects DROP COLUMN IF EXISTS retain_until;
@@ -0,0 +1,8 @@
+-- Media retention is tracked per object, independently of the rows that reference it:
+-- retain_until keeps unreferenced media around for the retention policy and
+-- on_hold pins media under moderation review or legal hold until released.
+ALTER TABLE media_objects ADD COLUMN retain_until TIMESTAMPTZ;
+ALTER TABLE media_objects ADD COLUMN on_hold BOOLEAN NOT NULL DEFAULT false;
+
+DROP INDEX IF EXISTS idx_media_objects_unreferenced;
+CREATE INDEX idx_media_objects_unreferenced ON media_objects(updated_at) WHERE ref_count <= 0 AND on_hold = false;
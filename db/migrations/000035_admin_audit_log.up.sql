@@ -0,0 +1,14 @@
+-- Record of privileged admin actions, kept even after the users involved are deleted
+CREATE TABLE admin_audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    admin_id UUID NOT NULL,
+    target_user_id UUID,
+    action VARCHAR(50) NOT NULL,
+    method VARCHAR(10) NOT NULL DEFAULT '',
+    path TEXT NOT NULL DEFAULT '',
+    status_code INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_admin_audit_log_admin ON admin_audit_log(admin_id, created_at DESC);
+CREATE INDEX idx_admin_audit_log_target ON admin_audit_log(target_user_id, created_at DESC);
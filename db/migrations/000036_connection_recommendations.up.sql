@@ -0,0 +1,12 @@
+-- "People you may know", precomputed periodically for active users
+CREATE TABLE connection_recommendations (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    candidate_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    mutual_count INTEGER NOT NULL DEFAULT 0,
+    crossing_count INTEGER NOT NULL DEFAULT 0,
+    score INTEGER NOT NULL DEFAULT 0,
+    computed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, candidate_id)
+);
+
+CREATE INDEX idx_connection_recommendations_rank ON connection_recommendations(user_id, score DESC);
@@ -0,0 +1,5 @@
+-- Delivery receipts: set when the recipient's client acknowledges a message
+ALTER TABLE messages ADD COLUMN delivered_at timestamptz;
+
+-- Messages already read were necessarily delivered
+UPDATE messages SET delivered_at = read_at WHERE read_at IS NOT NULL;
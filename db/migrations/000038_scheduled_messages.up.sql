@@ -0,0 +1,17 @@
+-- Messages waiting to be sent. They live outside messages so nothing pending
+-- shows up in history, conversation lists or unread counts until it is sent.
+CREATE TABLE scheduled_messages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    sender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    receiver_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL DEFAULT '',
+    media_url TEXT,
+    media_type TEXT,
+    expires_in_seconds BIGINT NOT NULL,
+    scheduled_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_scheduled_messages_due ON scheduled_messages(scheduled_at);
+CREATE INDEX idx_scheduled_messages_sender ON scheduled_messages(sender_id, scheduled_at);
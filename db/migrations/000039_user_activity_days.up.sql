@@ -0,0 +1,10 @@
+-- One row per user per (UTC) day they were active, for weekly activity summaries
+CREATE TABLE user_activity_days (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    PRIMARY KEY (user_id, day)
+);
+
+INSERT INTO user_activity_days (user_id, day)
+SELECT id, DATE(last_active_at) FROM users WHERE last_active_at IS NOT NULL
+ON CONFLICT DO NOTHING;
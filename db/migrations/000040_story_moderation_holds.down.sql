@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_stories_anonymous_by_user;
+DROP TABLE IF EXISTS story_moderation_holds;
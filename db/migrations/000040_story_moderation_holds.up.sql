@@ -0,0 +1,9 @@
+-- Stories kept out of every feed until a moderator releases them. Anonymous
+-- stories are always held on creation.
+CREATE TABLE story_moderation_holds (
+    story_id UUID PRIMARY KEY REFERENCES stories(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_stories_anonymous_by_user ON stories(user_id, created_at) WHERE is_anonymous = true;
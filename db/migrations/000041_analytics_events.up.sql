@@ -0,0 +1,23 @@
+-- Product events (signups, story posts, crossings, connections, messages) ingested
+-- from the Redis stream by the analytics worker. The dashboard reads these and the
+-- daily rollups instead of scanning the transactional tables. Raw events are
+-- pruned after a retention window; rollups are kept.
+CREATE TABLE analytics_events (
+    -- Redis stream entry ID, so re-delivered entries are ingested once
+    stream_id VARCHAR(32) PRIMARY KEY,
+    event_type VARCHAR(32) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    occurred_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_analytics_events_type_time ON analytics_events(event_type, occurred_at);
+CREATE INDEX idx_analytics_events_user_time ON analytics_events(user_id, occurred_at);
+CREATE INDEX idx_analytics_events_time ON analytics_events(occurred_at);
+
+CREATE TABLE analytics_daily (
+    day DATE NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    event_count BIGINT NOT NULL,
+    unique_users BIGINT NOT NULL,
+    PRIMARY KEY (day, event_type)
+);
@@ -0,0 +1,19 @@
+-- Media items of a message, in the order they were sent. messages.media_url and
+-- media_type keep mirroring the first one for clients that only show one.
+CREATE TABLE message_attachments (
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    media_url TEXT NOT NULL,
+    media_type TEXT NOT NULL,
+    PRIMARY KEY (message_id, position)
+);
+
+-- Attachments reference media objects like any other media_url column
+CREATE TRIGGER message_attachments_media_refs
+AFTER INSERT OR DELETE OR UPDATE OF media_url ON message_attachments
+FOR EACH ROW EXECUTE FUNCTION media_objects_track_refs();
+
+INSERT INTO message_attachments (message_id, position, media_url, media_type)
+SELECT id, 0, media_url, COALESCE(media_type, 'image')
+FROM messages
+WHERE media_url IS NOT NULL;
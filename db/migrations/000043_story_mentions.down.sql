@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_username_lower;
+
+-- Postgres can't drop an enum value; 'story_mention' stays in notification_type
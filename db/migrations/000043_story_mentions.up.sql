@@ -0,0 +1,4 @@
+ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'story_mention';
+
+-- Mentions look users up by lowercased username
+CREATE INDEX idx_users_username_lower ON users (lower(username));
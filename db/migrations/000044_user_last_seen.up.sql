@@ -0,0 +1,2 @@
+-- When the user's last WebSocket connection closed, for "last seen" in chat
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_seen_at timestamptz;
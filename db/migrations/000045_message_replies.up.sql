@@ -0,0 +1,3 @@
+-- The message a message replies to. No foreign key: deleting the quoted
+-- message leaves its replies pointing at an id that shows as unavailable.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS reply_to_message_id uuid;
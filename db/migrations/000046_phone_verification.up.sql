@@ -0,0 +1,2 @@
+-- Set once the user confirms their phone number with a one-time code
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_verified boolean NOT NULL DEFAULT false;
@@ -0,0 +1,13 @@
+-- Conversations a user has muted: a direct chat with target_user_id or a group.
+-- Messages are still delivered, just without raising the unread badge.
+CREATE TABLE muted_conversations (
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  target_user_id uuid REFERENCES users(id) ON DELETE CASCADE,
+  group_id uuid REFERENCES groups(id) ON DELETE CASCADE,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  CHECK ((target_user_id IS NULL) <> (group_id IS NULL))
+);
+
+CREATE UNIQUE INDEX muted_conversations_user_target ON muted_conversations (user_id, target_user_id) WHERE target_user_id IS NOT NULL;
+CREATE UNIQUE INDEX muted_conversations_user_group ON muted_conversations (user_id, group_id) WHERE group_id IS NOT NULL;
+CREATE INDEX idx_muted_conversations_group ON muted_conversations (group_id) WHERE group_id IS NOT NULL;
@@ -0,0 +1,4 @@
+-- The message a forwarded message was copied from, and who wrote it. No
+-- foreign keys: the original may be deleted or expire while the copy lives on.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS forwarded_from_message_id uuid;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS forwarded_from_sender_id uuid;
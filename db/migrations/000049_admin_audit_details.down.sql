@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_admin_audit_log_created;
+ALTER TABLE admin_audit_log DROP COLUMN IF EXISTS reason;
+ALTER TABLE admin_audit_log DROP COLUMN IF EXISTS target_id;
@@ -0,0 +1,5 @@
+-- What an admin action was applied to (a user, story or report) and why
+ALTER TABLE admin_audit_log ADD COLUMN IF NOT EXISTS target_id UUID;
+ALTER TABLE admin_audit_log ADD COLUMN IF NOT EXISTS reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_created ON admin_audit_log(created_at DESC);
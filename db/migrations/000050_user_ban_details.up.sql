@@ -0,0 +1,11 @@
+-- How a user is banned: 'shadow' hides their content without telling them,
+-- 'hard' locks them out, 'temp' locks them out until ban_expires_at.
+-- NULL when the user isn't banned.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS ban_type varchar
+  CHECK (ban_type IN ('shadow', 'hard', 'temp'));
+ALTER TABLE users ADD COLUMN IF NOT EXISTS ban_reason text;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS ban_expires_at timestamptz;
+
+UPDATE users SET ban_type = 'shadow' WHERE is_shadow_banned AND ban_type IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_ban_expires_at ON users(ban_expires_at) WHERE ban_type = 'temp';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_messages_story_sender;
+ALTER TABLE messages DROP COLUMN IF EXISTS story_id;
+
+-- Postgres can't drop an enum value; 'story_reply' stays in notification_type
@@ -0,0 +1,8 @@
+-- The story a message replies to. No foreign key: the reply keeps its context
+-- after the story expires and is deleted.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS story_id uuid;
+
+-- One reply per viewer per story is allowed without a connection
+CREATE INDEX IF NOT EXISTS idx_messages_story_sender ON messages (story_id, sender_id) WHERE story_id IS NOT NULL;
+
+ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'story_reply';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_reports_open_user;
+DROP INDEX IF EXISTS idx_reports_open_message;
+DROP INDEX IF EXISTS idx_reports_open_story;
+ALTER TABLE reports DROP COLUMN IF EXISTS target_message_id;
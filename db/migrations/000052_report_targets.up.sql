@@ -0,0 +1,24 @@
+-- Messages can be reported too. No foreign key: the report outlives the
+-- message when it expires.
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS target_message_id uuid;
+
+-- Keep only the earliest of any duplicate open reports before enforcing uniqueness
+DELETE FROM reports r
+USING reports earlier
+WHERE r.is_resolved = false
+  AND earlier.is_resolved = false
+  AND r.reporter_id = earlier.reporter_id
+  AND r.target_user_id IS NOT DISTINCT FROM earlier.target_user_id
+  AND r.target_story_id IS NOT DISTINCT FROM earlier.target_story_id
+  AND (r.created_at, r.id) > (earlier.created_at, earlier.id);
+
+-- One open report per reporter per target
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_story
+  ON reports (reporter_id, target_story_id)
+  WHERE is_resolved = false AND target_story_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_message
+  ON reports (reporter_id, target_message_id)
+  WHERE is_resolved = false AND target_message_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_user
+  ON reports (reporter_id, target_user_id)
+  WHERE is_resolved = false AND target_story_id IS NULL AND target_message_id IS NULL;
@@ -0,0 +1,11 @@
+-- A user's close friends: the connections who see their close_friends stories.
+-- The list is one-way; friend_id doesn't learn they are on it.
+CREATE TABLE close_friends (
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  friend_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY (user_id, friend_id),
+  CHECK (user_id <> friend_id)
+);
+
+CREATE INDEX idx_close_friends_friend ON close_friends (friend_id);
@@ -0,0 +1,14 @@
+-- Settings both people in a direct chat share. Each pair has one row, stored
+-- with the smaller user id first.
+CREATE TABLE conversation_settings (
+  user_a_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  user_b_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- Expiry for messages sent without their own; 0 means they never expire
+  default_expiry_seconds bigint NOT NULL CHECK (default_expiry_seconds >= 0),
+  updated_by uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  updated_at timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY (user_a_id, user_b_id),
+  CHECK (user_a_id < user_b_id)
+);
+
+CREATE INDEX idx_conversation_settings_user_b ON conversation_settings (user_b_id);
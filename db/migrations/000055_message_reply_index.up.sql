@@ -0,0 +1,2 @@
+-- Finds the replies to a message for reply counts and the replies thread
+CREATE INDEX IF NOT EXISTS idx_messages_reply_to ON messages (reply_to_message_id, created_at) WHERE reply_to_message_id IS NOT NULL;
@@ -0,0 +1,22 @@
+-- name: CreateAdminAuditLog :exec
+INSERT INTO admin_audit_log (
+  admin_id,
+  target_user_id,
+  target_id,
+  action,
+  reason,
+  method,
+  path,
+  status_code
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: ListAdminAuditLog :many
+SELECT * FROM admin_audit_log
+ORDER BY created_at DESC
+LIMIT $1
+OFFSET $2;
+
+-- name: CountAdminAuditLog :one
+SELECT COUNT(*) FROM admin_audit_log;
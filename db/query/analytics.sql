@@ -1,26 +1,51 @@
--- name: GetStreakRetentionStats :one
-SELECT 
-    COUNT(*) FILTER (WHERE activity_streak >= 3) as retained_users_count,
-    COUNT(*) as total_users_count,
-    (COUNT(*) FILTER (WHERE activity_streak >= 3)::float / NULLIF(COUNT(*), 0)::float) * 100 as retention_rate
-FROM users;
+-- name: CreateAnalyticsEvents :execrows
+-- Inserts a batch read from the event stream. Entries already ingested and
+-- events of accounts deleted in the meantime are skipped.
+INSERT INTO analytics_events (stream_id, event_type, user_id, occurred_at)
+SELECT e.stream_id, e.event_type, e.user_id, e.occurred_at
+FROM unnest(
+    sqlc.arg(stream_ids)::text[],
+    sqlc.arg(event_types)::text[],
+    sqlc.arg(user_ids)::uuid[],
+    sqlc.arg(occurred_ats)::timestamptz[]
+) AS e(stream_id, event_type, user_id, occurred_at)
+WHERE EXISTS (SELECT 1 FROM users u WHERE u.id = e.user_id)
+ON CONFLICT (stream_id) DO NOTHING;
 
--- name: GetEngagementStats :one
-SELECT 
-    COUNT(*) FILTER (WHERE created_at > NOW() - INTERVAL '7 days') as stories_last_7d,
-    (COUNT(*) FILTER (WHERE created_at > NOW() - INTERVAL '7 days')::float / NULLIF((SELECT COUNT(*) FROM users), 0)::float) as avg_stories_per_user_weekly
-FROM stories;
+-- name: RollupAnalyticsDaily :exec
+-- Recomputes the daily rollups of every day from from_day on
+INSERT INTO analytics_daily (day, event_type, event_count, unique_users)
+SELECT occurred_at::date AS day, event_type, COUNT(*), COUNT(DISTINCT user_id)
+FROM analytics_events
+WHERE occurred_at >= sqlc.arg(from_day)::date
+GROUP BY 1, 2
+ON CONFLICT (day, event_type) DO UPDATE
+SET event_count = EXCLUDED.event_count,
+    unique_users = EXCLUDED.unique_users;
 
--- name: GetConversionStats :one
-WITH crossing_stats AS (
-    SELECT COUNT(*) as total_crossings FROM crossings
-),
-connection_stats AS (
-    SELECT COUNT(*) as total_connections FROM connections WHERE status = 'accepted'
-)
-SELECT 
-    (SELECT total_connections FROM connection_stats) as total_connections,
-    (SELECT total_crossings FROM crossing_stats) as total_crossings,
-    -- Simple Ratio for now (Crossings -> Connections)
-    ((SELECT total_connections FROM connection_stats)::float / NULLIF((SELECT total_crossings FROM crossing_stats), 0)::float) * 100 as crossing_conversion_rate
-FROM crossing_stats, connection_stats;
+-- name: DeleteAnalyticsEventsBefore :execrows
+DELETE FROM analytics_events
+WHERE occurred_at < sqlc.arg(before)::timestamptz;
+
+-- name: GetAnalyticsTotalsSince :many
+SELECT event_type, SUM(event_count)::bigint AS event_count
+FROM analytics_daily
+WHERE day >= sqlc.arg(since)::date
+GROUP BY event_type
+ORDER BY event_type;
+
+-- name: GetSignupRetention :one
+-- Of the users who signed up in [cohort_start, cohort_end), how many did
+-- anything else since active_since
+SELECT
+    COUNT(*) AS cohort_size,
+    COUNT(*) FILTER (WHERE EXISTS (
+        SELECT 1 FROM analytics_events a
+        WHERE a.user_id = s.user_id
+          AND a.event_type <> 'signup'
+          AND a.occurred_at >= sqlc.arg(active_since)::timestamptz
+    )) AS retained_count
+FROM analytics_events s
+WHERE s.event_type = 'signup'
+  AND s.occurred_at >= sqlc.arg(cohort_start)::timestamptz
+  AND s.occurred_at < sqlc.arg(cohort_end)::timestamptz;
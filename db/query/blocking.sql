@@ -1,6 +1,8 @@
 -- name: BlockUser :one
+-- Blocking someone already blocked returns the existing block
 INSERT INTO blocked_users (blocker_id, blocked_id)
 VALUES ($1, $2)
+ON CONFLICT (blocker_id, blocked_id) DO UPDATE SET blocked_id = EXCLUDED.blocked_id
 RETURNING *;
 
 -- name: UnblockUser :exec
@@ -0,0 +1,22 @@
+-- name: AddCloseFriend :exec
+-- Adding someone already on the list is a no-op
+INSERT INTO close_friends (user_id, friend_id)
+VALUES ($1, $2)
+ON CONFLICT (user_id, friend_id) DO NOTHING;
+
+-- name: RemoveCloseFriend :exec
+DELETE FROM close_friends
+WHERE user_id = $1 AND friend_id = $2;
+
+-- name: ListCloseFriends :many
+SELECT u.id, u.username, u.full_name, u.avatar_url, cf.created_at
+FROM close_friends cf
+JOIN users u ON u.id = cf.friend_id
+WHERE cf.user_id = $1
+ORDER BY u.username;
+
+-- name: IsCloseFriend :one
+SELECT EXISTS (
+  SELECT 1 FROM close_friends
+  WHERE user_id = $1 AND friend_id = $2
+);
@@ -0,0 +1,87 @@
+-- name: DeleteConnectionRecommendations :exec
+DELETE FROM connection_recommendations
+WHERE user_id = $1;
+
+-- name: InsertConnectionRecommendations :exec
+-- Scores non-connected users by mutual connections (weighted 3) and recent crossings.
+-- Blocks in either direction and non-public profiles are excluded.
+WITH my_connections AS (
+    SELECT CASE WHEN c.requester_id = sqlc.arg(user_id)::uuid THEN c.target_id ELSE c.requester_id END AS friend_id
+    FROM connections c
+    WHERE (c.requester_id = sqlc.arg(user_id)::uuid OR c.target_id = sqlc.arg(user_id)::uuid)
+      AND c.status = 'accepted'
+),
+mutuals AS (
+    SELECT CASE WHEN c.requester_id = m.friend_id THEN c.target_id ELSE c.requester_id END AS candidate_id,
+           COUNT(*) AS mutual_count
+    FROM connections c
+    JOIN my_connections m ON m.friend_id IN (c.requester_id, c.target_id)
+    WHERE c.status = 'accepted'
+    GROUP BY 1
+),
+crossed AS (
+    SELECT CASE WHEN cr.user_id_1 = sqlc.arg(user_id)::uuid THEN cr.user_id_2 ELSE cr.user_id_1 END AS candidate_id,
+           COUNT(*) AS crossing_count
+    FROM crossings cr
+    WHERE (cr.user_id_1 = sqlc.arg(user_id)::uuid OR cr.user_id_2 = sqlc.arg(user_id)::uuid)
+      AND cr.occurred_at >= sqlc.arg(crossed_since)::timestamptz
+    GROUP BY 1
+),
+scored AS (
+    SELECT COALESCE(m.candidate_id, x.candidate_id) AS candidate_id,
+           COALESCE(m.mutual_count, 0) AS mutual_count,
+           COALESCE(x.crossing_count, 0) AS crossing_count
+    FROM mutuals m
+    FULL OUTER JOIN crossed x ON x.candidate_id = m.candidate_id
+)
+INSERT INTO connection_recommendations (user_id, candidate_id, mutual_count, crossing_count, score)
+SELECT sqlc.arg(user_id)::uuid, s.candidate_id, s.mutual_count, s.crossing_count,
+       s.mutual_count * 3 + s.crossing_count
+FROM scored s
+JOIN users u ON u.id = s.candidate_id
+WHERE s.candidate_id <> sqlc.arg(user_id)::uuid
+  AND u.is_shadow_banned = false
+  AND COALESCE(u.profile_visibility, 'public') = 'public'
+  AND NOT EXISTS (
+    SELECT 1 FROM connections c
+    WHERE (c.requester_id = sqlc.arg(user_id)::uuid AND c.target_id = s.candidate_id)
+       OR (c.requester_id = s.candidate_id AND c.target_id = sqlc.arg(user_id)::uuid)
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM blocked_users bu
+    WHERE (bu.blocker_id = sqlc.arg(user_id)::uuid AND bu.blocked_id = s.candidate_id)
+       OR (bu.blocker_id = s.candidate_id AND bu.blocked_id = sqlc.arg(user_id)::uuid)
+  )
+ORDER BY s.mutual_count * 3 + s.crossing_count DESC
+LIMIT sqlc.arg(result_limit);
+
+-- name: GetConnectionRecommendations :many
+-- Re-checks connections, blocks and visibility so stale rows never leak
+SELECT u.id,
+       u.username,
+       u.full_name,
+       u.avatar_url,
+       r.mutual_count,
+       r.crossing_count,
+       r.score
+FROM connection_recommendations r
+JOIN users u ON u.id = r.candidate_id
+WHERE r.user_id = sqlc.arg(user_id)::uuid
+  AND u.is_shadow_banned = false
+  AND COALESCE(u.profile_visibility, 'public') = 'public'
+  AND NOT EXISTS (
+    SELECT 1 FROM connections c
+    WHERE (c.requester_id = r.user_id AND c.target_id = r.candidate_id)
+       OR (c.requester_id = r.candidate_id AND c.target_id = r.user_id)
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM blocked_users bu
+    WHERE (bu.blocker_id = r.user_id AND bu.blocked_id = r.candidate_id)
+       OR (bu.blocker_id = r.candidate_id AND bu.blocked_id = r.user_id)
+  )
+ORDER BY r.score DESC, r.mutual_count DESC, u.id
+LIMIT sqlc.arg(result_limit);
+
+-- name: CountConnectionRecommendations :one
+SELECT COUNT(*) FROM connection_recommendations
+WHERE user_id = $1;
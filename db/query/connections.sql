@@ -9,7 +9,9 @@ INSERT INTO connections (
 
 -- name: UpdateConnectionStatus :one
 UPDATE connections
-SET status = $3, updated_at = now()
+SET status = $3,
+    updated_at = now(),
+    responded_at = COALESCE(responded_at, CASE WHEN $3 != 'pending' THEN now() END)
 WHERE requester_id = $1 AND target_id = $2
 RETURNING *;
 
@@ -68,7 +70,33 @@ WHERE c.requester_id = $1
   AND c.status = 'pending'
 ORDER BY c.created_at DESC;
 
+-- Relationships seen from the given user: direction tells incoming from outgoing
+-- name: ListConnectionsByStatus :many
+SELECT
+    c.requester_id,
+    c.target_id,
+    c.status,
+    c.created_at,
+    c.responded_at,
+    (c.requester_id = @user_id)::boolean AS is_outgoing,
+    u.id AS other_user_id,
+    u.username,
+    u.full_name,
+    u.avatar_url,
+    u.bio
+FROM connections c
+JOIN users u ON u.id = CASE WHEN c.requester_id = @user_id THEN c.target_id ELSE c.requester_id END
+WHERE (c.requester_id = @user_id OR c.target_id = @user_id)
+  AND c.status = @status
+ORDER BY COALESCE(c.responded_at, c.created_at) DESC;
+
 -- name: DeleteConnection :exec
+-- Close friends are a subset of connections, so disconnecting drops them both ways
+WITH dropped_close_friends AS (
+  DELETE FROM close_friends
+  WHERE (user_id = $1 AND friend_id = $2)
+     OR (user_id = $2 AND friend_id = $1)
+)
 DELETE FROM connections
 WHERE (requester_id = $1 AND target_id = $2)
    OR (requester_id = $2 AND target_id = $1);
@@ -0,0 +1,19 @@
+-- name: GetConversationSettings :one
+-- The pair's settings, whichever order the two users are given in
+SELECT * FROM conversation_settings
+WHERE user_a_id = LEAST(@user_id::uuid, @other_user_id::uuid)
+  AND user_b_id = GREATEST(@user_id::uuid, @other_user_id::uuid);
+
+-- name: UpsertConversationSettings :one
+INSERT INTO conversation_settings (user_a_id, user_b_id, default_expiry_seconds, updated_by)
+VALUES (
+  LEAST(@user_id::uuid, @other_user_id::uuid),
+  GREATEST(@user_id::uuid, @other_user_id::uuid),
+  @default_expiry_seconds,
+  @user_id::uuid
+)
+ON CONFLICT (user_a_id, user_b_id) DO UPDATE
+SET default_expiry_seconds = EXCLUDED.default_expiry_seconds,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = now()
+RETURNING *;
@@ -42,3 +42,32 @@ SELECT EXISTS (
   SELECT 1 FROM group_members
   WHERE group_id = $1 AND user_id = $2
 );
+
+-- name: DeleteUserSoleGroups :exec
+-- Deletes the groups a user created that have no other members
+DELETE FROM groups g
+WHERE g.created_by = $1
+  AND NOT EXISTS (
+    SELECT 1 FROM group_members gm
+    WHERE gm.group_id = g.id AND gm.user_id <> $1
+  );
+
+-- name: TransferUserGroups :exec
+-- Hands each group a user created to its longest-standing other member, who
+-- becomes an admin
+WITH successor AS (
+  SELECT DISTINCT ON (g.id) g.id AS group_id, gm.user_id
+  FROM groups g
+  JOIN group_members gm ON gm.group_id = g.id AND gm.user_id <> $1
+  WHERE g.created_by = $1
+  ORDER BY g.id, gm.joined_at
+), promoted AS (
+  UPDATE group_members gm
+  SET role = 'admin'
+  FROM successor s
+  WHERE gm.group_id = s.group_id AND gm.user_id = s.user_id
+)
+UPDATE groups g
+SET created_by = s.user_id
+FROM successor s
+WHERE g.id = s.group_id;
@@ -13,11 +13,39 @@ INSERT INTO locations (
 DELETE FROM locations
 WHERE expires_at < now();
 
--- name: GetHeatmapData :many
-SELECT 
-  ST_X(ST_SnapToGrid(geom, 0.001)) as longitude,
-  ST_Y(ST_SnapToGrid(geom, 0.001)) as latitude,
-  COUNT(*) as weight
+-- name: GetHeatmapCells :many
+-- Distinct users per geohash cell of the given precision inside a box since a given time
+SELECT
+  left(geohash, @precision::int)::text AS cell,
+  array_agg(DISTINCT user_id)::uuid[] AS user_ids
 FROM locations
-WHERE time_bucket > NOW() - INTERVAL '1 hour'
-GROUP BY ST_SnapToGrid(geom, 0.001);
+WHERE time_bucket > @since::timestamptz
+AND geom && ST_MakeEnvelope(@west::float8, @south::float8, @east::float8, @north::float8, 4326)
+GROUP BY 1;
+
+-- name: ListNearbyUsers :many
+-- The users among user_ids that viewer_id may discover nearby: not ghosted or
+-- shadow-banned, no block either way, sharing their location, and with a
+-- profile the viewer can see
+SELECT u.id, u.username, u.full_name, u.avatar_url
+FROM users u
+LEFT JOIN privacy_settings ps ON ps.user_id = u.id
+WHERE u.id = ANY(sqlc.arg(user_ids)::uuid[])
+  AND u.id <> sqlc.arg(viewer_id)::uuid
+  AND u.is_ghost_mode = false
+  AND u.is_shadow_banned = false
+  AND COALESCE(ps.show_location, true) = true
+  AND (
+    COALESCE(u.profile_visibility, 'public') = 'public'
+    OR (u.profile_visibility = 'connections' AND EXISTS (
+      SELECT 1 FROM connections c
+      WHERE c.status = 'accepted'
+        AND ((c.requester_id = sqlc.arg(viewer_id)::uuid AND c.target_id = u.id)
+          OR (c.requester_id = u.id AND c.target_id = sqlc.arg(viewer_id)::uuid))
+    ))
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM blocked_users bu
+    WHERE (bu.blocker_id = sqlc.arg(viewer_id)::uuid AND bu.blocked_id = u.id)
+       OR (bu.blocker_id = u.id AND bu.blocked_id = sqlc.arg(viewer_id)::uuid)
+  );
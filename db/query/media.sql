@@ -0,0 +1,40 @@
+-- name: UpsertMediaObject :one
+-- A re-upload keeps the longer of the two retention windows
+INSERT INTO media_objects (
+  hash,
+  object_key,
+  url,
+  size_bytes,
+  content_type,
+  retain_until
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (hash) DO UPDATE
+SET updated_at = NOW(),
+    retain_until = GREATEST(media_objects.retain_until, EXCLUDED.retain_until)
+RETURNING *;
+
+-- name: GetMediaObjectByHash :one
+SELECT * FROM media_objects
+WHERE hash = $1 LIMIT 1;
+
+-- Media nobody references, idle past the grace period, past its retention and not on hold
+-- name: ListUnreferencedMediaObjects :many
+SELECT * FROM media_objects
+WHERE ref_count <= 0
+  AND on_hold = false
+  AND updated_at < sqlc.arg(idle_before)
+  AND (retain_until IS NULL OR retain_until < sqlc.arg(now)::timestamptz)
+ORDER BY updated_at
+LIMIT sqlc.arg(batch_size);
+
+-- Only deletes if still unreferenced and not on hold, so a concurrent reuse or hold wins
+-- name: DeleteUnreferencedMediaObject :execrows
+DELETE FROM media_objects
+WHERE hash = $1 AND ref_count <= 0 AND on_hold = false;
+
+-- name: SetMediaObjectHold :execrows
+UPDATE media_objects
+SET on_hold = $2, updated_at = NOW()
+WHERE url = $1;
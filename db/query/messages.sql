@@ -6,11 +6,27 @@ INSERT INTO messages (
   content,
   media_url,
   media_type,
-  expires_at
+  expires_at,
+  reply_to_message_id,
+  forwarded_from_message_id,
+  forwarded_from_sender_id,
+  story_id
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
 ) RETURNING *;
 
+-- name: CreateMessageAttachments :exec
+-- Stores a message's attachments in the order given
+INSERT INTO message_attachments (message_id, position, media_url, media_type)
+SELECT sqlc.arg(message_id)::uuid, a.ord - 1, a.media_url, a.media_type
+FROM unnest(sqlc.arg(media_urls)::text[], sqlc.arg(media_types)::text[])
+  WITH ORDINALITY AS a(media_url, media_type, ord);
+
+-- name: ListMessageAttachments :many
+SELECT * FROM message_attachments
+WHERE message_id = $1
+ORDER BY position;
+
 -- name: ListMessages :many
 SELECT m.*,
        COALESCE(
@@ -26,7 +42,31 @@ SELECT m.*,
             JOIN users u ON mr.user_id = u.id
             WHERE mr.message_id = m.id),
            '[]'::json
-       ) as reactions
+       ) as reactions,
+       COALESCE(
+           (SELECT json_agg(json_build_object(
+               'url', ma.media_url,
+               'type', ma.media_type
+           ) ORDER BY ma.position)
+            FROM message_attachments ma
+            WHERE ma.message_id = m.id),
+           '[]'::json
+       ) as attachments,
+       (SELECT json_build_object(
+           'id', p.id,
+           'sender_id', p.sender_id,
+           'username', pu.username,
+           'content', p.content,
+           'media_type', p.media_type
+        )
+        FROM messages p
+        JOIN users pu ON p.sender_id = pu.id
+        WHERE p.id = m.reply_to_message_id
+          AND (p.expires_at IS NULL OR p.expires_at > NOW())) as reply_to,
+       (SELECT COUNT(*)
+        FROM messages r
+        WHERE r.reply_to_message_id = m.id
+          AND (r.expires_at IS NULL OR r.expires_at > NOW())) as reply_count
 FROM messages m
 WHERE ((m.sender_id = $1 AND m.receiver_id = $2)
    OR (m.sender_id = $2 AND m.receiver_id = $1))
@@ -51,7 +91,31 @@ SELECT m.*,
             JOIN users reaction_user ON mr.user_id = reaction_user.id
             WHERE mr.message_id = m.id),
            '[]'::json
-       ) as reactions
+       ) as reactions,
+       COALESCE(
+           (SELECT json_agg(json_build_object(
+               'url', ma.media_url,
+               'type', ma.media_type
+           ) ORDER BY ma.position)
+            FROM message_attachments ma
+            WHERE ma.message_id = m.id),
+           '[]'::json
+       ) as attachments,
+       (SELECT json_build_object(
+           'id', p.id,
+           'sender_id', p.sender_id,
+           'username', pu.username,
+           'content', p.content,
+           'media_type', p.media_type
+        )
+        FROM messages p
+        JOIN users pu ON p.sender_id = pu.id
+        WHERE p.id = m.reply_to_message_id
+          AND (p.expires_at IS NULL OR p.expires_at > NOW())) as reply_to,
+       (SELECT COUNT(*)
+        FROM messages r
+        WHERE r.reply_to_message_id = m.id
+          AND (r.expires_at IS NULL OR r.expires_at > NOW())) as reply_count
 FROM messages m
 JOIN users u ON m.sender_id = u.id
 WHERE m.group_id = $1
@@ -63,6 +127,28 @@ ORDER BY m.created_at ASC;
 DELETE FROM messages
 WHERE created_at < NOW() - INTERVAL '30 days';
 
+-- name: ClearExpiredMessageMedia :execrows
+-- Detaches media older than the retention policy from messages that outlive it,
+-- unless the media is on hold. The media GC then purges the object.
+WITH cleared_attachments AS (
+  DELETE FROM message_attachments ma
+  USING messages m
+  WHERE ma.message_id = m.id
+    AND m.created_at < sqlc.arg(created_before)::timestamptz
+    AND NOT EXISTS (
+      SELECT 1 FROM media_objects mo
+      WHERE mo.url = ma.media_url AND mo.on_hold = true
+    )
+)
+UPDATE messages m
+SET media_url = NULL, media_type = NULL
+WHERE m.media_url IS NOT NULL
+  AND m.created_at < sqlc.arg(created_before)::timestamptz
+  AND NOT EXISTS (
+    SELECT 1 FROM media_objects mo
+    WHERE mo.url = m.media_url AND mo.on_hold = true
+  );
+
 -- name: DeleteExpiredMessages :exec
 DELETE FROM messages
 WHERE expires_at IS NOT NULL AND expires_at < NOW();
@@ -86,27 +172,104 @@ RETURNING *;
 -- name: GetMessage :one
 SELECT * FROM messages WHERE id = $1;
 
+-- name: HasStoryReply :one
+-- Whether sender_id already replied to the story
+SELECT EXISTS (
+  SELECT 1 FROM messages
+  WHERE story_id = $1 AND sender_id = $2
+);
+
 -- name: MarkMessageRead :one
 UPDATE messages
-SET read_at = NOW()
+SET read_at = NOW(), delivered_at = COALESCE(delivered_at, NOW())
 WHERE id = $1 AND receiver_id = $2 AND read_at IS NULL
 RETURNING *;
 
--- name: MarkConversationRead :exec
+-- name: MarkConversationRead :many
+-- Returns the messages this call marked, for per-message read receipts
 UPDATE messages
-SET read_at = NOW()
-WHERE receiver_id = $1 AND sender_id = $2 AND read_at IS NULL;
+SET read_at = NOW(), delivered_at = COALESCE(delivered_at, NOW())
+WHERE receiver_id = $1 AND sender_id = $2 AND read_at IS NULL
+RETURNING id, read_at;
+
+-- name: GetMessageReplyPreview :one
+-- What a reply shows of the message it quotes; no row once that message is
+-- deleted or expired
+SELECT m.id, m.sender_id, u.username, m.content, m.media_type
+FROM messages m
+JOIN users u ON m.sender_id = u.id
+WHERE m.id = $1
+  AND (m.expires_at IS NULL OR m.expires_at > NOW());
+
+-- name: ListMessageReplies :many
+-- Unexpired replies to a message, oldest first. Deleted replies are gone
+-- from the table, so they drop out on their own.
+SELECT m.id, m.sender_id, u.username, u.avatar_url, m.content, m.media_url,
+       m.media_type, m.created_at, m.expires_at
+FROM messages m
+JOIN users u ON m.sender_id = u.id
+WHERE m.reply_to_message_id = sqlc.arg(message_id)::uuid
+  AND (m.expires_at IS NULL OR m.expires_at > NOW())
+ORDER BY m.created_at ASC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountMessageReplies :one
+-- How many replies ListMessageReplies returns across all pages
+SELECT COUNT(*) FROM messages
+WHERE reply_to_message_id = sqlc.arg(message_id)::uuid
+  AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: GetMessageStatus :many
+-- Delivery and read state of messages the user sent or received, so a
+-- reconnecting client can catch up on receipts it missed
+SELECT id, sender_id, receiver_id, delivered_at, read_at
+FROM messages
+WHERE id = ANY(sqlc.arg(message_ids)::uuid[])
+  AND (sender_id = sqlc.arg(user_id) OR receiver_id = sqlc.arg(user_id))
+ORDER BY created_at;
+
+-- name: MarkMessagesDelivered :many
+-- Records that the receiver's client got these messages. Only the first
+-- receipt counts, so already-delivered messages are not returned again.
+UPDATE messages
+SET delivered_at = NOW()
+WHERE id = ANY(sqlc.arg(message_ids)::uuid[])
+  AND receiver_id = sqlc.arg(receiver_id)
+  AND delivered_at IS NULL
+RETURNING id, sender_id, delivered_at;
 
 -- name: CreateMessageReaction :one
-INSERT INTO message_reactions (message_id, user_id, emoji)
-VALUES ($1, $2, $3)
-ON CONFLICT (message_id, user_id, emoji) DO NOTHING
-RETURNING *;
+-- Toggles one emoji of a user's reactions: adds it, or removes it if the user
+-- already reacted with it. Different emoji on the same message are kept apart.
+WITH removed AS (
+  DELETE FROM message_reactions
+  WHERE message_id = $1 AND user_id = $2 AND emoji = $3
+  RETURNING *
+), added AS (
+  INSERT INTO message_reactions (message_id, user_id, emoji)
+  SELECT $1, $2, $3
+  WHERE NOT EXISTS (SELECT 1 FROM removed)
+  ON CONFLICT (message_id, user_id, emoji) DO NOTHING
+  RETURNING *
+)
+SELECT id, message_id, user_id, emoji, created_at, true AS added FROM added
+UNION ALL
+SELECT id, message_id, user_id, emoji, created_at, false AS added FROM removed;
 
 -- name: DeleteMessageReaction :exec
 DELETE FROM message_reactions
 WHERE message_id = $1 AND user_id = $2 AND emoji = $3;
 
+-- name: GetAggregatedReactions :many
+-- One row per emoji on a message, in the order they were first used
+SELECT emoji,
+       COUNT(*) AS count,
+       bool_or(user_id = sqlc.arg(viewer_id))::bool AS reacted_by_me
+FROM message_reactions
+WHERE message_id = sqlc.arg(message_id)
+GROUP BY emoji
+ORDER BY MIN(created_at);
+
 -- name: GetMessageReactions :many
 SELECT mr.*, u.username, u.avatar_url
 FROM message_reactions mr
@@ -116,8 +279,15 @@ WHERE mr.message_id = $1
 ORDER BY mr.created_at ASC;
 
 -- name: GetUnreadMessageCount :one
+-- Expired messages and those from senders the receiver muted don't count
+-- toward the badge
 SELECT COUNT(*) FROM messages
-WHERE receiver_id = $1 AND read_at IS NULL;
+WHERE receiver_id = $1 AND read_at IS NULL
+  AND (expires_at IS NULL OR expires_at > NOW())
+  AND NOT EXISTS (
+    SELECT 1 FROM muted_conversations mc
+    WHERE mc.user_id = $1 AND mc.target_user_id = messages.sender_id
+  );
 
 -- name: GetConversationList :many
 WITH conversation_partners AS (
@@ -143,7 +313,10 @@ latest_messages AS (
     m.id as message_id,
     m.content as last_message,
     m.created_at as last_message_at,
-    m.sender_id as last_sender_id
+    m.sender_id as last_sender_id,
+    m.media_url as last_message_media_url,
+    m.media_type as last_message_media_type,
+    m.expires_at as last_message_expires_at
   FROM messages m
   WHERE (m.sender_id = $1 OR m.receiver_id = $1)
     AND (m.expires_at IS NULL OR m.expires_at > NOW())
@@ -162,6 +335,9 @@ SELECT
   lm.last_message,
   lm.last_message_at,
   lm.last_sender_id,
+  lm.last_message_media_type,
+  (lm.last_message_media_url IS NOT NULL AND lm.last_message_media_url <> '') as last_message_is_media,
+  lm.last_message_expires_at,
   COALESCE(
     (SELECT COUNT(*) 
      FROM messages m2
@@ -170,7 +346,11 @@ SELECT
        AND m2.read_at IS NULL
        AND (m2.expires_at IS NULL OR m2.expires_at > NOW())
     ), 0
-  ) as unread_count
+  ) as unread_count,
+  EXISTS (
+    SELECT 1 FROM muted_conversations mc
+    WHERE mc.user_id = $1 AND mc.target_user_id = u.id
+  ) as muted
 FROM conversation_partners cp
 JOIN users u ON u.id = cp.partner_id
 JOIN latest_messages lm ON lm.partner_id = cp.partner_id
@@ -180,3 +360,44 @@ ORDER BY lm.last_message_at DESC;
 DELETE FROM messages
 WHERE (sender_id = $1 AND receiver_id = $2)
    OR (sender_id = $2 AND receiver_id = $1);
+
+-- name: SearchDirectMessages :many
+-- Unexpired messages between two users whose text contains query, newest
+-- first. query is a LIKE pattern body, with % _ and \ escaped by the caller.
+SELECT m.id, m.sender_id, u.username, m.content, m.media_type, m.created_at
+FROM messages m
+JOIN users u ON m.sender_id = u.id
+WHERE ((m.sender_id = sqlc.arg(user_id)::uuid AND m.receiver_id = sqlc.arg(other_user_id)::uuid)
+    OR (m.sender_id = sqlc.arg(other_user_id)::uuid AND m.receiver_id = sqlc.arg(user_id)::uuid))
+  AND m.group_id IS NULL
+  AND (m.expires_at IS NULL OR m.expires_at > NOW())
+  AND m.content ILIKE '%' || sqlc.arg(query)::text || '%'
+ORDER BY m.created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountDirectMessageMatches :one
+-- How many messages SearchDirectMessages matches across all pages
+SELECT COUNT(*) FROM messages m
+WHERE ((m.sender_id = sqlc.arg(user_id)::uuid AND m.receiver_id = sqlc.arg(other_user_id)::uuid)
+    OR (m.sender_id = sqlc.arg(other_user_id)::uuid AND m.receiver_id = sqlc.arg(user_id)::uuid))
+  AND m.group_id IS NULL
+  AND (m.expires_at IS NULL OR m.expires_at > NOW())
+  AND m.content ILIKE '%' || sqlc.arg(query)::text || '%';
+
+-- name: SearchGroupMessages :many
+-- Unexpired messages in a group whose text contains query, newest first
+SELECT m.id, m.sender_id, u.username, m.content, m.media_type, m.created_at
+FROM messages m
+JOIN users u ON m.sender_id = u.id
+WHERE m.group_id = sqlc.arg(group_id)::uuid
+  AND (m.expires_at IS NULL OR m.expires_at > NOW())
+  AND m.content ILIKE '%' || sqlc.arg(query)::text || '%'
+ORDER BY m.created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountGroupMessageMatches :one
+-- How many messages SearchGroupMessages matches across all pages
+SELECT COUNT(*) FROM messages m
+WHERE m.group_id = sqlc.arg(group_id)::uuid
+  AND (m.expires_at IS NULL OR m.expires_at > NOW())
+  AND m.content ILIKE '%' || sqlc.arg(query)::text || '%';
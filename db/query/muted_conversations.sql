@@ -0,0 +1,28 @@
+-- name: MuteUserConversation :exec
+-- Muting an already muted conversation is a no-op
+INSERT INTO muted_conversations (user_id, target_user_id)
+VALUES ($1, $2)
+ON CONFLICT (user_id, target_user_id) WHERE target_user_id IS NOT NULL DO NOTHING;
+
+-- name: MuteGroupConversation :exec
+-- Muting an already muted group is a no-op
+INSERT INTO muted_conversations (user_id, group_id)
+VALUES ($1, $2)
+ON CONFLICT (user_id, group_id) WHERE group_id IS NOT NULL DO NOTHING;
+
+-- name: UnmuteConversation :exec
+-- target_id is either the other user of a direct chat or a group
+DELETE FROM muted_conversations
+WHERE user_id = sqlc.arg(user_id)
+  AND (target_user_id = sqlc.arg(target_id) OR group_id = sqlc.arg(target_id));
+
+-- name: IsConversationMuted :one
+SELECT EXISTS (
+  SELECT 1 FROM muted_conversations
+  WHERE user_id = $1 AND target_user_id = $2
+);
+
+-- name: ListGroupMuters :many
+-- Members who muted the group, so deliveries to them can skip the unread badge
+SELECT user_id FROM muted_conversations
+WHERE group_id = $1;
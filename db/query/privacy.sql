@@ -2,14 +2,18 @@
 SELECT * FROM privacy_settings WHERE user_id = $1;
 
 -- name: UpsertPrivacySettings :one
+-- Settings passed as null keep their current value, or the default on a new row
 INSERT INTO privacy_settings (
     user_id, who_can_message, who_can_see_stories, show_location
 ) VALUES (
-    $1, $2, $3, $4
+    sqlc.arg(user_id),
+    COALESCE(sqlc.narg(who_can_message), 'connections'),
+    COALESCE(sqlc.narg(who_can_see_stories), 'connections'),
+    COALESCE(sqlc.narg(show_location), true)
 ) ON CONFLICT (user_id) DO UPDATE
 SET 
-    who_can_message = EXCLUDED.who_can_message,
-    who_can_see_stories = EXCLUDED.who_can_see_stories,
-    show_location = EXCLUDED.show_location,
+    who_can_message = COALESCE(sqlc.narg(who_can_message), privacy_settings.who_can_message),
+    who_can_see_stories = COALESCE(sqlc.narg(who_can_see_stories), privacy_settings.who_can_see_stories),
+    show_location = COALESCE(sqlc.narg(show_location), privacy_settings.show_location),
     updated_at = NOW()
 RETURNING *;
@@ -3,12 +3,23 @@ INSERT INTO reports (
   reporter_id,
   target_user_id,
   target_story_id,
+  target_message_id,
   reason,
   description
 ) VALUES (
-  $1, $2, $3, $4, $5
+  $1, $2, $3, $4, $5, $6
 ) RETURNING *;
 
+-- Distinct reporters with an open report on a story
+-- name: CountOpenStoryReports :one
+SELECT COUNT(DISTINCT reporter_id) FROM reports
+WHERE target_story_id = $1 AND is_resolved = false;
+
+-- Admin: Count reports for pagination
+-- name: CountReports :one
+SELECT COUNT(*) FROM reports
+WHERE is_resolved = $1;
+
 -- Admin: List all reports
 -- name: ListReports :many
 SELECT r.*, 
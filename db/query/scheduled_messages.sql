@@ -0,0 +1,44 @@
+-- name: CreateScheduledMessage :one
+INSERT INTO scheduled_messages (
+  sender_id,
+  receiver_id,
+  content,
+  media_url,
+  media_type,
+  expires_in_seconds,
+  scheduled_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetScheduledMessage :one
+SELECT * FROM scheduled_messages
+WHERE id = $1 AND sender_id = $2;
+
+-- name: ListScheduledMessages :many
+SELECT * FROM scheduled_messages
+WHERE sender_id = $1
+ORDER BY scheduled_at ASC;
+
+-- name: UpdateScheduledMessage :one
+UPDATE scheduled_messages
+SET content = $3, media_url = $4, media_type = $5, scheduled_at = $6, updated_at = NOW()
+WHERE id = $1 AND sender_id = $2
+RETURNING *;
+
+-- name: DeleteScheduledMessage :execrows
+DELETE FROM scheduled_messages
+WHERE id = $1 AND sender_id = $2;
+
+-- name: ListDueScheduledMessages :many
+SELECT * FROM scheduled_messages
+WHERE scheduled_at <= sqlc.arg(due_before)
+ORDER BY scheduled_at ASC
+LIMIT sqlc.arg(result_limit);
+
+-- name: ClaimScheduledMessage :one
+-- Removes a due message so exactly one sender delivers it. No row means it was
+-- cancelled, postponed or already sent.
+DELETE FROM scheduled_messages
+WHERE id = sqlc.arg(id) AND scheduled_at <= sqlc.arg(due_before)
+RETURNING *;
@@ -6,11 +6,52 @@ INSERT INTO sessions (
   user_agent,
   client_ip,
   is_blocked,
-  expires_at
+  expires_at,
+  device_name,
+  platform
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
 ) RETURNING *;
 
 -- name: GetSession :one
 SELECT * FROM sessions
 WHERE id = $1 LIMIT 1;
+
+-- name: ListActiveSessions :many
+SELECT * FROM sessions
+WHERE user_id = $1
+  AND is_blocked = false
+  AND expires_at > now()
+ORDER BY created_at DESC;
+
+-- name: RevokeExcessSessions :execrows
+-- Keeps the newest max_sessions active sessions and revokes the rest
+UPDATE sessions
+SET is_blocked = true
+WHERE user_id = sqlc.arg(user_id)
+  AND is_blocked = false
+  AND expires_at > now()
+  AND id NOT IN (
+    SELECT s.id FROM sessions s
+    WHERE s.user_id = sqlc.arg(user_id)
+      AND s.is_blocked = false
+      AND s.expires_at > now()
+    ORDER BY s.created_at DESC
+    LIMIT sqlc.arg(max_sessions)
+  );
+
+-- name: RevokeSession :execrows
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1 AND user_id = $2 AND is_blocked = false;
+
+-- name: BlockSession :execrows
+-- Blocks one of a user's sessions even if it already is, so logging out twice succeeds
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1 AND user_id = $2;
+
+-- name: BlockUserSessions :execrows
+UPDATE sessions
+SET is_blocked = true
+WHERE user_id = $1 AND is_blocked = false;
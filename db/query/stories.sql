@@ -9,13 +9,16 @@ INSERT INTO stories (
   is_anonymous,
   show_location,
   is_premium,
-  expires_at
+  expires_at,
+  visibility
 ) VALUES (
-  @user_id, @media_url, @media_type, @caption, @geohash, ST_SetSRID(ST_MakePoint(@lng::float8, @lat::float8), 4326), @is_anonymous, @show_location, @is_premium, @expires_at
+  @user_id, @media_url, @media_type, @caption, @geohash, ST_SetSRID(ST_MakePoint(@lng::float8, @lat::float8), 4326), @is_anonymous, @show_location, @is_premium, @expires_at, @visibility
 ) RETURNING *, ST_Y(geom::geometry) as lat, ST_X(geom::geometry) as lng;
 
 -- name: GetStoryByID :one
-SELECT *, ST_Y(geom::geometry) as lat, ST_X(geom::geometry) as lng FROM stories
+SELECT *, ST_Y(geom::geometry) as lat, ST_X(geom::geometry) as lng,
+       (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = stories.id)::bigint AS reaction_count
+FROM stories
 WHERE id = $1 LIMIT 1;
 
 -- name: UpdateStory :one
@@ -28,11 +31,15 @@ WHERE id = $1
   AND user_id = $2
   AND created_at > NOW() - INTERVAL '15 minutes'
   AND expires_at > NOW()
-RETURNING *, ST_Y(geom::geometry) as lat, ST_X(geom::geometry) as lng;
+RETURNING *, ST_Y(geom::geometry) as lat, ST_X(geom::geometry) as lng,
+  (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = stories.id)::bigint AS reaction_count;
 
 -- name: GetStoriesWithinRadius :many
 SELECT s.*, u.username, u.avatar_url, u.is_premium,
-       ST_Y(s.geom::geometry) as lat, ST_X(s.geom::geometry) as lng
+       ST_Y(s.geom::geometry) as lat, ST_X(s.geom::geometry) as lng,
+       (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = s.id)::bigint AS reaction_count,
+       -- Matches before the LIMIT, so clients can tell when the feed was capped
+       COUNT(*) OVER() AS total_count
 FROM stories s
 JOIN users u ON s.user_id = u.id
 WHERE 
@@ -41,11 +48,22 @@ WHERE
     ST_MakePoint(sqlc.arg(lng)::float8, sqlc.arg(lat)::float8)::geography,
     sqlc.arg(radius_meters)
   )
-  AND s.expires_at > now()
+  AND s.expires_at > sqlc.arg(now)::timestamptz
+  -- Incremental refresh: only stories posted after the client's last fetch
+  AND (sqlc.narg(since)::timestamptz IS NULL OR s.created_at > sqlc.narg(since)::timestamptz)
+  -- Hashtag discovery: only stories tagged with the requested hashtag
+  AND (sqlc.narg(hashtag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM story_hashtags sh
+    WHERE sh.story_id = s.id AND sh.tag = sqlc.narg(hashtag)::text
+  ))
   -- Allow anonymous stories (handled in presentation)
   -- AND (s.is_anonymous = false OR s.user_id = @user_id)
   AND u.is_shadow_banned = false
   AND u.is_ghost_mode = false
+  -- Stories held for moderation are only visible to their author
+  AND (s.user_id = sqlc.arg(user_id) OR NOT EXISTS (
+    SELECT 1 FROM story_moderation_holds smh WHERE smh.story_id = s.id
+  ))
   -- Strict Streak Rule (DISABLED)
   -- AND DATE(u.last_active_at) >= CURRENT_DATE - INTERVAL '1 day'
   -- Block Logic: Exclude if blocked by either party (using blocked_users table)
@@ -54,6 +72,19 @@ WHERE
     WHERE (bu.blocker_id = sqlc.arg(user_id) AND bu.blocked_id = s.user_id)
        OR (bu.blocker_id = s.user_id AND bu.blocked_id = sqlc.arg(user_id))
   )
+  -- Story audience: connections and close_friends stories stay within that audience
+  AND (
+    s.visibility = 'public'
+    OR s.user_id = sqlc.arg(user_id)
+    OR (s.visibility = 'connections' AND EXISTS (
+      SELECT 1 FROM connections c
+      WHERE (c.requester_id = sqlc.arg(user_id) AND c.target_id = s.user_id OR c.requester_id = s.user_id AND c.target_id = sqlc.arg(user_id))
+      AND c.status = 'accepted'
+    ))
+    OR (s.visibility = 'close_friends' AND EXISTS (
+      SELECT 1 FROM close_friends cf WHERE cf.user_id = s.user_id AND cf.friend_id = sqlc.arg(user_id)
+    ))
+  )
   -- Privacy Settings Logic --
   AND (
     -- Case 1: My own stories (always visible)
@@ -81,14 +112,32 @@ WHERE
        NOT EXISTS (SELECT 1 FROM privacy_settings ps WHERE ps.user_id = s.user_id)
     )
   )
+  -- Connection-gated deployments: strangers only appear when they opted into a public audience
+  AND (
+    NOT sqlc.arg(connections_only)::boolean
+    OR s.user_id = sqlc.arg(user_id)
+    OR EXISTS (
+      SELECT 1 FROM connections c
+      WHERE (c.requester_id = sqlc.arg(user_id) AND c.target_id = s.user_id OR c.requester_id = s.user_id AND c.target_id = sqlc.arg(user_id))
+      AND c.status = 'accepted'
+    )
+    OR (
+      s.visibility = 'public'
+      AND EXISTS (
+        SELECT 1 FROM privacy_settings ps
+        WHERE ps.user_id = s.user_id AND ps.who_can_see_stories = 'everyone'
+      )
+    )
+  )
 ORDER BY 
   s.geom <-> ST_SetSRID(ST_MakePoint(sqlc.arg(lng)::float8, sqlc.arg(lat)::float8), 4326)
-LIMIT 50;
+LIMIT sqlc.arg(result_limit);
 
 -- name: GetConnectionStories :many
 -- Get stories from connected users (not limited by radius)
 SELECT s.*, u.username, u.avatar_url, u.is_premium,
-       ST_Y(s.geom::geometry) as lat, ST_X(s.geom::geometry) as lng
+       ST_Y(s.geom::geometry) as lat, ST_X(s.geom::geometry) as lng,
+       (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = s.id)::bigint AS reaction_count
 FROM stories s
 JOIN users u ON s.user_id = u.id
 JOIN connections c ON 
@@ -99,6 +148,10 @@ WHERE
   AND s.expires_at > now()
   AND u.is_shadow_banned = false
   AND u.is_shadow_banned = false
+  -- Stories held for moderation stay out of feeds until released
+  AND NOT EXISTS (
+    SELECT 1 FROM story_moderation_holds smh WHERE smh.story_id = s.id
+  )
   -- strict streak rule (DISABLED)
   -- AND DATE(u.last_active_at) >= CURRENT_DATE - INTERVAL '1 day'
   -- Block Logic: Exclude if blocked by either party
@@ -107,24 +160,84 @@ WHERE
     WHERE (bu.blocker_id = @user_id AND bu.blocked_id = s.user_id)
        OR (bu.blocker_id = s.user_id AND bu.blocked_id = @user_id)
   )
+  -- Close-friends stories only reach the author's close friends
+  AND (s.visibility <> 'close_friends' OR EXISTS (
+    SELECT 1 FROM close_friends cf WHERE cf.user_id = s.user_id AND cf.friend_id = @user_id
+  ))
 ORDER BY s.created_at DESC;
 
+-- name: ListConnectionStoryRings :many
+-- Live stories of the viewer's connections with the viewer's seen state, ordered
+-- as a stories tray: authors with unseen stories first, then by their latest
+-- story; each author's stories oldest first. Anonymous stories are left out
+-- because a ring names its author.
+SELECT s.id, s.user_id, s.media_url, s.media_type, s.thumbnail_url, s.caption, s.geohash,
+       s.visibility, s.expires_at, s.created_at, s.is_anonymous, s.show_location,
+       u.username, u.avatar_url, u.is_premium,
+       ST_Y(s.geom::geometry) as lat, ST_X(s.geom::geometry) as lng,
+       (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = s.id)::bigint AS reaction_count,
+       (sv.id IS NOT NULL)::bool AS seen,
+       bool_or(sv.id IS NULL) OVER (PARTITION BY s.user_id)::bool AS author_has_unseen,
+       max(s.created_at) OVER (PARTITION BY s.user_id)::timestamptz AS author_latest_at
+FROM stories s
+JOIN users u ON s.user_id = u.id
+JOIN connections c ON
+  (c.requester_id = @user_id AND c.target_id = s.user_id) OR
+  (c.target_id = @user_id AND c.requester_id = s.user_id)
+LEFT JOIN story_views sv ON sv.story_id = s.id AND sv.user_id = @user_id
+WHERE
+  c.status = 'accepted'
+  AND s.expires_at > now()
+  AND s.is_anonymous = false
+  AND u.is_shadow_banned = false
+  AND NOT EXISTS (
+    SELECT 1 FROM story_moderation_holds smh WHERE smh.story_id = s.id
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM blocked_users bu
+    WHERE (bu.blocker_id = @user_id AND bu.blocked_id = s.user_id)
+       OR (bu.blocker_id = s.user_id AND bu.blocked_id = @user_id)
+  )
+  -- Close-friends stories only reach the author's close friends
+  AND (s.visibility <> 'close_friends' OR EXISTS (
+    SELECT 1 FROM close_friends cf WHERE cf.user_id = s.user_id AND cf.friend_id = @user_id
+  ))
+ORDER BY author_has_unseen DESC, author_latest_at DESC, s.user_id, s.created_at ASC;
+
 -- name: GetStoriesInBounds :many
 -- Get stories within a bounding box for map view
 SELECT s.*, u.username, u.avatar_url,
-       ST_Y(s.geom::geometry) as lat, ST_X(s.geom::geometry) as lng
+       ST_Y(s.geom::geometry) as lat, ST_X(s.geom::geometry) as lng,
+       (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = s.id)::bigint AS reaction_count
 FROM stories s
 JOIN users u ON s.user_id = u.id
 WHERE s.geom && ST_MakeEnvelope(@west::float8, @south::float8, @east::float8, @north::float8, 4326)
 AND s.expires_at > now()
 AND u.is_shadow_banned = false
 AND u.is_ghost_mode = false
+-- Stories held for moderation are only visible to their author
+AND (s.user_id = @current_user_id OR NOT EXISTS (
+    SELECT 1 FROM story_moderation_holds smh WHERE smh.story_id = s.id
+))
 -- AND DATE(u.last_active_at) >= CURRENT_DATE - INTERVAL '1 day'
 AND NOT EXISTS (
     SELECT 1 FROM blocked_users bu 
     WHERE (bu.blocker_id = @current_user_id AND bu.blocked_id = s.user_id)
        OR (bu.blocker_id = s.user_id AND bu.blocked_id = @current_user_id)
 )
+-- Story audience: connections and close_friends stories stay within that audience
+AND (
+  s.visibility = 'public'
+  OR s.user_id = @current_user_id
+  OR (s.visibility = 'connections' AND EXISTS (
+    SELECT 1 FROM connections c
+    WHERE (c.requester_id = @current_user_id AND c.target_id = s.user_id OR c.requester_id = s.user_id AND c.target_id = @current_user_id)
+    AND c.status = 'accepted'
+  ))
+  OR (s.visibility = 'close_friends' AND EXISTS (
+    SELECT 1 FROM close_friends cf WHERE cf.user_id = s.user_id AND cf.friend_id = @current_user_id
+  ))
+)
 AND (
     s.user_id = @current_user_id
     OR
@@ -159,6 +272,9 @@ DELETE FROM stories
 WHERE id = $1;
 
 -- Admin: List all stories
+-- name: CountStories :one
+SELECT COUNT(*) FROM stories;
+
 -- name: ListAllStories :many
 SELECT s.*, u.username
 FROM stories s
@@ -180,3 +296,52 @@ SELECT EXISTS (
     WHERE user_id = $1 
     AND expires_at > now()
 );
+
+-- name: ListMyStories :many
+-- The owner's live stories plus archived (highlighted) stories whose original has expired, newest first
+SELECT *, COUNT(*) OVER() AS total_count FROM (
+  SELECT s.id AS story_id,
+         NULL::uuid AS archive_id,
+         s.media_url,
+         s.media_type,
+         s.thumbnail_url,
+         s.caption,
+         s.is_anonymous,
+         s.show_location,
+         s.created_at,
+         s.expires_at::timestamptz AS expires_at,
+         false AS is_highlight,
+         (SELECT COUNT(*) FROM story_views sv WHERE sv.story_id = s.id)::bigint AS view_count,
+         (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = s.id)::bigint AS reaction_count
+  FROM stories s
+  WHERE s.user_id = sqlc.arg(user_id)
+    AND s.expires_at > sqlc.arg(now)::timestamptz
+  UNION ALL
+  SELECT a.story_id,
+         a.id,
+         a.media_url,
+         a.media_type,
+         NULL::text,
+         a.caption,
+         COALESCE(a.is_anonymous, false),
+         COALESCE(a.show_location, true),
+         a.original_created_at,
+         NULL::timestamptz,
+         true,
+         (SELECT COUNT(*) FROM story_views sv WHERE sv.story_id = a.story_id)::bigint,
+         (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = a.story_id)::bigint
+  FROM archived_stories a
+  WHERE a.user_id = sqlc.arg(user_id)
+    -- A story archived while still live is listed once, as the live story
+    AND NOT EXISTS (
+      SELECT 1 FROM stories s
+      WHERE s.id = a.story_id AND s.expires_at > sqlc.arg(now)::timestamptz
+    )
+) mine
+ORDER BY created_at DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: ListUserStoryGeohashes :many
+-- Where a user's stories were posted, to expire the feeds they appear in
+SELECT DISTINCT geohash FROM stories
+WHERE user_id = $1;
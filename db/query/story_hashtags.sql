@@ -0,0 +1,72 @@
+-- name: CreateStoryHashtag :exec
+INSERT INTO story_hashtags (
+  story_id,
+  tag
+) VALUES (
+  $1, $2
+) ON CONFLICT (story_id, tag) DO NOTHING;
+
+-- name: DeleteStoryHashtags :exec
+DELETE FROM story_hashtags
+WHERE story_id = $1;
+
+-- name: GetTrendingHashtags :many
+-- Tags on live stories within the radius that the viewer could see in their
+-- feed, fastest-rising first
+SELECT sh.tag,
+       COUNT(*) AS story_count,
+       COUNT(*) FILTER (WHERE sh.created_at > sqlc.arg(recent_since)::timestamptz) AS recent_count
+FROM story_hashtags sh
+JOIN stories s ON s.id = sh.story_id
+JOIN users u ON u.id = s.user_id
+WHERE s.expires_at > sqlc.arg(now)::timestamptz
+  AND ST_DWithin(
+    s.geom::geography,
+    ST_MakePoint(sqlc.arg(lng)::float8, sqlc.arg(lat)::float8)::geography,
+    sqlc.arg(radius_meters)
+  )
+  AND u.is_shadow_banned = false
+  AND u.is_ghost_mode = false
+  -- Stories held for moderation stay out of feeds until released
+  AND NOT EXISTS (
+    SELECT 1 FROM story_moderation_holds smh WHERE smh.story_id = s.id
+  )
+  -- The same block, audience and privacy rules as GetStoriesWithinRadius, so
+  -- a tag can't reveal stories the viewer isn't allowed to see
+  AND NOT EXISTS (
+    SELECT 1 FROM blocked_users bu
+    WHERE (bu.blocker_id = sqlc.arg(user_id) AND bu.blocked_id = s.user_id)
+       OR (bu.blocker_id = s.user_id AND bu.blocked_id = sqlc.arg(user_id))
+  )
+  AND (
+    s.visibility = 'public'
+    OR s.user_id = sqlc.arg(user_id)
+    OR (s.visibility = 'connections' AND EXISTS (
+      SELECT 1 FROM connections c
+      WHERE (c.requester_id = sqlc.arg(user_id) AND c.target_id = s.user_id OR c.requester_id = s.user_id AND c.target_id = sqlc.arg(user_id))
+      AND c.status = 'accepted'
+    ))
+    OR (s.visibility = 'close_friends' AND EXISTS (
+      SELECT 1 FROM close_friends cf WHERE cf.user_id = s.user_id AND cf.friend_id = sqlc.arg(user_id)
+    ))
+  )
+  AND (
+    s.user_id = sqlc.arg(user_id)
+    OR EXISTS (
+      SELECT 1 FROM privacy_settings ps
+      WHERE ps.user_id = s.user_id
+      AND ps.show_location = true
+      AND (
+        ps.who_can_see_stories = 'everyone'
+        OR (ps.who_can_see_stories = 'connections' AND EXISTS (
+          SELECT 1 FROM connections c
+          WHERE (c.requester_id = sqlc.arg(user_id) AND c.target_id = s.user_id OR c.requester_id = s.user_id AND c.target_id = sqlc.arg(user_id))
+          AND c.status = 'accepted'
+        ))
+      )
+    )
+    OR NOT EXISTS (SELECT 1 FROM privacy_settings ps WHERE ps.user_id = s.user_id)
+  )
+GROUP BY sh.tag
+ORDER BY recent_count DESC, story_count DESC, sh.tag
+LIMIT sqlc.arg(result_limit);
@@ -7,6 +7,37 @@ INSERT INTO story_mentions (
 ) ON CONFLICT (story_id, mentioned_user_id) DO NOTHING
 RETURNING *;
 
+-- name: CreateStoryMentions :many
+-- Mentions the given users in a story and returns the ones newly mentioned.
+-- The author, anyone on either side of a block with them and anyone outside
+-- the story's audience are skipped.
+INSERT INTO story_mentions (
+  story_id,
+  mentioned_user_id
+)
+SELECT s.id, m.user_id
+FROM stories s, unnest(@mentioned_user_ids::uuid[]) AS m(user_id)
+WHERE s.id = @story_id
+  AND m.user_id <> s.user_id
+  AND NOT EXISTS (
+    SELECT 1 FROM blocked_users b
+    WHERE (b.blocker_id = m.user_id AND b.blocked_id = s.user_id)
+       OR (b.blocker_id = s.user_id AND b.blocked_id = m.user_id)
+  )
+  AND (
+    s.visibility = 'public'
+    OR (s.visibility = 'connections' AND EXISTS (
+      SELECT 1 FROM connections c
+      WHERE (c.requester_id = m.user_id AND c.target_id = s.user_id OR c.requester_id = s.user_id AND c.target_id = m.user_id)
+      AND c.status = 'accepted'
+    ))
+    OR (s.visibility = 'close_friends' AND EXISTS (
+      SELECT 1 FROM close_friends cf WHERE cf.user_id = s.user_id AND cf.friend_id = m.user_id
+    ))
+  )
+ON CONFLICT (story_id, mentioned_user_id) DO NOTHING
+RETURNING mentioned_user_id;
+
 -- name: GetStoryMentions :many
 SELECT sm.*, u.username, u.avatar_url
 FROM story_mentions sm
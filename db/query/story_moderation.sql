@@ -0,0 +1,32 @@
+-- name: CreateStoryModerationHold :exec
+INSERT INTO story_moderation_holds (story_id, reason)
+VALUES ($1, $2)
+ON CONFLICT (story_id) DO NOTHING;
+
+-- name: ReleaseStoryModerationHold :execrows
+DELETE FROM story_moderation_holds
+WHERE story_id = $1;
+
+-- name: IsStoryHeld :one
+SELECT EXISTS (
+    SELECT 1 FROM story_moderation_holds
+    WHERE story_id = $1
+);
+
+-- name: ListHeldStories :many
+-- Moderation queue: live stories waiting for review, oldest first. Shows the
+-- real author even for anonymous stories.
+SELECT s.id, s.user_id, u.username, s.media_url, s.media_type, s.caption, s.is_anonymous,
+       s.created_at, s.expires_at, h.reason, h.created_at AS held_at
+FROM story_moderation_holds h
+JOIN stories s ON s.id = h.story_id
+JOIN users u ON u.id = s.user_id
+WHERE s.expires_at > NOW()
+ORDER BY h.created_at ASC
+LIMIT $1 OFFSET $2;
+
+-- name: CountAnonymousStoriesSince :one
+SELECT COUNT(*) FROM stories
+WHERE user_id = $1
+  AND is_anonymous = true
+  AND created_at > sqlc.arg(since)::timestamptz;
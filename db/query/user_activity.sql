@@ -0,0 +1,9 @@
+-- name: ListUserActivityDays :many
+SELECT day FROM user_activity_days
+WHERE user_id = $1 AND day >= sqlc.arg(since)::date
+ORDER BY day ASC;
+
+-- name: DeleteOldUserActivityDays :exec
+-- Only recent days feed the activity summary
+DELETE FROM user_activity_days
+WHERE day < CURRENT_DATE - INTERVAL '60 days';
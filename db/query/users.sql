@@ -16,6 +16,11 @@ WHERE phone = $1 LIMIT 1;
 SELECT * FROM users
 WHERE username = $1 LIMIT 1;
 
+-- name: GetUsersByUsernames :many
+-- Matches case-insensitively, as mentions are parsed lowercased
+SELECT id, username FROM users
+WHERE lower(username) = ANY(@usernames::text[]);
+
 -- name: GetUserByID :one
 SELECT * FROM users
 WHERE id = $1 LIMIT 1;
@@ -42,26 +47,80 @@ WHERE id = $1;
 
 -- Admin Queries
 
--- name: ListUsers :many
+-- name: SearchAdminUsers :many
+-- Users whose username, email or phone contains query (any user when it's
+-- NULL), newest first. status is all, banned or active; a temporary ban that
+-- has run out counts as active.
 SELECT * FROM users
+WHERE (sqlc.narg(query)::text IS NULL
+    OR username ILIKE '%' || sqlc.narg(query)::text || '%'
+    OR email ILIKE '%' || sqlc.narg(query)::text || '%'
+    OR phone ILIKE '%' || sqlc.narg(query)::text || '%')
+  AND (sqlc.arg(status)::text = 'all'
+    OR (sqlc.arg(status)::text = 'banned') = (
+      is_shadow_banned OR (ban_type IS NOT NULL AND (ban_type <> 'temp' OR ban_expires_at > now()))
+    ))
 ORDER BY created_at DESC
-LIMIT $1 OFFSET $2;
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
--- name: CountUsers :one
-SELECT COUNT(*) FROM users;
+-- name: CountAdminUsers :one
+-- How many users SearchAdminUsers matches across all pages
+SELECT COUNT(*) FROM users
+WHERE (sqlc.narg(query)::text IS NULL
+    OR username ILIKE '%' || sqlc.narg(query)::text || '%'
+    OR email ILIKE '%' || sqlc.narg(query)::text || '%'
+    OR phone ILIKE '%' || sqlc.narg(query)::text || '%')
+  AND (sqlc.arg(status)::text = 'all'
+    OR (sqlc.arg(status)::text = 'banned') = (
+      is_shadow_banned OR (ban_type IS NOT NULL AND (ban_type <> 'temp' OR ban_expires_at > now()))
+    ));
 
 -- name: BanUser :one
+-- BanUser sets or clears a user's ban; a NULL ban_type lifts it
 UPDATE users
-SET is_shadow_banned = $2
+SET is_shadow_banned = $2,
+  ban_type = $3,
+  ban_reason = $4,
+  ban_expires_at = $5
 WHERE id = $1
 RETURNING *;
 
+-- name: ListActiveLockouts :many
+-- Users locked out by a hard ban or a suspension that hasn't ended
+SELECT id, ban_type, ban_expires_at FROM users
+WHERE ban_type = 'hard'
+  OR (ban_type = 'temp' AND ban_expires_at > now());
+
+-- name: LiftExpiredBans :execrows
+-- LiftExpiredBans clears suspensions whose end has passed
+UPDATE users
+SET ban_type = NULL,
+  ban_reason = NULL,
+  ban_expires_at = NULL
+WHERE ban_type = 'temp' AND ban_expires_at <= now();
+
 -- name: DeleteUser :exec
 DELETE FROM users
 WHERE id = $1;
 
+-- name: ListUserContacts :many
+-- Users whose cached data may include this user: connections in any state
+-- and direct message partners
+SELECT requester_id AS contact_id FROM connections WHERE target_id = $1
+UNION
+SELECT target_id FROM connections WHERE requester_id = $1
+UNION
+SELECT receiver_id FROM messages WHERE sender_id = $1 AND receiver_id IS NOT NULL
+UNION
+SELECT sender_id FROM messages WHERE receiver_id = $1;
+
 -- name: UpdateUserActivity :one
--- Updates last_active_at and calculates activity streak
+-- Updates last_active_at and calculates activity streak, recording today as an active day
+WITH active_day AS (
+  INSERT INTO user_activity_days (user_id, day)
+  VALUES ($1, CURRENT_DATE)
+  ON CONFLICT DO NOTHING
+)
 UPDATE users
 SET 
   last_active_at = now(),
@@ -157,6 +216,8 @@ WHERE id = $1
 RETURNING *;
 
 -- name: SearchUsers :many
+-- Users matching the query, leaving out anyone the viewer blocked or was
+-- blocked by
 SELECT 
   id,
   username,
@@ -169,6 +230,11 @@ FROM users
 WHERE 
   (username ILIKE '%' || sqlc.arg(query)::text || '%' OR full_name ILIKE '%' || sqlc.arg(query)::text || '%')
   AND is_shadow_banned = false
+  AND NOT EXISTS (
+    SELECT 1 FROM blocked_users bu
+    WHERE (bu.blocker_id = sqlc.arg(viewer_id) AND bu.blocked_id = users.id)
+       OR (bu.blocker_id = users.id AND bu.blocked_id = sqlc.arg(viewer_id))
+  )
 LIMIT 20;
 
 
@@ -217,3 +283,31 @@ SET
     password_reset_token = NULL,
     password_reset_expires_at = NULL
 WHERE id = $1;
+
+-- name: ListActiveUserIDs :many
+SELECT id FROM users
+WHERE last_active_at >= sqlc.arg(active_since)::timestamptz
+  AND is_shadow_banned = false
+ORDER BY id;
+
+-- name: UpdateUserLastSeen :exec
+UPDATE users
+SET last_seen_at = now()
+WHERE id = $1;
+
+-- name: GetUserPresence :one
+SELECT is_ghost_mode, ghost_mode_expires_at, last_seen_at FROM users
+WHERE id = $1;
+
+-- name: MarkPhoneVerified :one
+-- MarkPhoneVerified records that the user with this phone confirmed it with a one-time code
+UPDATE users
+SET phone_verified = true
+WHERE phone = $1
+RETURNING id;
+
+-- name: PromoteUserToAdmin :execrows
+-- PromoteUserToAdmin grants the admin role; it affects no rows when the user doesn't exist
+UPDATE users
+SET role = 'admin'
+WHERE id = $1;
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/util"
+)
+
+// Block relationships and messaging settings are read on every message send and
+// history fetch, so they are cached briefly and invalidated when either changes
+const accessCacheTTL = 5 * time.Minute
+
+// accessCacheMetrics counts cache lookups made by checkConnection
+var accessCacheMetrics struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func recordAccessCacheLookup(hit bool) {
+	if hit {
+		accessCacheMetrics.hits.Add(1)
+	} else {
+		accessCacheMetrics.misses.Add(1)
+	}
+}
+
+// blockCacheKey holds whether blockerID has blocked blockedID ("1" or "0")
+func blockCacheKey(blockerID, blockedID uuid.UUID) string {
+	return util.RedisKey("blocked:" + blockerID.String() + ":" + blockedID.String())
+}
+
+// whoCanMessageCacheKey holds a user's effective who_can_message setting
+func whoCanMessageCacheKey(userID uuid.UUID) string {
+	return util.RedisKey("who_can_message:" + userID.String())
+}
+
+// isUserBlockedCached reports whether blockerID has blocked blockedID, reading
+// through the cache. Redis errors fall back to the database.
+func (server *Server) isUserBlockedCached(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error) {
+	key := blockCacheKey(blockerID, blockedID)
+	if cached, err := server.redis.Get(ctx, key).Result(); err == nil {
+		recordAccessCacheLookup(true)
+		return cached == "1", nil
+	}
+	recordAccessCacheLookup(false)
+
+	blocked, err := server.store.IsUserBlocked(ctx, db.IsUserBlockedParams{
+		BlockerID: blockerID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	value := "0"
+	if blocked {
+		value = "1"
+	}
+	server.redis.Set(ctx, key, value, accessCacheTTL)
+	return blocked, nil
+}
+
+// whoCanMessageCached returns userID's who_can_message setting, defaulting to
+// "connections" when the user never saved any settings
+func (server *Server) whoCanMessageCached(ctx context.Context, userID uuid.UUID) (string, error) {
+	key := whoCanMessageCacheKey(userID)
+	if cached, err := server.redis.Get(ctx, key).Result(); err == nil {
+		recordAccessCacheLookup(true)
+		return cached, nil
+	}
+	recordAccessCacheLookup(false)
+
+	settings, err := server.store.GetPrivacySettings(ctx, userID)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	whoCanMessage := "connections"
+	if err == nil && settings.WhoCanMessage.Valid {
+		whoCanMessage = settings.WhoCanMessage.String
+	}
+	server.redis.Set(ctx, key, whoCanMessage, accessCacheTTL)
+	return whoCanMessage, nil
+}
+
+// invalidateBlockCache forgets the cached block state between two users in both directions
+func (server *Server) invalidateBlockCache(userID1, userID2 uuid.UUID) {
+	server.redis.Del(context.Background(), blockCacheKey(userID1, userID2), blockCacheKey(userID2, userID1))
+}
+
+// invalidatePrivacyCache forgets a user's cached messaging settings
+func (server *Server) invalidatePrivacyCache(userID uuid.UUID) {
+	server.redis.Del(context.Background(), whoCanMessageCacheKey(userID))
+}
+
+// Admin: Access cache hit rate for the messaging permission checks
+func (server *Server) getAccessCacheMetrics(ctx *gin.Context) {
+	hits := accessCacheMetrics.hits.Load()
+	misses := accessCacheMetrics.misses.Load()
+
+	var hitRate float64
+	if total := hits + misses; total > 0 {
+		hitRate = float64(hits) / float64(total)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"hits":     hits,
+		"misses":   misses,
+		"hit_rate": hitRate,
+	})
+}
@@ -1,15 +1,22 @@
 package api
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"privacy-social-backend/internal/repository/db"
 	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
 )
 
+var ErrActivityPrivate = errors.New("activity is only visible to connections")
+
 // getActivityStatus returns the user's activity status and visibility
 func (server *Server) getActivityStatus(ctx *gin.Context) {
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
@@ -62,7 +69,7 @@ func (server *Server) getActivityStatus(ctx *gin.Context) {
 			}
 
 			// 4. Check Real-time Online Status (Hub)
-			isOnline := server.hub.IsUserOnline(targetID)
+			isOnline := server.hub.IsUserOnline(ctx, targetID)
 
 			if isConnectedAndAccepted {
 				// If connected, we allow seeing the timestamp
@@ -101,3 +108,133 @@ func (server *Server) getActivityStatus(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, status)
 }
+
+// activitySummaryResponse is the streak and recent activity behind GET /me/activity
+type activitySummaryResponse struct {
+	UserID                 uuid.UUID     `json:"user_id"`
+	LastActiveAt           util.NullTime `json:"last_active_at"`
+	CurrentStreak          int32         `json:"current_streak"`
+	StreakFreezesRemaining int32         `json:"streak_freezes_remaining"`
+	CountedToday           bool          `json:"counted_today"`  // today already counts towards the streak
+	StreakAtRisk           bool          `json:"streak_at_risk"` // a streak that ends unless the user is active today
+	DaysActiveThisWeek     int           `json:"days_active_this_week"`
+	ActiveDaysThisWeek     []string      `json:"active_days_this_week"` // YYYY-MM-DD, weeks start Monday (UTC)
+}
+
+// getMyActivity returns the caller's streak and this week's activity. With
+// ?user_id= it returns a connection's activity instead; anyone else's is private.
+func (server *Server) getMyActivity(ctx *gin.Context) {
+	authPayload := getAuthPayload(ctx)
+
+	userID := authPayload.UserID
+	if targetIDStr := ctx.Query("user_id"); targetIDStr != "" {
+		targetID, ok := parseUUIDParam(ctx, targetIDStr, "user_id")
+		if !ok {
+			return
+		}
+		if targetID != authPayload.UserID {
+			connected, err := server.canSeeActivity(ctx, authPayload.UserID, targetID)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+			if !connected {
+				ctx.JSON(http.StatusForbidden, errorResponse(ErrActivityPrivate))
+				return
+			}
+		}
+		userID = targetID
+	}
+
+	user, err := server.store.GetUserByID(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	now := util.Now()
+	days, err := server.store.ListUserActivityDays(ctx, db.ListUserActivityDaysParams{
+		UserID: userID,
+		Since:  weekStart(now),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, summarizeActivity(user, days, now))
+}
+
+// canSeeActivity reports whether viewerID may see targetID's activity: an
+// accepted connection and no block either way
+func (server *Server) canSeeActivity(ctx context.Context, viewerID, targetID uuid.UUID) (bool, error) {
+	for _, pair := range [][2]uuid.UUID{{targetID, viewerID}, {viewerID, targetID}} {
+		blocked, err := server.isUserBlockedCached(ctx, pair[0], pair[1])
+		if err != nil {
+			return false, err
+		}
+		if blocked {
+			return false, nil
+		}
+	}
+
+	conn, err := server.store.GetConnection(ctx, db.GetConnectionParams{
+		RequesterID: viewerID,
+		TargetID:    targetID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return conn.Status == "accepted", nil
+}
+
+// weekStart is midnight UTC on the Monday of now's week
+func weekStart(now time.Time) time.Time {
+	today := truncateDay(now)
+	return today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// summarizeActivity mirrors the streak rules in UpdateUserActivity: a streak
+// survives missed days only while the user has streak freezes left
+func summarizeActivity(user db.User, days []time.Time, now time.Time) activitySummaryResponse {
+	today := truncateDay(now)
+	rsp := activitySummaryResponse{
+		UserID:                 user.ID,
+		LastActiveAt:           user.LastActiveAt,
+		StreakFreezesRemaining: user.StreakFreezesRemaining,
+		ActiveDaysThisWeek:     []string{},
+	}
+
+	if user.LastActiveAt.Valid {
+		lastDay := truncateDay(user.LastActiveAt.Time)
+		rsp.CountedToday = lastDay.Equal(today)
+
+		lapsed := lastDay.Before(today.AddDate(0, 0, -1)) && user.StreakFreezesRemaining == 0
+		if !lapsed {
+			rsp.CurrentStreak = user.ActivityStreak.Int32
+		}
+	}
+	rsp.StreakAtRisk = rsp.CurrentStreak > 0 && !rsp.CountedToday
+
+	start := weekStart(now)
+	for _, day := range days {
+		if day.Before(start) {
+			continue
+		}
+		rsp.ActiveDaysThisWeek = append(rsp.ActiveDaysThisWeek, day.UTC().Format("2006-01-02"))
+	}
+	rsp.DaysActiveThisWeek = len(rsp.ActiveDaysThisWeek)
+	return rsp
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/util"
+)
+
+func TestSummarizeActivity(t *testing.T) {
+	// A Thursday
+	now := time.Date(2026, 3, 5, 15, 0, 0, 0, time.UTC)
+	monday := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, monday, weekStart(now))
+
+	days := []time.Time{monday, monday.AddDate(0, 0, 2)}
+
+	testCases := []struct {
+		name       string
+		lastActive util.NullTime
+		freezes    int32
+		streak     int32
+		counted    bool
+		atRisk     bool
+	}{
+		{name: "ActiveToday", lastActive: util.ValidTime(now.Add(-time.Hour)), streak: 5, counted: true},
+		{name: "ActiveYesterday", lastActive: util.ValidTime(now.AddDate(0, 0, -1)), streak: 5, atRisk: true},
+		{name: "Lapsed", lastActive: util.ValidTime(now.AddDate(0, 0, -3)), streak: 0},
+		{name: "KeptByFreeze", lastActive: util.ValidTime(now.AddDate(0, 0, -3)), freezes: 1, streak: 5, atRisk: true},
+		{name: "NeverActive", streak: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := db.User{
+				LastActiveAt:           tc.lastActive,
+				ActivityStreak:         sql.NullInt32{Int32: 5, Valid: true},
+				StreakFreezesRemaining: tc.freezes,
+			}
+
+			rsp := summarizeActivity(user, days, now)
+			require.Equal(t, tc.streak, rsp.CurrentStreak)
+			require.Equal(t, tc.counted, rsp.CountedToday)
+			require.Equal(t, tc.atRisk, rsp.StreakAtRisk)
+			require.Equal(t, 2, rsp.DaysActiveThisWeek)
+			require.Equal(t, []string{"2026-03-02", "2026-03-04"}, rsp.ActiveDaysThisWeek)
+		})
+	}
+}
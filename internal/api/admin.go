@@ -1,21 +1,56 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"privacy-social-backend/internal/repository/db"
 	"privacy-social-backend/internal/service/admin"
+	"privacy-social-backend/internal/util"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 )
 
 const (
 	adminStatsCacheTTL = 1 * time.Minute
 )
 
+// SeedAdmin promotes ADMIN_SEED_USER_ID to admin. There is no endpoint that
+// grants the role, so this is how a deployment gets its first admin. It does
+// nothing when the setting is empty.
+func (server *Server) SeedAdmin(ctx context.Context) error {
+	if server.config.AdminSeedUserID == "" {
+		return nil
+	}
+	userID, err := uuid.Parse(server.config.AdminSeedUserID)
+	if err != nil {
+		return fmt.Errorf("invalid ADMIN_SEED_USER_ID: %w", err)
+	}
+
+	promoted, err := server.store.PromoteUserToAdmin(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if promoted == 0 {
+		log.Warn().Str("user_id", userID.String()).Msg("ADMIN_SEED_USER_ID does not match any user")
+		return nil
+	}
+	log.Info().Str("user_id", userID.String()).Msg("seed admin promoted")
+	return nil
+}
+
 // Admin: List Users
 type listUsersRequest struct {
 	PageID   int32 `form:"page" binding:"required,min=1"`
 	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+	// Query matches part of a username, email or phone
+	Query  string `form:"query" binding:"max=100"`
+	Status string `form:"status" binding:"omitempty,oneof=all banned active"`
 }
 
 func (server *Server) listUsers(ctx *gin.Context) {
@@ -28,23 +63,51 @@ func (server *Server) listUsers(ctx *gin.Context) {
 	users, count, err := server.admin.ListUsers(ctx, admin.ListUsersParams{
 		PageID:   req.PageID,
 		PageSize: req.PageSize,
+		Query:    strings.TrimSpace(req.Query),
+		Status:   req.Status,
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"users": users,
-		"total": count,
-		"page":  req.PageID,
-	})
+	response := make([]adminUserResponse, len(users))
+	for i, user := range users {
+		response[i] = newAdminUserResponse(user)
+	}
+
+	ctx.JSON(http.StatusOK, newPaginatedResponse(response, req.PageID, req.PageSize, count))
 }
 
-// Admin: Ban/Unban User
+// adminUserResponse is a user as admins see it, with their ban in plain fields.
+// A suspension that has run out is shown as lifted even before the cleanup
+// worker clears it.
+type adminUserResponse struct {
+	db.User
+	BanType      *string    `json:"ban_type"`
+	BanReason    *string    `json:"ban_reason"`
+	BanExpiresAt *time.Time `json:"ban_expires_at"`
+}
+
+func newAdminUserResponse(user db.User) adminUserResponse {
+	response := adminUserResponse{User: user}
+	expired := user.BanType.String == admin.BanTypeTemp && !user.BanExpiresAt.Time.After(util.Now())
+	if user.BanType.Valid && !expired {
+		response.BanType = &user.BanType.String
+		response.BanReason = nullStringToStrPtr(user.BanReason)
+		response.BanExpiresAt = user.BanExpiresAt.Ptr()
+	}
+	return response
+}
+
+// Admin: Ban/Unban User. Type is shadow (the default), hard or temp; temporary
+// bans need expires_at.
 type banUserRequest struct {
-	UserID string `json:"user_id" binding:"required,uuid"`
-	Ban    bool   `json:"ban" binding:"required"`
+	UserID    string     `json:"user_id" binding:"required,uuid"`
+	Ban       *bool      `json:"ban" binding:"required"`
+	Type      string     `json:"type" binding:"omitempty,oneof=shadow hard temp"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Reason    string     `json:"reason" binding:"max=500"`
 }
 
 func (server *Server) banUser(ctx *gin.Context) {
@@ -54,33 +117,85 @@ func (server *Server) banUser(ctx *gin.Context) {
 		return
 	}
 
-	_, ok := parseUUIDParam(ctx, req.UserID, "user_id")
+	userID, ok := parseUUIDParam(ctx, req.UserID, "user_id")
 	if !ok {
 		return
 	}
 
-	// Wait, req.UserID is string in my param struct but the parser helper returns uuid.
-	// Actually banUserRequest defined UserID as string.
-	// Service expects BanUserParams with string UserID to be parsed inside or UUID?
-	// AdminService.BanUser takes params.UserID as string and parses it.
-	// So I can pass req.UserID directly if I want, or pass the UUID.
-	// Let's check AdminService signature again.
-	// func (s *ServiceImpl) BanUser(ctx context.Context, params BanUserParams) (db.User, error)
-	// type BanUserParams struct { UserID string; Ban bool }
-
-	// So I should pass string. parseUUIDParam is doing validation which is good.
-	// I will keep validation but just pass the string to service.
+	action := auditActionUserUnban
+	if *req.Ban {
+		action = auditActionUserBan
+	}
+	setAdminAudit(ctx, adminAuditDetails{
+		Action:       action,
+		TargetID:     userID,
+		TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Reason:       req.Reason,
+	})
 
 	user, err := server.admin.BanUser(ctx, admin.BanUserParams{
-		UserID: req.UserID,
-		Ban:    req.Ban,
+		UserID:    req.UserID,
+		Ban:       *req.Ban,
+		Type:      req.Type,
+		Reason:    req.Reason,
+		ExpiresAt: req.ExpiresAt,
 	})
 	if err != nil {
+		if errors.Is(err, admin.ErrBanExpiryRequired) || errors.Is(err, admin.ErrBanExpiryNotAllowed) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The lockout cache is what keeps a banned user out, so the ban hasn't
+	// taken effect until it is written
+	if err := server.cacheLockout(ctx, user); err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, user)
+	ctx.JSON(http.StatusOK, newAdminUserResponse(user))
+}
+
+// Admin: Exempt a user the safety checks keep flagging wrongly, or end the exemption
+type setSafetyExemptionRequest struct {
+	Exempt *bool  `json:"exempt" binding:"required"`
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+func (server *Server) setSafetyExemption(ctx *gin.Context) {
+	userID, ok := parseUUIDParam(ctx, ctx.Param("id"), "id")
+	if !ok {
+		return
+	}
+	var req setSafetyExemptionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	action := auditActionSafetyUnexempt
+	if *req.Exempt {
+		action = auditActionSafetyExempt
+	}
+	setAdminAudit(ctx, adminAuditDetails{
+		Action:       action,
+		TargetID:     userID,
+		TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Reason:       req.Reason,
+	})
+
+	if err := server.safety.SetExempt(ctx, userID.String(), *req.Exempt); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"user_id": userID,
+		"exempt":  *req.Exempt,
+	})
 }
 
 // Admin: Delete User
@@ -94,6 +209,18 @@ func (server *Server) deleteUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	reason, ok := bindAdminReason(ctx)
+	if !ok {
+		return
+	}
+
+	userID := uuid.MustParse(req.UserID)
+	setAdminAudit(ctx, adminAuditDetails{
+		Action:       auditActionUserDelete,
+		TargetID:     userID,
+		TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Reason:       reason,
+	})
 
 	err := server.admin.DeleteUser(ctx, req.UserID)
 	if err != nil {
@@ -134,13 +261,13 @@ func (server *Server) listReports(ctx *gin.Context) {
 		return
 	}
 
-	reports, err := server.admin.ListReports(ctx, req.Resolved, req.PageID, req.PageSize)
+	reports, count, err := server.admin.ListReports(ctx, req.Resolved, req.PageID, req.PageSize)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, reports)
+	ctx.JSON(http.StatusOK, newPaginatedResponse(reports, req.PageID, req.PageSize, count))
 }
 
 // Admin: Resolve Report
@@ -155,6 +282,11 @@ func (server *Server) resolveReport(ctx *gin.Context) {
 		return
 	}
 
+	setAdminAudit(ctx, adminAuditDetails{
+		Action:   auditActionReportResolve,
+		TargetID: uuid.MustParse(req.ReportID),
+	})
+
 	report, err := server.admin.ResolveReport(ctx, req.ReportID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -175,6 +307,16 @@ func (server *Server) deleteStory(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	reason, ok := bindAdminReason(ctx)
+	if !ok {
+		return
+	}
+
+	setAdminAudit(ctx, adminAuditDetails{
+		Action:   auditActionStoryDelete,
+		TargetID: uuid.MustParse(req.StoryID),
+		Reason:   reason,
+	})
 
 	err := server.admin.DeleteStory(ctx, req.StoryID)
 	if err != nil {
@@ -198,11 +340,81 @@ func (server *Server) listAllStories(ctx *gin.Context) {
 		return
 	}
 
-	stories, err := server.admin.ListAllStories(ctx, req.PageID, req.PageSize)
+	stories, count, err := server.admin.ListAllStories(ctx, req.PageID, req.PageSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	for i := range stories {
+		stories[i].MediaUrl = server.media.Resolve(ctx, stories[i].MediaUrl)
+	}
+
+	ctx.JSON(http.StatusOK, newPaginatedResponse(stories, req.PageID, req.PageSize, count))
+}
+
+// Admin: Place or release a moderation hold on stored media
+type setMediaHoldRequest struct {
+	URL    string `json:"url" binding:"required"`
+	OnHold *bool  `json:"on_hold" binding:"required"`
+}
+
+func (server *Server) setMediaHold(ctx *gin.Context) {
+	var req setMediaHoldRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err := server.admin.SetMediaHold(ctx, req.URL, *req.OnHold)
+	if err != nil {
+		if errors.Is(err, admin.ErrMediaNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"url": req.URL, "on_hold": *req.OnHold})
+}
+
+// Admin: List stories held for moderation
+func (server *Server) listHeldStories(ctx *gin.Context) {
+	var req listAllStoriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	stories, err := server.admin.ListHeldStories(ctx, req.PageID, req.PageSize)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
+	for i := range stories {
+		stories[i].MediaUrl = server.media.Resolve(ctx, stories[i].MediaUrl)
+	}
 
 	ctx.JSON(http.StatusOK, stories)
 }
+
+// Admin: Release a held story into feeds
+func (server *Server) releaseStoryHold(ctx *gin.Context) {
+	var req deleteStoryRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err := server.admin.ReleaseStoryHold(ctx, req.StoryID)
+	if err != nil {
+		if errors.Is(err, admin.ErrStoryNotHeld) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "story released"})
+}
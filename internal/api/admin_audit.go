@@ -0,0 +1,162 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/repository/db"
+)
+
+// Audit log actions for admin moderation. Requests without a more specific
+// action are recorded as auditActionAdminRequest.
+const (
+	auditActionAdminRequest   = "admin.request"
+	auditActionUserBan        = "user.ban"
+	auditActionUserUnban      = "user.unban"
+	auditActionUserDelete     = "user.delete"
+	auditActionStoryDelete    = "story.delete"
+	auditActionReportResolve  = "report.resolve"
+	auditActionSafetyExempt   = "safety.exempt"
+	auditActionSafetyUnexempt = "safety.unexempt"
+
+	// adminAuditKey holds the adminAuditDetails a handler attaches to its request
+	adminAuditKey = "admin_audit"
+)
+
+// adminAuditDetails says what an admin request did, for its audit log entry
+type adminAuditDetails struct {
+	Action       string
+	TargetID     uuid.UUID
+	TargetUserID uuid.NullUUID
+	Reason       string
+}
+
+// setAdminAudit describes the action an admin handler is taking. The entry is
+// written by adminAuditMiddleware once the handler has answered, with its status.
+func setAdminAudit(ctx *gin.Context, details adminAuditDetails) {
+	ctx.Set(adminAuditKey, details)
+}
+
+// adminAuditMiddleware records every admin request that changes something,
+// with the acting admin's id and the outcome, once the handler has answered.
+// Reads are not recorded.
+func adminAuditMiddleware(server *Server) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		method := ctx.Request.Method
+		if method == http.MethodGet || method == http.MethodHead {
+			return
+		}
+		authPayload := getAuthPayload(ctx)
+
+		details := adminAuditDetails{Action: auditActionAdminRequest}
+		if set, ok := ctx.Get(adminAuditKey); ok {
+			details = set.(adminAuditDetails)
+		} else if strings.HasPrefix(ctx.FullPath(), "/admin/users/:id") {
+			// Routes under /admin/users/:id act on that user
+			if id, err := uuid.Parse(ctx.Param("id")); err == nil {
+				details.TargetID = id
+				details.TargetUserID = uuid.NullUUID{UUID: id, Valid: true}
+			}
+		}
+
+		log.Info().
+			Str("admin_id", authPayload.UserID.String()).
+			Str("action", details.Action).
+			Str("method", method).
+			Str("path", ctx.Request.URL.RequestURI()).
+			Int("status", ctx.Writer.Status()).
+			Msg("admin action")
+		server.recordAdminAudit(db.CreateAdminAuditLogParams{
+			AdminID:      authPayload.UserID,
+			TargetUserID: details.TargetUserID,
+			TargetID:     uuid.NullUUID{UUID: details.TargetID, Valid: details.TargetID != uuid.Nil},
+			Action:       details.Action,
+			Reason:       toNullString(details.Reason),
+			Method:       method,
+			Path:         ctx.Request.URL.RequestURI(),
+			StatusCode:   int32(ctx.Writer.Status()),
+		})
+	}
+}
+
+// adminReasonRequest is the optional body of admin deletes, explaining them
+// in the audit log
+type adminReasonRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// bindAdminReason reads the optional reason of a request without a required
+// body, writing the error response if the body is malformed
+func bindAdminReason(ctx *gin.Context) (string, bool) {
+	var req adminReasonRequest
+	if ctx.Request.ContentLength == 0 {
+		return "", true
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return "", false
+	}
+	return req.Reason, true
+}
+
+// auditLogEntryResponse is one admin audit log entry
+type auditLogEntryResponse struct {
+	ID           uuid.UUID     `json:"id"`
+	AdminID      uuid.UUID     `json:"admin_id"`
+	Action       string        `json:"action"`
+	TargetID     uuid.NullUUID `json:"target_id"`
+	TargetUserID uuid.NullUUID `json:"target_user_id"`
+	Reason       *string       `json:"reason"`
+	Method       string        `json:"method"`
+	Path         string        `json:"path"`
+	StatusCode   int32         `json:"status_code"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// Admin: List the audit log, newest first
+type listAuditLogRequest struct {
+	PageID   int32 `form:"page" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+func (server *Server) listAuditLog(ctx *gin.Context) {
+	var req listAuditLogRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	entries, count, err := server.admin.ListAuditLog(ctx, req.PageID, req.PageSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	response := make([]auditLogEntryResponse, len(entries))
+	for i, entry := range entries {
+		response[i] = auditLogEntryResponse{
+			ID:           entry.ID,
+			AdminID:      entry.AdminID,
+			Action:       entry.Action,
+			TargetID:     entry.TargetID,
+			TargetUserID: entry.TargetUserID,
+			Reason:       nullStringToStrPtr(entry.Reason),
+			Method:       entry.Method,
+			Path:         entry.Path,
+			StatusCode:   entry.StatusCode,
+			CreatedAt:    entry.CreatedAt,
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"entries": response,
+		"total":   count,
+		"page":    req.PageID,
+	})
+}
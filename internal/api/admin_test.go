@@ -0,0 +1,422 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestAdminAuditMiddleware(t *testing.T) {
+	callerID, targetID := uuid.New(), uuid.New()
+	target := uuid.NullUUID{UUID: targetID, Valid: true}
+	asAdmin := func(store *mockdb.MockStore) {
+		store.EXPECT().GetUserByID(gomock.Any(), callerID).Times(1).Return(db.User{ID: callerID, Role: db.UserRoleAdmin}, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		body          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "DeleteUserWithReason",
+			method: http.MethodDelete,
+			path:   fmt.Sprintf("/admin/users/%s", targetID),
+			body:   `{"reason":"spam account"}`,
+			buildStubs: func(store *mockdb.MockStore) {
+				asAdmin(store)
+				store.EXPECT().DeleteUser(gomock.Any(), targetID).Times(1).Return(nil)
+				store.EXPECT().
+					CreateAdminAuditLog(gomock.Any(), db.CreateAdminAuditLogParams{
+						AdminID:      callerID,
+						TargetUserID: target,
+						TargetID:     target,
+						Action:       auditActionUserDelete,
+						Reason:       sql.NullString{String: "spam account", Valid: true},
+						Method:       http.MethodDelete,
+						Path:         fmt.Sprintf("/admin/users/%s", targetID),
+						StatusCode:   http.StatusOK,
+					}).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			// ban is a required boolean, so false must still bind
+			name:   "Unban",
+			method: http.MethodPost,
+			path:   "/admin/users/ban",
+			body:   fmt.Sprintf(`{"user_id":%q,"ban":false}`, targetID),
+			buildStubs: func(store *mockdb.MockStore) {
+				asAdmin(store)
+				store.EXPECT().
+					BanUser(gomock.Any(), db.BanUserParams{ID: targetID, IsShadowBanned: false}).
+					Times(1).
+					Return(db.User{ID: targetID}, nil)
+				store.EXPECT().
+					CreateAdminAuditLog(gomock.Any(), db.CreateAdminAuditLogParams{
+						AdminID:      callerID,
+						TargetUserID: target,
+						TargetID:     target,
+						Action:       auditActionUserUnban,
+						Method:       http.MethodPost,
+						Path:         "/admin/users/ban",
+						StatusCode:   http.StatusOK,
+					}).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+			},
+		},
+		{
+			name:   "DeleteStory",
+			method: http.MethodDelete,
+			path:   fmt.Sprintf("/admin/stories/%s", targetID),
+			buildStubs: func(store *mockdb.MockStore) {
+				asAdmin(store)
+				store.EXPECT().DeleteStory(gomock.Any(), targetID).Times(1).Return(nil)
+				store.EXPECT().
+					CreateAdminAuditLog(gomock.Any(), db.CreateAdminAuditLogParams{
+						AdminID:    callerID,
+						TargetID:   target,
+						Action:     auditActionStoryDelete,
+						Method:     http.MethodDelete,
+						Path:       fmt.Sprintf("/admin/stories/%s", targetID),
+						StatusCode: http.StatusOK,
+					}).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "ReadsNotRecorded",
+			method: http.MethodGet,
+			path:   "/admin/audit?page=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				asAdmin(store)
+				store.EXPECT().
+					ListAdminAuditLog(gomock.Any(), db.ListAdminAuditLogParams{Limit: 5, Offset: 0}).
+					Times(1).
+					Return([]db.AdminAuditLog{{
+						AdminID:  callerID,
+						Action:   auditActionUserBan,
+						TargetID: target,
+						Reason:   sql.NullString{String: "harassment", Valid: true},
+					}}, nil)
+				store.EXPECT().CountAdminAuditLog(gomock.Any()).Times(1).Return(int64(1), nil)
+				store.EXPECT().CreateAdminAuditLog(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got struct {
+					Entries []auditLogEntryResponse `json:"entries"`
+					Total   int64                   `json:"total"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, int64(1), got.Total)
+				require.Len(t, got.Entries, 1)
+				require.Equal(t, "harassment", *got.Entries[0].Reason)
+			},
+		},
+		{
+			name:   "NonAdminRejected",
+			method: http.MethodDelete,
+			path:   fmt.Sprintf("/admin/users/%s", targetID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), callerID).Times(1).Return(db.User{ID: callerID, Role: db.UserRoleUser}, nil)
+				store.EXPECT().DeleteUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateAdminAuditLog(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			request, err := http.NewRequest(tc.method, tc.path, body)
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("caller", callerID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestSetSafetyExemption(t *testing.T) {
+	callerID, targetID := uuid.New(), uuid.New()
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUserByID(gomock.Any(), callerID).Times(2).Return(db.User{ID: callerID, Role: db.UserRoleAdmin}, nil)
+	for _, action := range []string{auditActionSafetyExempt, auditActionSafetyUnexempt} {
+		store.EXPECT().
+			CreateAdminAuditLog(gomock.Any(), gomock.Any()).
+			Times(1).
+			Do(func(_ context.Context, arg db.CreateAdminAuditLogParams) {
+				require.Equal(t, action, arg.Action)
+				require.Equal(t, targetID, arg.TargetUserID.UUID)
+			}).
+			Return(nil)
+	}
+
+	server := newTestServer(t, store)
+	accessToken, _, err := server.tokenMaker.CreateToken("caller", callerID, time.Minute)
+	require.NoError(t, err)
+	setExempt := func(exempt bool) {
+		body := fmt.Sprintf(`{"exempt":%t,"reason":"frequent flyer"}`, exempt)
+		request, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/admin/users/%s/safety-exemption", targetID), strings.NewReader(body))
+		require.NoError(t, err)
+		request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+
+	setExempt(true)
+	require.True(t, server.safety.IsExempt(context.Background(), targetID.String()))
+	setExempt(false)
+	require.False(t, server.safety.IsExempt(context.Background(), targetID.String()))
+}
+
+func TestSeedAdmin(t *testing.T) {
+	userID := uuid.New()
+
+	testCases := []struct {
+		name       string
+		seedUserID string
+		buildStubs func(store *mockdb.MockStore)
+		wantErr    bool
+	}{
+		{
+			name: "Unset",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().PromoteUserToAdmin(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:       "Promoted",
+			seedUserID: userID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().PromoteUserToAdmin(gomock.Any(), userID).Times(1).Return(int64(1), nil)
+			},
+		},
+		{
+			// A seed id left over from another database shouldn't stop the server
+			name:       "NoSuchUser",
+			seedUserID: userID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().PromoteUserToAdmin(gomock.Any(), userID).Times(1).Return(int64(0), nil)
+			},
+		},
+		{
+			name:       "InvalidID",
+			seedUserID: "not-a-uuid",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().PromoteUserToAdmin(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.AdminSeedUserID = tc.seedUserID
+
+			err := server.SeedAdmin(t.Context())
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestListReportsPagination(t *testing.T) {
+	callerID := uuid.New()
+
+	testCases := []struct {
+		name       string
+		query      string
+		buildStubs func(store *mockdb.MockStore)
+		want       string
+	}{
+		{
+			name:  "PartialLastPage",
+			query: "?page=2&page_size=5&resolved=true",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListReports(gomock.Any(), db.ListReportsParams{IsResolved: true, Limit: 5, Offset: 5}).
+					Times(1).
+					Return([]db.ListReportsRow{{Reason: db.ReportReasonSpam}}, nil)
+				store.EXPECT().CountReports(gomock.Any(), true).Times(1).Return(int64(11), nil)
+			},
+			want: `{"page":2,"page_size":5,"total":11,"total_pages":3}`,
+		},
+		{
+			name:  "Empty",
+			query: "?page=1&page_size=10",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListReports(gomock.Any(), gomock.Any()).Times(1).Return(nil, nil)
+				store.EXPECT().CountReports(gomock.Any(), false).Times(1).Return(int64(0), nil)
+			},
+			want: `{"page":1,"page_size":10,"total":0,"total_pages":0}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUserByID(gomock.Any(), callerID).AnyTimes().Return(db.User{ID: callerID, Role: db.UserRoleAdmin}, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			accessToken, _, err := server.tokenMaker.CreateToken("caller", callerID, time.Minute)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodGet, "/admin/reports"+tc.query, nil)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+			var rsp map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			data := rsp["data"]
+			delete(rsp, "data")
+			meta, err := json.Marshal(rsp)
+			require.NoError(t, err)
+			require.JSONEq(t, tc.want, string(meta))
+			require.True(t, strings.HasPrefix(string(data), "["), "data must be an array: %s", data)
+		})
+	}
+}
+
+func TestListUsersFilters(t *testing.T) {
+	callerID := uuid.New()
+
+	testCases := []struct {
+		name       string
+		query      string
+		buildStubs func(store *mockdb.MockStore)
+		wantCode   int
+	}{
+		{
+			name:  "QueryAndStatus",
+			query: "?page=2&page_size=20&query=%20alice%20&status=banned",
+			buildStubs: func(store *mockdb.MockStore) {
+				filter := sql.NullString{String: "alice", Valid: true}
+				store.EXPECT().
+					SearchAdminUsers(gomock.Any(), db.SearchAdminUsersParams{Query: filter, Status: "banned", Limit: 20, Offset: 20}).
+					Times(1).
+					Return([]db.User{{ID: uuid.New()}}, nil)
+				store.EXPECT().
+					CountAdminUsers(gomock.Any(), db.CountAdminUsersParams{Query: filter, Status: "banned"}).
+					Times(1).
+					Return(int64(21), nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			// No filters lists everyone
+			name:  "Defaults",
+			query: "?page=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SearchAdminUsers(gomock.Any(), db.SearchAdminUsersParams{Status: "all", Limit: 5}).
+					Times(1).
+					Return(nil, nil)
+				store.EXPECT().
+					CountAdminUsers(gomock.Any(), db.CountAdminUsersParams{Status: "all"}).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:  "InvalidStatus",
+			query: "?page=1&page_size=5&status=deleted",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SearchAdminUsers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:  "PageSizeTooLarge",
+			query: "?page=1&page_size=101",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SearchAdminUsers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUserByID(gomock.Any(), callerID).AnyTimes().Return(db.User{ID: callerID, Role: db.UserRoleAdmin}, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			accessToken, _, err := server.tokenMaker.CreateToken("caller", callerID, time.Minute)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodGet, "/admin/users"+tc.query, nil)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantCode, recorder.Code, recorder.Body.String())
+		})
+	}
+}
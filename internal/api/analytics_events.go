@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/service/analytics"
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// analyticsIngestTick is how often emitted events are moved into the event store
+	analyticsIngestTick = 1 * time.Minute
+	// analyticsPruneTick is how often raw events past their retention are deleted
+	analyticsPruneTick = 1 * time.Hour
+)
+
+// StartAnalyticsIngest moves the events handlers emit from the Redis stream into
+// the analytics tables the admin dashboard reads. Every instance can run it: the
+// stream's consumer group hands each entry to one of them.
+func (server *Server) StartAnalyticsIngest() {
+	consumer, err := os.Hostname()
+	if err != nil || consumer == "" {
+		consumer = "api"
+	}
+
+	ticker := time.NewTicker(analyticsIngestTick)
+	go func() {
+		var lastPrune time.Time
+		for {
+			<-ticker.C
+			ctx, cancel := context.WithTimeout(context.Background(), analyticsIngestTick)
+			if ingested, err := analytics.Ingest(ctx, server.store, server.redis, consumer); err != nil {
+				log.Error().Err(err).Int("ingested", ingested).Msg("failed to ingest analytics events")
+			}
+			if now := util.Now(); now.Sub(lastPrune) >= analyticsPruneTick {
+				if _, err := analytics.Prune(ctx, server.store, now); err != nil {
+					log.Error().Err(err).Msg("failed to prune analytics events")
+				} else {
+					lastPrune = now
+				}
+			}
+			cancel()
+		}
+	}()
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/analytics"
 	"privacy-social-backend/internal/util"
 
 	"github.com/gin-gonic/gin"
@@ -23,6 +25,17 @@ type googleUser struct {
 	EmailVerified bool   `json:"email_verified"`
 	Name          string `json:"name"`
 	Picture       string `json:"picture"`
+	// IssuedAt is when the token was issued, in Unix seconds
+	IssuedAt json.Number `json:"iat"`
+}
+
+// issuedWithin reports whether the token was issued no more than maxAge ago
+func (u *googleUser) issuedWithin(maxAge time.Duration) bool {
+	iat, err := u.IssuedAt.Int64()
+	if err != nil {
+		return false
+	}
+	return util.Now().Sub(time.Unix(iat, 0)) <= maxAge
 }
 
 func (server *Server) googleLogin(ctx *gin.Context) {
@@ -45,7 +58,7 @@ func (server *Server) googleLogin(ctx *gin.Context) {
 		}
 	} else if req.IDToken != "" {
 		// Verify existing ID Token
-		gUser, err = verifyGoogleToken(req.IDToken)
+		gUser, err = server.verifyGoogleIDToken(req.IDToken)
 		if err != nil {
 			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
 			return
@@ -86,6 +99,7 @@ func (server *Server) googleLogin(ctx *gin.Context) {
 						ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 						return
 					}
+					server.events.Emit(ctx, analytics.EventSignup, user.ID)
 
 					// Update with email and google_id
 					// I need a transaction or separate updates.
@@ -112,42 +126,14 @@ func (server *Server) googleLogin(ctx *gin.Context) {
 		}
 	}
 
-	// 5. Generate Tokens (Same as loginUser)
-	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.ID, server.config.AccessTokenDuration)
+	// 5. Start a session (Same as loginUser)
+	session, err := server.user.StartSession(ctx, user, sessionDevice(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, user.ID, server.config.RefreshTokenDuration)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
-	}
-
-	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
-		ID:           refreshPayload.ID,
-		UserID:       user.ID,
-		RefreshToken: refreshToken,
-		UserAgent:    ctx.Request.UserAgent(),
-		ClientIp:     ctx.ClientIP(),
-		IsBlocked:    false,
-		ExpiresAt:    refreshPayload.ExpiredAt,
-	})
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
-	}
-
-	rsp := loginUserResponse{
-		SessionID:             session.ID,
-		AccessToken:           accessToken,
-		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
-		RefreshToken:          refreshToken,
-		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
-		User:                  newUserResponse(user),
-	}
-	ctx.JSON(http.StatusOK, rsp)
+	ctx.JSON(http.StatusOK, newLoginUserResponse(session))
 }
 
 // GoogleCallback handles the redirect from Google and forwards it to Expo Go
@@ -200,7 +186,7 @@ func (server *Server) exchangeGoogleCode(code string) (*googleUser, error) {
 		return nil, err
 	}
 
-	return verifyGoogleToken(tokenResp.IDToken)
+	return server.verifyGoogleIDToken(tokenResp.IDToken)
 }
 
 func verifyGoogleToken(token string) (*googleUser, error) {
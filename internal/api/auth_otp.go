@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// otpTTL is how long a phone verification code can be used
+	otpTTL = 5 * time.Minute
+	// otpResendCooldown is the minimum time between codes sent to the same phone
+	otpResendCooldown = time.Minute
+	// otpMaxAttempts is how many guesses a code allows before it is thrown away
+	otpMaxAttempts = 3
+)
+
+var (
+	ErrOTPCooldown        = errors.New("a code was sent recently, wait before requesting another")
+	ErrOTPInvalid         = errors.New("invalid or expired code")
+	ErrOTPTooManyAttempts = errors.New("too many incorrect attempts, request a new code")
+)
+
+// otpKey holds the pending code for a phone and how many guesses it has had
+func otpKey(phone string) string {
+	return util.RedisKey("otp:" + phone)
+}
+
+func otpCooldownKey(phone string) string {
+	return util.RedisKey("otp_cooldown:" + phone)
+}
+
+// generateOTP returns a random 6-digit code
+func generateOTP() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+type requestOTPRequest struct {
+	Phone string `json:"phone" binding:"required"`
+}
+
+func (server *Server) requestOTP(ctx *gin.Context) {
+	var req requestOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// The cooldown applies whether or not the phone is registered, so it
+	// doesn't reveal which numbers have accounts
+	ok, err := server.redis.SetNX(ctx, otpCooldownKey(req.Phone), 1, otpResendCooldown).Result()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(ErrOTPCooldown))
+		return
+	}
+
+	sent := gin.H{"message": "If this phone is registered, a code has been sent.", "expires_in": int(otpTTL.Seconds())}
+
+	user, err := server.store.GetUserByPhone(ctx, req.Phone)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusOK, sent)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if user.PhoneVerified {
+		ctx.JSON(http.StatusOK, sent)
+		return
+	}
+
+	code, err := generateOTP()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// A new code replaces any earlier one and resets its attempts
+	key := otpKey(req.Phone)
+	_, err = server.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, "code", code, "attempts", 0)
+		pipe.Expire(ctx, key, otpTTL)
+		return nil
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Logged for development since there's no SMS provider
+	logger := requestLog(ctx)
+	logger.Debug().Str("phone", req.Phone).Str("code", code).Msg("phone OTP issued")
+
+	ctx.JSON(http.StatusOK, sent)
+}
+
+type verifyOTPRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code" binding:"required,len=6,numeric"`
+}
+
+func (server *Server) verifyOTP(ctx *gin.Context) {
+	var req verifyOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if err := server.checkOTP(ctx, req.Phone, req.Code); err != nil {
+		switch {
+		case errors.Is(err, ErrOTPInvalid):
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		case errors.Is(err, ErrOTPTooManyAttempts):
+			ctx.JSON(http.StatusTooManyRequests, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	userID, err := server.store.MarkPhoneVerified(ctx, req.Phone)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(ErrOTPInvalid))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.invalidateProfileCache(userID)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "phone verified"})
+}
+
+// checkOTPScript counts a guess at the code in KEYS[1] and compares it,
+// returning 1 for a match, 0 for a wrong guess, -1 once the guesses are used
+// up and -2 when there is no code. A match or the last allowed guess deletes
+// the code. Doing it all in one script means a guess racing a successful one
+// or a lockout can't bring the key back without its code or TTL.
+var checkOTPScript = redis.NewScript(`
+local stored = redis.call('HGET', KEYS[1], 'code')
+if not stored then
+  return -2
+end
+local max = tonumber(ARGV[2])
+local attempts = redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+if attempts > max then
+  redis.call('DEL', KEYS[1])
+  return -1
+end
+if stored == ARGV[1] then
+  redis.call('DEL', KEYS[1])
+  return 1
+end
+if attempts == max then
+  redis.call('DEL', KEYS[1])
+  return -1
+end
+return 0
+`)
+
+// checkOTP counts a guess against the phone's pending code and uses the code
+// up when it matches. The code is also discarded after otpMaxAttempts wrong
+// guesses.
+func (server *Server) checkOTP(ctx context.Context, phone, code string) error {
+	result, err := checkOTPScript.Run(ctx, server.redis, []string{otpKey(phone)}, code, otpMaxAttempts).Int()
+	if err != nil {
+		return err
+	}
+	switch result {
+	case 1:
+		return nil
+	case -1:
+		return ErrOTPTooManyAttempts
+	default:
+		return ErrOTPInvalid
+	}
+}
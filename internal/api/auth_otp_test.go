@@ -0,0 +1,225 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func postJSON(t *testing.T, server *Server, path string, body gin.H, userID *uuid.UUID) *httptest.ResponseRecorder {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	require.NoError(t, err)
+	if userID != nil {
+		accessToken, _, err := server.tokenMaker.CreateToken("user", *userID, time.Minute)
+		require.NoError(t, err)
+		request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestRequestOTP(t *testing.T) {
+	const phone = "+15550001111"
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUserByPhone(gomock.Any(), phone).Times(1).Return(db.User{ID: uuid.New(), Phone: phone}, nil)
+
+	server := newTestServer(t, store)
+	mr := miniredis.RunT(t)
+	server.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	recorder := postJSON(t, server, "/auth/otp/request", gin.H{"phone": phone}, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	code := mr.HGet(otpKey(phone), "code")
+	require.Len(t, code, 6)
+	require.Equal(t, otpTTL, mr.TTL(otpKey(phone)))
+
+	// Asking again within the cooldown sends nothing
+	recorder = postJSON(t, server, "/auth/otp/request", gin.H{"phone": phone}, nil)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, code, mr.HGet(otpKey(phone), "code"))
+}
+
+func TestRequestOTPUnknownPhone(t *testing.T) {
+	const phone = "+15550002222"
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUserByPhone(gomock.Any(), phone).Times(1).Return(db.User{}, sql.ErrNoRows)
+
+	server := newTestServer(t, store)
+	mr := miniredis.RunT(t)
+	server.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	recorder := postJSON(t, server, "/auth/otp/request", gin.H{"phone": phone}, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.False(t, mr.Exists(otpKey(phone)))
+}
+
+func TestVerifyOTP(t *testing.T) {
+	const phone = "+15550003333"
+	const code = "123456"
+	userID := uuid.New()
+
+	testCases := []struct {
+		name       string
+		guesses    []string
+		buildStubs func(store *mockdb.MockStore)
+		wantCodes  []int
+		wantKept   bool
+	}{
+		{
+			name:    "OK",
+			guesses: []string{code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().MarkPhoneVerified(gomock.Any(), phone).Times(1).Return(userID, nil)
+			},
+			wantCodes: []int{http.StatusOK},
+		},
+		{
+			name:    "WrongThenRight",
+			guesses: []string{"000000", code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().MarkPhoneVerified(gomock.Any(), phone).Times(1).Return(userID, nil)
+			},
+			wantCodes: []int{http.StatusBadRequest, http.StatusOK},
+		},
+		{
+			name:    "WrongOnce",
+			guesses: []string{"000000"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().MarkPhoneVerified(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantCodes: []int{http.StatusBadRequest},
+			wantKept:  true,
+		},
+		{
+			name:    "TooManyAttempts",
+			guesses: []string{"000000", "111111", "222222", code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().MarkPhoneVerified(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantCodes: []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusTooManyRequests, http.StatusBadRequest},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			mr := miniredis.RunT(t)
+			server.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			mr.HSet(otpKey(phone), "code", code, "attempts", "0")
+
+			for i, guess := range tc.guesses {
+				recorder := postJSON(t, server, "/auth/otp/verify", gin.H{"phone": phone, "code": guess}, nil)
+				require.Equal(t, tc.wantCodes[i], recorder.Code, "guess %d", i+1)
+			}
+			require.Equal(t, tc.wantKept, mr.Exists(otpKey(phone)))
+		})
+	}
+}
+
+func TestVerifyOTPAfterCodeUsed(t *testing.T) {
+	const phone = "+15550004444"
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().MarkPhoneVerified(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	mr := miniredis.RunT(t)
+	server.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	// A guess arriving after a successful verify or a lockout deleted the
+	// code must not leave an attempts-only key behind without a TTL
+	recorder := postJSON(t, server, "/auth/otp/verify", gin.H{"phone": phone, "code": "123456"}, nil)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.False(t, mr.Exists(otpKey(phone)))
+}
+
+func TestVerifyOTPKeepsTTL(t *testing.T) {
+	const phone = "+15550005555"
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	mr := miniredis.RunT(t)
+	server.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	mr.HSet(otpKey(phone), "code", "123456", "attempts", "0")
+	mr.SetTTL(otpKey(phone), otpTTL)
+
+	recorder := postJSON(t, server, "/auth/otp/verify", gin.H{"phone": phone, "code": "000000"}, nil)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Equal(t, "1", mr.HGet(otpKey(phone), "attempts"))
+	require.Equal(t, otpTTL, mr.TTL(otpKey(phone)))
+}
+
+func TestPhoneVerifiedMiddleware(t *testing.T) {
+	userID := uuid.New()
+
+	testCases := []struct {
+		name     string
+		required bool
+		user     db.User
+		wantCode int
+	}{
+		{
+			name:     "NotRequired",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Unverified",
+			required: true,
+			user:     db.User{ID: userID},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Verified",
+			required: true,
+			user:     db.User{ID: userID, PhoneVerified: true},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "GoogleAccount",
+			required: true,
+			user:     db.User{ID: userID, GoogleID: sql.NullString{String: "sub", Valid: true}},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			if tc.required {
+				store.EXPECT().GetUserByID(gomock.Any(), userID).Times(1).Return(tc.user, nil)
+			}
+
+			server := newTestServer(t, store)
+			server.config.RequirePhoneVerification = tc.required
+
+			// An empty body gets past the gate only to fail validation
+			recorder := postJSON(t, server, "/messages", gin.H{}, &userID)
+			require.Equal(t, tc.wantCode, recorder.Code)
+		})
+	}
+}
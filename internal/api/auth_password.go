@@ -2,7 +2,6 @@ package api
 
 import (
 	"database/sql"
-	"fmt"
 	"net/http"
 	"time"
 
@@ -36,20 +35,21 @@ func (server *Server) forgotPassword(ctx *gin.Context) {
 
 	// Generate Token
 	resetToken := util.RandomString(32)
-	expiresAt := time.Now().Add(15 * time.Minute)
+	expiresAt := util.Now().Add(15 * time.Minute)
 
 	_, err = server.store.SetPasswordResetToken(ctx, db.SetPasswordResetTokenParams{
 		Email:                  sql.NullString{String: req.Email, Valid: true},
 		PasswordResetToken:     sql.NullString{String: resetToken, Valid: true},
-		PasswordResetExpiresAt: sql.NullTime{Time: expiresAt, Valid: true},
+		PasswordResetExpiresAt: util.NullTime{Time: expiresAt, Valid: true},
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	// LOG TOKEN (For development since no SMTP)
-	fmt.Printf("------------\n[PASSWORD RESET]\nUser: %s\nToken: %s\n------------\n", req.Email, resetToken)
+	// Logged for development since there's no SMTP
+	logger := requestLog(ctx)
+	logger.Debug().Str("email", req.Email).Str("token", resetToken).Msg("password reset issued")
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "If this email exists, a reset link has been sent."})
 }
@@ -6,13 +6,18 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"privacy-social-backend/internal/service/story"
+	"privacy-social-backend/internal/service/user"
+	"privacy-social-backend/internal/util"
 )
 
 // conversationCacheKey generates a consistent cache key for a conversation between two users
 func conversationCacheKey(userID1, userID2 uuid.UUID) string {
 	ids := []string{userID1.String(), userID2.String()}
 	sort.Strings(ids)
-	return "messages:" + ids[0] + ":" + ids[1]
+	return util.RedisKey("messages:" + ids[0] + ":" + ids[1])
 }
 
 // invalidateConversationCache removes the cached conversation between two users
@@ -23,26 +28,65 @@ func (server *Server) invalidateConversationCache(userID1, userID2 uuid.UUID) {
 
 // invalidateProfileCache removes the cached profile for a user
 func (server *Server) invalidateProfileCache(userID uuid.UUID) {
-	cacheKey := "profile:" + userID.String()
+	cacheKey := util.RedisKey("profile:" + userID.String())
 	server.redis.Del(context.Background(), cacheKey)
 }
 
-// invalidateFeedCache removes the cached feed for a geohash
+// invalidateFeedCache expires every user's cached feed for a geohash
 func (server *Server) invalidateFeedCache(geohash string) {
-	cacheKey := "feed:" + geohash
-	server.redis.Del(context.Background(), cacheKey)
+	story.InvalidateFeedCell(context.Background(), server.redis, geohash)
+}
+
+// invalidateUserFeedCache expires a user's cached feeds in every cell
+func (server *Server) invalidateUserFeedCache(userID uuid.UUID) {
+	story.InvalidateUserFeeds(context.Background(), server.redis, userID)
+}
+
+// unreadCountTTL is how long a cached unread count, kept current by sends and
+// reads, is trusted before it's recounted from the database. The recount also
+// catches messages that expired unread.
+const unreadCountTTL = 5 * time.Minute
+
+// adjustUnreadCountScript adds ARGV[1] to a cached unread count. A count that
+// isn't cached stays that way for the next read to recount, and one that would
+// go negative has drifted, so it's dropped to be recounted too.
+var adjustUnreadCountScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+  return nil
+end
+local count = redis.call('INCRBY', KEYS[1], ARGV[1])
+if count < 0 then
+  redis.call('DEL', KEYS[1])
+end
+return count
+`)
+
+func unreadCountCacheKey(userID uuid.UUID) string {
+	return util.RedisKey("unread_count:" + userID.String())
 }
 
 // invalidateUnreadCountCache removes the cached unread count for a user
 func (server *Server) invalidateUnreadCountCache(userID uuid.UUID) {
-	unreadKey := "unread_count:" + userID.String()
-	server.redis.Del(context.Background(), unreadKey)
+	server.redis.Del(context.Background(), unreadCountCacheKey(userID))
 }
 
-// incrementUnreadCount increments the unread message count for a user
+// incrementUnreadCount counts a newly delivered message toward a user's badge
 func (server *Server) incrementUnreadCount(userID uuid.UUID) {
-	unreadKey := "unread_count:" + userID.String()
-	server.redis.Incr(context.Background(), unreadKey)
+	server.adjustUnreadCount(userID, 1)
+}
+
+// decrementUnreadCount takes n messages the user just read off their badge
+func (server *Server) decrementUnreadCount(userID uuid.UUID, n int) {
+	server.adjustUnreadCount(userID, -int64(n))
+}
+
+func (server *Server) adjustUnreadCount(userID uuid.UUID, delta int64) {
+	key := unreadCountCacheKey(userID)
+	err := adjustUnreadCountScript.Run(context.Background(), server.redis, []string{key}, delta).Err()
+	if err != nil && err != redis.Nil {
+		// A count that missed an update would be wrong until it expires
+		server.redis.Del(context.Background(), key)
+	}
 }
 
 // setCache stores data in Redis with the given key and TTL
@@ -57,6 +101,48 @@ func (server *Server) getCache(key string) (string, error) {
 
 // invalidateCrossingsCache removes the cached crossings for a user
 func (server *Server) invalidateCrossingsCache(userID uuid.UUID) {
-	cacheKey := "crossings:v3:" + userID.String()
+	cacheKey := util.RedisKey("crossings:v3:" + userID.String())
 	server.redis.Del(context.Background(), cacheKey)
 }
+
+// invalidateRecommendationsCache removes the cached connection recommendations for a user
+func (server *Server) invalidateRecommendationsCache(userID uuid.UUID) {
+	server.redis.Del(context.Background(), recommendationsCacheKey(userID))
+}
+
+// invalidateDeletedAccountCaches drops cached data that still shows a deleted
+// account: its own caches, its conversations, its contacts' lists, feeds and
+// unread counts, and the feed cells its stories were posted in
+func (server *Server) invalidateDeletedAccountCaches(userID uuid.UUID, deleted *user.DeletedAccount) {
+	ctx := context.Background()
+	server.invalidateProfileCache(userID)
+	server.invalidatePrivacyCache(userID)
+	server.invalidateUnreadCountCache(userID)
+	server.invalidateCrossingsCache(userID)
+	server.invalidateRecommendationsCache(userID)
+	server.invalidateUserFeedCache(userID)
+
+	for _, contactID := range deleted.ContactIDs {
+		server.invalidateConversationCache(userID, contactID)
+		server.invalidateUnreadCountCache(contactID)
+		server.invalidateCrossingsCache(contactID)
+		server.invalidateRecommendationsCache(contactID)
+		server.invalidateUserFeedCache(contactID)
+		server.redis.Del(ctx,
+			util.RedisKey("connections:"+contactID.String()),
+			util.RedisKey("stories:connections:"+contactID.String()),
+			storyRingsCacheKey(contactID),
+		)
+	}
+
+	cells := make(map[string]bool)
+	for _, geohash := range deleted.StoryGeohashes {
+		if len(geohash) > 5 {
+			geohash = geohash[:5]
+		}
+		if !cells[geohash] {
+			cells[geohash] = true
+			server.invalidateFeedCache(geohash)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/realtime"
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// MessageAckType is sent by a recipient's client once it has received messages
+	MessageAckType = "message_ack"
+	// MessageDeliveredType tells the sender their messages reached the recipient
+	MessageDeliveredType = "message_delivered"
+	// MessageReadType tells the sender the recipient opened the chat with their messages
+	MessageReadType = "message_read"
+
+	maxAckedMessages = 100
+)
+
+var ErrTooManyMessageIDs = errors.New("too many message_ids")
+
+// messageAck acknowledges receipt of one or more messages
+type messageAck struct {
+	MessageIDs []uuid.UUID `json:"message_ids"`
+}
+
+func (a messageAck) Validate() error {
+	if len(a.MessageIDs) == 0 {
+		return errors.New("message_ids is required")
+	}
+	if len(a.MessageIDs) > maxAckedMessages {
+		return ErrTooManyMessageIDs
+	}
+	return nil
+}
+
+// registerChatHandlers wires the chat events that need the store
+func (server *Server) registerChatHandlers() {
+	realtime.Handle(server.hub.Dispatcher(), MessageAckType, func(c *realtime.Client, ack messageAck) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.markMessagesDelivered(ctx, c.UserID, ack.MessageIDs)
+	})
+	server.registerTypingHandlers()
+}
+
+// markMessagesDelivered records delivery of messages sent to receiverID and
+// notifies each sender once per message
+func (server *Server) markMessagesDelivered(ctx context.Context, receiverID uuid.UUID, messageIDs []uuid.UUID) error {
+	delivered, err := server.store.MarkMessagesDelivered(ctx, db.MarkMessagesDeliveredParams{
+		MessageIds: messageIDs,
+		ReceiverID: uuid.NullUUID{UUID: receiverID, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	bySender := make(map[uuid.UUID][]db.MarkMessagesDeliveredRow)
+	for _, row := range delivered {
+		bySender[row.SenderID] = append(bySender[row.SenderID], row)
+	}
+
+	for senderID, rows := range bySender {
+		ids := make([]uuid.UUID, len(rows))
+		for i, row := range rows {
+			ids[i] = row.ID
+		}
+
+		// Cached history would otherwise keep showing these as undelivered
+		server.invalidateConversationCache(receiverID, senderID)
+
+		server.sendWSNotification(senderID, MessageDeliveredType, gin.H{
+			"message_ids":  ids,
+			"receiver_id":  receiverID,
+			"delivered_at": rows[0].DeliveredAt.Time,
+		})
+	}
+	return nil
+}
+
+// messageStatusRequest lists the messages to look up, comma-separated
+type messageStatusRequest struct {
+	MessageIDs string `form:"message_ids" binding:"required"`
+}
+
+// messageStatusResponse is where one message is in delivery: neither time set
+// is one tick, delivered_at is two, and read_at is read
+type messageStatusResponse struct {
+	ID          uuid.UUID     `json:"id"`
+	DeliveredAt util.NullTime `json:"delivered_at"`
+	ReadAt      util.NullTime `json:"read_at"`
+}
+
+// getMessageStatus returns delivery and read receipts for messages the caller
+// sent or received, for clients reconciling after a reconnect. Unknown ids and
+// other people's messages are left out.
+func (server *Server) getMessageStatus(ctx *gin.Context) {
+	var req messageStatusRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	parts := strings.Split(req.MessageIDs, ",")
+	if len(parts) > maxAckedMessages {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrTooManyMessageIDs))
+		return
+	}
+	ids := make([]uuid.UUID, 0, len(parts))
+	for _, part := range parts {
+		id, err := uuid.Parse(strings.TrimSpace(part))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	authPayload := getAuthPayload(ctx)
+	rows, err := server.store.GetMessageStatus(ctx, db.GetMessageStatusParams{
+		MessageIds: ids,
+		UserID:     authPayload.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]messageStatusResponse, len(rows))
+	for i, row := range rows {
+		rsp[i] = messageStatusResponse{
+			ID:          row.ID,
+			DeliveredAt: row.DeliveredAt,
+			ReadAt:      row.ReadAt,
+		}
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/realtime"
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestMessageAckValidate(t *testing.T) {
+	require.Error(t, messageAck{}.Validate())
+	require.Error(t, messageAck{MessageIDs: make([]uuid.UUID, maxAckedMessages+1)}.Validate())
+	require.NoError(t, messageAck{MessageIDs: []uuid.UUID{uuid.New()}}.Validate())
+}
+
+func TestMarkMessagesDelivered(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	receiver := uuid.New()
+	messageIDs := []uuid.UUID{uuid.New(), uuid.New()}
+
+	// Only messages addressed to the acking user are marked
+	store.EXPECT().
+		MarkMessagesDelivered(gomock.Any(), db.MarkMessagesDeliveredParams{
+			MessageIds: messageIDs,
+			ReceiverID: uuid.NullUUID{UUID: receiver, Valid: true},
+		}).
+		Times(1).
+		Return([]db.MarkMessagesDeliveredRow{{ID: messageIDs[0], SenderID: uuid.New()}}, nil)
+
+	require.NoError(t, server.markMessagesDelivered(t.Context(), receiver, messageIDs))
+}
+
+func TestDeliverGroupMessageFansOut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	server.hub = realtime.NewHub(rdb)
+
+	sender, alice, bob := uuid.New(), uuid.New(), uuid.New()
+	groupID := uuid.New()
+
+	store.EXPECT().
+		GetGroupMembers(gomock.Any(), groupID).
+		Times(1).
+		Return([]db.GetGroupMembersRow{{UserID: sender}, {UserID: alice}, {UserID: bob}}, nil)
+	store.EXPECT().
+		ListGroupMuters(gomock.Any(), uuid.NullUUID{UUID: groupID, Valid: true}).
+		Times(1).
+		Return([]uuid.UUID{alice}, nil)
+
+	server.deliverMessage(db.Message{
+		ID:       uuid.New(),
+		SenderID: sender,
+		GroupID:  uuid.NullUUID{UUID: groupID, Valid: true},
+		Content:  "hi all",
+	}, nil)
+
+	keys := mr.Keys()
+	require.Len(t, keys, 1)
+	entries, err := rdb.XRange(context.Background(), keys[0], "-", "+").Result()
+	require.NoError(t, err)
+
+	// Every other member once, then the echo to the sender. Only alice muted the group.
+	var targets []string
+	muted := map[string]bool{}
+	for _, entry := range entries {
+		target := entry.Values["target_user_id"].(string)
+		targets = append(targets, target)
+
+		var wsMsg struct {
+			Type    string    `json:"type"`
+			GroupID uuid.UUID `json:"group_id"`
+			Muted   bool      `json:"muted"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(entry.Values["payload"].(string)), &wsMsg))
+		require.Equal(t, "new_message", wsMsg.Type)
+		require.Equal(t, groupID, wsMsg.GroupID)
+		muted[target] = wsMsg.Muted
+	}
+	require.Equal(t, []string{alice.String(), bob.String(), sender.String()}, targets)
+	require.Equal(t, map[string]bool{alice.String(): true, bob.String(): false, sender.String(): false}, muted)
+}
+
+func TestMarkConversationReadReceipts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	server.redis = rdb
+	server.hub = realtime.NewHub(rdb)
+
+	reader, sender := uuid.New(), uuid.New()
+	read := []db.MarkConversationReadRow{
+		{ID: uuid.New(), ReadAt: util.NullTime{Time: util.Now(), Valid: true}},
+		{ID: uuid.New(), ReadAt: util.NullTime{Time: util.Now(), Valid: true}},
+	}
+	store.EXPECT().
+		MarkConversationRead(gomock.Any(), db.MarkConversationReadParams{
+			ReceiverID: uuid.NullUUID{UUID: reader, Valid: true},
+			SenderID:   sender,
+		}).
+		Times(1).
+		Return(read, nil)
+	store.EXPECT().
+		IsConversationMuted(gomock.Any(), db.IsConversationMutedParams{
+			UserID:       reader,
+			TargetUserID: uuid.NullUUID{UUID: sender, Valid: true},
+		}).
+		Times(1).
+		Return(false, nil)
+	require.NoError(t, mr.Set(util.RedisKey("unread_count:"+reader.String()), "5"))
+
+	request, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/messages/read/%s", sender), nil)
+	require.NoError(t, err)
+	accessToken, _, err := server.tokenMaker.CreateToken("user", reader, time.Minute)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	// The badge drops by the messages actually read instead of being recounted
+	count, err := mr.Get(util.RedisKey("unread_count:" + reader.String()))
+	require.NoError(t, err)
+	require.Equal(t, "3", count)
+
+	entries, err := rdb.XRange(context.Background(), util.RedisKey("locolive:stream:routing"), "-", "+").Result()
+	require.NoError(t, err)
+
+	var receipt *realtime.WSMessage
+	for _, entry := range entries {
+		var msg realtime.WSMessage
+		require.NoError(t, json.Unmarshal([]byte(entry.Values["payload"].(string)), &msg))
+		if msg.Type == MessageReadType {
+			require.Equal(t, sender.String(), entry.Values["target_user_id"])
+			receipt = &msg
+		}
+	}
+	require.NotNil(t, receipt)
+	payload := receipt.Payload.(map[string]interface{})
+	require.Equal(t, []interface{}{read[0].ID.String(), read[1].ID.String()}, payload["message_ids"])
+	require.Equal(t, reader.String(), payload["reader_id"])
+}
+
+func TestAdjustUnreadCount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	mr := miniredis.RunT(t)
+	server.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	userID := uuid.New()
+	key := util.RedisKey("unread_count:" + userID.String())
+
+	// Nothing cached: left for the next read to recount
+	server.incrementUnreadCount(userID)
+	require.False(t, mr.Exists(key))
+
+	require.NoError(t, mr.Set(key, "1"))
+	mr.SetTTL(key, unreadCountTTL)
+	server.incrementUnreadCount(userID)
+	count, err := mr.Get(key)
+	require.NoError(t, err)
+	require.Equal(t, "2", count)
+	require.Equal(t, unreadCountTTL, mr.TTL(key))
+
+	// Going below zero means the count drifted: it's dropped
+	server.decrementUnreadCount(userID, 3)
+	require.False(t, mr.Exists(key))
+}
+
+func TestGetUnreadMessageCount(t *testing.T) {
+	userID := uuid.New()
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUnreadMessageCount(gomock.Any(), uuid.NullUUID{UUID: userID, Valid: true}).
+		Times(1).
+		Return(int64(4), nil)
+
+	server := newTestServer(t, store)
+	mr := miniredis.RunT(t)
+	server.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+	require.NoError(t, err)
+	get := func() *httptest.ResponseRecorder {
+		request, err := http.NewRequest(http.MethodGet, "/messages/unread-count", nil)
+		require.NoError(t, err)
+		request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		return recorder
+	}
+
+	// A miss recounts and caches for a short while; a hit has the same shape
+	miss := get()
+	require.Equal(t, "MISS", miss.Header().Get("X-Cache"))
+	require.JSONEq(t, `{"unread_count":4}`, miss.Body.String())
+	require.Equal(t, unreadCountTTL, mr.TTL(util.RedisKey("unread_count:"+userID.String())))
+
+	hit := get()
+	require.Equal(t, "HIT", hit.Header().Get("X-Cache"))
+	require.JSONEq(t, `{"unread_count":4}`, hit.Body.String())
+}
+
+func TestGetMessageStatus(t *testing.T) {
+	userID := uuid.New()
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	delivered := util.NullTime{Time: util.Now(), Valid: true}
+
+	testCases := []struct {
+		name       string
+		query      string
+		buildStubs func(store *mockdb.MockStore)
+		wantCode   int
+	}{
+		{
+			name:  "OK",
+			query: ids[0].String() + "," + ids[1].String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetMessageStatus(gomock.Any(), db.GetMessageStatusParams{MessageIds: ids, UserID: userID}).
+					Times(1).
+					Return([]db.GetMessageStatusRow{{ID: ids[0], DeliveredAt: delivered}}, nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:  "InvalidID",
+			query: "nope",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessageStatus(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:  "TooMany",
+			query: strings.Repeat(ids[0].String()+",", maxAckedMessages) + ids[0].String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessageStatus(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			request, err := http.NewRequest(http.MethodGet, "/messages/status?message_ids="+tc.query, nil)
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantCode, recorder.Code, recorder.Body.String())
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+
+			var rsp []messageStatusResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			require.Len(t, rsp, 1)
+			require.Equal(t, ids[0], rsp[0].ID)
+			require.True(t, rsp[0].DeliveredAt.Valid)
+			require.False(t, rsp[0].ReadAt.Valid)
+		})
+	}
+}
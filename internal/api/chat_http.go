@@ -4,16 +4,21 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"privacy-social-backend/internal/realtime"
 	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/analytics"
 	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 )
 
 const chatCacheTTL = 10 * time.Minute
@@ -24,10 +29,7 @@ func (server *Server) checkConnection(ctx context.Context, userID1, userID2 uuid
 	// We need to check BOTH directions: userID1 blocks userID2 OR userID2 blocks userID1.
 
 	// Check if userID2 (target) blocked userID1 (requester) - Crucial for privacy
-	isBlockedByTarget, err := server.store.IsUserBlocked(ctx, db.IsUserBlockedParams{
-		BlockerID: userID2,
-		BlockedID: userID1,
-	})
+	isBlockedByTarget, err := server.isUserBlockedCached(ctx, userID2, userID1)
 	if err != nil {
 		return err
 	}
@@ -36,10 +38,7 @@ func (server *Server) checkConnection(ctx context.Context, userID1, userID2 uuid
 	}
 
 	// Check if userID1 (requester) blocked userID2 (target) - Usually UI prevents this, but API should too
-	isBlockedByRequester, err := server.store.IsUserBlocked(ctx, db.IsUserBlockedParams{
-		BlockerID: userID1,
-		BlockedID: userID2,
-	})
+	isBlockedByRequester, err := server.isUserBlockedCached(ctx, userID1, userID2)
 	if err != nil {
 		return err
 	}
@@ -68,17 +67,11 @@ func (server *Server) checkConnection(ctx context.Context, userID1, userID2 uuid
 	// If I set "Nobody", I probably don't want to be bothered properly.
 
 	// Let's fetch settings for target
-	settings, err := server.store.GetPrivacySettings(ctx, userID2)
-	if err != nil && err != sql.ErrNoRows {
+	whoCanMessage, err := server.whoCanMessageCached(ctx, userID2)
+	if err != nil {
 		return err
 	}
 
-	// Default to connections if no settings
-	whoCanMessage := "connections"
-	if err == nil && settings.WhoCanMessage.Valid {
-		whoCanMessage = settings.WhoCanMessage.String
-	}
-
 	if whoCanMessage == "nobody" {
 		return sql.ErrNoRows // Block access
 	}
@@ -110,7 +103,7 @@ func (server *Server) getChatHistory(ctx *gin.Context) {
 	// Create cache key with sorted IDs for consistency
 	ids := []string{authPayload.UserID.String(), targetID.String()}
 	sort.Strings(ids)
-	cacheKey := "messages:" + ids[0] + ":" + ids[1]
+	cacheKey := util.RedisKey("messages:" + ids[0] + ":" + ids[1])
 
 	// Try Redis cache first
 	cachedData, err := server.redis.Get(context.Background(), cacheKey).Result()
@@ -131,18 +124,24 @@ func (server *Server) getChatHistory(ctx *gin.Context) {
 
 	// Map to response struct to ensure Reactions are valid JSON, not Base64
 	type MessageResponse struct {
-		ID         uuid.UUID       `json:"id"`
-		SenderID   uuid.UUID       `json:"sender_id"`
-		ReceiverID *uuid.UUID      `json:"receiver_id"`
-		GroupID    *uuid.UUID      `json:"group_id"`
-		Content    string          `json:"content"`
-		IsRead     bool            `json:"is_read"`
-		CreatedAt  time.Time       `json:"created_at"`
-		ReadAt     sql.NullTime    `json:"read_at"`
-		ExpiresAt  sql.NullTime    `json:"expires_at"`
-		MediaUrl   *string         `json:"media_url"`
-		MediaType  *string         `json:"media_type"`
-		Reactions  json.RawMessage `json:"reactions"`
+		ID               uuid.UUID            `json:"id"`
+		SenderID         uuid.UUID            `json:"sender_id"`
+		ReceiverID       *uuid.UUID           `json:"receiver_id"`
+		GroupID          *uuid.UUID           `json:"group_id"`
+		Content          string               `json:"content"`
+		IsRead           bool                 `json:"is_read"`
+		CreatedAt        time.Time            `json:"created_at"`
+		ReadAt           util.NullTime        `json:"read_at"`
+		ExpiresAt        util.NullTime        `json:"expires_at"`
+		DeliveredAt      util.NullTime        `json:"delivered_at"`
+		MediaUrl         *string              `json:"media_url"`
+		MediaType        *string              `json:"media_type"`
+		Attachments      []MessageAttachment  `json:"attachments"`
+		Reactions        json.RawMessage      `json:"reactions"`
+		ReplyToMessageID *uuid.UUID           `json:"reply_to_message_id"`
+		ReplyTo          *messageReplyPreview `json:"reply_to"`
+		ReplyCount       int64                `json:"reply_count"`
+		StoryID          *uuid.UUID           `json:"story_id"`
 	}
 
 	responseMsgs := make([]MessageResponse, len(msgs))
@@ -173,19 +172,41 @@ func (server *Server) getChatHistory(ctx *gin.Context) {
 			groupID = &id
 		}
 
+		var replyToID *uuid.UUID
+		if m.ReplyToMessageID.Valid {
+			id := m.ReplyToMessageID.UUID
+			replyToID = &id
+		}
+
+		var storyID *uuid.UUID
+		if m.StoryID.Valid {
+			id := m.StoryID.UUID
+			storyID = &id
+		}
+
 		responseMsgs[i] = MessageResponse{
-			ID:         m.ID,
-			SenderID:   m.SenderID,
-			ReceiverID: receiverID,
-			GroupID:    groupID,
-			Content:    m.Content,
-			IsRead:     m.IsRead,
-			CreatedAt:  m.CreatedAt,
-			ReadAt:     m.ReadAt,
-			ExpiresAt:  m.ExpiresAt,
-			MediaUrl:   nullStringToStrPtr(m.MediaUrl),
-			MediaType:  nullStringToStrPtr(m.MediaType),
-			Reactions:  reactionsJSON,
+			ID:               m.ID,
+			SenderID:         m.SenderID,
+			ReceiverID:       receiverID,
+			GroupID:          groupID,
+			Content:          m.Content,
+			IsRead:           m.IsRead,
+			CreatedAt:        m.CreatedAt,
+			ReadAt:           m.ReadAt,
+			ExpiresAt:        m.ExpiresAt,
+			DeliveredAt:      m.DeliveredAt,
+			MediaUrl:         nullStringToStrPtr(m.MediaUrl),
+			MediaType:        nullStringToStrPtr(m.MediaType),
+			Attachments:      server.attachmentsWithMedia(ctx, messageAttachmentsFromJSON(m.Attachments)),
+			Reactions:        reactionsJSON,
+			ReplyToMessageID: replyToID,
+			ReplyTo:          replyPreviewFromJSON(m.ReplyToMessageID, m.ReplyTo),
+			ReplyCount:       m.ReplyCount,
+			StoryID:          storyID,
+		}
+		if m.MediaUrl.Valid {
+			mediaURL := server.media.Resolve(ctx, m.MediaUrl.String)
+			responseMsgs[i].MediaUrl = &mediaURL
 		}
 	}
 
@@ -197,128 +218,230 @@ func (server *Server) getChatHistory(ctx *gin.Context) {
 	ctx.Data(http.StatusOK, "application/json", responseJSON)
 }
 
+// checkRecipient checks senderID may post to the user or group a message is
+// addressed to, writing the error response otherwise. It returns the
+// receiver_id and group_id of the new message and whether the handler may go on.
+func (server *Server) checkRecipient(ctx *gin.Context, senderID uuid.UUID, receiver, group *uuid.UUID) (uuid.NullUUID, uuid.NullUUID, bool) {
+	var receiverID uuid.NullUUID
+	var groupID uuid.NullUUID
+
+	// Validation: Must have either ReceiverID OR GroupID, not both (for now)
+	if receiver == nil && group == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "recipient (user or group) is required"})
+		return receiverID, groupID, false
+	}
+
+	if receiver != nil {
+		if rejectSelfTarget(ctx, senderID, *receiver, "cannot message yourself") {
+			return receiverID, groupID, false
+		}
+		receiverID = uuid.NullUUID{UUID: *receiver, Valid: true}
+		// Check for mutual connection before sending (1:1 only)
+		if err := server.checkConnection(ctx, senderID, *receiver); err != nil {
+			if err == sql.ErrNoRows {
+				ctx.JSON(http.StatusForbidden, gin.H{"error": "You must be connected to this user to send messages."})
+				return receiverID, groupID, false
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return receiverID, groupID, false
+		}
+	}
+
+	if group != nil {
+		if !server.requireGroupMember(ctx, *group, senderID) {
+			return receiverID, groupID, false
+		}
+		groupID = uuid.NullUUID{UUID: *group, Valid: true}
+	}
+	return receiverID, groupID, true
+}
+
 // REST API helper to send a message
 type sendMessageRequest struct {
-	ReceiverID       *uuid.UUID `json:"receiver_id"`
-	GroupID          *uuid.UUID `json:"group_id"`
-	Content          string     `json:"content"` // Not required if media is present
-	MediaUrl         string     `json:"media_url"`
-	MediaType        string     `json:"media_type"`
-	ExpiresInSeconds int64      `json:"expires_in_seconds"` // Optional
+	ReceiverID       *uuid.UUID          `json:"receiver_id"`
+	GroupID          *uuid.UUID          `json:"group_id"`
+	Content          string              `json:"content"`    // Not required if media is present
+	MediaUrl         string              `json:"media_url"`  // Legacy single media; use Attachments
+	MediaType        string              `json:"media_type"` // Legacy single media; use Attachments
+	Attachments      []MessageAttachment `json:"attachments"`
+	ExpiresInSeconds int64               `json:"expires_in_seconds"`  // Optional
+	ScheduledAt      *time.Time          `json:"scheduled_at"`        // Optional: send later instead of now
+	ReplyToMessageID *uuid.UUID          `json:"reply_to_message_id"` // Optional: the message this one quotes
 }
 
 func (server *Server) sendMessage(ctx *gin.Context) {
 	var req sendMessageRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("ERROR: sendMessage JSON bind failed: %v\n", err)
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
-	fmt.Printf("DEBUG: Back-end received sendMessage request: %+v\n", req)
+
+	req.Content = sanitizeMessageContent(req.Content)
+	if err := validateMessageContent(req.Content); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	attachments, err := requestAttachments(req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" && len(attachments) == 0 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(&fieldError{Field: "content", Err: ErrEmptyMessage}))
+		return
+	}
+	if server.rejectBlockedText(ctx, req.Content) {
+		return
+	}
 
 	authPayload := getAuthPayload(ctx)
 
-	// Validation: Must have either ReceiverID OR GroupID, not both (for now)
-	if req.ReceiverID == nil && req.GroupID == nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "recipient (user or group) is required"})
+	receiverID, groupID, ok := server.checkRecipient(ctx, authPayload.UserID, req.ReceiverID, req.GroupID)
+	if !ok {
 		return
 	}
 
-	var receiverID uuid.NullUUID
-	var groupID uuid.NullUUID
+	var replyToID uuid.NullUUID
+	if req.ReplyToMessageID != nil {
+		if req.ScheduledAt != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(ErrScheduledReply))
+			return
+		}
+		if !server.checkReplyTarget(ctx, *req.ReplyToMessageID, authPayload.UserID, receiverID, groupID) {
+			return
+		}
+		replyToID = uuid.NullUUID{UUID: *req.ReplyToMessageID, Valid: true}
+	}
 
-	if req.ReceiverID != nil {
-		receiverID = uuid.NullUUID{UUID: *req.ReceiverID, Valid: true}
-		// Check for mutual connection before sending (1:1 only)
-		if err := server.checkConnection(ctx, authPayload.UserID, *req.ReceiverID); err != nil {
-			if err == sql.ErrNoRows {
-				ctx.JSON(http.StatusForbidden, gin.H{"error": "You must be connected to this user to send messages."})
+	// Handle expiry - the allowed range depends on the sender's tier, and a
+	// direct chat may set its own default (including never)
+	policy := newMessageExpiryPolicy(server.config)
+	var expiry time.Duration
+	if req.ExpiresInSeconds == 0 && receiverID.Valid {
+		expiry, err = server.conversationDefaultExpiry(ctx, authPayload.UserID, receiverID.UUID, policy)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	} else {
+		premium := false
+		if req.ExpiresInSeconds > int64(policy.Max/time.Second) {
+			// Only look up the tier when the free limit isn't enough
+			isPremium, err := server.isPremiumUser(ctx, authPayload.UserID)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 				return
 			}
-			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			premium = isPremium
+		}
+		expiry, err = policy.resolve(req.ExpiresInSeconds, premium)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
 			return
 		}
 	}
-
-	if req.GroupID != nil {
-		groupID = uuid.NullUUID{UUID: *req.GroupID, Valid: true}
-		// Check membership
-		// We need CheckGroupMembership query
-		// isMember, err := server.store.CheckGroupMembership(...)
-		// For MVP compile fix, assuming valid group ID or checking later.
-		// Actually, let's just proceed. The user is asking for Basic Group Chat.
+	expiresAt := util.NullTime{
+		Time:  util.Now().Add(expiry),
+		Valid: expiry > 0,
 	}
 
-	// Handle expiry - DEFAULT TO 24 HOURS (Snapchat-style)
-	var expiresAt sql.NullTime
-	if req.ExpiresInSeconds > 0 {
-		// Custom expiry time provided
-		expiresAt = sql.NullTime{
-			Time:  time.Now().UTC().Add(time.Duration(req.ExpiresInSeconds) * time.Second),
-			Valid: true,
-		}
-	} else {
-		// DEFAULT: All messages expire after 24 hours
-		expiresAt = sql.NullTime{
-			Time:  time.Now().UTC().Add(24 * time.Hour),
-			Valid: true,
-		}
+	if req.ScheduledAt != nil {
+		server.scheduleMessage(ctx, req, attachments, expiry)
+		return
 	}
 
-	msg, err := server.store.CreateMessage(ctx, db.CreateMessageParams{
-		SenderID:   authPayload.UserID,
-		ReceiverID: receiverID,
-		GroupID:    groupID,
-		Content:    req.Content,
-		MediaUrl:   toNullString(req.MediaUrl),
-		MediaType:  toNullString(req.MediaType),
-		ExpiresAt:  expiresAt,
-	})
+	msg, err := server.createMessage(ctx, db.CreateMessageParams{
+		SenderID:         authPayload.UserID,
+		ReceiverID:       receiverID,
+		GroupID:          groupID,
+		Content:          req.Content,
+		ExpiresAt:        expiresAt,
+		ReplyToMessageID: replyToID,
+	}, attachments)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	if receiverID.Valid {
-		// Invalidate cache for this conversation (1:1)
-		server.invalidateConversationCache(authPayload.UserID, receiverID.UUID)
-		server.incrementUnreadCount(receiverID.UUID)
-
-		wsMsg := realtime.WSMessage{
-			Type:      "new_message",
-			Payload:   msg,
-			SenderID:  authPayload.UserID,
-			CreatedAt: msg.CreatedAt,
-		}
-		wsMsgBytes, _ := json.Marshal(wsMsg)
-		server.hub.SendToUser(receiverID.UUID, wsMsgBytes)
-	} else if groupID.Valid {
-		// Group Logic
-		// 1. Invalidate Group Cache? "group_messages:{groupID}"
-		// server.invalidateGroupCache(groupID.UUID)
+	server.deliverMessage(msg, attachments)
 
-		// 2. Notify All Members
-		// members, _ := server.store.GetGroupMembers(ctx, groupID.UUID)
-		// for _, m := range members {
-		//    server.hub.SendToUser(m.UserID, wsMsgBytes)
-		// }
-		// Need GetGroupMembers query.
-	}
+	ctx.JSON(http.StatusCreated, sendMessageResponse{
+		messageResponse:    server.messageResponseWithMedia(ctx, msg, attachments),
+		EffectiveExpiresAt: expiresAt.Ptr(),
+	})
+}
 
-	// Also send to SENDER so their client can update the messages list
-	// Wait, for groups, sender is also a member.
-	// For 1:1, sender needs update.
+// deliverMessage runs the delivery side effects for a newly stored message:
+// cache invalidation, unread count, and the WS push to both participants
+func (server *Server) deliverMessage(stored db.Message, attachments []MessageAttachment) {
+	server.events.Emit(context.Background(), analytics.EventMessageSent, stored.SenderID)
+	server.metrics.messagesSent.Inc()
+	msg := server.messageResponseWithMedia(context.Background(), stored, attachments)
 
 	wsMsg := realtime.WSMessage{
 		Type:      "new_message",
 		Payload:   msg,
-		SenderID:  authPayload.UserID,
+		SenderID:  msg.SenderID,
 		CreatedAt: msg.CreatedAt,
 	}
+	if msg.GroupID.Valid {
+		wsMsg.GroupID = &msg.GroupID.UUID
+	}
 	wsMsgBytes, _ := json.Marshal(wsMsg)
-	server.hub.SendToUser(authPayload.UserID, wsMsgBytes) // Always echo back?
 
-	ctx.JSON(http.StatusCreated, msg)
+	// Recipients who muted the conversation still get the message, flagged so
+	// their clients stay quiet
+	mutedMsg := wsMsg
+	mutedMsg.Muted = true
+	mutedMsgBytes, _ := json.Marshal(mutedMsg)
+
+	if msg.ReceiverID.Valid {
+		// Invalidate cache for this conversation (1:1)
+		server.invalidateConversationCache(msg.SenderID, msg.ReceiverID.UUID)
+		if server.isConversationMuted(context.Background(), msg.ReceiverID.UUID, msg.SenderID) {
+			server.hub.SendToUser(msg.ReceiverID.UUID, mutedMsgBytes)
+		} else {
+			server.incrementUnreadCount(msg.ReceiverID.UUID)
+			server.hub.SendToUser(msg.ReceiverID.UUID, wsMsgBytes)
+		}
+	} else if msg.GroupID.Valid {
+		server.sendToGroup(msg.GroupID.UUID, msg.SenderID, wsMsgBytes, mutedMsgBytes)
+	}
+
+	// Echo to the sender too so their other devices update the messages list
+	server.hub.SendToUser(msg.SenderID, wsMsgBytes)
+}
+
+// sendToGroup pushes a WebSocket message to every member of a group except
+// the sender, and mutedMessage to members who muted the group. Members who
+// aren't connected are skipped by the hub's stream consumer.
+func (server *Server) sendToGroup(groupID, senderID uuid.UUID, message, mutedMessage []byte) {
+	members, err := server.store.GetGroupMembers(context.Background(), groupID)
+	if err != nil {
+		log.Error().Err(err).Str("group_id", groupID.String()).Msg("failed to load group members for delivery")
+		return
+	}
+	muters, err := server.store.ListGroupMuters(context.Background(), uuid.NullUUID{UUID: groupID, Valid: true})
+	if err != nil {
+		log.Error().Err(err).Str("group_id", groupID.String()).Msg("failed to load group mutes for delivery")
+	}
+	for _, member := range members {
+		if member.UserID == senderID {
+			continue
+		}
+		if slices.Contains(muters, member.UserID) {
+			server.hub.SendToUser(member.UserID, mutedMessage)
+			continue
+		}
+		server.hub.SendToUser(member.UserID, message)
+	}
+}
+
+// sendMessageResponse is the created message plus the expiry the server
+// applied; null when the message never expires
+type sendMessageResponse struct {
+	messageResponse
+	EffectiveExpiresAt *time.Time `json:"effective_expires_at"`
 }
 
 // deleteMessage allows a user to unsend/delete their own message
@@ -386,6 +509,14 @@ func (server *Server) editMessage(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	req.Content = sanitizeMessageContent(req.Content)
+	if err := validateMessageContent(req.Content); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if server.rejectBlockedText(ctx, req.Content) {
+		return
+	}
 
 	authPayload := getAuthPayload(ctx)
 
@@ -419,14 +550,21 @@ func (server *Server) editMessage(ctx *gin.Context) {
 		return
 	}
 
+	attachments, err := server.store.ListMessageAttachments(ctx, messageID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	rsp := server.messageResponseWithMedia(ctx, updatedMsg, messageAttachmentsFromRows(attachments))
+
 	// Invalidate cache and Notify
 	if originalMsg.ReceiverID.Valid {
 		server.invalidateConversationCache(originalMsg.SenderID, originalMsg.ReceiverID.UUID)
-		server.sendWSNotification(originalMsg.ReceiverID.UUID, "message_edited", updatedMsg)
+		server.sendWSNotification(originalMsg.ReceiverID.UUID, "message_edited", rsp)
 	}
 	// TODO: Handle Group edit notification
 
-	ctx.JSON(http.StatusOK, updatedMsg)
+	ctx.JSON(http.StatusOK, rsp)
 }
 
 // saveMessage prevents a message from expiring (sets expires_at to NULL)
@@ -465,6 +603,19 @@ func (server *Server) saveMessage(ctx *gin.Context) {
 		return
 	}
 
+	// Saved messages never expire, which is beyond any free-tier expiry cap
+	if server.config.MessageSaveRequiresPremium {
+		premium, err := server.isPremiumUser(ctx, authPayload.UserID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if !premium {
+			ctx.JSON(http.StatusForbidden, errorResponse(ErrSaveMessageRequiresPremium))
+			return
+		}
+	}
+
 	// Save the message (set expires_at to NULL)
 	savedMsg, err := server.store.SaveMessage(ctx, messageID)
 	if err != nil {
@@ -500,7 +651,7 @@ func (server *Server) markConversationRead(ctx *gin.Context) {
 
 	authPayload := getAuthPayload(ctx)
 
-	err := server.store.MarkConversationRead(ctx, db.MarkConversationReadParams{
+	read, err := server.store.MarkConversationRead(ctx, db.MarkConversationReadParams{
 		ReceiverID: uuid.NullUUID{UUID: authPayload.UserID, Valid: true},
 		SenderID:   senderID,
 	})
@@ -512,8 +663,10 @@ func (server *Server) markConversationRead(ctx *gin.Context) {
 	// Invalidate cache
 	server.invalidateConversationCache(authPayload.UserID, senderID)
 
-	// Clear Unread Count Cache for Reader (User)
-	server.invalidateUnreadCountCache(authPayload.UserID)
+	// Take what was just read off the reader's badge; muted chats were never on it
+	if len(read) > 0 && !server.isConversationMuted(ctx, authPayload.UserID, senderID) {
+		server.decrementUnreadCount(authPayload.UserID, len(read))
+	}
 
 	// Notify sender that their messages were read
 	wsMsg := realtime.WSMessage{
@@ -529,15 +682,39 @@ func (server *Server) markConversationRead(ctx *gin.Context) {
 	// Notify Self (Reader) to update badges on other devices
 	server.hub.SendToUser(authPayload.UserID, wsMsgBytes)
 
+	// Per-message receipts, for the sender's read ticks
+	if len(read) > 0 {
+		ids := make([]uuid.UUID, len(read))
+		for i, row := range read {
+			ids[i] = row.ID
+		}
+		server.sendWSNotification(senderID, MessageReadType, gin.H{
+			"message_ids": ids,
+			"reader_id":   authPayload.UserID,
+			"read_at":     read[0].ReadAt.Time,
+		})
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "Conversation marked as read"})
 }
 
+var ErrReactionConflict = errors.New("the reaction was changed by another request, try again")
+
 // Reaction request body
 type reactionRequest struct {
 	Emoji string `json:"emoji" binding:"required"`
 }
 
-// addReaction adds a reaction to a message
+// reactionToggleResponse is the reaction a toggle added or removed, with the
+// message's reaction counts afterwards
+type reactionToggleResponse struct {
+	db.CreateMessageReactionRow
+	Reactions []db.GetAggregatedReactionsRow `json:"reactions"`
+}
+
+// addReaction toggles the caller's reaction with an emoji: it is added, or
+// removed if the caller already reacted with it. A user may hold several
+// different emoji on one message.
 func (server *Server) addReaction(ctx *gin.Context) {
 	messageIDStr := ctx.Param("id")
 	messageID, err := uuid.Parse(messageIDStr)
@@ -551,6 +728,10 @@ func (server *Server) addReaction(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	if err := server.validateReaction(req.Emoji); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
@@ -571,46 +752,28 @@ func (server *Server) addReaction(ctx *gin.Context) {
 		Emoji:     req.Emoji,
 	})
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// A concurrent toggle of the same emoji won the race
+			ctx.JSON(http.StatusConflict, errorResponse(ErrReactionConflict))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	// Invalidate cache
-	if msg.ReceiverID.Valid {
-		ids := []string{msg.SenderID.String(), msg.ReceiverID.UUID.String()}
-		sort.Strings(ids)
-		cacheKey := "messages:" + ids[0] + ":" + ids[1]
-		server.redis.Del(context.Background(), cacheKey)
-	}
-
-	// Notify the other user
-	var otherUserID uuid.UUID
-	shouldNotify := false
-
-	if msg.SenderID == authPayload.UserID {
-		if msg.ReceiverID.Valid {
-			otherUserID = msg.ReceiverID.UUID
-			shouldNotify = true
-		}
-	} else {
-		otherUserID = msg.SenderID // If I'm receiver, notify sender
-		shouldNotify = true
+	reactions, err := server.aggregatedReactions(ctx, messageID, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
-	if shouldNotify {
-		wsMsg := realtime.WSMessage{
-			Type: "reaction_added",
-			Payload: gin.H{
-				"message_id": messageID,
-				"user_id":    authPayload.UserID,
-				"emoji":      req.Emoji,
-			},
-		}
-		wsMsgBytes, _ := json.Marshal(wsMsg)
-		server.hub.SendToUser(otherUserID, wsMsgBytes)
+	eventType, status := "reaction_added", http.StatusCreated
+	if !reaction.Added {
+		eventType, status = "reaction_removed", http.StatusOK
 	}
+	server.notifyReaction(ctx, msg, authPayload.UserID, eventType, req.Emoji)
 
-	ctx.JSON(http.StatusCreated, reaction)
+	ctx.JSON(status, reactionToggleResponse{CreateMessageReactionRow: reaction, Reactions: reactions})
 }
 
 // removeReaction removes a reaction from a message
@@ -651,37 +814,19 @@ func (server *Server) removeReaction(ctx *gin.Context) {
 		return
 	}
 
-	// Notify the other user
-	var otherUserID uuid.UUID
-	shouldNotify := false
-
-	if msg.SenderID == authPayload.UserID {
-		if msg.ReceiverID.Valid {
-			otherUserID = msg.ReceiverID.UUID
-			shouldNotify = true
-		}
-	} else {
-		otherUserID = msg.SenderID
-		shouldNotify = true
-	}
-
-	if shouldNotify {
-		wsMsg := realtime.WSMessage{
-			Type: "reaction_removed",
-			Payload: gin.H{
-				"message_id": messageID,
-				"user_id":    authPayload.UserID,
-				"emoji":      req.Emoji,
-			},
-		}
-		wsMsgBytes, _ := json.Marshal(wsMsg)
-		server.hub.SendToUser(otherUserID, wsMsgBytes)
+	reactions, err := server.aggregatedReactions(ctx, messageID, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Reaction removed"})
+	server.notifyReaction(ctx, msg, authPayload.UserID, "reaction_removed", req.Emoji)
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Reaction removed", "reactions": reactions})
 }
 
-// getMessageReactions gets all reactions for a message
+// getMessageReactions returns the reaction counts of a message, one entry
+// per emoji, flagging the ones the caller reacted with
 func (server *Server) getMessageReactions(ctx *gin.Context) {
 	messageIDStr := ctx.Param("id")
 	messageID, err := uuid.Parse(messageIDStr)
@@ -690,7 +835,8 @@ func (server *Server) getMessageReactions(ctx *gin.Context) {
 		return
 	}
 
-	reactions, err := server.store.GetMessageReactions(ctx, messageID)
+	authPayload := getAuthPayload(ctx)
+	reactions, err := server.aggregatedReactions(ctx, messageID, authPayload.UserID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -699,16 +845,62 @@ func (server *Server) getMessageReactions(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, reactions)
 }
 
+// aggregatedReactions returns the reaction counts of a message as viewerID
+// sees them, never nil so an unreacted message encodes as []
+func (server *Server) aggregatedReactions(ctx context.Context, messageID, viewerID uuid.UUID) ([]db.GetAggregatedReactionsRow, error) {
+	reactions, err := server.store.GetAggregatedReactions(ctx, db.GetAggregatedReactionsParams{
+		ViewerID:  viewerID,
+		MessageID: messageID,
+	})
+	if reactions == nil {
+		reactions = []db.GetAggregatedReactionsRow{}
+	}
+	return reactions, err
+}
+
+// notifyReaction invalidates the chat cache and tells the other participant
+// of a direct message, or the author of a group message, that actorID's
+// reactions changed. The payload carries the counts as the recipient sees them.
+func (server *Server) notifyReaction(ctx context.Context, msg db.Message, actorID uuid.UUID, eventType, emoji string) {
+	// Invalidate cache
+	if msg.ReceiverID.Valid {
+		server.invalidateConversationCache(msg.SenderID, msg.ReceiverID.UUID)
+	}
+
+	// Notify the other user
+	var otherUserID uuid.UUID
+	if msg.SenderID == actorID {
+		if !msg.ReceiverID.Valid {
+			return
+		}
+		otherUserID = msg.ReceiverID.UUID
+	} else {
+		otherUserID = msg.SenderID // If I'm receiver, notify sender
+	}
+
+	reactions, err := server.aggregatedReactions(ctx, msg.ID, otherUserID)
+	if err != nil {
+		log.Error().Err(err).Str("message_id", msg.ID.String()).Msg("failed to count reactions for notification")
+		return
+	}
+	server.sendWSNotification(otherUserID, eventType, gin.H{
+		"message_id": msg.ID,
+		"user_id":    actorID,
+		"emoji":      emoji,
+		"reactions":  reactions,
+	})
+}
+
 // getUnreadMessageCount returns the total number of unread messages for the user
 func (server *Server) getUnreadMessageCount(ctx *gin.Context) {
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
 	// Try Redis first
-	cacheKey := "unread_count:" + authPayload.UserID.String()
+	cacheKey := unreadCountCacheKey(authPayload.UserID)
 	cachedCount, err := server.redis.Get(context.Background(), cacheKey).Int64()
 	if err == nil {
 		ctx.Header("X-Cache", "HIT")
-		ctx.JSON(http.StatusOK, gin.H{"count": cachedCount})
+		ctx.JSON(http.StatusOK, gin.H{"unread_count": cachedCount})
 		return
 	}
 
@@ -718,8 +910,8 @@ func (server *Server) getUnreadMessageCount(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
-	// Cache the result
-	server.redis.Set(context.Background(), cacheKey, count, 30*time.Minute)
+	// Sends and reads keep the cached count current until it's recounted
+	server.redis.Set(context.Background(), cacheKey, count, unreadCountTTL)
 
 	ctx.Header("X-Cache", "MISS")
 	ctx.JSON(http.StatusOK, gin.H{"unread_count": count})
@@ -738,14 +930,18 @@ func (server *Server) getConversationList(ctx *gin.Context) {
 
 	// Convert to response format
 	type ConversationResponse struct {
-		ID            uuid.UUID `json:"id"`
-		Username      string    `json:"username"`
-		FullName      string    `json:"full_name"`
-		AvatarUrl     string    `json:"avatar_url"`
-		LastMessage   string    `json:"last_message"`
-		LastMessageAt time.Time `json:"last_message_at"`
-		LastSenderID  uuid.UUID `json:"last_sender_id"`
-		UnreadCount   int64     `json:"unread_count"`
+		ID                   uuid.UUID  `json:"id"`
+		Username             string     `json:"username"`
+		FullName             string     `json:"full_name"`
+		AvatarUrl            string     `json:"avatar_url"`
+		LastMessage          string     `json:"last_message"`
+		LastMessageAt        time.Time  `json:"last_message_at"`
+		LastMessageMediaType string     `json:"last_message_media_type,omitempty"`
+		LastMessageExpiresAt *time.Time `json:"last_message_expires_at"`
+		IsMedia              bool       `json:"is_media"`
+		LastSenderID         uuid.UUID  `json:"last_sender_id"`
+		UnreadCount          int64      `json:"unread_count"`
+		Muted                bool       `json:"muted"`
 	}
 
 	response := make([]ConversationResponse, len(conversations))
@@ -756,23 +952,45 @@ func (server *Server) getConversationList(ctx *gin.Context) {
 		}
 
 		response[i] = ConversationResponse{
-			ID:            conv.ID,
-			Username:      conv.Username,
-			FullName:      conv.FullName,
-			AvatarUrl:     conv.AvatarUrl.String,
-			LastMessage:   conv.LastMessage,
-			LastMessageAt: conv.LastMessageAt,
-			LastSenderID:  conv.LastSenderID,
-			UnreadCount:   unreadCount,
+			ID:                   conv.ID,
+			Username:             conv.Username,
+			FullName:             conv.FullName,
+			AvatarUrl:            conv.AvatarUrl.String,
+			LastMessage:          messagePreview(conv.LastMessage, conv.LastMessageMediaType.String, conv.LastMessageIsMedia),
+			LastMessageAt:        conv.LastMessageAt,
+			LastMessageMediaType: conv.LastMessageMediaType.String,
+			LastMessageExpiresAt: conv.LastMessageExpiresAt.Ptr(),
+			IsMedia:              conv.LastMessageIsMedia,
+			LastSenderID:         conv.LastSenderID,
+			UnreadCount:          unreadCount,
+			Muted:                conv.Muted,
 		}
 	}
 
 	ctx.JSON(http.StatusOK, response)
 }
 
+// messagePreview is the inbox text for a message: its content, or a
+// placeholder naming the media when it was sent without text
+func messagePreview(content, mediaType string, isMedia bool) string {
+	if content != "" || !isMedia {
+		return content
+	}
+	switch mediaType {
+	case "video":
+		return "🎥 Video"
+	case "audio":
+		return "🎤 Audio"
+	case "image", "":
+		// Legacy media without a type is an image, as in legacyAttachments
+		return "📷 Photo"
+	}
+	return "📎 Attachment"
+}
+
 // deleteConversation deletes all messages between the authenticated user and another user
 func (server *Server) deleteConversation(ctx *gin.Context) {
-	userIDStr := ctx.Param("userId")
+	userIDStr := ctx.Param("id")
 	userID, ok := parseUUIDParam(ctx, userIDStr, "user_id")
 	if !ok {
 		return
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/realtime"
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestAddReaction(t *testing.T) {
+	userID, senderID, messageID := uuid.New(), uuid.New(), uuid.New()
+	msg := db.Message{ID: messageID, SenderID: senderID, ReceiverID: uuid.NullUUID{UUID: userID, Valid: true}}
+	toggle := db.CreateMessageReactionParams{MessageID: messageID, UserID: userID, Emoji: "🔥"}
+	counts := []db.GetAggregatedReactionsRow{{Emoji: "🔥", Count: 2, ReactedByMe: true}, {Emoji: "👍", Count: 1}}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, events []realtime.WSMessage)
+	}{
+		{
+			name: "Added",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessage(gomock.Any(), messageID).Times(1).Return(msg, nil)
+				store.EXPECT().CreateMessageReaction(gomock.Any(), toggle).Times(1).
+					Return(db.CreateMessageReactionRow{MessageID: messageID, UserID: userID, Emoji: "🔥", Added: true}, nil)
+				store.EXPECT().
+					GetAggregatedReactions(gomock.Any(), db.GetAggregatedReactionsParams{ViewerID: userID, MessageID: messageID}).
+					Times(1).
+					Return(counts, nil)
+				// The sender's view of the same counts
+				store.EXPECT().
+					GetAggregatedReactions(gomock.Any(), db.GetAggregatedReactionsParams{ViewerID: senderID, MessageID: messageID}).
+					Times(1).
+					Return([]db.GetAggregatedReactionsRow{{Emoji: "🔥", Count: 2, ReactedByMe: true}, {Emoji: "👍", Count: 1, ReactedByMe: true}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, events []realtime.WSMessage) {
+				require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+				var got reactionToggleResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.True(t, got.Added)
+				require.Equal(t, counts, got.Reactions)
+
+				require.Len(t, events, 1)
+				require.Equal(t, "reaction_added", events[0].Type)
+				payload := events[0].Payload.(map[string]interface{})
+				require.Equal(t, "🔥", payload["emoji"])
+				require.Equal(t, []interface{}{
+					map[string]interface{}{"emoji": "🔥", "count": float64(2), "reacted_by_me": true},
+					map[string]interface{}{"emoji": "👍", "count": float64(1), "reacted_by_me": true},
+				}, payload["reactions"])
+			},
+		},
+		{
+			name: "ToggledOff",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessage(gomock.Any(), messageID).Times(1).Return(msg, nil)
+				store.EXPECT().CreateMessageReaction(gomock.Any(), toggle).Times(1).
+					Return(db.CreateMessageReactionRow{MessageID: messageID, UserID: userID, Emoji: "🔥", Added: false}, nil)
+				store.EXPECT().GetAggregatedReactions(gomock.Any(), gomock.Any()).Times(2).Return(nil, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, events []realtime.WSMessage) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				require.JSONEq(t, `[]`, mustJSONField(t, recorder.Body.Bytes(), "reactions"))
+
+				require.Len(t, events, 1)
+				require.Equal(t, "reaction_removed", events[0].Type)
+			},
+		},
+		{
+			name: "Conflict",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessage(gomock.Any(), messageID).Times(1).Return(msg, nil)
+				store.EXPECT().CreateMessageReaction(gomock.Any(), toggle).Times(1).
+					Return(db.CreateMessageReactionRow{}, sql.ErrNoRows)
+				store.EXPECT().GetAggregatedReactions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, events []realtime.WSMessage) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Empty(t, events)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+			server.redis = rdb
+			server.hub = realtime.NewHub(rdb)
+
+			recorder := postJSON(t, server, fmt.Sprintf("/messages/%s/reactions", messageID), gin.H{"emoji": "🔥"}, &userID)
+
+			entries, err := rdb.XRange(context.Background(), util.RedisKey("locolive:stream:routing"), "-", "+").Result()
+			require.NoError(t, err)
+			var events []realtime.WSMessage
+			for _, entry := range entries {
+				require.Equal(t, senderID.String(), entry.Values["target_user_id"])
+				var event realtime.WSMessage
+				require.NoError(t, json.Unmarshal([]byte(entry.Values["payload"].(string)), &event))
+				events = append(events, event)
+			}
+			tc.checkResponse(t, recorder, events)
+		})
+	}
+}
+
+func TestGetMessageReactionsEmpty(t *testing.T) {
+	userID, messageID := uuid.New(), uuid.New()
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAggregatedReactions(gomock.Any(), db.GetAggregatedReactionsParams{ViewerID: userID, MessageID: messageID}).
+		Times(1).
+		Return(nil, nil)
+
+	server := newTestServer(t, store)
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/messages/%s/reactions", messageID), nil)
+	require.NoError(t, err)
+	accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `[]`, recorder.Body.String())
+}
+
+// mustJSONField returns one top-level field of a JSON object, re-encoded
+func mustJSONField(t *testing.T, body []byte, field string) string {
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &fields))
+	return string(fields[field])
+}
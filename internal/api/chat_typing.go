@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/realtime"
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// TypingType is sent by a client while its user is composing a message, and
+	// forwarded as is
+	TypingType = "typing"
+	// StopTypingType is sent by a client when its user stops composing
+	StopTypingType = "stop_typing"
+	// TypingStoppedType tells the other side of a conversation StopTypingType arrived
+	TypingStoppedType = "typing_stopped"
+
+	typingTimeout = 5 * time.Second
+)
+
+// typingEvent names the conversation a user is typing in: one user or a group
+type typingEvent struct {
+	ReceiverID *uuid.UUID `json:"receiver_id"`
+	GroupID    *uuid.UUID `json:"group_id"`
+}
+
+func (e typingEvent) Validate() error {
+	hasReceiver := e.ReceiverID != nil && *e.ReceiverID != uuid.Nil
+	hasGroup := e.GroupID != nil && *e.GroupID != uuid.Nil
+	if hasReceiver == hasGroup {
+		return errors.New("exactly one of receiver_id or group_id is required")
+	}
+	return nil
+}
+
+// registerTypingHandlers wires typing indicators for direct and group chats
+func (server *Server) registerTypingHandlers() {
+	for inbound, outbound := range map[string]string{
+		TypingType:     TypingType,
+		StopTypingType: TypingStoppedType,
+	} {
+		realtime.Handle(server.hub.Dispatcher(), inbound, func(c *realtime.Client, e typingEvent) error {
+			ctx, cancel := context.WithTimeout(context.Background(), typingTimeout)
+			defer cancel()
+			return server.forwardTyping(ctx, c, e, outbound)
+		})
+	}
+}
+
+// forwardTyping sends msgType to the rest of the conversation. created_at is
+// the server's clock, so clients can expire a typing state that never got its
+// typing_stopped. Typing to someone the user can't message is dropped silently.
+func (server *Server) forwardTyping(ctx context.Context, c *realtime.Client, e typingEvent, msgType string) error {
+	message, err := json.Marshal(realtime.WSMessage{
+		Type: msgType,
+		Payload: map[string]interface{}{
+			"user_id":  c.UserID,
+			"username": c.Username,
+		},
+		SenderID:  c.UserID,
+		CreatedAt: util.Now(),
+		GroupID:   e.GroupID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if e.GroupID != nil {
+		members, err := server.store.GetGroupMembers(ctx, *e.GroupID)
+		if err != nil {
+			return err
+		}
+		isMember := false
+		for _, member := range members {
+			if member.UserID == c.UserID {
+				isMember = true
+				break
+			}
+		}
+		if !isMember {
+			return ErrNotGroupMember
+		}
+		for _, member := range members {
+			if member.UserID != c.UserID {
+				server.hub.SendToUser(member.UserID, message)
+			}
+		}
+		return nil
+	}
+
+	if err := server.checkConnection(ctx, c.UserID, *e.ReceiverID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	server.hub.SendToUser(*e.ReceiverID, message)
+	return nil
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/realtime"
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestForwardTyping(t *testing.T) {
+	senderID, receiverID, otherID := uuid.New(), uuid.New(), uuid.New()
+	groupID := uuid.New()
+
+	testCases := []struct {
+		name       string
+		event      typingEvent
+		buildStubs func(store *mockdb.MockStore)
+		wantErr    error
+		wantTo     []uuid.UUID
+	}{
+		{
+			name:  "Connected",
+			event: typingEvent{ReceiverID: &receiverID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).Times(2).Return(false, nil)
+				store.EXPECT().GetConnection(gomock.Any(), gomock.Any()).Times(1).Return(db.Connection{Status: "accepted"}, nil)
+				store.EXPECT().GetPrivacySettings(gomock.Any(), receiverID).Times(1).Return(db.PrivacySetting{}, sql.ErrNoRows)
+			},
+			wantTo: []uuid.UUID{receiverID},
+		},
+		{
+			name:  "NotConnected",
+			event: typingEvent{ReceiverID: &receiverID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).Times(2).Return(false, nil)
+				store.EXPECT().GetConnection(gomock.Any(), gomock.Any()).Times(1).Return(db.Connection{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name:  "Group",
+			event: typingEvent{GroupID: &groupID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetGroupMembers(gomock.Any(), groupID).Times(1).Return([]db.GetGroupMembersRow{
+					{GroupID: groupID, UserID: senderID},
+					{GroupID: groupID, UserID: receiverID},
+					{GroupID: groupID, UserID: otherID},
+				}, nil)
+			},
+			wantTo: []uuid.UUID{receiverID, otherID},
+		},
+		{
+			name:  "NotGroupMember",
+			event: typingEvent{GroupID: &groupID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetGroupMembers(gomock.Any(), groupID).Times(1).Return([]db.GetGroupMembersRow{
+					{GroupID: groupID, UserID: receiverID},
+				}, nil)
+			},
+			wantErr: ErrNotGroupMember,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			mr := miniredis.RunT(t)
+			rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { rdb.Close() })
+			server.redis = rdb
+			server.hub = realtime.NewHub(rdb)
+
+			client := &realtime.Client{UserID: senderID, Username: "sender"}
+			err := server.forwardTyping(context.Background(), client, tc.event, TypingStoppedType)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			entries, err := rdb.XRange(context.Background(), util.RedisKey("locolive:stream:routing"), "-", "+").Result()
+			require.NoError(t, err)
+			require.Len(t, entries, len(tc.wantTo))
+			for i, entry := range entries {
+				require.Equal(t, tc.wantTo[i].String(), entry.Values["target_user_id"])
+
+				var msg realtime.WSMessage
+				require.NoError(t, json.Unmarshal([]byte(entry.Values["payload"].(string)), &msg))
+				require.Equal(t, TypingStoppedType, msg.Type)
+				require.Equal(t, senderID, msg.SenderID)
+				require.False(t, msg.CreatedAt.IsZero())
+				require.Equal(t, tc.event.GroupID, msg.GroupID)
+			}
+		})
+	}
+}
+
+func TestTypingEventValidate(t *testing.T) {
+	id := uuid.New()
+	require.NoError(t, typingEvent{ReceiverID: &id}.Validate())
+	require.NoError(t, typingEvent{GroupID: &id}.Validate())
+	require.Error(t, typingEvent{}.Validate())
+	require.Error(t, typingEvent{ReceiverID: &id, GroupID: &id}.Validate())
+}
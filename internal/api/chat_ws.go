@@ -1,8 +1,6 @@
 package api
 
 import (
-	"net/http"
-
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog/log"
@@ -11,31 +9,11 @@ import (
 	"privacy-social-backend/internal/token"
 )
 
+// upgrader is copied per connection with CheckOrigin set to the server's
+// CORS_ALLOWED_ORIGINS
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// In debug mode, allow all origins for development
-		if gin.Mode() == gin.DebugMode {
-			return true
-		}
-
-		// In production, check against allowed origins
-		origin := r.Header.Get("Origin")
-		allowedOrigins := []string{
-			"http://localhost:5173",
-			"http://localhost:3000",
-
-			"https://yourdomain.com", // Add your production domain
-		}
-
-		for _, allowed := range allowedOrigins {
-			if origin == allowed {
-				return true
-			}
-		}
-		return false
-	},
 }
 
 // chatWebSocket handles WebSocket connections for real-time chat
@@ -43,7 +21,9 @@ func (server *Server) chatWebSocket(ctx *gin.Context) {
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
 	// Upgrade HTTP to WS
-	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	wsUpgrader := upgrader
+	wsUpgrader.CheckOrigin = server.origins.allowsRequest
+	conn, err := wsUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to set websocket upgrade")
 		return
@@ -55,6 +35,8 @@ func (server *Server) chatWebSocket(ctx *gin.Context) {
 		Conn:     conn,
 		Send:     make(chan []byte, 256),
 		Username: authPayload.Username,
+		Version:  realtime.NegotiateVersion(ctx.Request),
+		Batch:    realtime.NegotiateBatching(ctx.Request),
 	}
 
 	server.hub.Register <- client
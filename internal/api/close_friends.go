@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/util"
+)
+
+var ErrCloseFriendNotConnected = errors.New("only connections can be added to close friends")
+
+type closeFriendResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	FullName  string    `json:"full_name"`
+	AvatarUrl string    `json:"avatar_url"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// listCloseFriends serves GET /users/me/close-friends
+func (server *Server) listCloseFriends(ctx *gin.Context) {
+	authPayload := getAuthPayload(ctx)
+
+	friends, err := server.store.ListCloseFriends(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]closeFriendResponse, len(friends))
+	for i, f := range friends {
+		rsp[i] = closeFriendResponse{
+			ID:        f.ID,
+			Username:  f.Username,
+			FullName:  f.FullName,
+			AvatarUrl: f.AvatarUrl.String,
+			AddedAt:   f.CreatedAt,
+		}
+	}
+	ctx.JSON(http.StatusOK, gin.H{"close_friends": rsp})
+}
+
+// addCloseFriend serves POST /users/me/close-friends/:id. Only accepted
+// connections can be added; adding someone already on the list succeeds.
+func (server *Server) addCloseFriend(ctx *gin.Context) {
+	friendID, ok := parseUUIDParam(ctx, ctx.Param("id"), "user_id")
+	if !ok {
+		return
+	}
+	authPayload := getAuthPayload(ctx)
+	if rejectSelfTarget(ctx, authPayload.UserID, friendID, "cannot add yourself to close friends") {
+		return
+	}
+
+	conn, err := server.store.GetConnection(ctx, db.GetConnectionParams{
+		RequesterID: authPayload.UserID,
+		TargetID:    friendID,
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err != nil || conn.Status != db.ConnectionStatusAccepted {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrCloseFriendNotConnected))
+		return
+	}
+
+	if err := server.store.AddCloseFriend(ctx, db.AddCloseFriendParams{
+		UserID:   authPayload.UserID,
+		FriendID: friendID,
+	}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.invalidateStoryAudienceCaches(friendID)
+
+	ctx.JSON(http.StatusOK, gin.H{"user_id": friendID, "close_friend": true})
+}
+
+// removeCloseFriend serves DELETE /users/me/close-friends/:id. Removing
+// someone who isn't on the list succeeds.
+func (server *Server) removeCloseFriend(ctx *gin.Context) {
+	friendID, ok := parseUUIDParam(ctx, ctx.Param("id"), "user_id")
+	if !ok {
+		return
+	}
+	authPayload := getAuthPayload(ctx)
+
+	if err := server.store.RemoveCloseFriend(ctx, db.RemoveCloseFriendParams{
+		UserID:   authPayload.UserID,
+		FriendID: friendID,
+	}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.invalidateStoryAudienceCaches(friendID)
+
+	ctx.JSON(http.StatusOK, gin.H{"user_id": friendID, "close_friend": false})
+}
+
+// invalidateStoryAudienceCaches drops the cached story lists of a user who
+// just joined or left someone's close friends, so close_friends stories
+// appear or disappear for them right away
+func (server *Server) invalidateStoryAudienceCaches(userID uuid.UUID) {
+	server.invalidateUserFeedCache(userID)
+	server.redis.Del(context.Background(),
+		util.RedisKey("stories:connections:"+userID.String()),
+		storyRingsCacheKey(userID),
+	)
+}
+
+// storyAudienceIncludes reports whether viewerID is in the audience of a story
+// authorID posted with the given visibility. Public stories and the author's
+// own need no lookup.
+func (server *Server) storyAudienceIncludes(ctx context.Context, visibility db.StoryAvailability, authorID, viewerID uuid.UUID) (bool, error) {
+	if viewerID == authorID {
+		return true, nil
+	}
+	switch visibility {
+	case db.StoryAvailabilityConnections:
+		conn, err := server.store.GetConnection(ctx, db.GetConnectionParams{
+			RequesterID: viewerID,
+			TargetID:    authorID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return conn.Status == db.ConnectionStatusAccepted, nil
+	case db.StoryAvailabilityCloseFriends:
+		return server.store.IsCloseFriend(ctx, db.IsCloseFriendParams{
+			UserID:   authorID,
+			FriendID: viewerID,
+		})
+	}
+	return true, nil
+}
+
+// rejectOutsideAudience writes a 404 and returns true when viewerID isn't in
+// the story's audience; to them the story doesn't exist
+func (server *Server) rejectOutsideAudience(ctx *gin.Context, visibility db.StoryAvailability, authorID, viewerID uuid.UUID) bool {
+	included, err := server.storyAudienceIncludes(ctx, visibility, authorID, viewerID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return true
+	}
+	if !included {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "story not found"})
+		return true
+	}
+	return false
+}
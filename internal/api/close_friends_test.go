@@ -0,0 +1,135 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestAddCloseFriend(t *testing.T) {
+	userID, friendID := uuid.New(), uuid.New()
+	storiesKey := util.RedisKey("stories:connections:" + friendID.String())
+
+	testCases := []struct {
+		name          string
+		friendID      uuid.UUID
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, mr *miniredis.Miniredis)
+	}{
+		{
+			name:     "OK",
+			friendID: friendID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetConnection(gomock.Any(), db.GetConnectionParams{RequesterID: userID, TargetID: friendID}).
+					Times(1).
+					Return(db.Connection{RequesterID: friendID, TargetID: userID, Status: db.ConnectionStatusAccepted}, nil)
+				store.EXPECT().
+					AddCloseFriend(gomock.Any(), db.AddCloseFriendParams{UserID: userID, FriendID: friendID}).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mr *miniredis.Miniredis) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				require.JSONEq(t, fmt.Sprintf(`{"user_id":%q,"close_friend":true}`, friendID), recorder.Body.String())
+				// The friend's cached stories are dropped so close-friends stories show up
+				require.False(t, mr.Exists(storiesKey))
+			},
+		},
+		{
+			name:     "NotConnected",
+			friendID: friendID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetConnection(gomock.Any(), gomock.Any()).Times(1).Return(db.Connection{}, sql.ErrNoRows)
+				store.EXPECT().AddCloseFriend(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mr *miniredis.Miniredis) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), ErrCloseFriendNotConnected.Error())
+			},
+		},
+		{
+			name:     "PendingConnection",
+			friendID: friendID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetConnection(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Connection{RequesterID: userID, TargetID: friendID, Status: db.ConnectionStatusPending}, nil)
+				store.EXPECT().AddCloseFriend(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mr *miniredis.Miniredis) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "Self",
+			friendID: userID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetConnection(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().AddCloseFriend(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mr *miniredis.Miniredis) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			mr := miniredis.RunT(t)
+			server.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			require.NoError(t, mr.Set(storiesKey, "[]"))
+
+			recorder := postJSON(t, server, fmt.Sprintf("/users/me/close-friends/%s", tc.friendID), nil, &userID)
+			tc.checkResponse(t, recorder, mr)
+		})
+	}
+}
+
+func TestRemoveCloseFriend(t *testing.T) {
+	userID, friendID := uuid.New(), uuid.New()
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		RemoveCloseFriend(gomock.Any(), db.RemoveCloseFriendParams{UserID: userID, FriendID: friendID}).
+		Times(1).
+		Return(nil)
+
+	server := newTestServer(t, store)
+	mr := miniredis.RunT(t)
+	server.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ringsKey := storyRingsCacheKey(friendID)
+	require.NoError(t, mr.Set(ringsKey, "[]"))
+
+	request, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/me/close-friends/%s", friendID), nil)
+	require.NoError(t, err)
+	accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, fmt.Sprintf(`{"user_id":%q,"close_friend":false}`, friendID), recorder.Body.String())
+	require.False(t, mr.Exists(ringsKey))
+}
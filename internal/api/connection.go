@@ -11,6 +11,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/analytics"
 	"privacy-social-backend/internal/token"
 )
 
@@ -22,9 +23,42 @@ type friendResponse struct {
 	LastActiveAt *time.Time `json:"last_active_at"`
 }
 
+type listConnectionsRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending accepted"`
+}
+
+type connectionUserResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	FullName  string    `json:"full_name"`
+	AvatarUrl string    `json:"avatar_url"`
+	Bio       string    `json:"bio"`
+}
+
+// connectionResponse describes a relationship relative to the authenticated user
+type connectionResponse struct {
+	RequesterID uuid.UUID              `json:"requester_id"`
+	TargetID    uuid.UUID              `json:"target_id"`
+	Status      db.ConnectionStatus    `json:"status"`
+	Direction   string                 `json:"direction"` // "incoming" or "outgoing"
+	CreatedAt   time.Time              `json:"created_at"`
+	RespondedAt *time.Time             `json:"responded_at"`
+	User        connectionUserResponse `json:"user"`
+}
+
 func (server *Server) listConnections(ctx *gin.Context) {
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
+	var req listConnectionsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.Status != "" {
+		server.listConnectionsByStatus(ctx, authPayload.UserID, db.ConnectionStatus(req.Status))
+		return
+	}
+
 	connections, err := server.store.ListConnections(ctx, authPayload.UserID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -49,6 +83,47 @@ func (server *Server) listConnections(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, rsp)
 }
 
+// listConnectionsByStatus returns relationships with their direction and timestamps
+func (server *Server) listConnectionsByStatus(ctx *gin.Context, userID uuid.UUID, status db.ConnectionStatus) {
+	rows, err := server.store.ListConnectionsByStatus(ctx, db.ListConnectionsByStatusParams{
+		UserID: userID,
+		Status: status,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]connectionResponse, len(rows))
+	for i, r := range rows {
+		direction := "incoming"
+		if r.IsOutgoing {
+			direction = "outgoing"
+		}
+		var respondedAt *time.Time
+		if r.RespondedAt.Valid {
+			respondedAt = &r.RespondedAt.Time
+		}
+		rsp[i] = connectionResponse{
+			RequesterID: r.RequesterID,
+			TargetID:    r.TargetID,
+			Status:      r.Status,
+			Direction:   direction,
+			CreatedAt:   r.CreatedAt,
+			RespondedAt: respondedAt,
+			User: connectionUserResponse{
+				ID:        r.OtherUserID,
+				Username:  r.Username,
+				FullName:  r.FullName,
+				AvatarUrl: r.AvatarUrl.String,
+				Bio:       r.Bio.String,
+			},
+		}
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
 func (server *Server) listPendingRequests(ctx *gin.Context) {
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
@@ -77,6 +152,11 @@ type connectionRequest struct {
 	TargetUserID string `json:"target_user_id" binding:"required,uuid"`
 }
 
+// dailyConnectionRequestLimit caps the connection requests a user sends in 24 hours
+const dailyConnectionRequestLimit = 20
+
+var ErrConnectionRequestLimit = fmt.Errorf("daily connection request limit reached (%d/day)", dailyConnectionRequestLimit)
+
 func (server *Server) sendConnectionRequest(ctx *gin.Context) {
 	var req connectionRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -92,19 +172,18 @@ func (server *Server) sendConnectionRequest(ctx *gin.Context) {
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	if targetID == authPayload.UserID {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "cannot connect with yourself"})
+	if rejectSelfTarget(ctx, authPayload.UserID, targetID, "cannot connect with yourself") {
 		return
 	}
 
-	// Spam prevention: limit to 20 connection requests per day
+	// Spam prevention: limit connection requests per day
 	count, err := server.store.CountConnectionRequestsToday(ctx, authPayload.UserID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
-	if count >= 20 {
-		ctx.JSON(http.StatusTooManyRequests, gin.H{"error": "daily connection request limit reached (20/day)"})
+	if count >= dailyConnectionRequestLimit {
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(ErrConnectionRequestLimit))
 		return
 	}
 
@@ -147,6 +226,9 @@ func (server *Server) sendConnectionRequest(ctx *gin.Context) {
 		log.Error().Err(err).Msg("failed to create connection request notification")
 	}
 
+	server.invalidateRecommendationsCache(authPayload.UserID)
+	server.invalidateRecommendationsCache(targetID)
+
 	ctx.JSON(http.StatusCreated, conn)
 }
 
@@ -167,6 +249,9 @@ func (server *Server) updateConnection(ctx *gin.Context) {
 		return
 	}
 	authPayload := getAuthPayload(ctx)
+	if rejectSelfTarget(ctx, authPayload.UserID, requesterID, "cannot respond to your own connection request") {
+		return
+	}
 
 	conn, err := server.store.UpdateConnectionStatus(ctx, db.UpdateConnectionStatusParams{
 		RequesterID: requesterID,
@@ -178,8 +263,15 @@ func (server *Server) updateConnection(ctx *gin.Context) {
 		return
 	}
 
+	// Audience-gated stories depend on the relationship
+	server.invalidateUserFeedCache(requesterID)
+	server.invalidateUserFeedCache(authPayload.UserID)
+
 	// Create notification if connection was accepted
 	if req.Status == "accepted" {
+		server.events.Emit(ctx, analytics.EventConnectionAccepted, requesterID)
+		server.events.Emit(ctx, analytics.EventConnectionAccepted, authPayload.UserID)
+
 		accepter, err := server.store.GetUserByID(ctx, authPayload.UserID)
 		if err == nil {
 			_, err = server.store.CreateNotification(ctx, db.CreateNotificationParams{
@@ -207,6 +299,9 @@ func (server *Server) deleteConnection(ctx *gin.Context) {
 	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if rejectSelfTarget(ctx, authPayload.UserID, targetUserID, "cannot remove a connection with yourself") {
+		return
+	}
 
 	err = server.store.DeleteConnection(ctx, db.DeleteConnectionParams{
 		RequesterID: authPayload.UserID,
@@ -217,6 +312,9 @@ func (server *Server) deleteConnection(ctx *gin.Context) {
 		return
 	}
 
+	server.invalidateUserFeedCache(authPayload.UserID)
+	server.invalidateUserFeedCache(targetUserID)
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "connection deleted"})
 }
 
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/connection"
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	recommendationsCacheTTL = 10 * time.Minute
+	recommendationsLimit    = 20
+)
+
+func recommendationsCacheKey(userID uuid.UUID) string {
+	return util.RedisKey("recommendations:" + userID.String())
+}
+
+type connectionRecommendationResponse struct {
+	ID            uuid.UUID `json:"id"`
+	Username      string    `json:"username"`
+	FullName      string    `json:"full_name"`
+	AvatarUrl     string    `json:"avatar_url"`
+	MutualCount   int32     `json:"mutual_count"`
+	CrossingCount int32     `json:"crossing_count"`
+}
+
+// getConnectionRecommendations returns "people you may know", ranked by mutual
+// connections and how often paths crossed. Rankings are precomputed by the
+// recommendation worker; users it hasn't reached yet are computed on demand.
+func (server *Server) getConnectionRecommendations(ctx *gin.Context) {
+	authPayload := getAuthPayload(ctx)
+
+	cacheKey := recommendationsCacheKey(authPayload.UserID)
+	if cachedData, err := server.getCache(cacheKey); err == nil && cachedData != "" {
+		ctx.Header("X-Cache", "HIT")
+		ctx.Data(http.StatusOK, "application/json", []byte(cachedData))
+		return
+	}
+
+	stored, err := server.store.CountConnectionRecommendations(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if stored == 0 {
+		if err := connection.RefreshRecommendations(ctx, server.store, authPayload.UserID); err != nil {
+			log.Error().Err(err).Str("user_id", authPayload.UserID.String()).Msg("failed to compute connection recommendations")
+		}
+	}
+
+	recommendations, err := server.store.GetConnectionRecommendations(ctx, db.GetConnectionRecommendationsParams{
+		UserID:      authPayload.UserID,
+		ResultLimit: recommendationsLimit,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]connectionRecommendationResponse, len(recommendations))
+	for i, r := range recommendations {
+		rsp[i] = connectionRecommendationResponse{
+			ID:            r.ID,
+			Username:      r.Username,
+			FullName:      r.FullName,
+			AvatarUrl:     r.AvatarUrl.String,
+			MutualCount:   r.MutualCount,
+			CrossingCount: r.CrossingCount,
+		}
+	}
+
+	if responseJSON, err := json.Marshal(rsp); err == nil {
+		server.setCache(cacheKey, responseJSON, recommendationsCacheTTL)
+	}
+
+	ctx.Header("X-Cache", "MISS")
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestGetConnectionRecommendations(t *testing.T) {
+	userID := uuid.New()
+	candidate := db.GetConnectionRecommendationsRow{ID: uuid.New(), Username: "candidate", MutualCount: 2, CrossingCount: 1, Score: 7}
+	expectRead := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetConnectionRecommendations(gomock.Any(), db.GetConnectionRecommendationsParams{UserID: userID, ResultLimit: recommendationsLimit}).
+			Times(1).
+			Return([]db.GetConnectionRecommendationsRow{candidate}, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Precomputed",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CountConnectionRecommendations(gomock.Any(), userID).Times(1).Return(int64(12), nil)
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+				expectRead(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp []connectionRecommendationResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 1)
+				require.Equal(t, candidate.ID, rsp[0].ID)
+				require.Equal(t, int32(2), rsp[0].MutualCount)
+			},
+		},
+		{
+			// The worker hasn't reached this user yet
+			name: "ComputedOnDemand",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().CountConnectionRecommendations(gomock.Any(), userID).Times(1).Return(int64(0), nil),
+					store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).Return(nil),
+					store.EXPECT().GetConnectionRecommendations(gomock.Any(), gomock.Any()).Times(1).
+						Return([]db.GetConnectionRecommendationsRow{candidate}, nil),
+				)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp []connectionRecommendationResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 1)
+			},
+		},
+		{
+			// A failed refresh still answers with whatever is stored
+			name: "OnDemandFails",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CountConnectionRecommendations(gomock.Any(), userID).Times(1).Return(int64(0), nil)
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).Return(errors.New("deadlock"))
+				store.EXPECT().GetConnectionRecommendations(gomock.Any(), gomock.Any()).Times(1).
+					Return([]db.GetConnectionRecommendationsRow{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `[]`, recorder.Body.String())
+			},
+		},
+		{
+			name: "CountFails",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CountConnectionRecommendations(gomock.Any(), userID).Times(1).Return(int64(0), errors.New("boom"))
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetConnectionRecommendations(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			tc.checkResponse(t, getWithAuth(t, server, "/connections/recommendations", userID))
+		})
+	}
+}
+
+func TestGetConnectionRecommendationsCached(t *testing.T) {
+	userID := uuid.New()
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().CountConnectionRecommendations(gomock.Any(), userID).Times(1).Return(int64(1), nil)
+	store.EXPECT().GetConnectionRecommendations(gomock.Any(), gomock.Any()).Times(1).
+		Return([]db.GetConnectionRecommendationsRow{{ID: uuid.New()}}, nil)
+
+	server := newTestServer(t, store)
+	recorder := getWithAuth(t, server, "/connections/recommendations", userID)
+	require.Equal(t, "MISS", recorder.Header().Get("X-Cache"))
+
+	recorder = getWithAuth(t, server, "/connections/recommendations", userID)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "HIT", recorder.Header().Get("X-Cache"))
+
+	// Blocking drops the cached list, so the next read re-checks the store
+	store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+	recorder = postJSON(t, server, "/users/"+uuid.NewString()+"/block", nil, &userID)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	store.EXPECT().CountConnectionRecommendations(gomock.Any(), userID).Times(1).Return(int64(1), nil)
+	store.EXPECT().GetConnectionRecommendations(gomock.Any(), gomock.Any()).Times(1).
+		Return([]db.GetConnectionRecommendationsRow{}, nil)
+	recorder = getWithAuth(t, server, "/connections/recommendations", userID)
+	require.Equal(t, "MISS", recorder.Header().Get("X-Cache"))
+}
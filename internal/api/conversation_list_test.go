@@ -0,0 +1,83 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestMessagePreview(t *testing.T) {
+	require.Equal(t, "hi", messagePreview("hi", "image", true))
+	require.Equal(t, "", messagePreview("", "", false))
+	require.Equal(t, "📷 Photo", messagePreview("", "image", true))
+	require.Equal(t, "📷 Photo", messagePreview("", "", true))
+	require.Equal(t, "🎥 Video", messagePreview("", "video", true))
+	require.Equal(t, "🎤 Audio", messagePreview("", "audio", true))
+	require.Equal(t, "📎 Attachment", messagePreview("", "document", true))
+}
+
+func TestGetConversationListPreviews(t *testing.T) {
+	userID := uuid.New()
+	expiresAt := util.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetConversationList(gomock.Any(), uuid.NullUUID{UUID: userID, Valid: true}).
+		Times(1).
+		Return([]db.GetConversationListRow{
+			{
+				ID:                   uuid.New(),
+				LastMessageMediaType: sql.NullString{String: "image", Valid: true},
+				LastMessageIsMedia:   true,
+				LastMessageExpiresAt: util.NullTime{Time: expiresAt, Valid: true},
+				UnreadCount:          int64(2),
+			},
+			{ID: uuid.New(), LastMessage: "saved"},
+		}, nil)
+
+	server := newTestServer(t, store)
+	request, err := http.NewRequest(http.MethodGet, "/conversations", nil)
+	require.NoError(t, err)
+	accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	var rsp []struct {
+		LastMessage          string     `json:"last_message"`
+		LastMessageMediaType string     `json:"last_message_media_type"`
+		LastMessageExpiresAt *time.Time `json:"last_message_expires_at"`
+		IsMedia              bool       `json:"is_media"`
+		UnreadCount          int64      `json:"unread_count"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Len(t, rsp, 2)
+
+	// A photo sent without text gets a placeholder instead of a blank preview
+	require.Equal(t, "📷 Photo", rsp[0].LastMessage)
+	require.Equal(t, "image", rsp[0].LastMessageMediaType)
+	require.True(t, rsp[0].IsMedia)
+	require.NotNil(t, rsp[0].LastMessageExpiresAt)
+	require.True(t, expiresAt.Equal(*rsp[0].LastMessageExpiresAt))
+	require.Equal(t, int64(2), rsp[0].UnreadCount)
+
+	// A saved message never expires
+	require.Equal(t, "saved", rsp[1].LastMessage)
+	require.False(t, rsp[1].IsMedia)
+	require.Nil(t, rsp[1].LastMessageExpiresAt)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/repository/db"
+)
+
+var ErrConversationNotFound = errors.New("no such user or group")
+
+// muteConversation stops a direct chat or a group from raising the caller's
+// unread badge. :id is the other user or a group the caller belongs to.
+// Messages are still delivered, flagged as muted.
+func (server *Server) muteConversation(ctx *gin.Context) {
+	targetID, ok := parseUUIDParam(ctx, ctx.Param("id"), "conversation_id")
+	if !ok {
+		return
+	}
+	authPayload := getAuthPayload(ctx)
+	if rejectSelfTarget(ctx, authPayload.UserID, targetID, "cannot mute yourself") {
+		return
+	}
+	target := uuid.NullUUID{UUID: targetID, Valid: true}
+
+	isMember, err := server.store.CheckGroupMembership(ctx, db.CheckGroupMembershipParams{
+		GroupID: targetID,
+		UserID:  authPayload.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if isMember {
+		err = server.store.MuteGroupConversation(ctx, db.MuteGroupConversationParams{
+			UserID:  authPayload.UserID,
+			GroupID: target,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"conversation_id": targetID, "type": "group", "muted": true})
+		return
+	}
+
+	if _, err := server.store.GetUserByID(ctx, targetID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrConversationNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	err = server.store.MuteUserConversation(ctx, db.MuteUserConversationParams{
+		UserID:       authPayload.UserID,
+		TargetUserID: target,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	// The cached badge may include this chat's unread messages, which no longer count
+	server.invalidateUnreadCountCache(authPayload.UserID)
+
+	ctx.JSON(http.StatusOK, gin.H{"conversation_id": targetID, "type": "user", "muted": true})
+}
+
+// unmuteConversation lets a muted chat or group raise the unread badge again.
+// Unmuting a conversation that isn't muted succeeds.
+func (server *Server) unmuteConversation(ctx *gin.Context) {
+	targetID, ok := parseUUIDParam(ctx, ctx.Param("id"), "conversation_id")
+	if !ok {
+		return
+	}
+	authPayload := getAuthPayload(ctx)
+
+	err := server.store.UnmuteConversation(ctx, db.UnmuteConversationParams{
+		UserID:   authPayload.UserID,
+		TargetID: uuid.NullUUID{UUID: targetID, Valid: true},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.invalidateUnreadCountCache(authPayload.UserID)
+
+	ctx.JSON(http.StatusOK, gin.H{"conversation_id": targetID, "muted": false})
+}
+
+// isConversationMuted reports whether userID muted their direct chat with
+// otherID. Lookup failures count as not muted, so a message is never hidden
+// from the badge by mistake.
+func (server *Server) isConversationMuted(ctx context.Context, userID, otherID uuid.UUID) bool {
+	muted, err := server.store.IsConversationMuted(ctx, db.IsConversationMutedParams{
+		UserID:       userID,
+		TargetUserID: uuid.NullUUID{UUID: otherID, Valid: true},
+	})
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("failed to check conversation mute")
+		return false
+	}
+	return muted
+}
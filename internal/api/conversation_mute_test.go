@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/realtime"
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestMuteConversation(t *testing.T) {
+	userID, targetID := uuid.New(), uuid.New()
+	target := uuid.NullUUID{UUID: targetID, Valid: true}
+
+	testCases := []struct {
+		name          string
+		targetID      uuid.UUID
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, mr *miniredis.Miniredis)
+	}{
+		{
+			name:     "Group",
+			targetID: targetID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CheckGroupMembership(gomock.Any(), db.CheckGroupMembershipParams{GroupID: targetID, UserID: userID}).
+					Times(1).
+					Return(true, nil)
+				store.EXPECT().
+					MuteGroupConversation(gomock.Any(), db.MuteGroupConversationParams{UserID: userID, GroupID: target}).
+					Times(1).
+					Return(nil)
+				store.EXPECT().MuteUserConversation(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mr *miniredis.Miniredis) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, fmt.Sprintf(`{"conversation_id":%q,"type":"group","muted":true}`, targetID), recorder.Body.String())
+			},
+		},
+		{
+			name:     "User",
+			targetID: targetID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CheckGroupMembership(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), targetID).Times(1).Return(db.User{ID: targetID}, nil)
+				store.EXPECT().
+					MuteUserConversation(gomock.Any(), db.MuteUserConversationParams{UserID: userID, TargetUserID: target}).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mr *miniredis.Miniredis) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, fmt.Sprintf(`{"conversation_id":%q,"type":"user","muted":true}`, targetID), recorder.Body.String())
+				// The cached badge is dropped so it's recounted without this chat
+				require.False(t, mr.Exists(util.RedisKey("unread_count:"+userID.String())))
+			},
+		},
+		{
+			name:     "NotFound",
+			targetID: targetID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CheckGroupMembership(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), targetID).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().MuteUserConversation(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mr *miniredis.Miniredis) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "Self",
+			targetID: userID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CheckGroupMembership(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mr *miniredis.Miniredis) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			mr := miniredis.RunT(t)
+			server.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			require.NoError(t, mr.Set(util.RedisKey("unread_count:"+userID.String()), "3"))
+
+			recorder := postJSON(t, server, fmt.Sprintf("/conversations/%s/mute", tc.targetID), nil, &userID)
+			tc.checkResponse(t, recorder, mr)
+		})
+	}
+}
+
+func TestUnmuteConversation(t *testing.T) {
+	userID, targetID := uuid.New(), uuid.New()
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		UnmuteConversation(gomock.Any(), db.UnmuteConversationParams{
+			UserID:   userID,
+			TargetID: uuid.NullUUID{UUID: targetID, Valid: true},
+		}).
+		Times(1).
+		Return(nil)
+
+	server := newTestServer(t, store)
+	mr := miniredis.RunT(t)
+	server.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	require.NoError(t, mr.Set(util.RedisKey("unread_count:"+userID.String()), "3"))
+
+	request, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/conversations/%s/mute", targetID), nil)
+	require.NoError(t, err)
+	accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.False(t, mr.Exists(util.RedisKey("unread_count:"+userID.String())))
+}
+
+func TestDeliverDirectMessageToMutedReceiver(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	server.redis = rdb
+	server.hub = realtime.NewHub(rdb)
+
+	sender, receiver := uuid.New(), uuid.New()
+	store.EXPECT().
+		IsConversationMuted(gomock.Any(), db.IsConversationMutedParams{
+			UserID:       receiver,
+			TargetUserID: uuid.NullUUID{UUID: sender, Valid: true},
+		}).
+		Times(1).
+		Return(true, nil)
+
+	server.deliverMessage(db.Message{
+		ID:         uuid.New(),
+		SenderID:   sender,
+		ReceiverID: uuid.NullUUID{UUID: receiver, Valid: true},
+		Content:    "psst",
+	}, nil)
+
+	// Delivered, flagged as muted, without bumping the badge
+	require.False(t, mr.Exists(util.RedisKey("unread_count:"+receiver.String())))
+
+	entries, err := rdb.XRange(context.Background(), util.RedisKey("locolive:stream:routing"), "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, receiver.String(), entries[0].Values["target_user_id"])
+
+	var wsMsg realtime.WSMessage
+	require.NoError(t, json.Unmarshal([]byte(entries[0].Values["payload"].(string)), &wsMsg))
+	require.True(t, wsMsg.Muted)
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/repository/db"
+)
+
+var ErrNeverExpireRequiresPremium = errors.New("chats that never expire are a premium feature")
+
+type conversationSettingsResponse struct {
+	ConversationID       uuid.UUID  `json:"conversation_id"`
+	DefaultExpirySeconds int64      `json:"default_expiry_seconds"`
+	UpdatedBy            *uuid.UUID `json:"updated_by"`
+	UpdatedAt            *time.Time `json:"updated_at"`
+}
+
+func newConversationSettingsResponse(otherUserID uuid.UUID, settings db.ConversationSetting) conversationSettingsResponse {
+	return conversationSettingsResponse{
+		ConversationID:       otherUserID,
+		DefaultExpirySeconds: settings.DefaultExpirySeconds,
+		UpdatedBy:            &settings.UpdatedBy,
+		UpdatedAt:            &settings.UpdatedAt,
+	}
+}
+
+// getConversationSettings serves GET /conversations/:id/settings. A chat
+// nobody has configured reports the server-wide default expiry.
+func (server *Server) getConversationSettings(ctx *gin.Context) {
+	otherUserID, ok := parseUUIDParam(ctx, ctx.Param("id"), "user_id")
+	if !ok {
+		return
+	}
+	authPayload := getAuthPayload(ctx)
+
+	settings, err := server.store.GetConversationSettings(ctx, db.GetConversationSettingsParams{
+		UserID:      authPayload.UserID,
+		OtherUserID: otherUserID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusOK, conversationSettingsResponse{
+			ConversationID:       otherUserID,
+			DefaultExpirySeconds: int64(newMessageExpiryPolicy(server.config).Default / time.Second),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newConversationSettingsResponse(otherUserID, settings))
+}
+
+type updateConversationSettingsRequest struct {
+	// 0 means messages in the chat never expire
+	DefaultExpirySeconds *int64 `json:"default_expiry_seconds" binding:"required"`
+}
+
+// updateConversationSettings serves PUT /conversations/:id/settings. The
+// setting is shared by both participants, so the other one is told over WS.
+func (server *Server) updateConversationSettings(ctx *gin.Context) {
+	otherUserID, ok := parseUUIDParam(ctx, ctx.Param("id"), "user_id")
+	if !ok {
+		return
+	}
+	var req updateConversationSettingsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	authPayload := getAuthPayload(ctx)
+	if rejectSelfTarget(ctx, authPayload.UserID, otherUserID, "cannot configure a chat with yourself") {
+		return
+	}
+
+	if err := server.checkConnection(ctx, authPayload.UserID, otherUserID); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "You must be connected to this user to change chat settings."})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	seconds := *req.DefaultExpirySeconds
+	policy := newMessageExpiryPolicy(server.config)
+	if seconds == 0 {
+		// Messages that never expire are what saving a message buys, so the
+		// same tier rule applies
+		if server.config.MessageSaveRequiresPremium {
+			premium, err := server.isPremiumUser(ctx, authPayload.UserID)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+			if !premium {
+				ctx.JSON(http.StatusForbidden, errorResponse(ErrNeverExpireRequiresPremium))
+				return
+			}
+		}
+	} else {
+		premium := false
+		if seconds > int64(policy.Max/time.Second) {
+			isPremium, err := server.isPremiumUser(ctx, authPayload.UserID)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+			premium = isPremium
+		}
+		if _, err := policy.resolve(seconds, premium); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	settings, err := server.store.UpsertConversationSettings(ctx, db.UpsertConversationSettingsParams{
+		UserID:               authPayload.UserID,
+		OtherUserID:          otherUserID,
+		DefaultExpirySeconds: seconds,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Each side sees the chat keyed by the other participant
+	server.sendWSNotification(otherUserID, "conversation_settings_updated",
+		newConversationSettingsResponse(authPayload.UserID, settings))
+	server.sendWSNotification(authPayload.UserID, "conversation_settings_updated",
+		newConversationSettingsResponse(otherUserID, settings))
+
+	ctx.JSON(http.StatusOK, newConversationSettingsResponse(otherUserID, settings))
+}
+
+// conversationDefaultExpiry is the expiry for a direct message sent without
+// expires_in_seconds: the chat's own setting, or the policy default when it
+// has none. Zero means the message never expires.
+func (server *Server) conversationDefaultExpiry(ctx context.Context, senderID, receiverID uuid.UUID, policy messageExpiryPolicy) (time.Duration, error) {
+	settings, err := server.store.GetConversationSettings(ctx, db.GetConversationSettingsParams{
+		UserID:      senderID,
+		OtherUserID: receiverID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return policy.Default, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(settings.DefaultExpirySeconds) * time.Second, nil
+}
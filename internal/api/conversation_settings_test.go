@@ -0,0 +1,264 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/realtime"
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+// allowChat stubs the checks a connected pair who can message each other pass
+func allowChat(store *mockdb.MockStore, otherID uuid.UUID) {
+	store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).Times(2).Return(false, nil)
+	store.EXPECT().GetConnection(gomock.Any(), gomock.Any()).Times(1).Return(db.Connection{Status: "accepted"}, nil)
+	store.EXPECT().GetPrivacySettings(gomock.Any(), otherID).Times(1).Return(db.PrivacySetting{}, sql.ErrNoRows)
+}
+
+func TestUpdateConversationSettings(t *testing.T) {
+	userID, otherID := uuid.New(), uuid.New()
+	updatedAt := util.Now()
+
+	testCases := []struct {
+		name          string
+		otherID       uuid.UUID
+		body          gin.H
+		savePremium   bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, rdb *redis.Client)
+	}{
+		{
+			name:    "OK",
+			otherID: otherID,
+			body:    gin.H{"default_expiry_seconds": 3600},
+			buildStubs: func(store *mockdb.MockStore) {
+				allowChat(store, otherID)
+				store.EXPECT().
+					UpsertConversationSettings(gomock.Any(), db.UpsertConversationSettingsParams{
+						UserID:               userID,
+						OtherUserID:          otherID,
+						DefaultExpirySeconds: 3600,
+					}).
+					Times(1).
+					Return(db.ConversationSetting{DefaultExpirySeconds: 3600, UpdatedBy: userID, UpdatedAt: updatedAt}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rdb *redis.Client) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				require.JSONEq(t, fmt.Sprintf("%q", otherID), mustJSONField(t, recorder.Body.Bytes(), "conversation_id"))
+				require.JSONEq(t, "3600", mustJSONField(t, recorder.Body.Bytes(), "default_expiry_seconds"))
+
+				// The other participant hears about it, keyed by the caller
+				entries, err := rdb.XRange(context.Background(), util.RedisKey("locolive:stream:routing"), "-", "+").Result()
+				require.NoError(t, err)
+				require.Len(t, entries, 2)
+				require.Equal(t, otherID.String(), entries[0].Values["target_user_id"])
+
+				var wsMsg realtime.WSMessage
+				require.NoError(t, json.Unmarshal([]byte(entries[0].Values["payload"].(string)), &wsMsg))
+				require.Equal(t, "conversation_settings_updated", wsMsg.Type)
+				payload, err := json.Marshal(wsMsg.Payload)
+				require.NoError(t, err)
+				require.JSONEq(t, fmt.Sprintf("%q", userID), mustJSONField(t, payload, "conversation_id"))
+			},
+		},
+		{
+			name:        "NeverExpirePremium",
+			otherID:     otherID,
+			body:        gin.H{"default_expiry_seconds": 0},
+			savePremium: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				allowChat(store, otherID)
+				store.EXPECT().
+					GetUserByID(gomock.Any(), userID).
+					Times(1).
+					Return(db.User{ID: userID, IsPremium: sql.NullBool{Bool: true, Valid: true}}, nil)
+				store.EXPECT().
+					UpsertConversationSettings(gomock.Any(), db.UpsertConversationSettingsParams{UserID: userID, OtherUserID: otherID}).
+					Times(1).
+					Return(db.ConversationSetting{UpdatedBy: userID, UpdatedAt: updatedAt}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rdb *redis.Client) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				require.JSONEq(t, "0", mustJSONField(t, recorder.Body.Bytes(), "default_expiry_seconds"))
+			},
+		},
+		{
+			name:        "NeverExpireFree",
+			otherID:     otherID,
+			body:        gin.H{"default_expiry_seconds": 0},
+			savePremium: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				allowChat(store, otherID)
+				store.EXPECT().GetUserByID(gomock.Any(), userID).Times(1).Return(db.User{ID: userID}, nil)
+				store.EXPECT().UpsertConversationSettings(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rdb *redis.Client) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), ErrNeverExpireRequiresPremium.Error())
+			},
+		},
+		{
+			name:    "OutOfRange",
+			otherID: otherID,
+			body:    gin.H{"default_expiry_seconds": -5},
+			buildStubs: func(store *mockdb.MockStore) {
+				allowChat(store, otherID)
+				store.EXPECT().UpsertConversationSettings(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rdb *redis.Client) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:    "NotConnected",
+			otherID: otherID,
+			body:    gin.H{"default_expiry_seconds": 3600},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).Times(2).Return(false, nil)
+				store.EXPECT().GetConnection(gomock.Any(), gomock.Any()).Times(1).Return(db.Connection{}, sql.ErrNoRows)
+				store.EXPECT().UpsertConversationSettings(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rdb *redis.Client) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:    "MissingValue",
+			otherID: otherID,
+			body:    gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertConversationSettings(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rdb *redis.Client) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:    "Self",
+			otherID: userID,
+			body:    gin.H{"default_expiry_seconds": 3600},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertConversationSettings(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rdb *redis.Client) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.MessageSaveRequiresPremium = tc.savePremium
+			mr := miniredis.RunT(t)
+			rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { rdb.Close() })
+			server.redis = rdb
+			server.hub = realtime.NewHub(rdb)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/conversations/%s/settings", tc.otherID), bytes.NewReader(data))
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, rdb)
+		})
+	}
+}
+
+func TestSendMessageConversationDefaultExpiry(t *testing.T) {
+	userID, otherID := uuid.New(), uuid.New()
+	settingsKey := db.GetConversationSettingsParams{UserID: userID, OtherUserID: otherID}
+
+	testCases := []struct {
+		name        string
+		body        gin.H
+		buildStubs  func(store *mockdb.MockStore)
+		wantExpires time.Duration // 0 means the message never expires
+	}{
+		{
+			name: "NoSetting",
+			body: gin.H{"receiver_id": otherID, "content": "hi"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetConversationSettings(gomock.Any(), settingsKey).Times(1).Return(db.ConversationSetting{}, sql.ErrNoRows)
+			},
+			wantExpires: 24 * time.Hour,
+		},
+		{
+			name: "ChatDefault",
+			body: gin.H{"receiver_id": otherID, "content": "hi"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetConversationSettings(gomock.Any(), settingsKey).Times(1).Return(db.ConversationSetting{DefaultExpirySeconds: 3600}, nil)
+			},
+			wantExpires: time.Hour,
+		},
+		{
+			name: "NeverExpire",
+			body: gin.H{"receiver_id": otherID, "content": "hi"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetConversationSettings(gomock.Any(), settingsKey).Times(1).Return(db.ConversationSetting{}, nil)
+			},
+		},
+		{
+			// An explicit expiry wins over the chat's setting
+			name: "Explicit",
+			body: gin.H{"receiver_id": otherID, "content": "hi", "expires_in_seconds": 600},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetConversationSettings(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantExpires: 10 * time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			allowChat(store, otherID)
+			tc.buildStubs(store)
+			store.EXPECT().
+				CreateMessage(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, arg db.CreateMessageParams) (db.Message, error) {
+					require.Equal(t, tc.wantExpires > 0, arg.ExpiresAt.Valid)
+					if tc.wantExpires > 0 {
+						require.WithinDuration(t, util.Now().Add(tc.wantExpires), arg.ExpiresAt.Time, time.Minute)
+					}
+					return db.Message{ID: uuid.New(), SenderID: arg.SenderID, ReceiverID: arg.ReceiverID, Content: arg.Content, ExpiresAt: arg.ExpiresAt}, nil
+				})
+			store.EXPECT().IsConversationMuted(gomock.Any(), gomock.Any()).AnyTimes().Return(false, nil)
+
+			server := newTestServer(t, store)
+			recorder := postJSON(t, server, "/messages", tc.body, &userID)
+			require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+			if tc.wantExpires == 0 {
+				require.JSONEq(t, "null", mustJSONField(t, recorder.Body.Bytes(), "effective_expires_at"))
+			}
+		})
+	}
+}
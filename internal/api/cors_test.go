@@ -0,0 +1,123 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/config"
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func newCORSTestServer(t *testing.T, origins string) *Server {
+	ctrl := gomock.NewController(t)
+	server, err := NewServer(config.Config{
+		TokenSymmetricKey:  "12345678901234567890123456789012",
+		RedisAddress:       miniredis.RunT(t).Addr(),
+		CorsAllowedOrigins: origins,
+	}, mockdb.NewMockStore(ctrl), nil)
+	require.NoError(t, err)
+	return server
+}
+
+func TestCORS(t *testing.T) {
+	server := newCORSTestServer(t, " https://app.example.com/ ,https://admin.example.com")
+
+	testCases := []struct {
+		name          string
+		method        string
+		origin        string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "NoOrigin",
+			method: http.MethodGet,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+			},
+		},
+		{
+			name:   "Allowed",
+			method: http.MethodGet,
+			origin: "https://APP.example.com",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "https://APP.example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+				require.Equal(t, "true", recorder.Header().Get("Access-Control-Allow-Credentials"))
+				require.Equal(t, "Origin", recorder.Header().Get("Vary"))
+			},
+		},
+		{
+			name:   "Preflight",
+			method: http.MethodOptions,
+			origin: "https://admin.example.com",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+				require.Equal(t, "https://admin.example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+				require.Contains(t, recorder.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+				require.Contains(t, recorder.Header().Get("Access-Control-Allow-Methods"), http.MethodPatch)
+			},
+		},
+		{
+			name:   "NotAllowed",
+			method: http.MethodGet,
+			origin: "https://evil.example.com",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+			},
+		},
+		{
+			name:   "PreflightNotAllowed",
+			method: http.MethodOptions,
+			origin: "https://evil.example.com",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := http.NewRequest(tc.method, "/", nil)
+			require.NoError(t, err)
+			if tc.origin != "" {
+				request.Header.Set("Origin", tc.origin)
+			}
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestChatWebSocketRejectsOrigin(t *testing.T) {
+	server := newCORSTestServer(t, "https://app.example.com")
+
+	// Browsers don't preflight a WebSocket upgrade, so it's refused outright
+	request, err := http.NewRequest(http.MethodGet, "/ws/chat", nil)
+	require.NoError(t, err)
+	accessToken, _, err := server.tokenMaker.CreateToken("user", uuid.New(), time.Minute)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+
+	require.True(t, server.origins.allowsRequest(request))
+	request.Header.Set("Origin", "https://evil.example.com")
+	require.False(t, server.origins.allowsRequest(request))
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+}
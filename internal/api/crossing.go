@@ -10,6 +10,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
 )
 
 const crossingsCacheTTL = 5 * time.Minute
@@ -30,7 +31,7 @@ func (server *Server) getCrossings(ctx *gin.Context) {
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
 	// Try Redis cache first
-	cacheKey := "crossings:v3:" + authPayload.UserID.String()
+	cacheKey := util.RedisKey("crossings:v3:" + authPayload.UserID.String())
 	cachedData, err := server.redis.Get(context.Background(), cacheKey).Result()
 	if err == nil && cachedData != "" {
 		ctx.Header("X-Cache", "HIT")
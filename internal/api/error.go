@@ -1,7 +1,25 @@
 package api
 
-import "github.com/gin-gonic/gin"
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fieldError is a validation failure of one request field; errorResponse
+// names the field so clients can point at it
+type fieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *fieldError) Error() string { return e.Err.Error() }
+func (e *fieldError) Unwrap() error { return e.Err }
 
 func errorResponse(err error) gin.H {
+	var fe *fieldError
+	if errors.As(err, &fe) {
+		return gin.H{"error": err.Error(), "field": fe.Field}
+	}
 	return gin.H{"error": err.Error()}
 }
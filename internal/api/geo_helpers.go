@@ -1,6 +1,10 @@
 package api
 
-import "github.com/mmcloughlin/geohash"
+import (
+	"github.com/mmcloughlin/geohash"
+
+	"privacy-social-backend/internal/service/location"
+)
 
 // truncatedGeohash generates a geohash and truncates it to the specified precision
 func truncatedGeohash(lat, lng float64, precision int) string {
@@ -10,3 +14,9 @@ func truncatedGeohash(lat, lng float64, precision int) string {
 	}
 	return hash
 }
+
+// locationMinUsers is the k every aggregate location response (heatmap cells,
+// map clusters, nearby counts) must cover before it is shown
+func (server *Server) locationMinUsers() int {
+	return location.MinUsers(server.config.LocationMinUsers)
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 
 	"privacy-social-backend/internal/repository/db"
@@ -9,6 +10,12 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrGroupNotFound  = errors.New("group not found")
+	ErrNotGroupMember = errors.New("you are not a member of this group")
 )
 
 type createGroupRequest struct {
@@ -17,6 +24,18 @@ type createGroupRequest struct {
 	MemberIDs   []uuid.UUID `json:"member_ids"` // Initial members
 }
 
+type skippedGroupMember struct {
+	UserID uuid.UUID `json:"user_id"`
+	Reason string    `json:"reason"`
+}
+
+// createGroupResponse embeds the group so existing clients keep reading its fields unchanged
+type createGroupResponse struct {
+	db.Group
+	Added   []uuid.UUID          `json:"added"`
+	Skipped []skippedGroupMember `json:"skipped"`
+}
+
 func (server *Server) createGroup(ctx *gin.Context) {
 	var req createGroupRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -49,18 +68,72 @@ func (server *Server) createGroup(ctx *gin.Context) {
 		return
 	}
 
-	// Add other members
+	resp := createGroupResponse{
+		Group:   group,
+		Added:   []uuid.UUID{},
+		Skipped: []skippedGroupMember{},
+	}
+
+	// Add other members, reporting why any were left out
+	seen := map[uuid.UUID]bool{authPayload.UserID: true}
 	for _, memberID := range req.MemberIDs {
-		// Verify connection? (Optional but good practice)
-		// ...
-		server.store.AddGroupMember(ctx, db.AddGroupMemberParams{
+		if seen[memberID] {
+			resp.Skipped = append(resp.Skipped, skippedGroupMember{UserID: memberID, Reason: "duplicate"})
+			continue
+		}
+		seen[memberID] = true
+
+		if reason := server.groupMemberSkipReason(ctx, authPayload.UserID, memberID); reason != "" {
+			resp.Skipped = append(resp.Skipped, skippedGroupMember{UserID: memberID, Reason: reason})
+			continue
+		}
+
+		_, err := server.store.AddGroupMember(ctx, db.AddGroupMemberParams{
 			GroupID: group.ID,
 			UserID:  memberID,
 			Role:    "member",
 		})
+		if err != nil {
+			reason := "add_failed"
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+				reason = "user_not_found"
+			}
+			resp.Skipped = append(resp.Skipped, skippedGroupMember{UserID: memberID, Reason: reason})
+			continue
+		}
+		resp.Added = append(resp.Added, memberID)
 	}
 
-	ctx.JSON(http.StatusCreated, group)
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// groupMemberSkipReason returns why memberID can't be added by creatorID, or "" if they can
+func (server *Server) groupMemberSkipReason(ctx *gin.Context, creatorID, memberID uuid.UUID) string {
+	for _, pair := range [][2]uuid.UUID{{creatorID, memberID}, {memberID, creatorID}} {
+		blocked, err := server.store.IsUserBlocked(ctx, db.IsUserBlockedParams{BlockerID: pair[0], BlockedID: pair[1]})
+		if err != nil {
+			return "lookup_failed"
+		}
+		if blocked {
+			return "blocked"
+		}
+	}
+
+	if !server.config.GroupMembersRequireConnection {
+		return ""
+	}
+
+	conn, err := server.store.GetConnection(ctx, db.GetConnectionParams{RequesterID: creatorID, TargetID: memberID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "not_connected"
+		}
+		return "lookup_failed"
+	}
+	if conn.Status != db.ConnectionStatusAccepted {
+		return "not_connected"
+	}
+	return ""
 }
 
 func (server *Server) getMyGroups(ctx *gin.Context) {
@@ -82,14 +155,52 @@ func (server *Server) getGroupMessages(ctx *gin.Context) {
 		return
 	}
 
-	// Verify membership
-	// ... (Skipping for MVP speed, but important later)
+	authPayload := getAuthPayload(ctx)
+	if !server.requireGroupMember(ctx, groupID, authPayload.UserID) {
+		return
+	}
 
 	msgs, err := server.store.GetGroupMessages(ctx, uuid.NullUUID{UUID: groupID, Valid: true})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
+	for i := range msgs {
+		if msgs[i].MediaUrl.Valid {
+			msgs[i].MediaUrl.String = server.media.Resolve(ctx, msgs[i].MediaUrl.String)
+		}
+		msgs[i].Attachments = server.attachmentsWithMedia(ctx, messageAttachmentsFromJSON(msgs[i].Attachments))
+		msgs[i].ReplyTo = replyPreviewFromJSON(msgs[i].ReplyToMessageID, msgs[i].ReplyTo)
+	}
 
 	ctx.JSON(http.StatusOK, msgs)
 }
+
+// requireGroupMember checks userID belongs to the group, writing 404 when the
+// group doesn't exist and 403 when they aren't a member. It reports whether the
+// handler may go on.
+func (server *Server) requireGroupMember(ctx *gin.Context, groupID, userID uuid.UUID) bool {
+	isMember, err := server.store.CheckGroupMembership(ctx, db.CheckGroupMembershipParams{
+		GroupID: groupID,
+		UserID:  userID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if isMember {
+		return true
+	}
+
+	// Only non-members pay for telling a missing group apart
+	if _, err := server.store.GetGroupByID(ctx, groupID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrGroupNotFound))
+			return false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	ctx.JSON(http.StatusForbidden, errorResponse(ErrNotGroupMember))
+	return false
+}
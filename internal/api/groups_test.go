@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestGroupMembershipRequired(t *testing.T) {
+	userID := uuid.New()
+	groupID := uuid.New()
+	membership := db.CheckGroupMembershipParams{GroupID: groupID, UserID: userID}
+
+	sendBody := gin.H{"group_id": groupID, "content": "hi"}
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "ReadAsMember",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/groups/%s/messages", groupID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CheckGroupMembership(gomock.Any(), membership).Times(1).Return(true, nil)
+				store.EXPECT().GetGroupByID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetGroupMessages(gomock.Any(), uuid.NullUUID{UUID: groupID, Valid: true}).Times(1).Return([]db.GetGroupMessagesRow{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "ReadAsNonMember",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/groups/%s/messages", groupID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CheckGroupMembership(gomock.Any(), membership).Times(1).Return(false, nil)
+				store.EXPECT().GetGroupByID(gomock.Any(), groupID).Times(1).Return(db.Group{ID: groupID}, nil)
+				store.EXPECT().GetGroupMessages(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "ReadMissingGroup",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/groups/%s/messages", groupID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CheckGroupMembership(gomock.Any(), membership).Times(1).Return(false, nil)
+				store.EXPECT().GetGroupByID(gomock.Any(), groupID).Times(1).Return(db.Group{}, sql.ErrNoRows)
+				store.EXPECT().GetGroupMessages(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "SendAsNonMember",
+			method: http.MethodPost,
+			url:    "/messages",
+			body:   sendBody,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CheckGroupMembership(gomock.Any(), membership).Times(1).Return(false, nil)
+				store.EXPECT().GetGroupByID(gomock.Any(), groupID).Times(1).Return(db.Group{ID: groupID}, nil)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "SendToMissingGroup",
+			method: http.MethodPost,
+			url:    "/messages",
+			body:   sendBody,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CheckGroupMembership(gomock.Any(), membership).Times(1).Return(false, nil)
+				store.EXPECT().GetGroupByID(gomock.Any(), groupID).Times(1).Return(db.Group{}, sql.ErrNoRows)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds each dependency check so a hung database or Redis
+// fails the probe instead of stalling it
+const readinessTimeout = 2 * time.Second
+
+// healthCheck is the liveness probe: the process is up and serving
+func (server *Server) healthCheck(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readinessCheck is the readiness probe. It returns 503 naming the failed
+// dependency when the database or Redis can't be reached. Storage is only
+// reported, since local development runs without it.
+func (server *Server) readinessCheck(ctx *gin.Context) {
+	checks := gin.H{}
+	ready := true
+
+	check := func(name string, ping func(context.Context) error) {
+		pingCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+		defer cancel()
+		if err := ping(pingCtx); err != nil {
+			checks[name] = err.Error()
+			ready = false
+			return
+		}
+		checks[name] = "ok"
+	}
+	check("database", server.store.Ping)
+	check("redis", func(ctx context.Context) error {
+		return server.redis.Ping(ctx).Err()
+	})
+
+	if server.storage != nil {
+		checks["storage"] = "configured"
+	} else {
+		checks["storage"] = "not configured"
+	}
+
+	if !ready {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
+}
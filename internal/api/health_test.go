@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestHealthCheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().Ping(gomock.Any()).Times(0)
+	server := newTestServer(t, store)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestReadinessCheck(t *testing.T) {
+	testCases := []struct {
+		name       string
+		dbErr      error
+		redisDown  bool
+		wantCode   int
+		wantChecks map[string]string
+	}{
+		{
+			name:       "Ready",
+			wantCode:   http.StatusOK,
+			wantChecks: map[string]string{"database": "ok", "redis": "ok", "storage": "not configured"},
+		},
+		{
+			name:       "DatabaseDown",
+			dbErr:      errors.New("connection refused"),
+			wantCode:   http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"database": "connection refused", "redis": "ok", "storage": "not configured"},
+		},
+		{
+			name:      "RedisDown",
+			redisDown: true,
+			wantCode:  http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().Ping(gomock.Any()).Times(1).Return(tc.dbErr)
+
+			server := newTestServer(t, store)
+			mr := miniredis.RunT(t)
+			server.redis = redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+			if tc.redisDown {
+				mr.Close()
+			}
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ready", nil))
+			require.Equal(t, tc.wantCode, recorder.Code)
+
+			var rsp struct {
+				Checks map[string]string `json:"checks"`
+			}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			if tc.redisDown {
+				require.Equal(t, "ok", rsp.Checks["database"])
+				require.NotEqual(t, "ok", rsp.Checks["redis"])
+				return
+			}
+			require.Equal(t, tc.wantChecks, rsp.Checks)
+		})
+	}
+}
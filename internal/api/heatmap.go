@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mmcloughlin/geohash"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/location"
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// heatmapMaxSpan is the widest box in degrees (~220km) a request may cover on either axis
+	heatmapMaxSpan = 2.0
+	// heatmapCellsPerSide is roughly how many cells a box is split into along its longer side
+	heatmapCellsPerSide = 50
+	// heatmapFinestPrecision (~1.2km x 0.6km) is the finest a heatmap ever gets, however small the box
+	heatmapFinestPrecision = 6
+	// heatmapMinPrecision (~39km x 20km) is as far up as sparse cells are merged before they're dropped
+	heatmapMinPrecision = 4
+	// heatmapMaxPoints caps the cells in a response; busier boxes are coarsened
+	// until they fit. A box within heatmapMaxSpan has far fewer
+	// heatmapMinPrecision cells, so coarsening always gets there.
+	heatmapMaxPoints = 500
+	// heatmapDefaultWindow is how far back a heatmap looks without ?since=
+	heatmapDefaultWindow = time.Hour
+	// heatmapCacheTTL is short next to the shortest window a heatmap covers
+	heatmapCacheTTL = 5 * time.Minute
+)
+
+type getHeatmapRequest struct {
+	North float64 `form:"north" binding:"required,min=-90,max=90"`
+	South float64 `form:"south" binding:"required,min=-90,max=90"`
+	East  float64 `form:"east" binding:"required,min=-180,max=180"`
+	West  float64 `form:"west" binding:"required,min=-180,max=180"`
+	// Since is a duration such as 30m or 6h; defaults to heatmapDefaultWindow
+	Since string `form:"since"`
+}
+
+type heatmapPoint struct {
+	Geohash   string  `json:"geohash"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Weight    int64   `json:"weight"`
+}
+
+// geohashCellWidth is the width in degrees of longitude of a geohash cell
+func geohashCellWidth(precision int) float64 {
+	lngBits := (5*precision + 1) / 2
+	return 360 / float64(uint64(1)<<lngBits)
+}
+
+// heatmapPrecision picks the finest geohash precision that keeps a box of span
+// degrees within heatmapCellsPerSide cells
+func heatmapPrecision(span float64) int {
+	for precision := heatmapFinestPrecision; precision > heatmapMinPrecision; precision-- {
+		if geohashCellWidth(precision)*heatmapCellsPerSide >= span {
+			return precision
+		}
+	}
+	return heatmapMinPrecision
+}
+
+// heatmapWindow parses ?since= and rounds it up to whole location time buckets,
+// so nearby windows share a cache entry. Windows run from one bucket up to the
+// location TTL, past which there is nothing left to count.
+func heatmapWindow(since string) (time.Duration, error) {
+	if since == "" {
+		return heatmapDefaultWindow, nil
+	}
+	window, err := time.ParseDuration(since)
+	if err != nil || window <= 0 || window > locationTTL {
+		return 0, fmt.Errorf("since must be a duration between %s and %s", bucketDuration, locationTTL)
+	}
+	if rem := window % bucketDuration; rem != 0 {
+		window += bucketDuration - rem
+	}
+	return window, nil
+}
+
+// snapHeatmapBox widens the box outwards to whole heatmapMinPrecision cells, so
+// nearby boxes share a cache entry and every cell, merged or not, is counted
+// whole rather than cut down by a box edge
+func snapHeatmapBox(req getHeatmapRequest, precision int) db.GetHeatmapCellsParams {
+	sw := geohash.BoundingBox(geohash.EncodeWithPrecision(req.South, req.West, heatmapMinPrecision))
+	ne := geohash.BoundingBox(geohash.EncodeWithPrecision(req.North, req.East, heatmapMinPrecision))
+	return db.GetHeatmapCellsParams{
+		Precision: int32(precision),
+		North:     ne.MaxLat,
+		South:     sw.MinLat,
+		East:      ne.MaxLng,
+		West:      sw.MinLng,
+	}
+}
+
+// getHeatmap serves GET /location/heatmap: how many distinct users pinged from
+// each geohash cell of a bounding box within a recent window (the last hour by
+// default). Cells with fewer than LOCATION_MIN_USERS users are merged into
+// coarser cells or dropped, at most heatmapMaxPoints cells are returned, and
+// results are shared by everyone asking for the same snapped box and window.
+func (server *Server) getHeatmap(ctx *gin.Context) {
+	var req getHeatmapRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.North <= req.South {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "north must be greater than south"})
+		return
+	}
+	if req.East <= req.West {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "east must be greater than west"})
+		return
+	}
+	span := math.Max(req.North-req.South, req.East-req.West)
+	if span > heatmapMaxSpan {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("bounding box may span at most %g degrees", heatmapMaxSpan)})
+		return
+	}
+	window, err := heatmapWindow(req.Since)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := snapHeatmapBox(req, heatmapPrecision(span))
+	minUsers := server.locationMinUsers()
+
+	cacheKey := util.RedisKey(fmt.Sprintf("heatmap:%d:%.4f:%.4f:%.4f:%.4f:%d:%d", arg.Precision, arg.North, arg.South, arg.East, arg.West, minUsers, int64(window/time.Minute)))
+	cachedData, err := server.redis.Get(ctx, cacheKey).Result()
+	if err == nil && cachedData != "" {
+		ctx.Header("X-Cache", "HIT")
+		ctx.Data(http.StatusOK, "application/json", []byte(cachedData))
+		return
+	}
+
+	arg.Since = util.Now().Add(-window)
+	rows, err := server.store.GetHeatmapCells(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	cells := make([]location.Cell[struct{}], len(rows))
+	for i, row := range rows {
+		cells[i] = location.Cell[struct{}]{Geohash: row.Cell, Members: row.UserIds}
+	}
+	cells = location.AnonymizeCells(cells, minUsers, heatmapMinPrecision)
+	cells = location.CoarsenCells(cells, heatmapMaxPoints, heatmapMinPrecision)
+
+	rsp := make([]heatmapPoint, len(cells))
+	for i, cell := range cells {
+		lat, lng := geohash.DecodeCenter(cell.Geohash)
+		rsp[i] = heatmapPoint{
+			Geohash:   cell.Geohash,
+			Latitude:  lat,
+			Longitude: lng,
+			Weight:    int64(len(cell.Members)),
+		}
+	}
+
+	responseJSON, _ := json.Marshal(rsp)
+	server.redis.Set(ctx, cacheKey, responseJSON, heatmapCacheTTL)
+
+	ctx.Header("X-Cache", "MISS")
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcloughlin/geohash"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestHeatmapPrecision(t *testing.T) {
+	require.Equal(t, heatmapFinestPrecision, heatmapPrecision(0.01))
+	require.Equal(t, heatmapFinestPrecision, heatmapPrecision(0.5))
+	require.Equal(t, 5, heatmapPrecision(1))
+	require.Equal(t, 5, heatmapPrecision(heatmapMaxSpan))
+}
+
+func TestSnapHeatmapBox(t *testing.T) {
+	req := getHeatmapRequest{North: 12.9731, South: 12.9702, East: 77.5961, West: 77.5933}
+	arg := snapHeatmapBox(req, heatmapFinestPrecision)
+	require.Equal(t, int32(heatmapFinestPrecision), arg.Precision)
+
+	// The box grows to the whole precision-4 cell around it
+	cell := geohash.BoundingBox(geohash.EncodeWithPrecision(req.North, req.East, heatmapMinPrecision))
+	require.Equal(t, cell.MaxLat, arg.North)
+	require.Equal(t, cell.MinLat, arg.South)
+	require.Equal(t, cell.MaxLng, arg.East)
+	require.Equal(t, cell.MinLng, arg.West)
+}
+
+func TestHeatmapWindow(t *testing.T) {
+	window, err := heatmapWindow("")
+	require.NoError(t, err)
+	require.Equal(t, heatmapDefaultWindow, window)
+
+	// Rounded up to whole time buckets
+	window, err = heatmapWindow("25m")
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Minute, window)
+
+	window, err = heatmapWindow("24h")
+	require.NoError(t, err)
+	require.Equal(t, locationTTL, window)
+
+	for _, since := range []string{"0s", "-1h", "25h", "6"} {
+		_, err := heatmapWindow(since)
+		require.Error(t, err, since)
+	}
+}
+
+func TestGetHeatmapSince(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetHeatmapCells(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.GetHeatmapCellsParams) ([]db.GetHeatmapCellsRow, error) {
+			require.WithinDuration(t, util.Now().Add(-6*time.Hour), arg.Since, time.Minute)
+			return nil, nil
+		})
+
+	server := newTestServer(t, store)
+	request, err := http.NewRequest(http.MethodGet, "/location/heatmap?north=12.98&south=12.97&east=77.60&west=77.59&since=6h", nil)
+	require.NoError(t, err)
+
+	accessToken, _, err := server.tokenMaker.CreateToken("user", uuid.New(), time.Minute)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	require.JSONEq(t, "[]", recorder.Body.String())
+}
+
+func TestGetHeatmapRejectsBadBoxes(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"NoBox", ""},
+		{"Inverted", "?north=12&south=13&east=78&west=77"},
+		{"TooLarge", "?north=40&south=10&east=90&west=60"},
+		{"BadSince", "?north=13&south=12&east=78&west=77&since=yesterday"},
+		{"SinceTooLong", "?north=13&south=12&east=78&west=77&since=48h"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetHeatmapCells(gomock.Any(), gomock.Any()).Times(0)
+
+			server := newTestServer(t, store)
+			request, err := http.NewRequest(http.MethodGet, "/location/heatmap"+tc.query, nil)
+			require.NoError(t, err)
+
+			accessToken, _, err := server.tokenMaker.CreateToken("user", uuid.New(), time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		})
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
 )
 
 // getAuthPayload extracts the authenticated user's payload from the context
@@ -27,6 +30,30 @@ func parseUUIDParam(ctx *gin.Context, value string, paramName string) (uuid.UUID
 	return id, true
 }
 
+// rejectSelfTarget responds 400 when an action targets the caller's own account.
+// Returns true if the request was rejected.
+func rejectSelfTarget(ctx *gin.Context, selfID, targetID uuid.UUID, message string) bool {
+	if selfID != targetID {
+		return false
+	}
+	ctx.JSON(http.StatusBadRequest, gin.H{"error": message})
+	return true
+}
+
+// rejectInvalidOption writes 400 listing the allowed values when value is set
+// and isn't one of them. It reports whether it did.
+func rejectInvalidOption(ctx *gin.Context, field string, value *string, allowed []string) bool {
+	if value == nil || slices.Contains(allowed, *value) {
+		return false
+	}
+	ctx.JSON(http.StatusBadRequest, gin.H{
+		"error":   fmt.Sprintf("%s must be one of: %s", field, strings.Join(allowed, ", ")),
+		"field":   field,
+		"allowed": allowed,
+	})
+	return true
+}
+
 // toNullString converts a string to a sql.NullString
 func toNullString(s string) sql.NullString {
 	return sql.NullString{
@@ -35,6 +62,14 @@ func toNullString(s string) sql.NullString {
 	}
 }
 
+// strPtrToNullString converts a *string to a sql.NullString, null when nil
+func strPtrToNullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
 // nullStringToStrPtr converts a sql.NullString to a *string
 func nullStringToStrPtr(ns sql.NullString) *string {
 	if ns.Valid {
@@ -42,3 +77,19 @@ func nullStringToStrPtr(ns sql.NullString) *string {
 	}
 	return nil
 }
+
+// validateReaction checks emoji is a single emoji and, when configured, one of the allowed reactions
+func (server *Server) validateReaction(emoji string) error {
+	if err := util.ValidateReaction(emoji); err != nil {
+		return err
+	}
+	if len(server.config.AllowedReactions) == 0 {
+		return nil
+	}
+	for _, allowed := range server.config.AllowedReactions {
+		if emoji == strings.TrimSpace(allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("reaction %q is not allowed", emoji)
+}
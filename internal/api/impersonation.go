@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/repository/db"
+)
+
+const (
+	// Audit log actions for admin impersonation
+	auditActionImpersonateStart   = "impersonate.start"
+	auditActionImpersonateRequest = "impersonate.request"
+
+	// impersonationHeader flags every response served with an impersonation token
+	impersonationHeader = "X-Impersonation"
+)
+
+var (
+	ErrImpersonationReadOnly   = errors.New("impersonation tokens are read-only")
+	ErrImpersonationNotAllowed = errors.New("only admins can impersonate regular users")
+)
+
+// impersonationBlockedPaths are GET routes that still act as the user, so they are
+// off limits to impersonation tokens even though they are reads
+var impersonationBlockedPaths = map[string]bool{
+	"/ws/chat": true,
+}
+
+// recordAdminAudit writes an audit entry; failures are logged but never fail the request
+func (server *Server) recordAdminAudit(params db.CreateAdminAuditLogParams) {
+	if err := server.store.CreateAdminAuditLog(context.Background(), params); err != nil {
+		log.Error().Err(err).Str("action", params.Action).Msg("failed to write admin audit log")
+	}
+}
+
+// impersonationMiddleware keeps impersonation tokens to read-only requests and
+// audits every request made with one
+func impersonationMiddleware(server *Server) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload := getAuthPayload(ctx)
+		if !payload.IsImpersonation() {
+			ctx.Next()
+			return
+		}
+
+		ctx.Header(impersonationHeader, "read-only")
+
+		method := ctx.Request.Method
+		readOnly := method == http.MethodGet || method == http.MethodHead
+		if !readOnly || impersonationBlockedPaths[ctx.FullPath()] {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(ErrImpersonationReadOnly))
+		} else {
+			ctx.Next()
+		}
+
+		server.recordAdminAudit(db.CreateAdminAuditLogParams{
+			AdminID:      payload.ImpersonatorID,
+			TargetUserID: uuid.NullUUID{UUID: payload.UserID, Valid: true},
+			Action:       auditActionImpersonateRequest,
+			Method:       method,
+			Path:         ctx.Request.URL.RequestURI(),
+			StatusCode:   int32(ctx.Writer.Status()),
+		})
+	}
+}
+
+type impersonateUserResponse struct {
+	AccessToken string       `json:"access_token"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	ReadOnly    bool         `json:"read_only"`
+	User        userResponse `json:"user"`
+}
+
+// Admin: Mint a short-lived read-only token to view the app as a user
+func (server *Server) impersonateUser(ctx *gin.Context) {
+	targetID, ok := parseUUIDParam(ctx, ctx.Param("id"), "id")
+	if !ok {
+		return
+	}
+
+	authPayload := getAuthPayload(ctx)
+	if rejectSelfTarget(ctx, authPayload.UserID, targetID, "cannot impersonate yourself") {
+		return
+	}
+
+	// Moderators can use the admin panel, but viewing as a user is admin-only
+	admin, err := server.store.GetUserByID(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if admin.Role != "admin" {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrImpersonationNotAllowed))
+		return
+	}
+
+	target, err := server.store.GetUserByID(ctx, targetID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if target.Role != "user" {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrImpersonationNotAllowed))
+		return
+	}
+
+	accessToken, payload, err := server.tokenMaker.CreateImpersonationToken(
+		target.Username,
+		target.ID,
+		admin.ID,
+		server.config.ImpersonationTokenDuration,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.recordAdminAudit(db.CreateAdminAuditLogParams{
+		AdminID:      admin.ID,
+		TargetUserID: uuid.NullUUID{UUID: target.ID, Valid: true},
+		Action:       auditActionImpersonateStart,
+		Method:       ctx.Request.Method,
+		Path:         ctx.Request.URL.RequestURI(),
+		StatusCode:   http.StatusCreated,
+	})
+
+	ctx.JSON(http.StatusCreated, impersonateUserResponse{
+		AccessToken: accessToken,
+		ExpiresAt:   payload.ExpiredAt,
+		ReadOnly:    true,
+		User:        newUserResponse(target),
+	})
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestImpersonateUser(t *testing.T) {
+	adminID, targetID := uuid.New(), uuid.New()
+	asRole := func(store *mockdb.MockStore, role db.UserRole) {
+		// Once for adminMiddleware, once for the handler's admin-only check
+		store.EXPECT().GetUserByID(gomock.Any(), adminID).AnyTimes().Return(db.User{ID: adminID, Role: role}, nil)
+	}
+	path := fmt.Sprintf("/admin/users/%s/impersonate", targetID)
+
+	testCases := []struct {
+		name          string
+		path          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, actions []string)
+	}{
+		{
+			name: "OK",
+			path: path,
+			buildStubs: func(store *mockdb.MockStore) {
+				asRole(store, db.UserRoleAdmin)
+				store.EXPECT().GetUserByID(gomock.Any(), targetID).Times(1).
+					Return(db.User{ID: targetID, Username: "target", Role: db.UserRoleUser}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, actions []string) {
+				require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+				var rsp impersonateUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.True(t, rsp.ReadOnly)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.Contains(t, actions, auditActionImpersonateStart)
+			},
+		},
+		{
+			name: "TargetIsAdmin",
+			path: path,
+			buildStubs: func(store *mockdb.MockStore) {
+				asRole(store, db.UserRoleAdmin)
+				store.EXPECT().GetUserByID(gomock.Any(), targetID).Times(1).
+					Return(db.User{ID: targetID, Role: db.UserRoleAdmin}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, actions []string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.NotContains(t, actions, auditActionImpersonateStart)
+			},
+		},
+		{
+			name: "TargetIsModerator",
+			path: path,
+			buildStubs: func(store *mockdb.MockStore) {
+				asRole(store, db.UserRoleAdmin)
+				store.EXPECT().GetUserByID(gomock.Any(), targetID).Times(1).
+					Return(db.User{ID: targetID, Role: db.UserRoleModerator}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, actions []string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.NotContains(t, actions, auditActionImpersonateStart)
+			},
+		},
+		{
+			name: "CallerIsModerator",
+			path: path,
+			buildStubs: func(store *mockdb.MockStore) {
+				asRole(store, db.UserRoleModerator)
+				store.EXPECT().GetUserByID(gomock.Any(), targetID).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, actions []string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.NotContains(t, actions, auditActionImpersonateStart)
+			},
+		},
+		{
+			name: "Self",
+			path: fmt.Sprintf("/admin/users/%s/impersonate", adminID),
+			buildStubs: func(store *mockdb.MockStore) {
+				asRole(store, db.UserRoleAdmin)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, actions []string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.NotContains(t, actions, auditActionImpersonateStart)
+			},
+		},
+		{
+			name: "TargetNotFound",
+			path: path,
+			buildStubs: func(store *mockdb.MockStore) {
+				asRole(store, db.UserRoleAdmin)
+				store.EXPECT().GetUserByID(gomock.Any(), targetID).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, actions []string) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.NotContains(t, actions, auditActionImpersonateStart)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			var actions []string
+			store.EXPECT().CreateAdminAuditLog(gomock.Any(), gomock.Any()).AnyTimes().
+				Do(func(_ context.Context, arg db.CreateAdminAuditLogParams) {
+					actions = append(actions, arg.Action)
+				}).
+				Return(nil)
+
+			server := newTestServer(t, store)
+			request, err := http.NewRequest(http.MethodPost, tc.path, nil)
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("admin", adminID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, actions)
+		})
+	}
+}
+
+func TestImpersonationMiddleware(t *testing.T) {
+	adminID, userID := uuid.New(), uuid.New()
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		buildStubs func(store *mockdb.MockStore)
+		wantStatus int
+	}{
+		{
+			name:   "ReadAllowed",
+			method: http.MethodGet,
+			path:   "/notifications/unread-count",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CountUnreadNotifications(gomock.Any(), userID).Times(1).Return(int64(2), nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "WriteRejected",
+			method: http.MethodPost,
+			path:   "/connections/update",
+			body:   fmt.Sprintf(`{"requester_id":%q,"status":"accepted"}`, uuid.New()),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateConnectionStatus(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "DeleteRejected",
+			method: http.MethodDelete,
+			path:   fmt.Sprintf("/connections/%s", uuid.New()),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteConnection(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			// A GET, but the socket sends messages as the user
+			name:       "ChatSocketRefused",
+			method:     http.MethodGet,
+			path:       "/ws/chat",
+			buildStubs: func(store *mockdb.MockStore) {},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			// Every request is audited against the admin, allowed or not
+			store.EXPECT().
+				CreateAdminAuditLog(gomock.Any(), db.CreateAdminAuditLogParams{
+					AdminID:      adminID,
+					TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+					Action:       auditActionImpersonateRequest,
+					Method:       tc.method,
+					Path:         tc.path,
+					StatusCode:   int32(tc.wantStatus),
+				}).
+				Times(1).
+				Return(nil)
+
+			server := newTestServer(t, store)
+			request, err := http.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateImpersonationToken("user", userID, adminID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantStatus, recorder.Code, recorder.Body.String())
+			require.Equal(t, "read-only", recorder.Header().Get(impersonationHeader))
+		})
+	}
+
+	t.Run("RegularTokenNotAudited", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().CountUnreadNotifications(gomock.Any(), userID).Times(1).Return(int64(0), nil)
+		store.EXPECT().CreateAdminAuditLog(gomock.Any(), gomock.Any()).Times(0)
+
+		server := newTestServer(t, store)
+		recorder := getWithAuth(t, server, "/notifications/unread-count", userID)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Empty(t, recorder.Header().Get(impersonationHeader))
+	})
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"database/sql"
 	"net/http"
 	"time"
 
@@ -10,7 +9,9 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/safety"
 	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
 )
 
 const (
@@ -22,6 +23,8 @@ const (
 type updateLocationRequest struct {
 	Latitude  float64 `json:"latitude" binding:"required,min=-90,max=90"`
 	Longitude float64 `json:"longitude" binding:"required,min=-180,max=180"`
+	// Client carries device hints (mock provider, spoofing apps) for the safety checks
+	Client safety.ClientHints `json:"client"`
 }
 
 func (server *Server) updateLocation(ctx *gin.Context) {
@@ -36,12 +39,12 @@ func (server *Server) updateLocation(ctx *gin.Context) {
 	// Ghost Mode Logic
 	user, userErr := server.store.GetUserByID(ctx, authPayload.UserID)
 	if userErr == nil && user.IsGhostMode {
-		if user.GhostModeExpiresAt.Valid && time.Now().After(user.GhostModeExpiresAt.Time) {
+		if util.IsExpired(user.GhostModeExpiresAt, util.Now()) {
 			// Auto Disable
 			server.store.ToggleGhostMode(ctx, db.ToggleGhostModeParams{
 				ID:                 authPayload.UserID,
 				IsGhostMode:        false,
-				GhostModeExpiresAt: sql.NullTime{},
+				GhostModeExpiresAt: util.NullTime{},
 			})
 		} else {
 			// Ghost Mode Active: Do not update location
@@ -57,22 +60,22 @@ func (server *Server) updateLocation(ctx *gin.Context) {
 	}
 
 	// Safety Check: Fake GPS
-	val := server.safety.ValidateUserMovement(ctx, authPayload.UserID.String(), req.Latitude, req.Longitude)
+	val := server.safety.Evaluate(ctx, safety.Event{
+		UserID: authPayload.UserID.String(),
+		Action: safety.ActionLocationUpdate,
+		Lat:    req.Latitude,
+		Lng:    req.Longitude,
+		Client: req.Client,
+	})
 	if !val.Allowed {
-		if val.ShouldBan {
-			server.store.BanUser(ctx, db.BanUserParams{
-				ID:             authPayload.UserID,
-				IsShadowBanned: true,
-			})
-			log.Warn().Str("user_id", authPayload.UserID.String()).Msg("User shadow-banned for fake GPS")
-		}
+		server.enforcer.Apply(ctx, authPayload.UserID, safety.ActionLocationUpdate, val)
 		// Return success to maintain illusion, but do NOT save the fake location
 		ctx.JSON(http.StatusOK, gin.H{"status": "updated"})
 		return
 	}
 
 	// Privacy: Time Bucket
-	now := time.Now().UTC()
+	now := util.Now()
 	bucketTime := now.Truncate(bucketDuration)
 
 	// Privacy: Expiry
@@ -106,30 +109,3 @@ func (server *Server) updateLocation(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{"status": "updated"})
 }
-
-type heatmapPoint struct {
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
-	Weight    int64   `json:"weight"`
-}
-
-func (server *Server) getHeatmap(ctx *gin.Context) {
-	data, err := server.store.GetHeatmapData(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
-	}
-
-	rsp := make([]heatmapPoint, len(data))
-	for i, d := range data {
-		lat, _ := d.Latitude.(float64)
-		lng, _ := d.Longitude.(float64)
-		rsp[i] = heatmapPoint{
-			Latitude:  lat,
-			Longitude: lng,
-			Weight:    d.Weight,
-		}
-	}
-
-	ctx.JSON(http.StatusOK, rsp)
-}
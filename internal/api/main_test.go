@@ -3,6 +3,8 @@ package api
 import (
 	"testing"
 
+	"github.com/alicebob/miniredis/v2"
+
 	"privacy-social-backend/internal/config"
 	"privacy-social-backend/internal/repository"
 
@@ -14,7 +16,7 @@ func newTestServer(t *testing.T, store repository.Store) *Server {
 		TokenSymmetricKey:    "12345678901234567890123456789012",
 		AccessTokenDuration:  15 * 60 * 1000000000,      // 15 minutes in nanoseconds
 		RefreshTokenDuration: 24 * 60 * 60 * 1000000000, // 24 hours
-		RedisAddress:         "localhost:6379",
+		RedisAddress:         miniredis.RunT(t).Addr(),
 	}
 
 	server, err := NewServer(config, store, nil)
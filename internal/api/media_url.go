@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/storage"
+)
+
+// minMediaURLExpiry keeps presigned URLs alive longer than any response cache
+// that embeds them (feeds, chat history) can serve them
+const minMediaURLExpiry = 2 * chatCacheTTL
+
+// mediaURLExpiry is the configured presigned URL lifetime, raised to the minimum
+func mediaURLExpiry(configured time.Duration) time.Duration {
+	if configured < minMediaURLExpiry {
+		return minMediaURLExpiry
+	}
+	return configured
+}
+
+// isMediaURL reports whether raw is media we can serve: an upload path, a
+// private object reference or an absolute http(s) URL
+func isMediaURL(raw string) bool {
+	if _, ok := storage.PrivateKey(raw); ok || strings.HasPrefix(raw, "/uploads/") {
+		return true
+	}
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// resolveStoryMedia presigns a story response's media when it is stored privately
+func (server *Server) resolveStoryMedia(ctx context.Context, rsp *StoryResponse) {
+	rsp.MediaURL = server.media.Resolve(ctx, rsp.MediaURL)
+	if rsp.ThumbnailURL != nil {
+		thumbnail := server.media.Resolve(ctx, *rsp.ThumbnailURL)
+		rsp.ThumbnailURL = &thumbnail
+	}
+}
+
+// messageWithMedia returns msg with its media presigned when it is stored privately.
+// Only the copy sent to clients changes; rows keep the reference.
+func (server *Server) messageWithMedia(ctx context.Context, msg db.Message) db.Message {
+	if msg.MediaUrl.Valid {
+		msg.MediaUrl.String = server.media.Resolve(ctx, msg.MediaUrl.String)
+	}
+	return msg
+}
+
+// scheduledWithMedia is messageWithMedia for messages that haven't been sent yet
+func (server *Server) scheduledWithMedia(ctx context.Context, scheduled db.ScheduledMessage) db.ScheduledMessage {
+	if scheduled.MediaUrl.Valid {
+		scheduled.MediaUrl.String = server.media.Resolve(ctx, scheduled.MediaUrl.String)
+	}
+	return scheduled
+}
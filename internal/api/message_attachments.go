@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"privacy-social-backend/internal/repository/db"
+)
+
+// maxMessageAttachments caps the media items one message may carry
+const maxMessageAttachments = 10
+
+// messageAttachmentTypes are the media types a message attachment may have
+var messageAttachmentTypes = map[string]bool{
+	"image": true,
+	"video": true,
+	"audio": true,
+	"file":  true,
+}
+
+var (
+	ErrTooManyAttachments        = fmt.Errorf("a message may have at most %d attachments", maxMessageAttachments)
+	ErrAttachmentsAndLegacyMedia = errors.New("send either attachments or media_url, not both")
+	ErrUnsupportedAttachmentType = errors.New("media type must be one of: image, video, audio, file")
+	ErrInvalidAttachmentURL      = errors.New("not a valid media URL")
+)
+
+// MessageAttachment is one media item of a message
+type MessageAttachment struct {
+	URL  string `json:"url"`
+	Type string `json:"type"`
+}
+
+// validate checks the type is supported and the URL is one we can serve
+func (a MessageAttachment) validate() error {
+	if !messageAttachmentTypes[a.Type] {
+		return fmt.Errorf("attachment type %q: %w", a.Type, ErrUnsupportedAttachmentType)
+	}
+	if !isMediaURL(a.URL) {
+		return fmt.Errorf("attachment url %q: %w", a.URL, ErrInvalidAttachmentURL)
+	}
+	return nil
+}
+
+// legacyAttachments turns the single media_url/media_type pair older clients
+// send into an attachment list. A missing type is taken to be an image.
+func legacyAttachments(mediaURL, mediaType string) []MessageAttachment {
+	if mediaURL == "" {
+		return nil
+	}
+	if mediaType == "" {
+		mediaType = "image"
+	}
+	return []MessageAttachment{{URL: mediaURL, Type: mediaType}}
+}
+
+// requestAttachments returns the validated attachments of a send request,
+// whether it used the attachments list or the legacy media fields. Errors are
+// *fieldError naming the field at fault.
+func requestAttachments(req sendMessageRequest) ([]MessageAttachment, error) {
+	if len(req.Attachments) == 0 {
+		attachments := legacyAttachments(req.MediaUrl, req.MediaType)
+		for _, attachment := range attachments {
+			if err := attachment.validate(); err != nil {
+				field := "media_url"
+				if errors.Is(err, ErrUnsupportedAttachmentType) {
+					field = "media_type"
+				}
+				return nil, &fieldError{Field: field, Err: err}
+			}
+		}
+		return attachments, nil
+	}
+
+	if req.MediaUrl != "" {
+		return nil, &fieldError{Field: "media_url", Err: ErrAttachmentsAndLegacyMedia}
+	}
+	if len(req.Attachments) > maxMessageAttachments {
+		return nil, &fieldError{Field: "attachments", Err: ErrTooManyAttachments}
+	}
+	for _, attachment := range req.Attachments {
+		if err := attachment.validate(); err != nil {
+			return nil, &fieldError{Field: "attachments", Err: err}
+		}
+	}
+	return req.Attachments, nil
+}
+
+// createMessageWithAttachments stores a message and its attachments with q. The
+// first attachment is mirrored into media_url/media_type for older clients.
+func createMessageWithAttachments(ctx context.Context, q db.Querier, arg db.CreateMessageParams, attachments []MessageAttachment) (db.Message, error) {
+	if len(attachments) > 0 {
+		arg.MediaUrl = toNullString(attachments[0].URL)
+		arg.MediaType = toNullString(attachments[0].Type)
+	}
+	msg, err := q.CreateMessage(ctx, arg)
+	if err != nil || len(attachments) == 0 {
+		return msg, err
+	}
+
+	params := db.CreateMessageAttachmentsParams{MessageID: msg.ID}
+	for _, attachment := range attachments {
+		params.MediaUrls = append(params.MediaUrls, attachment.URL)
+		params.MediaTypes = append(params.MediaTypes, attachment.Type)
+	}
+	return msg, q.CreateMessageAttachments(ctx, params)
+}
+
+// createMessage stores a message, in a transaction when it has attachments
+func (server *Server) createMessage(ctx context.Context, arg db.CreateMessageParams, attachments []MessageAttachment) (db.Message, error) {
+	if len(attachments) == 0 {
+		return server.store.CreateMessage(ctx, arg)
+	}
+
+	var msg db.Message
+	err := server.store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		msg, err = createMessageWithAttachments(ctx, q, arg, attachments)
+		return err
+	})
+	return msg, err
+}
+
+// messageAttachmentsFromRows converts stored attachment rows
+func messageAttachmentsFromRows(rows []db.MessageAttachment) []MessageAttachment {
+	attachments := make([]MessageAttachment, len(rows))
+	for i, row := range rows {
+		attachments[i] = MessageAttachment{URL: row.MediaUrl, Type: row.MediaType}
+	}
+	return attachments
+}
+
+// messageAttachmentsFromJSON decodes the attachments column aggregated by the
+// message list queries
+func messageAttachmentsFromJSON(raw interface{}) []MessageAttachment {
+	attachments := []MessageAttachment{}
+	switch v := raw.(type) {
+	case []byte:
+		json.Unmarshal(v, &attachments)
+	case string:
+		json.Unmarshal([]byte(v), &attachments)
+	}
+	return attachments
+}
+
+// messageResponse is a message as clients see it: the stored row with its
+// media presigned, the full attachment list and a preview of the message it
+// replies to
+type messageResponse struct {
+	db.Message
+	Attachments []MessageAttachment  `json:"attachments"`
+	ReplyTo     *messageReplyPreview `json:"reply_to"`
+}
+
+// messageResponseWithMedia builds the response for msg, presigning privately
+// stored media in both the legacy fields and the attachments
+func (server *Server) messageResponseWithMedia(ctx context.Context, msg db.Message, attachments []MessageAttachment) messageResponse {
+	return messageResponse{
+		Message:     server.messageWithMedia(ctx, msg),
+		Attachments: server.attachmentsWithMedia(ctx, attachments),
+		ReplyTo:     server.replyPreview(ctx, msg),
+	}
+}
+
+// attachmentsWithMedia returns a copy of attachments with private media presigned
+func (server *Server) attachmentsWithMedia(ctx context.Context, attachments []MessageAttachment) []MessageAttachment {
+	resolved := make([]MessageAttachment, len(attachments))
+	for i, attachment := range attachments {
+		resolved[i] = MessageAttachment{URL: server.media.Resolve(ctx, attachment.URL), Type: attachment.Type}
+	}
+	return resolved
+}
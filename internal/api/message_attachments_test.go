@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestRequestAttachments(t *testing.T) {
+	testCases := []struct {
+		name  string
+		req   sendMessageRequest
+		check func(t *testing.T, attachments []MessageAttachment, err error)
+	}{
+		{
+			name: "Legacy",
+			req:  sendMessageRequest{MediaUrl: "https://cdn.example.com/a.jpg"},
+			check: func(t *testing.T, attachments []MessageAttachment, err error) {
+				require.NoError(t, err)
+				require.Equal(t, []MessageAttachment{{URL: "https://cdn.example.com/a.jpg", Type: "image"}}, attachments)
+			},
+		},
+		{
+			name: "LegacyBadType",
+			req:  sendMessageRequest{MediaUrl: "https://cdn.example.com/a.bin", MediaType: "binary"},
+			check: func(t *testing.T, _ []MessageAttachment, err error) {
+				require.ErrorIs(t, err, ErrUnsupportedAttachmentType)
+				var fe *fieldError
+				require.ErrorAs(t, err, &fe)
+				require.Equal(t, "media_type", fe.Field)
+			},
+		},
+		{
+			name: "File",
+			req:  sendMessageRequest{Attachments: []MessageAttachment{{URL: "r2:uploads/notes.pdf", Type: "file"}}},
+			check: func(t *testing.T, attachments []MessageAttachment, err error) {
+				require.NoError(t, err)
+				require.Len(t, attachments, 1)
+			},
+		},
+		{
+			name: "Several",
+			req: sendMessageRequest{Attachments: []MessageAttachment{
+				{URL: "r2:uploads/a.jpg", Type: "image"},
+				{URL: "/uploads/b.mp4", Type: "video"},
+			}},
+			check: func(t *testing.T, attachments []MessageAttachment, err error) {
+				require.NoError(t, err)
+				require.Len(t, attachments, 2)
+			},
+		},
+		{
+			name: "TextOnly",
+			req:  sendMessageRequest{Content: "hi"},
+			check: func(t *testing.T, attachments []MessageAttachment, err error) {
+				require.NoError(t, err)
+				require.Empty(t, attachments)
+			},
+		},
+		{
+			name: "BothForms",
+			req: sendMessageRequest{
+				MediaUrl:    "https://cdn.example.com/a.jpg",
+				Attachments: []MessageAttachment{{URL: "https://cdn.example.com/b.jpg", Type: "image"}},
+			},
+			check: func(t *testing.T, _ []MessageAttachment, err error) {
+				require.ErrorIs(t, err, ErrAttachmentsAndLegacyMedia)
+			},
+		},
+		{
+			name: "TooMany",
+			req:  sendMessageRequest{Attachments: make([]MessageAttachment, maxMessageAttachments+1)},
+			check: func(t *testing.T, _ []MessageAttachment, err error) {
+				require.ErrorIs(t, err, ErrTooManyAttachments)
+			},
+		},
+		{
+			name: "BadType",
+			req:  sendMessageRequest{Attachments: []MessageAttachment{{URL: "https://cdn.example.com/a.exe", Type: "binary"}}},
+			check: func(t *testing.T, _ []MessageAttachment, err error) {
+				require.ErrorIs(t, err, ErrUnsupportedAttachmentType)
+			},
+		},
+		{
+			name: "BadURL",
+			req:  sendMessageRequest{Attachments: []MessageAttachment{{URL: "javascript:alert(1)", Type: "image"}}},
+			check: func(t *testing.T, _ []MessageAttachment, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attachments, err := requestAttachments(tc.req)
+			tc.check(t, attachments, err)
+		})
+	}
+}
+
+func TestCreateMessageWithAttachmentsMirrorsFirst(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	sender, receiver := uuid.New(), uuid.New()
+	attachments := []MessageAttachment{
+		{URL: "https://cdn.example.com/a.jpg", Type: "image"},
+		{URL: "https://cdn.example.com/b.mp4", Type: "video"},
+	}
+	created := db.Message{ID: uuid.New(), SenderID: sender}
+
+	store.EXPECT().
+		CreateMessage(gomock.Any(), db.CreateMessageParams{
+			SenderID:   sender,
+			ReceiverID: uuid.NullUUID{UUID: receiver, Valid: true},
+			MediaUrl:   toNullString("https://cdn.example.com/a.jpg"),
+			MediaType:  toNullString("image"),
+		}).
+		Return(created, nil)
+	store.EXPECT().
+		CreateMessageAttachments(gomock.Any(), db.CreateMessageAttachmentsParams{
+			MessageID:  created.ID,
+			MediaUrls:  []string{"https://cdn.example.com/a.jpg", "https://cdn.example.com/b.mp4"},
+			MediaTypes: []string{"image", "video"},
+		}).
+		Return(nil)
+
+	msg, err := createMessageWithAttachments(context.Background(), store, db.CreateMessageParams{
+		SenderID:   sender,
+		ReceiverID: uuid.NullUUID{UUID: receiver, Valid: true},
+	}, attachments)
+	require.NoError(t, err)
+	require.Equal(t, created.ID, msg.ID)
+}
+
+func TestMessageAttachmentsFromJSON(t *testing.T) {
+	attachments := messageAttachmentsFromJSON([]byte(`[{"url":"https://cdn.example.com/a.jpg","type":"image"}]`))
+	require.Equal(t, []MessageAttachment{{URL: "https://cdn.example.com/a.jpg", Type: "image"}}, attachments)
+
+	require.NotNil(t, messageAttachmentsFromJSON(nil))
+}
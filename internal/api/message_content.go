@@ -0,0 +1,36 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxMessageContentLength caps the characters of a message's text
+const maxMessageContentLength = 4000
+
+var (
+	ErrMessageTooLong = fmt.Errorf("content must be at most %d characters", maxMessageContentLength)
+	ErrEmptyMessage   = errors.New("content or media is required")
+)
+
+// sanitizeMessageContent drops control characters from message text, keeping
+// newlines and tabs
+func sanitizeMessageContent(content string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, content)
+}
+
+// validateMessageContent checks sanitized text against the length limit
+func validateMessageContent(content string) error {
+	if utf8.RuneCountInString(content) > maxMessageContentLength {
+		return &fieldError{Field: "content", Err: ErrMessageTooLong}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestSanitizeMessageContent(t *testing.T) {
+	require.Equal(t, "hello\n\tworld", sanitizeMessageContent("he\x00llo\r\n\tw\x1borld\u0085"))
+	require.Equal(t, "héllo 👋", sanitizeMessageContent("héllo 👋"))
+}
+
+func TestSendMessageValidation(t *testing.T) {
+	userID, otherID := uuid.New(), uuid.New()
+
+	testCases := []struct {
+		name      string
+		body      gin.H
+		wantField string
+	}{
+		{
+			name:      "Empty",
+			body:      gin.H{"receiver_id": otherID},
+			wantField: "content",
+		},
+		{
+			// Text that is nothing but control characters and spaces is empty
+			name:      "OnlyControlCharacters",
+			body:      gin.H{"receiver_id": otherID, "content": " \x00\x07 "},
+			wantField: "content",
+		},
+		{
+			name:      "TooLong",
+			body:      gin.H{"receiver_id": otherID, "content": strings.Repeat("é", maxMessageContentLength+1)},
+			wantField: "content",
+		},
+		{
+			name:      "BadMediaType",
+			body:      gin.H{"receiver_id": otherID, "media_url": "https://cdn.example.com/a.bin", "media_type": "binary"},
+			wantField: "media_type",
+		},
+		{
+			name:      "BadMediaURL",
+			body:      gin.H{"receiver_id": otherID, "media_url": "javascript:alert(1)", "media_type": "file"},
+			wantField: "media_url",
+		},
+		{
+			name:      "BadAttachment",
+			body:      gin.H{"receiver_id": otherID, "attachments": []gin.H{{"url": "https://cdn.example.com/a.jpg", "type": "sticker"}}},
+			wantField: "attachments",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+
+			server := newTestServer(t, store)
+			recorder := postJSON(t, server, "/messages", tc.body, &userID)
+			require.Equal(t, http.StatusBadRequest, recorder.Code, recorder.Body.String())
+			require.JSONEq(t, fmt.Sprintf("%q", tc.wantField), mustJSONField(t, recorder.Body.Bytes(), "field"))
+		})
+	}
+}
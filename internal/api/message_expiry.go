@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/config"
+)
+
+var ErrSaveMessageRequiresPremium = errors.New("saving messages is a premium feature")
+
+// messageExpiryPolicy bounds how long a message may live, by sender tier
+type messageExpiryPolicy struct {
+	Default    time.Duration
+	Min        time.Duration
+	Max        time.Duration
+	PremiumMax time.Duration
+}
+
+func newMessageExpiryPolicy(config config.Config) messageExpiryPolicy {
+	policy := messageExpiryPolicy{
+		Default:    config.MessageDefaultExpiry,
+		Min:        config.MessageMinExpiry,
+		Max:        config.MessageMaxExpiry,
+		PremiumMax: config.MessageMaxExpiryPremium,
+	}
+	if policy.Default <= 0 {
+		policy.Default = 24 * time.Hour
+	}
+	if policy.Max < policy.Default {
+		policy.Max = policy.Default
+	}
+	if policy.PremiumMax < policy.Max {
+		policy.PremiumMax = policy.Max
+	}
+	return policy
+}
+
+// maxFor is the longest expiry the sender's tier allows
+func (p messageExpiryPolicy) maxFor(premium bool) time.Duration {
+	if premium {
+		return p.PremiumMax
+	}
+	return p.Max
+}
+
+// resolve turns a requested expires_in_seconds into the expiry to apply.
+// Zero means the default; anything outside [Min, tier max] is rejected.
+func (p messageExpiryPolicy) resolve(expiresInSeconds int64, premium bool) (time.Duration, error) {
+	if expiresInSeconds == 0 {
+		return p.Default, nil
+	}
+
+	maxExpiry := p.maxFor(premium)
+	expiry := time.Duration(expiresInSeconds) * time.Second
+	if expiresInSeconds < 0 || expiresInSeconds > int64(maxExpiry/time.Second) || expiry < p.Min {
+		return 0, fmt.Errorf("expires_in_seconds must be between %d and %d", int64(p.Min/time.Second), int64(maxExpiry/time.Second))
+	}
+	return expiry, nil
+}
+
+// isPremiumUser reports the user's tier for message policy checks
+func (server *Server) isPremiumUser(ctx context.Context, userID uuid.UUID) (bool, error) {
+	user, err := server.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsPremium.Valid && user.IsPremium.Bool, nil
+}
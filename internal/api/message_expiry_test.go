@@ -0,0 +1,49 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"privacy-social-backend/internal/config"
+)
+
+func TestMessageExpiryPolicy(t *testing.T) {
+	policy := newMessageExpiryPolicy(config.Config{
+		MessageDefaultExpiry:    24 * time.Hour,
+		MessageMinExpiry:        10 * time.Second,
+		MessageMaxExpiry:        7 * 24 * time.Hour,
+		MessageMaxExpiryPremium: 30 * 24 * time.Hour,
+	})
+
+	testCases := []struct {
+		name      string
+		seconds   int64
+		premium   bool
+		want      time.Duration
+		wantError bool
+	}{
+		{name: "Default", seconds: 0, want: 24 * time.Hour},
+		{name: "InRange", seconds: 3600, want: time.Hour},
+		{name: "Negative", seconds: -1, wantError: true},
+		{name: "BelowMin", seconds: 5, wantError: true},
+		{name: "FreeAtMax", seconds: 7 * 24 * 3600, want: 7 * 24 * time.Hour},
+		{name: "FreeAboveMax", seconds: 8 * 24 * 3600, wantError: true},
+		{name: "PremiumAboveFreeMax", seconds: 8 * 24 * 3600, premium: true, want: 8 * 24 * time.Hour},
+		{name: "PremiumAboveMax", seconds: 31 * 24 * 3600, premium: true, wantError: true},
+		{name: "Overflow", seconds: 1 << 62, premium: true, wantError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := policy.resolve(tc.seconds, tc.premium)
+			if tc.wantError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/util"
+)
+
+var (
+	ErrForwardSourceNotFound = errors.New("the message being forwarded does not exist")
+	ErrForwardSourceExpired  = errors.New("the message being forwarded has expired")
+)
+
+// forwardMessageRequest names the conversation a message is forwarded to
+type forwardMessageRequest struct {
+	ReceiverID *uuid.UUID `json:"receiver_id"`
+	GroupID    *uuid.UUID `json:"group_id"`
+}
+
+// forwardMessage copies a message the caller can see into another
+// conversation. The copy records the message and sender it came from, and
+// expires like a newly sent message rather than when the original does.
+func (server *Server) forwardMessage(ctx *gin.Context) {
+	messageID, ok := parseUUIDParam(ctx, ctx.Param("id"), "message_id")
+	if !ok {
+		return
+	}
+	var req forwardMessageRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	authPayload := getAuthPayload(ctx)
+
+	original, ok := server.forwardSource(ctx, messageID, authPayload.UserID)
+	if !ok {
+		return
+	}
+	if server.rejectBlockedText(ctx, original.Content) {
+		return
+	}
+
+	receiverID, groupID, ok := server.checkRecipient(ctx, authPayload.UserID, req.ReceiverID, req.GroupID)
+	if !ok {
+		return
+	}
+
+	attachmentRows, err := server.store.ListMessageAttachments(ctx, original.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	attachments := messageAttachmentsFromRows(attachmentRows)
+	if len(attachments) == 0 {
+		// Messages from before attachments only have the legacy media fields
+		attachments = legacyAttachments(original.MediaUrl.String, original.MediaType.String)
+	}
+
+	// Forwarding a forward credits the message it was first copied from
+	forwardedFrom, forwardedSender := original.ID, original.SenderID
+	if original.ForwardedFromMessageID.Valid {
+		forwardedFrom, forwardedSender = original.ForwardedFromMessageID.UUID, original.ForwardedFromSenderID.UUID
+	}
+
+	// A direct chat's own default expiry applies to forwards into it too
+	policy := newMessageExpiryPolicy(server.config)
+	expiry := policy.Default
+	if receiverID.Valid {
+		expiry, err = server.conversationDefaultExpiry(ctx, authPayload.UserID, receiverID.UUID, policy)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+	expiresAt := util.NullTime{
+		Time:  util.Now().Add(expiry),
+		Valid: expiry > 0,
+	}
+
+	msg, err := server.createMessage(ctx, db.CreateMessageParams{
+		SenderID:               authPayload.UserID,
+		ReceiverID:             receiverID,
+		GroupID:                groupID,
+		Content:                original.Content,
+		ExpiresAt:              expiresAt,
+		ForwardedFromMessageID: uuid.NullUUID{UUID: forwardedFrom, Valid: true},
+		ForwardedFromSenderID:  uuid.NullUUID{UUID: forwardedSender, Valid: true},
+	}, attachments)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.deliverMessage(msg, attachments)
+
+	ctx.JSON(http.StatusCreated, sendMessageResponse{
+		messageResponse:    server.messageResponseWithMedia(ctx, msg, attachments),
+		EffectiveExpiresAt: expiresAt.Ptr(),
+	})
+}
+
+// forwardSource loads the message being forwarded, writing the error response
+// unless it exists, hasn't expired and userID took part in its conversation.
+// Messages the caller can't see are reported as missing.
+func (server *Server) forwardSource(ctx *gin.Context, messageID, userID uuid.UUID) (db.Message, bool) {
+	original, err := server.store.GetMessage(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrForwardSourceNotFound))
+			return original, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return original, false
+	}
+
+	participant, err := server.isMessageParticipant(ctx, original, userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return original, false
+	}
+	if !participant {
+		ctx.JSON(http.StatusNotFound, errorResponse(ErrForwardSourceNotFound))
+		return original, false
+	}
+
+	if original.ExpiresAt.Valid && !original.ExpiresAt.Time.After(util.Now()) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrForwardSourceExpired))
+		return original, false
+	}
+	return original, true
+}
+
+// isMessageParticipant reports whether userID sent or received msg, or is a
+// member of the group it was posted to
+func (server *Server) isMessageParticipant(ctx context.Context, msg db.Message, userID uuid.UUID) (bool, error) {
+	if msg.SenderID == userID {
+		return true, nil
+	}
+	if msg.GroupID.Valid {
+		return server.store.CheckGroupMembership(ctx, db.CheckGroupMembershipParams{
+			GroupID: msg.GroupID.UUID,
+			UserID:  userID,
+		})
+	}
+	return msg.ReceiverID.Valid && msg.ReceiverID.UUID == userID, nil
+}
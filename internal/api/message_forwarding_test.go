@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/realtime"
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestForwardMessage(t *testing.T) {
+	userID, friendID := uuid.New(), uuid.New()
+	messageID, groupID := uuid.New(), uuid.New()
+	toGroup := uuid.NullUUID{UUID: groupID, Valid: true}
+	membership := db.CheckGroupMembershipParams{GroupID: groupID, UserID: userID}
+
+	// A direct message the caller received, with an expiry about to run out
+	received := db.Message{
+		ID:         messageID,
+		SenderID:   friendID,
+		ReceiverID: uuid.NullUUID{UUID: userID, Valid: true},
+		Content:    "see you there",
+		ExpiresAt:  util.NullTime{Time: util.Now().Add(time.Minute), Valid: true},
+	}
+
+	// expectCreate checks the copy and echoes it back as stored
+	expectCreate := func(store *mockdb.MockStore, fromID, fromSender uuid.UUID) {
+		store.EXPECT().
+			CreateMessage(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.CreateMessageParams) (db.Message, error) {
+				require.Equal(t, userID, arg.SenderID)
+				require.Equal(t, toGroup, arg.GroupID)
+				require.False(t, arg.ReceiverID.Valid)
+				require.Equal(t, received.Content, arg.Content)
+				require.Equal(t, uuid.NullUUID{UUID: fromID, Valid: true}, arg.ForwardedFromMessageID)
+				require.Equal(t, uuid.NullUUID{UUID: fromSender, Valid: true}, arg.ForwardedFromSenderID)
+				// A fresh default expiry, not the original's
+				require.WithinDuration(t, util.Now().Add(24*time.Hour), arg.ExpiresAt.Time, time.Minute)
+				return db.Message{
+					ID:                     uuid.New(),
+					SenderID:               arg.SenderID,
+					GroupID:                arg.GroupID,
+					Content:                arg.Content,
+					ExpiresAt:              arg.ExpiresAt,
+					ForwardedFromMessageID: arg.ForwardedFromMessageID,
+					ForwardedFromSenderID:  arg.ForwardedFromSenderID,
+				}, nil
+			})
+		store.EXPECT().GetGroupMembers(gomock.Any(), groupID).Times(1).Return(nil, nil)
+		store.EXPECT().ListGroupMuters(gomock.Any(), toGroup).Times(1).Return(nil, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"group_id": groupID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessage(gomock.Any(), messageID).Times(1).Return(received, nil)
+				store.EXPECT().CheckGroupMembership(gomock.Any(), membership).Times(1).Return(true, nil)
+				store.EXPECT().ListMessageAttachments(gomock.Any(), messageID).Times(1).Return(nil, nil)
+				expectCreate(store, messageID, friendID)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+				var got sendMessageResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, messageID, got.ForwardedFromMessageID.UUID)
+				require.Equal(t, friendID, got.ForwardedFromSenderID.UUID)
+			},
+		},
+		{
+			name: "ForwardOfForward",
+			body: gin.H{"group_id": groupID},
+			buildStubs: func(store *mockdb.MockStore) {
+				rootID, rootSender := uuid.New(), uuid.New()
+				forward := received
+				forward.ForwardedFromMessageID = uuid.NullUUID{UUID: rootID, Valid: true}
+				forward.ForwardedFromSenderID = uuid.NullUUID{UUID: rootSender, Valid: true}
+
+				store.EXPECT().GetMessage(gomock.Any(), messageID).Times(1).Return(forward, nil)
+				store.EXPECT().CheckGroupMembership(gomock.Any(), membership).Times(1).Return(true, nil)
+				store.EXPECT().ListMessageAttachments(gomock.Any(), messageID).Times(1).Return(nil, nil)
+				expectCreate(store, rootID, rootSender)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+			},
+		},
+		{
+			name: "NotFound",
+			body: gin.H{"group_id": groupID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessage(gomock.Any(), messageID).Times(1).Return(db.Message{}, sql.ErrNoRows)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "NotParticipant",
+			body: gin.H{"group_id": groupID},
+			buildStubs: func(store *mockdb.MockStore) {
+				otherGroup := uuid.New()
+				store.EXPECT().GetMessage(gomock.Any(), messageID).Times(1).Return(db.Message{
+					ID:       messageID,
+					SenderID: friendID,
+					GroupID:  uuid.NullUUID{UUID: otherGroup, Valid: true},
+				}, nil)
+				store.EXPECT().
+					CheckGroupMembership(gomock.Any(), db.CheckGroupMembershipParams{GroupID: otherGroup, UserID: userID}).
+					Times(1).
+					Return(false, nil)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Contains(t, recorder.Body.String(), ErrForwardSourceNotFound.Error())
+			},
+		},
+		{
+			name: "Expired",
+			body: gin.H{"group_id": groupID},
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := received
+				expired.ExpiresAt = util.NullTime{Time: util.Now().Add(-time.Minute), Valid: true}
+				store.EXPECT().GetMessage(gomock.Any(), messageID).Times(1).Return(expired, nil)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), ErrForwardSourceExpired.Error())
+			},
+		},
+		{
+			name: "NotGroupMember",
+			body: gin.H{"group_id": groupID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessage(gomock.Any(), messageID).Times(1).Return(received, nil)
+				store.EXPECT().CheckGroupMembership(gomock.Any(), membership).Times(1).Return(false, nil)
+				store.EXPECT().GetGroupByID(gomock.Any(), groupID).Times(1).Return(db.Group{ID: groupID}, nil)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoRecipient",
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessage(gomock.Any(), messageID).Times(1).Return(received, nil)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+			server.redis = rdb
+			server.hub = realtime.NewHub(rdb)
+
+			recorder := postJSON(t, server, fmt.Sprintf("/messages/%s/forward", messageID), tc.body, &userID)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestForwardMessageConversationDefaultExpiry(t *testing.T) {
+	userID, friendID, otherID, messageID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	settingsKey := db.GetConversationSettingsParams{UserID: userID, OtherUserID: otherID}
+	received := db.Message{
+		ID:         messageID,
+		SenderID:   friendID,
+		ReceiverID: uuid.NullUUID{UUID: userID, Valid: true},
+		Content:    "see you there",
+	}
+
+	testCases := []struct {
+		name        string
+		settings    db.ConversationSetting
+		settingsErr error
+		wantExpires time.Duration // 0 means the copy never expires
+	}{
+		{name: "NoSetting", settingsErr: sql.ErrNoRows, wantExpires: 24 * time.Hour},
+		{name: "ChatDefault", settings: db.ConversationSetting{DefaultExpirySeconds: 3600}, wantExpires: time.Hour},
+		{name: "NeverExpire"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetMessage(gomock.Any(), messageID).Times(1).Return(received, nil)
+			allowChat(store, otherID)
+			store.EXPECT().ListMessageAttachments(gomock.Any(), messageID).Times(1).Return(nil, nil)
+			store.EXPECT().GetConversationSettings(gomock.Any(), settingsKey).Times(1).Return(tc.settings, tc.settingsErr)
+			store.EXPECT().
+				CreateMessage(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, arg db.CreateMessageParams) (db.Message, error) {
+					require.Equal(t, tc.wantExpires > 0, arg.ExpiresAt.Valid)
+					if tc.wantExpires > 0 {
+						require.WithinDuration(t, util.Now().Add(tc.wantExpires), arg.ExpiresAt.Time, time.Minute)
+					}
+					return db.Message{ID: uuid.New(), SenderID: arg.SenderID, ReceiverID: arg.ReceiverID, Content: arg.Content, ExpiresAt: arg.ExpiresAt}, nil
+				})
+			store.EXPECT().IsConversationMuted(gomock.Any(), gomock.Any()).AnyTimes().Return(false, nil)
+
+			server := newTestServer(t, store)
+			recorder := postJSON(t, server, fmt.Sprintf("/messages/%s/forward", messageID), gin.H{"receiver_id": otherID}, &userID)
+			require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+			if tc.wantExpires == 0 {
+				require.JSONEq(t, "null", mustJSONField(t, recorder.Body.Bytes(), "effective_expires_at"))
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/util"
+)
+
+// replyPreviewMaxRunes caps how much of the quoted message a reply carries
+const replyPreviewMaxRunes = 100
+
+// messageRepliesDefaultPageSize is used when page_size is omitted
+const messageRepliesDefaultPageSize = 20
+
+// replyUnavailableContent stands in for a quoted message that was deleted or
+// has expired
+const replyUnavailableContent = "message unavailable"
+
+var (
+	ErrReplyTargetNotFound    = errors.New("the message being replied to does not exist")
+	ErrReplyTargetExpired     = errors.New("the message being replied to has expired")
+	ErrReplyOtherConversation = errors.New("a reply must be in the same conversation as the message it quotes")
+	ErrScheduledReply         = errors.New("replies cannot be scheduled")
+	ErrRepliedMessageNotFound = errors.New("message not found")
+)
+
+// messageReplyPreview is the part of a quoted message a reply shows, so
+// clients can render it without fetching the original
+type messageReplyPreview struct {
+	ID          uuid.UUID  `json:"id"`
+	SenderID    *uuid.UUID `json:"sender_id"`
+	Username    string     `json:"username,omitempty"`
+	Content     string     `json:"content"`
+	MediaType   *string    `json:"media_type"`
+	Unavailable bool       `json:"unavailable"`
+}
+
+// storedReplyPreview is the reply_to column aggregated by the message list
+// queries, null when the quoted message is gone
+type storedReplyPreview struct {
+	ID        uuid.UUID `json:"id"`
+	SenderID  uuid.UUID `json:"sender_id"`
+	Username  string    `json:"username"`
+	Content   string    `json:"content"`
+	MediaType *string   `json:"media_type"`
+}
+
+func newReplyPreview(id, senderID uuid.UUID, username, content string, mediaType *string) *messageReplyPreview {
+	runes := []rune(content)
+	if len(runes) > replyPreviewMaxRunes {
+		content = string(runes[:replyPreviewMaxRunes]) + "…"
+	}
+	return &messageReplyPreview{
+		ID:        id,
+		SenderID:  &senderID,
+		Username:  username,
+		Content:   content,
+		MediaType: mediaType,
+	}
+}
+
+// unavailableReplyPreview is shown for a quoted message that no longer exists
+func unavailableReplyPreview(id uuid.UUID) *messageReplyPreview {
+	return &messageReplyPreview{ID: id, Content: replyUnavailableContent, Unavailable: true}
+}
+
+// replyPreviewFromJSON decodes the reply_to column of the message list
+// queries. It returns nil for messages that aren't replies.
+func replyPreviewFromJSON(replyToID uuid.NullUUID, raw interface{}) *messageReplyPreview {
+	if !replyToID.Valid {
+		return nil
+	}
+	var data []byte
+	switch v := raw.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	}
+	var stored storedReplyPreview
+	if len(data) == 0 || json.Unmarshal(data, &stored) != nil {
+		return unavailableReplyPreview(replyToID.UUID)
+	}
+	return newReplyPreview(stored.ID, stored.SenderID, stored.Username, stored.Content, stored.MediaType)
+}
+
+// replyPreview looks up the preview of the message msg quotes. It returns nil
+// for messages that aren't replies.
+func (server *Server) replyPreview(ctx context.Context, msg db.Message) *messageReplyPreview {
+	if !msg.ReplyToMessageID.Valid {
+		return nil
+	}
+	row, err := server.store.GetMessageReplyPreview(ctx, msg.ReplyToMessageID.UUID)
+	if err != nil {
+		return unavailableReplyPreview(msg.ReplyToMessageID.UUID)
+	}
+	return newReplyPreview(row.ID, row.SenderID, row.Username, row.Content, nullStringToStrPtr(row.MediaType))
+}
+
+// checkReplyTarget checks the message being replied to exists, hasn't expired
+// and belongs to the conversation the reply goes to, writing the error
+// response otherwise. It reports whether the handler may go on.
+func (server *Server) checkReplyTarget(ctx *gin.Context, parentID, senderID uuid.UUID, receiverID, groupID uuid.NullUUID) bool {
+	parent, err := server.store.GetMessage(ctx, parentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrReplyTargetNotFound))
+			return false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	sameConversation := false
+	if groupID.Valid {
+		sameConversation = parent.GroupID == groupID
+	} else if !parent.GroupID.Valid && parent.ReceiverID.Valid {
+		sameConversation = (parent.SenderID == senderID && parent.ReceiverID.UUID == receiverID.UUID) ||
+			(parent.SenderID == receiverID.UUID && parent.ReceiverID.UUID == senderID)
+	}
+	if !sameConversation {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrReplyOtherConversation))
+		return false
+	}
+
+	if parent.ExpiresAt.Valid && !parent.ExpiresAt.Time.After(util.Now()) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrReplyTargetExpired))
+		return false
+	}
+	return true
+}
+
+type listMessageRepliesRequest struct {
+	PageID   int32 `form:"page" binding:"omitempty,min=1"`
+	PageSize int32 `form:"page_size" binding:"omitempty,min=5,max=50"`
+}
+
+// messageReply is one message in the replies thread of another
+type messageReply struct {
+	ID        uuid.UUID     `json:"id"`
+	SenderID  uuid.UUID     `json:"sender_id"`
+	Username  string        `json:"username"`
+	AvatarUrl string        `json:"avatar_url"`
+	Content   string        `json:"content"`
+	MediaUrl  *string       `json:"media_url"`
+	MediaType *string       `json:"media_type"`
+	CreatedAt time.Time     `json:"created_at"`
+	ExpiresAt util.NullTime `json:"expires_at"`
+}
+
+// getMessageReplies serves GET /messages/:id/replies: the unexpired replies
+// to a message, oldest first. Only people in the message's conversation see
+// them; to everyone else the message doesn't exist.
+func (server *Server) getMessageReplies(ctx *gin.Context) {
+	messageID, ok := parseUUIDParam(ctx, ctx.Param("id"), "message_id")
+	if !ok {
+		return
+	}
+	var req listMessageRepliesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.PageID == 0 {
+		req.PageID = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = messageRepliesDefaultPageSize
+	}
+	authPayload := getAuthPayload(ctx)
+
+	parent, err := server.store.GetMessage(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrRepliedMessageNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	participant, err := server.isMessageParticipant(ctx, parent, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !participant || (parent.ExpiresAt.Valid && !parent.ExpiresAt.Time.After(util.Now())) {
+		ctx.JSON(http.StatusNotFound, errorResponse(ErrRepliedMessageNotFound))
+		return
+	}
+
+	rows, err := server.store.ListMessageReplies(ctx, db.ListMessageRepliesParams{
+		MessageID: messageID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	total, err := server.store.CountMessageReplies(ctx, messageID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	replies := make([]messageReply, len(rows))
+	for i, row := range rows {
+		replies[i] = messageReply{
+			ID:        row.ID,
+			SenderID:  row.SenderID,
+			Username:  row.Username,
+			AvatarUrl: row.AvatarUrl.String,
+			Content:   row.Content,
+			MediaType: nullStringToStrPtr(row.MediaType),
+			CreatedAt: row.CreatedAt,
+			ExpiresAt: row.ExpiresAt,
+		}
+		if row.MediaUrl.Valid {
+			mediaURL := server.media.Resolve(ctx, row.MediaUrl.String)
+			replies[i].MediaUrl = &mediaURL
+		}
+	}
+	ctx.JSON(http.StatusOK, newPaginatedResponse(replies, req.PageID, req.PageSize, total))
+}
@@ -0,0 +1,353 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestSendGroupMessageReply(t *testing.T) {
+	userID := uuid.New()
+	groupID := uuid.New()
+	parentID := uuid.New()
+	membership := db.CheckGroupMembershipParams{GroupID: groupID, UserID: userID}
+	inGroup := uuid.NullUUID{UUID: groupID, Valid: true}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ParentMissing",
+			body: gin.H{"group_id": groupID, "content": "hi", "reply_to_message_id": parentID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CheckGroupMembership(gomock.Any(), membership).Times(1).Return(true, nil)
+				store.EXPECT().GetMessage(gomock.Any(), parentID).Times(1).Return(db.Message{}, sql.ErrNoRows)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "ParentInOtherGroup",
+			body: gin.H{"group_id": groupID, "content": "hi", "reply_to_message_id": parentID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CheckGroupMembership(gomock.Any(), membership).Times(1).Return(true, nil)
+				store.EXPECT().GetMessage(gomock.Any(), parentID).Times(1).Return(db.Message{
+					ID:      parentID,
+					GroupID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+				}, nil)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), ErrReplyOtherConversation.Error())
+			},
+		},
+		{
+			name: "ParentExpired",
+			body: gin.H{"group_id": groupID, "content": "hi", "reply_to_message_id": parentID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CheckGroupMembership(gomock.Any(), membership).Times(1).Return(true, nil)
+				store.EXPECT().GetMessage(gomock.Any(), parentID).Times(1).Return(db.Message{
+					ID:        parentID,
+					GroupID:   inGroup,
+					ExpiresAt: util.NullTime{Time: util.Now().Add(-time.Minute), Valid: true},
+				}, nil)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), ErrReplyTargetExpired.Error())
+			},
+		},
+		{
+			name: "Scheduled",
+			body: gin.H{"group_id": groupID, "content": "hi", "reply_to_message_id": parentID, "scheduled_at": time.Now().Add(time.Hour)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CheckGroupMembership(gomock.Any(), membership).Times(1).Return(true, nil)
+				store.EXPECT().GetMessage(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), ErrScheduledReply.Error())
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/messages", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCheckReplyTargetDirect(t *testing.T) {
+	me := uuid.New()
+	peer := uuid.New()
+	parentID := uuid.New()
+	toPeer := uuid.NullUUID{UUID: peer, Valid: true}
+
+	testCases := []struct {
+		name   string
+		parent db.Message
+		ok     bool
+	}{
+		{
+			name:   "SentByMe",
+			parent: db.Message{ID: parentID, SenderID: me, ReceiverID: toPeer},
+			ok:     true,
+		},
+		{
+			name:   "SentByPeer",
+			parent: db.Message{ID: parentID, SenderID: peer, ReceiverID: uuid.NullUUID{UUID: me, Valid: true}},
+			ok:     true,
+		},
+		{
+			name:   "OtherConversation",
+			parent: db.Message{ID: parentID, SenderID: peer, ReceiverID: uuid.NullUUID{UUID: uuid.New(), Valid: true}},
+		},
+		{
+			name:   "GroupMessage",
+			parent: db.Message{ID: parentID, SenderID: peer, GroupID: uuid.NullUUID{UUID: uuid.New(), Valid: true}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetMessage(gomock.Any(), parentID).Times(1).Return(tc.parent, nil)
+			server := newTestServer(t, store)
+
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/messages", nil)
+
+			require.Equal(t, tc.ok, server.checkReplyTarget(ctx, parentID, me, toPeer, uuid.NullUUID{}))
+			if !tc.ok {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestReplyPreviewFromJSON(t *testing.T) {
+	parentID := uuid.New()
+	senderID := uuid.New()
+	replyTo := uuid.NullUUID{UUID: parentID, Valid: true}
+
+	require.Nil(t, replyPreviewFromJSON(uuid.NullUUID{}, nil))
+
+	preview := replyPreviewFromJSON(replyTo, nil)
+	require.Equal(t, unavailableReplyPreview(parentID), preview)
+	require.Equal(t, replyUnavailableContent, preview.Content)
+
+	raw, err := json.Marshal(gin.H{
+		"id":         parentID,
+		"sender_id":  senderID,
+		"username":   "alice",
+		"content":    strings.Repeat("é", replyPreviewMaxRunes+20),
+		"media_type": "image",
+	})
+	require.NoError(t, err)
+	preview = replyPreviewFromJSON(replyTo, raw)
+	require.False(t, preview.Unavailable)
+	require.Equal(t, senderID, *preview.SenderID)
+	require.Equal(t, "alice", preview.Username)
+	require.Equal(t, "image", *preview.MediaType)
+	require.Equal(t, strings.Repeat("é", replyPreviewMaxRunes)+"…", preview.Content)
+}
+
+func TestReplyPreviewOfDeletedMessage(t *testing.T) {
+	parentID := uuid.New()
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetMessageReplyPreview(gomock.Any(), parentID).Times(1).Return(db.GetMessageReplyPreviewRow{}, sql.ErrNoRows)
+	server := newTestServer(t, store)
+
+	msg := db.Message{ID: uuid.New(), ReplyToMessageID: uuid.NullUUID{UUID: parentID, Valid: true}}
+	rsp := server.messageResponseWithMedia(t.Context(), msg, nil)
+	require.NotNil(t, rsp.ReplyTo)
+	require.True(t, rsp.ReplyTo.Unavailable)
+	require.Equal(t, parentID, rsp.ReplyTo.ID)
+}
+
+func TestGetMessageReplies(t *testing.T) {
+	userID, otherID, strangerID := uuid.New(), uuid.New(), uuid.New()
+	groupID := uuid.New()
+	parentID := uuid.New()
+	direct := db.Message{
+		ID:         parentID,
+		SenderID:   otherID,
+		ReceiverID: uuid.NullUUID{UUID: userID, Valid: true},
+	}
+
+	testCases := []struct {
+		name          string
+		caller        uuid.UUID
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			caller: userID,
+			query:  "?page=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessage(gomock.Any(), parentID).Times(1).Return(direct, nil)
+				store.EXPECT().
+					ListMessageReplies(gomock.Any(), db.ListMessageRepliesParams{MessageID: parentID, Limit: 5, Offset: 5}).
+					Times(1).
+					Return([]db.ListMessageRepliesRow{{
+						ID:        uuid.New(),
+						SenderID:  userID,
+						Username:  "me",
+						Content:   "agreed",
+						CreatedAt: time.Now(),
+					}}, nil)
+				store.EXPECT().CountMessageReplies(gomock.Any(), parentID).Times(1).Return(int64(6), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				var rsp PaginatedResponse[messageReply]
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, int32(2), rsp.Page)
+				require.Equal(t, int64(6), rsp.Total)
+				require.Equal(t, int64(2), rsp.TotalPages)
+				require.Len(t, rsp.Data, 1)
+				require.Equal(t, "agreed", rsp.Data[0].Content)
+				require.Nil(t, rsp.Data[0].MediaUrl)
+			},
+		},
+		{
+			name:   "DefaultPage",
+			caller: userID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessage(gomock.Any(), parentID).Times(1).Return(direct, nil)
+				store.EXPECT().
+					ListMessageReplies(gomock.Any(), db.ListMessageRepliesParams{MessageID: parentID, Limit: messageRepliesDefaultPageSize, Offset: 0}).
+					Times(1).
+					Return(nil, nil)
+				store.EXPECT().CountMessageReplies(gomock.Any(), parentID).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				require.Contains(t, recorder.Body.String(), `"data":[]`)
+			},
+		},
+		{
+			name:   "PageSizeTooLarge",
+			caller: userID,
+			query:  "?page_size=500",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "NotParticipant",
+			caller: strangerID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessage(gomock.Any(), parentID).Times(1).Return(direct, nil)
+				store.EXPECT().ListMessageReplies(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Contains(t, recorder.Body.String(), ErrRepliedMessageNotFound.Error())
+			},
+		},
+		{
+			name:   "NotGroupMember",
+			caller: strangerID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessage(gomock.Any(), parentID).Times(1).Return(db.Message{
+					ID:       parentID,
+					SenderID: otherID,
+					GroupID:  uuid.NullUUID{UUID: groupID, Valid: true},
+				}, nil)
+				store.EXPECT().
+					CheckGroupMembership(gomock.Any(), db.CheckGroupMembershipParams{GroupID: groupID, UserID: strangerID}).
+					Times(1).
+					Return(false, nil)
+				store.EXPECT().ListMessageReplies(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "ParentExpired",
+			caller: userID,
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := direct
+				expired.ExpiresAt = util.NullTime{Time: util.Now().Add(-time.Minute), Valid: true}
+				store.EXPECT().GetMessage(gomock.Any(), parentID).Times(1).Return(expired, nil)
+				store.EXPECT().ListMessageReplies(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "ParentMissing",
+			caller: userID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetMessage(gomock.Any(), parentID).Times(1).Return(db.Message{}, sql.ErrNoRows)
+				store.EXPECT().ListMessageReplies(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := getWithAuth(t, server, "/messages/"+parentID.String()+"/replies"+tc.query, tc.caller)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
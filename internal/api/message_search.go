@@ -0,0 +1,195 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/repository/db"
+)
+
+const (
+	// messageSearchDefaultPageSize is used when page_size is omitted
+	messageSearchDefaultPageSize = 20
+	// messageSnippetContext is how many characters of text a snippet keeps on
+	// each side of the match
+	messageSnippetContext = 40
+)
+
+type searchMessagesRequest struct {
+	Query    string `form:"q" binding:"required,max=100"`
+	PageID   int32  `form:"page" binding:"omitempty,min=1"`
+	PageSize int32  `form:"page_size" binding:"omitempty,min=5,max=50"`
+}
+
+// messageSearchResult is one matching message with a snippet of the text
+// around the match; clients jump to it in the history by id
+type messageSearchResult struct {
+	ID        uuid.UUID `json:"id"`
+	SenderID  uuid.UUID `json:"sender_id"`
+	Username  string    `json:"username"`
+	Content   string    `json:"content"`
+	Snippet   string    `json:"snippet"`
+	MediaType *string   `json:"media_type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newMessageSearchResult(id, senderID uuid.UUID, username, content string, mediaType sql.NullString, createdAt time.Time, query string) messageSearchResult {
+	return messageSearchResult{
+		ID:        id,
+		SenderID:  senderID,
+		Username:  username,
+		Content:   content,
+		Snippet:   messageSnippet(content, query),
+		MediaType: nullStringToStrPtr(mediaType),
+		CreatedAt: createdAt,
+	}
+}
+
+// bindMessageSearch reads the search query and page, filling in defaults. It
+// writes a 400 and returns false when they're invalid.
+func bindMessageSearch(ctx *gin.Context) (searchMessagesRequest, bool) {
+	var req searchMessagesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return req, false
+	}
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "q must not be blank"})
+		return req, false
+	}
+	if req.PageID == 0 {
+		req.PageID = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = messageSearchDefaultPageSize
+	}
+	return req, true
+}
+
+// searchDirectMessages serves GET /messages/search?user_id=&q=: the caller's
+// unexpired messages with that user containing q, newest first
+func (server *Server) searchDirectMessages(ctx *gin.Context) {
+	otherUserID, ok := parseUUIDParam(ctx, ctx.Query("user_id"), "user_id")
+	if !ok {
+		return
+	}
+	req, ok := bindMessageSearch(ctx)
+	if !ok {
+		return
+	}
+	authPayload := getAuthPayload(ctx)
+
+	// The same check as reading the history
+	if err := server.checkConnection(ctx, authPayload.UserID, otherUserID); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "You must be connected to this user to chat."})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	pattern := escapeLikePattern(req.Query)
+	rows, err := server.store.SearchDirectMessages(ctx, db.SearchDirectMessagesParams{
+		UserID:      authPayload.UserID,
+		OtherUserID: otherUserID,
+		Query:       pattern,
+		Limit:       req.PageSize,
+		Offset:      (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	total, err := server.store.CountDirectMessageMatches(ctx, db.CountDirectMessageMatchesParams{
+		UserID:      authPayload.UserID,
+		OtherUserID: otherUserID,
+		Query:       pattern,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	results := make([]messageSearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = newMessageSearchResult(row.ID, row.SenderID, row.Username, row.Content, row.MediaType, row.CreatedAt, req.Query)
+	}
+	ctx.JSON(http.StatusOK, newPaginatedResponse(results, req.PageID, req.PageSize, total))
+}
+
+// searchGroupMessages serves GET /groups/:id/messages/search?q=, for members only
+func (server *Server) searchGroupMessages(ctx *gin.Context) {
+	groupID, ok := parseUUIDParam(ctx, ctx.Param("id"), "group_id")
+	if !ok {
+		return
+	}
+	req, ok := bindMessageSearch(ctx)
+	if !ok {
+		return
+	}
+	authPayload := getAuthPayload(ctx)
+	if !server.requireGroupMember(ctx, groupID, authPayload.UserID) {
+		return
+	}
+
+	pattern := escapeLikePattern(req.Query)
+	rows, err := server.store.SearchGroupMessages(ctx, db.SearchGroupMessagesParams{
+		GroupID: groupID,
+		Query:   pattern,
+		Limit:   req.PageSize,
+		Offset:  (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	total, err := server.store.CountGroupMessageMatches(ctx, db.CountGroupMessageMatchesParams{
+		GroupID: groupID,
+		Query:   pattern,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	results := make([]messageSearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = newMessageSearchResult(row.ID, row.SenderID, row.Username, row.Content, row.MediaType, row.CreatedAt, req.Query)
+	}
+	ctx.JSON(http.StatusOK, newPaginatedResponse(results, req.PageID, req.PageSize, total))
+}
+
+// escapeLikePattern makes user input match literally inside a LIKE pattern
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// messageSnippet cuts content down to the first case-insensitive match of
+// query with up to messageSnippetContext characters either side, marking cut
+// ends with an ellipsis. Without a match it keeps the start of the message.
+func messageSnippet(content, query string) string {
+	runes := []rune(content)
+	start := 0
+	if i := strings.Index(strings.ToLower(content), strings.ToLower(query)); i >= 0 && i <= len(content) {
+		start = utf8.RuneCountInString(content[:i])
+	}
+
+	from := max(start-messageSnippetContext, 0)
+	to := min(start+utf8.RuneCountInString(query)+messageSnippetContext, len(runes))
+	snippet := string(runes[from:to])
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	if to < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestMessageSnippet(t *testing.T) {
+	require.Equal(t, "see you at the park", messageSnippet("see you at the park", "PARK"))
+
+	long := strings.Repeat("a", 60) + " birthday " + strings.Repeat("b", 60)
+	snippet := messageSnippet(long, "Birthday")
+	require.Equal(t, "…"+strings.Repeat("a", 39)+" birthday "+strings.Repeat("b", 39)+"…", snippet)
+
+	// Without a match the start of the message is kept
+	require.Equal(t, strings.Repeat("a", 44)+"…", messageSnippet(strings.Repeat("a", 60), "zzzz"))
+}
+
+func TestEscapeLikePattern(t *testing.T) {
+	require.Equal(t, `100\%`, escapeLikePattern("100%"))
+	require.Equal(t, `a\_b\\c`, escapeLikePattern(`a_b\c`))
+}
+
+func TestSearchDirectMessages(t *testing.T) {
+	userID, otherID := uuid.New(), uuid.New()
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?user_id=" + otherID.String() + "&q=+50%25+off&page=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				allowChat(store, otherID)
+				store.EXPECT().
+					SearchDirectMessages(gomock.Any(), db.SearchDirectMessagesParams{
+						UserID:      userID,
+						OtherUserID: otherID,
+						Query:       `50\% off`,
+						Limit:       5,
+						Offset:      5,
+					}).
+					Times(1).
+					Return([]db.SearchDirectMessagesRow{{
+						ID:        uuid.New(),
+						SenderID:  otherID,
+						Username:  "other",
+						Content:   "everything is 50% off today",
+						CreatedAt: time.Now(),
+					}}, nil)
+				store.EXPECT().
+					CountDirectMessageMatches(gomock.Any(), db.CountDirectMessageMatchesParams{
+						UserID:      userID,
+						OtherUserID: otherID,
+						Query:       `50\% off`,
+					}).
+					Times(1).
+					Return(int64(6), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				var rsp PaginatedResponse[messageSearchResult]
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, int64(6), rsp.Total)
+				require.Equal(t, int64(2), rsp.TotalPages)
+				require.Len(t, rsp.Data, 1)
+				require.Equal(t, "everything is 50% off today", rsp.Data[0].Snippet)
+				require.Nil(t, rsp.Data[0].MediaType)
+			},
+		},
+		{
+			name:  "NotConnected",
+			query: "?user_id=" + otherID.String() + "&q=hi",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).Times(2).Return(false, nil)
+				store.EXPECT().GetConnection(gomock.Any(), gomock.Any()).Times(1).Return(db.Connection{}, sql.ErrNoRows)
+				store.EXPECT().SearchDirectMessages(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:  "BlankQuery",
+			query: "?user_id=" + otherID.String() + "&q=+++",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SearchDirectMessages(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "BadUserID",
+			query: "?user_id=nope&q=hi",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SearchDirectMessages(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := getWithAuth(t, server, "/messages/search"+tc.query, userID)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestSearchGroupMessagesNotMember(t *testing.T) {
+	userID, groupID := uuid.New(), uuid.New()
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CheckGroupMembership(gomock.Any(), db.CheckGroupMembershipParams{GroupID: groupID, UserID: userID}).
+		Times(1).
+		Return(false, nil)
+	store.EXPECT().GetGroupByID(gomock.Any(), groupID).Times(1).Return(db.Group{ID: groupID}, nil)
+	store.EXPECT().SearchGroupMessages(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	recorder := getWithAuth(t, server, "/groups/"+groupID.String()+"/messages/search?q=hi", userID)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+}
+
+func getWithAuth(t *testing.T, server *Server, path string, userID uuid.UUID) *httptest.ResponseRecorder {
+	request, err := http.NewRequest(http.MethodGet, path, nil)
+	require.NoError(t, err)
+	accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	return recorder
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/realtime"
+)
+
+const (
+	metricsNamespace = "locolive"
+	// streamLagTimeout bounds the Redis call made for each scrape
+	streamLagTimeout = 2 * time.Second
+	// unmatchedRoute labels requests that hit no route, so unknown paths
+	// don't each get a series
+	unmatchedRoute = "unmatched"
+)
+
+// serverMetrics are the Prometheus metrics served at GET /metrics. Each server
+// has its own registry, so tests can build as many servers as they like.
+type serverMetrics struct {
+	registry          *prometheus.Registry
+	requests          *prometheus.CounterVec
+	requestDuration   *prometheus.HistogramVec
+	messagesSent      prometheus.Counter
+	storiesCreated    prometheus.Counter
+	crossingsDetected prometheus.Counter
+}
+
+func newServerMetrics(hub *realtime.Hub) *serverMetrics {
+	m := &serverMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests answered, by route template and status.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to answer HTTP requests, by route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		messagesSent: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "messages_sent_total",
+			Help:      "Chat messages delivered, including scheduled and shared ones.",
+		}),
+		storiesCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "stories_created_total",
+			Help:      "Stories posted.",
+		}),
+		crossingsDetected: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "crossings_detected_total",
+			Help:      "Crossings detected between two users.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.messagesSent,
+		m.storiesCreated,
+		m.crossingsDetected,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "websocket_connections",
+			Help:      "WebSocket connections open on this instance.",
+		}, func() float64 {
+			return float64(hub.ConnectionCount())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "routing_stream_lag",
+			Help:      "Chat routing stream entries this instance hasn't read yet.",
+		}, func() float64 {
+			return streamLag(hub)
+		}),
+	)
+	return m
+}
+
+// streamLag reads the hub's routing stream lag for a scrape. NaN stands for
+// unknown, so an unreachable Redis doesn't look like an empty backlog.
+func streamLag(hub *realtime.Hub) float64 {
+	ctx, cancel := context.WithTimeout(context.Background(), streamLagTimeout)
+	defer cancel()
+	lag, err := hub.StreamLag(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("cannot read routing stream lag")
+		return math.NaN()
+	}
+	if lag < 0 {
+		return math.NaN()
+	}
+	return float64(lag)
+}
+
+// metricsMiddleware counts and times each request under its route template,
+// never the raw path, to keep the number of series down
+func (server *Server) metricsMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(ctx.Writer.Status())
+		server.metrics.requests.WithLabelValues(ctx.Request.Method, route, status).Inc()
+		server.metrics.requestDuration.WithLabelValues(ctx.Request.Method, route, status).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// serveMetrics serves GET /metrics in the Prometheus text format
+func (server *Server) serveMetrics(ctx *gin.Context) {
+	promhttp.HandlerFor(server.metrics.registry, promhttp.HandlerOpts{}).ServeHTTP(ctx.Writer, ctx.Request)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/service/location"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	userID := uuid.New()
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	postJSON(t, server, "/messages", gin.H{}, &userID)
+	for _, path := range []string{"/users/" + uuid.NewString(), "/no/such/route"} {
+		request, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+		server.router.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	// Routes are labelled by template, never by the raw path
+	require.Equal(t, 1.0, testutil.ToFloat64(server.metrics.requests.WithLabelValues(http.MethodPost, "/messages", "400")))
+	require.Equal(t, 1.0, testutil.ToFloat64(server.metrics.requests.WithLabelValues(http.MethodGet, "/users/:id", "401")))
+	require.Equal(t, 1.0, testutil.ToFloat64(server.metrics.requests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")))
+
+	request, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	body := recorder.Body.String()
+	require.Contains(t, body, `locolive_http_requests_total{method="POST",route="/messages",status="400"} 1`)
+	require.Contains(t, body, `locolive_http_request_duration_seconds_bucket{method="POST",route="/messages",status="400"`)
+	require.Contains(t, body, "locolive_websocket_connections 0")
+	require.Contains(t, body, "locolive_routing_stream_lag")
+	require.Contains(t, body, "locolive_messages_sent_total 0")
+}
+
+func TestCrossingsDetectedCountedOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	user1, user2 := uuid.New(), uuid.New()
+	crossing := db.Crossing{ID: uuid.New(), UserID1: user1, UserID2: user2}
+	server.sendCrossingNotification(user1, location.CrossingEvent{Crossing: crossing, CrossedWith: user2})
+	server.sendCrossingNotification(user2, location.CrossingEvent{Crossing: crossing, CrossedWith: user1})
+
+	require.Equal(t, 1.0, testutil.ToFloat64(server.metrics.crossingsDetected))
+}
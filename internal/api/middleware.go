@@ -70,6 +70,12 @@ func adminMiddleware(server *Server) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
+		// An admin viewing as a user must never pick up that user's admin rights
+		if authPayload.IsImpersonation() {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(ErrImpersonationReadOnly))
+			return
+		}
+
 		// Get user from database to check role
 		user, err := server.store.GetUserByID(ctx, authPayload.UserID)
 		if err != nil {
@@ -91,24 +97,34 @@ func adminMiddleware(server *Server) gin.HandlerFunc {
 	}
 }
 
-// corsMiddleware handles the CORS middleware
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		origin := c.Request.Header.Get("Origin")
-		if origin != "" {
-			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
-		} else {
-			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+var ErrPhoneNotVerified = errors.New("verify your phone number first")
+
+// phoneVerifiedMiddleware refuses users who haven't verified their phone when
+// REQUIRE_PHONE_VERIFICATION is on. Google sign-ins have no real phone and are
+// let through.
+func phoneVerifiedMiddleware(server *Server) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !server.config.RequirePhoneVerification {
+			ctx.Next()
+			return
 		}
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		user, err := server.store.GetUserByID(ctx, authPayload.UserID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
 			return
 		}
 
-		c.Next()
+		if !user.PhoneVerified && !user.GoogleID.Valid {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(ErrPhoneNotVerified))
+			return
+		}
+
+		ctx.Next()
 	}
 }
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"privacy-social-backend/internal/service/moderation"
+)
+
+// rejectBlockedText answers 422 when text has a blocked word, reporting whether it did
+func (server *Server) rejectBlockedText(ctx *gin.Context, text string) bool {
+	if _, blocked := server.moderation.FilterText(text); blocked {
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(moderation.ErrBlockedContent))
+		return true
+	}
+	return false
+}
+
+// moderateCaption returns the caption to store: as sent, or with blocked words
+// starred out under MODERATION_MODE=mask. Under reject it answers 422 and
+// reports false instead.
+func (server *Server) moderateCaption(ctx *gin.Context, caption string) (string, bool) {
+	clean, blocked := server.moderation.FilterText(caption)
+	if !blocked {
+		return caption, true
+	}
+	if server.moderation.Mode() == moderation.ModeMask {
+		return clean, true
+	}
+	ctx.JSON(http.StatusUnprocessableEntity, errorResponse(moderation.ErrBlockedContent))
+	return "", false
+}
+
+// Admin: Reload the blocked word list from MODERATION_WORDS and MODERATION_WORDS_FILE
+func (server *Server) reloadModerationWords(ctx *gin.Context) {
+	count, err := server.moderation.Reload()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"words": count})
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/service/moderation"
+)
+
+func TestBlockedContentRejected(t *testing.T) {
+	testCases := []struct {
+		name   string
+		method string
+		url    string
+		body   gin.H
+	}{
+		{
+			name:   "SendMessage",
+			method: http.MethodPost,
+			url:    "/messages",
+			body:   gin.H{"receiver_id": uuid.New(), "content": "oh D4RN"},
+		},
+		{
+			name:   "EditMessage",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/messages/%s", uuid.New()),
+			body:   gin.H{"content": "darn it"},
+		},
+		{
+			name:   "UpdateStoryCaption",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/stories/%s", uuid.New()),
+			body:   gin.H{"caption": "darn!"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			// Nothing may reach the store once the content is refused
+			store := mockdb.NewMockStore(ctrl)
+
+			server := newTestServer(t, store)
+			filter, err := moderation.NewFilter(moderation.Config{Words: "darn"})
+			require.NoError(t, err)
+			server.moderation = filter
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			accessToken, _, err := server.tokenMaker.CreateToken("user", uuid.New(), time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusUnprocessableEntity, recorder.Code, recorder.Body.String())
+		})
+	}
+}
+
+func TestModerateCaptionMask(t *testing.T) {
+	server := newTestServer(t, mockdb.NewMockStore(gomock.NewController(t)))
+	filter, err := moderation.NewFilter(moderation.Config{Words: "darn", Mode: moderation.ModeMask})
+	require.NoError(t, err)
+	server.moderation = filter
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	caption, ok := server.moderateCaption(ctx, "darn this view")
+	require.True(t, ok)
+	require.Equal(t, "**** this view", caption)
+}
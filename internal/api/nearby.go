@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mmcloughlin/geohash"
+
+	"privacy-social-backend/internal/service/location"
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// activeNowWindow is how recent a location ping must be to count as active
+	activeNowWindow = 15 * time.Minute
+	// activeNearbyCacheTTL keeps the count fresh enough without hitting Redis geo on every open
+	activeNearbyCacheTTL = 1 * time.Minute
+	// activeNearbyGeohashPrecision is the cell (~1.2km) that counts are shared across
+	activeNearbyGeohashPrecision = 6
+	// defaultActiveNearbyRadius applies when no radius is requested
+	defaultActiveNearbyRadius = 2000.0
+	// activeNearbyRadiusStep snaps radii so nearby requests share cache entries
+	activeNearbyRadiusStep = 500.0
+)
+
+// activeCountBuckets are the only counts ever shown, so a response never pins
+// down how many people are nearby when there are only a few
+var activeCountBuckets = []int{5, 10, 20, 50, 100, 200, 500, 1000}
+
+type getActiveNearbyRequest struct {
+	Latitude  float64 `form:"lat" binding:"required,min=-90,max=90"`
+	Longitude float64 `form:"lng" binding:"required,min=-180,max=180"`
+	// Radius in meters; zero uses defaultActiveNearbyRadius
+	Radius float64 `form:"radius" binding:"omitempty,min=500,max=20000"`
+}
+
+type activeNearbyResponse struct {
+	// AtLeast is the bucket the count falls in; 0 means fewer than the smallest bucket
+	AtLeast int     `json:"at_least"`
+	Label   string  `json:"label"`
+	Radius  float64 `json:"radius"`
+}
+
+// bucketActiveCount rounds a count down to its bucket
+func bucketActiveCount(count int) int {
+	bucket := 0
+	for _, b := range activeCountBuckets {
+		if count < b {
+			break
+		}
+		bucket = b
+	}
+	return bucket
+}
+
+// activeNearbyLabel words a bucket; 0 reads as fewer than the smallest bucket
+// or minUsers, whichever hides more
+func activeNearbyLabel(bucket, minUsers int) string {
+	if bucket == 0 {
+		return fmt.Sprintf("Fewer than %d people active nearby", max(activeCountBuckets[0], minUsers))
+	}
+	return fmt.Sprintf("%d+ people active nearby", bucket)
+}
+
+// getActiveNearby serves GET /nearby/active-count: a coarse count of users who
+// pinged their location recently around a point. Ghost-mode users drop out of the
+// location index, counts below LOCATION_MIN_USERS read as 0, and only the bucket
+// leaves the server.
+func (server *Server) getActiveNearby(ctx *gin.Context) {
+	var req getActiveNearbyRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	radius := defaultActiveNearbyRadius
+	if req.Radius > 0 {
+		radius = math.Round(req.Radius/activeNearbyRadiusStep) * activeNearbyRadiusStep
+	}
+
+	// Everyone in the same cell shares one count centred on that cell
+	cell := geohash.EncodeWithPrecision(req.Latitude, req.Longitude, activeNearbyGeohashPrecision)
+	lat, lng := geohash.DecodeCenter(cell)
+	cacheKey := util.RedisKey(fmt.Sprintf("nearby:active:%s:%.0f", cell, radius))
+
+	cachedData, err := server.redis.Get(ctx, cacheKey).Result()
+	if err == nil && cachedData != "" {
+		ctx.Header("X-Cache", "HIT")
+		ctx.Data(http.StatusOK, "application/json", []byte(cachedData))
+		return
+	}
+
+	count, err := server.location.CountActiveNearby(ctx, lat, lng, radius, util.Now().Add(-activeNowWindow))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	minUsers := server.locationMinUsers()
+	bucket := bucketActiveCount(location.AnonymizeCount(count, minUsers))
+	response := activeNearbyResponse{
+		AtLeast: bucket,
+		Label:   activeNearbyLabel(bucket, minUsers),
+		Radius:  radius,
+	}
+
+	responseJSON, _ := json.Marshal(response)
+	server.redis.Set(ctx, cacheKey, responseJSON, activeNearbyCacheTTL)
+
+	ctx.Header("X-Cache", "MISS")
+	ctx.JSON(http.StatusOK, response)
+}
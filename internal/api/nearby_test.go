@@ -0,0 +1,19 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketActiveCount(t *testing.T) {
+	require.Equal(t, 0, bucketActiveCount(0))
+	require.Equal(t, 0, bucketActiveCount(4))
+	require.Equal(t, 5, bucketActiveCount(5))
+	require.Equal(t, 20, bucketActiveCount(49))
+	require.Equal(t, 1000, bucketActiveCount(5000))
+
+	require.Equal(t, "Fewer than 5 people active nearby", activeNearbyLabel(0, 3))
+	require.Equal(t, "Fewer than 8 people active nearby", activeNearbyLabel(0, 8))
+	require.Equal(t, "50+ people active nearby", activeNearbyLabel(50, 8))
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/location"
+	"privacy-social-backend/internal/util"
+)
+
+// defaultNearbyUsersRadius applies when no radius is requested
+const defaultNearbyUsersRadius = 1000.0
+
+// nearbyDistanceBuckets are the only distances ever shown, so a response never
+// gives away exactly how far someone is
+var nearbyDistanceBuckets = []struct {
+	meters float64
+	label  string
+}{
+	{100, "<100m"},
+	{500, "<500m"},
+	{1000, "<1km"},
+	{2000, "<2km"},
+	{5000, "<5km"},
+}
+
+type getNearbyUsersRequest struct {
+	// Radius in meters; zero uses defaultNearbyUsersRadius
+	Radius float64 `form:"radius" binding:"omitempty,min=100,max=5000"`
+}
+
+type nearbyUserResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	FullName  string    `json:"full_name"`
+	AvatarUrl string    `json:"avatar_url"`
+	Distance  string    `json:"distance"`
+}
+
+type nearbyUsersResponse struct {
+	Users  []nearbyUserResponse `json:"users"`
+	Radius float64              `json:"radius"`
+}
+
+// bucketNearbyDistance words meters as the smallest bucket it fits under
+func bucketNearbyDistance(meters float64) string {
+	for _, b := range nearbyDistanceBuckets {
+		if meters < b.meters {
+			return b.label
+		}
+	}
+	return nearbyDistanceBuckets[len(nearbyDistanceBuckets)-1].label
+}
+
+// getNearbyUsers serves GET /nearby: users who pinged their location recently
+// within the radius of the caller's own last ping, nearest first. Ghost-mode,
+// blocked and shadow-banned users are left out, as are those hiding their
+// location or with a profile the caller can't see. Only a distance bucket
+// leaves the server, never coordinates.
+func (server *Server) getNearbyUsers(ctx *gin.Context) {
+	var req getNearbyUsersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := getAuthPayload(ctx)
+	radius := defaultNearbyUsersRadius
+	if req.Radius > 0 {
+		radius = req.Radius
+	}
+
+	nearby, err := server.location.FindNearbyUsers(ctx, authPayload.UserID, radius, util.Now().Add(-activeNowWindow))
+	if err != nil {
+		if errors.Is(err, location.ErrNoRecentLocation) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := nearbyUsersResponse{Users: []nearbyUserResponse{}, Radius: radius}
+	if len(nearby) == 0 {
+		ctx.JSON(http.StatusOK, rsp)
+		return
+	}
+
+	ids := make([]uuid.UUID, len(nearby))
+	for i, n := range nearby {
+		ids[i] = n.UserID
+	}
+	visible, err := server.store.ListNearbyUsers(ctx, db.ListNearbyUsersParams{
+		UserIds:  ids,
+		ViewerID: authPayload.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	byID := make(map[uuid.UUID]db.ListNearbyUsersRow, len(visible))
+	for _, u := range visible {
+		byID[u.ID] = u
+	}
+	// Keep the nearest-first order of the location search
+	for _, n := range nearby {
+		u, ok := byID[n.UserID]
+		if !ok {
+			continue
+		}
+		rsp.Users = append(rsp.Users, nearbyUserResponse{
+			ID:        u.ID,
+			Username:  u.Username,
+			FullName:  u.FullName,
+			AvatarUrl: u.AvatarUrl.String,
+			Distance:  bucketNearbyDistance(n.DistanceMeters),
+		})
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
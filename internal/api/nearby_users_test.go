@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestBucketNearbyDistance(t *testing.T) {
+	require.Equal(t, "<100m", bucketNearbyDistance(0))
+	require.Equal(t, "<500m", bucketNearbyDistance(100))
+	require.Equal(t, "<1km", bucketNearbyDistance(999))
+	require.Equal(t, "<5km", bucketNearbyDistance(4999))
+	require.Equal(t, "<5km", bucketNearbyDistance(5000))
+}
+
+func TestGetNearbyUsers(t *testing.T) {
+	const lat, lng = 12.9716, 77.5946
+	userID, bobID, daveID, farID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	testCases := []struct {
+		name          string
+		query         string
+		setup         func(t *testing.T, server *Server)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setup: func(t *testing.T, server *Server) {
+				// Far enough apart that no crossings are detected
+				ctx := context.Background()
+				require.NoError(t, server.location.UpdateUserLocation(ctx, userID, lat, lng))
+				require.NoError(t, server.location.UpdateUserLocation(ctx, bobID, lat+0.003, lng))   // ~330m
+				require.NoError(t, server.location.UpdateUserLocation(ctx, daveID, lat, lng+0.0015)) // ~160m
+				require.NoError(t, server.location.UpdateUserLocation(ctx, farID, lat+0.1, lng))     // ~11km
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListNearbyUsers(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ListNearbyUsersParams) ([]db.ListNearbyUsersRow, error) {
+						require.Equal(t, userID, arg.ViewerID)
+						require.ElementsMatch(t, []uuid.UUID{bobID, daveID}, arg.UserIds)
+						// The store's order doesn't matter
+						return []db.ListNearbyUsersRow{
+							{ID: bobID, Username: "bob"},
+							{ID: daveID, Username: "dave"},
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				var rsp nearbyUsersResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, defaultNearbyUsersRadius, rsp.Radius)
+				require.Len(t, rsp.Users, 2)
+				require.Equal(t, daveID, rsp.Users[0].ID)
+				require.Equal(t, "<500m", rsp.Users[0].Distance)
+				require.Equal(t, bobID, rsp.Users[1].ID)
+				require.NotContains(t, recorder.Body.String(), "lat")
+			},
+		},
+		{
+			// Users the store filters out (blocked, private, ghosted) never show
+			name: "Filtered",
+			setup: func(t *testing.T, server *Server) {
+				ctx := context.Background()
+				require.NoError(t, server.location.UpdateUserLocation(ctx, userID, lat, lng))
+				require.NoError(t, server.location.UpdateUserLocation(ctx, bobID, lat+0.003, lng))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListNearbyUsers(gomock.Any(), gomock.Any()).Times(1).Return(nil, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				require.JSONEq(t, `[]`, mustJSONField(t, recorder.Body.Bytes(), "users"))
+			},
+		},
+		{
+			name:  "NoRecentLocation",
+			setup: func(t *testing.T, server *Server) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListNearbyUsers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:  "RadiusTooLarge",
+			query: "?radius=50000",
+			setup: func(t *testing.T, server *Server) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListNearbyUsers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			tc.setup(t, server)
+
+			request, err := http.NewRequest(http.MethodGet, "/nearby"+tc.query, nil)
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
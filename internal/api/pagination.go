@@ -0,0 +1,25 @@
+package api
+
+// PaginatedResponse is one page of a list along with what a client needs to
+// render a pager
+type PaginatedResponse[T any] struct {
+	Page       int32 `json:"page"`
+	PageSize   int32 `json:"page_size"`
+	Total      int64 `json:"total"`
+	TotalPages int64 `json:"total_pages"`
+	Data       []T   `json:"data"`
+}
+
+// newPaginatedResponse wraps a page of data; an empty page is sent as [] rather than null
+func newPaginatedResponse[T any](data []T, page, pageSize int32, total int64) PaginatedResponse[T] {
+	if data == nil {
+		data = []T{}
+	}
+	return PaginatedResponse[T]{
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: (total + int64(pageSize) - 1) / int64(pageSize),
+		Data:       data,
+	}
+}
@@ -1,12 +1,12 @@
 package api
 
 import (
-	"database/sql"
 	"net/http"
 	"time"
 
 	"privacy-social-backend/internal/repository/db"
 	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
 
 	"github.com/gin-gonic/gin"
 )
@@ -32,11 +32,11 @@ func (server *Server) boostProfile(ctx *gin.Context) {
 	}
 
 	// Activate 24h Boost
-	expiresAt := time.Now().UTC().Add(24 * time.Hour)
+	expiresAt := util.Now().Add(24 * time.Hour)
 
 	_, err = server.store.BoostUser(ctx, db.BoostUserParams{
 		ID:             authPayload.UserID,
-		BoostExpiresAt: sql.NullTime{Time: expiresAt, Valid: true},
+		BoostExpiresAt: util.NullTime{Time: expiresAt, Valid: true},
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/realtime"
+	"privacy-social-backend/internal/repository"
+	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
+)
+
+const lastSeenTimeout = 5 * time.Second
+
+// recordLastSeen saves when a user's last connection to this instance closed
+func recordLastSeen(store repository.Store) realtime.OfflineFunc {
+	return func(userID uuid.UUID) {
+		ctx, cancel := context.WithTimeout(context.Background(), lastSeenTimeout)
+		defer cancel()
+
+		if err := store.UpdateUserLastSeen(ctx, userID); err != nil {
+			log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to record last seen")
+		}
+	}
+}
+
+type presenceResponse struct {
+	UserID   uuid.UUID     `json:"user_id"`
+	Online   bool          `json:"online"`
+	LastSeen util.NullTime `json:"last_seen"`
+}
+
+// getUserPresence tells whether a user is connected to any instance and when
+// they were last seen. Ghost-mode and blocked users always look offline.
+func (server *Server) getUserPresence(ctx *gin.Context) {
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	presence, err := server.store.GetUserPresence(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := presenceResponse{UserID: userID}
+
+	hidden := presence.IsGhostMode && !util.IsExpired(presence.GhostModeExpiresAt, util.Now())
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !hidden && authPayload.UserID != userID {
+		for _, pair := range [][2]uuid.UUID{{userID, authPayload.UserID}, {authPayload.UserID, userID}} {
+			blocked, err := server.isUserBlockedCached(ctx, pair[0], pair[1])
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+			if blocked {
+				hidden = true
+				break
+			}
+		}
+	}
+
+	if !hidden {
+		rsp.Online = server.hub.IsUserOnline(ctx, userID)
+		rsp.LastSeen = presence.LastSeenAt
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/realtime"
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestGetUserPresence(t *testing.T) {
+	viewerID := uuid.New()
+	userID := uuid.New()
+	lastSeen := util.NullTime{Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true}
+
+	testCases := []struct {
+		name       string
+		presence   db.GetUserPresenceRow
+		connected  bool
+		blocked    bool
+		wantOnline bool
+		wantSeen   bool
+	}{
+		{
+			name:       "Online",
+			presence:   db.GetUserPresenceRow{LastSeenAt: lastSeen},
+			connected:  true,
+			wantOnline: true,
+			wantSeen:   true,
+		},
+		{
+			name:     "Offline",
+			presence: db.GetUserPresenceRow{LastSeenAt: lastSeen},
+			wantSeen: true,
+		},
+		{
+			name:      "GhostMode",
+			presence:  db.GetUserPresenceRow{IsGhostMode: true, LastSeenAt: lastSeen},
+			connected: true,
+		},
+		{
+			name: "GhostModeExpired",
+			presence: db.GetUserPresenceRow{
+				IsGhostMode:        true,
+				GhostModeExpiresAt: util.NullTime{Time: util.Now().Add(-time.Minute), Valid: true},
+				LastSeenAt:         lastSeen,
+			},
+			connected:  true,
+			wantOnline: true,
+			wantSeen:   true,
+		},
+		{
+			name:      "Blocked",
+			presence:  db.GetUserPresenceRow{LastSeenAt: lastSeen},
+			connected: true,
+			blocked:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUserPresence(gomock.Any(), userID).Times(1).Return(tc.presence, nil)
+			store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).AnyTimes().Return(tc.blocked, nil)
+
+			server := newTestServer(t, store)
+			mr := miniredis.RunT(t)
+			rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { rdb.Close() })
+			server.redis = rdb
+			server.hub = realtime.NewHub(rdb)
+
+			// Connected through another instance: only the Redis key says so
+			if tc.connected {
+				require.NoError(t, mr.Set(util.RedisKey("presence:"+userID.String()), "1"))
+			}
+
+			request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%s/presence", userID), nil)
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("user", viewerID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+			var rsp presenceResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			require.Equal(t, userID, rsp.UserID)
+			require.Equal(t, tc.wantOnline, rsp.Online)
+			require.Equal(t, tc.wantSeen, rsp.LastSeen.Valid)
+		})
+	}
+}
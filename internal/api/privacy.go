@@ -8,9 +8,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 
 	"privacy-social-backend/internal/repository/db"
 	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
 )
 
 // Privacy Settings Handlers
@@ -57,9 +59,105 @@ func (server *Server) updatePrivacySettings(ctx *gin.Context) {
 		return
 	}
 
+	server.invalidatePrivacyCache(payload.UserID)
+
 	ctx.JSON(http.StatusOK, newPrivacySettingResponse(settings))
 }
 
+// privacyAudiences are who a setting such as who_can_message may allow
+var privacyAudiences = []string{"everyone", "connections", "nobody"}
+
+// profileVisibilities are the allowed profile_visibility values
+var profileVisibilities = []string{"public", "connections", "private"}
+
+// updateMyPrivacyRequest changes only the settings it includes
+type updateMyPrivacyRequest struct {
+	WhoCanMessage     *string `json:"who_can_message"`
+	WhoCanSeeStories  *string `json:"who_can_see_stories"`
+	ProfileVisibility *string `json:"profile_visibility"`
+	ShowLocation      *bool   `json:"show_location"`
+}
+
+type myPrivacyResponse struct {
+	PrivacySettingResponse
+	ProfileVisibility string `json:"profile_visibility"`
+}
+
+// updateMyPrivacy applies req to the user's privacy settings and profile
+// visibility with q and returns the settings as they now stand
+func updateMyPrivacy(ctx context.Context, q db.Querier, userID uuid.UUID, req updateMyPrivacyRequest) (myPrivacyResponse, error) {
+	arg := db.UpsertPrivacySettingsParams{
+		UserID:           userID,
+		WhoCanMessage:    strPtrToNullString(req.WhoCanMessage),
+		WhoCanSeeStories: strPtrToNullString(req.WhoCanSeeStories),
+	}
+	if req.ShowLocation != nil {
+		arg.ShowLocation = sql.NullBool{Bool: *req.ShowLocation, Valid: true}
+	}
+	settings, err := q.UpsertPrivacySettings(ctx, arg)
+	if err != nil {
+		return myPrivacyResponse{}, err
+	}
+
+	var visibility sql.NullString
+	if req.ProfileVisibility != nil {
+		profile, err := q.UpdateUserProfile(ctx, db.UpdateUserProfileParams{
+			ID:                userID,
+			ProfileVisibility: strPtrToNullString(req.ProfileVisibility),
+		})
+		if err != nil {
+			return myPrivacyResponse{}, err
+		}
+		visibility = profile.ProfileVisibility
+	} else {
+		user, err := q.GetUserByID(ctx, userID)
+		if err != nil {
+			return myPrivacyResponse{}, err
+		}
+		visibility = user.ProfileVisibility
+	}
+
+	return myPrivacyResponse{
+		PrivacySettingResponse: newPrivacySettingResponse(settings),
+		ProfileVisibility:      visibility.String,
+	}, nil
+}
+
+// updateMyPrivacySettings serves PUT /users/me/privacy. Unlike PUT /privacy,
+// settings left out of the body keep their current value.
+func (server *Server) updateMyPrivacySettings(ctx *gin.Context) {
+	var req updateMyPrivacyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if rejectInvalidOption(ctx, "who_can_message", req.WhoCanMessage, privacyAudiences) ||
+		rejectInvalidOption(ctx, "who_can_see_stories", req.WhoCanSeeStories, privacyAudiences) ||
+		rejectInvalidOption(ctx, "profile_visibility", req.ProfileVisibility, profileVisibilities) {
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var rsp myPrivacyResponse
+	err := server.store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		rsp, err = updateMyPrivacy(ctx, q, payload.UserID, req)
+		return err
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.invalidatePrivacyCache(payload.UserID)
+	if req.ProfileVisibility != nil {
+		server.invalidateProfileCache(payload.UserID)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
 func (server *Server) getPrivacySettings(ctx *gin.Context) {
 	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
@@ -88,6 +186,8 @@ type blockUserRequest struct {
 	UserID string `json:"user_id" binding:"required,uuid"`
 }
 
+// blockUser serves POST /users/block, which names the user in the body.
+// POST /users/:id/block is the same through the path.
 func (server *Server) blockUser(ctx *gin.Context) {
 	var req blockUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -95,21 +195,50 @@ func (server *Server) blockUser(ctx *gin.Context) {
 		return
 	}
 
-	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	blockID, ok := parseUUIDParam(ctx, req.UserID, "user_id")
 	if !ok {
 		return
 	}
+	server.block(ctx, blockID)
+}
+
+// blockUserByID serves POST /users/:id/block
+func (server *Server) blockUserByID(ctx *gin.Context) {
+	blockID, ok := parseUUIDParam(ctx, ctx.Param("id"), "user_id")
+	if !ok {
+		return
+	}
+	server.block(ctx, blockID)
+}
+
+// blockAndDisconnect records the block and removes any connection between the
+// two users, so neither can keep chatting or seeing stories through it
+func blockAndDisconnect(ctx context.Context, q db.Querier, blockerID, blockedID uuid.UUID) error {
+	_, err := q.BlockUser(ctx, db.BlockUserParams{
+		BlockerID: blockerID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		return err
+	}
+	return q.DeleteConnection(ctx, db.DeleteConnectionParams{
+		RequesterID: blockerID,
+		TargetID:    blockedID,
+	})
+}
+
+// block has the authenticated user block blockID. Blocking someone already
+// blocked succeeds.
+func (server *Server) block(ctx *gin.Context, blockID uuid.UUID) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
 	// Prevent blocking self
-	if payload.UserID == blockID {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "cannot block yourself"})
+	if rejectSelfTarget(ctx, payload.UserID, blockID, "cannot block yourself") {
 		return
 	}
 
-	_, err := server.store.BlockUser(ctx, db.BlockUserParams{
-		BlockerID: payload.UserID,
-		BlockedID: blockID,
+	err := server.store.ExecTx(ctx, func(q *db.Queries) error {
+		return blockAndDisconnect(ctx, q, payload.UserID, blockID)
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -117,13 +246,25 @@ func (server *Server) blockUser(ctx *gin.Context) {
 	}
 
 	// Invalidate caches
-	server.invalidateProfileCache(payload.UserID)
-	server.invalidateProfileCache(blockID)
-	server.redis.Del(context.Background(), "connections:"+payload.UserID.String())
+	server.invalidateBlockCache(payload.UserID, blockID)
+	server.invalidateConversationCache(payload.UserID, blockID)
+	for _, userID := range []uuid.UUID{payload.UserID, blockID} {
+		server.invalidateRecommendationsCache(userID)
+		server.invalidateProfileCache(userID)
+		server.invalidateCrossingsCache(userID)
+		server.invalidateUserFeedCache(userID)
+		server.redis.Del(context.Background(),
+			util.RedisKey("connections:"+userID.String()),
+			util.RedisKey("stories:connections:"+userID.String()),
+			storyRingsCacheKey(userID),
+		)
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "user blocked"})
 }
 
+// unblockUser serves DELETE /users/:id/block and DELETE /users/block/:id.
+// Unblocking someone who isn't blocked succeeds.
 func (server *Server) unblockUser(ctx *gin.Context) {
 	targetIDStr := ctx.Param("id")
 	targetID, ok := parseUUIDParam(ctx, targetIDStr, "user_id")
@@ -132,6 +273,9 @@ func (server *Server) unblockUser(ctx *gin.Context) {
 	}
 
 	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if rejectSelfTarget(ctx, payload.UserID, targetID, "cannot unblock yourself") {
+		return
+	}
 
 	err := server.store.UnblockUser(ctx, db.UnblockUserParams{
 		BlockerID: payload.UserID,
@@ -142,6 +286,10 @@ func (server *Server) unblockUser(ctx *gin.Context) {
 		return
 	}
 
+	server.invalidateBlockCache(payload.UserID, targetID)
+	server.invalidateUserFeedCache(payload.UserID)
+	server.invalidateUserFeedCache(targetID)
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "user unblocked"})
 }
 
@@ -192,27 +340,89 @@ func (server *Server) toggleGhostMode(ctx *gin.Context) {
 
 	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	var expiresAt sql.NullTime
-	if req.Enabled && req.Duration > 0 {
-		expiresAt = sql.NullTime{
-			Time:  time.Now().Add(time.Duration(req.Duration) * time.Minute),
+	user, err := server.setGhostMode(ctx, payload.UserID, req.Enabled, req.Duration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Return the updated user object so frontend gets fresh data
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type enableGhostModeRequest struct {
+	// 0 or omitted = indefinite; timed ghost mode lasts at most 30 days
+	DurationMinutes int `json:"duration_minutes" binding:"min=0,max=43200"`
+}
+
+type ghostModeResponse struct {
+	IsGhostMode        bool       `json:"is_ghost_mode"`
+	GhostModeExpiresAt *time.Time `json:"ghost_mode_expires_at"`
+}
+
+// enableGhostMode serves POST /users/me/ghost: hide from nearby, presence and
+// crossings for duration_minutes, or until turned off
+func (server *Server) enableGhostMode(ctx *gin.Context) {
+	var req enableGhostModeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.setGhostMode(ctx, payload.UserID, true, req.DurationMinutes)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ghostModeResponse{
+		IsGhostMode:        user.IsGhostMode,
+		GhostModeExpiresAt: user.GhostModeExpiresAt.Ptr(),
+	})
+}
+
+// disableGhostMode serves DELETE /users/me/ghost, ending ghost mode early.
+// Turning it off when it isn't on succeeds.
+func (server *Server) disableGhostMode(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if _, err := server.setGhostMode(ctx, payload.UserID, false, 0); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ghostModeResponse{IsGhostMode: false})
+}
+
+// setGhostMode turns ghost mode on for minutes (0 = indefinitely) or off
+func (server *Server) setGhostMode(ctx context.Context, userID uuid.UUID, enabled bool, minutes int) (db.User, error) {
+	var expiresAt util.NullTime
+	if enabled && minutes > 0 {
+		expiresAt = util.NullTime{
+			Time:  util.Now().Add(time.Duration(minutes) * time.Minute),
 			Valid: true,
 		}
 	}
 
 	// Call existing ToggleGhostMode query - it returns the updated user
 	user, err := server.store.ToggleGhostMode(ctx, db.ToggleGhostModeParams{
-		ID:                 payload.UserID,
-		IsGhostMode:        req.Enabled,
+		ID:                 userID,
+		IsGhostMode:        enabled,
 		GhostModeExpiresAt: expiresAt,
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
+		return user, err
 	}
 
-	// Return the updated user object so frontend gets fresh data
-	ctx.JSON(http.StatusOK, newUserResponse(user))
+	// Ghosts stop counting as active nearby straight away, not once their last ping ages out
+	if enabled {
+		if err := server.location.RemoveUserLocation(ctx, userID); err != nil {
+			log.Error().Err(err).Msg("Failed to remove ghost user from location index")
+		}
+	}
+	return user, nil
 }
 
 func (server *Server) panicMode(ctx *gin.Context) {
@@ -225,6 +435,11 @@ func (server *Server) panicMode(ctx *gin.Context) {
 		return
 	}
 
+	server.invalidatePrivacyCache(payload.UserID)
+	if err := server.location.RemoveUserLocation(ctx, payload.UserID); err != nil {
+		log.Error().Err(err).Msg("Failed to remove user from location index")
+	}
+
 	// Invalidate token/session would be good here but handled by expiry usually
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "all data deleted"})
@@ -0,0 +1,447 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/service/story"
+	"privacy-social-backend/internal/util"
+)
+
+func TestBlockAndDisconnect(t *testing.T) {
+	blockerID := uuid.New()
+	blockedID := uuid.New()
+
+	t.Run("OK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		q := mockdb.NewMockStore(ctrl)
+		gomock.InOrder(
+			q.EXPECT().BlockUser(gomock.Any(), db.BlockUserParams{BlockerID: blockerID, BlockedID: blockedID}).
+				Times(1).Return(db.BlockedUser{}, nil),
+			q.EXPECT().DeleteConnection(gomock.Any(), db.DeleteConnectionParams{RequesterID: blockerID, TargetID: blockedID}).
+				Times(1).Return(nil),
+		)
+		require.NoError(t, blockAndDisconnect(context.Background(), q, blockerID, blockedID))
+	})
+
+	t.Run("BlockFails", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		q := mockdb.NewMockStore(ctrl)
+		q.EXPECT().BlockUser(gomock.Any(), gomock.Any()).Times(1).Return(db.BlockedUser{}, errors.New("boom"))
+		q.EXPECT().DeleteConnection(gomock.Any(), gomock.Any()).Times(0)
+		require.Error(t, blockAndDisconnect(context.Background(), q, blockerID, blockedID))
+	})
+}
+
+func TestBlockUserByID(t *testing.T) {
+	userID := uuid.New()
+	targetID := uuid.New()
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Block",
+			method: http.MethodPost,
+			url:    "/users/" + targetID.String() + "/block",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "BlockInvalidID",
+			method: http.MethodPost,
+			url:    "/users/not-a-uuid/block",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "BlockFails",
+			method: http.MethodPost,
+			url:    "/users/" + targetID.String() + "/block",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).Return(errors.New("rolled back"))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:   "Unblock",
+			method: http.MethodDelete,
+			url:    "/users/" + targetID.String() + "/block",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UnblockUser(gomock.Any(), db.UnblockUserParams{BlockerID: userID, BlockedID: targetID}).
+					Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.redis = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+
+			request, err := http.NewRequest(tc.method, tc.url, nil)
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRelationshipChangesInvalidateFeeds(t *testing.T) {
+	userID := uuid.New()
+	otherID := uuid.New()
+	const cell = "tdr1v"
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		body       gin.H
+		buildStubs func(store *mockdb.MockStore)
+	}{
+		{
+			name:   "Block",
+			method: http.MethodPost,
+			url:    "/users/" + otherID.String() + "/block",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+		},
+		{
+			name:   "Unblock",
+			method: http.MethodDelete,
+			url:    "/users/" + otherID.String() + "/block",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UnblockUser(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+		},
+		{
+			name:   "AcceptConnection",
+			method: http.MethodPost,
+			url:    "/connections/update",
+			body:   gin.H{"requester_id": otherID.String(), "status": "accepted"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateConnectionStatus(gomock.Any(), gomock.Any()).Times(1).Return(db.Connection{}, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), userID).Times(1).Return(db.User{ID: userID}, nil)
+				store.EXPECT().CreateNotification(gomock.Any(), gomock.Any()).Times(1).Return(db.Notification{}, nil)
+			},
+		},
+		{
+			name:   "DeleteConnection",
+			method: http.MethodDelete,
+			url:    "/connections/" + otherID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteConnection(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			ctx := context.Background()
+			before := make(map[uuid.UUID]string)
+			for _, id := range []uuid.UUID{userID, otherID} {
+				key, err := story.FeedCacheKey(ctx, server.redis, cell, 3000, id)
+				require.NoError(t, err)
+				before[id] = key
+			}
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				require.NoError(t, json.NewEncoder(&body).Encode(tc.body))
+			}
+			request, err := http.NewRequest(tc.method, tc.url, &body)
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			// Both sides' feeds depend on the relationship
+			for id, key := range before {
+				after, err := story.FeedCacheKey(ctx, server.redis, cell, 3000, id)
+				require.NoError(t, err)
+				require.NotEqual(t, key, after)
+			}
+		})
+	}
+}
+
+func TestUpdateMyPrivacy(t *testing.T) {
+	userID := uuid.New()
+	nobody := "nobody"
+	private := "private"
+	hidden := false
+
+	t.Run("KeepsUnsetSettings", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		q := mockdb.NewMockStore(ctrl)
+		q.EXPECT().UpsertPrivacySettings(gomock.Any(), db.UpsertPrivacySettingsParams{
+			UserID:        userID,
+			WhoCanMessage: sql.NullString{String: nobody, Valid: true},
+		}).Times(1).Return(db.PrivacySetting{
+			UserID:           userID,
+			WhoCanMessage:    sql.NullString{String: nobody, Valid: true},
+			WhoCanSeeStories: sql.NullString{String: "connections", Valid: true},
+			ShowLocation:     sql.NullBool{Bool: true, Valid: true},
+		}, nil)
+		q.EXPECT().UpdateUserProfile(gomock.Any(), gomock.Any()).Times(0)
+		q.EXPECT().GetUserByID(gomock.Any(), userID).Times(1).
+			Return(db.User{ID: userID, ProfileVisibility: sql.NullString{String: "public", Valid: true}}, nil)
+
+		rsp, err := updateMyPrivacy(context.Background(), q, userID, updateMyPrivacyRequest{WhoCanMessage: &nobody})
+		require.NoError(t, err)
+		require.Equal(t, "nobody", rsp.WhoCanMessage)
+		require.Equal(t, "connections", rsp.WhoCanSeeStories)
+		require.True(t, rsp.ShowLocation)
+		require.Equal(t, "public", rsp.ProfileVisibility)
+	})
+
+	t.Run("ProfileVisibility", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		q := mockdb.NewMockStore(ctrl)
+		q.EXPECT().UpsertPrivacySettings(gomock.Any(), db.UpsertPrivacySettingsParams{
+			UserID:       userID,
+			ShowLocation: sql.NullBool{Bool: false, Valid: true},
+		}).Times(1).Return(db.PrivacySetting{UserID: userID}, nil)
+		q.EXPECT().UpdateUserProfile(gomock.Any(), db.UpdateUserProfileParams{
+			ID:                userID,
+			ProfileVisibility: sql.NullString{String: private, Valid: true},
+		}).Times(1).Return(db.UpdateUserProfileRow{ID: userID, ProfileVisibility: sql.NullString{String: private, Valid: true}}, nil)
+		q.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+
+		rsp, err := updateMyPrivacy(context.Background(), q, userID, updateMyPrivacyRequest{
+			ProfileVisibility: &private,
+			ShowLocation:      &hidden,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "private", rsp.ProfileVisibility)
+	})
+}
+
+func TestUpdateMyPrivacySettingsValidation(t *testing.T) {
+	userID := uuid.New()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"who_can_message": "everyone", "profile_visibility": "connections"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownWhoCanMessage",
+			body: gin.H{"who_can_message": "friends"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				var rsp struct {
+					Field   string   `json:"field"`
+					Allowed []string `json:"allowed"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, "who_can_message", rsp.Field)
+				require.Equal(t, privacyAudiences, rsp.Allowed)
+			},
+		},
+		{
+			name: "UnknownProfileVisibility",
+			body: gin.H{"profile_visibility": "hidden"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "public, connections, private")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.redis = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPut, "/users/me/privacy", bytes.NewReader(data))
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestEnableGhostMode(t *testing.T) {
+	userID := uuid.New()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Timed",
+			body: gin.H{"duration_minutes": 90},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ToggleGhostMode(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ToggleGhostModeParams) (db.User, error) {
+						require.Equal(t, userID, arg.ID)
+						require.True(t, arg.IsGhostMode)
+						require.True(t, arg.GhostModeExpiresAt.Valid)
+						require.WithinDuration(t, util.Now().Add(90*time.Minute), arg.GhostModeExpiresAt.Time, time.Minute)
+						return db.User{ID: userID, IsGhostMode: true, GhostModeExpiresAt: arg.GhostModeExpiresAt}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				var rsp ghostModeResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.True(t, rsp.IsGhostMode)
+				require.NotNil(t, rsp.GhostModeExpiresAt)
+			},
+		},
+		{
+			name: "Indefinite",
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ToggleGhostMode(gomock.Any(), db.ToggleGhostModeParams{ID: userID, IsGhostMode: true}).
+					Times(1).
+					Return(db.User{ID: userID, IsGhostMode: true}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				require.JSONEq(t, `{"is_ghost_mode":true,"ghost_mode_expires_at":null}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "TooLong",
+			body: gin.H{"duration_minutes": 31 * 24 * 60},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ToggleGhostMode(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Negative",
+			body: gin.H{"duration_minutes": -5},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ToggleGhostMode(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := postJSON(t, server, "/users/me/ghost", tc.body, &userID)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDisableGhostMode(t *testing.T) {
+	userID := uuid.New()
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ToggleGhostMode(gomock.Any(), db.ToggleGhostModeParams{ID: userID, IsGhostMode: false}).
+		Times(1).
+		Return(db.User{ID: userID}, nil)
+
+	server := newTestServer(t, store)
+	request, err := http.NewRequest(http.MethodDelete, "/users/me/ghost", nil)
+	require.NoError(t, err)
+	accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"is_ghost_mode":false,"ghost_mode_expires_at":null}`, recorder.Body.String())
+}
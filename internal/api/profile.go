@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 
 	"privacy-social-backend/internal/repository/db"
 	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
 )
 
 const profileCacheTTL = 10 * time.Minute
@@ -95,8 +98,8 @@ func (server *Server) getUserProfile(ctx *gin.Context) {
 	authPayload, exists := ctx.Get(authorizationPayloadKey)
 	if exists && authPayload != nil {
 		payload := authPayload.(*token.Payload)
-		// Don't track self-views
-		if payload.UserID != userID {
+		// Don't track self-views or views made by an admin impersonating the user
+		if payload.UserID != userID && !payload.IsImpersonation() {
 			// Track asynchronously to not block response
 			go func() {
 				server.store.TrackProfileView(context.Background(), db.TrackProfileViewParams{
@@ -108,7 +111,7 @@ func (server *Server) getUserProfile(ctx *gin.Context) {
 	}
 
 	// Try Redis cache first
-	cacheKey := "profile:" + userID.String()
+	cacheKey := util.RedisKey("profile:" + userID.String())
 	cachedData, err := server.redis.Get(context.Background(), cacheKey).Result()
 	if err == nil && cachedData != "" {
 		ctx.Header("X-Cache", "HIT")
@@ -141,7 +144,7 @@ func (server *Server) getMyProfile(ctx *gin.Context) {
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
 	// Try Redis cache first
-	cacheKey := "profile:" + authPayload.UserID.String()
+	cacheKey := util.RedisKey("profile:" + authPayload.UserID.String())
 	cachedData, err := server.redis.Get(context.Background(), cacheKey).Result()
 	if err == nil && cachedData != "" {
 		ctx.Header("X-Cache", "HIT")
@@ -209,7 +212,7 @@ func (server *Server) updateProfile(ctx *gin.Context) {
 	}
 
 	// Invalidate cache
-	cacheKey := "profile:" + payload.UserID.String()
+	cacheKey := util.RedisKey("profile:" + payload.UserID.String())
 	server.redis.Del(context.Background(), cacheKey)
 
 	// Return updated profile structure (simplified for update response)
@@ -245,6 +248,72 @@ func (server *Server) updateProfile(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, rsp)
 }
 
+// profileThemes are the app themes a user may pick
+var profileThemes = []string{"auto", "light", "dark"}
+
+// patchProfileRequest changes only the fields it includes. An empty
+// avatar_url, banner_url or bio clears it.
+type patchProfileRequest struct {
+	Bio       *string `json:"bio"`
+	AvatarUrl *string `json:"avatar_url"`
+	BannerUrl *string `json:"banner_url"`
+	Theme     *string `json:"theme"`
+}
+
+// validateProfileURL checks a profile image URL is empty or media we can serve
+func validateProfileURL(field string, value *string) error {
+	if value == nil || *value == "" || isMediaURL(*value) {
+		return nil
+	}
+	return fmt.Errorf("%s %q is not a valid media URL", field, *value)
+}
+
+// patchMyProfile serves PATCH /users/me for the profile-editing screen
+func (server *Server) patchMyProfile(ctx *gin.Context) {
+	var req patchProfileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if rejectInvalidOption(ctx, "theme", req.Theme, profileThemes) {
+		return
+	}
+	if err := errors.Join(
+		validateProfileURL("avatar_url", req.AvatarUrl),
+		validateProfileURL("banner_url", req.BannerUrl),
+	); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	_, err := server.store.UpdateUserProfile(ctx, db.UpdateUserProfileParams{
+		ID:        payload.UserID,
+		Bio:       strPtrToNullString(req.Bio),
+		AvatarUrl: strPtrToNullString(req.AvatarUrl),
+		BannerUrl: strPtrToNullString(req.BannerUrl),
+		Theme:     strPtrToNullString(req.Theme),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	server.invalidateProfileCache(payload.UserID)
+
+	user, err := server.store.GetUserByID(ctx, payload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
 // ProfileVisitorResponse represents a user who viewed the profile
 type ProfileVisitorResponse struct {
 	ID        uuid.UUID `json:"id"`
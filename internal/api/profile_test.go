@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestPatchMyProfile(t *testing.T) {
+	userID := uuid.New()
+	updated := db.User{
+		ID:        userID,
+		Username:  "alice",
+		Bio:       sql.NullString{String: "hello", Valid: true},
+		Theme:     sql.NullString{String: "dark", Valid: true},
+		AvatarUrl: sql.NullString{String: "https://cdn.example.com/a.jpg", Valid: true},
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OnlyGivenFields",
+			body: gin.H{"bio": "hello", "theme": "dark"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserProfile(gomock.Any(), db.UpdateUserProfileParams{
+					ID:    userID,
+					Bio:   sql.NullString{String: "hello", Valid: true},
+					Theme: sql.NullString{String: "dark", Valid: true},
+				}).Times(1).Return(db.UpdateUserProfileRow{ID: userID}, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), userID).Times(1).Return(updated, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp userResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, newUserResponse(updated), rsp)
+			},
+		},
+		{
+			name: "ClearAvatar",
+			body: gin.H{"avatar_url": ""},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserProfile(gomock.Any(), db.UpdateUserProfileParams{
+					ID:        userID,
+					AvatarUrl: sql.NullString{String: "", Valid: true},
+				}).Times(1).Return(db.UpdateUserProfileRow{ID: userID}, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), userID).Times(1).Return(db.User{ID: userID}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownTheme",
+			body: gin.H{"theme": "neon"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserProfile(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "auto, light, dark")
+			},
+		},
+		{
+			name: "InvalidBannerURL",
+			body: gin.H{"banner_url": "javascript:alert(1)"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserProfile(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "banner_url")
+			},
+		},
+		{
+			name: "UserGone",
+			body: gin.H{"bio": "hello"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserProfile(gomock.Any(), gomock.Any()).Times(1).Return(db.UpdateUserProfileRow{}, sql.ErrNoRows)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.redis = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPatch, "/users/me", bytes.NewReader(data))
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -8,23 +13,27 @@ import (
 	mgin "github.com/ulule/limiter/v3/drivers/middleware/gin"
 	"github.com/ulule/limiter/v3/drivers/store/memory"
 	sredis "github.com/ulule/limiter/v3/drivers/store/redis"
+
+	"privacy-social-backend/internal/config"
+	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
 )
 
-// Rate limit configurations
+// Default rate limits, used when the matching RATE_LIMIT_* setting is empty
 var (
-	// General API rate limit: 500 requests per minute (increased from 100)
+	// General API rate limit: 500 requests per minute per IP (increased from 100)
 	generalRate = limiter.Rate{
 		Period: 1 * time.Minute,
 		Limit:  500,
 	}
 
-	// Auth endpoints: 60 requests per 1 minute
+	// Auth endpoints: 60 requests per 1 minute per IP
 	authRate = limiter.Rate{
 		Period: 1 * time.Minute,
 		Limit:  60,
 	}
 
-	// Story creation: 50 per hour
+	// Story creation: 50 per hour per user
 	storyRate = limiter.Rate{
 		Period: 1 * time.Hour,
 		Limit:  50,
@@ -36,15 +45,70 @@ var (
 		Limit:  600,
 	}
 
-	// Messages: 200 per minute
+	// Messages: 200 per minute per user
 	messageRate = limiter.Rate{
 		Period: 1 * time.Minute,
 		Limit:  200,
 	}
+
+	// Heatmap: 60 per 10 minutes per user, enough to pan a map but slow to scrape
+	heatmapRate = limiter.Rate{
+		Period: 10 * time.Minute,
+		Limit:  60,
+	}
+
+	// Nearby users: 30 per 10 minutes per user, so moving around to narrow down
+	// where someone is stays slow
+	nearbyUsersRate = limiter.Rate{
+		Period: 10 * time.Minute,
+		Limit:  30,
+	}
 )
 
-// createRateLimiter creates a rate limiter with Redis store
-func (server *Server) createRateLimiter(rate limiter.Rate) gin.HandlerFunc {
+var ErrRateLimited = errors.New("too many requests, try again later")
+
+// rateLimits are the configurable limits, parsed once at startup
+type rateLimits struct {
+	general limiter.Rate
+	auth    limiter.Rate
+	story   limiter.Rate
+	message limiter.Rate
+}
+
+// newRateLimits reads the RATE_LIMIT_* settings, each in limiter's
+// "<limit>-<period>" form such as "60-M"
+func newRateLimits(cfg config.Config) (rateLimits, error) {
+	var limits rateLimits
+	var err error
+	if limits.general, err = parseRate("RATE_LIMIT_GENERAL", cfg.RateLimitGeneral, generalRate); err != nil {
+		return limits, err
+	}
+	if limits.auth, err = parseRate("RATE_LIMIT_AUTH", cfg.RateLimitAuth, authRate); err != nil {
+		return limits, err
+	}
+	if limits.story, err = parseRate("RATE_LIMIT_STORY", cfg.RateLimitStory, storyRate); err != nil {
+		return limits, err
+	}
+	if limits.message, err = parseRate("RATE_LIMIT_MESSAGE", cfg.RateLimitMessage, messageRate); err != nil {
+		return limits, err
+	}
+	return limits, nil
+}
+
+func parseRate(name, formatted string, fallback limiter.Rate) (limiter.Rate, error) {
+	if formatted == "" {
+		return fallback, nil
+	}
+	rate, err := limiter.NewRateFromFormatted(formatted)
+	if err != nil {
+		return limiter.Rate{}, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return rate, nil
+}
+
+// createRateLimiter limits requests per client IP. Each limiter needs its own
+// prefix, or limiters sharing one would count each other's requests.
+func (server *Server) createRateLimiter(prefix string, rate limiter.Rate) gin.HandlerFunc {
 	// Bypass rate limiting in tests
 	if gin.Mode() == gin.TestMode {
 		return func(ctx *gin.Context) {
@@ -52,21 +116,28 @@ func (server *Server) createRateLimiter(rate limiter.Rate) gin.HandlerFunc {
 		}
 	}
 
-	store, err := sredis.NewStoreWithOptions(server.redis, limiter.StoreOptions{
-		Prefix:   "rate_limit",
-		MaxRetry: 3,
-	})
-	if err != nil {
-		// Fallback to in-memory if Redis fails
-		store = memory.NewStore()
+	return skipLoopback(server.newRateLimitMiddleware(prefix, rate, mgin.DefaultKeyGetter))
+}
+
+// createUserRateLimiter limits requests per signed-in user, so spreading them
+// over several IPs doesn't help. It must run after authMiddleware.
+func (server *Server) createUserRateLimiter(prefix string, rate limiter.Rate) gin.HandlerFunc {
+	if gin.Mode() == gin.TestMode {
+		return func(ctx *gin.Context) {
+			ctx.Next()
+		}
 	}
 
-	instance := limiter.New(store, rate)
-	middleware := mgin.NewMiddleware(instance)
+	return server.newRateLimitMiddleware(prefix, rate, rateLimitUserKey)
+}
 
+// skipLoopback lets requests from localhost past a per-IP limiter, for local
+// development. It checks the connecting address rather than ClientIP, so a
+// request can't get past by claiming 127.0.0.1 in X-Forwarded-For. Per-user
+// limits apply to localhost too.
+func skipLoopback(middleware gin.HandlerFunc) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		// Bypass for localhost / load tests
-		ip := ctx.ClientIP()
+		ip := ctx.RemoteIP()
 		if ip == "::1" || ip == "127.0.0.1" {
 			ctx.Next()
 			return
@@ -75,27 +146,90 @@ func (server *Server) createRateLimiter(rate limiter.Rate) gin.HandlerFunc {
 	}
 }
 
+// trustedProxies splits the TRUSTED_PROXIES setting. An empty setting gives
+// nil, which trusts no proxy.
+func trustedProxies(setting string) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(setting, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+func rateLimitUserKey(ctx *gin.Context) string {
+	return ctx.MustGet(authorizationPayloadKey).(*token.Payload).UserID.String()
+}
+
+// newRateLimitMiddleware counts requests by key under prefix. Over the limit it
+// answers 429 with Retry-After set to when the current window ends.
+func (server *Server) newRateLimitMiddleware(prefix string, rate limiter.Rate, key mgin.KeyGetter) gin.HandlerFunc {
+	instance := limiter.New(server.rateLimitStore(prefix), rate)
+	return mgin.NewMiddleware(instance,
+		mgin.WithKeyGetter(key),
+		mgin.WithLimitReachedHandler(rateLimitReached),
+	)
+}
+
+// rateLimitReached runs after the middleware has set X-RateLimit-Reset, the
+// Unix time the window ends
+func rateLimitReached(ctx *gin.Context) {
+	retryAfter := int64(1)
+	if reset, err := strconv.ParseInt(ctx.Writer.Header().Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		retryAfter = max(reset-util.Now().Unix(), 1)
+	}
+	ctx.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+	ctx.JSON(http.StatusTooManyRequests, errorResponse(ErrRateLimited))
+}
+
+// rateLimitStore keeps counters in Redis under prefix. The Redis store
+// increments and sets the expiry in one Lua script, so concurrent requests
+// can't slip past the limit.
+func (server *Server) rateLimitStore(prefix string) limiter.Store {
+	store, err := sredis.NewStoreWithOptions(server.redis, limiter.StoreOptions{
+		Prefix:   util.RedisKey(prefix),
+		MaxRetry: 3,
+	})
+	if err != nil {
+		// Fallback to in-memory if Redis fails
+		return memory.NewStore()
+	}
+	return store
+}
+
 // generalRateLimiter applies general rate limiting
 func (server *Server) generalRateLimiter() gin.HandlerFunc {
-	return server.createRateLimiter(generalRate)
+	return server.createRateLimiter("rate_limit", server.rateLimits.general)
 }
 
 // authRateLimiter applies strict rate limiting for auth endpoints
 func (server *Server) authRateLimiter() gin.HandlerFunc {
-	return server.createRateLimiter(authRate)
+	return server.createRateLimiter("rate_limit:auth", server.rateLimits.auth)
 }
 
 // storyRateLimiter applies rate limiting for story creation
 func (server *Server) storyRateLimiter() gin.HandlerFunc {
-	return server.createRateLimiter(storyRate)
+	return server.createUserRateLimiter("rate_limit:story", server.rateLimits.story)
 }
 
 // locationRateLimiter applies rate limiting for location updates
 func (server *Server) locationRateLimiter() gin.HandlerFunc {
-	return server.createRateLimiter(locationRate)
+	return server.createRateLimiter("rate_limit:location", locationRate)
 }
 
 // messageRateLimiter applies rate limiting for messaging
 func (server *Server) messageRateLimiter() gin.HandlerFunc {
-	return server.createRateLimiter(messageRate)
+	return server.createUserRateLimiter("rate_limit:message", server.rateLimits.message)
+}
+
+// heatmapRateLimiter limits heatmap reads per user on a counter of their own, so
+// sweeping many boxes stays slow even when spread over several IPs
+func (server *Server) heatmapRateLimiter() gin.HandlerFunc {
+	return server.createUserRateLimiter("rate_limit:heatmap", heatmapRate)
+}
+
+// nearbyUsersRateLimiter limits nearby user searches per user
+func (server *Server) nearbyUsersRateLimiter() gin.HandlerFunc {
+	return server.createUserRateLimiter("rate_limit:nearby", nearbyUsersRate)
 }
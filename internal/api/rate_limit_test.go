@@ -0,0 +1,172 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/ulule/limiter/v3"
+	mgin "github.com/ulule/limiter/v3/drivers/middleware/gin"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/config"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/token"
+)
+
+func TestNewRateLimits(t *testing.T) {
+	limits, err := newRateLimits(config.Config{})
+	require.NoError(t, err)
+	require.Equal(t, authRate, limits.auth)
+	require.Equal(t, messageRate, limits.message)
+
+	limits, err = newRateLimits(config.Config{RateLimitStory: "10-H"})
+	require.NoError(t, err)
+	require.Equal(t, limiter.Rate{Formatted: "10-H", Period: time.Hour, Limit: 10}, limits.story)
+
+	_, err = newRateLimits(config.Config{RateLimitAuth: "sixty"})
+	require.ErrorContains(t, err, "RATE_LIMIT_AUTH")
+}
+
+func TestUserRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	server.redis = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		userID := uuid.MustParse(ctx.GetHeader("X-User"))
+		ctx.Set(authorizationPayloadKey, &token.Payload{UserID: userID})
+	})
+	router.Use(server.newRateLimitMiddleware("rate_limit:test", limiter.Rate{Period: time.Minute, Limit: 2}, rateLimitUserKey))
+	router.POST("/", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	send := func(userID uuid.UUID) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+		request.Header.Set("X-User", userID.String())
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	alice, bob := uuid.New(), uuid.New()
+	require.Equal(t, http.StatusOK, send(alice).Code)
+	require.Equal(t, http.StatusOK, send(alice).Code)
+
+	recorder := send(alice)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Contains(t, recorder.Body.String(), ErrRateLimited.Error())
+	retryAfter, err := strconv.Atoi(recorder.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, retryAfter, 1)
+	require.LessOrEqual(t, retryAfter, 60)
+
+	// Another user on the same IP has a count of their own
+	require.Equal(t, http.StatusOK, send(bob).Code)
+}
+
+func TestRateLimitSkipsLoopback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	server.redis = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+
+	once := limiter.Rate{Period: time.Minute, Limit: 1}
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies(nil))
+	router.Use(skipLoopback(server.newRateLimitMiddleware("rate_limit:test", once, mgin.DefaultKeyGetter)))
+	router.POST("/", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	send := func(remoteAddr, forwardedFor string) int {
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+		request.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			request.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	for range 3 {
+		require.Equal(t, http.StatusOK, send("127.0.0.1:5000", ""))
+		require.Equal(t, http.StatusOK, send("[::1]:5000", ""))
+	}
+
+	// Claiming localhost in X-Forwarded-For doesn't get past the limit, and
+	// neither does claiming a fresh address each time
+	require.Equal(t, http.StatusOK, send("203.0.113.7:5000", "127.0.0.1"))
+	require.Equal(t, http.StatusTooManyRequests, send("203.0.113.7:5000", "127.0.0.1"))
+	require.Equal(t, http.StatusTooManyRequests, send("203.0.113.7:5000", "198.51.100.1"))
+}
+
+func TestUserRateLimitAppliesToLoopback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	server.redis = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+
+	userID := uuid.New()
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Set(authorizationPayloadKey, &token.Payload{UserID: userID})
+	})
+	once := limiter.Rate{Period: time.Minute, Limit: 1}
+	router.Use(server.newRateLimitMiddleware("rate_limit:test", once, rateLimitUserKey))
+	router.POST("/", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	send := func() int {
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+		request.RemoteAddr = "127.0.0.1:5000"
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	require.Equal(t, http.StatusOK, send())
+	require.Equal(t, http.StatusTooManyRequests, send())
+}
+
+func TestTrustedProxies(t *testing.T) {
+	require.Nil(t, trustedProxies(""))
+	require.Equal(t, []string{"10.0.0.0/8", "192.168.1.2"}, trustedProxies(" 10.0.0.0/8, ,192.168.1.2 "))
+}
+
+func TestRateLimitersKeepSeparateCounts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		prefixA  string
+		prefixB  string
+		wantCode int
+	}{
+		{name: "OwnPrefix", prefixA: "rate_limit:a", prefixB: "rate_limit:b", wantCode: http.StatusOK},
+		{name: "SharedPrefix", prefixA: "rate_limit", prefixB: "rate_limit", wantCode: http.StatusTooManyRequests},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			server := newTestServer(t, mockdb.NewMockStore(ctrl))
+			server.redis = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+
+			once := limiter.Rate{Period: time.Minute, Limit: 1}
+			ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+			router := gin.New()
+			router.POST("/a", server.newRateLimitMiddleware(tc.prefixA, once, mgin.DefaultKeyGetter), ok)
+			router.POST("/b", server.newRateLimitMiddleware(tc.prefixB, once, mgin.DefaultKeyGetter), ok)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/a", nil))
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			recorder = httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/b", nil))
+			require.Equal(t, tc.wantCode, recorder.Code)
+		})
+	}
+}
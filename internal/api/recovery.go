@@ -0,0 +1,46 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrInternalServer = errors.New("internal server error")
+
+// recoveryMiddleware turns a handler panic into a 500 with a generic JSON
+// body, logging the panic and its stack under the request id. The panic value
+// never reaches the client. When the handler already answered, or took the
+// connection over for a WebSocket, nothing more is written.
+func recoveryMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			// Aborting a response is how net/http expects handlers to bail out
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+
+			logger := requestLog(ctx)
+			logger.Error().
+				Str("panic", fmt.Sprint(r)).
+				Str("stack", string(debug.Stack())).
+				Str("method", ctx.Request.Method).
+				Str("path", ctx.Request.URL.Path).
+				Msg("handler panicked")
+
+			if ctx.Writer.Written() {
+				ctx.Abort()
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(ErrInternalServer))
+		}()
+		ctx.Next()
+	}
+}
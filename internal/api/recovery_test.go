@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	server.router.GET("/test/panic", func(ctx *gin.Context) {
+		panic("secret connection string")
+	})
+	buf := captureLog(t)
+
+	request, err := http.NewRequest(http.MethodGet, "/test/panic", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.JSONEq(t, `{"error":"internal server error"}`, recorder.Body.String())
+	requestID := recorder.Header().Get(requestIDHeader)
+	require.NotEmpty(t, requestID)
+
+	// The panic line, then the request line with the 500
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	require.Equal(t, "handler panicked", entry["message"])
+	require.Equal(t, requestID, entry["request_id"])
+	require.Equal(t, "secret connection string", entry["panic"])
+	require.Contains(t, entry["stack"], "TestRecoveryMiddleware")
+	require.Contains(t, lines[1], `"status":500`)
+}
+
+func TestRecoveryMiddlewareAfterResponse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	server.router.GET("/test/panic", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+		panic("too late")
+	})
+	captureLog(t)
+
+	request, err := http.NewRequest(http.MethodGet, "/test/panic", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+
+	// What was already sent stands; nothing is appended to it
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"status":"ok"}`, recorder.Body.String())
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"privacy-social-backend/internal/service/user"
+)
+
+type renewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+// renewAccessToken exchanges a refresh token for a new access token. Any
+// problem with the token or its session is a 401 so clients fall back to login.
+func (server *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := server.user.RenewAccessToken(ctx, req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrInvalidRefreshToken), errors.Is(err, user.ErrSessionNotFound),
+			errors.Is(err, user.ErrSessionBlocked), errors.Is(err, user.ErrSessionExpired),
+			errors.Is(err, user.ErrTokenMismatch):
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, renewAccessTokenResponse{
+		AccessToken:          result.AccessToken,
+		AccessTokenExpiresAt: result.AccessTokenExpiresAt,
+	})
+}
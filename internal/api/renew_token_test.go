@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/service/user"
+)
+
+func TestRenewAccessToken(t *testing.T) {
+	userID := uuid.New()
+
+	testCases := []struct {
+		name string
+		// session turns the issued refresh token into the stored session
+		session       func(refreshToken string, id uuid.UUID) (db.Session, error)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			session: func(refreshToken string, id uuid.UUID) (db.Session, error) {
+				return db.Session{ID: id, UserID: userID, RefreshToken: refreshToken, ExpiresAt: time.Now().Add(time.Hour)}, nil
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp renewAccessTokenResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.AccessToken)
+				require.True(t, rsp.AccessTokenExpiresAt.After(time.Now()))
+			},
+		},
+		{
+			name: "Blocked",
+			session: func(refreshToken string, id uuid.UUID) (db.Session, error) {
+				return db.Session{ID: id, UserID: userID, RefreshToken: refreshToken, IsBlocked: true, ExpiresAt: time.Now().Add(time.Hour)}, nil
+			},
+			checkResponse: requireRenewRejected(user.ErrSessionBlocked),
+		},
+		{
+			name: "Expired",
+			session: func(refreshToken string, id uuid.UUID) (db.Session, error) {
+				return db.Session{ID: id, UserID: userID, RefreshToken: refreshToken, ExpiresAt: time.Now().Add(-time.Minute)}, nil
+			},
+			checkResponse: requireRenewRejected(user.ErrSessionExpired),
+		},
+		{
+			name: "OtherUser",
+			session: func(refreshToken string, id uuid.UUID) (db.Session, error) {
+				return db.Session{ID: id, UserID: uuid.New(), RefreshToken: refreshToken, ExpiresAt: time.Now().Add(time.Hour)}, nil
+			},
+			checkResponse: requireRenewRejected(user.ErrTokenMismatch),
+		},
+		{
+			name: "OtherToken",
+			session: func(_ string, id uuid.UUID) (db.Session, error) {
+				return db.Session{ID: id, UserID: userID, RefreshToken: "another-token", ExpiresAt: time.Now().Add(time.Hour)}, nil
+			},
+			checkResponse: requireRenewRejected(user.ErrTokenMismatch),
+		},
+		{
+			name: "NoSession",
+			session: func(string, uuid.UUID) (db.Session, error) {
+				return db.Session{}, sql.ErrNoRows
+			},
+			checkResponse: requireRenewRejected(user.ErrSessionNotFound),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			refreshToken, refreshPayload, err := server.tokenMaker.CreateToken("user", userID, time.Hour)
+			require.NoError(t, err)
+
+			store.EXPECT().
+				GetSession(gomock.Any(), refreshPayload.ID).
+				Times(1).
+				Return(tc.session(refreshToken, refreshPayload.ID))
+
+			data, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/tokens/renew", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRenewAccessTokenInvalidToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	data, err := json.Marshal(gin.H{"refresh_token": "not-a-token"})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/tokens/renew", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func requireRenewRejected(want error) func(t *testing.T, recorder *httptest.ResponseRecorder) {
+	return func(t *testing.T, recorder *httptest.ResponseRecorder) {
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+		var rsp struct {
+			Error string `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+		require.Equal(t, want.Error(), rsp.Error)
+	}
+}
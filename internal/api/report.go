@@ -2,21 +2,56 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
 
 	"privacy-social-backend/internal/repository/db"
 )
 
+const (
+	reportTargetStory   = "story"
+	reportTargetUser    = "user"
+	reportTargetMessage = "message"
+
+	// reportedHoldReason is the moderation hold reason for stories hidden
+	// after ReportAutoHideThreshold people reported them
+	reportedHoldReason = "reported"
+)
+
+var (
+	ErrAlreadyReported = errors.New("you already reported this and it's awaiting review")
+	ErrReportTarget    = errors.New("must target a user, story or message")
+)
+
 type createReportRequest struct {
-	TargetUserID  string `json:"target_user_id"`  // Optional
-	TargetStoryID string `json:"target_story_id"` // Optional
+	TargetType string `json:"target_type" binding:"omitempty,oneof=story user message"`
+	TargetID   string `json:"target_id" binding:"omitempty,uuid"`
+	// Older clients name the target with one of these instead
+	TargetUserID  string `json:"target_user_id"`
+	TargetStoryID string `json:"target_story_id"`
 	Reason        string `json:"reason" binding:"required,oneof=spam abuse inappropriate other"`
 	Description   string `json:"description"`
 }
 
+// target returns the type and id of what is being reported, reading the
+// older target_story_id/target_user_id fields when target_type isn't set
+func (req createReportRequest) target() (string, string) {
+	switch {
+	case req.TargetType != "":
+		return req.TargetType, req.TargetID
+	case req.TargetStoryID != "":
+		return reportTargetStory, req.TargetStoryID
+	case req.TargetUserID != "":
+		return reportTargetUser, req.TargetUserID
+	}
+	return "", ""
+}
+
 func (server *Server) createReport(ctx *gin.Context) {
 	var req createReportRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -26,43 +61,132 @@ func (server *Server) createReport(ctx *gin.Context) {
 
 	authPayload := getAuthPayload(ctx)
 
-	var targetUserID uuid.NullUUID
-	if req.TargetUserID != "" {
-		id, err := uuid.Parse(req.TargetUserID)
+	targetType, rawID := req.target()
+	if rawID == "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrReportTarget))
+		return
+	}
+	targetID, ok := parseUUIDParam(ctx, rawID, "target_id")
+	if !ok {
+		return
+	}
+
+	arg := db.CreateReportParams{
+		ReporterID:  authPayload.UserID,
+		Reason:      db.ReportReason(req.Reason),
+		Description: sql.NullString{String: req.Description, Valid: req.Description != ""},
+	}
+	var mediaURL, geohash string
+	switch targetType {
+	case reportTargetStory:
+		story, err := server.store.GetStoryByID(ctx, targetID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "story not found"})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if rejectSelfTarget(ctx, authPayload.UserID, story.UserID, "cannot report your own story") {
+			return
+		}
+		arg.TargetStoryID = uuid.NullUUID{UUID: story.ID, Valid: true}
+		arg.TargetUserID = uuid.NullUUID{UUID: story.UserID, Valid: true}
+		mediaURL, geohash = story.MediaUrl, story.Geohash
+	case reportTargetMessage:
+		// Only someone in the conversation can report a message; to anyone
+		// else it doesn't exist
+		msg, err := server.store.GetMessage(ctx, targetID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		participant, err := server.isMessageParticipant(ctx, msg, authPayload.UserID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target_user_id"})
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if !participant {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
 			return
 		}
-		targetUserID = uuid.NullUUID{UUID: id, Valid: true}
+		if rejectSelfTarget(ctx, authPayload.UserID, msg.SenderID, "cannot report your own message") {
+			return
+		}
+		arg.TargetMessageID = uuid.NullUUID{UUID: msg.ID, Valid: true}
+		arg.TargetUserID = uuid.NullUUID{UUID: msg.SenderID, Valid: true}
+	default:
+		if rejectSelfTarget(ctx, authPayload.UserID, targetID, "cannot report yourself") {
+			return
+		}
+		if _, err := server.store.GetUserByID(ctx, targetID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		arg.TargetUserID = uuid.NullUUID{UUID: targetID, Valid: true}
 	}
 
-	var targetStoryID uuid.NullUUID
-	if req.TargetStoryID != "" {
-		id, err := uuid.Parse(req.TargetStoryID)
-		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target_story_id"})
+	report, err := server.store.CreateReport(ctx, arg)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			ctx.JSON(http.StatusConflict, errorResponse(ErrAlreadyReported))
 			return
 		}
-		targetStoryID = uuid.NullUUID{UUID: id, Valid: true}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
-	// Validate that at least one target is present
-	if !targetUserID.Valid && !targetStoryID.Valid {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "must target user or story"})
+	if arg.TargetStoryID.Valid {
+		// Keep the reported media out of the GC until a moderator releases it
+		if _, err := server.store.SetMediaObjectHold(ctx, db.SetMediaObjectHoldParams{
+			Url:    mediaURL,
+			OnHold: true,
+		}); err != nil {
+			log.Error().Err(err).Str("story_id", arg.TargetStoryID.UUID.String()).Msg("failed to place media hold")
+		}
+		server.autoHideReportedStory(ctx, arg.TargetStoryID, geohash)
+	}
+
+	ctx.JSON(http.StatusCreated, report)
+}
+
+// autoHideReportedStory holds a story for review once enough different people
+// have open reports on it. Failures are logged; the report itself stands.
+func (server *Server) autoHideReportedStory(ctx *gin.Context, storyID uuid.NullUUID, geohash string) {
+	threshold := server.config.ReportAutoHideThreshold
+	if threshold <= 0 {
 		return
 	}
 
-	report, err := server.store.CreateReport(ctx, db.CreateReportParams{
-		ReporterID:    authPayload.UserID,
-		TargetUserID:  targetUserID,
-		TargetStoryID: targetStoryID,
-		Reason:        db.ReportReason(req.Reason),
-		Description:   sql.NullString{String: req.Description, Valid: req.Description != ""},
-	})
+	count, err := server.store.CountOpenStoryReports(ctx, storyID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		log.Error().Err(err).Str("story_id", storyID.UUID.String()).Msg("failed to count story reports")
+		return
+	}
+	if count < int64(threshold) {
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, report)
+	if err := server.store.CreateStoryModerationHold(ctx, db.CreateStoryModerationHoldParams{
+		StoryID: storyID.UUID,
+		Reason:  reportedHoldReason,
+	}); err != nil {
+		log.Error().Err(err).Str("story_id", storyID.UUID.String()).Msg("failed to hold reported story")
+		return
+	}
+	if len(geohash) > 5 {
+		geohash = geohash[:5]
+	}
+	server.invalidateFeedCache(geohash)
+	log.Info().Str("story_id", storyID.UUID.String()).Int64("reports", count).Msg("story hidden pending review after reports")
 }
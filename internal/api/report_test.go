@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestCreateReport(t *testing.T) {
+	userID, ownerID, storyID, messageID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	story := db.GetStoryByIDRow{ID: storyID, UserID: ownerID, MediaUrl: "r2://media/a.jpg", Geohash: "tdr1y2"}
+	storyRef := uuid.NullUUID{UUID: storyID, Valid: true}
+	ownerRef := uuid.NullUUID{UUID: ownerID, Valid: true}
+
+	// createReport stubs storing the report, checking what it targets
+	createReport := func(store *mockdb.MockStore, want db.CreateReportParams) {
+		store.EXPECT().
+			CreateReport(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.CreateReportParams) (db.Report, error) {
+				require.Equal(t, userID, arg.ReporterID)
+				require.Equal(t, want.TargetUserID, arg.TargetUserID)
+				require.Equal(t, want.TargetStoryID, arg.TargetStoryID)
+				require.Equal(t, want.TargetMessageID, arg.TargetMessageID)
+				require.Equal(t, db.ReportReasonSpam, arg.Reason)
+				return db.Report{
+					ID:              uuid.New(),
+					ReporterID:      arg.ReporterID,
+					TargetUserID:    arg.TargetUserID,
+					TargetStoryID:   arg.TargetStoryID,
+					TargetMessageID: arg.TargetMessageID,
+					Reason:          arg.Reason,
+				}, nil
+			})
+	}
+	// reportStory stubs a first-time report on the story, up to the auto-hide count
+	reportStory := func(store *mockdb.MockStore, reporters int64) {
+		store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).Return(story, nil)
+		createReport(store, db.CreateReportParams{TargetUserID: ownerRef, TargetStoryID: storyRef})
+		store.EXPECT().
+			SetMediaObjectHold(gomock.Any(), db.SetMediaObjectHoldParams{Url: story.MediaUrl, OnHold: true}).
+			Times(1).
+			Return(int64(1), nil)
+		store.EXPECT().CountOpenStoryReports(gomock.Any(), storyRef).Times(1).Return(reporters, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Story",
+			body: gin.H{"target_type": "story", "target_id": storyID, "reason": "spam"},
+			buildStubs: func(store *mockdb.MockStore) {
+				reportStory(store, 2)
+				store.EXPECT().CreateStoryModerationHold(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+				require.NotEqual(t, `""`, mustJSONField(t, recorder.Body.Bytes(), "id"))
+			},
+		},
+		{
+			// Enough people reporting a story hides it from feeds until reviewed
+			name: "StoryReachesThreshold",
+			body: gin.H{"target_type": "story", "target_id": storyID, "reason": "spam"},
+			buildStubs: func(store *mockdb.MockStore) {
+				reportStory(store, 3)
+				store.EXPECT().
+					CreateStoryModerationHold(gomock.Any(), db.CreateStoryModerationHoldParams{StoryID: storyID, Reason: reportedHoldReason}).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+			},
+		},
+		{
+			name: "LegacyStoryField",
+			body: gin.H{"target_story_id": storyID, "reason": "spam"},
+			buildStubs: func(store *mockdb.MockStore) {
+				reportStory(store, 1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+			},
+		},
+		{
+			name: "Message",
+			body: gin.H{"target_type": "message", "target_id": messageID, "reason": "spam"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetMessage(gomock.Any(), messageID).
+					Times(1).
+					Return(db.Message{ID: messageID, SenderID: ownerID, ReceiverID: uuid.NullUUID{UUID: userID, Valid: true}}, nil)
+				createReport(store, db.CreateReportParams{
+					TargetUserID:    ownerRef,
+					TargetMessageID: uuid.NullUUID{UUID: messageID, Valid: true},
+				})
+				store.EXPECT().CountOpenStoryReports(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+			},
+		},
+		{
+			// Messages in other people's conversations are reported as missing
+			name: "MessageNotParticipant",
+			body: gin.H{"target_type": "message", "target_id": messageID, "reason": "spam"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetMessage(gomock.Any(), messageID).
+					Times(1).
+					Return(db.Message{ID: messageID, SenderID: ownerID, ReceiverID: uuid.NullUUID{UUID: uuid.New(), Valid: true}}, nil)
+				store.EXPECT().CreateReport(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "User",
+			body: gin.H{"target_type": "user", "target_id": ownerID, "reason": "spam"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), ownerID).Times(1).Return(db.User{ID: ownerID}, nil)
+				createReport(store, db.CreateReportParams{TargetUserID: ownerRef})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+			},
+		},
+		{
+			name: "UserNotFound",
+			body: gin.H{"target_type": "user", "target_id": ownerID, "reason": "spam"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), ownerID).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().CreateReport(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "Self",
+			body: gin.H{"target_type": "user", "target_id": userID, "reason": "spam"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateReport(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyReported",
+			body: gin.H{"target_type": "story", "target_id": storyID, "reason": "spam"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).Return(story, nil)
+				store.EXPECT().
+					CreateReport(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Report{}, &pq.Error{Code: "23505"})
+				store.EXPECT().CountOpenStoryReports(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Contains(t, recorder.Body.String(), ErrAlreadyReported.Error())
+			},
+		},
+		{
+			name: "NoTarget",
+			body: gin.H{"reason": "spam"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateReport(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidTargetType",
+			body: gin.H{"target_type": "group", "target_id": storyID, "reason": "spam"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateReport(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.ReportAutoHideThreshold = 3
+			recorder := postJSON(t, server, "/reports", tc.body, &userID)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
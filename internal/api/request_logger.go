@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/token"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDKey    = "request_id"
+	// maxRequestIDLength bounds a request id taken from the caller
+	maxRequestIDLength = 128
+)
+
+// requestLogPathsSkipped are polled too often to be worth a line each
+var requestLogPathsSkipped = map[string]bool{
+	"/health": true,
+}
+
+// requestLogger tags each request with an id, echoed in X-Request-ID, and logs
+// it once answered with its status, latency and the authenticated user. An id
+// sent by a proxy in front of us is kept so the logs line up.
+func requestLogger() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+		ctx.Set(requestIDKey, requestID)
+		ctx.Header(requestIDHeader, requestID)
+
+		start := time.Now()
+		ctx.Next()
+
+		path := ctx.Request.URL.Path
+		if requestLogPathsSkipped[path] {
+			return
+		}
+
+		status := ctx.Writer.Status()
+		event := log.Info()
+		switch {
+		case status >= http.StatusInternalServerError:
+			event = log.Error()
+		case status >= http.StatusBadRequest:
+			event = log.Warn()
+		}
+		event = event.
+			Str("request_id", requestID).
+			Str("method", ctx.Request.Method).
+			Str("path", path).
+			Str("route", ctx.FullPath()).
+			Int("status", status).
+			Dur("latency", time.Since(start)).
+			Str("client_ip", ctx.ClientIP())
+		if payload, ok := ctx.Get(authorizationPayloadKey); ok {
+			event = event.Str("user_id", payload.(*token.Payload).UserID.String())
+		}
+		if len(ctx.Errors) > 0 {
+			event = event.Str("error", ctx.Errors.String())
+		}
+		event.Msg("request")
+	}
+}
+
+// requestLog is the logger for a handler, carrying the request's id
+func requestLog(ctx *gin.Context) zerolog.Logger {
+	return log.With().Str("request_id", ctx.GetString(requestIDKey)).Logger()
+}
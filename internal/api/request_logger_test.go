@@ -0,0 +1,82 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+// captureLog sends the global logger to a buffer for the rest of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	saved := log.Logger
+	log.Logger = zerolog.New(&buf)
+	t.Cleanup(func() { log.Logger = saved })
+	return &buf
+}
+
+func TestRequestLogger(t *testing.T) {
+	userID := uuid.New()
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	buf := captureLog(t)
+
+	// Rejected before the store is touched
+	recorder := postJSON(t, server, "/messages", gin.H{}, &userID)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	requestID := recorder.Header().Get(requestIDHeader)
+	require.NotEmpty(t, requestID)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, "warn", entry["level"])
+	require.Equal(t, requestID, entry["request_id"])
+	require.Equal(t, http.MethodPost, entry["method"])
+	require.Equal(t, "/messages", entry["path"])
+	require.EqualValues(t, http.StatusBadRequest, entry["status"])
+	require.Equal(t, userID.String(), entry["user_id"])
+	require.Contains(t, entry, "latency")
+	require.Contains(t, entry, "client_ip")
+}
+
+func TestRequestLoggerKeepsCallerID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	buf := captureLog(t)
+
+	request, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	request.Header.Set(requestIDHeader, "edge-123")
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, "edge-123", recorder.Header().Get(requestIDHeader))
+	require.Contains(t, buf.String(), `"request_id":"edge-123"`)
+	require.NotContains(t, buf.String(), "user_id")
+}
+
+func TestRequestLoggerSkipsHealth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	buf := captureLog(t)
+
+	request, err := http.NewRequest(http.MethodGet, "/health", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get(requestIDHeader))
+	require.Empty(t, buf.String())
+}
@@ -6,10 +6,19 @@ import (
 )
 
 func (server *Server) setupRouter() {
-	router := gin.Default()
+	router := gin.New()
+	router.Use(requestLogger())
+	router.Use(server.metricsMiddleware())
+	router.Use(recoveryMiddleware())
+
+	// Load balancer probes and the Prometheus scrape, registered before the
+	// middleware below so they skip CORS, gzip and rate limiting
+	router.GET("/health", server.healthCheck)
+	router.GET("/ready", server.readinessCheck)
+	router.GET("/metrics", server.serveMetrics)
 
 	// CORS Middleware
-	router.Use(corsMiddleware())
+	router.Use(corsMiddleware(server.origins))
 
 	// Enable gzip compression (70% bandwidth reduction)
 	router.Use(gzip.Gzip(gzip.DefaultCompression))
@@ -26,10 +35,16 @@ func (server *Server) setupRouter() {
 	})
 	router.POST("/users", server.authRateLimiter(), server.createUser)
 	router.POST("/users/login", server.authRateLimiter(), server.loginUser)
+	router.POST("/tokens/renew", server.authRateLimiter(), server.renewAccessToken)
 	router.POST("/auth/google", server.authRateLimiter(), server.googleLogin)
 	router.GET("/auth/google/callback", server.googleCallback) // New Relay for Expo Go
 	router.POST("/auth/forgot-password", server.authRateLimiter(), server.forgotPassword)
 	router.POST("/auth/reset-password", server.authRateLimiter(), server.resetPassword)
+	router.POST("/auth/otp/request", server.authRateLimiter(), server.requestOTP)
+	router.POST("/auth/otp/verify", server.authRateLimiter(), server.verifyOTP)
+
+	// Public story links for sharing outside the app
+	router.GET("/s/:id", server.getPublicStory)
 
 	// Static uploads
 	router.Static("/uploads", "./uploads")
@@ -37,20 +52,35 @@ func (server *Server) setupRouter() {
 	// Protected routes
 	authRoutes := router.Group("/")
 	authRoutes.Use(authMiddleware(server.tokenMaker))
+	authRoutes.Use(impersonationMiddleware(server))
+	authRoutes.Use(banMiddleware(server))
 
 	// File upload
 	authRoutes.POST("/upload", server.uploadFile)
+	authRoutes.POST("/uploads/presign", server.presignUpload)
+	authRoutes.POST("/uploads/presign/complete", server.confirmPresignedUpload)
+	authRoutes.POST("/uploads/multipart", server.createMultipartUpload)
+	authRoutes.POST("/uploads/multipart/parts", server.presignUploadParts)
+	authRoutes.POST("/uploads/multipart/complete", server.completeMultipartUpload)
+	authRoutes.DELETE("/uploads/multipart", server.abortMultipartUpload)
 
 	authRoutes.POST("/location/ping", server.locationRateLimiter(), server.updateLocation)
-	authRoutes.GET("/location/heatmap", server.getHeatmap)
+	authRoutes.GET("/location/heatmap", server.heatmapRateLimiter(), server.getHeatmap)
+	authRoutes.GET("/nearby", server.nearbyUsersRateLimiter(), server.getNearbyUsers)
+	authRoutes.GET("/nearby/active-count", server.getActiveNearby)
 	// Stories
 	authRoutes.GET("/feed", server.getFeed)
-	authRoutes.POST("/stories", server.storyRateLimiter(), server.createStory)
+	authRoutes.POST("/stories", server.storyRateLimiter(), phoneVerifiedMiddleware(server), server.createStory)
 	authRoutes.GET("/stories/:id", server.getStory)
 	authRoutes.PUT("/stories/:id", server.updateStory)
 	authRoutes.DELETE("/stories/:id", server.deleteUserStory)
 	authRoutes.GET("/stories/map", server.getStoriesMap)
 	authRoutes.GET("/stories/connections", server.getConnectionStories)
+	authRoutes.GET("/stories/connections/rings", server.getConnectionStoryRings)
+	authRoutes.GET("/stories/by-hashtag/:tag", server.getStoriesByHashtag)
+	authRoutes.GET("/hashtags/trending", server.getTrendingHashtags)
+	authRoutes.GET("/me/stories", server.getMyStories)
+	authRoutes.GET("/me/activity", server.getMyActivity)
 
 	// Archive Stories
 	authRoutes.POST("/stories/:id/archive", server.archiveStory)
@@ -59,6 +89,7 @@ func (server *Server) setupRouter() {
 
 	authRoutes.GET("/connections", server.listConnections)
 	authRoutes.GET("/connections/suggested", server.getSuggestedConnections)
+	authRoutes.GET("/connections/recommendations", server.getConnectionRecommendations)
 	authRoutes.GET("/connections/requests", server.listPendingRequests)
 	authRoutes.GET("/connections/sent", server.listSentRequests)
 	authRoutes.POST("/connections/request", server.sendConnectionRequest)
@@ -74,13 +105,24 @@ func (server *Server) setupRouter() {
 	// Chat & Messages
 	authRoutes.GET("/conversations", server.getConversationList)
 	authRoutes.GET("/messages", server.messageRateLimiter(), server.getChatHistory)
-	authRoutes.POST("/messages", server.messageRateLimiter(), server.sendMessage)
+	authRoutes.POST("/messages", server.messageRateLimiter(), phoneVerifiedMiddleware(server), server.sendMessage)
 	authRoutes.GET("/messages/unread-count", server.getUnreadMessageCount)
+	authRoutes.GET("/messages/status", server.getMessageStatus)
+	authRoutes.GET("/messages/search", server.searchDirectMessages)
+	authRoutes.GET("/messages/scheduled", server.listScheduledMessages)
+	authRoutes.PUT("/messages/scheduled/:id", server.updateScheduledMessage)
+	authRoutes.DELETE("/messages/scheduled/:id", server.cancelScheduledMessage)
 	authRoutes.PUT("/messages/read/:userId", server.markConversationRead)
 	authRoutes.DELETE("/messages/:id", server.deleteMessage)
 	authRoutes.PUT("/messages/:id", server.editMessage)
 	authRoutes.PUT("/messages/:id/save", server.saveMessage) // Save message to prevent expiry
-	authRoutes.DELETE("/conversations/:userId", server.deleteConversation)
+	authRoutes.GET("/messages/:id/replies", server.getMessageReplies)
+	authRoutes.POST("/messages/:id/forward", server.messageRateLimiter(), phoneVerifiedMiddleware(server), server.forwardMessage)
+	authRoutes.DELETE("/conversations/:id", server.deleteConversation)
+	authRoutes.POST("/conversations/:id/mute", server.muteConversation)
+	authRoutes.DELETE("/conversations/:id/mute", server.unmuteConversation)
+	authRoutes.GET("/conversations/:id/settings", server.getConversationSettings)
+	authRoutes.PUT("/conversations/:id/settings", server.updateConversationSettings)
 	authRoutes.POST("/messages/:id/reactions", server.addReaction)
 	authRoutes.DELETE("/messages/:id/reactions", server.removeReaction)
 	authRoutes.GET("/messages/:id/reactions", server.getMessageReactions)
@@ -88,17 +130,31 @@ func (server *Server) setupRouter() {
 
 	authRoutes.GET("/crossings", server.getCrossings)
 	authRoutes.PUT("/profile", server.updateProfile)
+	authRoutes.PATCH("/users/me", server.patchMyProfile)
 	authRoutes.POST("/reports", server.createReport)
 	authRoutes.POST("/profile/boost", server.boostProfile)
 	authRoutes.PUT("/account/email", server.updateUserEmail)
 	authRoutes.PUT("/account/password", server.updateUserPassword)
+	authRoutes.DELETE("/users/me", server.deleteAccount)
+	authRoutes.GET("/account/sessions", server.listSessions)
+	authRoutes.DELETE("/account/sessions/:id", server.revokeSession)
+	authRoutes.POST("/sessions/logout", server.logout)
+	authRoutes.POST("/sessions/logout-all", server.logoutAll)
 
 	// Privacy features
 	authRoutes.GET("/privacy", server.getPrivacySettings)
 	authRoutes.PUT("/privacy", server.updatePrivacySettings)
+	authRoutes.PUT("/users/me/privacy", server.updateMyPrivacySettings)
+	authRoutes.POST("/users/me/ghost", server.enableGhostMode)
+	authRoutes.DELETE("/users/me/ghost", server.disableGhostMode)
+	authRoutes.GET("/users/me/close-friends", server.listCloseFriends)
+	authRoutes.POST("/users/me/close-friends/:id", server.addCloseFriend)
+	authRoutes.DELETE("/users/me/close-friends/:id", server.removeCloseFriend)
 	authRoutes.POST("/users/block", server.blockUser)
 	authRoutes.DELETE("/users/block/:id", server.unblockUser)
 	authRoutes.GET("/users/blocked", server.getBlockedUsers)
+	authRoutes.POST("/users/:id/block", server.blockUserByID)
+	authRoutes.DELETE("/users/:id/block", server.unblockUser)
 	authRoutes.PUT("/location/ghost-mode", server.toggleGhostMode)
 	authRoutes.POST("/location/panic", server.panicMode)
 
@@ -108,6 +164,7 @@ func (server *Server) setupRouter() {
 	authRoutes.POST("/stories/:id/react", server.reactToStory)
 	authRoutes.DELETE("/stories/:id/react", server.deleteStoryReaction)
 	authRoutes.GET("/stories/:id/reactions", server.getStoryReactions)
+	authRoutes.POST("/stories/:id/reply", server.messageRateLimiter(), phoneVerifiedMiddleware(server), server.replyToStory)
 	authRoutes.POST("/stories/share", server.shareStory)
 
 	// Activity & Visibility
@@ -116,6 +173,7 @@ func (server *Server) setupRouter() {
 	// User Profiles
 	authRoutes.GET("/users/search", server.searchUsers)
 	authRoutes.GET("/users/:id", server.getUserProfile)
+	authRoutes.GET("/users/:id/presence", server.getUserPresence)
 	authRoutes.GET("/profile/me", server.getMyProfile)
 	authRoutes.GET("/profile/visitors", server.getProfileVisitors)
 
@@ -123,21 +181,31 @@ func (server *Server) setupRouter() {
 	authRoutes.POST("/groups", server.createGroup)
 	authRoutes.GET("/groups", server.getMyGroups)
 	authRoutes.GET("/groups/:id/messages", server.getGroupMessages)
+	authRoutes.GET("/groups/:id/messages/search", server.searchGroupMessages)
 
 	// Admin routes
 
 	adminRoutes := router.Group("/admin")
 	adminRoutes.Use(authMiddleware(server.tokenMaker))
 	adminRoutes.Use(adminMiddleware(server))
+	adminRoutes.Use(adminAuditMiddleware(server))
 
 	adminRoutes.GET("/users", server.listUsers)
 	adminRoutes.POST("/users/ban", server.banUser)
 	adminRoutes.DELETE("/users/:id", server.deleteUser)
+	adminRoutes.POST("/users/:id/impersonate", server.impersonateUser)
+	adminRoutes.PUT("/users/:id/safety-exemption", server.setSafetyExemption)
 	adminRoutes.GET("/stats", server.getStats)
+	adminRoutes.GET("/audit", server.listAuditLog)
+	adminRoutes.GET("/metrics/access-cache", server.getAccessCacheMetrics)
 	adminRoutes.GET("/reports", server.listReports)
 	adminRoutes.PUT("/reports/:id/resolve", server.resolveReport)
 	adminRoutes.GET("/stories", server.listAllStories)
+	adminRoutes.GET("/stories/held", server.listHeldStories)
 	adminRoutes.DELETE("/stories/:id", server.deleteStory)
+	adminRoutes.POST("/stories/:id/release", server.releaseStoryHold)
+	adminRoutes.PUT("/media/hold", server.setMediaHold)
+	adminRoutes.POST("/moderation/reload", server.reloadModerationWords)
 
 	server.router = router
 }
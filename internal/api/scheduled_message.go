@@ -0,0 +1,323 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// maxScheduleAhead is how far in the future a message may be scheduled
+	maxScheduleAhead = 30 * 24 * time.Hour
+	// scheduledMessageTick is how often due scheduled messages are sent
+	scheduledMessageTick = 15 * time.Second
+	// scheduledMessageBatch caps how many due messages one tick sends
+	scheduledMessageBatch = 100
+
+	// ScheduledMessageFailedType tells the sender a scheduled message was dropped
+	ScheduledMessageFailedType = "scheduled_message_failed"
+)
+
+var (
+	ErrScheduleInPast      = errors.New("scheduled_at must be in the future")
+	ErrScheduleTooFar      = errors.New("scheduled_at must be within 30 days")
+	ErrScheduleGroup       = errors.New("scheduled messages are only supported for direct messages")
+	ErrScheduleAttachments = errors.New("scheduled messages support a single attachment")
+	ErrScheduledNotFound   = errors.New("scheduled message not found")
+	errRecipientForbidden  = errors.New("recipient no longer accepts messages from the sender")
+)
+
+// validateScheduledAt checks a requested send time against the scheduling window
+func validateScheduledAt(scheduledAt time.Time) error {
+	now := util.Now()
+	if !scheduledAt.After(now) {
+		return ErrScheduleInPast
+	}
+	if scheduledAt.After(now.Add(maxScheduleAhead)) {
+		return ErrScheduleTooFar
+	}
+	return nil
+}
+
+// scheduleMessage stores an already validated send request for later delivery.
+// The connection check still runs again at send time.
+func (server *Server) scheduleMessage(ctx *gin.Context, req sendMessageRequest, attachments []MessageAttachment, expiry time.Duration) {
+	if req.GroupID != nil || req.ReceiverID == nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrScheduleGroup))
+		return
+	}
+	if len(attachments) > 1 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrScheduleAttachments))
+		return
+	}
+	var media MessageAttachment
+	if len(attachments) == 1 {
+		media = attachments[0]
+	}
+	if err := validateScheduledAt(*req.ScheduledAt); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := getAuthPayload(ctx)
+	scheduled, err := server.store.CreateScheduledMessage(ctx, db.CreateScheduledMessageParams{
+		SenderID:         authPayload.UserID,
+		ReceiverID:       *req.ReceiverID,
+		Content:          req.Content,
+		MediaUrl:         toNullString(media.URL),
+		MediaType:        toNullString(media.Type),
+		ExpiresInSeconds: int64(expiry / time.Second),
+		ScheduledAt:      req.ScheduledAt.UTC(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, server.scheduledWithMedia(ctx, scheduled))
+}
+
+// listScheduledMessages returns the caller's pending messages, soonest first
+func (server *Server) listScheduledMessages(ctx *gin.Context) {
+	authPayload := getAuthPayload(ctx)
+
+	scheduled, err := server.store.ListScheduledMessages(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if scheduled == nil {
+		scheduled = []db.ScheduledMessage{}
+	}
+	for i := range scheduled {
+		scheduled[i] = server.scheduledWithMedia(ctx, scheduled[i])
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+// updateScheduledMessageRequest changes a pending message; omitted fields are kept
+type updateScheduledMessageRequest struct {
+	Content     *string    `json:"content"`
+	MediaUrl    *string    `json:"media_url"`
+	MediaType   *string    `json:"media_type"`
+	ScheduledAt *time.Time `json:"scheduled_at"`
+}
+
+// updateScheduledMessage edits a message that hasn't been sent yet
+func (server *Server) updateScheduledMessage(ctx *gin.Context) {
+	scheduledID, ok := parseUUIDParam(ctx, ctx.Param("id"), "id")
+	if !ok {
+		return
+	}
+
+	var req updateScheduledMessageRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.Content != nil {
+		*req.Content = sanitizeMessageContent(*req.Content)
+		if err := validateMessageContent(*req.Content); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		if server.rejectBlockedText(ctx, *req.Content) {
+			return
+		}
+	}
+
+	authPayload := getAuthPayload(ctx)
+
+	scheduled, err := server.store.GetScheduledMessage(ctx, db.GetScheduledMessageParams{
+		ID:       scheduledID,
+		SenderID: authPayload.UserID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrScheduledNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.UpdateScheduledMessageParams{
+		ID:          scheduled.ID,
+		SenderID:    scheduled.SenderID,
+		Content:     scheduled.Content,
+		MediaUrl:    scheduled.MediaUrl,
+		MediaType:   scheduled.MediaType,
+		ScheduledAt: scheduled.ScheduledAt,
+	}
+	if req.Content != nil {
+		arg.Content = *req.Content
+	}
+	if req.MediaUrl != nil {
+		arg.MediaUrl = toNullString(*req.MediaUrl)
+	}
+	if req.MediaType != nil {
+		arg.MediaType = toNullString(*req.MediaType)
+	}
+	if req.ScheduledAt != nil {
+		if err := validateScheduledAt(*req.ScheduledAt); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		arg.ScheduledAt = req.ScheduledAt.UTC()
+	}
+	if arg.Content == "" && !arg.MediaUrl.Valid {
+		ctx.JSON(http.StatusBadRequest, errorResponse(&fieldError{Field: "content", Err: ErrEmptyMessage}))
+		return
+	}
+
+	// Sent between the read and the update: the row is gone
+	updated, err := server.store.UpdateScheduledMessage(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrScheduledNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, server.scheduledWithMedia(ctx, updated))
+}
+
+// cancelScheduledMessage deletes a message that hasn't been sent yet
+func (server *Server) cancelScheduledMessage(ctx *gin.Context) {
+	scheduledID, ok := parseUUIDParam(ctx, ctx.Param("id"), "id")
+	if !ok {
+		return
+	}
+
+	authPayload := getAuthPayload(ctx)
+
+	deleted, err := server.store.DeleteScheduledMessage(ctx, db.DeleteScheduledMessageParams{
+		ID:       scheduledID,
+		SenderID: authPayload.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if deleted == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(ErrScheduledNotFound))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Scheduled message cancelled"})
+}
+
+// StartScheduledMessageDispatcher sends scheduled messages once they are due
+func (server *Server) StartScheduledMessageDispatcher() {
+	ticker := time.NewTicker(scheduledMessageTick)
+	go func() {
+		for {
+			<-ticker.C
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			sent, err := server.sendDueScheduledMessages(ctx)
+			cancel()
+			if err != nil {
+				log.Error().Err(err).Int("sent", sent).Msg("failed to send scheduled messages")
+			}
+		}
+	}()
+}
+
+// sendDueScheduledMessages delivers every due scheduled message through the
+// normal delivery path and reports how many were sent
+func (server *Server) sendDueScheduledMessages(ctx context.Context) (int, error) {
+	now := util.Now()
+	due, err := server.store.ListDueScheduledMessages(ctx, db.ListDueScheduledMessagesParams{
+		DueBefore:   now,
+		ResultLimit: scheduledMessageBatch,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, scheduled := range due {
+		msg, err := server.sendScheduledMessage(ctx, scheduled, now)
+		if err != nil {
+			if errors.Is(err, errRecipientForbidden) {
+				server.sendWSNotification(scheduled.SenderID, ScheduledMessageFailedType, gin.H{
+					"id":          scheduled.ID,
+					"receiver_id": scheduled.ReceiverID,
+					"reason":      "recipient_unavailable",
+				})
+				continue
+			}
+			log.Error().Err(err).Str("scheduled_message_id", scheduled.ID.String()).Msg("failed to send scheduled message")
+			continue
+		}
+		if msg == nil {
+			continue // cancelled, postponed or sent by another instance
+		}
+		server.deliverMessage(*msg, legacyAttachments(msg.MediaUrl.String, msg.MediaType.String))
+		sent++
+	}
+	return sent, nil
+}
+
+// sendScheduledMessage turns one due scheduled message into a real message. The
+// connection and blocks are checked again, since they may have changed since it
+// was scheduled; a message that may no longer be sent is dropped.
+func (server *Server) sendScheduledMessage(ctx context.Context, scheduled db.ScheduledMessage, now time.Time) (*db.Message, error) {
+	if err := server.checkConnection(ctx, scheduled.SenderID, scheduled.ReceiverID); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, err // transient, retried next tick
+		}
+		_, err := server.store.DeleteScheduledMessage(ctx, db.DeleteScheduledMessageParams{
+			ID:       scheduled.ID,
+			SenderID: scheduled.SenderID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return nil, errRecipientForbidden
+	}
+
+	var msg *db.Message
+	err := server.store.ExecTx(ctx, func(q *db.Queries) error {
+		// Claim first so an edit or cancel racing this tick wins, and so only one
+		// instance sends it
+		claimed, err := q.ClaimScheduledMessage(ctx, db.ClaimScheduledMessageParams{
+			ID:        scheduled.ID,
+			DueBefore: now,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+
+		created, err := createMessageWithAttachments(ctx, q, db.CreateMessageParams{
+			SenderID:   claimed.SenderID,
+			ReceiverID: uuid.NullUUID{UUID: claimed.ReceiverID, Valid: true},
+			Content:    claimed.Content,
+			ExpiresAt: util.NullTime{
+				Time:  now.Add(time.Duration(claimed.ExpiresInSeconds) * time.Second),
+				Valid: claimed.ExpiresInSeconds > 0, // 0 came from a chat that never expires
+			},
+		}, legacyAttachments(claimed.MediaUrl.String, claimed.MediaType.String))
+		if err != nil {
+			return err
+		}
+		msg = &created
+		return nil
+	})
+	return msg, err
+}
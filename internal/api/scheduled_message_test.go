@@ -0,0 +1,51 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestValidateScheduledAt(t *testing.T) {
+	now := util.Now()
+
+	require.ErrorIs(t, validateScheduledAt(now.Add(-time.Minute)), ErrScheduleInPast)
+	require.ErrorIs(t, validateScheduledAt(now.Add(maxScheduleAhead+time.Hour)), ErrScheduleTooFar)
+	require.NoError(t, validateScheduledAt(now.Add(time.Hour)))
+}
+
+func TestSendScheduledMessageBlockedMeanwhile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	scheduled := db.ScheduledMessage{
+		ID:          uuid.New(),
+		SenderID:    uuid.New(),
+		ReceiverID:  uuid.New(),
+		Content:     "later",
+		ScheduledAt: util.Now(),
+	}
+
+	// The receiver blocked the sender after the message was scheduled: it is
+	// dropped rather than delivered
+	store.EXPECT().
+		IsUserBlocked(gomock.Any(), db.IsUserBlockedParams{BlockerID: scheduled.ReceiverID, BlockedID: scheduled.SenderID}).
+		Return(true, nil)
+	store.EXPECT().
+		DeleteScheduledMessage(gomock.Any(), db.DeleteScheduledMessageParams{ID: scheduled.ID, SenderID: scheduled.SenderID}).
+		Times(1).
+		Return(int64(1), nil)
+	store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+
+	msg, err := server.sendScheduledMessage(t.Context(), scheduled, util.Now())
+	require.ErrorIs(t, err, errRecipientForbidden)
+	require.Nil(t, msg)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestRejectSelfTarget(t *testing.T) {
+	self := uuid.New()
+	storyID := uuid.New()
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		body       gin.H
+		buildStubs func(store *mockdb.MockStore)
+	}{
+		{
+			name:   "SendMessage",
+			method: http.MethodPost,
+			url:    "/messages",
+			body:   gin.H{"receiver_id": self, "content": "hi"},
+		},
+		{
+			name:   "ConnectionRequest",
+			method: http.MethodPost,
+			url:    "/connections/request",
+			body:   gin.H{"target_user_id": self},
+		},
+		{
+			name:   "ConnectionUpdate",
+			method: http.MethodPost,
+			url:    "/connections/update",
+			body:   gin.H{"requester_id": self, "status": "accepted"},
+		},
+		{
+			name:   "ConnectionDelete",
+			method: http.MethodDelete,
+			url:    "/connections/" + self.String(),
+		},
+		{
+			name:   "Block",
+			method: http.MethodPost,
+			url:    "/users/block",
+			body:   gin.H{"user_id": self},
+		},
+		{
+			name:   "Unblock",
+			method: http.MethodDelete,
+			url:    "/users/block/" + self.String(),
+		},
+		{
+			name:   "BlockByID",
+			method: http.MethodPost,
+			url:    "/users/" + self.String() + "/block",
+		},
+		{
+			name:   "UnblockByID",
+			method: http.MethodDelete,
+			url:    "/users/" + self.String() + "/block",
+		},
+		{
+			name:   "ReactToOwnStory",
+			method: http.MethodPost,
+			url:    "/stories/" + storyID.String() + "/react",
+			body:   gin.H{"emoji": "🔥"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStoryByID(gomock.Any(), storyID).
+					Times(1).
+					Return(db.GetStoryByIDRow{ID: storyID, UserID: self}, nil)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Any store call beyond the stubs fails the test: nothing may be written
+			store := mockdb.NewMockStore(ctrl)
+			if tc.buildStubs != nil {
+				tc.buildStubs(store)
+			}
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				require.NoError(t, json.NewEncoder(&body).Encode(tc.body))
+			}
+			request, err := http.NewRequest(tc.method, tc.url, &body)
+			require.NoError(t, err)
+
+			accessToken, _, err := server.tokenMaker.CreateToken("self", self, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusBadRequest, recorder.Code, recorder.Body.String())
+		})
+	}
+}
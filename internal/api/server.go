@@ -1,21 +1,29 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
 
 	"privacy-social-backend/internal/config"
 	"privacy-social-backend/internal/realtime"
 	"privacy-social-backend/internal/repository"
 	"privacy-social-backend/internal/service/admin"
+	"privacy-social-backend/internal/service/analytics"
 	"privacy-social-backend/internal/service/location"
+	"privacy-social-backend/internal/service/moderation"
 	"privacy-social-backend/internal/service/safety"
 	"privacy-social-backend/internal/service/storage"
 	"privacy-social-backend/internal/service/story"
 	"privacy-social-backend/internal/service/user"
 	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
 )
 
 // Server serves HTTP requests for our privacy social service
@@ -27,11 +35,30 @@ type Server struct {
 	router     *gin.Engine
 	hub        *realtime.Hub
 	safety     *safety.Monitor
+	enforcer   *safety.Enforcer
 	location   *location.RedisLocationService
 	story      story.Service
 	user       user.Service
 	admin      admin.Service
 	storage    storage.Service
+	// media turns stored media references into URLs for responses
+	media *storage.MediaResolver
+	// events feeds the admin analytics; see StartAnalyticsIngest
+	events *analytics.Emitter
+	// moderation filters blocked words out of messages and captions
+	moderation *moderation.Filter
+	// rateLimits are the configured request limits, see rate_limit.go
+	rateLimits rateLimits
+	// metrics are served at GET /metrics, see metrics.go
+	metrics *serverMetrics
+	// origins are the browser origins allowed to call the API and open the
+	// chat WebSocket, see cors.go
+	origins originAllowlist
+	// verifyGoogleIDToken checks a Google ID token with Google; tests replace it
+	verifyGoogleIDToken func(idToken string) (*googleUser, error)
+	// httpServer is set by Start and stopped by Shutdown
+	httpServer *http.Server
+	httpMu     sync.Mutex
 }
 
 // NewServer creates a new HTTP server and setup routing
@@ -52,17 +79,54 @@ func NewServer(
 	}
 
 	rdb := redis.NewClient(opt)
+	util.SetRedisKeyPrefix(config.RedisKeyPrefix)
 	hub := realtime.NewHub(rdb)
+	hub.SetInstanceID(config.HubInstanceID)
+	hub.OnOffline(recordLastSeen(store))
 	go hub.Run() // Start the hub in a goroutine
 
-	safetyMonitor := safety.NewMonitor(rdb)
-	locationService := location.NewRedisLocationService(rdb, store)
-	storyService := story.NewService(store, rdb, safetyMonitor)
+	safetyMonitor := safety.NewMonitor(rdb, safety.MonitorConfig{
+		BlockThreshold:    config.SafetyBlockThreshold,
+		BanThreshold:      config.SafetyBanThreshold,
+		MaxPostsPerHour:   config.SafetyMaxStoriesPerHour,
+		BanStrikes:        config.SafetyBanStrikes,
+		MaxSpeedKmH:       config.SafetyMaxSpeedKmH,
+		TeleportWindow:    config.SafetyTeleportWindow,
+		TeleportMaxJumpKm: config.SafetyTeleportMaxJumpKm,
+	})
+	safetyEnforcer := safety.NewEnforcer(store, config.SafetyEnforce)
+	locationService := location.NewRedisLocationService(rdb, store, location.CrossingConfig{
+		RadiusMeters: config.CrossingRadiusMeters,
+		MinDwell:     config.CrossingMinDwell,
+		Cooldown:     config.CrossingCooldown,
+	})
+	storyService := story.NewService(store, rdb, safetyMonitor, safetyEnforcer, story.FeedConfig{
+		ConnectionsOnly: config.FeedConnectionsOnly,
+		ResultLimit:     config.FeedResultLimit,
+		DefaultRadius:   config.FeedDefaultRadius,
+		MaxRadius:       config.FeedMaxRadius,
+		RadiusStep:      config.FeedRadiusStep,
+	}, story.AnonymousConfig{
+		DailyLimit: config.AnonymousStoryDailyLimit,
+	})
 	userService := user.NewService(store, tokenMaker, user.TokenConfig{
 		AccessTokenDuration:  config.AccessTokenDuration,
 		RefreshTokenDuration: config.RefreshTokenDuration,
+		MaxSessionsPerUser:   config.MaxSessionsPerUser,
 	})
 	adminService := admin.NewService(store, rdb)
+	moderationFilter, err := moderation.NewFilter(moderation.Config{
+		Words:     config.ModerationWords,
+		WordsFile: config.ModerationWordsFile,
+		Mode:      moderation.Mode(config.ModerationMode),
+	})
+	if err != nil {
+		return nil, err
+	}
+	limits, err := newRateLimits(config)
+	if err != nil {
+		return nil, err
+	}
 
 	server := &Server{
 		config:     config,
@@ -70,21 +134,66 @@ func NewServer(
 		tokenMaker: tokenMaker,
 		redis:      rdb,
 		safety:     safetyMonitor,
+		enforcer:   safetyEnforcer,
 		hub:        hub,
 		location:   locationService,
 		story:      storyService,
 		user:       userService,
 		admin:      adminService,
 		storage:    storageService,
+		events:     analytics.NewEmitter(rdb),
+		moderation: moderationFilter,
+		rateLimits: limits,
+		metrics:    newServerMetrics(hub),
+		origins:    newOriginAllowlist(config.CorsAllowedOrigins),
+
+		verifyGoogleIDToken: verifyGoogleToken,
+	}
+	if storageService != nil {
+		server.media = storage.NewMediaResolver(storageService, mediaURLExpiry(config.MediaURLExpiry))
 	}
 
+	locationService.SetCrossingNotifier(server.sendCrossingNotification)
+	server.registerChatHandlers()
+
 	server.setupRouter()
+	// With no proxies trusted, gin reports the connecting address as the
+	// client IP and ignores X-Forwarded-For, which anyone could set
+	if err := server.router.SetTrustedProxies(trustedProxies(config.TrustedProxies)); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
 	return server, nil
 }
 
-// Start runs the HTTP server on a specific address
+// Start runs the HTTP server on a specific address until Shutdown is called
 func (server *Server) Start(address string) error {
+	server.httpMu.Lock()
+	server.httpServer = &http.Server{
+		Addr:    address,
+		Handler: server.router,
+	}
+	httpServer := server.httpServer
+	server.httpMu.Unlock()
+
 	// Force HTTP for localtunnel compatibility
-	fmt.Printf("Starting HTTP server on %s\n", address)
-	return server.router.Run(address)
+	log.Info().Str("address", address).Msg("starting HTTP server")
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting connections, waits for in-flight requests to finish
+// and then stops the chat hub's stream consumer, giving up when ctx is done.
+// WebSocket connections aren't waited for.
+func (server *Server) Shutdown(ctx context.Context) error {
+	server.httpMu.Lock()
+	httpServer := server.httpServer
+	server.httpMu.Unlock()
+
+	var httpErr error
+	if httpServer != nil {
+		httpErr = httpServer.Shutdown(ctx)
+	}
+	return errors.Join(httpErr, server.hub.Stop(ctx))
 }
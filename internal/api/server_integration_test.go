@@ -0,0 +1,172 @@
+//go:build integration
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"privacy-social-backend/internal/config"
+	"privacy-social-backend/internal/repository"
+	"privacy-social-backend/internal/testutil"
+	"privacy-social-backend/internal/util"
+)
+
+// These tests drive the real router, services, Postgres and Redis:
+//
+//	make test-env-up test-integration
+
+// setupTestServer wires a Server against a freshly migrated database and the test
+// Redis. Keys live under a per-test prefix, so runs never see each other's caches.
+func setupTestServer(t *testing.T) *Server {
+	conn := testutil.NewTestDB(t)
+	redisAddress := testutil.NewTestRedisAddress(t)
+
+	config := config.Config{
+		TokenSymmetricKey:    util.RandomString(32),
+		AccessTokenDuration:  15 * time.Minute,
+		RefreshTokenDuration: 24 * time.Hour,
+		RedisAddress:         redisAddress,
+		RedisKeyPrefix:       "test_" + util.RandomString(8),
+	}
+
+	server, err := NewServer(config, repository.NewStore(conn), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		server.redis.Close()
+		util.SetRedisKeyPrefix("")
+	})
+	return server
+}
+
+// doJSON sends body as JSON (when non-nil) and decodes the response into out
+func doJSON(t *testing.T, server *Server, method, path, accessToken string, body, out any) int {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+
+	request, err := http.NewRequest(method, path, bytes.NewReader(data))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	if out != nil {
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), out), recorder.Body.String())
+	}
+	return recorder.Code
+}
+
+func TestRegisterLoginPostAndSeeFeed(t *testing.T) {
+	server := setupTestServer(t)
+
+	phone := fmt.Sprintf("+91%010d", time.Now().UnixNano()%1e10)
+	password := util.RandomString(10)
+
+	// Registering logs in too; logging in again must reach the same account
+	var created loginUserResponse
+	code := doJSON(t, server, http.MethodPost, "/users", "", gin.H{
+		"phone":     phone,
+		"username":  "e2e" + util.RandomString(8),
+		"full_name": "End To End",
+		"password":  password,
+	}, &created)
+	require.Equal(t, http.StatusCreated, code)
+
+	var login loginUserResponse
+	code = doJSON(t, server, http.MethodPost, "/users/login", "", gin.H{
+		"phone":    phone,
+		"password": password,
+	}, &login)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, created.User.ID, login.User.ID)
+	require.NotEmpty(t, login.AccessToken)
+
+	const lat, lng = 12.9716, 77.5946
+	var posted StoryResponse
+	code = doJSON(t, server, http.MethodPost, "/stories", login.AccessToken, gin.H{
+		"media_url":     "https://example.com/e2e.jpg",
+		"media_type":    "image",
+		"latitude":      lat,
+		"longitude":     lng,
+		"caption":       "hello from the harness",
+		"show_location": true,
+	}, &posted)
+	require.Equal(t, http.StatusCreated, code)
+
+	var feed struct {
+		Stories []StoryResponse `json:"stories"`
+		Count   int             `json:"count"`
+	}
+	code = doJSON(t, server, http.MethodGet, fmt.Sprintf("/feed?latitude=%f&longitude=%f", lat, lng), login.AccessToken, nil, &feed)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 1, feed.Count)
+	require.Equal(t, posted.ID, feed.Stories[0].ID)
+	require.NotNil(t, feed.Stories[0].Caption)
+	require.Equal(t, "hello from the harness", *feed.Stories[0].Caption)
+}
+
+func TestDeleteOwnAccount(t *testing.T) {
+	server := setupTestServer(t)
+
+	phone := fmt.Sprintf("+91%010d", time.Now().UnixNano()%1e10)
+	password := util.RandomString(10)
+
+	var created loginUserResponse
+	code := doJSON(t, server, http.MethodPost, "/users", "", gin.H{
+		"phone":     phone,
+		"username":  "del" + util.RandomString(8),
+		"full_name": "Leaving Soon",
+		"password":  password,
+	}, &created)
+	require.Equal(t, http.StatusCreated, code)
+
+	// A group they created and a story they posted must not block the delete
+	code = doJSON(t, server, http.MethodPost, "/groups", created.AccessToken, gin.H{"name": "solo"}, nil)
+	require.Equal(t, http.StatusCreated, code)
+	code = doJSON(t, server, http.MethodPost, "/stories", created.AccessToken, gin.H{
+		"media_url":  "https://example.com/bye.jpg",
+		"media_type": "image",
+		"latitude":   12.9716,
+		"longitude":  77.5946,
+	}, nil)
+	require.Equal(t, http.StatusCreated, code)
+
+	code = doJSON(t, server, http.MethodDelete, "/users/me", created.AccessToken, gin.H{"password": password + "x"}, nil)
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	code = doJSON(t, server, http.MethodDelete, "/users/me", created.AccessToken, gin.H{"password": password}, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	code = doJSON(t, server, http.MethodPost, "/users/login", "", gin.H{"phone": phone, "password": password}, nil)
+	require.NotEqual(t, http.StatusOK, code)
+	code = doJSON(t, server, http.MethodPost, "/tokens/renew", "", gin.H{"refresh_token": created.RefreshToken}, nil)
+	require.NotEqual(t, http.StatusOK, code)
+}
+
+func TestReadyAgainstRealDependencies(t *testing.T) {
+	server := setupTestServer(t)
+
+	var rsp struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}
+	require.Equal(t, http.StatusOK, doJSON(t, server, http.MethodGet, "/ready", "", nil, &rsp))
+	require.Equal(t, "ready", rsp.Status)
+	require.Equal(t, "ok", rsp.Checks["database"])
+	require.Equal(t, "ok", rsp.Checks["redis"])
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestServerShutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	started := make(chan error, 1)
+	go func() { started <- server.Start("127.0.0.1:0") }()
+	require.Eventually(t, func() bool {
+		server.httpMu.Lock()
+		defer server.httpMu.Unlock()
+		return server.httpServer != nil
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, server.Shutdown(ctx))
+
+	// Start reports a clean stop rather than http.ErrServerClosed
+	select {
+	case err := <-started:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after Shutdown")
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/user"
+	"privacy-social-backend/internal/token"
+)
+
+const (
+	// Optional headers clients send on login so users can tell their sessions apart
+	deviceNameHeader     = "X-Device-Name"
+	devicePlatformHeader = "X-Device-Platform"
+	maxDeviceFieldLength = 100
+)
+
+// sessionDevice collects the device metadata stored with a new session
+func sessionDevice(ctx *gin.Context) user.SessionDevice {
+	return user.SessionDevice{
+		UserAgent:  ctx.Request.UserAgent(),
+		ClientIP:   ctx.ClientIP(),
+		DeviceName: truncateDeviceField(ctx.GetHeader(deviceNameHeader)),
+		Platform:   truncateDeviceField(ctx.GetHeader(devicePlatformHeader)),
+	}
+}
+
+func truncateDeviceField(value string) string {
+	runes := []rune(value)
+	if len(runes) > maxDeviceFieldLength {
+		return string(runes[:maxDeviceFieldLength])
+	}
+	return value
+}
+
+type sessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	DeviceName string    `json:"device_name"`
+	Platform   string    `json:"platform"`
+	UserAgent  string    `json:"user_agent"`
+	ClientIP   string    `json:"client_ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func newSessionResponse(session db.Session) sessionResponse {
+	return sessionResponse{
+		ID:         session.ID,
+		DeviceName: session.DeviceName,
+		Platform:   session.Platform,
+		UserAgent:  session.UserAgent,
+		ClientIP:   session.ClientIp,
+		CreatedAt:  session.CreatedAt,
+		ExpiresAt:  session.ExpiresAt,
+	}
+}
+
+// listSessions returns the authenticated user's active sessions, newest first
+func (server *Server) listSessions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	sessions, err := server.user.ListSessions(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]sessionResponse, len(sessions))
+	for i, session := range sessions {
+		rsp[i] = newSessionResponse(session)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"sessions": rsp})
+}
+
+// revokeSession signs one of the authenticated user's sessions out
+func (server *Server) revokeSession(ctx *gin.Context) {
+	sessionID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.user.RevokeSession(ctx, authPayload.UserID, sessionID)
+	if err != nil {
+		if errors.Is(err, user.ErrSessionNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
+
+var ErrLogoutTarget = errors.New("send either session_id or refresh_token")
+
+type logoutRequest struct {
+	SessionID    *uuid.UUID `json:"session_id"`
+	RefreshToken string     `json:"refresh_token"`
+}
+
+// logout blocks one of the authenticated user's sessions, named by its ID or
+// its refresh token. Logging out of a session that is already blocked is fine.
+func (server *Server) logout(ctx *gin.Context) {
+	var req logoutRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if (req.SessionID == nil) == (req.RefreshToken == "") {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrLogoutTarget))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// A session's ID is the ID of its refresh token
+	var sessionID uuid.UUID
+	if req.SessionID != nil {
+		sessionID = *req.SessionID
+	} else {
+		refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		sessionID = refreshPayload.ID
+	}
+
+	err := server.user.Logout(ctx, authPayload.UserID, sessionID)
+	if err != nil {
+		if errors.Is(err, user.ErrSessionNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// logoutAll blocks every session of the authenticated user
+func (server *Server) logoutAll(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	revoked, err := server.user.LogoutAll(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "logged out of all sessions", "revoked": revoked})
+}
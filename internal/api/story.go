@@ -1,25 +1,29 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/mmcloughlin/geohash"
+	"github.com/rs/zerolog/log"
 
 	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/analytics"
+	"privacy-social-backend/internal/service/safety"
 	"privacy-social-backend/internal/service/story"
 	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
 )
 
 const (
-	defaultRadiusMeters = 5000  // 5km
-	maxRadiusMeters     = 20000 // 20km
-	radiusStepMeters    = 5000  // 5km step
-	feedCacheTTL        = 5 * time.Minute
+	feedCacheTTL = 5 * time.Minute
 )
 
 type createStoryRequest struct {
@@ -30,6 +34,10 @@ type createStoryRequest struct {
 	Caption      string  `json:"caption"`
 	IsAnonymous  bool    `json:"is_anonymous"`
 	ShowLocation bool    `json:"show_location"`
+	// Audience is who sees the story: public (the default), connections or close_friends
+	Audience string `json:"audience" binding:"omitempty,oneof=public connections close_friends"`
+	// Client carries device hints (mock provider, spoofing apps) for the safety checks
+	Client safety.ClientHints `json:"client"`
 }
 
 func (server *Server) createStory(ctx *gin.Context) {
@@ -39,6 +47,12 @@ func (server *Server) createStory(ctx *gin.Context) {
 		return
 	}
 
+	caption, ok := server.moderateCaption(ctx, req.Caption)
+	if !ok {
+		return
+	}
+	req.Caption = caption
+
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
 	result, err := server.story.CreateStory(ctx, story.CreateStoryParams{
@@ -50,18 +64,53 @@ func (server *Server) createStory(ctx *gin.Context) {
 		Caption:      req.Caption,
 		IsAnonymous:  req.IsAnonymous,
 		ShowLocation: req.ShowLocation,
+		Audience:     db.StoryAvailability(req.Audience),
+		Client:       req.Client,
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		switch {
+		case errors.Is(err, story.ErrAnonymousStoryLimit):
+			ctx.JSON(http.StatusTooManyRequests, errorResponse(err))
+		case errors.Is(err, story.ErrSafetyRejected):
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		case errors.Is(err, story.ErrAnonymousStoryLinks), errors.Is(err, story.ErrAnonymousStoryAudience):
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, toStoryResponseFromCreate(*result))
+	server.events.Emit(ctx, analytics.EventStoryPosted, authPayload.UserID)
+	server.metrics.storiesCreated.Inc()
+
+	// Mentions would reveal who posted an anonymous story, so only named stories notify
+	if !result.IsAnonymous {
+		go func() {
+			mentionCtx, cancel := context.WithTimeout(context.Background(), storyMentionTimeout)
+			defer cancel()
+			if err := server.createStoryMentions(mentionCtx, result.ID, authPayload, req.Caption); err != nil {
+				log.Error().Err(err).Str("story_id", result.ID.String()).Msg("failed to create story mentions")
+			}
+		}()
+	}
+
+	rsp := toStoryResponseFromCreate(*result)
+	rsp.ShareURL = server.storyShareURL(rsp.ID)
+	server.resolveStoryMedia(ctx, &rsp)
+	rsp.PendingReview = result.IsAnonymous
+
+	ctx.JSON(http.StatusCreated, rsp)
 }
 
 type getFeedRequest struct {
 	Latitude  float64 `form:"latitude" binding:"required,min=-90,max=90"`
 	Longitude float64 `form:"longitude" binding:"required,min=-180,max=180"`
+	// Since (RFC3339) returns only stories posted after it, for incremental refresh
+	Since time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	// Radius in meters; zero uses FEED_DEFAULT_RADIUS, and others are clamped
+	// between it and FEED_MAX_RADIUS
+	Radius float64 `form:"radius" binding:"omitempty,gt=0"`
 }
 
 func (server *Server) getFeed(ctx *gin.Context) {
@@ -73,53 +122,100 @@ func (server *Server) getFeed(ctx *gin.Context) {
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	// Create cache key based on user's geohash (5 chars = ~2.4km precision)
+	// Resolve the radius up front so requests rounding to the same step share a cache entry
+	radius := server.story.FeedRadius(req.Radius)
+	req.Radius = radius
+
+	// Incremental refreshes are small, per-client deltas: serve them straight
+	// from the database so they never overwrite the full feed in the cache
+	if !req.Since.IsZero() {
+		server.getFeedSince(ctx, req, authPayload)
+		return
+	}
+
+	// Cache per user within the 5-char geohash cell (~2.4km): the feed is filtered
+	// by the requester's blocks and audience, so it must never be shared across users
 	userGeohash := geohash.Encode(req.Latitude, req.Longitude)
 	if len(userGeohash) > 5 {
 		userGeohash = userGeohash[:5]
 	}
-	cacheKey := "feed:" + userGeohash
+	cacheKey, cacheErr := story.FeedCacheKey(ctx, server.redis, userGeohash, radius, authPayload.UserID)
 
 	// Try to get from Redis cache first
-	cachedData, err := server.redis.Get(ctx, cacheKey).Result()
-	if err == nil && cachedData != "" {
-		// Cache hit - return cached data
-		ctx.Header("X-Cache", "HIT")
-		ctx.Data(http.StatusOK, "application/json", []byte(cachedData))
-		return
+	if cacheErr == nil {
+		cachedData, err := server.redis.Get(ctx, cacheKey).Result()
+		if err == nil && cachedData != "" {
+			// Cache hit - return cached data
+			ctx.Header("X-Cache", "HIT")
+			ctx.Data(http.StatusOK, "application/json", []byte(cachedData))
+			return
+		}
 	}
 
-	stories, message, radius, err := server.story.GetFeed(ctx, story.GetFeedParams{
-		UserID:    authPayload.UserID,
-		Latitude:  req.Latitude,
-		Longitude: req.Longitude,
+	feed, err := server.story.GetFeed(ctx, story.GetFeedParams{
+		UserID:       authPayload.UserID,
+		Latitude:     req.Latitude,
+		Longitude:    req.Longitude,
+		RadiusMeters: radius,
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	// Convert to response DTOs
-	storyResponses := make([]StoryResponse, len(stories))
-	for i, story := range stories {
-		storyResponses[i] = toStoryResponse(story)
+	response := server.feedResponse(ctx, feed, authPayload.UserID)
+
+	// Cache the result for 5 minutes
+	if cacheErr == nil {
+		responseJSON, _ := json.Marshal(response)
+		server.redis.Set(ctx, cacheKey, responseJSON, feedCacheTTL)
 	}
 
-	response := gin.H{
-		"stories":       storyResponses,
-		"count":         len(storyResponses),
-		"message":       message,
-		"search_radius": radius,
+	ctx.Header("X-Cache", "MISS")
+	ctx.JSON(http.StatusOK, response)
+}
+
+// getFeedSince returns the stories posted after req.Since, bypassing the feed cache
+func (server *Server) getFeedSince(ctx *gin.Context, req getFeedRequest, authPayload *token.Payload) {
+	feed, err := server.story.GetFeed(ctx, story.GetFeedParams{
+		UserID:       authPayload.UserID,
+		Latitude:     req.Latitude,
+		Longitude:    req.Longitude,
+		RadiusMeters: req.Radius,
+		Since:        req.Since,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
-	// Cache the result for 5 minutes
-	responseJSON, _ := json.Marshal(response)
-	server.redis.Set(ctx, cacheKey, responseJSON, feedCacheTTL)
+	response := server.feedResponse(ctx, feed, authPayload.UserID)
+	response["since"] = req.Since.UTC()
 
-	ctx.Header("X-Cache", "MISS")
+	ctx.Header("X-Cache", "BYPASS")
 	ctx.JSON(http.StatusOK, response)
 }
 
+// feedResponse converts a feed result into the JSON body shared by full and incremental fetches
+func (server *Server) feedResponse(ctx context.Context, feed *story.FeedResult, viewerID uuid.UUID) gin.H {
+	storyResponses := make([]StoryResponse, len(feed.Stories))
+	for i, story := range feed.Stories {
+		storyResponses[i] = toStoryResponse(story, viewerID)
+		storyResponses[i].ShareURL = server.storyShareURL(story.ID)
+		server.resolveStoryMedia(ctx, &storyResponses[i])
+	}
+
+	return gin.H{
+		"stories":       storyResponses,
+		"count":         len(storyResponses),
+		"total":         feed.Total,
+		"truncated":     feed.Truncated,
+		"message":       feed.Message,
+		"search_radius": feed.Radius,
+		"as_of":         feed.AsOf,
+	}
+}
+
 // deleteStory allows users to delete their own stories
 func (server *Server) deleteUserStory(ctx *gin.Context) {
 	storyID, err := uuid.Parse(ctx.Param("id"))
@@ -167,9 +263,58 @@ func (server *Server) updateStory(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	if req.Caption != nil {
+		caption, ok := server.moderateCaption(ctx, *req.Caption)
+		if !ok {
+			return
+		}
+		req.Caption = &caption
+	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
+	// Anonymous stories follow the same rules whether posted that way or switched later
+	becameAnonymous := false
+	if req.Caption != nil || (req.IsAnonymous != nil && *req.IsAnonymous) {
+		current, err := server.store.GetStoryByID(ctx, storyID)
+		if err == nil && current.UserID != authPayload.UserID {
+			err = sql.ErrNoRows
+		}
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.JSON(http.StatusForbidden, gin.H{"error": "story not found, expired, or edit window closed (15 minutes)"})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		caption := current.Caption.String
+		if req.Caption != nil {
+			caption = *req.Caption
+		}
+		becameAnonymous = req.IsAnonymous != nil && *req.IsAnonymous && !current.IsAnonymous
+		switch {
+		case becameAnonymous && current.Visibility != db.StoryAvailabilityPublic:
+			err = story.ErrAnonymousStoryAudience
+		case becameAnonymous:
+			err = server.story.CheckAnonymousStory(ctx, authPayload.UserID, caption)
+		case current.IsAnonymous && util.ContainsLink(caption):
+			err = story.ErrAnonymousStoryLinks
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, story.ErrAnonymousStoryLimit):
+				ctx.JSON(http.StatusTooManyRequests, errorResponse(err))
+			case errors.Is(err, story.ErrAnonymousStoryLinks), errors.Is(err, story.ErrAnonymousStoryAudience):
+				ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			default:
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			}
+			return
+		}
+	}
+
 	// Prepare nullable parameters for SQL
 	var captionArg sql.NullString
 	if req.Caption != nil {
@@ -203,6 +348,19 @@ func (server *Server) updateStory(ctx *gin.Context) {
 		return
 	}
 
+	if becameAnonymous {
+		if err := server.story.HoldAnonymousStory(ctx, story.ID); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	if req.Caption != nil {
+		if err := server.story.IndexHashtags(ctx, story.ID, *req.Caption); err != nil {
+			log.Error().Err(err).Str("story_id", story.ID.String()).Msg("Failed to reindex story hashtags")
+		}
+	}
+
 	// Invalidate feed cache
 	userGeohash := story.Geohash
 	if len(userGeohash) > 5 {
@@ -212,6 +370,8 @@ func (server *Server) updateStory(ctx *gin.Context) {
 
 	// Convert to response
 	rsp := toStoryResponseFromUpdate(story)
+	rsp.ShareURL = server.storyShareURL(rsp.ID)
+	server.resolveStoryMedia(ctx, &rsp)
 
 	ctx.JSON(http.StatusOK, rsp)
 }
@@ -221,7 +381,7 @@ func (server *Server) getConnectionStories(ctx *gin.Context) {
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
 	// Cache key based on user ID
-	cacheKey := "stories:connections:" + authPayload.UserID.String()
+	cacheKey := util.RedisKey("stories:connections:" + authPayload.UserID.String())
 
 	// Try Redis cache first
 	cachedData, err := server.redis.Get(ctx, cacheKey).Result()
@@ -240,7 +400,9 @@ func (server *Server) getConnectionStories(ctx *gin.Context) {
 	// Convert to response DTOs
 	storyResponses := make([]StoryResponse, len(stories))
 	for i, story := range stories {
-		storyResponses[i] = toStoryResponseFromConnection(story)
+		storyResponses[i] = toStoryResponseFromConnection(story, authPayload.UserID)
+		storyResponses[i].ShareURL = server.storyShareURL(story.ID)
+		server.resolveStoryMedia(ctx, &storyResponses[i])
 	}
 
 	// Cache for 5 minutes
@@ -251,6 +413,52 @@ func (server *Server) getConnectionStories(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, storyResponses)
 }
 
+// getMyStories lists the caller's live stories and expired highlights, newest first
+func (server *Server) getMyStories(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	rows, err := server.store.ListMyStories(ctx, db.ListMyStoriesParams{
+		UserID:     authPayload.UserID,
+		Now:        util.Now(),
+		PageLimit:  int32(pageSize),
+		PageOffset: int32((page - 1) * pageSize),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var total int64
+	stories := make([]MyStoryResponse, len(rows))
+	for i, row := range rows {
+		stories[i] = toMyStoryResponse(row)
+		stories[i].MediaURL = server.media.Resolve(ctx, stories[i].MediaURL)
+		if stories[i].ThumbnailURL != nil {
+			thumbnail := server.media.Resolve(ctx, *stories[i].ThumbnailURL)
+			stories[i].ThumbnailURL = &thumbnail
+		}
+		total = row.TotalCount
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"stories":     stories,
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	})
+}
+
 // getStory retrieves a single story by ID
 func (server *Server) getStory(ctx *gin.Context) {
 	storyID, err := uuid.Parse(ctx.Param("id"))
@@ -270,20 +478,50 @@ func (server *Server) getStory(ctx *gin.Context) {
 	}
 
 	// Check if story is expired
-	if time.Now().After(story.ExpiresAt) {
+	if !util.Now().Before(story.ExpiresAt) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "story has expired"})
 		return
 	}
 
+	// Stories awaiting moderation are only visible to their author
+	held, err := server.store.IsStoryHeld(ctx, storyID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if held && story.UserID != authPayload.UserID {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "story not found"})
+		return
+	}
+	if server.rejectOutsideAudience(ctx, story.Visibility, story.UserID, authPayload.UserID) {
+		return
+	}
+
 	// Convert to response DTO
-	rsp := toStoryResponseFromGet(story)
-
-	// Fetch author details since they aren't in the partial story object
-	user, err := server.store.GetUserByID(ctx, story.UserID)
-	if err == nil {
-		rsp.Username = user.Username
-		if user.AvatarUrl.Valid {
-			rsp.AvatarURL = &user.AvatarUrl.String
+	rsp := toStoryResponseFromGet(story, authPayload.UserID)
+	rsp.ShareURL = server.storyShareURL(rsp.ID)
+	server.resolveStoryMedia(ctx, &rsp)
+	rsp.PendingReview = held
+
+	if story.UserID == authPayload.UserID {
+		viewCount, err := server.store.CountStoryViews(ctx, storyID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		rsp.ViewCount = &viewCount
+	}
+
+	// Fetch author details since they aren't in the partial story object,
+	// unless the story is anonymous to this viewer
+	if rsp.UserID != uuid.Nil {
+		user, err := server.store.GetUserByID(ctx, story.UserID)
+		if err == nil {
+			rsp.Username = user.Username
+			if user.AvatarUrl.Valid {
+				rsp.AvatarURL = &user.AvatarUrl.String
+			}
 		}
 	}
 
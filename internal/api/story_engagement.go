@@ -54,6 +54,9 @@ func (server *Server) viewStory(ctx *gin.Context) {
 		ctx.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
 		return
 	}
+	if server.rejectOutsideAudience(ctx, story.Visibility, story.UserID, authPayload.UserID) {
+		return
+	}
 
 	if story.UserID == authPayload.UserID {
 		// Do not record view for own story
@@ -69,6 +72,8 @@ func (server *Server) viewStory(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
+	// The viewer's rings now show this story as seen
+	server.redis.Del(ctx, storyRingsCacheKey(authPayload.UserID))
 
 	// Notify story owner via WebSocket
 	event := struct {
@@ -129,8 +134,11 @@ func (server *Server) getStoryViewers(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, viewers)
 }
 
+// StoryReactionType tells a story's author someone reacted to it
+const StoryReactionType = "story_reaction"
+
 type createReactionRequest struct {
-	Emoji string `json:"emoji" binding:"required,min=1,max=10"`
+	Emoji string `json:"emoji" binding:"required"`
 }
 
 // reactToStory adds or updates a reaction to a story
@@ -146,6 +154,10 @@ func (server *Server) reactToStory(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	if err := server.validateReaction(bodyReq.Emoji); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
 
 	authPayload := getAuthPayload(ctx)
 	storyID, ok := parseUUIDParam(ctx, uriReq.StoryID, "story_id")
@@ -153,6 +165,34 @@ func (server *Server) reactToStory(ctx *gin.Context) {
 		return
 	}
 
+	story, err := server.store.GetStoryByID(ctx, storyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "story not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if rejectSelfTarget(ctx, authPayload.UserID, story.UserID, "cannot react to your own story") {
+		return
+	}
+	isBlocked, err := server.store.IsUserBlocked(ctx, db.IsUserBlockedParams{
+		BlockerID: story.UserID,
+		BlockedID: authPayload.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if isBlocked {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+	if server.rejectOutsideAudience(ctx, story.Visibility, story.UserID, authPayload.UserID) {
+		return
+	}
+
 	reaction, err := server.store.CreateStoryReaction(ctx, db.CreateStoryReactionParams{
 		StoryID: storyID,
 		UserID:  authPayload.UserID,
@@ -163,6 +203,16 @@ func (server *Server) reactToStory(ctx *gin.Context) {
 		return
 	}
 
+	// The author is told who reacted even on an anonymous story; only the
+	// author's identity is hidden, and the reactor's response reveals nothing
+	server.sendWSNotification(story.UserID, StoryReactionType, map[string]interface{}{
+		"story_id":   story.ID,
+		"user_id":    authPayload.UserID,
+		"username":   authPayload.Username,
+		"emoji":      reaction.Emoji,
+		"created_at": reaction.CreatedAt,
+	})
+
 	ctx.JSON(http.StatusOK, reaction)
 }
 
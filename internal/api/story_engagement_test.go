@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/realtime"
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestReactToStory(t *testing.T) {
+	userID, ownerID, storyID := uuid.New(), uuid.New(), uuid.New()
+	story := db.GetStoryByIDRow{ID: storyID, UserID: ownerID}
+	reaction := db.StoryReaction{StoryID: storyID, UserID: userID, Emoji: "🔥"}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, events []realtime.WSMessage)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).Return(story, nil)
+				store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+				store.EXPECT().
+					CreateStoryReaction(gomock.Any(), db.CreateStoryReactionParams{StoryID: storyID, UserID: userID, Emoji: "🔥"}).
+					Times(1).
+					Return(reaction, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, events []realtime.WSMessage) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				require.Len(t, events, 1)
+				require.Equal(t, StoryReactionType, events[0].Type)
+				payload := events[0].Payload.(map[string]interface{})
+				require.Equal(t, storyID.String(), payload["story_id"])
+				require.Equal(t, userID.String(), payload["user_id"])
+				require.Equal(t, "user", payload["username"])
+				require.Equal(t, "🔥", payload["emoji"])
+			},
+		},
+		{
+			// Anonymity hides the author, so the author still learns who reacted
+			name: "AnonymousStory",
+			buildStubs: func(store *mockdb.MockStore) {
+				anonymous := story
+				anonymous.IsAnonymous = true
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).Return(anonymous, nil)
+				store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+				store.EXPECT().CreateStoryReaction(gomock.Any(), gomock.Any()).Times(1).Return(reaction, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, events []realtime.WSMessage) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				require.NotContains(t, recorder.Body.String(), ownerID.String())
+				require.Len(t, events, 1)
+			},
+		},
+		{
+			name: "CloseFriend",
+			buildStubs: func(store *mockdb.MockStore) {
+				closeFriends := story
+				closeFriends.Visibility = db.StoryAvailabilityCloseFriends
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).Return(closeFriends, nil)
+				store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+				store.EXPECT().
+					IsCloseFriend(gomock.Any(), db.IsCloseFriendParams{UserID: ownerID, FriendID: userID}).
+					Times(1).
+					Return(true, nil)
+				store.EXPECT().CreateStoryReaction(gomock.Any(), gomock.Any()).Times(1).Return(reaction, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, events []realtime.WSMessage) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				require.Len(t, events, 1)
+			},
+		},
+		{
+			// A close-friends story doesn't exist for anyone off the list
+			name: "OutsideAudience",
+			buildStubs: func(store *mockdb.MockStore) {
+				closeFriends := story
+				closeFriends.Visibility = db.StoryAvailabilityCloseFriends
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).Return(closeFriends, nil)
+				store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+				store.EXPECT().IsCloseFriend(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+				store.EXPECT().CreateStoryReaction(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, events []realtime.WSMessage) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Empty(t, events)
+			},
+		},
+		{
+			name: "BlockedByOwner",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).Return(story, nil)
+				store.EXPECT().
+					IsUserBlocked(gomock.Any(), db.IsUserBlockedParams{BlockerID: ownerID, BlockedID: userID}).
+					Times(1).
+					Return(true, nil)
+				store.EXPECT().CreateStoryReaction(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, events []realtime.WSMessage) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Empty(t, events)
+			},
+		},
+		{
+			name: "NotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).Return(db.GetStoryByIDRow{}, sql.ErrNoRows)
+				store.EXPECT().CreateStoryReaction(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, events []realtime.WSMessage) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Empty(t, events)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.hub = realtime.NewHub(server.redis)
+
+			recorder := postJSON(t, server, fmt.Sprintf("/stories/%s/react", storyID), gin.H{"emoji": "🔥"}, &userID)
+
+			entries, err := server.redis.XRange(context.Background(), util.RedisKey("locolive:stream:routing"), "-", "+").Result()
+			require.NoError(t, err)
+			var events []realtime.WSMessage
+			for _, entry := range entries {
+				require.Equal(t, ownerID.String(), entry.Values["target_user_id"])
+				var event realtime.WSMessage
+				require.NoError(t, json.Unmarshal([]byte(entry.Values["payload"].(string)), &event))
+				events = append(events, event)
+			}
+			tc.checkResponse(t, recorder, events)
+		})
+	}
+}
+
+func TestStoryResponseReactionCount(t *testing.T) {
+	rsp := toStoryResponseFromGet(db.GetStoryByIDRow{ReactionCount: 3}, uuid.New())
+	require.Equal(t, int64(3), rsp.ReactionCount)
+	data, err := json.Marshal(rsp)
+	require.NoError(t, err)
+	require.JSONEq(t, `3`, mustJSONField(t, data, "reaction_count"))
+}
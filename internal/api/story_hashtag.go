@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mmcloughlin/geohash"
+
+	"privacy-social-backend/internal/service/story"
+	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// hashtagCacheTTL is shorter than the feed's: popular tags churn quickly
+	hashtagCacheTTL = 2 * time.Minute
+	// trendingCacheTTL keeps trending counts close to live while sparing the aggregate query
+	trendingCacheTTL = 1 * time.Minute
+	// trendingGeohashPrecision is the cell (~4.9km) that trending results are shared across
+	trendingGeohashPrecision = 5
+)
+
+type getStoriesByHashtagRequest struct {
+	Latitude  float64 `form:"latitude" binding:"required,min=-90,max=90"`
+	Longitude float64 `form:"longitude" binding:"required,min=-180,max=180"`
+}
+
+// getStoriesByHashtag returns nearby live stories tagged with a hashtag, with the
+// same expiry, block and audience rules as the feed
+func (server *Server) getStoriesByHashtag(ctx *gin.Context) {
+	tag := util.NormalizeHashtag(ctx.Param("tag"))
+	if tag == "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("invalid hashtag")))
+		return
+	}
+
+	var req getStoriesByHashtagRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	userGeohash := geohash.Encode(req.Latitude, req.Longitude)
+	if len(userGeohash) > 5 {
+		userGeohash = userGeohash[:5]
+	}
+	cacheKey, cacheErr := story.HashtagCacheKey(ctx, server.redis, tag, userGeohash, authPayload.UserID)
+
+	if cacheErr == nil {
+		cachedData, err := server.redis.Get(ctx, cacheKey).Result()
+		if err == nil && cachedData != "" {
+			ctx.Header("X-Cache", "HIT")
+			ctx.Data(http.StatusOK, "application/json", []byte(cachedData))
+			return
+		}
+	}
+
+	feed, err := server.story.GetFeed(ctx, story.GetFeedParams{
+		UserID:    authPayload.UserID,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		Hashtag:   tag,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := server.feedResponse(ctx, feed, authPayload.UserID)
+	response["hashtag"] = tag
+
+	if cacheErr == nil {
+		responseJSON, _ := json.Marshal(response)
+		server.redis.Set(ctx, cacheKey, responseJSON, hashtagCacheTTL)
+	}
+
+	ctx.Header("X-Cache", "MISS")
+	ctx.JSON(http.StatusOK, response)
+}
+
+type getTrendingHashtagsRequest struct {
+	Latitude  float64 `form:"latitude" binding:"required,min=-90,max=90"`
+	Longitude float64 `form:"longitude" binding:"required,min=-180,max=180"`
+	// Radius in meters; zero uses story.DefaultTrendingRadius
+	Radius float64 `form:"radius" binding:"omitempty,min=500,max=50000"`
+}
+
+// getTrendingHashtags returns the hashtags rising fastest on live stories in the area
+func (server *Server) getTrendingHashtags(ctx *gin.Context) {
+	var req getTrendingHashtagsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	radius := req.Radius
+	if radius == 0 {
+		radius = story.DefaultTrendingRadius
+	}
+
+	// Trending is an area aggregate centred on a coarse cell, but it only
+	// counts stories this viewer may see, so the cache is per user
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	cell := geohash.EncodeWithPrecision(req.Latitude, req.Longitude, trendingGeohashPrecision)
+	lat, lng := geohash.DecodeCenter(cell)
+	cacheKey := util.RedisKey(fmt.Sprintf("hashtags:trending:%s:%.0f:%s", cell, radius, authPayload.UserID))
+
+	cachedData, err := server.redis.Get(ctx, cacheKey).Result()
+	if err == nil && cachedData != "" {
+		ctx.Header("X-Cache", "HIT")
+		ctx.Data(http.StatusOK, "application/json", []byte(cachedData))
+		return
+	}
+
+	trending, err := server.story.TrendingHashtags(ctx, story.TrendingHashtagsParams{
+		UserID:       authPayload.UserID,
+		Latitude:     lat,
+		Longitude:    lng,
+		RadiusMeters: radius,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := gin.H{
+		"hashtags":      trending,
+		"count":         len(trending),
+		"search_radius": radius,
+	}
+
+	responseJSON, _ := json.Marshal(response)
+	server.redis.Set(ctx, cacheKey, responseJSON, trendingCacheTTL)
+
+	ctx.Header("X-Cache", "MISS")
+	ctx.JSON(http.StatusOK, response)
+}
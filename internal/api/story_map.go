@@ -11,7 +11,9 @@ import (
 	"github.com/mmcloughlin/geohash"
 
 	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/location"
 	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
 )
 
 type getStoriesMapRequest struct {
@@ -21,7 +23,13 @@ type getStoriesMapRequest struct {
 	West  float64 `form:"west" binding:"required,min=-180,max=180"`
 }
 
-const mapCacheTTL = 5 * time.Minute
+const (
+	mapCacheTTL = 5 * time.Minute
+	// mapClusterPrecision is the geohash length stories are clustered at
+	mapClusterPrecision = 5
+	// mapClusterMinPrecision (~156km) is as far up as sparse clusters are merged before they're dropped
+	mapClusterMinPrecision = 3
+)
 
 // getStoriesMap returns stories within a bounding box for map display
 func (server *Server) getStoriesMap(ctx *gin.Context) {
@@ -45,7 +53,7 @@ func (server *Server) getStoriesMap(ctx *gin.Context) {
 	}
 
 	// Create cache key from bounding box (rounded to 2 decimals for better cache hits) + UserID for personalization
-	cacheKey := fmt.Sprintf("map:%.2f:%.2f:%.2f:%.2f:%s", req.North, req.South, req.East, req.West, authPayload.UserID)
+	cacheKey := util.RedisKey(fmt.Sprintf("map:%.2f:%.2f:%.2f:%.2f:%s", req.North, req.South, req.East, req.West, authPayload.UserID))
 
 	// Try Redis cache first
 	cachedData, err := server.redis.Get(context.Background(), cacheKey).Result()
@@ -67,16 +75,27 @@ func (server *Server) getStoriesMap(ctx *gin.Context) {
 		return
 	}
 
-	// Cluster stories by geohash (5 chars = ~2.4km precision)
-	clusters := make(map[string][]db.GetStoriesInBoundsRow)
+	// Cluster stories by geohash (5 chars = ~2.4km precision), then merge clusters
+	// with too few distinct authors into coarser ones so none points at one person
+	clusters := make(map[string]*location.Cell[db.GetStoriesInBoundsRow])
 	for _, story := range stories {
-		// Get 5-char geohash for clustering
 		hash := story.Geohash
-		if len(hash) > 5 {
-			hash = hash[:5]
+		if len(hash) > mapClusterPrecision {
+			hash = hash[:mapClusterPrecision]
+		}
+		cluster, ok := clusters[hash]
+		if !ok {
+			cluster = &location.Cell[db.GetStoriesInBoundsRow]{Geohash: hash}
+			clusters[hash] = cluster
 		}
-		clusters[hash] = append(clusters[hash], story)
+		cluster.Members = append(cluster.Members, story.UserID)
+		cluster.Items = append(cluster.Items, story)
+	}
+	cells := make([]location.Cell[db.GetStoriesInBoundsRow], 0, len(clusters))
+	for _, cluster := range clusters {
+		cells = append(cells, *cluster)
 	}
+	cells = location.AnonymizeCells(cells, server.locationMinUsers(), mapClusterMinPrecision)
 
 	// Convert clusters to response format
 	type ClusterResponse struct {
@@ -88,22 +107,27 @@ func (server *Server) getStoriesMap(ctx *gin.Context) {
 	}
 
 	var response []ClusterResponse
-	for hash, clusterStories := range clusters {
-		lat, lng := geohash.Decode(hash)
+	total := 0
+	for _, cell := range cells {
+		clusterStories := cell.Items
+		total += len(clusterStories)
+		lat, lng := geohash.Decode(cell.Geohash)
 
 		cluster := ClusterResponse{
-			Geohash:   hash,
+			Geohash:   cell.Geohash,
 			Latitude:  lat,
 			Longitude: lng,
 			Count:     len(clusterStories),
 		}
 
 		// If cluster has 3 or fewer stories, include them
-		// Otherwise just show count for privacy
+		// Otherwise just show count for privacy. Only possible when LOCATION_MIN_USERS is 3 or less.
 		if len(clusterStories) <= 3 {
 			cluster.Stories = make([]StoryResponse, len(clusterStories))
 			for i, story := range clusterStories {
-				cluster.Stories[i] = toStoryResponseFromBounds(story)
+				cluster.Stories[i] = toStoryResponseFromBounds(story, authPayload.UserID)
+				cluster.Stories[i].ShareURL = server.storyShareURL(story.ID)
+				server.resolveStoryMedia(ctx, &cluster.Stories[i])
 			}
 		}
 
@@ -112,7 +136,7 @@ func (server *Server) getStoriesMap(ctx *gin.Context) {
 
 	result := gin.H{
 		"clusters": response,
-		"total":    len(stories),
+		"total":    total,
 	}
 
 	// Cache the result
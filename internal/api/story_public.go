@@ -0,0 +1,190 @@
+package api
+
+import (
+	"database/sql"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	publicStoryCacheControl = "public, max-age=60"
+	privateStoryTitle       = "Private story"
+	privateStoryDescription = "Open LocoLive to see stories shared with you."
+)
+
+// storyShareURL is the public link for a story
+func (server *Server) storyShareURL(storyID uuid.UUID) string {
+	return strings.TrimSuffix(server.config.PublicBaseURL, "/") + "/s/" + storyID.String()
+}
+
+// storyDeepLink opens a story in the app
+func (server *Server) storyDeepLink(storyID uuid.UUID) string {
+	return server.config.AppDeepLinkBase + "story/" + storyID.String()
+}
+
+// publicStoryResponse is what link previews see. Private and unknown stories get
+// the same generic body so a link never reveals whether a restricted story exists.
+type publicStoryResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	Status       string     `json:"status"` // "public", "private" or "expired"
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	MediaURL     string     `json:"media_url,omitempty"`
+	MediaType    string     `json:"media_type,omitempty"`
+	ThumbnailURL string     `json:"thumbnail_url,omitempty"`
+	Username     string     `json:"username,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	ShareURL     string     `json:"share_url"`
+	DeepLink     string     `json:"deep_link"`
+}
+
+// getPublicStory serves GET /s/:id without authentication: story metadata for
+// link previews (OG tags when a browser or crawler asks for HTML) plus a deep link
+func (server *Server) getPublicStory(ctx *gin.Context) {
+	storyID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "story not found"})
+		return
+	}
+
+	rsp, err := server.publicStory(ctx, storyID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	status := http.StatusOK
+	if rsp.Status == "expired" {
+		status = http.StatusGone
+	}
+
+	ctx.Header("Cache-Control", publicStoryCacheControl)
+	if ctx.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+		ctx.Status(status)
+		ctx.Header("Content-Type", "text/html; charset=utf-8")
+		if err := publicStoryTemplate.Execute(ctx.Writer, rsp); err != nil {
+			ctx.Error(err)
+		}
+		return
+	}
+	ctx.JSON(status, rsp)
+}
+
+// publicStory applies expiry, audience and anonymity rules to a story for public display
+func (server *Server) publicStory(ctx *gin.Context, storyID uuid.UUID) (publicStoryResponse, error) {
+	rsp := publicStoryResponse{
+		ID:          storyID,
+		Status:      "private",
+		Title:       privateStoryTitle,
+		Description: privateStoryDescription,
+		ShareURL:    server.storyShareURL(storyID),
+		DeepLink:    server.storyDeepLink(storyID),
+	}
+
+	story, err := server.store.GetStoryByID(ctx, storyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return rsp, nil
+		}
+		return rsp, err
+	}
+
+	// Only stories meant for everyone leave the app: public visibility and an
+	// author whose settings let anyone see their stories
+	if story.Visibility != db.StoryAvailabilityPublic {
+		return rsp, nil
+	}
+	author, err := server.store.GetUserByID(ctx, story.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return rsp, nil
+		}
+		return rsp, err
+	}
+	if author.IsShadowBanned {
+		return rsp, nil
+	}
+	settings, err := server.store.GetPrivacySettings(ctx, story.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return rsp, nil // default audience is connections
+		}
+		return rsp, err
+	}
+	if settings.WhoCanSeeStories.String != "everyone" {
+		return rsp, nil
+	}
+	// Held stories wait for moderation before anyone else sees them
+	held, err := server.store.IsStoryHeld(ctx, story.ID)
+	if err != nil {
+		return rsp, err
+	}
+	if held {
+		return rsp, nil
+	}
+
+	if !util.Now().Before(story.ExpiresAt) {
+		rsp.Status = "expired"
+		rsp.Title = "This story has expired"
+		return rsp, nil
+	}
+
+	rsp.Status = "public"
+	rsp.MediaURL = server.media.Resolve(ctx, story.MediaUrl)
+	rsp.MediaType = story.MediaType
+	if story.ThumbnailUrl.Valid {
+		rsp.ThumbnailURL = server.media.Resolve(ctx, story.ThumbnailUrl.String)
+	}
+	rsp.ExpiresAt = &story.ExpiresAt
+	rsp.Title = "A story on LocoLive"
+	if !story.IsAnonymous {
+		rsp.Username = author.Username
+		rsp.Title = "@" + author.Username + " on LocoLive"
+	}
+	rsp.Description = privateStoryDescription
+	if story.Caption.Valid && story.Caption.String != "" {
+		rsp.Description = story.Caption.String
+	}
+	return rsp, nil
+}
+
+// publicStoryTemplate renders Open Graph tags for link previews and forwards
+// browsers to the app
+var publicStoryTemplate = template.Must(template.New("story").Funcs(template.FuncMap{
+	// Deep links use the app's own scheme, which html/template would otherwise
+	// replace; they come from config, never from user input
+	"appURL": func(link string) template.URL { return template.URL(link) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta property="og:type" content="article">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:url" content="{{.ShareURL}}">
+{{- if eq .MediaType "video"}}
+<meta property="og:video" content="{{.MediaURL}}">
+{{- if .ThumbnailURL}}
+<meta property="og:image" content="{{.ThumbnailURL}}">
+{{- end}}
+{{- else if .MediaURL}}
+<meta property="og:image" content="{{.MediaURL}}">
+{{- end}}
+<meta name="twitter:card" content="summary_large_image">
+<meta http-equiv="refresh" content="0; url={{appURL .DeepLink}}">
+</head>
+<body>
+<p>{{.Description}}</p>
+<p><a href="{{appURL .DeepLink}}">Open in LocoLive</a></p>
+</body>
+</html>
+`))
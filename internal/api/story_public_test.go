@@ -0,0 +1,161 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestGetPublicStory(t *testing.T) {
+	storyID := uuid.New()
+	author := db.User{ID: uuid.New(), Username: "author"}
+
+	liveStory := func() db.GetStoryByIDRow {
+		return db.GetStoryByIDRow{
+			ID:         storyID,
+			UserID:     author.ID,
+			MediaUrl:   "https://cdn.example.com/story.jpg",
+			MediaType:  "image",
+			Caption:    sql.NullString{String: "sunset", Valid: true},
+			Visibility: db.StoryAvailabilityPublic,
+			ExpiresAt:  util.Now().Add(time.Hour),
+		}
+	}
+	everyone := db.PrivacySetting{UserID: author.ID, WhoCanSeeStories: sql.NullString{String: "everyone", Valid: true}}
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, code int, rsp publicStoryResponse)
+	}{
+		{
+			name: "Public",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Return(liveStory(), nil)
+				store.EXPECT().GetUserByID(gomock.Any(), author.ID).Return(author, nil)
+				store.EXPECT().GetPrivacySettings(gomock.Any(), author.ID).Return(everyone, nil)
+				store.EXPECT().IsStoryHeld(gomock.Any(), storyID).Return(false, nil)
+			},
+			check: func(t *testing.T, code int, rsp publicStoryResponse) {
+				require.Equal(t, http.StatusOK, code)
+				require.Equal(t, "public", rsp.Status)
+				require.Equal(t, "author", rsp.Username)
+				require.Equal(t, "sunset", rsp.Description)
+				require.NotEmpty(t, rsp.MediaURL)
+			},
+		},
+		{
+			name: "AnonymousHidesAuthor",
+			buildStubs: func(store *mockdb.MockStore) {
+				story := liveStory()
+				story.IsAnonymous = true
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Return(story, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), author.ID).Return(author, nil)
+				store.EXPECT().GetPrivacySettings(gomock.Any(), author.ID).Return(everyone, nil)
+				store.EXPECT().IsStoryHeld(gomock.Any(), storyID).Return(false, nil)
+			},
+			check: func(t *testing.T, code int, rsp publicStoryResponse) {
+				require.Equal(t, "public", rsp.Status)
+				require.Empty(t, rsp.Username)
+				require.NotContains(t, rsp.Title, "author")
+			},
+		},
+		{
+			name: "HeldLooksPrivate",
+			buildStubs: func(store *mockdb.MockStore) {
+				story := liveStory()
+				story.IsAnonymous = true
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Return(story, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), author.ID).Return(author, nil)
+				store.EXPECT().GetPrivacySettings(gomock.Any(), author.ID).Return(everyone, nil)
+				store.EXPECT().IsStoryHeld(gomock.Any(), storyID).Return(true, nil)
+			},
+			check: requirePrivateStory,
+		},
+		{
+			name: "ConnectionsOnlyStory",
+			buildStubs: func(store *mockdb.MockStore) {
+				story := liveStory()
+				story.Visibility = db.StoryAvailabilityConnections
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Return(story, nil)
+			},
+			check: requirePrivateStory,
+		},
+		{
+			name: "AuthorAudienceConnections",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Return(liveStory(), nil)
+				store.EXPECT().GetUserByID(gomock.Any(), author.ID).Return(author, nil)
+				store.EXPECT().GetPrivacySettings(gomock.Any(), author.ID).Return(db.PrivacySetting{}, sql.ErrNoRows)
+			},
+			check: requirePrivateStory,
+		},
+		{
+			name: "NotFoundLooksPrivate",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Return(db.GetStoryByIDRow{}, sql.ErrNoRows)
+			},
+			check: requirePrivateStory,
+		},
+		{
+			name: "Expired",
+			buildStubs: func(store *mockdb.MockStore) {
+				story := liveStory()
+				story.ExpiresAt = util.Now().Add(-time.Minute)
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Return(story, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), author.ID).Return(author, nil)
+				store.EXPECT().GetPrivacySettings(gomock.Any(), author.ID).Return(everyone, nil)
+				store.EXPECT().IsStoryHeld(gomock.Any(), storyID).Return(false, nil)
+			},
+			check: func(t *testing.T, code int, rsp publicStoryResponse) {
+				require.Equal(t, http.StatusGone, code)
+				require.Equal(t, "expired", rsp.Status)
+				require.Empty(t, rsp.MediaURL)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/s/"+storyID.String(), nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+
+			var rsp publicStoryResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			require.Contains(t, rsp.ShareURL, "/s/"+storyID.String())
+			tc.check(t, recorder.Code, rsp)
+		})
+	}
+}
+
+func requirePrivateStory(t *testing.T, code int, rsp publicStoryResponse) {
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "private", rsp.Status)
+	require.Equal(t, privateStoryTitle, rsp.Title)
+	require.Empty(t, rsp.MediaURL)
+	require.Empty(t, rsp.Username)
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/util"
+)
+
+var (
+	ErrStoryAlreadyReplied = errors.New("you already replied to this story; connect to keep chatting")
+	ErrAnonymousStoryReply = errors.New("anonymous stories can't be replied to")
+	ErrMessagesClosed      = errors.New("this user doesn't accept messages")
+)
+
+type storyReplyRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// replyToStory messages a story's author without needing a connection first.
+// Replying is treated as consent to one inbound message, so a viewer who isn't
+// connected gets one reply per story. It also sends the author a connection
+// request, and accepting it opens the chat under the normal rules.
+func (server *Server) replyToStory(ctx *gin.Context) {
+	var uriReq viewStoryRequest
+	if err := ctx.ShouldBindUri(&uriReq); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req storyReplyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if server.rejectBlockedText(ctx, req.Content) {
+		return
+	}
+
+	authPayload := getAuthPayload(ctx)
+	storyID, ok := parseUUIDParam(ctx, uriReq.StoryID, "story_id")
+	if !ok {
+		return
+	}
+
+	story, err := server.store.GetStoryByID(ctx, storyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "story not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !util.Now().Before(story.ExpiresAt) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "story has expired"})
+		return
+	}
+	if rejectSelfTarget(ctx, authPayload.UserID, story.UserID, "cannot reply to your own story") {
+		return
+	}
+	if server.rejectOutsideAudience(ctx, story.Visibility, story.UserID, authPayload.UserID) {
+		return
+	}
+	// A DM names its receiver, which would reveal who posted the story
+	if story.IsAnonymous {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrAnonymousStoryReply))
+		return
+	}
+	held, err := server.store.IsStoryHeld(ctx, storyID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if held {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "story not found"})
+		return
+	}
+
+	for _, pair := range [][2]uuid.UUID{{story.UserID, authPayload.UserID}, {authPayload.UserID, story.UserID}} {
+		blocked, err := server.isUserBlockedCached(ctx, pair[0], pair[1])
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if blocked {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+			return
+		}
+	}
+	whoCanMessage, err := server.whoCanMessageCached(ctx, story.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if whoCanMessage == "nobody" {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrMessagesClosed))
+		return
+	}
+
+	conn, err := server.store.GetConnection(ctx, db.GetConnectionParams{
+		RequesterID: authPayload.UserID,
+		TargetID:    story.UserID,
+	})
+	needsRequest := err == sql.ErrNoRows
+	if err != nil && !needsRequest {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if conn.Status == "blocked" {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+	connected := conn.Status == "accepted"
+
+	if !connected {
+		replied, err := server.store.HasStoryReply(ctx, db.HasStoryReplyParams{
+			StoryID:  uuid.NullUUID{UUID: storyID, Valid: true},
+			SenderID: authPayload.UserID,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if replied {
+			ctx.JSON(http.StatusConflict, errorResponse(ErrStoryAlreadyReplied))
+			return
+		}
+	}
+	// The reply also sends a connection request, so it counts against the same limit
+	if needsRequest {
+		count, err := server.store.CountConnectionRequestsToday(ctx, authPayload.UserID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if count >= dailyConnectionRequestLimit {
+			ctx.JSON(http.StatusTooManyRequests, errorResponse(ErrConnectionRequestLimit))
+			return
+		}
+	}
+
+	// The reply lands in the direct chat with the author, so it follows that
+	// chat's default expiry
+	expiry, err := server.conversationDefaultExpiry(ctx, authPayload.UserID, story.UserID, newMessageExpiryPolicy(server.config))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	expiresAt := util.NullTime{Time: util.Now().Add(expiry), Valid: expiry > 0}
+	msg, err := server.createMessage(ctx, db.CreateMessageParams{
+		SenderID:   authPayload.UserID,
+		ReceiverID: uuid.NullUUID{UUID: story.UserID, Valid: true},
+		Content:    req.Content,
+		ExpiresAt:  expiresAt,
+		StoryID:    uuid.NullUUID{UUID: storyID, Valid: true},
+	}, nil)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if needsRequest {
+		_, err := server.store.CreateConnectionRequest(ctx, db.CreateConnectionRequestParams{
+			RequesterID: authPayload.UserID,
+			TargetID:    story.UserID,
+		})
+		var pqErr *pq.Error
+		if err != nil && !(errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation") {
+			log.Error().Err(err).Msg("failed to create connection request for story reply")
+		} else if err == nil {
+			server.invalidateRecommendationsCache(authPayload.UserID)
+			server.invalidateRecommendationsCache(story.UserID)
+		}
+	}
+
+	_, err = server.store.CreateNotification(ctx, db.CreateNotificationParams{
+		UserID:         story.UserID,
+		Type:           db.NotificationTypeStoryReply,
+		Title:          "New Story Reply",
+		Message:        fmt.Sprintf("%s replied to your story", authPayload.Username),
+		RelatedUserID:  uuid.NullUUID{UUID: authPayload.UserID, Valid: true},
+		RelatedStoryID: uuid.NullUUID{UUID: storyID, Valid: true},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to create story reply notification")
+	}
+
+	server.deliverMessage(msg, nil)
+
+	ctx.JSON(http.StatusCreated, sendMessageResponse{
+		messageResponse:    server.messageResponseWithMedia(ctx, msg, nil),
+		EffectiveExpiresAt: expiresAt.Ptr(),
+	})
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestReplyToStory(t *testing.T) {
+	userID, ownerID, storyID := uuid.New(), uuid.New(), uuid.New()
+	live := db.GetStoryByIDRow{ID: storyID, UserID: ownerID, ExpiresAt: util.Now().Add(time.Hour)}
+	storyRef := uuid.NullUUID{UUID: storyID, Valid: true}
+	settingsKey := db.GetConversationSettingsParams{UserID: userID, OtherUserID: ownerID}
+
+	// allowReply stubs the checks a reply to a live, visible story passes
+	allowReply := func(store *mockdb.MockStore) {
+		store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).Return(live, nil)
+		store.EXPECT().IsStoryHeld(gomock.Any(), storyID).Times(1).Return(false, nil)
+		store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).Times(2).Return(false, nil)
+		store.EXPECT().GetPrivacySettings(gomock.Any(), ownerID).Times(1).Return(db.PrivacySetting{}, sql.ErrNoRows)
+	}
+	// expectDelivery stubs storing and delivering the reply, which expires
+	// after wantExpires, or never when it's 0
+	expectDelivery := func(store *mockdb.MockStore, wantExpires time.Duration) {
+		store.EXPECT().
+			CreateMessage(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.CreateMessageParams) (db.Message, error) {
+				require.Equal(t, userID, arg.SenderID)
+				require.Equal(t, uuid.NullUUID{UUID: ownerID, Valid: true}, arg.ReceiverID)
+				require.Equal(t, storyRef, arg.StoryID)
+				require.Equal(t, wantExpires > 0, arg.ExpiresAt.Valid)
+				if wantExpires > 0 {
+					require.WithinDuration(t, util.Now().Add(wantExpires), arg.ExpiresAt.Time, time.Minute)
+				}
+				return db.Message{ID: uuid.New(), SenderID: arg.SenderID, ReceiverID: arg.ReceiverID, Content: arg.Content, StoryID: arg.StoryID, ExpiresAt: arg.ExpiresAt}, nil
+			})
+		store.EXPECT().
+			CreateNotification(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.CreateNotificationParams) (db.Notification, error) {
+				require.Equal(t, ownerID, arg.UserID)
+				require.Equal(t, db.NotificationTypeStoryReply, arg.Type)
+				require.Equal(t, storyRef, arg.RelatedStoryID)
+				return db.Notification{}, nil
+			})
+		store.EXPECT().IsConversationMuted(gomock.Any(), gomock.Any()).AnyTimes().Return(false, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "NotConnected",
+			buildStubs: func(store *mockdb.MockStore) {
+				allowReply(store)
+				store.EXPECT().GetConnection(gomock.Any(), gomock.Any()).Times(1).Return(db.Connection{}, sql.ErrNoRows)
+				store.EXPECT().
+					HasStoryReply(gomock.Any(), db.HasStoryReplyParams{StoryID: storyRef, SenderID: userID}).
+					Times(1).
+					Return(false, nil)
+				store.EXPECT().CountConnectionRequestsToday(gomock.Any(), userID).Times(1).Return(int64(0), nil)
+				store.EXPECT().GetConversationSettings(gomock.Any(), settingsKey).Times(1).Return(db.ConversationSetting{}, sql.ErrNoRows)
+				expectDelivery(store, 24*time.Hour)
+				// The author can accept or ignore the reply as a connection request
+				store.EXPECT().
+					CreateConnectionRequest(gomock.Any(), db.CreateConnectionRequestParams{RequesterID: userID, TargetID: ownerID}).
+					Times(1).
+					Return(db.Connection{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+				require.JSONEq(t, fmt.Sprintf("%q", storyID), mustJSONField(t, recorder.Body.Bytes(), "story_id"))
+			},
+		},
+		{
+			// Connected users may reply as often as they like, with no new request
+			name: "Connected",
+			buildStubs: func(store *mockdb.MockStore) {
+				allowReply(store)
+				store.EXPECT().GetConnection(gomock.Any(), gomock.Any()).Times(1).Return(db.Connection{Status: "accepted"}, nil)
+				store.EXPECT().HasStoryReply(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateConnectionRequest(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetConversationSettings(gomock.Any(), settingsKey).Times(1).Return(db.ConversationSetting{}, sql.ErrNoRows)
+				expectDelivery(store, 24*time.Hour)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+			},
+		},
+		{
+			// The reply lands in the direct chat, so it takes that chat's default
+			name: "ChatDefault",
+			buildStubs: func(store *mockdb.MockStore) {
+				allowReply(store)
+				store.EXPECT().GetConnection(gomock.Any(), gomock.Any()).Times(1).Return(db.Connection{Status: "accepted"}, nil)
+				store.EXPECT().
+					GetConversationSettings(gomock.Any(), settingsKey).
+					Times(1).
+					Return(db.ConversationSetting{DefaultExpirySeconds: 3600}, nil)
+				expectDelivery(store, time.Hour)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+			},
+		},
+		{
+			name: "ChatNeverExpires",
+			buildStubs: func(store *mockdb.MockStore) {
+				allowReply(store)
+				store.EXPECT().GetConnection(gomock.Any(), gomock.Any()).Times(1).Return(db.Connection{Status: "accepted"}, nil)
+				store.EXPECT().GetConversationSettings(gomock.Any(), settingsKey).Times(1).Return(db.ConversationSetting{}, nil)
+				expectDelivery(store, 0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+				require.JSONEq(t, "null", mustJSONField(t, recorder.Body.Bytes(), "effective_expires_at"))
+			},
+		},
+		{
+			name: "AlreadyReplied",
+			buildStubs: func(store *mockdb.MockStore) {
+				allowReply(store)
+				store.EXPECT().GetConnection(gomock.Any(), gomock.Any()).Times(1).Return(db.Connection{Status: "pending"}, nil)
+				store.EXPECT().HasStoryReply(gomock.Any(), gomock.Any()).Times(1).Return(true, nil)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "AuthorAcceptsNoMessages",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).Return(live, nil)
+				store.EXPECT().IsStoryHeld(gomock.Any(), storyID).Times(1).Return(false, nil)
+				store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).Times(2).Return(false, nil)
+				store.EXPECT().
+					GetPrivacySettings(gomock.Any(), ownerID).
+					Times(1).
+					Return(db.PrivacySetting{WhoCanMessage: sql.NullString{String: "nobody", Valid: true}}, nil)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), ErrMessagesClosed.Error())
+			},
+		},
+		{
+			name: "BlockedByAuthor",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).Return(live, nil)
+				store.EXPECT().IsStoryHeld(gomock.Any(), storyID).Times(1).Return(false, nil)
+				store.EXPECT().
+					IsUserBlocked(gomock.Any(), db.IsUserBlockedParams{BlockerID: ownerID, BlockedID: userID}).
+					Times(1).
+					Return(true, nil)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "AnonymousStory",
+			buildStubs: func(store *mockdb.MockStore) {
+				anonymous := live
+				anonymous.IsAnonymous = true
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).Return(anonymous, nil)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), ownerID.String())
+			},
+		},
+		{
+			name: "OwnStory",
+			buildStubs: func(store *mockdb.MockStore) {
+				own := live
+				own.UserID = userID
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).Return(own, nil)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Expired",
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := live
+				expired.ExpiresAt = util.Now().Add(-time.Minute)
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).Return(expired, nil)
+				store.EXPECT().CreateMessage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := postJSON(t, server, fmt.Sprintf("/stories/%s/reply", storyID), gin.H{"content": "love this spot"}, &userID)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -27,22 +27,46 @@ type StoryResponse struct {
 	AvatarURL    *string   `json:"avatar_url"`
 	Lat          float64   `json:"lat"`
 	Lng          float64   `json:"lng"`
+	// ShareURL is the public link for sharing the story outside the app
+	ShareURL string `json:"share_url"`
+	// PendingReview is set on a just-posted story that is held for moderation
+	PendingReview bool `json:"pending_review,omitempty"`
+	// Seen reports whether the viewer has opened the story; only set in story rings
+	Seen *bool `json:"seen,omitempty"`
+	// ViewCount is how many distinct users viewed the story; only shown to its author
+	ViewCount *int64 `json:"view_count,omitempty"`
+	// ReactionCount is how many users reacted to the story
+	ReactionCount int64 `json:"reaction_count"`
+}
+
+// hideAnonymousAuthor blanks who posted an anonymous story for everyone but
+// its author, who still needs to recognise their own story. The premium flag
+// goes too, since it is copied from the author's tier.
+func (rsp *StoryResponse) hideAnonymousAuthor(viewerID uuid.UUID) {
+	if !rsp.IsAnonymous || rsp.UserID == viewerID {
+		return
+	}
+	rsp.UserID = uuid.Nil
+	rsp.Username = ""
+	rsp.AvatarURL = nil
+	rsp.IsPremium = nil
 }
 
 // Convert db.GetStoriesWithinRadiusRow to StoryResponse
-func toStoryResponse(row db.GetStoriesWithinRadiusRow) StoryResponse {
+func toStoryResponse(row db.GetStoriesWithinRadiusRow, viewerID uuid.UUID) StoryResponse {
 	resp := StoryResponse{
-		ID:           row.ID,
-		UserID:       row.UserID,
-		MediaURL:     row.MediaUrl,
-		MediaType:    row.MediaType,
-		Geohash:      row.Geohash,
-		Visibility:   string(row.Visibility),
-		ExpiresAt:    row.ExpiresAt,
-		CreatedAt:    row.CreatedAt,
-		IsAnonymous:  row.IsAnonymous,
-		ShowLocation: row.ShowLocation,
-		Username:     row.Username,
+		ID:            row.ID,
+		UserID:        row.UserID,
+		MediaURL:      row.MediaUrl,
+		MediaType:     row.MediaType,
+		Geohash:       row.Geohash,
+		Visibility:    string(row.Visibility),
+		ExpiresAt:     row.ExpiresAt,
+		CreatedAt:     row.CreatedAt,
+		IsAnonymous:   row.IsAnonymous,
+		ShowLocation:  row.ShowLocation,
+		ReactionCount: row.ReactionCount,
+		Username:      row.Username,
 	}
 
 	if val, ok := row.Lat.(float64); ok {
@@ -68,23 +92,69 @@ func toStoryResponse(row db.GetStoriesWithinRadiusRow) StoryResponse {
 		resp.IsPremium = &row.IsPremium.Bool
 	}
 
+	resp.hideAnonymousAuthor(viewerID)
 	return resp
 }
 
 // Convert db.GetConnectionStoriesRow to StoryResponse
-func toStoryResponseFromConnection(row db.GetConnectionStoriesRow) StoryResponse {
+func toStoryResponseFromConnection(row db.GetConnectionStoriesRow, viewerID uuid.UUID) StoryResponse {
 	resp := StoryResponse{
-		ID:           row.ID,
-		UserID:       row.UserID,
-		MediaURL:     row.MediaUrl,
-		MediaType:    row.MediaType,
-		Geohash:      row.Geohash,
-		Visibility:   string(row.Visibility),
-		ExpiresAt:    row.ExpiresAt,
-		CreatedAt:    row.CreatedAt,
-		IsAnonymous:  row.IsAnonymous,
-		ShowLocation: row.ShowLocation,
-		Username:     row.Username,
+		ID:            row.ID,
+		UserID:        row.UserID,
+		MediaURL:      row.MediaUrl,
+		MediaType:     row.MediaType,
+		Geohash:       row.Geohash,
+		Visibility:    string(row.Visibility),
+		ExpiresAt:     row.ExpiresAt,
+		CreatedAt:     row.CreatedAt,
+		IsAnonymous:   row.IsAnonymous,
+		ShowLocation:  row.ShowLocation,
+		ReactionCount: row.ReactionCount,
+		Username:      row.Username,
+	}
+
+	if val, ok := row.Lat.(float64); ok {
+		resp.Lat = val
+	}
+	if val, ok := row.Lng.(float64); ok {
+		resp.Lng = val
+	}
+
+	if row.ThumbnailUrl.Valid {
+		resp.ThumbnailURL = &row.ThumbnailUrl.String
+	}
+
+	if row.Caption.Valid {
+		resp.Caption = &row.Caption.String
+	}
+
+	if row.AvatarUrl.Valid {
+		resp.AvatarURL = &row.AvatarUrl.String
+	}
+
+	if row.IsPremium.Valid {
+		resp.IsPremium = &row.IsPremium.Bool
+	}
+
+	resp.hideAnonymousAuthor(viewerID)
+	return resp
+}
+
+func toStoryResponseFromRing(row db.ListConnectionStoryRingsRow, viewerID uuid.UUID) StoryResponse {
+	resp := StoryResponse{
+		ID:            row.ID,
+		UserID:        row.UserID,
+		MediaURL:      row.MediaUrl,
+		MediaType:     row.MediaType,
+		Geohash:       row.Geohash,
+		Visibility:    string(row.Visibility),
+		ExpiresAt:     row.ExpiresAt,
+		CreatedAt:     row.CreatedAt,
+		IsAnonymous:   row.IsAnonymous,
+		ShowLocation:  row.ShowLocation,
+		ReactionCount: row.ReactionCount,
+		Username:      row.Username,
+		Seen:          &row.Seen,
 	}
 
 	if val, ok := row.Lat.(float64); ok {
@@ -110,23 +180,25 @@ func toStoryResponseFromConnection(row db.GetConnectionStoriesRow) StoryResponse
 		resp.IsPremium = &row.IsPremium.Bool
 	}
 
+	resp.hideAnonymousAuthor(viewerID)
 	return resp
 }
 
 // Convert db.GetStoriesInBoundsRow to StoryResponse
-func toStoryResponseFromBounds(row db.GetStoriesInBoundsRow) StoryResponse {
+func toStoryResponseFromBounds(row db.GetStoriesInBoundsRow, viewerID uuid.UUID) StoryResponse {
 	resp := StoryResponse{
-		ID:           row.ID,
-		UserID:       row.UserID,
-		MediaURL:     row.MediaUrl,
-		MediaType:    row.MediaType,
-		Geohash:      row.Geohash,
-		Visibility:   string(row.Visibility),
-		ExpiresAt:    row.ExpiresAt,
-		CreatedAt:    row.CreatedAt,
-		IsAnonymous:  row.IsAnonymous,
-		ShowLocation: row.ShowLocation,
-		Username:     row.Username,
+		ID:            row.ID,
+		UserID:        row.UserID,
+		MediaURL:      row.MediaUrl,
+		MediaType:     row.MediaType,
+		Geohash:       row.Geohash,
+		Visibility:    string(row.Visibility),
+		ExpiresAt:     row.ExpiresAt,
+		CreatedAt:     row.CreatedAt,
+		IsAnonymous:   row.IsAnonymous,
+		ShowLocation:  row.ShowLocation,
+		ReactionCount: row.ReactionCount,
+		Username:      row.Username,
 	}
 
 	if val, ok := row.Lat.(float64); ok {
@@ -148,6 +220,7 @@ func toStoryResponseFromBounds(row db.GetStoriesInBoundsRow) StoryResponse {
 		resp.AvatarURL = &row.AvatarUrl.String
 	}
 
+	resp.hideAnonymousAuthor(viewerID)
 	return resp
 }
 
@@ -190,19 +263,20 @@ func toStoryResponseFromCreate(row db.CreateStoryRow) StoryResponse {
 }
 
 // Convert db.GetStoryByIDRow to StoryResponse
-func toStoryResponseFromGet(row db.GetStoryByIDRow) StoryResponse {
+func toStoryResponseFromGet(row db.GetStoryByIDRow, viewerID uuid.UUID) StoryResponse {
 	resp := StoryResponse{
-		ID:           row.ID,
-		UserID:       row.UserID,
-		MediaURL:     row.MediaUrl,
-		MediaType:    row.MediaType,
-		Geohash:      row.Geohash,
-		Visibility:   string(row.Visibility),
-		ExpiresAt:    row.ExpiresAt,
-		CreatedAt:    row.CreatedAt,
-		IsAnonymous:  row.IsAnonymous,
-		ShowLocation: row.ShowLocation,
-		Username:     "",
+		ID:            row.ID,
+		UserID:        row.UserID,
+		MediaURL:      row.MediaUrl,
+		MediaType:     row.MediaType,
+		Geohash:       row.Geohash,
+		Visibility:    string(row.Visibility),
+		ExpiresAt:     row.ExpiresAt,
+		CreatedAt:     row.CreatedAt,
+		IsAnonymous:   row.IsAnonymous,
+		ShowLocation:  row.ShowLocation,
+		ReactionCount: row.ReactionCount,
+		Username:      "",
 	}
 
 	if val, ok := row.Lat.(float64); ok {
@@ -224,23 +298,25 @@ func toStoryResponseFromGet(row db.GetStoryByIDRow) StoryResponse {
 		resp.IsPremium = &row.IsPremium.Bool
 	}
 
+	resp.hideAnonymousAuthor(viewerID)
 	return resp
 }
 
 // Convert db.UpdateStoryRow to StoryResponse
 func toStoryResponseFromUpdate(row db.UpdateStoryRow) StoryResponse {
 	resp := StoryResponse{
-		ID:           row.ID,
-		UserID:       row.UserID,
-		MediaURL:     row.MediaUrl,
-		MediaType:    row.MediaType,
-		Geohash:      row.Geohash,
-		Visibility:   string(row.Visibility),
-		ExpiresAt:    row.ExpiresAt,
-		CreatedAt:    row.CreatedAt,
-		IsAnonymous:  row.IsAnonymous,
-		ShowLocation: row.ShowLocation,
-		Username:     "",
+		ID:            row.ID,
+		UserID:        row.UserID,
+		MediaURL:      row.MediaUrl,
+		MediaType:     row.MediaType,
+		Geohash:       row.Geohash,
+		Visibility:    string(row.Visibility),
+		ExpiresAt:     row.ExpiresAt,
+		CreatedAt:     row.CreatedAt,
+		IsAnonymous:   row.IsAnonymous,
+		ShowLocation:  row.ShowLocation,
+		ReactionCount: row.ReactionCount,
+		Username:      "",
 	}
 
 	if val, ok := row.Lat.(float64); ok {
@@ -264,3 +340,53 @@ func toStoryResponseFromUpdate(row db.UpdateStoryRow) StoryResponse {
 
 	return resp
 }
+
+// MyStoryResponse is one entry on the owner's story dashboard
+type MyStoryResponse struct {
+	StoryID       uuid.UUID  `json:"story_id"`
+	ArchiveID     *uuid.UUID `json:"archive_id"`
+	MediaURL      string     `json:"media_url"`
+	MediaType     string     `json:"media_type"`
+	ThumbnailURL  *string    `json:"thumbnail_url"`
+	Caption       *string    `json:"caption"`
+	IsAnonymous   bool       `json:"is_anonymous"`
+	ShowLocation  bool       `json:"show_location"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	IsHighlight   bool       `json:"is_highlight"`
+	ViewCount     int64      `json:"view_count"`
+	ReactionCount int64      `json:"reaction_count"`
+}
+
+// Convert db.ListMyStoriesRow to MyStoryResponse
+func toMyStoryResponse(row db.ListMyStoriesRow) MyStoryResponse {
+	resp := MyStoryResponse{
+		StoryID:       row.StoryID,
+		MediaURL:      row.MediaUrl,
+		MediaType:     row.MediaType,
+		IsAnonymous:   row.IsAnonymous,
+		ShowLocation:  row.ShowLocation,
+		CreatedAt:     row.CreatedAt,
+		IsHighlight:   row.IsHighlight,
+		ViewCount:     row.ViewCount,
+		ReactionCount: row.ReactionCount,
+	}
+
+	if row.ArchiveID.Valid {
+		resp.ArchiveID = &row.ArchiveID.UUID
+	}
+
+	if row.ThumbnailUrl.Valid {
+		resp.ThumbnailURL = &row.ThumbnailUrl.String
+	}
+
+	if row.Caption.Valid {
+		resp.Caption = &row.Caption.String
+	}
+
+	if row.ExpiresAt.Valid {
+		resp.ExpiresAt = &row.ExpiresAt.Time
+	}
+
+	return resp
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
+)
+
+// storyRingResponse is one author in the connections stories tray
+type storyRingResponse struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	AvatarURL *string   `json:"avatar_url"`
+	// HasUnseen is true while any of the author's live stories is unopened
+	HasUnseen bool `json:"has_unseen"`
+	// LatestAt is when the author's newest live story was posted
+	LatestAt time.Time       `json:"latest_at"`
+	Stories  []StoryResponse `json:"stories"`
+}
+
+// storyRingsCacheKey caches a viewer's tray; viewing a story deletes it
+func storyRingsCacheKey(userID uuid.UUID) string {
+	return util.RedisKey("stories:rings:" + userID.String())
+}
+
+// getConnectionStoryRings serves GET /stories/connections/rings: connection
+// stories grouped per author, unseen authors first, for rendering story rings
+func (server *Server) getConnectionStoryRings(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	cacheKey := storyRingsCacheKey(authPayload.UserID)
+
+	cachedData, err := server.redis.Get(ctx, cacheKey).Result()
+	if err == nil && cachedData != "" {
+		ctx.Header("X-Cache", "HIT")
+		ctx.Data(http.StatusOK, "application/json", []byte(cachedData))
+		return
+	}
+
+	rows, err := server.store.ListConnectionStoryRings(ctx, authPayload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rings := server.storyRings(ctx, rows, authPayload.UserID)
+
+	responseJSON, _ := json.Marshal(rings)
+	server.redis.Set(ctx, cacheKey, responseJSON, feedCacheTTL)
+
+	ctx.Header("X-Cache", "MISS")
+	ctx.JSON(http.StatusOK, rings)
+}
+
+// storyRings groups rows into rings. The query already orders them ring by ring,
+// so an author's stories are always consecutive.
+func (server *Server) storyRings(ctx context.Context, rows []db.ListConnectionStoryRingsRow, viewerID uuid.UUID) []storyRingResponse {
+	rings := make([]storyRingResponse, 0)
+	for _, row := range rows {
+		if len(rings) == 0 || rings[len(rings)-1].UserID != row.UserID {
+			ring := storyRingResponse{
+				UserID:    row.UserID,
+				Username:  row.Username,
+				HasUnseen: row.AuthorHasUnseen,
+				LatestAt:  row.AuthorLatestAt,
+			}
+			if row.AvatarUrl.Valid {
+				ring.AvatarURL = &row.AvatarUrl.String
+			}
+			rings = append(rings, ring)
+		}
+
+		rsp := toStoryResponseFromRing(row, viewerID)
+		rsp.ShareURL = server.storyShareURL(row.ID)
+		server.resolveStoryMedia(ctx, &rsp)
+		ring := &rings[len(rings)-1]
+		ring.Stories = append(ring.Stories, rsp)
+	}
+	return rings
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"privacy-social-backend/internal/repository/db"
+)
+
+func TestStoryRingsGroupsConsecutiveAuthors(t *testing.T) {
+	server := newTestServer(t, nil)
+	alice, bob := uuid.New(), uuid.New()
+	aliceLatest := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	bobLatest := aliceLatest.Add(time.Hour)
+
+	row := func(author uuid.UUID, username string, seen, hasUnseen bool, latest time.Time) db.ListConnectionStoryRingsRow {
+		return db.ListConnectionStoryRingsRow{
+			ID:              uuid.New(),
+			UserID:          author,
+			Username:        username,
+			MediaUrl:        "https://example.com/" + username + ".jpg",
+			AvatarUrl:       sql.NullString{String: "https://example.com/" + username + ".png", Valid: true},
+			Seen:            seen,
+			AuthorHasUnseen: hasUnseen,
+			AuthorLatestAt:  latest,
+		}
+	}
+
+	// As ordered by the query: alice has an unseen story so she comes before bob
+	rows := []db.ListConnectionStoryRingsRow{
+		row(alice, "alice", true, true, aliceLatest),
+		row(alice, "alice", false, true, aliceLatest),
+		row(bob, "bob", true, false, bobLatest),
+	}
+
+	rings := server.storyRings(context.Background(), rows, uuid.New())
+	require.Len(t, rings, 2)
+
+	require.Equal(t, alice, rings[0].UserID)
+	require.True(t, rings[0].HasUnseen)
+	require.Equal(t, aliceLatest, rings[0].LatestAt)
+	require.Equal(t, "https://example.com/alice.png", *rings[0].AvatarURL)
+	require.Len(t, rings[0].Stories, 2)
+	require.Equal(t, rows[0].ID, rings[0].Stories[0].ID)
+	require.True(t, *rings[0].Stories[0].Seen)
+	require.False(t, *rings[0].Stories[1].Seen)
+
+	require.Equal(t, bob, rings[1].UserID)
+	require.False(t, rings[1].HasUnseen)
+	require.Len(t, rings[1].Stories, 1)
+}
+
+func TestStoryRingsEmpty(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	rings := server.storyRings(context.Background(), nil, uuid.New())
+	require.NotNil(t, rings)
+	require.Empty(t, rings)
+}
@@ -1,15 +1,19 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"regexp"
-	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 
 	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/analytics"
+	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
 )
 
 type shareStoryRequest struct {
@@ -37,6 +41,9 @@ func (server *Server) shareStory(ctx *gin.Context) {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "story not found"})
 		return
 	}
+	if server.rejectOutsideAudience(ctx, story.Visibility, story.UserID, authPayload.UserID) {
+		return
+	}
 
 	// Create message with story link in content
 	// Use relative path for internal deep linking in frontend
@@ -45,7 +52,7 @@ func (server *Server) shareStory(ctx *gin.Context) {
 
 	for _, userIDStr := range req.UserIDs {
 		targetUserID, err := uuid.Parse(userIDStr)
-		if err != nil {
+		if err != nil || targetUserID == authPayload.UserID {
 			continue
 		}
 
@@ -53,6 +60,10 @@ func (server *Server) shareStory(ctx *gin.Context) {
 		if err := server.checkConnection(ctx, authPayload.UserID, targetUserID); err != nil {
 			continue // Skip non-connected users
 		}
+		// The link would be dead for anyone outside the story's audience
+		if included, err := server.storyAudienceIncludes(ctx, story.Visibility, story.UserID, targetUserID); err != nil || !included {
+			continue
+		}
 
 		// Create message with story link in content
 		_, err = server.store.CreateMessage(ctx, db.CreateMessageParams{
@@ -64,6 +75,8 @@ func (server *Server) shareStory(ctx *gin.Context) {
 		if err != nil {
 			continue
 		}
+		server.events.Emit(ctx, analytics.EventMessageSent, authPayload.UserID)
+		server.metrics.messagesSent.Inc()
 
 		successCount++
 	}
@@ -74,67 +87,56 @@ func (server *Server) shareStory(ctx *gin.Context) {
 	})
 }
 
-// parseMentions extracts @username mentions from text
-func parseMentions(text string) []string {
-	re := regexp.MustCompile(`@(\w+)`)
-	matches := re.FindAllStringSubmatch(text, -1)
-
-	mentions := make([]string, 0)
-	seen := make(map[string]bool)
-
-	for _, match := range matches {
-		if len(match) > 1 {
-			username := strings.ToLower(match[1])
-			if !seen[username] {
-				mentions = append(mentions, username)
-				seen[username] = true
-			}
-		}
-	}
-
-	return mentions
-}
+const (
+	// storyMentionType is the WebSocket event type for a story mention
+	storyMentionType = string(db.NotificationTypeStoryMention)
+	// storyMentionTimeout bounds the mention work that runs after a story is posted
+	storyMentionTimeout = 10 * time.Second
+)
 
-// createStoryMentions creates mention records for a story
-func (server *Server) createStoryMentions(ctx *gin.Context, storyID uuid.UUID, caption string) error {
-	if caption == "" {
+// createStoryMentions records the @username mentions in a story's caption and
+// tells each newly mentioned user. Unknown usernames are ignored.
+func (server *Server) createStoryMentions(ctx context.Context, storyID uuid.UUID, author *token.Payload, caption string) error {
+	usernames := util.ParseMentions(caption)
+	if len(usernames) == 0 {
 		return nil
 	}
 
-	mentions := parseMentions(caption)
-	if len(mentions) == 0 {
-		return nil
+	users, err := server.store.GetUsersByUsernames(ctx, usernames)
+	if err != nil || len(users) == 0 {
+		return err
+	}
+	userIDs := make([]uuid.UUID, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
 	}
 
-	for _, username := range mentions {
-		// Get user by username
-		user, err := server.store.GetUserByUsername(ctx, username)
-		if err != nil {
-			continue // Skip if user not found
-		}
-
-		// Create mention
-		_, err = server.store.CreateStoryMention(ctx, db.CreateStoryMentionParams{
-			StoryID:         storyID,
-			MentionedUserID: user.ID,
-		})
-		if err != nil {
-			continue // Skip on error
-		}
+	mentioned, err := server.store.CreateStoryMentions(ctx, db.CreateStoryMentionsParams{
+		MentionedUserIds: userIDs,
+		StoryID:          storyID,
+	})
+	if err != nil {
+		return err
+	}
 
-		// Send notification to mentioned user
+	for _, userID := range mentioned {
 		_, err = server.store.CreateNotification(ctx, db.CreateNotificationParams{
-			UserID:         user.ID,
-			Type:           "story_mention",
+			UserID:         userID,
+			Type:           db.NotificationTypeStoryMention,
 			Title:          "You were mentioned!",
-			Message:        "You were mentioned in a story",
+			Message:        fmt.Sprintf("%s mentioned you in a story", author.Username),
+			RelatedUserID:  uuid.NullUUID{UUID: author.UserID, Valid: true},
 			RelatedStoryID: uuid.NullUUID{UUID: storyID, Valid: true},
 		})
 		if err != nil {
-			// Log error but don't fail the whole operation
-			continue
+			log.Error().Err(err).Str("story_id", storyID.String()).Msg("failed to create story mention notification")
 		}
-	}
 
+		server.sendWSNotification(userID, storyMentionType, gin.H{
+			"story_id":     storyID,
+			"mentioned_by": author.UserID,
+			"username":     author.Username,
+		})
+	}
 	return nil
 }
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/realtime"
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/token"
+)
+
+func TestCreateStoryMentions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	server.hub = realtime.NewHub(rdb)
+
+	author := &token.Payload{UserID: uuid.New(), Username: "author"}
+	storyID := uuid.New()
+	alice, bob := uuid.New(), uuid.New()
+
+	// nobody doesn't exist; bob was already mentioned, so only alice is told
+	store.EXPECT().
+		GetUsersByUsernames(gomock.Any(), []string{"alice", "bob", "nobody"}).
+		Times(1).
+		Return([]db.GetUsersByUsernamesRow{{ID: alice, Username: "Alice"}, {ID: bob, Username: "bob"}}, nil)
+	store.EXPECT().
+		CreateStoryMentions(gomock.Any(), db.CreateStoryMentionsParams{
+			MentionedUserIds: []uuid.UUID{alice, bob},
+			StoryID:          storyID,
+		}).
+		Times(1).
+		Return([]uuid.UUID{alice}, nil)
+	store.EXPECT().
+		CreateNotification(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateNotificationParams) (db.Notification, error) {
+			require.Equal(t, alice, arg.UserID)
+			require.Equal(t, db.NotificationTypeStoryMention, arg.Type)
+			require.Equal(t, storyID, arg.RelatedStoryID.UUID)
+			return db.Notification{}, nil
+		})
+
+	err := server.createStoryMentions(context.Background(), storyID, author, "with @Alice @bob and @nobody")
+	require.NoError(t, err)
+
+	keys := mr.Keys()
+	require.Len(t, keys, 1)
+	entries, err := rdb.XRange(context.Background(), keys[0], "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, alice.String(), entries[0].Values["target_user_id"])
+}
+
+func TestCreateStoryMentionsWithoutMentions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUsersByUsernames(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	author := &token.Payload{UserID: uuid.New(), Username: "author"}
+	require.NoError(t, server.createStoryMentions(context.Background(), uuid.New(), author, "no one here"))
+}
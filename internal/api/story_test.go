@@ -0,0 +1,197 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestGetStoryViewCount(t *testing.T) {
+	author := db.User{ID: uuid.New(), Username: "author"}
+	story := db.GetStoryByIDRow{
+		ID:         uuid.New(),
+		UserID:     author.ID,
+		MediaUrl:   "https://cdn.example.com/story.jpg",
+		MediaType:  "image",
+		Visibility: db.StoryAvailabilityPublic,
+		ExpiresAt:  util.Now().Add(time.Hour),
+	}
+
+	testCases := []struct {
+		name       string
+		viewer     uuid.UUID
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, rsp StoryResponse)
+	}{
+		{
+			name:   "Author",
+			viewer: author.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CountStoryViews(gomock.Any(), story.ID).Times(1).Return(int64(7), nil)
+			},
+			check: func(t *testing.T, rsp StoryResponse) {
+				require.NotNil(t, rsp.ViewCount)
+				require.Equal(t, int64(7), *rsp.ViewCount)
+			},
+		},
+		{
+			name:   "OtherViewer",
+			viewer: uuid.New(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CountStoryViews(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, rsp StoryResponse) {
+				require.Nil(t, rsp.ViewCount)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetStoryByID(gomock.Any(), story.ID).Times(1).Return(story, nil)
+			store.EXPECT().IsStoryHeld(gomock.Any(), story.ID).Times(1).Return(false, nil)
+			store.EXPECT().GetUserByID(gomock.Any(), author.ID).Times(1).Return(author, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/stories/%s", story.ID), nil)
+			require.NoError(t, err)
+
+			accessToken, _, err := server.tokenMaker.CreateToken("user", tc.viewer, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+			var rsp StoryResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			tc.check(t, rsp)
+		})
+	}
+}
+
+func TestGetStoryHidesAnonymousAuthor(t *testing.T) {
+	author := db.User{
+		ID:        uuid.New(),
+		Username:  "author",
+		AvatarUrl: sql.NullString{String: "https://cdn.example.com/me.jpg", Valid: true},
+	}
+	story := db.GetStoryByIDRow{
+		ID:          uuid.New(),
+		UserID:      author.ID,
+		MediaUrl:    "https://cdn.example.com/story.jpg",
+		MediaType:   "image",
+		Visibility:  db.StoryAvailabilityPublic,
+		ExpiresAt:   util.Now().Add(time.Hour),
+		IsAnonymous: true,
+	}
+
+	testCases := []struct {
+		name       string
+		viewer     uuid.UUID
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, rsp StoryResponse, body string)
+	}{
+		{
+			name:   "OtherViewer",
+			viewer: uuid.New(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, rsp StoryResponse, body string) {
+				require.Equal(t, uuid.Nil, rsp.UserID)
+				require.Empty(t, rsp.Username)
+				require.Nil(t, rsp.AvatarURL)
+				require.NotContains(t, body, author.ID.String())
+			},
+		},
+		{
+			name:   "Author",
+			viewer: author.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CountStoryViews(gomock.Any(), story.ID).Times(1).Return(int64(0), nil)
+				store.EXPECT().GetUserByID(gomock.Any(), author.ID).Times(1).Return(author, nil)
+			},
+			check: func(t *testing.T, rsp StoryResponse, body string) {
+				require.Equal(t, author.ID, rsp.UserID)
+				require.Equal(t, author.Username, rsp.Username)
+				require.NotNil(t, rsp.AvatarURL)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetStoryByID(gomock.Any(), story.ID).Times(1).Return(story, nil)
+			store.EXPECT().IsStoryHeld(gomock.Any(), story.ID).Times(1).Return(false, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := getWithAuth(t, server, fmt.Sprintf("/stories/%s", story.ID), tc.viewer)
+			require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+			var rsp StoryResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			tc.check(t, rsp, recorder.Body.String())
+		})
+	}
+}
+
+func TestStoryResponseHidesAnonymousAuthor(t *testing.T) {
+	authorID, viewerID := uuid.New(), uuid.New()
+	avatar := sql.NullString{String: "https://cdn.example.com/me.jpg", Valid: true}
+	premium := sql.NullBool{Bool: true, Valid: true}
+
+	builders := map[string]func(anonymous bool, viewer uuid.UUID) StoryResponse{
+		"Feed": func(anonymous bool, viewer uuid.UUID) StoryResponse {
+			return toStoryResponse(db.GetStoriesWithinRadiusRow{UserID: authorID, Username: "author", AvatarUrl: avatar, IsPremium: premium, IsAnonymous: anonymous}, viewer)
+		},
+		"Connections": func(anonymous bool, viewer uuid.UUID) StoryResponse {
+			return toStoryResponseFromConnection(db.GetConnectionStoriesRow{UserID: authorID, Username: "author", AvatarUrl: avatar, IsPremium: premium, IsAnonymous: anonymous}, viewer)
+		},
+		"Rings": func(anonymous bool, viewer uuid.UUID) StoryResponse {
+			return toStoryResponseFromRing(db.ListConnectionStoryRingsRow{UserID: authorID, Username: "author", AvatarUrl: avatar, IsPremium: premium, IsAnonymous: anonymous}, viewer)
+		},
+		"Bounds": func(anonymous bool, viewer uuid.UUID) StoryResponse {
+			return toStoryResponseFromBounds(db.GetStoriesInBoundsRow{UserID: authorID, Username: "author", AvatarUrl: avatar, IsAnonymous: anonymous}, viewer)
+		},
+	}
+
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			rsp := build(true, viewerID)
+			require.Equal(t, uuid.Nil, rsp.UserID)
+			require.Empty(t, rsp.Username)
+			require.Nil(t, rsp.AvatarURL)
+			require.Nil(t, rsp.IsPremium)
+
+			// The author still sees their own story as theirs
+			rsp = build(true, authorID)
+			require.Equal(t, authorID, rsp.UserID)
+			require.Equal(t, "author", rsp.Username)
+
+			rsp = build(false, viewerID)
+			require.Equal(t, authorID, rsp.UserID)
+			require.Equal(t, "author", rsp.Username)
+			require.NotNil(t, rsp.AvatarURL)
+		})
+	}
+}
@@ -1,20 +1,114 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/storage"
 	"privacy-social-backend/internal/util"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 )
 
 type uploadResponse struct {
 	URL string `json:"url"`
+	// PreviewURL is a short-lived URL for showing privately stored media right
+	// away; URL is the reference to send back as media_url
+	PreviewURL string `json:"preview_url,omitempty"`
+	// ThumbURL is a 400px-wide thumbnail, left out when none could be made
+	ThumbURL        string `json:"thumb_url,omitempty"`
+	ThumbPreviewURL string `json:"thumb_preview_url,omitempty"`
+}
+
+// newUploadResponse adds preview URLs when the upload is stored privately
+func (server *Server) newUploadResponse(ctx *gin.Context, ref, thumbRef string) uploadResponse {
+	rsp := uploadResponse{URL: ref, ThumbURL: thumbRef}
+	if _, private := storage.PrivateKey(ref); private {
+		rsp.PreviewURL = server.media.Resolve(ctx, ref)
+	}
+	if _, private := storage.PrivateKey(thumbRef); private {
+		rsp.ThumbPreviewURL = server.media.Resolve(ctx, thumbRef)
+	}
+	return rsp
+}
+
+var ErrPosterNotImage = errors.New("poster must be an image")
+
+// thumbnailSource returns what an upload's thumbnail is made from: an image
+// itself, or the poster frame a video was sent with, if any. A poster is
+// checked like any upload, and ok is false once a bad one has been answered.
+// Callers close source when it isn't file.
+func (server *Server) thumbnailSource(ctx *gin.Context, file multipart.File, contentType string) (source multipart.File, ok bool) {
+	if strings.HasPrefix(contentType, "image/") {
+		return file, true
+	}
+	if !strings.HasPrefix(contentType, "video/") {
+		return nil, true
+	}
+
+	posterHeader, err := ctx.FormFile("poster")
+	if err != nil {
+		return nil, true
+	}
+	poster, err := posterHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(fmt.Errorf("failed to open poster: %w", err)))
+		return nil, false
+	}
+	posterType, ok := server.checkUploadFile(ctx, poster, posterHeader)
+	if !ok {
+		poster.Close()
+		return nil, false
+	}
+	if !strings.HasPrefix(posterType, "image/") {
+		poster.Close()
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":         ErrPosterNotImage.Error(),
+			"detected_type": posterType,
+		})
+		return nil, false
+	}
+	return poster, true
+}
+
+// makeThumbnail is best-effort: an upload still succeeds without a thumbnail
+func makeThumbnail(source multipart.File) (data []byte, contentType string, ok bool) {
+	if source == nil {
+		return nil, "", false
+	}
+	if _, err := source.Seek(0, io.SeekStart); err != nil {
+		log.Warn().Err(err).Msg("failed to rewind thumbnail source")
+		return nil, "", false
+	}
+	data, contentType, err := storage.MakeThumbnail(source)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to make thumbnail")
+		return nil, "", false
+	}
+	return data, contentType, true
 }
 
 func (server *Server) uploadFile(ctx *gin.Context) {
+	if !server.limitUploadBody(ctx) {
+		return
+	}
+
 	fileHeader, err := ctx.FormFile("file")
 	if err != nil {
+		if isBodyTooLarge(err) {
+			server.rejectOversizedUpload(ctx)
+			return
+		}
 		ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("no file uploaded")))
 		return
 	}
@@ -26,6 +120,26 @@ func (server *Server) uploadFile(ctx *gin.Context) {
 	}
 	defer file.Close()
 
+	contentType, ok := server.checkUploadFile(ctx, file, fileHeader)
+	if !ok {
+		return
+	}
+	// Stored objects get the sniffed type, not whatever the client claimed
+	fileHeader.Header.Set("Content-Type", contentType)
+
+	thumbSource, ok := server.thumbnailSource(ctx, file, contentType)
+	if !ok {
+		return
+	}
+	if thumbSource != nil && thumbSource != file {
+		defer thumbSource.Close()
+	}
+
+	if server.storage != nil {
+		server.uploadToStorage(ctx, file, fileHeader, thumbSource)
+		return
+	}
+
 	// Save locally to ./uploads
 	filename := util.RandomString(32) + "_" + fileHeader.Filename
 	dst := "./uploads/" + filename
@@ -42,7 +156,359 @@ func (server *Server) uploadFile(ctx *gin.Context) {
 	// For simplicity and "make it work", let's return the relative path "/uploads/<filename>"
 	publicURL := "/uploads/" + filename
 
+	var thumbURL string
+	if data, _, ok := makeThumbnail(thumbSource); ok {
+		thumbDst := "./uploads/" + storage.ThumbnailKey(filename)
+		err := os.MkdirAll(filepath.Dir(thumbDst), 0o755)
+		if err == nil {
+			err = os.WriteFile(thumbDst, data, 0o644)
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to save thumbnail locally")
+		} else {
+			thumbURL = "/uploads/" + storage.ThumbnailKey(filename)
+		}
+	}
+
 	ctx.JSON(http.StatusOK, uploadResponse{
-		URL: publicURL,
+		URL:      publicURL,
+		ThumbURL: thumbURL,
 	})
 }
+
+// uploadToStorage stores the file in object storage, reusing an existing object when
+// the content has been uploaded before, and records it for reference-counted GC.
+// The thumbnail made from thumbSource is stored next to it when that works.
+func (server *Server) uploadToStorage(ctx *gin.Context, file multipart.File, fileHeader *multipart.FileHeader, thumbSource multipart.File) {
+	result, err := server.storage.UploadFile(ctx, file, fileHeader)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return
+	}
+
+	result, err = server.recordMediaObject(ctx, result)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var thumbRef string
+	if data, contentType, ok := makeThumbnail(thumbSource); ok {
+		thumbRef, err = server.storage.UploadThumbnail(ctx, result.Key, data, contentType)
+		if err != nil {
+			log.Warn().Err(err).Str("key", result.Key).Msg("failed to upload thumbnail")
+		}
+	}
+
+	ctx.JSON(http.StatusOK, server.newUploadResponse(ctx, result.URL, thumbRef))
+}
+
+// recordMediaObject tracks a stored object in media_objects, so references
+// to it are counted and the GC can purge it once nothing uses it. Content
+// already tracked under another key, such as one from before keys dropped the
+// file extension, keeps that key: the returned result points at the tracked
+// object, and a copy this upload just wrote is deleted again.
+func (server *Server) recordMediaObject(ctx context.Context, result storage.UploadResult) (storage.UploadResult, error) {
+	// Retention is per object, so media can outlive (or be purged before) the rows using it
+	var retainUntil util.NullTime
+	if retention := server.config.MediaRetention(); retention > 0 {
+		retainUntil = util.NullTime{Time: util.Now().Add(retention), Valid: true}
+	}
+
+	// Upserting also refreshes updated_at, keeping a re-upload out of the GC window
+	stored, err := server.store.UpsertMediaObject(ctx, db.UpsertMediaObjectParams{
+		Hash:        result.Hash,
+		ObjectKey:   result.Key,
+		Url:         result.URL,
+		SizeBytes:   result.Size,
+		ContentType: result.ContentType,
+		RetainUntil: retainUntil,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if stored.ObjectKey != result.Key {
+		// A deduplicated object wasn't written by this upload and may already
+		// be in use, so only a fresh copy is removed
+		if !result.Deduplicated {
+			if err := server.storage.DeleteObject(ctx, result.Key); err != nil {
+				log.Warn().Err(err).Str("key", result.Key).Msg("failed to delete duplicate upload")
+			}
+		}
+		result.Key, result.URL = stored.ObjectKey, stored.Url
+	}
+	return result, nil
+}
+
+var (
+	ErrUploadContentType = errors.New("content_type must be an image, video or audio type")
+	ErrUploadExtension   = errors.New("extension must be a file extension like .jpg")
+	ErrUploadNotFound    = errors.New("nothing has been uploaded to this key")
+)
+
+// uploadExtension is a file extension with its leading dot
+var uploadExtension = regexp.MustCompile(`^\.[a-zA-Z0-9]{1,10}$`)
+
+type presignUploadRequest struct {
+	ContentType string `json:"content_type" binding:"required"`
+	Extension   string `json:"extension" binding:"required"`
+}
+
+type presignUploadResponse struct {
+	// UploadURL takes a PUT of the file with the same Content-Type header
+	UploadURL   string `json:"upload_url"`
+	URL         string `json:"url"`
+	Key         string `json:"key"`
+	ContentType string `json:"content_type"`
+}
+
+// presignUpload lets the client PUT a file straight to object storage instead
+// of streaming it through POST /upload
+func (server *Server) presignUpload(ctx *gin.Context) {
+	var req presignUploadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !checkUploadContentType(ctx, req.ContentType) {
+		return
+	}
+	ext := req.Extension
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	if !uploadExtension.MatchString(ext) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrUploadExtension))
+		return
+	}
+	if !server.requireStorage(ctx) {
+		return
+	}
+
+	authPayload := getAuthPayload(ctx)
+	uploadURL, url, key, err := server.storage.GeneratePresignedUploadURL(ctx, authPayload.UserID.String(), req.ContentType, ext)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, presignUploadResponse{
+		UploadURL:   uploadURL,
+		URL:         url,
+		Key:         key,
+		ContentType: req.ContentType,
+	})
+}
+
+type confirmPresignedUploadRequest struct {
+	Key string `json:"key" binding:"required"`
+}
+
+// confirmPresignedUpload checks a file PUT to a presigned URL, as
+// completeMultipartUpload does for multipart uploads
+func (server *Server) confirmPresignedUpload(ctx *gin.Context) {
+	var req confirmPresignedUploadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !server.requireStorage(ctx) {
+		return
+	}
+	if !ownsUploadKey(ctx, req.Key) {
+		return
+	}
+
+	result, err := server.storage.StatObject(ctx, req.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrUploadNotFound))
+			return
+		}
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return
+	}
+
+	server.finishDirectUpload(ctx, result)
+}
+
+// checkUploadContentType answers 400 unless a direct upload claims to be an
+// image, video or audio file. The content itself is checked when the upload
+// is confirmed or completed, see finishDirectUpload.
+func checkUploadContentType(ctx *gin.Context, contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, "/")
+	if mediaType != "image" && mediaType != "video" && mediaType != "audio" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrUploadContentType))
+		return false
+	}
+	return true
+}
+
+type createMultipartUploadRequest struct {
+	Filename    string `json:"filename" binding:"required"`
+	ContentType string `json:"content_type" binding:"required"`
+}
+
+type multipartUploadRequest struct {
+	Key      string `json:"key" binding:"required"`
+	UploadID string `json:"upload_id" binding:"required"`
+}
+
+type presignUploadPartsRequest struct {
+	multipartUploadRequest
+	PartNumbers []int32 `json:"part_numbers" binding:"required,min=1,max=100"`
+}
+
+type completeMultipartUploadRequest struct {
+	multipartUploadRequest
+	Parts []storage.CompletedPart `json:"parts" binding:"required,min=1,dive"`
+}
+
+// requireStorage aborts with 503 when object storage is not configured
+func (server *Server) requireStorage(ctx *gin.Context) bool {
+	if server.storage == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "storage is not configured"})
+		return false
+	}
+	return true
+}
+
+// ownedUpload returns the upload if its key lives under the caller's prefix
+func ownedUpload(ctx *gin.Context, req multipartUploadRequest) (storage.MultipartUpload, bool) {
+	if !ownsUploadKey(ctx, req.Key) {
+		return storage.MultipartUpload{}, false
+	}
+	return storage.MultipartUpload{Key: req.Key, UploadID: req.UploadID}, true
+}
+
+// ownsUploadKey answers 403 unless key lives under the caller's prefix, where
+// presigned and multipart uploads put their objects
+func ownsUploadKey(ctx *gin.Context, key string) bool {
+	authPayload := getAuthPayload(ctx)
+	if !strings.HasPrefix(key, authPayload.UserID.String()+"/") {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "upload does not belong to you"})
+		return false
+	}
+	return true
+}
+
+func (server *Server) createMultipartUpload(ctx *gin.Context) {
+	var req createMultipartUploadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !checkUploadContentType(ctx, req.ContentType) {
+		return
+	}
+	if !server.requireStorage(ctx) {
+		return
+	}
+
+	authPayload := getAuthPayload(ctx)
+	upload, err := server.storage.CreateMultipartUpload(ctx, authPayload.UserID.String(), req.Filename, req.ContentType)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, upload)
+}
+
+func (server *Server) presignUploadParts(ctx *gin.Context) {
+	var req presignUploadPartsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !server.requireStorage(ctx) {
+		return
+	}
+	upload, ok := ownedUpload(ctx, req.multipartUploadRequest)
+	if !ok {
+		return
+	}
+
+	parts, err := server.storage.PresignUploadParts(ctx, upload, req.PartNumbers)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"parts": parts})
+}
+
+func (server *Server) completeMultipartUpload(ctx *gin.Context) {
+	var req completeMultipartUploadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !server.requireStorage(ctx) {
+		return
+	}
+	upload, ok := ownedUpload(ctx, req.multipartUploadRequest)
+	if !ok {
+		return
+	}
+
+	result, err := server.storage.CompleteMultipartUpload(ctx, upload, req.Parts)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return
+	}
+
+	server.finishDirectUpload(ctx, result)
+}
+
+// finishDirectUpload checks an object the client wrote straight to storage.
+// The bytes never passed through here, so they get the same checks as
+// POST /upload now, and a refused file is removed again. An accepted file is
+// recorded in media_objects.
+func (server *Server) finishDirectUpload(ctx *gin.Context, result storage.UploadResult) {
+	head, err := server.storage.ReadObjectHead(ctx, result.Key, sniffLen)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return
+	}
+	contentType, ok := server.checkUploadContent(ctx, head, result.Size)
+	if !ok {
+		if err := server.storage.DeleteObject(ctx, result.Key); err != nil {
+			log.Warn().Err(err).Str("key", result.Key).Msg("failed to delete refused upload")
+		}
+		return
+	}
+	result.ContentType = contentType
+
+	result, err = server.recordMediaObject(ctx, result)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, server.newUploadResponse(ctx, result.URL, ""))
+}
+
+func (server *Server) abortMultipartUpload(ctx *gin.Context) {
+	var req multipartUploadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !server.requireStorage(ctx) {
+		return
+	}
+	upload, ok := ownedUpload(ctx, req)
+	if !ok {
+		return
+	}
+
+	if err := server.storage.AbortMultipartUpload(ctx, upload); err != nil {
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Upload aborted"})
+}
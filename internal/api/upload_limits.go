@@ -0,0 +1,137 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultUploadAllowedTypes is used when UPLOAD_ALLOWED_TYPES is empty
+	defaultUploadAllowedTypes = "image/jpeg,image/png,image/webp,video/mp4"
+	defaultUploadMaxImageMB   = 25
+	defaultUploadMaxVideoMB   = 100
+	// multipartOverhead leaves room for the form boundaries and headers around the file
+	multipartOverhead = 1 << 20
+	// sniffLen is how much of a file http.DetectContentType looks at
+	sniffLen = 512
+)
+
+// uploadAllowedTypes is the configured content type allowlist for POST /upload
+func (server *Server) uploadAllowedTypes() []string {
+	configured := server.config.UploadAllowedTypes
+	if strings.TrimSpace(configured) == "" {
+		configured = defaultUploadAllowedTypes
+	}
+
+	var types []string
+	for _, t := range strings.Split(configured, ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// uploadMaxBytes is the size limit for an upload of contentType
+func (server *Server) uploadMaxBytes(contentType string) int64 {
+	if strings.HasPrefix(contentType, "video/") {
+		return megabytes(server.config.UploadMaxVideoMB, defaultUploadMaxVideoMB)
+	}
+	return megabytes(server.config.UploadMaxImageMB, defaultUploadMaxImageMB)
+}
+
+// uploadMaxRequestBytes bounds a whole POST /upload request: the largest file
+// any allowed type may be, plus the multipart framing
+func (server *Server) uploadMaxRequestBytes() int64 {
+	return max(server.uploadMaxBytes("image/"), server.uploadMaxBytes("video/")) + multipartOverhead
+}
+
+func megabytes(configured, fallback int) int64 {
+	if configured <= 0 {
+		configured = fallback
+	}
+	return int64(configured) << 20
+}
+
+// limitUploadBody refuses a request whose Content-Length is over the limit and
+// caps the body, so a missing or lying Content-Length can't get a larger file in
+func (server *Server) limitUploadBody(ctx *gin.Context) bool {
+	limit := server.uploadMaxRequestBytes()
+	if ctx.Request.ContentLength > limit {
+		server.rejectOversizedUpload(ctx)
+		return false
+	}
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+	return true
+}
+
+// rejectOversizedUpload answers 413 for a request body over uploadMaxRequestBytes
+func (server *Server) rejectOversizedUpload(ctx *gin.Context) {
+	limit := server.uploadMaxRequestBytes() - multipartOverhead
+	ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":       fmt.Sprintf("upload is too large: the limit is %d bytes", limit),
+		"limit_bytes": limit,
+	})
+}
+
+// isBodyTooLarge reports whether err came from the MaxBytesReader in limitUploadBody
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// checkUploadFile sniffs the file's type from its content, ignoring what the
+// client claimed, and checks it against the allowlist and that type's size
+// limit. It answers 415 or 413 and returns false when the file is refused.
+func (server *Server) checkUploadFile(ctx *gin.Context, file multipart.File, fileHeader *multipart.FileHeader) (string, bool) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(fmt.Errorf("failed to read file: %w", err)))
+		return "", false
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(fmt.Errorf("failed to rewind file: %w", err)))
+		return "", false
+	}
+
+	return server.checkUploadContent(ctx, head[:n], fileHeader.Size)
+}
+
+// checkUploadContent is checkUploadFile for an upload whose first bytes and
+// size are already known
+func (server *Server) checkUploadContent(ctx *gin.Context, head []byte, size int64) (string, bool) {
+	detected, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	allowed := server.uploadAllowedTypes()
+	isAllowed := false
+	for _, t := range allowed {
+		if t == detected {
+			isAllowed = true
+			break
+		}
+	}
+	if !isAllowed {
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":         fmt.Sprintf("file type %s is not allowed", detected),
+			"detected_type": detected,
+			"allowed_types": allowed,
+		})
+		return "", false
+	}
+
+	if limit := server.uploadMaxBytes(detected); size > limit {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":         fmt.Sprintf("%s file is too large: the limit is %d bytes", detected, limit),
+			"detected_type": detected,
+			"limit_bytes":   limit,
+		})
+		return "", false
+	}
+	return detected, true
+}
@@ -0,0 +1,697 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/service/storage"
+)
+
+// presignStorage only implements GeneratePresignedUploadURL
+type presignStorage struct {
+	storage.Service
+	ext string
+}
+
+func (s *presignStorage) GeneratePresignedUploadURL(_ context.Context, _, _, ext string) (string, string, string, error) {
+	s.ext = ext
+	return "https://upload.example.com/media/k" + ext, "https://bucket.r2.dev/media/k" + ext, "media/k" + ext, nil
+}
+
+func TestPresignUpload(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     gin.H
+		storage  bool
+		wantCode int
+		wantExt  string
+	}{
+		{
+			name:     "OK",
+			body:     gin.H{"content_type": "video/mp4", "extension": "mp4"},
+			storage:  true,
+			wantCode: http.StatusOK,
+			wantExt:  ".mp4",
+		},
+		{
+			name:     "NotMedia",
+			body:     gin.H{"content_type": "application/pdf", "extension": ".pdf"},
+			storage:  true,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "BadExtension",
+			body:     gin.H{"content_type": "image/jpeg", "extension": "../x.jpg"},
+			storage:  true,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "NoStorage",
+			body:     gin.H{"content_type": "image/jpeg", "extension": ".jpg"},
+			wantCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, mockdb.NewMockStore(gomock.NewController(t)))
+			fake := &presignStorage{}
+			if tc.storage {
+				server.storage = fake
+			}
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/uploads/presign", bytes.NewReader(data))
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("user", uuid.New(), time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantCode, recorder.Code, recorder.Body.String())
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+
+			var rsp presignUploadResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			require.Equal(t, tc.wantExt, fake.ext)
+			require.Equal(t, "https://bucket.r2.dev/media/k.mp4", rsp.URL)
+			require.Equal(t, "video/mp4", rsp.ContentType)
+			require.NotEmpty(t, rsp.UploadURL)
+		})
+	}
+}
+
+// uploadStorage only implements UploadFile, UploadThumbnail and DeleteObject
+type uploadStorage struct {
+	storage.Service
+	contentType  string
+	deduplicated bool
+	thumbnail    []byte
+	thumbErr     error
+	deleted      []string
+}
+
+func (s *uploadStorage) DeleteObject(_ context.Context, key string) error {
+	s.deleted = append(s.deleted, key)
+	return nil
+}
+
+func (s *uploadStorage) UploadThumbnail(_ context.Context, key string, data []byte, _ string) (string, error) {
+	if s.thumbErr != nil {
+		return "", s.thumbErr
+	}
+	s.thumbnail = data
+	return "https://bucket.r2.dev/" + storage.ThumbnailKey(key), nil
+}
+
+func (s *uploadStorage) UploadFile(_ context.Context, _ multipart.File, fileHeader *multipart.FileHeader) (storage.UploadResult, error) {
+	s.contentType = fileHeader.Header.Get("Content-Type")
+	return storage.UploadResult{
+		URL:          "https://bucket.r2.dev/media/h.png",
+		Key:          "media/h.png",
+		Hash:         "h",
+		ContentType:  s.contentType,
+		Deduplicated: s.deduplicated,
+	}, nil
+}
+
+// echoMediaObject stores the media object as given, as the first upload of
+// some content does
+func echoMediaObject(_ context.Context, arg db.UpsertMediaObjectParams) (db.MediaObject, error) {
+	return db.MediaObject{Hash: arg.Hash, ObjectKey: arg.ObjectKey, Url: arg.Url}, nil
+}
+
+func TestUploadFileValidation(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+
+	testCases := []struct {
+		name string
+		file []byte
+		// unknownLength hides the Content-Length, as a lying client would
+		unknownLength bool
+		wantCode      int
+		wantType      string
+	}{
+		{
+			name:     "OK",
+			file:     append(png, make([]byte, 1024)...),
+			wantCode: http.StatusOK,
+			wantType: "image/png",
+		},
+		{
+			name:     "NotAllowed",
+			file:     []byte("GIF89a plus some bytes"),
+			wantCode: http.StatusUnsupportedMediaType,
+			wantType: "image/gif",
+		},
+		{
+			name:     "FileTooLarge",
+			file:     append(png, make([]byte, 3<<19)...),
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantType: "image/png",
+		},
+		{
+			name:     "RequestTooLarge",
+			file:     append(png, make([]byte, 3<<20)...),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:          "RequestTooLargeWithoutLength",
+			file:          append(png, make([]byte, 3<<20)...),
+			unknownLength: true,
+			wantCode:      http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			if tc.wantCode == http.StatusOK {
+				store.EXPECT().UpsertMediaObject(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(echoMediaObject)
+			}
+
+			server := newTestServer(t, store)
+			server.config.UploadMaxImageMB = 1
+			server.config.UploadMaxVideoMB = 1
+			fake := &uploadStorage{}
+			server.storage = fake
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, err := writer.CreateFormFile("file", "photo.jpg")
+			require.NoError(t, err)
+			_, err = part.Write(tc.file)
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+
+			request, err := http.NewRequest(http.MethodPost, "/upload", body)
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", writer.FormDataContentType())
+			if tc.unknownLength {
+				request.ContentLength = -1
+			}
+			accessToken, _, err := server.tokenMaker.CreateToken("user", uuid.New(), time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantCode, recorder.Code, recorder.Body.String())
+
+			if tc.wantCode == http.StatusOK {
+				// The client's header said nothing useful; the stored type is sniffed
+				require.Equal(t, "image/png", fake.contentType)
+				return
+			}
+			var rsp struct {
+				Error        string `json:"error"`
+				DetectedType string `json:"detected_type"`
+			}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			require.NotEmpty(t, rsp.Error)
+			require.Equal(t, tc.wantType, rsp.DetectedType)
+		})
+	}
+}
+
+func TestUploadFileReusesTrackedObject(t *testing.T) {
+	// The same bytes, tracked under the key an earlier upload gave them
+	tracked := db.MediaObject{Hash: "h", ObjectKey: "media/h.jpg", Url: "https://bucket.r2.dev/media/h.jpg"}
+
+	testCases := []struct {
+		name         string
+		stored       func(ctx context.Context, arg db.UpsertMediaObjectParams) (db.MediaObject, error)
+		deduplicated bool
+		wantURL      string
+		wantDeleted  []string
+	}{
+		{
+			name:    "FirstUpload",
+			stored:  echoMediaObject,
+			wantURL: "https://bucket.r2.dev/media/h.png",
+		},
+		{
+			// The copy this upload wrote is untracked, so it goes again
+			name: "TrackedUnderOtherKey",
+			stored: func(context.Context, db.UpsertMediaObjectParams) (db.MediaObject, error) {
+				return tracked, nil
+			},
+			wantURL:     tracked.Url,
+			wantDeleted: []string{"media/h.png"},
+		},
+		{
+			// An object that was already there may be in use, so it stays
+			name: "DeduplicatedUnderOtherKey",
+			stored: func(context.Context, db.UpsertMediaObjectParams) (db.MediaObject, error) {
+				return tracked, nil
+			},
+			deduplicated: true,
+			wantURL:      tracked.Url,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().UpsertMediaObject(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(tc.stored)
+
+			server := newTestServer(t, store)
+			fake := &uploadStorage{deduplicated: tc.deduplicated}
+			server.storage = fake
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, err := writer.CreateFormFile("file", "photo.png")
+			require.NoError(t, err)
+			_, err = part.Write(append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...))
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+
+			request, err := http.NewRequest(http.MethodPost, "/upload", body)
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", writer.FormDataContentType())
+			accessToken, _, err := server.tokenMaker.CreateToken("user", uuid.New(), time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+			var rsp uploadResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			require.Equal(t, tc.wantURL, rsp.URL)
+			require.Equal(t, tc.wantDeleted, fake.deleted)
+		})
+	}
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func TestUploadFileThumbnail(t *testing.T) {
+	photo := testPNG(t, 800, 600)
+	video := append([]byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"), make([]byte, 64)...)
+
+	testCases := []struct {
+		name     string
+		file     []byte
+		poster   []byte
+		thumbErr error
+		wantCode int
+		// wantThumb is the thumbnail's size, zero when there should be none
+		wantThumb image.Point
+	}{
+		{
+			name:      "Image",
+			file:      photo,
+			wantCode:  http.StatusOK,
+			wantThumb: image.Pt(400, 300),
+		},
+		{
+			name:      "SmallImageNotUpscaled",
+			file:      testPNG(t, 200, 100),
+			wantCode:  http.StatusOK,
+			wantThumb: image.Pt(200, 100),
+		},
+		{
+			name:     "UndecodableImage",
+			file:     append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...),
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "ThumbnailUploadFails",
+			file:     photo,
+			thumbErr: errors.New("bucket unavailable"),
+			wantCode: http.StatusOK,
+		},
+		{
+			name:      "VideoWithPoster",
+			file:      video,
+			poster:    photo,
+			wantCode:  http.StatusOK,
+			wantThumb: image.Pt(400, 300),
+		},
+		{
+			name:     "VideoWithoutPoster",
+			file:     video,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "PosterNotImage",
+			file:     video,
+			poster:   video,
+			wantCode: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			if tc.wantCode == http.StatusOK {
+				store.EXPECT().UpsertMediaObject(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(echoMediaObject)
+			}
+
+			server := newTestServer(t, store)
+			fake := &uploadStorage{thumbErr: tc.thumbErr}
+			server.storage = fake
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, err := writer.CreateFormFile("file", "upload.bin")
+			require.NoError(t, err)
+			_, err = part.Write(tc.file)
+			require.NoError(t, err)
+			if tc.poster != nil {
+				part, err := writer.CreateFormFile("poster", "poster.bin")
+				require.NoError(t, err)
+				_, err = part.Write(tc.poster)
+				require.NoError(t, err)
+			}
+			require.NoError(t, writer.Close())
+
+			request, err := http.NewRequest(http.MethodPost, "/upload", body)
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", writer.FormDataContentType())
+			accessToken, _, err := server.tokenMaker.CreateToken("user", uuid.New(), time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantCode, recorder.Code, recorder.Body.String())
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+
+			var rsp uploadResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			require.Equal(t, "https://bucket.r2.dev/media/h.png", rsp.URL)
+			if tc.wantThumb == (image.Point{}) {
+				require.Empty(t, rsp.ThumbURL)
+				return
+			}
+			require.Equal(t, "https://bucket.r2.dev/thumb/media/h.png", rsp.ThumbURL)
+			thumb, _, err := image.DecodeConfig(bytes.NewReader(fake.thumbnail))
+			require.NoError(t, err)
+			require.Equal(t, tc.wantThumb, image.Pt(thumb.Width, thumb.Height))
+		})
+	}
+}
+
+// multipartStorage only implements the multipart calls, StatObject and
+// DeleteObject, serving file as the completed or presigned object
+type multipartStorage struct {
+	storage.Service
+	file        []byte
+	contentType string
+	deleted     []string
+}
+
+func (s *multipartStorage) CreateMultipartUpload(_ context.Context, keyPrefix, _, contentType string) (storage.MultipartUpload, error) {
+	s.contentType = contentType
+	return storage.MultipartUpload{Key: keyPrefix + "/u.mp4", UploadID: "upload"}, nil
+}
+
+func (s *multipartStorage) CompleteMultipartUpload(_ context.Context, upload storage.MultipartUpload, _ []storage.CompletedPart) (storage.UploadResult, error) {
+	return storage.UploadResult{
+		URL:         "https://bucket.r2.dev/" + upload.Key,
+		Key:         upload.Key,
+		Hash:        upload.Key,
+		Size:        int64(len(s.file)),
+		ContentType: "application/octet-stream",
+	}, nil
+}
+
+func (s *multipartStorage) StatObject(_ context.Context, key string) (storage.UploadResult, error) {
+	if s.file == nil {
+		return storage.UploadResult{}, storage.ErrObjectNotFound
+	}
+	return storage.UploadResult{
+		URL:         "https://bucket.r2.dev/" + key,
+		Key:         key,
+		Hash:        key,
+		Size:        int64(len(s.file)),
+		ContentType: "application/octet-stream",
+	}, nil
+}
+
+func (s *multipartStorage) ReadObjectHead(_ context.Context, _ string, n int64) ([]byte, error) {
+	return s.file[:min(n, int64(len(s.file)))], nil
+}
+
+func (s *multipartStorage) DeleteObject(_ context.Context, key string) error {
+	s.deleted = append(s.deleted, key)
+	return nil
+}
+
+func TestCreateMultipartUpload(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     gin.H
+		wantCode int
+	}{
+		{
+			name:     "OK",
+			body:     gin.H{"filename": "clip.mp4", "content_type": "video/mp4"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "NotMedia",
+			body:     gin.H{"filename": "run.sh", "content_type": "application/x-sh"},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "NoContentType",
+			body:     gin.H{"filename": "clip.mp4"},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, mockdb.NewMockStore(gomock.NewController(t)))
+			fake := &multipartStorage{}
+			server.storage = fake
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/uploads/multipart", bytes.NewReader(data))
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("user", uuid.New(), time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantCode, recorder.Code, recorder.Body.String())
+			if tc.wantCode == http.StatusOK {
+				require.Equal(t, "video/mp4", fake.contentType)
+			} else {
+				require.Empty(t, fake.contentType)
+			}
+		})
+	}
+}
+
+func TestCompleteMultipartUpload(t *testing.T) {
+	userID := uuid.New()
+	key := userID.String() + "/u.mp4"
+	video := append([]byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"), make([]byte, 64)...)
+
+	testCases := []struct {
+		name       string
+		file       []byte
+		buildStubs func(store *mockdb.MockStore)
+		wantCode   int
+	}{
+		{
+			name: "OK",
+			file: video,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertMediaObject(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpsertMediaObjectParams) (db.MediaObject, error) {
+						require.Equal(t, key, arg.Hash)
+						require.Equal(t, key, arg.ObjectKey)
+						require.Equal(t, "https://bucket.r2.dev/"+key, arg.Url)
+						require.Equal(t, int64(len(video)), arg.SizeBytes)
+						require.Equal(t, "video/mp4", arg.ContentType)
+						return echoMediaObject(context.Background(), arg)
+					})
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "NotAllowed",
+			file: []byte("#!/bin/sh\nrm -rf /\n"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertMediaObject(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "TooLarge",
+			file: append(video, make([]byte, 2<<20)...),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertMediaObject(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.UploadMaxVideoMB = 1
+			fake := &multipartStorage{file: tc.file}
+			server.storage = fake
+
+			data, err := json.Marshal(gin.H{
+				"key":       key,
+				"upload_id": "upload",
+				"parts":     []gin.H{{"part_number": 1, "etag": "e1"}},
+			})
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/uploads/multipart/complete", bytes.NewReader(data))
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantCode, recorder.Code, recorder.Body.String())
+			if tc.wantCode == http.StatusOK {
+				require.Empty(t, fake.deleted)
+				return
+			}
+			// A refused file doesn't stay in the bucket
+			require.Equal(t, []string{key}, fake.deleted)
+		})
+	}
+}
+
+func TestConfirmPresignedUpload(t *testing.T) {
+	userID := uuid.New()
+	key := userID.String() + "/p.mp4"
+	video := append([]byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"), make([]byte, 64)...)
+
+	testCases := []struct {
+		name        string
+		key         string
+		file        []byte
+		buildStubs  func(store *mockdb.MockStore)
+		wantCode    int
+		wantDeleted []string
+	}{
+		{
+			name: "OK",
+			key:  key,
+			file: video,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertMediaObject(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpsertMediaObjectParams) (db.MediaObject, error) {
+						require.Equal(t, key, arg.ObjectKey)
+						require.Equal(t, int64(len(video)), arg.SizeBytes)
+						require.Equal(t, "video/mp4", arg.ContentType)
+						return echoMediaObject(context.Background(), arg)
+					})
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "NotAllowed",
+			key:  key,
+			file: []byte("#!/bin/sh\nrm -rf /\n"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertMediaObject(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantCode:    http.StatusUnsupportedMediaType,
+			wantDeleted: []string{key},
+		},
+		{
+			name: "TooLarge",
+			key:  key,
+			file: append(video, make([]byte, 2<<20)...),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertMediaObject(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantCode:    http.StatusRequestEntityTooLarge,
+			wantDeleted: []string{key},
+		},
+		{
+			name: "NotUploaded",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertMediaObject(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			// Someone else's upload, or a content-addressed object, can't be confirmed
+			name: "NotOwner",
+			key:  "media/" + uuid.NewString(),
+			file: video,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertMediaObject(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.config.UploadMaxVideoMB = 1
+			fake := &multipartStorage{file: tc.file}
+			server.storage = fake
+
+			recorder := postJSON(t, server, "/uploads/presign/complete", gin.H{"key": tc.key}, &userID)
+			require.Equal(t, tc.wantCode, recorder.Code, recorder.Body.String())
+			require.Equal(t, tc.wantDeleted, fake.deleted)
+			if tc.wantCode == http.StatusOK {
+				var rsp uploadResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, "https://bucket.r2.dev/"+key, rsp.URL)
+			}
+		})
+	}
+}
@@ -1,16 +1,21 @@
 package api
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
 
 	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/analytics"
 	"privacy-social-backend/internal/service/user"
 	"privacy-social-backend/internal/token"
+	"privacy-social-backend/internal/util"
 )
 
 type createUserRequest struct {
@@ -31,6 +36,7 @@ type userResponse struct {
 	Theme             string    `json:"theme"`
 	ProfileVisibility string    `json:"profile_visibility"`
 	Email             string    `json:"email"`
+	PhoneVerified     bool      `json:"phone_verified"`
 	IsGhostMode       bool      `json:"is_ghost_mode"`
 	CreatedAt         time.Time `json:"created_at"`
 }
@@ -47,6 +53,7 @@ func newUserResponse(user db.User) userResponse {
 		Theme:             user.Theme.String,
 		ProfileVisibility: user.ProfileVisibility.String,
 		Email:             user.Email.String,
+		PhoneVerified:     user.PhoneVerified,
 		IsGhostMode:       user.IsGhostMode,
 		CreatedAt:         user.CreatedAt,
 	}
@@ -77,42 +84,16 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	// Generate Tokens for Auto-Login
-	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.ID, server.config.AccessTokenDuration)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
-	}
+	server.events.Emit(ctx, analytics.EventSignup, user.ID)
 
-	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, user.ID, server.config.RefreshTokenDuration)
+	// Auto-login
+	session, err := server.user.StartSession(ctx, user, sessionDevice(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
-		ID:           refreshPayload.ID,
-		UserID:       user.ID,
-		RefreshToken: refreshToken,
-		UserAgent:    ctx.Request.UserAgent(),
-		ClientIp:     ctx.ClientIP(),
-		IsBlocked:    false,
-		ExpiresAt:    refreshPayload.ExpiredAt,
-	})
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
-	}
-
-	rsp := loginUserResponse{
-		SessionID:             session.ID,
-		AccessToken:           accessToken,
-		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
-		RefreshToken:          refreshToken,
-		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
-		User:                  newUserResponse(user),
-	}
-
+	rsp := newLoginUserResponse(session)
 	ctx.JSON(http.StatusCreated, rsp)
 }
 
@@ -130,6 +111,17 @@ type loginUserResponse struct {
 	User                  userResponse `json:"user"`
 }
 
+func newLoginUserResponse(result *user.LoginUserResult) loginUserResponse {
+	return loginUserResponse{
+		SessionID:             result.SessionID,
+		AccessToken:           result.AccessToken,
+		AccessTokenExpiresAt:  result.AccessTokenExpiresAt,
+		RefreshToken:          result.RefreshToken,
+		RefreshTokenExpiresAt: result.RefreshTokenExpiresAt,
+		User:                  newUserResponse(result.User),
+	}
+}
+
 func (server *Server) loginUser(ctx *gin.Context) {
 	var req loginUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -138,10 +130,9 @@ func (server *Server) loginUser(ctx *gin.Context) {
 	}
 
 	result, err := server.user.LoginUser(ctx, user.LoginUserParams{
-		Phone:     req.Phone,
-		Password:  req.Password,
-		UserAgent: ctx.Request.UserAgent(),
-		ClientIP:  ctx.ClientIP(),
+		Phone:    req.Phone,
+		Password: req.Password,
+		Device:   sessionDevice(ctx),
 	})
 	if err != nil {
 		if err.Error() == "user not found" {
@@ -156,15 +147,7 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
-	rsp := loginUserResponse{
-		SessionID:             result.SessionID,
-		AccessToken:           result.AccessToken,
-		AccessTokenExpiresAt:  result.AccessTokenExpiresAt,
-		RefreshToken:          result.RefreshToken,
-		RefreshTokenExpiresAt: result.RefreshTokenExpiresAt,
-		User:                  newUserResponse(result.User),
-	}
-	ctx.JSON(http.StatusOK, rsp)
+	ctx.JSON(http.StatusOK, newLoginUserResponse(result))
 }
 
 type searchUsersRequest struct {
@@ -178,7 +161,9 @@ func (server *Server) searchUsers(ctx *gin.Context) {
 		return
 	}
 
-	users, err := server.user.SearchUsers(ctx, req.Query)
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	users, err := server.user.SearchUsers(ctx, payload.UserID, req.Query)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -245,3 +230,66 @@ func (server *Server) updateUserPassword(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "password updated successfully"})
 }
+
+// googleReauthMaxAge is how recently a Google ID token must have been issued
+// to stand in for the password when deleting an account
+const googleReauthMaxAge = 5 * time.Minute
+
+var ErrStaleGoogleToken = errors.New("google sign-in is too old, sign in with Google again")
+
+type deleteAccountRequest struct {
+	Password string `json:"password" binding:"required_without=IDToken"`
+	// IDToken is a fresh Google ID token, for accounts that sign in with Google
+	IDToken string `json:"id_token" binding:"required_without=Password"`
+}
+
+// deleteAccount lets users delete their own account. The password, or for a
+// Google sign-in account a fresh Google ID token, is asked for again so a
+// stolen access token alone can't do it.
+func (server *Server) deleteAccount(ctx *gin.Context) {
+	var req deleteAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	reauth := user.AccountReauth{Password: req.Password}
+	if req.IDToken != "" {
+		gUser, err := server.verifyGoogleIDToken(req.IDToken)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		if !gUser.issuedWithin(googleReauthMaxAge) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(ErrStaleGoogleToken))
+			return
+		}
+		reauth = user.AccountReauth{GoogleSub: gUser.Sub}
+	}
+
+	deleted, err := server.user.DeleteAccount(ctx, payload.UserID, reauth)
+	if err != nil {
+		if errors.Is(err, user.ErrIncorrectPassword) || errors.Is(err, user.ErrGoogleAccountMismatch) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.invalidateDeletedAccountCaches(payload.UserID, deleted)
+	if err := server.location.RemoveUserLocation(ctx, payload.UserID); err != nil {
+		log.Error().Err(err).Msg("Failed to remove deleted user from location index")
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":    "account deleted",
+		"deleted_at": util.Now(),
+	})
+}
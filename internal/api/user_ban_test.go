@@ -0,0 +1,276 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+func TestBanMiddleware(t *testing.T) {
+	userID := uuid.New()
+
+	testCases := []struct {
+		name          string
+		lockout       string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "NotBanned",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListActiveSessions(gomock.Any(), userID).Times(1).Return(nil, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:    "HardBanned",
+			lockout: "hard",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListActiveSessions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), ErrAccountBanned.Error())
+			},
+		},
+		{
+			name:    "Suspended",
+			lockout: util.Now().Add(time.Hour).Format(time.RFC3339),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListActiveSessions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				var got struct {
+					Error          string    `json:"error"`
+					SuspendedUntil time.Time `json:"suspended_until"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, ErrAccountSuspended.Error(), got.Error)
+				require.WithinDuration(t, util.Now().Add(time.Hour), got.SuspendedUntil, time.Minute)
+			},
+		},
+		{
+			// The key normally expires with the suspension, but an expired end is never enforced
+			name:    "SuspensionOver",
+			lockout: util.Now().Add(-time.Minute).Format(time.RFC3339),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListActiveSessions(gomock.Any(), userID).Times(1).Return(nil, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			if tc.lockout != "" {
+				require.NoError(t, server.redis.Set(context.Background(), lockoutCacheKey(userID), tc.lockout, 0).Err())
+			}
+
+			request, err := http.NewRequest(http.MethodGet, "/account/sessions", nil)
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("user", userID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestBanUserTypes(t *testing.T) {
+	adminID, targetID := uuid.New(), uuid.New()
+	until := util.Now().Add(48 * time.Hour).Truncate(time.Second)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server)
+	}{
+		{
+			name: "HardBan",
+			body: gin.H{"user_id": targetID, "ban": true, "type": "hard", "reason": "threats"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BanUser(gomock.Any(), db.BanUserParams{
+						ID:        targetID,
+						BanType:   sql.NullString{String: "hard", Valid: true},
+						BanReason: sql.NullString{String: "threats", Valid: true},
+					}).
+					Times(1).
+					Return(db.User{
+						ID:        targetID,
+						BanType:   sql.NullString{String: "hard", Valid: true},
+						BanReason: sql.NullString{String: "threats", Valid: true},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				require.JSONEq(t, `"hard"`, mustJSONField(t, recorder.Body.Bytes(), "ban_type"))
+				require.JSONEq(t, `"threats"`, mustJSONField(t, recorder.Body.Bytes(), "ban_reason"))
+
+				lockout, err := server.redis.Get(context.Background(), lockoutCacheKey(targetID)).Result()
+				require.NoError(t, err)
+				require.Equal(t, "hard", lockout)
+			},
+		},
+		{
+			name: "TemporaryBan",
+			body: gin.H{"user_id": targetID, "ban": true, "type": "temp", "expires_at": until},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BanUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.BanUserParams) (db.User, error) {
+						require.False(t, arg.IsShadowBanned)
+						require.Equal(t, "temp", arg.BanType.String)
+						require.True(t, until.Equal(arg.BanExpiresAt.Time))
+						return db.User{ID: targetID, BanType: arg.BanType, BanExpiresAt: arg.BanExpiresAt}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+				ttl, err := server.redis.TTL(context.Background(), lockoutCacheKey(targetID)).Result()
+				require.NoError(t, err)
+				require.InDelta(t, (48 * time.Hour).Seconds(), ttl.Seconds(), 60)
+			},
+		},
+		{
+			name: "ShadowBanByDefault",
+			body: gin.H{"user_id": targetID, "ban": true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BanUser(gomock.Any(), db.BanUserParams{
+						ID:             targetID,
+						IsShadowBanned: true,
+						BanType:        sql.NullString{String: "shadow", Valid: true},
+					}).
+					Times(1).
+					Return(db.User{ID: targetID, IsShadowBanned: true, BanType: sql.NullString{String: "shadow", Valid: true}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				require.Zero(t, server.redis.Exists(context.Background(), lockoutCacheKey(targetID)).Val())
+			},
+		},
+		{
+			name: "TemporaryWithoutExpiry",
+			body: gin.H{"user_id": targetID, "ban": true, "type": "temp"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BanUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiryOnHardBan",
+			body: gin.H{"user_id": targetID, "ban": true, "type": "hard", "expires_at": until},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BanUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownType",
+			body: gin.H{"user_id": targetID, "ban": true, "type": "forever"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BanUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUserByID(gomock.Any(), adminID).Times(1).Return(db.User{ID: adminID, Role: db.UserRoleAdmin}, nil)
+			store.EXPECT().CreateAdminAuditLog(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/admin/users/ban", bytes.NewReader(data))
+			require.NoError(t, err)
+			accessToken, _, err := server.tokenMaker.CreateToken("admin", adminID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server)
+		})
+	}
+}
+
+func TestListUsersHidesEndedSuspension(t *testing.T) {
+	ended := newAdminUserResponse(db.User{
+		BanType:      sql.NullString{String: "temp", Valid: true},
+		BanReason:    sql.NullString{String: "spam", Valid: true},
+		BanExpiresAt: util.NullTime{Time: util.Now().Add(-time.Minute), Valid: true},
+	})
+	require.Nil(t, ended.BanType)
+	require.Nil(t, ended.BanReason)
+
+	active := newAdminUserResponse(db.User{
+		BanType:      sql.NullString{String: "temp", Valid: true},
+		BanExpiresAt: util.NullTime{Time: util.Now().Add(time.Hour), Valid: true},
+	})
+	require.Equal(t, "temp", *active.BanType)
+	require.NotNil(t, active.BanExpiresAt)
+}
+
+func TestRestoreLockouts(t *testing.T) {
+	hardID, tempID := uuid.New(), uuid.New()
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListActiveLockouts(gomock.Any()).Times(1).Return([]db.ListActiveLockoutsRow{
+		{ID: hardID, BanType: sql.NullString{String: "hard", Valid: true}},
+		{
+			ID:           tempID,
+			BanType:      sql.NullString{String: "temp", Valid: true},
+			BanExpiresAt: util.NullTime{Time: util.Now().Add(time.Hour), Valid: true},
+		},
+	}, nil)
+
+	server := newTestServer(t, store)
+	require.NoError(t, server.RestoreLockouts(context.Background()))
+
+	require.Equal(t, "hard", server.redis.Get(context.Background(), lockoutCacheKey(hardID)).Val())
+	require.Positive(t, server.redis.TTL(context.Background(), lockoutCacheKey(tempID)).Val())
+}
@@ -2,21 +2,30 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 
 	"privacy-social-backend/internal/repository/db"
 	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/service/story"
+	"privacy-social-backend/internal/service/user"
 	"privacy-social-backend/internal/util"
 )
 
@@ -79,6 +88,11 @@ func TestCreateUser(t *testing.T) {
 					CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
 					Times(1).
 					Return(user, nil)
+				// Auto-login records the session (and applies the session cap) in a transaction
+				store.EXPECT().
+					ExecTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
 			},
 			checkResponse: func(rec *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusCreated, rec.Code)
@@ -172,10 +186,220 @@ func requireBodyMatchUser(t *testing.T, body *bytes.Buffer, user db.User) {
 		AvatarUrl string `json:"avatar_url"`
 	}
 
-	var gotUser userResponse
-	err = json.Unmarshal(data, &gotUser)
+	// Registration auto-logs in, so the user is nested in the login response
+	var got struct {
+		User userResponse `json:"user"`
+	}
+	err = json.Unmarshal(data, &got)
 	require.NoError(t, err)
-	require.Equal(t, user.Username, gotUser.Username)
-	require.Equal(t, user.FullName, gotUser.FullName)
+	require.Equal(t, user.Username, got.User.Username)
+	require.Equal(t, user.FullName, got.User.FullName)
 	// Password is not returned, so we can't check it directly here, but schema validation covers it.
 }
+
+func TestDeleteAccount(t *testing.T) {
+	account, password := randomUser(t)
+	account.ID = uuid.New()
+
+	// Signed up with Google, so its password is random and unknown
+	googleAccount, _ := randomUser(t)
+	googleAccount.ID = uuid.New()
+	googleAccount.GoogleID = sql.NullString{String: "google-sub", Valid: true}
+	freshToken := func(sub string) *googleUser {
+		return &googleUser{Sub: sub, EmailVerified: true, IssuedAt: json.Number(strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10))}
+	}
+
+	testCases := []struct {
+		name    string
+		account db.User
+		body    gin.H
+		// google is what the Google ID token in the body verifies as
+		google        *googleUser
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "GoogleOnlyAccount",
+			account: googleAccount,
+			body:    gin.H{"id_token": "token"},
+			google:  freshToken("google-sub"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), googleAccount.ID).Times(1).Return(googleAccount, nil)
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			},
+		},
+		{
+			name:    "OtherGoogleAccount",
+			account: googleAccount,
+			body:    gin.H{"id_token": "token"},
+			google:  freshToken("someone-else"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), googleAccount.ID).Times(1).Return(googleAccount, nil)
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:    "GoogleTokenOnPasswordAccount",
+			account: account,
+			body:    gin.H{"id_token": "token"},
+			google:  freshToken("google-sub"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:    "StaleGoogleToken",
+			account: googleAccount,
+			body:    gin.H{"id_token": "token"},
+			google: &googleUser{
+				Sub:      "google-sub",
+				IssuedAt: json.Number(strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+				require.Contains(t, rec.Body.String(), ErrStaleGoogleToken.Error())
+			},
+		},
+		{
+			name:    "InvalidGoogleToken",
+			account: googleAccount,
+			body:    gin.H{"id_token": "token"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name: "OK",
+			body: gin.H{"password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Contains(t, rec.Body.String(), "deleted_at")
+			},
+		},
+		{
+			name: "WrongPassword",
+			body: gin.H{"password": password + "x"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name: "MissingPassword",
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
+		{
+			name: "TxFails",
+			body: gin.H{"password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), account.ID).Times(1).Return(account, nil)
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).Return(errors.New("rolled back"))
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, rec.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.redis = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+			server.verifyGoogleIDToken = func(string) (*googleUser, error) {
+				if tc.google == nil {
+					return nil, errors.New("invalid token")
+				}
+				return tc.google, nil
+			}
+
+			caller := tc.account
+			if caller.ID == uuid.Nil {
+				caller = account
+			}
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodDelete, "/users/me", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			accessToken, _, err := server.tokenMaker.CreateToken(caller.Username, caller.ID, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestInvalidateDeletedAccountCaches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	mr := miniredis.RunT(t)
+	server.redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	userID := uuid.New()
+	contactID := uuid.New()
+	bystanderID := uuid.New()
+	keys := []string{
+		util.RedisKey("profile:" + userID.String()),
+		conversationCacheKey(userID, contactID),
+		util.RedisKey("unread_count:" + contactID.String()),
+		util.RedisKey("stories:connections:" + contactID.String()),
+		storyRingsCacheKey(contactID),
+	}
+	kept := storyRingsCacheKey(bystanderID)
+	for _, key := range append(keys, kept) {
+		require.NoError(t, mr.Set(key, "cached"))
+	}
+
+	server.invalidateDeletedAccountCaches(userID, &user.DeletedAccount{
+		ContactIDs:     []uuid.UUID{contactID},
+		StoryGeohashes: []string{"tdr1v9q", "tdr1vx"},
+	})
+
+	for _, key := range keys {
+		require.False(t, mr.Exists(key), key)
+	}
+	require.True(t, mr.Exists(kept))
+
+	generation, err := server.redis.Get(context.Background(), story.FeedCellGenerationKey("tdr1v")).Int()
+	require.NoError(t, err)
+	require.Equal(t, 1, generation, "each feed cell is expired once")
+}
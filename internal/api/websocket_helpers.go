@@ -1,8 +1,11 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"privacy-social-backend/internal/realtime"
+	"privacy-social-backend/internal/service/analytics"
+	"privacy-social-backend/internal/service/location"
 
 	"github.com/google/uuid"
 )
@@ -16,3 +19,20 @@ func (server *Server) sendWSNotification(userID uuid.UUID, msgType string, paylo
 	wsMsgBytes, _ := json.Marshal(wsMsg)
 	server.hub.SendToUser(userID, wsMsgBytes)
 }
+
+// sendCrossingNotification pushes a freshly detected crossing to one of its users
+func (server *Server) sendCrossingNotification(recipient uuid.UUID, event location.CrossingEvent) {
+	server.events.Emit(context.Background(), analytics.EventCrossing, recipient)
+	// Both users of a crossing are notified; count it once
+	if recipient == event.Crossing.UserID1 {
+		server.metrics.crossingsDetected.Inc()
+	}
+	server.sendWSNotification(recipient, location.CrossingDetectedType, map[string]interface{}{
+		"crossing_id":     event.Crossing.ID,
+		"crossed_with":    event.CrossedWith,
+		"username":        event.CrossedWithUsername,
+		"distance_meters": event.DistanceMeters,
+		"location":        event.Crossing.LocationCenter,
+		"occurred_at":     event.Crossing.OccurredAt,
+	})
+}
@@ -11,6 +11,7 @@ type Config struct {
 	DBSource             string        `mapstructure:"DB_SOURCE"`
 	ServerAddress        string        `mapstructure:"SERVER_ADDRESS"`
 	RedisAddress         string        `mapstructure:"REDIS_ADDRESS"`
+	RedisKeyPrefix       string        `mapstructure:"REDIS_KEY_PREFIX"`
 	TokenSymmetricKey    string        `mapstructure:"JWT_SECRET"`
 	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
 	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
@@ -21,6 +22,117 @@ type Config struct {
 	R2SecretKey          string        `mapstructure:"R2_SECRET_KEY"`
 	R2BucketName         string        `mapstructure:"R2_BUCKET_NAME"`
 	ExpoRedirectURL      string        `mapstructure:"EXPO_REDIRECT_URL"`
+	// GroupMembersRequireConnection restricts group invites to accepted connections
+	GroupMembersRequireConnection bool `mapstructure:"GROUP_MEMBERS_REQUIRE_CONNECTION"`
+	// FeedConnectionsOnly gates the nearby feed to connections and public-audience authors
+	FeedConnectionsOnly bool `mapstructure:"FEED_CONNECTIONS_ONLY"`
+	// FeedResultLimit caps stories returned per feed request
+	FeedResultLimit int `mapstructure:"FEED_RESULT_LIMIT"`
+	// AllowedReactions optionally restricts reactions to a curated, comma-separated set
+	AllowedReactions []string `mapstructure:"ALLOWED_REACTIONS"`
+	// MaxSessionsPerUser revokes a user's oldest sessions beyond this many; 0 is unlimited
+	MaxSessionsPerUser int `mapstructure:"MAX_SESSIONS_PER_USER"`
+	// MediaRetentionDays keeps uploaded media at least this long and detaches it from
+	// messages older than this, independently of message expiry; 0 disables the policy
+	MediaRetentionDays int `mapstructure:"MEDIA_RETENTION_DAYS"`
+	// ImpersonationTokenDuration is how long an admin's read-only "view as user" token lasts
+	ImpersonationTokenDuration time.Duration `mapstructure:"IMPERSONATION_TOKEN_DURATION"`
+	// CrossingRadiusMeters is how close two users must be to cross paths
+	CrossingRadiusMeters float64 `mapstructure:"CROSSING_RADIUS_METERS"`
+	// CrossingMinDwell is how long they must stay that close before it counts
+	CrossingMinDwell time.Duration `mapstructure:"CROSSING_MIN_DWELL"`
+	// CrossingCooldown is the minimum time between crossings of the same pair
+	CrossingCooldown time.Duration `mapstructure:"CROSSING_COOLDOWN"`
+	// PublicBaseURL prefixes public share links such as /s/:id; empty leaves them relative
+	PublicBaseURL string `mapstructure:"PUBLIC_BASE_URL"`
+	// AppDeepLinkBase prefixes links that open the mobile app, e.g. locolive://
+	AppDeepLinkBase string `mapstructure:"APP_DEEP_LINK_BASE"`
+	// MessageDefaultExpiry applies when a message doesn't ask for its own expiry
+	MessageDefaultExpiry time.Duration `mapstructure:"MESSAGE_DEFAULT_EXPIRY"`
+	// MessageMinExpiry is the shortest expiry a message may ask for
+	MessageMinExpiry time.Duration `mapstructure:"MESSAGE_MIN_EXPIRY"`
+	// MessageMaxExpiry caps message expiry for free users
+	MessageMaxExpiry time.Duration `mapstructure:"MESSAGE_MAX_EXPIRY"`
+	// MessageMaxExpiryPremium caps message expiry for premium users
+	MessageMaxExpiryPremium time.Duration `mapstructure:"MESSAGE_MAX_EXPIRY_PREMIUM"`
+	// MessageSaveRequiresPremium limits saving messages (never expire) to premium users
+	MessageSaveRequiresPremium bool `mapstructure:"MESSAGE_SAVE_REQUIRES_PREMIUM"`
+	// AnonymousStoryDailyLimit caps anonymous stories per author in any 24 hours
+	AnonymousStoryDailyLimit int `mapstructure:"ANONYMOUS_STORY_DAILY_LIMIT"`
+	// ReportAutoHideThreshold is how many people reporting a story hides it from
+	// feeds until a moderator reviews it; 0 turns auto-hiding off
+	ReportAutoHideThreshold int `mapstructure:"REPORT_AUTO_HIDE_THRESHOLD"`
+	// SafetyBlockThreshold is the combined safety risk score at which an action is refused
+	SafetyBlockThreshold float64 `mapstructure:"SAFETY_BLOCK_THRESHOLD"`
+	// SafetyBanThreshold is the combined safety risk score at which an action counts as a ban strike
+	SafetyBanThreshold float64 `mapstructure:"SAFETY_BAN_THRESHOLD"`
+	// SafetyBanStrikes is how many ban strikes within 24 hours get a user shadow-banned
+	SafetyBanStrikes int `mapstructure:"SAFETY_BAN_STRIKES"`
+	// SafetyEnforce places the bans the safety checks call for; off, they are only logged
+	SafetyEnforce bool `mapstructure:"SAFETY_ENFORCE"`
+	// SafetyMaxStoriesPerHour is when the rapid-posting safety signal starts adding risk
+	SafetyMaxStoriesPerHour int `mapstructure:"SAFETY_MAX_STORIES_PER_HOUR"`
+	// SafetyMaxSpeedKmH is the fastest plausible movement between two location pings
+	SafetyMaxSpeedKmH float64 `mapstructure:"SAFETY_MAX_SPEED_KMH"`
+	// SafetyTeleportWindow is how soon after a ping a jump of more than
+	// SafetyTeleportMaxJumpKm counts as a teleport
+	SafetyTeleportWindow time.Duration `mapstructure:"SAFETY_TELEPORT_WINDOW"`
+	// SafetyTeleportMaxJumpKm is how far a user may move within SafetyTeleportWindow
+	SafetyTeleportMaxJumpKm float64 `mapstructure:"SAFETY_TELEPORT_MAX_JUMP_KM"`
+	// R2PrivateBucket stores uploads as private object references served through presigned URLs
+	R2PrivateBucket bool `mapstructure:"R2_PRIVATE_BUCKET"`
+	// R2PublicBaseURL is where public bucket objects are served, such as a CDN
+	// domain. Empty falls back to https://<bucket>.r2.dev.
+	R2PublicBaseURL string `mapstructure:"R2_PUBLIC_BASE_URL"`
+	// MediaURLExpiry is how long presigned media URLs in responses stay valid
+	MediaURLExpiry time.Duration `mapstructure:"MEDIA_URL_EXPIRY"`
+	// FeedDefaultRadius is the feed search radius in meters when a request doesn't ask for one
+	FeedDefaultRadius float64 `mapstructure:"FEED_DEFAULT_RADIUS"`
+	// FeedMaxRadius is the largest feed radius in meters; larger requests are clamped to it
+	FeedMaxRadius float64 `mapstructure:"FEED_MAX_RADIUS"`
+	// FeedRadiusStep rounds requested feed radii up to a multiple of this many meters
+	FeedRadiusStep float64 `mapstructure:"FEED_RADIUS_STEP"`
+	// LocationMinUsers is the fewest distinct users any aggregate location cell, cluster or count may cover
+	LocationMinUsers int `mapstructure:"LOCATION_MIN_USERS"`
+	// ModerationWords is a comma-separated list of words blocked in messages and captions
+	ModerationWords string `mapstructure:"MODERATION_WORDS"`
+	// ModerationWordsFile is a file of blocked words, one per line, added to ModerationWords
+	ModerationWordsFile string `mapstructure:"MODERATION_WORDS_FILE"`
+	// ModerationMode is "reject" or "mask": what happens to a story caption with a blocked word
+	ModerationMode string `mapstructure:"MODERATION_MODE"`
+	// UploadURLExpiry is how long presigned URLs from POST /uploads/presign accept the upload
+	UploadURLExpiry time.Duration `mapstructure:"UPLOAD_URL_EXPIRY"`
+	// UploadAllowedTypes is a comma-separated allowlist of content types POST /upload accepts, sniffed from the file itself
+	UploadAllowedTypes string `mapstructure:"UPLOAD_ALLOWED_TYPES"`
+	// UploadMaxImageMB is the largest non-video file POST /upload accepts, in megabytes
+	UploadMaxImageMB int `mapstructure:"UPLOAD_MAX_IMAGE_MB"`
+	// UploadMaxVideoMB is the largest video POST /upload accepts, in megabytes
+	UploadMaxVideoMB int `mapstructure:"UPLOAD_MAX_VIDEO_MB"`
+	// HubInstanceID names this instance's consumer group on the chat routing stream; defaults to the host name
+	HubInstanceID string `mapstructure:"HUB_INSTANCE_ID"`
+	// RateLimitGeneral caps requests per IP across all routes, as "<limit>-<period>" with period S, M, H or D
+	RateLimitGeneral string `mapstructure:"RATE_LIMIT_GENERAL"`
+	// RateLimitAuth caps sign-up, login, token and OTP requests per IP
+	RateLimitAuth string `mapstructure:"RATE_LIMIT_AUTH"`
+	// RateLimitStory caps stories posted per user
+	RateLimitStory string `mapstructure:"RATE_LIMIT_STORY"`
+	// RateLimitMessage caps message sends and history reads per user
+	RateLimitMessage string `mapstructure:"RATE_LIMIT_MESSAGE"`
+	// RequirePhoneVerification stops users posting stories or sending messages until they verify their phone
+	RequirePhoneVerification bool `mapstructure:"REQUIRE_PHONE_VERIFICATION"`
+	// AdminSeedUserID is promoted to admin at startup, so a new deployment has someone who can use the admin routes
+	AdminSeedUserID string `mapstructure:"ADMIN_SEED_USER_ID"`
+	// LogFormat is "console" for readable local logs or "json" for log shippers
+	LogFormat string `mapstructure:"LOG_FORMAT"`
+	// CorsAllowedOrigins lists the browser origins, comma-separated, allowed to call the API and open the chat WebSocket
+	CorsAllowedOrigins string `mapstructure:"CORS_ALLOWED_ORIGINS"`
+	// TrustedProxies lists the proxy IPs or CIDRs, comma-separated, whose X-Forwarded-For is believed; empty trusts none
+	TrustedProxies string `mapstructure:"TRUSTED_PROXIES"`
+}
+
+// MediaRetention is MediaRetentionDays as a duration
+func (c Config) MediaRetention() time.Duration {
+	return time.Duration(c.MediaRetentionDays) * 24 * time.Hour
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -30,6 +142,51 @@ func LoadConfig(path string) (config Config, err error) {
 
 	viper.AutomaticEnv()
 
+	viper.SetDefault("GROUP_MEMBERS_REQUIRE_CONNECTION", true)
+	viper.SetDefault("FEED_CONNECTIONS_ONLY", false)
+	viper.SetDefault("FEED_RESULT_LIMIT", 50)
+	viper.SetDefault("MAX_SESSIONS_PER_USER", 5)
+	viper.SetDefault("IMPERSONATION_TOKEN_DURATION", "15m")
+	viper.SetDefault("CROSSING_RADIUS_METERS", 80)
+	viper.SetDefault("CROSSING_MIN_DWELL", "0s")
+	viper.SetDefault("CROSSING_COOLDOWN", "24h")
+	viper.SetDefault("APP_DEEP_LINK_BASE", "locolive://")
+	viper.SetDefault("MESSAGE_DEFAULT_EXPIRY", "24h")
+	viper.SetDefault("MESSAGE_MIN_EXPIRY", "10s")
+	viper.SetDefault("MESSAGE_MAX_EXPIRY", "168h")
+	viper.SetDefault("MESSAGE_MAX_EXPIRY_PREMIUM", "720h")
+	viper.SetDefault("MESSAGE_SAVE_REQUIRES_PREMIUM", true)
+	viper.SetDefault("ANONYMOUS_STORY_DAILY_LIMIT", 3)
+	viper.SetDefault("REPORT_AUTO_HIDE_THRESHOLD", 3)
+	viper.SetDefault("SAFETY_BLOCK_THRESHOLD", 1.0)
+	viper.SetDefault("SAFETY_BAN_THRESHOLD", 1.5)
+	viper.SetDefault("SAFETY_BAN_STRIKES", 3)
+	viper.SetDefault("SAFETY_ENFORCE", true)
+	viper.SetDefault("SAFETY_MAX_STORIES_PER_HOUR", 20)
+	viper.SetDefault("SAFETY_MAX_SPEED_KMH", 1000)
+	viper.SetDefault("SAFETY_TELEPORT_WINDOW", "2m")
+	viper.SetDefault("SAFETY_TELEPORT_MAX_JUMP_KM", 150)
+	viper.SetDefault("R2_PRIVATE_BUCKET", false)
+	viper.SetDefault("R2_PUBLIC_BASE_URL", "")
+	viper.SetDefault("MEDIA_URL_EXPIRY", "1h")
+	viper.SetDefault("FEED_DEFAULT_RADIUS", 50000)
+	viper.SetDefault("FEED_MAX_RADIUS", 50000)
+	viper.SetDefault("FEED_RADIUS_STEP", 5000)
+	viper.SetDefault("LOCATION_MIN_USERS", 5)
+	viper.SetDefault("MODERATION_MODE", "reject")
+	viper.SetDefault("UPLOAD_URL_EXPIRY", "15m")
+	viper.SetDefault("UPLOAD_ALLOWED_TYPES", "image/jpeg,image/png,image/webp,video/mp4")
+	viper.SetDefault("UPLOAD_MAX_IMAGE_MB", 25)
+	viper.SetDefault("UPLOAD_MAX_VIDEO_MB", 100)
+	viper.SetDefault("REQUIRE_PHONE_VERIFICATION", false)
+	viper.SetDefault("RATE_LIMIT_GENERAL", "500-M")
+	viper.SetDefault("RATE_LIMIT_AUTH", "60-M")
+	viper.SetDefault("RATE_LIMIT_STORY", "50-H")
+	viper.SetDefault("RATE_LIMIT_MESSAGE", "200-M")
+	viper.SetDefault("LOG_FORMAT", "console")
+	viper.SetDefault("CORS_ALLOWED_ORIGINS", "http://localhost:5173,http://localhost:3000")
+	viper.SetDefault("TRUSTED_PROXIES", "")
+
 	err = viper.ReadInConfig()
 	if err != nil {
 		return
@@ -0,0 +1,92 @@
+package realtime
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// BatchMessageType wraps several queued messages in a single frame; its
+	// payload is a JSON array of complete messages for the client to unpack
+	BatchMessageType = "batch"
+
+	// MaxBatchSize caps how many messages are coalesced into one frame
+	MaxBatchSize = 32
+	// BatchFlushInterval is how long WritePump waits for more messages before
+	// flushing a partial batch
+	BatchFlushInterval = 10 * time.Millisecond
+
+	// BatchQueryParam and BatchHeader let clients opt in to batched frames when connecting
+	BatchQueryParam = "batch"
+	BatchHeader     = "X-WS-Batch"
+)
+
+// NegotiateBatching reports whether the client asked for batched frames.
+// Clients that don't ask keep receiving one message per frame.
+func NegotiateBatching(r *http.Request) bool {
+	raw := r.URL.Query().Get(BatchQueryParam)
+	if raw == "" {
+		raw = r.Header.Get(BatchHeader)
+	}
+
+	enabled, err := strconv.ParseBool(raw)
+	return err == nil && enabled
+}
+
+// collectBatch gathers first plus whatever else arrives on Send until the batch
+// is full or the flush interval passes. open is false once the hub closed Send.
+func (c *Client) collectBatch(first []byte) (batch [][]byte, open bool) {
+	batch = [][]byte{first}
+
+	timer := time.NewTimer(BatchFlushInterval)
+	defer timer.Stop()
+
+	for len(batch) < MaxBatchSize {
+		select {
+		case message, ok := <-c.Send:
+			if !ok {
+				return batch, false
+			}
+			batch = append(batch, message)
+		case <-timer.C:
+			return batch, true
+		}
+	}
+	return batch, true
+}
+
+// encodeBatch builds a single frame from a batch. A lone message is sent as-is.
+func encodeBatch(batch [][]byte) ([]byte, error) {
+	if len(batch) == 1 {
+		return batch[0], nil
+	}
+
+	messages := make([]json.RawMessage, len(batch))
+	for i, message := range batch {
+		messages[i] = message
+	}
+	return json.Marshal(WSMessage{
+		Type:      BatchMessageType,
+		Payload:   messages,
+		CreatedAt: util.Now(),
+	})
+}
+
+// writeBatch sends a batch as one frame, falling back to one frame per message
+// if any of them isn't valid JSON and so can't be embedded in the array
+func (c *Client) writeBatch(batch [][]byte) error {
+	frame, err := encodeBatch(batch)
+	if err != nil {
+		for _, message := range batch {
+			if err := c.writeFrame(message); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return c.writeFrame(frame)
+}
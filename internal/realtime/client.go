@@ -2,13 +2,20 @@ package realtime
 
 import (
 	"encoding/json"
+	"fmt"
+	"runtime/debug"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/util"
 )
 
+// pingPeriod is how often WritePump pings the connection and refreshes presence
+const pingPeriod = 54 * time.Second
+
 // Client represents a connected user
 type Client struct {
 	Hub      *Hub
@@ -16,20 +23,30 @@ type Client struct {
 	Conn     *websocket.Conn
 	Send     chan []byte
 	Username string
+	// Version is the schema version negotiated at connect time (see NegotiateVersion)
+	Version int
+	// Batch coalesces queued messages into "batch" frames (see NegotiateBatching)
+	Batch bool
 }
 
-// WSMessage defines the structure of WebSocket messages
+// WSMessage defines the structure of WebSocket messages.
+// When marshalled it also carries "v" (schema version) and "pv" (payload version).
 type WSMessage struct {
 	Type      string      `json:"type"` // "new_message", "typing", etc.
 	Payload   interface{} `json:"payload"`
 	SenderID  uuid.UUID   `json:"sender_id,omitempty"`
 	CreatedAt time.Time   `json:"created_at,omitempty"`
+	GroupID   *uuid.UUID  `json:"group_id,omitempty"` // Set for group messages so clients can route them
+	Muted     bool        `json:"muted,omitempty"`    // The recipient muted this conversation, so don't notify
 }
 
 // WritePump pumps messages from the hub to the websocket connection.
 func (c *Client) WritePump() {
-	ticker := time.NewTicker(54 * time.Second) // Ping period
+	ticker := time.NewTicker(pingPeriod)
 	defer func() {
+		if r := recover(); r != nil {
+			c.logPanic("write", r)
+		}
 		ticker.Stop()
 		c.Conn.Close()
 	}()
@@ -37,20 +54,28 @@ func (c *Client) WritePump() {
 	for {
 		select {
 		case message, ok := <-c.Send:
-			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second)) // Write wait
 			if !ok {
 				// The hub closed the channel.
+				c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
 
-			w, err := c.Conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
+			if !c.Batch {
+				c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second)) // Write wait
+				if err := c.writeFrame(message); err != nil {
+					return
+				}
+				continue
 			}
-			w.Write(message)
 
-			if err := w.Close(); err != nil {
+			batch, open := c.collectBatch(message)
+			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := c.writeBatch(batch); err != nil {
+				return
+			}
+			if !open {
+				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
 		case <-ticker.C:
@@ -58,13 +83,28 @@ func (c *Client) WritePump() {
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+			c.Hub.refreshPresence(c.UserID)
 		}
 	}
 }
 
+// writeFrame writes message as a single text frame
+func (c *Client) writeFrame(message []byte) error {
+	w, err := c.Conn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+	w.Write(message)
+	return w.Close()
+}
+
 // ReadPump pumps messages from the websocket connection to the hub.
 func (c *Client) ReadPump() {
 	defer func() {
+		// A panic while handling one frame drops this connection, not the server
+		if r := recover(); r != nil {
+			c.logPanic("read", r)
+		}
 		c.Hub.Unregister <- c
 		c.Conn.Close()
 	}()
@@ -84,24 +124,44 @@ func (c *Client) ReadPump() {
 			break
 		}
 
-		// Handle typing indicator messages
-		var wsMsg struct {
-			Type       string    `json:"type"`
-			ReceiverID uuid.UUID `json:"receiver_id"`
-		}
-		if err := json.Unmarshal(message, &wsMsg); err == nil {
-			if wsMsg.Type == "typing" {
-				// Forward typing indicator to the receiver
-				typingMsg := WSMessage{
-					Type: "typing",
-					Payload: map[string]interface{}{
-						"user_id":  c.UserID,
-						"username": c.Username,
-					},
-				}
-				typingBytes, _ := json.Marshal(typingMsg)
-				c.Hub.SendToUser(wsMsg.ReceiverID, typingBytes)
-			}
-		}
+		c.Hub.dispatcher.Dispatch(c, message)
+	}
+}
+
+// sendError reports a rejected inbound message back to this connection only
+func (c *Client) sendError(msgType, code, detail string) {
+	errBytes, err := json.Marshal(WSMessage{
+		Type: "error",
+		Payload: map[string]interface{}{
+			"code":    code,
+			"message": detail,
+			"type":    msgType,
+		},
+		CreatedAt: util.Now(),
+	})
+	if err != nil {
+		return
 	}
+
+	c.Hub.mutex.RLock()
+	defer c.Hub.mutex.RUnlock()
+
+	// The hub closes Send on unregister, so only write while still registered
+	if !c.Hub.clients[c.UserID][c] {
+		return
+	}
+	select {
+	case c.Send <- errBytes:
+	default:
+	}
+}
+
+// logPanic records a panic recovered in one of the client's pumps
+func (c *Client) logPanic(pump string, r any) {
+	log.Error().
+		Str("panic", fmt.Sprint(r)).
+		Str("stack", string(debug.Stack())).
+		Str("user_id", c.UserID.String()).
+		Str("pump", pump).
+		Msg("websocket pump panicked")
 }
@@ -0,0 +1,82 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// InboundMessage is implemented by every client→server event payload
+type InboundMessage interface {
+	Validate() error
+}
+
+// inboundHandler decodes, validates and handles one raw inbound message
+type inboundHandler func(c *Client, raw []byte) error
+
+// Dispatcher routes inbound WebSocket messages to the handler registered for their type
+type Dispatcher struct {
+	mu       sync.RWMutex
+	handlers map[string]inboundHandler
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		handlers: make(map[string]inboundHandler),
+	}
+}
+
+// errMalformed marks decode/validation failures so they are reported distinctly from handler errors
+var errMalformed = errors.New("malformed message")
+
+// Handle registers fn for msgType. The whole message is decoded into T and validated
+// before fn is called. Registering the same type twice replaces the earlier handler.
+func Handle[T InboundMessage](d *Dispatcher, msgType string, fn func(c *Client, msg T) error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.handlers[msgType] = func(c *Client, raw []byte) error {
+		var msg T
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			return fmt.Errorf("%w: %v", errMalformed, err)
+		}
+		if err := msg.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", errMalformed, err)
+		}
+		return fn(c, msg)
+	}
+}
+
+// Dispatch routes raw to its handler. Unknown, malformed or failing messages are
+// logged and reported back to the client; the connection is left open.
+func (d *Dispatcher) Dispatch(c *Client, raw []byte) {
+	var envelope struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil || envelope.Type == "" {
+		log.Warn().Str("user_id", c.UserID.String()).Msg("Dropping WebSocket message without a type")
+		c.sendError("", "malformed", "message must be a JSON object with a type")
+		return
+	}
+
+	d.mu.RLock()
+	handler, ok := d.handlers[envelope.Type]
+	d.mu.RUnlock()
+	if !ok {
+		log.Warn().Str("user_id", c.UserID.String()).Str("type", envelope.Type).Msg("Unknown WebSocket message type")
+		c.sendError(envelope.Type, "unknown_type", "unsupported message type")
+		return
+	}
+
+	if err := handler(c, raw); err != nil {
+		code := "handler_error"
+		if errors.Is(err, errMalformed) {
+			code = "malformed"
+		}
+		log.Warn().Err(err).Str("user_id", c.UserID.String()).Str("type", envelope.Type).Msg("Failed to handle WebSocket message")
+		c.sendError(envelope.Type, code, err.Error())
+	}
+}
@@ -2,16 +2,35 @@ package realtime
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/util"
 )
 
 const (
 	streamKey = "locolive:stream:routing"
+	// streamGroupPrefix names each instance's consumer group. Every instance needs
+	// every entry, so instances can't share one group; each has its own, which
+	// keeps its read position across restarts.
+	streamGroupPrefix = "hub:"
+	streamReadCount   = 10
+	streamBlock       = 2 * time.Second
+	streamRetryDelay  = 5 * time.Second
+	// streamGroupIdleCutoff is how long an instance's group may go unread before
+	// the others destroy it. Host names can change on every deploy, and each old
+	// name would otherwise leave a group behind holding the entries it never read.
+	streamGroupIdleCutoff = 24 * time.Hour
+	// streamGroupPruneInterval is how often each instance looks for idle groups
+	streamGroupPruneInterval = time.Hour
 )
 
 // Hub maintains the set of active clients and broadcasts messages to the
@@ -21,20 +40,64 @@ type Hub struct {
 	Unregister chan *Client
 	mutex      sync.RWMutex
 	redis      *redis.Client
+	dispatcher *Dispatcher
+	onOffline  OfflineFunc
+	// instanceID names this instance's stream consumer group and consumer
+	instanceID string
+	// ctx is cancelled by Stop to end the stream consumer
+	ctx     context.Context
+	stop    context.CancelFunc
+	running atomic.Bool
+	// streamDone is closed once the stream consumer has returned
+	streamDone chan struct{}
 }
 
+// NewHub creates a hub that reads the routing stream as this host. Use
+// SetInstanceID where host names don't survive a restart.
 func NewHub(rdb *redis.Client) *Hub {
+	instanceID, err := os.Hostname()
+	if err != nil || instanceID == "" {
+		instanceID = uuid.New().String()
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
 	return &Hub{
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
 		clients:    make(map[uuid.UUID]map[*Client]bool),
 		redis:      rdb,
+		dispatcher: NewDispatcher(),
+		instanceID: instanceID,
+		ctx:        ctx,
+		stop:       stop,
+		streamDone: make(chan struct{}),
+	}
+}
+
+// SetInstanceID sets the name this instance reads the routing stream under. It
+// must be stable across restarts for messages sent meanwhile to be picked up.
+// Set it before Run.
+func (h *Hub) SetInstanceID(id string) {
+	if id != "" {
+		h.instanceID = id
 	}
 }
 
+// Dispatcher returns the registry for inbound client messages
+func (h *Hub) Dispatcher() *Dispatcher {
+	return h.dispatcher
+}
+
+// Run registers and unregisters clients, and consumes the routing stream until
+// Stop is called
 func (h *Hub) Run() {
+	h.running.Store(true)
+
 	// Start consuming Redis Stream messages
-	go h.listenRedisStream()
+	go func() {
+		defer close(h.streamDone)
+		h.listenRedisStream(h.ctx)
+	}()
 
 	for {
 		select {
@@ -45,9 +108,11 @@ func (h *Hub) Run() {
 			}
 			h.clients[client.UserID][client] = true
 			h.mutex.Unlock()
+			h.refreshPresence(client.UserID)
 			log.Info().Str("username", client.Username).Msg("Client registered")
 
 		case client := <-h.Unregister:
+			offline := false
 			h.mutex.Lock()
 			if userClients, ok := h.clients[client.UserID]; ok {
 				if _, ok := userClients[client]; ok {
@@ -55,58 +120,224 @@ func (h *Hub) Run() {
 					close(client.Send)
 					if len(userClients) == 0 {
 						delete(h.clients, client.UserID)
+						offline = true
 					}
 				}
 			}
 			h.mutex.Unlock()
+			if offline {
+				h.clearPresence(client.UserID)
+			}
 			log.Info().Str("username", client.Username).Msg("Client unregistered")
 		}
 	}
 }
 
-// listenRedisStream pumps messages from Redis Stream to local clients
-func (h *Hub) listenRedisStream() {
-	// Start reading from the end of the stream ($)
-	lastID := "$"
+// Stop ends the stream consumer and waits for it to finish the entries it is
+// handling, or for ctx to be done. Clients can still register and unregister,
+// so connections closing during shutdown don't block.
+func (h *Hub) Stop(ctx context.Context) error {
+	h.stop()
+	if !h.running.Load() {
+		return nil
+	}
+	select {
+	case <-h.streamDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-	for {
-		// Block for up to 2 seconds waiting for new messages
-		streams, err := h.redis.XRead(context.Background(), &redis.XReadArgs{
-			Streams: []string{streamKey, lastID},
-			Count:   10,
-			Block:   2000 * time.Millisecond,
+// listenRedisStream pumps messages from Redis Stream to local clients. It reads
+// through this instance's consumer group, so entries published while it was
+// down are read when it comes back, and acknowledges each once handled.
+func (h *Hub) listenRedisStream(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := h.ensureStreamGroup(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to create Redis Stream consumer group")
+			sleepCtx(ctx, streamRetryDelay)
+			continue
+		}
+		break
+	}
+	h.claimPending(ctx)
+	h.pruneStreamGroups(ctx)
+	pruned := time.Now()
+
+	for ctx.Err() == nil {
+		if time.Since(pruned) >= streamGroupPruneInterval {
+			h.pruneStreamGroups(ctx)
+			pruned = time.Now()
+		}
+
+		streams, err := h.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    h.streamGroup(),
+			Consumer: h.instanceID,
+			Streams:  []string{util.RedisKey(streamKey), ">"},
+			Count:    streamReadCount,
+			Block:    streamBlock,
 		}).Result()
 
 		if err == redis.Nil {
 			continue // No new messages
 		}
 		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
 			log.Error().Err(err).Msg("Failed to read from Redis Stream")
-			time.Sleep(5 * time.Second) // Backoff on error
+			// The group goes with the stream if the key is deleted
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				if err := h.ensureStreamGroup(ctx); err != nil {
+					log.Error().Err(err).Msg("Failed to create Redis Stream consumer group")
+				}
+			}
+			sleepCtx(ctx, streamRetryDelay) // Backoff on error
 			continue
 		}
 
 		for _, stream := range streams {
 			for _, msg := range stream.Messages {
-				lastID = msg.ID
+				h.handleStreamEntry(ctx, msg)
+			}
+		}
+	}
+}
 
-				targetUserIDStr, ok := msg.Values["target_user_id"].(string)
-				if !ok {
-					continue
-				}
-				payload, ok := msg.Values["payload"].(string)
-				if !ok {
-					continue
-				}
+// sleepCtx waits for d, returning early once ctx is done
+func sleepCtx(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
 
-				userID, err := uuid.Parse(targetUserIDStr)
-				if err != nil {
-					continue
-				}
+// ConnectionCount is the number of WebSocket connections open on this instance
+func (h *Hub) ConnectionCount() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	n := 0
+	for _, userClients := range h.clients {
+		n += len(userClients)
+	}
+	return n
+}
+
+// StreamLag is how many routing stream entries this instance's consumer group
+// hasn't read yet. It is -1 when Redis can't tell, as before Redis 7.
+func (h *Hub) StreamLag(ctx context.Context) (int64, error) {
+	groups, err := h.redis.XInfoGroups(ctx, util.RedisKey(streamKey)).Result()
+	if err != nil {
+		return 0, err
+	}
+	for _, group := range groups {
+		if group.Name == h.streamGroup() {
+			return group.Lag, nil
+		}
+	}
+	return 0, fmt.Errorf("consumer group %s not found", h.streamGroup())
+}
 
-				h.broadcastToLocal(userID, []byte(payload))
+// streamGroup is this instance's consumer group on the routing stream
+func (h *Hub) streamGroup() string {
+	return streamGroupPrefix + h.instanceID
+}
+
+// ensureStreamGroup creates this instance's consumer group, and the stream if
+// needed. A new group starts at the end of the stream; an existing one keeps
+// its position.
+func (h *Hub) ensureStreamGroup(ctx context.Context) error {
+	err := h.redis.XGroupCreateMkStream(ctx, util.RedisKey(streamKey), h.streamGroup(), "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// pruneStreamGroups destroys other instances' consumer groups once none of
+// their consumers has read for streamGroupIdleCutoff. Groups without consumers
+// are left alone, as their instance may only just have created them.
+func (h *Hub) pruneStreamGroups(ctx context.Context) {
+	key := util.RedisKey(streamKey)
+	groups, err := h.redis.XInfoGroups(ctx, key).Result()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list Redis Stream consumer groups")
+		return
+	}
+
+	for _, group := range groups {
+		if !strings.HasPrefix(group.Name, streamGroupPrefix) || group.Name == h.streamGroup() {
+			continue
+		}
+		consumers, err := h.redis.XInfoConsumers(ctx, key, group.Name).Result()
+		if err != nil {
+			log.Error().Err(err).Str("group", group.Name).Msg("Failed to list Redis Stream consumers")
+			continue
+		}
+		if len(consumers) == 0 {
+			continue
+		}
+
+		idle := true
+		for _, consumer := range consumers {
+			if consumer.Idle < streamGroupIdleCutoff {
+				idle = false
+				break
 			}
 		}
+		if !idle {
+			continue
+		}
+
+		if err := h.redis.XGroupDestroy(ctx, key, group.Name).Err(); err != nil {
+			log.Error().Err(err).Str("group", group.Name).Msg("Failed to destroy idle Redis Stream consumer group")
+			continue
+		}
+		log.Info().Str("group", group.Name).Msg("Destroyed idle Redis Stream consumer group")
+	}
+}
+
+// claimPending handles entries this instance read but never acknowledged,
+// typically because it stopped while delivering them
+func (h *Hub) claimPending(ctx context.Context) {
+	start := "0-0"
+	for {
+		messages, next, err := h.redis.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   util.RedisKey(streamKey),
+			Group:    h.streamGroup(),
+			Consumer: h.instanceID,
+			Start:    start,
+			Count:    100,
+		}).Result()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to claim pending Redis Stream messages")
+			return
+		}
+
+		for _, msg := range messages {
+			h.handleStreamEntry(ctx, msg)
+		}
+		if next == "0-0" {
+			return
+		}
+		start = next
+	}
+}
+
+// handleStreamEntry delivers one routing entry to its user's local clients, if
+// any, and acknowledges it. Malformed entries are acknowledged and dropped.
+func (h *Hub) handleStreamEntry(ctx context.Context, msg redis.XMessage) {
+	targetUserIDStr, _ := msg.Values["target_user_id"].(string)
+	payload, ok := msg.Values["payload"].(string)
+	if userID, err := uuid.Parse(targetUserIDStr); err == nil && ok {
+		h.broadcastToLocal(userID, []byte(payload))
+	}
+
+	if err := h.redis.XAck(ctx, util.RedisKey(streamKey), h.streamGroup(), msg.ID).Err(); err != nil {
+		log.Error().Err(err).Str("id", msg.ID).Msg("Failed to acknowledge Redis Stream message")
 	}
 }
 
@@ -118,7 +349,7 @@ func (h *Hub) broadcastToLocal(userID uuid.UUID, message []byte) {
 	if clients, ok := h.clients[userID]; ok {
 		for client := range clients {
 			select {
-			case client.Send <- message:
+			case client.Send <- encodeFor(client.Version, message):
 			default:
 				close(client.Send)
 				delete(clients, client)
@@ -134,7 +365,7 @@ func (h *Hub) SendToUser(userID uuid.UUID, message []byte) {
 	// We use "*" to let Redis generate the ID
 	// We define fields "target_user_id" and "payload"
 	err := h.redis.XAdd(context.Background(), &redis.XAddArgs{
-		Stream: streamKey,
+		Stream: util.RedisKey(streamKey),
 		Values: map[string]interface{}{
 			"target_user_id": userID.String(),
 			"payload":        string(message),
@@ -148,12 +379,3 @@ func (h *Hub) SendToUser(userID uuid.UUID, message []byte) {
 		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to publish message to Redis Stream")
 	}
 }
-
-// IsUserOnline checks if a user has any active connections (Local check only for now)
-func (h *Hub) IsUserOnline(userID uuid.UUID) bool {
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
-
-	clients, ok := h.clients[userID]
-	return ok && len(clients) > 0
-}
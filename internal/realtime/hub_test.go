@@ -0,0 +1,147 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"privacy-social-backend/internal/util"
+)
+
+func TestListenRedisStreamResumes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	ctx := context.Background()
+
+	userID := uuid.New()
+	hub := NewHub(rdb)
+	hub.SetInstanceID("instance-a")
+	client := &Client{Hub: hub, UserID: userID, Send: make(chan []byte, 4)}
+	hub.clients[userID] = map[*Client]bool{client: true}
+
+	// The instance ran before, so its group exists; this was read but not acknowledged when it stopped
+	require.NoError(t, hub.ensureStreamGroup(ctx))
+	hub.SendToUser(userID, []byte(`{"type":"pending"}`))
+	_, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    hub.streamGroup(),
+		Consumer: "instance-a",
+		Streams:  []string{util.RedisKey(streamKey), ">"},
+	}).Result()
+	require.NoError(t, err)
+
+	// And this was published while it was down
+	hub.SendToUser(userID, []byte(`{"type":"missed"}`))
+
+	listenCtx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+	go hub.listenRedisStream(listenCtx)
+
+	for _, want := range []string{"pending", "missed"} {
+		select {
+		case msg := <-client.Send:
+			require.Contains(t, string(msg), want)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s message not delivered", want)
+		}
+	}
+
+	require.Eventually(t, func() bool {
+		pending, err := rdb.XPending(ctx, util.RedisKey(streamKey), hub.streamGroup()).Result()
+		return err == nil && pending.Count == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestStreamGroupPerInstance(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	// Instances never share a group, so each gets every entry; creating one twice is fine
+	a, b := NewHub(rdb), NewHub(rdb)
+	a.SetInstanceID("a")
+	b.SetInstanceID("b")
+	require.NotEqual(t, a.streamGroup(), b.streamGroup())
+	require.NoError(t, a.ensureStreamGroup(context.Background()))
+	require.NoError(t, a.ensureStreamGroup(context.Background()))
+	require.NoError(t, b.ensureStreamGroup(context.Background()))
+
+	groups, err := rdb.XInfoGroups(context.Background(), util.RedisKey(streamKey)).Result()
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+}
+
+func TestPruneStreamGroups(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	ctx := context.Background()
+
+	hubs := make(map[string]*Hub)
+	for _, id := range []string{"self", "gone", "live", "starting"} {
+		hubs[id] = NewHub(rdb)
+		hubs[id].SetInstanceID(id)
+		require.NoError(t, hubs[id].ensureStreamGroup(ctx))
+	}
+	// Some other reader of the stream, not a hub
+	require.NoError(t, rdb.XGroupCreate(ctx, util.RedisKey(streamKey), "audit", "$").Err())
+
+	// miniredis only tracks when a consumer was last seen on XCLAIM, so this
+	// stands in for the XREADGROUP a running hub makes every streamBlock
+	read := func(group, consumer string) {
+		err := rdb.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   util.RedisKey(streamKey),
+			Group:    group,
+			Consumer: consumer,
+			Messages: []string{"0-1"},
+		}).Err()
+		require.NoError(t, err)
+	}
+
+	start := time.Now()
+	mr.SetTime(start)
+	for _, id := range []string{"self", "gone", "live"} {
+		read(hubs[id].streamGroup(), id)
+	}
+	read("audit", "auditor")
+
+	// A day later only "live" and "self" are still reading
+	mr.SetTime(start.Add(streamGroupIdleCutoff + time.Minute))
+	read(hubs["live"].streamGroup(), "live")
+	hubs["self"].pruneStreamGroups(ctx)
+
+	groups, err := rdb.XInfoGroups(ctx, util.RedisKey(streamKey)).Result()
+	require.NoError(t, err)
+	var names []string
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	require.ElementsMatch(t, []string{"audit", "hub:self", "hub:live", "hub:starting"}, names)
+}
+
+func TestHubStop(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	// Stopping a hub that never ran doesn't wait
+	require.NoError(t, NewHub(rdb).Stop(context.Background()))
+
+	hub := NewHub(rdb)
+	go hub.Run()
+	require.Eventually(t, hub.running.Load, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, hub.Stop(ctx))
+
+	// Clients can still come and go after the consumer has stopped
+	client := &Client{Hub: hub, UserID: uuid.New(), Send: make(chan []byte, 1)}
+	hub.Register <- client
+	hub.Unregister <- client
+}
@@ -0,0 +1,73 @@
+package realtime
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// presenceTTL outlives the ping period, so the key only lapses when an
+	// instance dies without unregistering its clients
+	presenceTTL = 2 * pingPeriod
+	// presenceTimeout bounds presence writes made from the hub loop
+	presenceTimeout = 2 * time.Second
+)
+
+// OfflineFunc is called when a user's last connection to this instance closes
+type OfflineFunc func(userID uuid.UUID)
+
+// presenceKey marks a user as connected to some instance
+func presenceKey(userID uuid.UUID) string {
+	return util.RedisKey("presence:" + userID.String())
+}
+
+// OnOffline sets the function called when a user goes offline on this
+// instance. It runs in its own goroutine. Set it before Run.
+func (h *Hub) OnOffline(fn OfflineFunc) {
+	h.onOffline = fn
+}
+
+// refreshPresence marks userID online for another presenceTTL
+func (h *Hub) refreshPresence(userID uuid.UUID) {
+	ctx, cancel := context.WithTimeout(context.Background(), presenceTimeout)
+	defer cancel()
+
+	if err := h.redis.Set(ctx, presenceKey(userID), "1", presenceTTL).Err(); err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to refresh presence")
+	}
+}
+
+// clearPresence marks userID offline and records when they were last seen
+func (h *Hub) clearPresence(userID uuid.UUID) {
+	ctx, cancel := context.WithTimeout(context.Background(), presenceTimeout)
+	defer cancel()
+
+	if err := h.redis.Del(ctx, presenceKey(userID)).Err(); err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to clear presence")
+	}
+	if h.onOffline != nil {
+		go h.onOffline(userID)
+	}
+}
+
+// IsUserOnline reports whether the user is connected to any instance
+func (h *Hub) IsUserOnline(ctx context.Context, userID uuid.UUID) bool {
+	h.mutex.RLock()
+	local := len(h.clients[userID]) > 0
+	h.mutex.RUnlock()
+	if local {
+		return true
+	}
+
+	count, err := h.redis.Exists(ctx, presenceKey(userID)).Result()
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to read presence")
+		return false
+	}
+	return count > 0
+}
@@ -0,0 +1,146 @@
+package realtime
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// SchemaVersion is the newest WebSocket protocol version this server speaks.
+	// Bump it whenever an event payload changes shape and register a downgrade
+	// for that event with RegisterPayloadVersion.
+	SchemaVersion = 1
+	// MinSchemaVersion is assumed for clients that don't negotiate (older app builds)
+	MinSchemaVersion = 1
+
+	// SchemaVersionQueryParam and SchemaVersionHeader let clients announce the
+	// newest version they understand when connecting
+	SchemaVersionQueryParam = "v"
+	SchemaVersionHeader     = "X-WS-Version"
+)
+
+// Downgrader rewrites a payload from one version to the version before it
+type Downgrader func(payload json.RawMessage) (json.RawMessage, error)
+
+type downgradeKey struct {
+	msgType string
+	version int
+}
+
+var (
+	versionsMu      sync.RWMutex
+	payloadVersions = map[string]int{}
+	downgraders     = map[downgradeKey]Downgrader{}
+)
+
+// RegisterPayloadVersion records that msgType payloads are now emitted at version,
+// and how to turn a version payload into the shape of version-1 for older clients.
+// version must not exceed SchemaVersion; clients at SchemaVersion skip downgrading entirely.
+func RegisterPayloadVersion(msgType string, version int, downgrade Downgrader) {
+	if version > SchemaVersion {
+		panic("realtime: payload version " + strconv.Itoa(version) + " for " + msgType + " exceeds SchemaVersion")
+	}
+
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+
+	if version > payloadVersions[msgType] {
+		payloadVersions[msgType] = version
+	}
+	downgraders[downgradeKey{msgType: msgType, version: version}] = downgrade
+}
+
+// payloadVersion returns the current payload version for msgType (1 when never bumped)
+func payloadVersion(msgType string) int {
+	versionsMu.RLock()
+	defer versionsMu.RUnlock()
+
+	if v, ok := payloadVersions[msgType]; ok {
+		return v
+	}
+	return 1
+}
+
+// MarshalJSON stamps every outgoing message with the schema and payload versions
+func (m WSMessage) MarshalJSON() ([]byte, error) {
+	type plain WSMessage
+	return json.Marshal(struct {
+		plain
+		V  int `json:"v"`
+		PV int `json:"pv"`
+	}{
+		plain: plain(m),
+		V:     SchemaVersion,
+		PV:    payloadVersion(m.Type),
+	})
+}
+
+// NegotiateVersion reads the client's requested schema version from the
+// connect request, clamped to what the server supports.
+func NegotiateVersion(r *http.Request) int {
+	raw := r.URL.Query().Get(SchemaVersionQueryParam)
+	if raw == "" {
+		raw = r.Header.Get(SchemaVersionHeader)
+	}
+
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < MinSchemaVersion {
+		return MinSchemaVersion
+	}
+	if v > SchemaVersion {
+		return SchemaVersion
+	}
+	return v
+}
+
+// encodeFor adapts an outgoing message to the version a client negotiated.
+// Messages the client already understands are passed through untouched.
+func encodeFor(clientVersion int, message []byte) []byte {
+	if clientVersion >= SchemaVersion {
+		return message
+	}
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(message, &envelope); err != nil {
+		return message
+	}
+
+	var msgType string
+	var pv int
+	if err := json.Unmarshal(envelope["type"], &msgType); err != nil {
+		return message
+	}
+	if err := json.Unmarshal(envelope["pv"], &pv); err != nil || pv <= clientVersion {
+		return message
+	}
+
+	payload := envelope["payload"]
+	versionsMu.RLock()
+	for ; pv > clientVersion; pv-- {
+		downgrade, ok := downgraders[downgradeKey{msgType: msgType, version: pv}]
+		if !ok {
+			break
+		}
+		next, err := downgrade(payload)
+		if err != nil {
+			log.Warn().Err(err).Str("type", msgType).Int("pv", pv).Msg("Failed to downgrade WebSocket payload")
+			break
+		}
+		payload = next
+	}
+	versionsMu.RUnlock()
+
+	envelope["payload"] = payload
+	envelope["pv"], _ = json.Marshal(pv)
+	envelope["v"], _ = json.Marshal(clientVersion)
+
+	adapted, err := json.Marshal(envelope)
+	if err != nil {
+		return message
+	}
+	return adapted
+}
@@ -7,72 +7,144 @@ package db
 
 import (
 	"context"
-)
+	"time"
 
-const getConversionStats = `-- name: GetConversionStats :one
-WITH crossing_stats AS (
-    SELECT COUNT(*) as total_crossings FROM crossings
-),
-connection_stats AS (
-    SELECT COUNT(*) as total_connections FROM connections WHERE status = 'accepted'
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
-SELECT 
-    (SELECT total_connections FROM connection_stats) as total_connections,
-    (SELECT total_crossings FROM crossing_stats) as total_crossings,
-    -- Simple Ratio for now (Crossings -> Connections)
-    ((SELECT total_connections FROM connection_stats)::float / NULLIF((SELECT total_crossings FROM crossing_stats), 0)::float) * 100 as crossing_conversion_rate
-FROM crossing_stats, connection_stats
+
+const createAnalyticsEvents = `-- name: CreateAnalyticsEvents :execrows
+INSERT INTO analytics_events (stream_id, event_type, user_id, occurred_at)
+SELECT e.stream_id, e.event_type, e.user_id, e.occurred_at
+FROM unnest(
+    $1::text[],
+    $2::text[],
+    $3::uuid[],
+    $4::timestamptz[]
+) AS e(stream_id, event_type, user_id, occurred_at)
+WHERE EXISTS (SELECT 1 FROM users u WHERE u.id = e.user_id)
+ON CONFLICT (stream_id) DO NOTHING
 `
 
-type GetConversionStatsRow struct {
-	TotalConnections       int64 `json:"total_connections"`
-	TotalCrossings         int64 `json:"total_crossings"`
-	CrossingConversionRate int32 `json:"crossing_conversion_rate"`
+type CreateAnalyticsEventsParams struct {
+	StreamIds   []string    `json:"stream_ids"`
+	EventTypes  []string    `json:"event_types"`
+	UserIds     []uuid.UUID `json:"user_ids"`
+	OccurredAts []time.Time `json:"occurred_ats"`
 }
 
-func (q *Queries) GetConversionStats(ctx context.Context) (GetConversionStatsRow, error) {
-	row := q.db.QueryRowContext(ctx, getConversionStats)
-	var i GetConversionStatsRow
-	err := row.Scan(&i.TotalConnections, &i.TotalCrossings, &i.CrossingConversionRate)
-	return i, err
+// Inserts a batch read from the event stream. Entries already ingested and
+// events of accounts deleted in the meantime are skipped.
+func (q *Queries) CreateAnalyticsEvents(ctx context.Context, arg CreateAnalyticsEventsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createAnalyticsEvents,
+		pq.Array(arg.StreamIds),
+		pq.Array(arg.EventTypes),
+		pq.Array(arg.UserIds),
+		pq.Array(arg.OccurredAts),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const getEngagementStats = `-- name: GetEngagementStats :one
-SELECT 
-    COUNT(*) FILTER (WHERE created_at > NOW() - INTERVAL '7 days') as stories_last_7d,
-    (COUNT(*) FILTER (WHERE created_at > NOW() - INTERVAL '7 days')::float / NULLIF((SELECT COUNT(*) FROM users), 0)::float) as avg_stories_per_user_weekly
-FROM stories
+const deleteAnalyticsEventsBefore = `-- name: DeleteAnalyticsEventsBefore :execrows
+DELETE FROM analytics_events
+WHERE occurred_at < $1::timestamptz
 `
 
-type GetEngagementStatsRow struct {
-	StoriesLast7d           int64 `json:"stories_last_7d"`
-	AvgStoriesPerUserWeekly int32 `json:"avg_stories_per_user_weekly"`
+func (q *Queries) DeleteAnalyticsEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAnalyticsEventsBefore, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-func (q *Queries) GetEngagementStats(ctx context.Context) (GetEngagementStatsRow, error) {
-	row := q.db.QueryRowContext(ctx, getEngagementStats)
-	var i GetEngagementStatsRow
-	err := row.Scan(&i.StoriesLast7d, &i.AvgStoriesPerUserWeekly)
-	return i, err
+const getAnalyticsTotalsSince = `-- name: GetAnalyticsTotalsSince :many
+SELECT event_type, SUM(event_count)::bigint AS event_count
+FROM analytics_daily
+WHERE day >= $1::date
+GROUP BY event_type
+ORDER BY event_type
+`
+
+type GetAnalyticsTotalsSinceRow struct {
+	EventType  string `json:"event_type"`
+	EventCount int64  `json:"event_count"`
+}
+
+func (q *Queries) GetAnalyticsTotalsSince(ctx context.Context, since time.Time) ([]GetAnalyticsTotalsSinceRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAnalyticsTotalsSince, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAnalyticsTotalsSinceRow
+	for rows.Next() {
+		var i GetAnalyticsTotalsSinceRow
+		if err := rows.Scan(&i.EventType, &i.EventCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const getStreakRetentionStats = `-- name: GetStreakRetentionStats :one
-SELECT 
-    COUNT(*) FILTER (WHERE activity_streak >= 3) as retained_users_count,
-    COUNT(*) as total_users_count,
-    (COUNT(*) FILTER (WHERE activity_streak >= 3)::float / NULLIF(COUNT(*), 0)::float) * 100 as retention_rate
-FROM users
+const getSignupRetention = `-- name: GetSignupRetention :one
+SELECT
+    COUNT(*) AS cohort_size,
+    COUNT(*) FILTER (WHERE EXISTS (
+        SELECT 1 FROM analytics_events a
+        WHERE a.user_id = s.user_id
+          AND a.event_type <> 'signup'
+          AND a.occurred_at >= $1::timestamptz
+    )) AS retained_count
+FROM analytics_events s
+WHERE s.event_type = 'signup'
+  AND s.occurred_at >= $2::timestamptz
+  AND s.occurred_at < $3::timestamptz
 `
 
-type GetStreakRetentionStatsRow struct {
-	RetainedUsersCount int64 `json:"retained_users_count"`
-	TotalUsersCount    int64 `json:"total_users_count"`
-	RetentionRate      int32 `json:"retention_rate"`
+type GetSignupRetentionParams struct {
+	ActiveSince time.Time `json:"active_since"`
+	CohortStart time.Time `json:"cohort_start"`
+	CohortEnd   time.Time `json:"cohort_end"`
+}
+
+type GetSignupRetentionRow struct {
+	CohortSize    int64 `json:"cohort_size"`
+	RetainedCount int64 `json:"retained_count"`
 }
 
-func (q *Queries) GetStreakRetentionStats(ctx context.Context) (GetStreakRetentionStatsRow, error) {
-	row := q.db.QueryRowContext(ctx, getStreakRetentionStats)
-	var i GetStreakRetentionStatsRow
-	err := row.Scan(&i.RetainedUsersCount, &i.TotalUsersCount, &i.RetentionRate)
+// Of the users who signed up in [cohort_start, cohort_end), how many did
+// anything else since active_since
+func (q *Queries) GetSignupRetention(ctx context.Context, arg GetSignupRetentionParams) (GetSignupRetentionRow, error) {
+	row := q.db.QueryRowContext(ctx, getSignupRetention, arg.ActiveSince, arg.CohortStart, arg.CohortEnd)
+	var i GetSignupRetentionRow
+	err := row.Scan(&i.CohortSize, &i.RetainedCount)
 	return i, err
 }
+
+const rollupAnalyticsDaily = `-- name: RollupAnalyticsDaily :exec
+INSERT INTO analytics_daily (day, event_type, event_count, unique_users)
+SELECT occurred_at::date AS day, event_type, COUNT(*), COUNT(DISTINCT user_id)
+FROM analytics_events
+WHERE occurred_at >= $1::date
+GROUP BY 1, 2
+ON CONFLICT (day, event_type) DO UPDATE
+SET event_count = EXCLUDED.event_count,
+    unique_users = EXCLUDED.unique_users
+`
+
+// Recomputes the daily rollups of every day from from_day on
+func (q *Queries) RollupAnalyticsDaily(ctx context.Context, fromDay time.Time) error {
+	_, err := q.db.ExecContext(ctx, rollupAnalyticsDaily, fromDay)
+	return err
+}
@@ -10,11 +10,13 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"privacy-social-backend/internal/util"
 )
 
 const blockUser = `-- name: BlockUser :one
 INSERT INTO blocked_users (blocker_id, blocked_id)
 VALUES ($1, $2)
+ON CONFLICT (blocker_id, blocked_id) DO UPDATE SET blocked_id = EXCLUDED.blocked_id
 RETURNING id, blocker_id, blocked_id, created_at
 `
 
@@ -23,6 +25,7 @@ type BlockUserParams struct {
 	BlockedID uuid.UUID `json:"blocked_id"`
 }
 
+// Blocking someone already blocked returns the existing block
 func (q *Queries) BlockUser(ctx context.Context, arg BlockUserParams) (BlockedUser, error) {
 	row := q.db.QueryRowContext(ctx, blockUser, arg.BlockerID, arg.BlockedID)
 	var i BlockedUser
@@ -48,7 +51,7 @@ type GetBlockedUsersRow struct {
 	Username  string         `json:"username"`
 	FullName  string         `json:"full_name"`
 	AvatarUrl sql.NullString `json:"avatar_url"`
-	BlockedAt sql.NullTime   `json:"blocked_at"`
+	BlockedAt util.NullTime  `json:"blocked_at"`
 }
 
 func (q *Queries) GetBlockedUsers(ctx context.Context, blockerID uuid.UUID) ([]GetBlockedUsersRow, error) {
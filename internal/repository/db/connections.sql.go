@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"privacy-social-backend/internal/util"
 )
 
 const countConnectionRequestsToday = `-- name: CountConnectionRequestsToday :one
@@ -34,7 +35,7 @@ INSERT INTO connections (
   status
 ) VALUES (
   $1, $2, 'pending'
-) RETURNING requester_id, target_id, status, created_at, updated_at
+) RETURNING requester_id, target_id, status, created_at, updated_at, responded_at
 `
 
 type CreateConnectionRequestParams struct {
@@ -51,11 +52,17 @@ func (q *Queries) CreateConnectionRequest(ctx context.Context, arg CreateConnect
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RespondedAt,
 	)
 	return i, err
 }
 
 const deleteConnection = `-- name: DeleteConnection :exec
+WITH dropped_close_friends AS (
+  DELETE FROM close_friends
+  WHERE (user_id = $1 AND friend_id = $2)
+     OR (user_id = $2 AND friend_id = $1)
+)
 DELETE FROM connections
 WHERE (requester_id = $1 AND target_id = $2)
    OR (requester_id = $2 AND target_id = $1)
@@ -66,13 +73,14 @@ type DeleteConnectionParams struct {
 	TargetID    uuid.UUID `json:"target_id"`
 }
 
+// Close friends are a subset of connections, so disconnecting drops them both ways
 func (q *Queries) DeleteConnection(ctx context.Context, arg DeleteConnectionParams) error {
 	_, err := q.db.ExecContext(ctx, deleteConnection, arg.RequesterID, arg.TargetID)
 	return err
 }
 
 const getConnection = `-- name: GetConnection :one
-SELECT requester_id, target_id, status, created_at, updated_at FROM connections
+SELECT requester_id, target_id, status, created_at, updated_at, responded_at FROM connections
 WHERE (requester_id = $1 AND target_id = $2)
    OR (requester_id = $2 AND target_id = $1)
 LIMIT 1
@@ -92,6 +100,7 @@ func (q *Queries) GetConnection(ctx context.Context, arg GetConnectionParams) (C
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RespondedAt,
 	)
 	return i, err
 }
@@ -191,7 +200,7 @@ type ListConnectionsRow struct {
 	Username     string         `json:"username"`
 	FullName     string         `json:"full_name"`
 	AvatarUrl    sql.NullString `json:"avatar_url"`
-	LastActiveAt sql.NullTime   `json:"last_active_at"`
+	LastActiveAt util.NullTime  `json:"last_active_at"`
 }
 
 func (q *Queries) ListConnections(ctx context.Context, requesterID uuid.UUID) ([]ListConnectionsRow, error) {
@@ -223,6 +232,81 @@ func (q *Queries) ListConnections(ctx context.Context, requesterID uuid.UUID) ([
 	return items, nil
 }
 
+const listConnectionsByStatus = `-- name: ListConnectionsByStatus :many
+SELECT
+    c.requester_id,
+    c.target_id,
+    c.status,
+    c.created_at,
+    c.responded_at,
+    (c.requester_id = $1)::boolean AS is_outgoing,
+    u.id AS other_user_id,
+    u.username,
+    u.full_name,
+    u.avatar_url,
+    u.bio
+FROM connections c
+JOIN users u ON u.id = CASE WHEN c.requester_id = $1 THEN c.target_id ELSE c.requester_id END
+WHERE (c.requester_id = $1 OR c.target_id = $1)
+  AND c.status = $2
+ORDER BY COALESCE(c.responded_at, c.created_at) DESC
+`
+
+type ListConnectionsByStatusParams struct {
+	UserID uuid.UUID        `json:"user_id"`
+	Status ConnectionStatus `json:"status"`
+}
+
+type ListConnectionsByStatusRow struct {
+	RequesterID uuid.UUID        `json:"requester_id"`
+	TargetID    uuid.UUID        `json:"target_id"`
+	Status      ConnectionStatus `json:"status"`
+	CreatedAt   time.Time        `json:"created_at"`
+	RespondedAt util.NullTime    `json:"responded_at"`
+	IsOutgoing  bool             `json:"is_outgoing"`
+	OtherUserID uuid.UUID        `json:"other_user_id"`
+	Username    string           `json:"username"`
+	FullName    string           `json:"full_name"`
+	AvatarUrl   sql.NullString   `json:"avatar_url"`
+	Bio         sql.NullString   `json:"bio"`
+}
+
+// Relationships seen from the given user: direction tells incoming from outgoing
+func (q *Queries) ListConnectionsByStatus(ctx context.Context, arg ListConnectionsByStatusParams) ([]ListConnectionsByStatusRow, error) {
+	rows, err := q.db.QueryContext(ctx, listConnectionsByStatus, arg.UserID, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListConnectionsByStatusRow
+	for rows.Next() {
+		var i ListConnectionsByStatusRow
+		if err := rows.Scan(
+			&i.RequesterID,
+			&i.TargetID,
+			&i.Status,
+			&i.CreatedAt,
+			&i.RespondedAt,
+			&i.IsOutgoing,
+			&i.OtherUserID,
+			&i.Username,
+			&i.FullName,
+			&i.AvatarUrl,
+			&i.Bio,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listPendingRequests = `-- name: ListPendingRequests :many
 SELECT 
     c.requester_id, 
@@ -339,9 +423,11 @@ func (q *Queries) ListSentConnectionRequests(ctx context.Context, requesterID uu
 
 const updateConnectionStatus = `-- name: UpdateConnectionStatus :one
 UPDATE connections
-SET status = $3, updated_at = now()
+SET status = $3,
+    updated_at = now(),
+    responded_at = COALESCE(responded_at, CASE WHEN $3 != 'pending' THEN now() END)
 WHERE requester_id = $1 AND target_id = $2
-RETURNING requester_id, target_id, status, created_at, updated_at
+RETURNING requester_id, target_id, status, created_at, updated_at, responded_at
 `
 
 type UpdateConnectionStatusParams struct {
@@ -359,6 +445,7 @@ func (q *Queries) UpdateConnectionStatus(ctx context.Context, arg UpdateConnecti
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RespondedAt,
 	)
 	return i, err
 }
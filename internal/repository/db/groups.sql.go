@@ -92,6 +92,21 @@ func (q *Queries) CreateGroup(ctx context.Context, arg CreateGroupParams) (Group
 	return i, err
 }
 
+const deleteUserSoleGroups = `-- name: DeleteUserSoleGroups :exec
+DELETE FROM groups g
+WHERE g.created_by = $1
+  AND NOT EXISTS (
+    SELECT 1 FROM group_members gm
+    WHERE gm.group_id = g.id AND gm.user_id <> $1
+  )
+`
+
+// Deletes the groups a user created that have no other members
+func (q *Queries) DeleteUserSoleGroups(ctx context.Context, createdBy uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUserSoleGroups, createdBy)
+	return err
+}
+
 const getGroupByID = `-- name: GetGroupByID :one
 SELECT id, name, description, created_by, created_at, image_url FROM groups
 WHERE id = $1 LIMIT 1
@@ -207,3 +222,29 @@ func (q *Queries) RemoveGroupMember(ctx context.Context, arg RemoveGroupMemberPa
 	_, err := q.db.ExecContext(ctx, removeGroupMember, arg.GroupID, arg.UserID)
 	return err
 }
+
+const transferUserGroups = `-- name: TransferUserGroups :exec
+WITH successor AS (
+  SELECT DISTINCT ON (g.id) g.id AS group_id, gm.user_id
+  FROM groups g
+  JOIN group_members gm ON gm.group_id = g.id AND gm.user_id <> $1
+  WHERE g.created_by = $1
+  ORDER BY g.id, gm.joined_at
+), promoted AS (
+  UPDATE group_members gm
+  SET role = 'admin'
+  FROM successor s
+  WHERE gm.group_id = s.group_id AND gm.user_id = s.user_id
+)
+UPDATE groups g
+SET created_by = s.user_id
+FROM successor s
+WHERE g.id = s.group_id
+`
+
+// Hands each group a user created to its longest-standing other member, who
+// becomes an admin
+func (q *Queries) TransferUserGroups(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, transferUserGroups, userID)
+	return err
+}
@@ -7,9 +7,11 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createLocation = `-- name: CreateLocation :one
@@ -65,32 +67,116 @@ func (q *Queries) DeleteExpiredLocations(ctx context.Context) error {
 	return err
 }
 
-const getHeatmapData = `-- name: GetHeatmapData :many
-SELECT 
-  ST_X(ST_SnapToGrid(geom, 0.001)) as longitude,
-  ST_Y(ST_SnapToGrid(geom, 0.001)) as latitude,
-  COUNT(*) as weight
+const getHeatmapCells = `-- name: GetHeatmapCells :many
+SELECT
+  left(geohash, $1::int)::text AS cell,
+  array_agg(DISTINCT user_id)::uuid[] AS user_ids
 FROM locations
-WHERE time_bucket > NOW() - INTERVAL '1 hour'
-GROUP BY ST_SnapToGrid(geom, 0.001)
+WHERE time_bucket > $2::timestamptz
+AND geom && ST_MakeEnvelope($3::float8, $4::float8, $5::float8, $6::float8, 4326)
+GROUP BY 1
 `
 
-type GetHeatmapDataRow struct {
-	Longitude interface{} `json:"longitude"`
-	Latitude  interface{} `json:"latitude"`
-	Weight    int64       `json:"weight"`
+type GetHeatmapCellsParams struct {
+	Precision int32     `json:"precision"`
+	Since     time.Time `json:"since"`
+	West      float64   `json:"west"`
+	South     float64   `json:"south"`
+	East      float64   `json:"east"`
+	North     float64   `json:"north"`
 }
 
-func (q *Queries) GetHeatmapData(ctx context.Context) ([]GetHeatmapDataRow, error) {
-	rows, err := q.db.QueryContext(ctx, getHeatmapData)
+type GetHeatmapCellsRow struct {
+	Cell    string      `json:"cell"`
+	UserIds []uuid.UUID `json:"user_ids"`
+}
+
+// Distinct users per geohash cell of the given precision inside a box since a given time
+func (q *Queries) GetHeatmapCells(ctx context.Context, arg GetHeatmapCellsParams) ([]GetHeatmapCellsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getHeatmapCells,
+		arg.Precision,
+		arg.Since,
+		arg.West,
+		arg.South,
+		arg.East,
+		arg.North,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetHeatmapCellsRow
+	for rows.Next() {
+		var i GetHeatmapCellsRow
+		if err := rows.Scan(&i.Cell, pq.Array(&i.UserIds)); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNearbyUsers = `-- name: ListNearbyUsers :many
+SELECT u.id, u.username, u.full_name, u.avatar_url
+FROM users u
+LEFT JOIN privacy_settings ps ON ps.user_id = u.id
+WHERE u.id = ANY($1::uuid[])
+  AND u.id <> $2::uuid
+  AND u.is_ghost_mode = false
+  AND u.is_shadow_banned = false
+  AND COALESCE(ps.show_location, true) = true
+  AND (
+    COALESCE(u.profile_visibility, 'public') = 'public'
+    OR (u.profile_visibility = 'connections' AND EXISTS (
+      SELECT 1 FROM connections c
+      WHERE c.status = 'accepted'
+        AND ((c.requester_id = $2::uuid AND c.target_id = u.id)
+          OR (c.requester_id = u.id AND c.target_id = $2::uuid))
+    ))
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM blocked_users bu
+    WHERE (bu.blocker_id = $2::uuid AND bu.blocked_id = u.id)
+       OR (bu.blocker_id = u.id AND bu.blocked_id = $2::uuid)
+  )
+`
+
+type ListNearbyUsersParams struct {
+	UserIds  []uuid.UUID `json:"user_ids"`
+	ViewerID uuid.UUID   `json:"viewer_id"`
+}
+
+type ListNearbyUsersRow struct {
+	ID        uuid.UUID      `json:"id"`
+	Username  string         `json:"username"`
+	FullName  string         `json:"full_name"`
+	AvatarUrl sql.NullString `json:"avatar_url"`
+}
+
+// The users among user_ids that viewer_id may discover nearby: not ghosted or
+// shadow-banned, no block either way, sharing their location, and with a
+// profile the viewer can see
+func (q *Queries) ListNearbyUsers(ctx context.Context, arg ListNearbyUsersParams) ([]ListNearbyUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listNearbyUsers, pq.Array(arg.UserIds), arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetHeatmapDataRow
+	var items []ListNearbyUsersRow
 	for rows.Next() {
-		var i GetHeatmapDataRow
-		if err := rows.Scan(&i.Longitude, &i.Latitude, &i.Weight); err != nil {
+		var i ListNearbyUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.FullName,
+			&i.AvatarUrl,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -11,8 +11,98 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"privacy-social-backend/internal/util"
 )
 
+const clearExpiredMessageMedia = `-- name: ClearExpiredMessageMedia :execrows
+WITH cleared_attachments AS (
+  DELETE FROM message_attachments ma
+  USING messages m
+  WHERE ma.message_id = m.id
+    AND m.created_at < $1::timestamptz
+    AND NOT EXISTS (
+      SELECT 1 FROM media_objects mo
+      WHERE mo.url = ma.media_url AND mo.on_hold = true
+    )
+)
+UPDATE messages m
+SET media_url = NULL, media_type = NULL
+WHERE m.media_url IS NOT NULL
+  AND m.created_at < $1::timestamptz
+  AND NOT EXISTS (
+    SELECT 1 FROM media_objects mo
+    WHERE mo.url = m.media_url AND mo.on_hold = true
+  )
+`
+
+// Detaches media older than the retention policy from messages that outlive it,
+// unless the media is on hold. The media GC then purges the object.
+func (q *Queries) ClearExpiredMessageMedia(ctx context.Context, createdBefore time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, clearExpiredMessageMedia, createdBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countDirectMessageMatches = `-- name: CountDirectMessageMatches :one
+SELECT COUNT(*) FROM messages m
+WHERE ((m.sender_id = $1::uuid AND m.receiver_id = $2::uuid)
+    OR (m.sender_id = $2::uuid AND m.receiver_id = $1::uuid))
+  AND m.group_id IS NULL
+  AND (m.expires_at IS NULL OR m.expires_at > NOW())
+  AND m.content ILIKE '%' || $3::text || '%'
+`
+
+type CountDirectMessageMatchesParams struct {
+	UserID      uuid.UUID `json:"user_id"`
+	OtherUserID uuid.UUID `json:"other_user_id"`
+	Query       string    `json:"query"`
+}
+
+// How many messages SearchDirectMessages matches across all pages
+func (q *Queries) CountDirectMessageMatches(ctx context.Context, arg CountDirectMessageMatchesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countDirectMessageMatches, arg.UserID, arg.OtherUserID, arg.Query)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countGroupMessageMatches = `-- name: CountGroupMessageMatches :one
+SELECT COUNT(*) FROM messages m
+WHERE m.group_id = $1::uuid
+  AND (m.expires_at IS NULL OR m.expires_at > NOW())
+  AND m.content ILIKE '%' || $2::text || '%'
+`
+
+type CountGroupMessageMatchesParams struct {
+	GroupID uuid.UUID `json:"group_id"`
+	Query   string    `json:"query"`
+}
+
+// How many messages SearchGroupMessages matches across all pages
+func (q *Queries) CountGroupMessageMatches(ctx context.Context, arg CountGroupMessageMatchesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countGroupMessageMatches, arg.GroupID, arg.Query)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countMessageReplies = `-- name: CountMessageReplies :one
+SELECT COUNT(*) FROM messages
+WHERE reply_to_message_id = $1::uuid
+  AND (expires_at IS NULL OR expires_at > NOW())
+`
+
+// How many replies ListMessageReplies returns across all pages
+func (q *Queries) CountMessageReplies(ctx context.Context, messageID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countMessageReplies, messageID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createMessage = `-- name: CreateMessage :one
 INSERT INTO messages (
   sender_id,
@@ -21,20 +111,28 @@ INSERT INTO messages (
   content,
   media_url,
   media_type,
-  expires_at
+  expires_at,
+  reply_to_message_id,
+  forwarded_from_message_id,
+  forwarded_from_sender_id,
+  story_id
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7
-) RETURNING id, sender_id, receiver_id, content, is_read, created_at, read_at, expires_at, media_url, media_type, group_id
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+) RETURNING id, sender_id, receiver_id, content, is_read, created_at, read_at, expires_at, media_url, media_type, group_id, delivered_at, reply_to_message_id, forwarded_from_message_id, forwarded_from_sender_id, story_id
 `
 
 type CreateMessageParams struct {
-	SenderID   uuid.UUID      `json:"sender_id"`
-	ReceiverID uuid.NullUUID  `json:"receiver_id"`
-	GroupID    uuid.NullUUID  `json:"group_id"`
-	Content    string         `json:"content"`
-	MediaUrl   sql.NullString `json:"media_url"`
-	MediaType  sql.NullString `json:"media_type"`
-	ExpiresAt  sql.NullTime   `json:"expires_at"`
+	SenderID               uuid.UUID      `json:"sender_id"`
+	ReceiverID             uuid.NullUUID  `json:"receiver_id"`
+	GroupID                uuid.NullUUID  `json:"group_id"`
+	Content                string         `json:"content"`
+	MediaUrl               sql.NullString `json:"media_url"`
+	MediaType              sql.NullString `json:"media_type"`
+	ExpiresAt              util.NullTime  `json:"expires_at"`
+	ReplyToMessageID       uuid.NullUUID  `json:"reply_to_message_id"`
+	ForwardedFromMessageID uuid.NullUUID  `json:"forwarded_from_message_id"`
+	ForwardedFromSenderID  uuid.NullUUID  `json:"forwarded_from_sender_id"`
+	StoryID                uuid.NullUUID  `json:"story_id"`
 }
 
 func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error) {
@@ -46,6 +144,10 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		arg.MediaUrl,
 		arg.MediaType,
 		arg.ExpiresAt,
+		arg.ReplyToMessageID,
+		arg.ForwardedFromMessageID,
+		arg.ForwardedFromSenderID,
+		arg.StoryID,
 	)
 	var i Message
 	err := row.Scan(
@@ -60,15 +162,49 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		&i.MediaUrl,
 		&i.MediaType,
 		&i.GroupID,
+		&i.DeliveredAt,
+		&i.ReplyToMessageID,
+		&i.ForwardedFromMessageID,
+		&i.ForwardedFromSenderID,
+		&i.StoryID,
 	)
 	return i, err
 }
 
+const createMessageAttachments = `-- name: CreateMessageAttachments :exec
+INSERT INTO message_attachments (message_id, position, media_url, media_type)
+SELECT $1::uuid, a.ord - 1, a.media_url, a.media_type
+FROM unnest($2::text[], $3::text[])
+  WITH ORDINALITY AS a(media_url, media_type, ord)
+`
+
+type CreateMessageAttachmentsParams struct {
+	MessageID  uuid.UUID `json:"message_id"`
+	MediaUrls  []string  `json:"media_urls"`
+	MediaTypes []string  `json:"media_types"`
+}
+
+// Stores a message's attachments in the order given
+func (q *Queries) CreateMessageAttachments(ctx context.Context, arg CreateMessageAttachmentsParams) error {
+	_, err := q.db.ExecContext(ctx, createMessageAttachments, arg.MessageID, pq.Array(arg.MediaUrls), pq.Array(arg.MediaTypes))
+	return err
+}
+
 const createMessageReaction = `-- name: CreateMessageReaction :one
-INSERT INTO message_reactions (message_id, user_id, emoji)
-VALUES ($1, $2, $3)
-ON CONFLICT (message_id, user_id, emoji) DO NOTHING
-RETURNING id, message_id, user_id, emoji, created_at
+WITH removed AS (
+  DELETE FROM message_reactions
+  WHERE message_id = $1 AND user_id = $2 AND emoji = $3
+  RETURNING id, message_id, user_id, emoji, created_at
+), added AS (
+  INSERT INTO message_reactions (message_id, user_id, emoji)
+  SELECT $1, $2, $3
+  WHERE NOT EXISTS (SELECT 1 FROM removed)
+  ON CONFLICT (message_id, user_id, emoji) DO NOTHING
+  RETURNING id, message_id, user_id, emoji, created_at
+)
+SELECT id, message_id, user_id, emoji, created_at, true AS added FROM added
+UNION ALL
+SELECT id, message_id, user_id, emoji, created_at, false AS added FROM removed
 `
 
 type CreateMessageReactionParams struct {
@@ -77,15 +213,27 @@ type CreateMessageReactionParams struct {
 	Emoji     string    `json:"emoji"`
 }
 
-func (q *Queries) CreateMessageReaction(ctx context.Context, arg CreateMessageReactionParams) (MessageReaction, error) {
+type CreateMessageReactionRow struct {
+	ID        uuid.UUID `json:"id"`
+	MessageID uuid.UUID `json:"message_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Emoji     string    `json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+	Added     bool      `json:"added"`
+}
+
+// Toggles one emoji of a user's reactions: adds it, or removes it if the user
+// already reacted with it. Different emoji on the same message are kept apart.
+func (q *Queries) CreateMessageReaction(ctx context.Context, arg CreateMessageReactionParams) (CreateMessageReactionRow, error) {
 	row := q.db.QueryRowContext(ctx, createMessageReaction, arg.MessageID, arg.UserID, arg.Emoji)
-	var i MessageReaction
+	var i CreateMessageReactionRow
 	err := row.Scan(
 		&i.ID,
 		&i.MessageID,
 		&i.UserID,
 		&i.Emoji,
 		&i.CreatedAt,
+		&i.Added,
 	)
 	return i, err
 }
@@ -158,6 +306,51 @@ func (q *Queries) DeleteOldMessages(ctx context.Context) error {
 	return err
 }
 
+const getAggregatedReactions = `-- name: GetAggregatedReactions :many
+SELECT emoji,
+       COUNT(*) AS count,
+       bool_or(user_id = $1)::bool AS reacted_by_me
+FROM message_reactions
+WHERE message_id = $2
+GROUP BY emoji
+ORDER BY MIN(created_at)
+`
+
+type GetAggregatedReactionsParams struct {
+	ViewerID  uuid.UUID `json:"viewer_id"`
+	MessageID uuid.UUID `json:"message_id"`
+}
+
+type GetAggregatedReactionsRow struct {
+	Emoji       string `json:"emoji"`
+	Count       int64  `json:"count"`
+	ReactedByMe bool   `json:"reacted_by_me"`
+}
+
+// One row per emoji on a message, in the order they were first used
+func (q *Queries) GetAggregatedReactions(ctx context.Context, arg GetAggregatedReactionsParams) ([]GetAggregatedReactionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAggregatedReactions, arg.ViewerID, arg.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAggregatedReactionsRow
+	for rows.Next() {
+		var i GetAggregatedReactionsRow
+		if err := rows.Scan(&i.Emoji, &i.Count, &i.ReactedByMe); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getConversationList = `-- name: GetConversationList :many
 WITH conversation_partners AS (
   SELECT DISTINCT
@@ -182,7 +375,10 @@ latest_messages AS (
     m.id as message_id,
     m.content as last_message,
     m.created_at as last_message_at,
-    m.sender_id as last_sender_id
+    m.sender_id as last_sender_id,
+    m.media_url as last_message_media_url,
+    m.media_type as last_message_media_type,
+    m.expires_at as last_message_expires_at
   FROM messages m
   WHERE (m.sender_id = $1 OR m.receiver_id = $1)
     AND (m.expires_at IS NULL OR m.expires_at > NOW())
@@ -201,6 +397,9 @@ SELECT
   lm.last_message,
   lm.last_message_at,
   lm.last_sender_id,
+  lm.last_message_media_type,
+  (lm.last_message_media_url IS NOT NULL AND lm.last_message_media_url <> '') as last_message_is_media,
+  lm.last_message_expires_at,
   COALESCE(
     (SELECT COUNT(*) 
      FROM messages m2
@@ -209,7 +408,11 @@ SELECT
        AND m2.read_at IS NULL
        AND (m2.expires_at IS NULL OR m2.expires_at > NOW())
     ), 0
-  ) as unread_count
+  ) as unread_count,
+  EXISTS (
+    SELECT 1 FROM muted_conversations mc
+    WHERE mc.user_id = $1 AND mc.target_user_id = u.id
+  ) as muted
 FROM conversation_partners cp
 JOIN users u ON u.id = cp.partner_id
 JOIN latest_messages lm ON lm.partner_id = cp.partner_id
@@ -217,14 +420,18 @@ ORDER BY lm.last_message_at DESC
 `
 
 type GetConversationListRow struct {
-	ID            uuid.UUID      `json:"id"`
-	Username      string         `json:"username"`
-	FullName      string         `json:"full_name"`
-	AvatarUrl     sql.NullString `json:"avatar_url"`
-	LastMessage   string         `json:"last_message"`
-	LastMessageAt time.Time      `json:"last_message_at"`
-	LastSenderID  uuid.UUID      `json:"last_sender_id"`
-	UnreadCount   interface{}    `json:"unread_count"`
+	ID                   uuid.UUID      `json:"id"`
+	Username             string         `json:"username"`
+	FullName             string         `json:"full_name"`
+	AvatarUrl            sql.NullString `json:"avatar_url"`
+	LastMessage          string         `json:"last_message"`
+	LastMessageAt        time.Time      `json:"last_message_at"`
+	LastSenderID         uuid.UUID      `json:"last_sender_id"`
+	LastMessageMediaType sql.NullString `json:"last_message_media_type"`
+	LastMessageIsMedia   bool           `json:"last_message_is_media"`
+	LastMessageExpiresAt util.NullTime  `json:"last_message_expires_at"`
+	UnreadCount          interface{}    `json:"unread_count"`
+	Muted                bool           `json:"muted"`
 }
 
 func (q *Queries) GetConversationList(ctx context.Context, receiverID uuid.NullUUID) ([]GetConversationListRow, error) {
@@ -244,7 +451,11 @@ func (q *Queries) GetConversationList(ctx context.Context, receiverID uuid.NullU
 			&i.LastMessage,
 			&i.LastMessageAt,
 			&i.LastSenderID,
+			&i.LastMessageMediaType,
+			&i.LastMessageIsMedia,
+			&i.LastMessageExpiresAt,
 			&i.UnreadCount,
+			&i.Muted,
 		); err != nil {
 			return nil, err
 		}
@@ -260,7 +471,7 @@ func (q *Queries) GetConversationList(ctx context.Context, receiverID uuid.NullU
 }
 
 const getGroupMessages = `-- name: GetGroupMessages :many
-SELECT m.id, m.sender_id, m.receiver_id, m.content, m.is_read, m.created_at, m.read_at, m.expires_at, m.media_url, m.media_type, m.group_id, 
+SELECT m.id, m.sender_id, m.receiver_id, m.content, m.is_read, m.created_at, m.read_at, m.expires_at, m.media_url, m.media_type, m.group_id, m.delivered_at, m.reply_to_message_id, m.forwarded_from_message_id, m.forwarded_from_sender_id, m.story_id, 
        u.username, 
        u.avatar_url,
        COALESCE(
@@ -276,7 +487,31 @@ SELECT m.id, m.sender_id, m.receiver_id, m.content, m.is_read, m.created_at, m.r
             JOIN users reaction_user ON mr.user_id = reaction_user.id
             WHERE mr.message_id = m.id),
            '[]'::json
-       ) as reactions
+       ) as reactions,
+       COALESCE(
+           (SELECT json_agg(json_build_object(
+               'url', ma.media_url,
+               'type', ma.media_type
+           ) ORDER BY ma.position)
+            FROM message_attachments ma
+            WHERE ma.message_id = m.id),
+           '[]'::json
+       ) as attachments,
+       (SELECT json_build_object(
+           'id', p.id,
+           'sender_id', p.sender_id,
+           'username', pu.username,
+           'content', p.content,
+           'media_type', p.media_type
+        )
+        FROM messages p
+        JOIN users pu ON p.sender_id = pu.id
+        WHERE p.id = m.reply_to_message_id
+          AND (p.expires_at IS NULL OR p.expires_at > NOW())) as reply_to,
+       (SELECT COUNT(*)
+        FROM messages r
+        WHERE r.reply_to_message_id = m.id
+          AND (r.expires_at IS NULL OR r.expires_at > NOW())) as reply_count
 FROM messages m
 JOIN users u ON m.sender_id = u.id
 WHERE m.group_id = $1
@@ -284,20 +519,28 @@ ORDER BY m.created_at ASC
 `
 
 type GetGroupMessagesRow struct {
-	ID         uuid.UUID      `json:"id"`
-	SenderID   uuid.UUID      `json:"sender_id"`
-	ReceiverID uuid.NullUUID  `json:"receiver_id"`
-	Content    string         `json:"content"`
-	IsRead     bool           `json:"is_read"`
-	CreatedAt  time.Time      `json:"created_at"`
-	ReadAt     sql.NullTime   `json:"read_at"`
-	ExpiresAt  sql.NullTime   `json:"expires_at"`
-	MediaUrl   sql.NullString `json:"media_url"`
-	MediaType  sql.NullString `json:"media_type"`
-	GroupID    uuid.NullUUID  `json:"group_id"`
-	Username   string         `json:"username"`
-	AvatarUrl  sql.NullString `json:"avatar_url"`
-	Reactions  interface{}    `json:"reactions"`
+	ID                     uuid.UUID      `json:"id"`
+	SenderID               uuid.UUID      `json:"sender_id"`
+	ReceiverID             uuid.NullUUID  `json:"receiver_id"`
+	Content                string         `json:"content"`
+	IsRead                 bool           `json:"is_read"`
+	CreatedAt              time.Time      `json:"created_at"`
+	ReadAt                 util.NullTime  `json:"read_at"`
+	ExpiresAt              util.NullTime  `json:"expires_at"`
+	MediaUrl               sql.NullString `json:"media_url"`
+	MediaType              sql.NullString `json:"media_type"`
+	GroupID                uuid.NullUUID  `json:"group_id"`
+	DeliveredAt            util.NullTime  `json:"delivered_at"`
+	ReplyToMessageID       uuid.NullUUID  `json:"reply_to_message_id"`
+	ForwardedFromMessageID uuid.NullUUID  `json:"forwarded_from_message_id"`
+	ForwardedFromSenderID  uuid.NullUUID  `json:"forwarded_from_sender_id"`
+	StoryID                uuid.NullUUID  `json:"story_id"`
+	Username               string         `json:"username"`
+	AvatarUrl              sql.NullString `json:"avatar_url"`
+	Reactions              interface{}    `json:"reactions"`
+	Attachments            interface{}    `json:"attachments"`
+	ReplyTo                interface{}    `json:"reply_to"`
+	ReplyCount             int64          `json:"reply_count"`
 }
 
 func (q *Queries) GetGroupMessages(ctx context.Context, groupID uuid.NullUUID) ([]GetGroupMessagesRow, error) {
@@ -321,9 +564,17 @@ func (q *Queries) GetGroupMessages(ctx context.Context, groupID uuid.NullUUID) (
 			&i.MediaUrl,
 			&i.MediaType,
 			&i.GroupID,
+			&i.DeliveredAt,
+			&i.ReplyToMessageID,
+			&i.ForwardedFromMessageID,
+			&i.ForwardedFromSenderID,
+			&i.StoryID,
 			&i.Username,
 			&i.AvatarUrl,
 			&i.Reactions,
+			&i.Attachments,
+			&i.ReplyTo,
+			&i.ReplyCount,
 		); err != nil {
 			return nil, err
 		}
@@ -339,7 +590,7 @@ func (q *Queries) GetGroupMessages(ctx context.Context, groupID uuid.NullUUID) (
 }
 
 const getMessage = `-- name: GetMessage :one
-SELECT id, sender_id, receiver_id, content, is_read, created_at, read_at, expires_at, media_url, media_type, group_id FROM messages WHERE id = $1
+SELECT id, sender_id, receiver_id, content, is_read, created_at, read_at, expires_at, media_url, media_type, group_id, delivered_at, reply_to_message_id, forwarded_from_message_id, forwarded_from_sender_id, story_id FROM messages WHERE id = $1
 `
 
 func (q *Queries) GetMessage(ctx context.Context, id uuid.UUID) (Message, error) {
@@ -357,6 +608,11 @@ func (q *Queries) GetMessage(ctx context.Context, id uuid.UUID) (Message, error)
 		&i.MediaUrl,
 		&i.MediaType,
 		&i.GroupID,
+		&i.DeliveredAt,
+		&i.ReplyToMessageID,
+		&i.ForwardedFromMessageID,
+		&i.ForwardedFromSenderID,
+		&i.StoryID,
 	)
 	return i, err
 }
@@ -411,11 +667,101 @@ func (q *Queries) GetMessageReactions(ctx context.Context, messageID uuid.UUID)
 	return items, nil
 }
 
+const getMessageReplyPreview = `-- name: GetMessageReplyPreview :one
+SELECT m.id, m.sender_id, u.username, m.content, m.media_type
+FROM messages m
+JOIN users u ON m.sender_id = u.id
+WHERE m.id = $1
+  AND (m.expires_at IS NULL OR m.expires_at > NOW())
+`
+
+type GetMessageReplyPreviewRow struct {
+	ID        uuid.UUID      `json:"id"`
+	SenderID  uuid.UUID      `json:"sender_id"`
+	Username  string         `json:"username"`
+	Content   string         `json:"content"`
+	MediaType sql.NullString `json:"media_type"`
+}
+
+// What a reply shows of the message it quotes; no row once that message is
+// deleted or expired
+func (q *Queries) GetMessageReplyPreview(ctx context.Context, id uuid.UUID) (GetMessageReplyPreviewRow, error) {
+	row := q.db.QueryRowContext(ctx, getMessageReplyPreview, id)
+	var i GetMessageReplyPreviewRow
+	err := row.Scan(
+		&i.ID,
+		&i.SenderID,
+		&i.Username,
+		&i.Content,
+		&i.MediaType,
+	)
+	return i, err
+}
+
+const getMessageStatus = `-- name: GetMessageStatus :many
+SELECT id, sender_id, receiver_id, delivered_at, read_at
+FROM messages
+WHERE id = ANY($1::uuid[])
+  AND (sender_id = $2 OR receiver_id = $2)
+ORDER BY created_at
+`
+
+type GetMessageStatusParams struct {
+	MessageIds []uuid.UUID `json:"message_ids"`
+	UserID     uuid.UUID   `json:"user_id"`
+}
+
+type GetMessageStatusRow struct {
+	ID          uuid.UUID     `json:"id"`
+	SenderID    uuid.UUID     `json:"sender_id"`
+	ReceiverID  uuid.NullUUID `json:"receiver_id"`
+	DeliveredAt util.NullTime `json:"delivered_at"`
+	ReadAt      util.NullTime `json:"read_at"`
+}
+
+// Delivery and read state of messages the user sent or received, so a
+// reconnecting client can catch up on receipts it missed
+func (q *Queries) GetMessageStatus(ctx context.Context, arg GetMessageStatusParams) ([]GetMessageStatusRow, error) {
+	rows, err := q.db.QueryContext(ctx, getMessageStatus, pq.Array(arg.MessageIds), arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMessageStatusRow
+	for rows.Next() {
+		var i GetMessageStatusRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SenderID,
+			&i.ReceiverID,
+			&i.DeliveredAt,
+			&i.ReadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUnreadMessageCount = `-- name: GetUnreadMessageCount :one
 SELECT COUNT(*) FROM messages
 WHERE receiver_id = $1 AND read_at IS NULL
+  AND (expires_at IS NULL OR expires_at > NOW())
+  AND NOT EXISTS (
+    SELECT 1 FROM muted_conversations mc
+    WHERE mc.user_id = $1 AND mc.target_user_id = messages.sender_id
+  )
 `
 
+// Expired messages and those from senders the receiver muted don't count
+// toward the badge
 func (q *Queries) GetUnreadMessageCount(ctx context.Context, receiverID uuid.NullUUID) (int64, error) {
 	row := q.db.QueryRowContext(ctx, getUnreadMessageCount, receiverID)
 	var count int64
@@ -423,8 +769,126 @@ func (q *Queries) GetUnreadMessageCount(ctx context.Context, receiverID uuid.Nul
 	return count, err
 }
 
+const hasStoryReply = `-- name: HasStoryReply :one
+SELECT EXISTS (
+  SELECT 1 FROM messages
+  WHERE story_id = $1 AND sender_id = $2
+)
+`
+
+type HasStoryReplyParams struct {
+	StoryID  uuid.NullUUID `json:"story_id"`
+	SenderID uuid.UUID     `json:"sender_id"`
+}
+
+// Whether sender_id already replied to the story
+func (q *Queries) HasStoryReply(ctx context.Context, arg HasStoryReplyParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, hasStoryReply, arg.StoryID, arg.SenderID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listMessageAttachments = `-- name: ListMessageAttachments :many
+SELECT message_id, position, media_url, media_type FROM message_attachments
+WHERE message_id = $1
+ORDER BY position
+`
+
+func (q *Queries) ListMessageAttachments(ctx context.Context, messageID uuid.UUID) ([]MessageAttachment, error) {
+	rows, err := q.db.QueryContext(ctx, listMessageAttachments, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MessageAttachment
+	for rows.Next() {
+		var i MessageAttachment
+		if err := rows.Scan(
+			&i.MessageID,
+			&i.Position,
+			&i.MediaUrl,
+			&i.MediaType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMessageReplies = `-- name: ListMessageReplies :many
+SELECT m.id, m.sender_id, u.username, u.avatar_url, m.content, m.media_url,
+       m.media_type, m.created_at, m.expires_at
+FROM messages m
+JOIN users u ON m.sender_id = u.id
+WHERE m.reply_to_message_id = $1::uuid
+  AND (m.expires_at IS NULL OR m.expires_at > NOW())
+ORDER BY m.created_at ASC
+LIMIT $2 OFFSET $3
+`
+
+type ListMessageRepliesParams struct {
+	MessageID uuid.UUID `json:"message_id"`
+	Limit     int32     `json:"limit"`
+	Offset    int32     `json:"offset"`
+}
+
+type ListMessageRepliesRow struct {
+	ID        uuid.UUID      `json:"id"`
+	SenderID  uuid.UUID      `json:"sender_id"`
+	Username  string         `json:"username"`
+	AvatarUrl sql.NullString `json:"avatar_url"`
+	Content   string         `json:"content"`
+	MediaUrl  sql.NullString `json:"media_url"`
+	MediaType sql.NullString `json:"media_type"`
+	CreatedAt time.Time      `json:"created_at"`
+	ExpiresAt util.NullTime  `json:"expires_at"`
+}
+
+// Unexpired replies to a message, oldest first. Deleted replies are gone
+// from the table, so they drop out on their own.
+func (q *Queries) ListMessageReplies(ctx context.Context, arg ListMessageRepliesParams) ([]ListMessageRepliesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listMessageReplies, arg.MessageID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMessageRepliesRow
+	for rows.Next() {
+		var i ListMessageRepliesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SenderID,
+			&i.Username,
+			&i.AvatarUrl,
+			&i.Content,
+			&i.MediaUrl,
+			&i.MediaType,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listMessages = `-- name: ListMessages :many
-SELECT m.id, m.sender_id, m.receiver_id, m.content, m.is_read, m.created_at, m.read_at, m.expires_at, m.media_url, m.media_type, m.group_id,
+SELECT m.id, m.sender_id, m.receiver_id, m.content, m.is_read, m.created_at, m.read_at, m.expires_at, m.media_url, m.media_type, m.group_id, m.delivered_at, m.reply_to_message_id, m.forwarded_from_message_id, m.forwarded_from_sender_id, m.story_id,
        COALESCE(
            (SELECT json_agg(json_build_object(
                'id', mr.id,
@@ -438,7 +902,31 @@ SELECT m.id, m.sender_id, m.receiver_id, m.content, m.is_read, m.created_at, m.r
             JOIN users u ON mr.user_id = u.id
             WHERE mr.message_id = m.id),
            '[]'::json
-       ) as reactions
+       ) as reactions,
+       COALESCE(
+           (SELECT json_agg(json_build_object(
+               'url', ma.media_url,
+               'type', ma.media_type
+           ) ORDER BY ma.position)
+            FROM message_attachments ma
+            WHERE ma.message_id = m.id),
+           '[]'::json
+       ) as attachments,
+       (SELECT json_build_object(
+           'id', p.id,
+           'sender_id', p.sender_id,
+           'username', pu.username,
+           'content', p.content,
+           'media_type', p.media_type
+        )
+        FROM messages p
+        JOIN users pu ON p.sender_id = pu.id
+        WHERE p.id = m.reply_to_message_id
+          AND (p.expires_at IS NULL OR p.expires_at > NOW())) as reply_to,
+       (SELECT COUNT(*)
+        FROM messages r
+        WHERE r.reply_to_message_id = m.id
+          AND (r.expires_at IS NULL OR r.expires_at > NOW())) as reply_count
 FROM messages m
 WHERE ((m.sender_id = $1 AND m.receiver_id = $2)
    OR (m.sender_id = $2 AND m.receiver_id = $1))
@@ -453,18 +941,26 @@ type ListMessagesParams struct {
 }
 
 type ListMessagesRow struct {
-	ID         uuid.UUID      `json:"id"`
-	SenderID   uuid.UUID      `json:"sender_id"`
-	ReceiverID uuid.NullUUID  `json:"receiver_id"`
-	Content    string         `json:"content"`
-	IsRead     bool           `json:"is_read"`
-	CreatedAt  time.Time      `json:"created_at"`
-	ReadAt     sql.NullTime   `json:"read_at"`
-	ExpiresAt  sql.NullTime   `json:"expires_at"`
-	MediaUrl   sql.NullString `json:"media_url"`
-	MediaType  sql.NullString `json:"media_type"`
-	GroupID    uuid.NullUUID  `json:"group_id"`
-	Reactions  interface{}    `json:"reactions"`
+	ID                     uuid.UUID      `json:"id"`
+	SenderID               uuid.UUID      `json:"sender_id"`
+	ReceiverID             uuid.NullUUID  `json:"receiver_id"`
+	Content                string         `json:"content"`
+	IsRead                 bool           `json:"is_read"`
+	CreatedAt              time.Time      `json:"created_at"`
+	ReadAt                 util.NullTime  `json:"read_at"`
+	ExpiresAt              util.NullTime  `json:"expires_at"`
+	MediaUrl               sql.NullString `json:"media_url"`
+	MediaType              sql.NullString `json:"media_type"`
+	GroupID                uuid.NullUUID  `json:"group_id"`
+	DeliveredAt            util.NullTime  `json:"delivered_at"`
+	ReplyToMessageID       uuid.NullUUID  `json:"reply_to_message_id"`
+	ForwardedFromMessageID uuid.NullUUID  `json:"forwarded_from_message_id"`
+	ForwardedFromSenderID  uuid.NullUUID  `json:"forwarded_from_sender_id"`
+	StoryID                uuid.NullUUID  `json:"story_id"`
+	Reactions              interface{}    `json:"reactions"`
+	Attachments            interface{}    `json:"attachments"`
+	ReplyTo                interface{}    `json:"reply_to"`
+	ReplyCount             int64          `json:"reply_count"`
 }
 
 func (q *Queries) ListMessages(ctx context.Context, arg ListMessagesParams) ([]ListMessagesRow, error) {
@@ -488,7 +984,15 @@ func (q *Queries) ListMessages(ctx context.Context, arg ListMessagesParams) ([]L
 			&i.MediaUrl,
 			&i.MediaType,
 			&i.GroupID,
+			&i.DeliveredAt,
+			&i.ReplyToMessageID,
+			&i.ForwardedFromMessageID,
+			&i.ForwardedFromSenderID,
+			&i.StoryID,
 			&i.Reactions,
+			&i.Attachments,
+			&i.ReplyTo,
+			&i.ReplyCount,
 		); err != nil {
 			return nil, err
 		}
@@ -503,10 +1007,11 @@ func (q *Queries) ListMessages(ctx context.Context, arg ListMessagesParams) ([]L
 	return items, nil
 }
 
-const markConversationRead = `-- name: MarkConversationRead :exec
+const markConversationRead = `-- name: MarkConversationRead :many
 UPDATE messages
-SET read_at = NOW()
+SET read_at = NOW(), delivered_at = COALESCE(delivered_at, NOW())
 WHERE receiver_id = $1 AND sender_id = $2 AND read_at IS NULL
+RETURNING id, read_at
 `
 
 type MarkConversationReadParams struct {
@@ -514,16 +1019,40 @@ type MarkConversationReadParams struct {
 	SenderID   uuid.UUID     `json:"sender_id"`
 }
 
-func (q *Queries) MarkConversationRead(ctx context.Context, arg MarkConversationReadParams) error {
-	_, err := q.db.ExecContext(ctx, markConversationRead, arg.ReceiverID, arg.SenderID)
-	return err
+type MarkConversationReadRow struct {
+	ID     uuid.UUID     `json:"id"`
+	ReadAt util.NullTime `json:"read_at"`
+}
+
+// Returns the messages this call marked, for per-message read receipts
+func (q *Queries) MarkConversationRead(ctx context.Context, arg MarkConversationReadParams) ([]MarkConversationReadRow, error) {
+	rows, err := q.db.QueryContext(ctx, markConversationRead, arg.ReceiverID, arg.SenderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MarkConversationReadRow
+	for rows.Next() {
+		var i MarkConversationReadRow
+		if err := rows.Scan(&i.ID, &i.ReadAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const markMessageRead = `-- name: MarkMessageRead :one
 UPDATE messages
-SET read_at = NOW()
+SET read_at = NOW(), delivered_at = COALESCE(delivered_at, NOW())
 WHERE id = $1 AND receiver_id = $2 AND read_at IS NULL
-RETURNING id, sender_id, receiver_id, content, is_read, created_at, read_at, expires_at, media_url, media_type, group_id
+RETURNING id, sender_id, receiver_id, content, is_read, created_at, read_at, expires_at, media_url, media_type, group_id, delivered_at, reply_to_message_id, forwarded_from_message_id, forwarded_from_sender_id, story_id
 `
 
 type MarkMessageReadParams struct {
@@ -546,15 +1075,65 @@ func (q *Queries) MarkMessageRead(ctx context.Context, arg MarkMessageReadParams
 		&i.MediaUrl,
 		&i.MediaType,
 		&i.GroupID,
+		&i.DeliveredAt,
+		&i.ReplyToMessageID,
+		&i.ForwardedFromMessageID,
+		&i.ForwardedFromSenderID,
+		&i.StoryID,
 	)
 	return i, err
 }
 
+const markMessagesDelivered = `-- name: MarkMessagesDelivered :many
+UPDATE messages
+SET delivered_at = NOW()
+WHERE id = ANY($1::uuid[])
+  AND receiver_id = $2
+  AND delivered_at IS NULL
+RETURNING id, sender_id, delivered_at
+`
+
+type MarkMessagesDeliveredParams struct {
+	MessageIds []uuid.UUID   `json:"message_ids"`
+	ReceiverID uuid.NullUUID `json:"receiver_id"`
+}
+
+type MarkMessagesDeliveredRow struct {
+	ID          uuid.UUID     `json:"id"`
+	SenderID    uuid.UUID     `json:"sender_id"`
+	DeliveredAt util.NullTime `json:"delivered_at"`
+}
+
+// Records that the receiver's client got these messages. Only the first
+// receipt counts, so already-delivered messages are not returned again.
+func (q *Queries) MarkMessagesDelivered(ctx context.Context, arg MarkMessagesDeliveredParams) ([]MarkMessagesDeliveredRow, error) {
+	rows, err := q.db.QueryContext(ctx, markMessagesDelivered, pq.Array(arg.MessageIds), arg.ReceiverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MarkMessagesDeliveredRow
+	for rows.Next() {
+		var i MarkMessagesDeliveredRow
+		if err := rows.Scan(&i.ID, &i.SenderID, &i.DeliveredAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const saveMessage = `-- name: SaveMessage :one
 UPDATE messages
 SET expires_at = NULL
 WHERE id = $1
-RETURNING id, sender_id, receiver_id, content, is_read, created_at, read_at, expires_at, media_url, media_type, group_id
+RETURNING id, sender_id, receiver_id, content, is_read, created_at, read_at, expires_at, media_url, media_type, group_id, delivered_at, reply_to_message_id, forwarded_from_message_id, forwarded_from_sender_id, story_id
 `
 
 func (q *Queries) SaveMessage(ctx context.Context, id uuid.UUID) (Message, error) {
@@ -572,15 +1151,151 @@ func (q *Queries) SaveMessage(ctx context.Context, id uuid.UUID) (Message, error
 		&i.MediaUrl,
 		&i.MediaType,
 		&i.GroupID,
+		&i.DeliveredAt,
+		&i.ReplyToMessageID,
+		&i.ForwardedFromMessageID,
+		&i.ForwardedFromSenderID,
+		&i.StoryID,
 	)
 	return i, err
 }
 
+const searchDirectMessages = `-- name: SearchDirectMessages :many
+SELECT m.id, m.sender_id, u.username, m.content, m.media_type, m.created_at
+FROM messages m
+JOIN users u ON m.sender_id = u.id
+WHERE ((m.sender_id = $1::uuid AND m.receiver_id = $2::uuid)
+    OR (m.sender_id = $2::uuid AND m.receiver_id = $1::uuid))
+  AND m.group_id IS NULL
+  AND (m.expires_at IS NULL OR m.expires_at > NOW())
+  AND m.content ILIKE '%' || $3::text || '%'
+ORDER BY m.created_at DESC
+LIMIT $4 OFFSET $5
+`
+
+type SearchDirectMessagesParams struct {
+	UserID      uuid.UUID `json:"user_id"`
+	OtherUserID uuid.UUID `json:"other_user_id"`
+	Query       string    `json:"query"`
+	Limit       int32     `json:"limit"`
+	Offset      int32     `json:"offset"`
+}
+
+type SearchDirectMessagesRow struct {
+	ID        uuid.UUID      `json:"id"`
+	SenderID  uuid.UUID      `json:"sender_id"`
+	Username  string         `json:"username"`
+	Content   string         `json:"content"`
+	MediaType sql.NullString `json:"media_type"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// Unexpired messages between two users whose text contains query, newest
+// first. query is a LIKE pattern body, with % _ and \ escaped by the caller.
+func (q *Queries) SearchDirectMessages(ctx context.Context, arg SearchDirectMessagesParams) ([]SearchDirectMessagesRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchDirectMessages,
+		arg.UserID,
+		arg.OtherUserID,
+		arg.Query,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchDirectMessagesRow
+	for rows.Next() {
+		var i SearchDirectMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SenderID,
+			&i.Username,
+			&i.Content,
+			&i.MediaType,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchGroupMessages = `-- name: SearchGroupMessages :many
+SELECT m.id, m.sender_id, u.username, m.content, m.media_type, m.created_at
+FROM messages m
+JOIN users u ON m.sender_id = u.id
+WHERE m.group_id = $1::uuid
+  AND (m.expires_at IS NULL OR m.expires_at > NOW())
+  AND m.content ILIKE '%' || $2::text || '%'
+ORDER BY m.created_at DESC
+LIMIT $3 OFFSET $4
+`
+
+type SearchGroupMessagesParams struct {
+	GroupID uuid.UUID `json:"group_id"`
+	Query   string    `json:"query"`
+	Limit   int32     `json:"limit"`
+	Offset  int32     `json:"offset"`
+}
+
+type SearchGroupMessagesRow struct {
+	ID        uuid.UUID      `json:"id"`
+	SenderID  uuid.UUID      `json:"sender_id"`
+	Username  string         `json:"username"`
+	Content   string         `json:"content"`
+	MediaType sql.NullString `json:"media_type"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// Unexpired messages in a group whose text contains query, newest first
+func (q *Queries) SearchGroupMessages(ctx context.Context, arg SearchGroupMessagesParams) ([]SearchGroupMessagesRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchGroupMessages,
+		arg.GroupID,
+		arg.Query,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchGroupMessagesRow
+	for rows.Next() {
+		var i SearchGroupMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SenderID,
+			&i.Username,
+			&i.Content,
+			&i.MediaType,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateMessage = `-- name: UpdateMessage :one
 UPDATE messages
 SET content = $3, media_url = $4, media_type = $5
 WHERE id = $1 AND sender_id = $2
-RETURNING id, sender_id, receiver_id, content, is_read, created_at, read_at, expires_at, media_url, media_type, group_id
+RETURNING id, sender_id, receiver_id, content, is_read, created_at, read_at, expires_at, media_url, media_type, group_id, delivered_at, reply_to_message_id, forwarded_from_message_id, forwarded_from_sender_id, story_id
 `
 
 type UpdateMessageParams struct {
@@ -612,6 +1327,11 @@ func (q *Queries) UpdateMessage(ctx context.Context, arg UpdateMessageParams) (M
 		&i.MediaUrl,
 		&i.MediaType,
 		&i.GroupID,
+		&i.DeliveredAt,
+		&i.ReplyToMessageID,
+		&i.ForwardedFromMessageID,
+		&i.ForwardedFromSenderID,
+		&i.StoryID,
 	)
 	return i, err
 }
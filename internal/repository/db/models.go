@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"privacy-social-backend/internal/util"
 )
 
 type ConnectionStatus string
@@ -65,6 +66,8 @@ const (
 	NotificationTypeCrossingDetected   NotificationType = "crossing_detected"
 	NotificationTypeMessageReceived    NotificationType = "message_received"
 	NotificationTypeStoryReaction      NotificationType = "story_reaction"
+	NotificationTypeStoryMention       NotificationType = "story_mention"
+	NotificationTypeStoryReply         NotificationType = "story_reply"
 )
 
 func (e *NotificationType) Scan(src interface{}) error {
@@ -232,6 +235,34 @@ func (ns NullUserRole) Value() (driver.Value, error) {
 	return string(ns.UserRole), nil
 }
 
+type AdminAuditLog struct {
+	ID           uuid.UUID      `json:"id"`
+	AdminID      uuid.UUID      `json:"admin_id"`
+	TargetUserID uuid.NullUUID  `json:"target_user_id"`
+	Action       string         `json:"action"`
+	Method       string         `json:"method"`
+	Path         string         `json:"path"`
+	StatusCode   int32          `json:"status_code"`
+	CreatedAt    time.Time      `json:"created_at"`
+	TargetID     uuid.NullUUID  `json:"target_id"`
+	Reason       sql.NullString `json:"reason"`
+}
+
+type AnalyticsDaily struct {
+	Day         time.Time `json:"day"`
+	EventType   string    `json:"event_type"`
+	EventCount  int64     `json:"event_count"`
+	UniqueUsers int64     `json:"unique_users"`
+}
+
+type AnalyticsEvent struct {
+	// Redis stream entry ID, so re-delivered entries are ingested once
+	StreamID   string    `json:"stream_id"`
+	EventType  string    `json:"event_type"`
+	UserID     uuid.UUID `json:"user_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
 type ArchivedStory struct {
 	ID                uuid.UUID      `json:"id"`
 	UserID            uuid.UUID      `json:"user_id"`
@@ -244,15 +275,21 @@ type ArchivedStory struct {
 	IsAnonymous       sql.NullBool   `json:"is_anonymous"`
 	ShowLocation      sql.NullBool   `json:"show_location"`
 	OriginalCreatedAt time.Time      `json:"original_created_at"`
-	ArchivedAt        sql.NullTime   `json:"archived_at"`
-	CreatedAt         sql.NullTime   `json:"created_at"`
+	ArchivedAt        util.NullTime  `json:"archived_at"`
+	CreatedAt         util.NullTime  `json:"created_at"`
 }
 
 type BlockedUser struct {
-	ID        uuid.UUID    `json:"id"`
-	BlockerID uuid.UUID    `json:"blocker_id"`
-	BlockedID uuid.UUID    `json:"blocked_id"`
-	CreatedAt sql.NullTime `json:"created_at"`
+	ID        uuid.UUID     `json:"id"`
+	BlockerID uuid.UUID     `json:"blocker_id"`
+	BlockedID uuid.UUID     `json:"blocked_id"`
+	CreatedAt util.NullTime `json:"created_at"`
+}
+
+type CloseFriend struct {
+	UserID    uuid.UUID `json:"user_id"`
+	FriendID  uuid.UUID `json:"friend_id"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Connection struct {
@@ -261,6 +298,24 @@ type Connection struct {
 	Status      ConnectionStatus `json:"status"`
 	CreatedAt   time.Time        `json:"created_at"`
 	UpdatedAt   time.Time        `json:"updated_at"`
+	RespondedAt util.NullTime    `json:"responded_at"`
+}
+
+type ConnectionRecommendation struct {
+	UserID        uuid.UUID `json:"user_id"`
+	CandidateID   uuid.UUID `json:"candidate_id"`
+	MutualCount   int32     `json:"mutual_count"`
+	CrossingCount int32     `json:"crossing_count"`
+	Score         int32     `json:"score"`
+	ComputedAt    time.Time `json:"computed_at"`
+}
+
+type ConversationSetting struct {
+	UserAID              uuid.UUID `json:"user_a_id"`
+	UserBID              uuid.UUID `json:"user_b_id"`
+	DefaultExpirySeconds int64     `json:"default_expiry_seconds"`
+	UpdatedBy            uuid.UUID `json:"updated_by"`
+	UpdatedAt            time.Time `json:"updated_at"`
 }
 
 type Crossing struct {
@@ -298,18 +353,43 @@ type Location struct {
 	ExpiresAt  time.Time   `json:"expires_at"`
 }
 
+type MediaObject struct {
+	Hash        string        `json:"hash"`
+	ObjectKey   string        `json:"object_key"`
+	Url         string        `json:"url"`
+	SizeBytes   int64         `json:"size_bytes"`
+	ContentType string        `json:"content_type"`
+	RefCount    int32         `json:"ref_count"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	RetainUntil util.NullTime `json:"retain_until"`
+	OnHold      bool          `json:"on_hold"`
+}
+
 type Message struct {
-	ID         uuid.UUID      `json:"id"`
-	SenderID   uuid.UUID      `json:"sender_id"`
-	ReceiverID uuid.NullUUID  `json:"receiver_id"`
-	Content    string         `json:"content"`
-	IsRead     bool           `json:"is_read"`
-	CreatedAt  time.Time      `json:"created_at"`
-	ReadAt     sql.NullTime   `json:"read_at"`
-	ExpiresAt  sql.NullTime   `json:"expires_at"`
-	MediaUrl   sql.NullString `json:"media_url"`
-	MediaType  sql.NullString `json:"media_type"`
-	GroupID    uuid.NullUUID  `json:"group_id"`
+	ID                     uuid.UUID      `json:"id"`
+	SenderID               uuid.UUID      `json:"sender_id"`
+	ReceiverID             uuid.NullUUID  `json:"receiver_id"`
+	Content                string         `json:"content"`
+	IsRead                 bool           `json:"is_read"`
+	CreatedAt              time.Time      `json:"created_at"`
+	ReadAt                 util.NullTime  `json:"read_at"`
+	ExpiresAt              util.NullTime  `json:"expires_at"`
+	MediaUrl               sql.NullString `json:"media_url"`
+	MediaType              sql.NullString `json:"media_type"`
+	GroupID                uuid.NullUUID  `json:"group_id"`
+	DeliveredAt            util.NullTime  `json:"delivered_at"`
+	ReplyToMessageID       uuid.NullUUID  `json:"reply_to_message_id"`
+	ForwardedFromMessageID uuid.NullUUID  `json:"forwarded_from_message_id"`
+	ForwardedFromSenderID  uuid.NullUUID  `json:"forwarded_from_sender_id"`
+	StoryID                uuid.NullUUID  `json:"story_id"`
+}
+
+type MessageAttachment struct {
+	MessageID uuid.UUID `json:"message_id"`
+	Position  int32     `json:"position"`
+	MediaUrl  string    `json:"media_url"`
+	MediaType string    `json:"media_type"`
 }
 
 type MessageReaction struct {
@@ -320,6 +400,13 @@ type MessageReaction struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type MutedConversation struct {
+	UserID       uuid.UUID     `json:"user_id"`
+	TargetUserID uuid.NullUUID `json:"target_user_id"`
+	GroupID      uuid.NullUUID `json:"group_id"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
 type Notification struct {
 	ID                uuid.UUID        `json:"id"`
 	UserID            uuid.UUID        `json:"user_id"`
@@ -338,8 +425,8 @@ type PrivacySetting struct {
 	WhoCanMessage    sql.NullString `json:"who_can_message"`
 	WhoCanSeeStories sql.NullString `json:"who_can_see_stories"`
 	ShowLocation     sql.NullBool   `json:"show_location"`
-	CreatedAt        sql.NullTime   `json:"created_at"`
-	UpdatedAt        sql.NullTime   `json:"updated_at"`
+	CreatedAt        util.NullTime  `json:"created_at"`
+	UpdatedAt        util.NullTime  `json:"updated_at"`
 }
 
 type ProfileView struct {
@@ -350,14 +437,28 @@ type ProfileView struct {
 }
 
 type Report struct {
-	ID            uuid.UUID      `json:"id"`
-	ReporterID    uuid.UUID      `json:"reporter_id"`
-	TargetUserID  uuid.NullUUID  `json:"target_user_id"`
-	TargetStoryID uuid.NullUUID  `json:"target_story_id"`
-	Reason        ReportReason   `json:"reason"`
-	Description   sql.NullString `json:"description"`
-	IsResolved    bool           `json:"is_resolved"`
-	CreatedAt     time.Time      `json:"created_at"`
+	ID              uuid.UUID      `json:"id"`
+	ReporterID      uuid.UUID      `json:"reporter_id"`
+	TargetUserID    uuid.NullUUID  `json:"target_user_id"`
+	TargetStoryID   uuid.NullUUID  `json:"target_story_id"`
+	Reason          ReportReason   `json:"reason"`
+	Description     sql.NullString `json:"description"`
+	IsResolved      bool           `json:"is_resolved"`
+	CreatedAt       time.Time      `json:"created_at"`
+	TargetMessageID uuid.NullUUID  `json:"target_message_id"`
+}
+
+type ScheduledMessage struct {
+	ID               uuid.UUID      `json:"id"`
+	SenderID         uuid.UUID      `json:"sender_id"`
+	ReceiverID       uuid.UUID      `json:"receiver_id"`
+	Content          string         `json:"content"`
+	MediaUrl         sql.NullString `json:"media_url"`
+	MediaType        sql.NullString `json:"media_type"`
+	ExpiresInSeconds int64          `json:"expires_in_seconds"`
+	ScheduledAt      time.Time      `json:"scheduled_at"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
 }
 
 type Session struct {
@@ -369,6 +470,8 @@ type Session struct {
 	IsBlocked    bool      `json:"is_blocked"`
 	ExpiresAt    time.Time `json:"expires_at"`
 	CreatedAt    time.Time `json:"created_at"`
+	DeviceName   string    `json:"device_name"`
+	Platform     string    `json:"platform"`
 }
 
 type Story struct {
@@ -388,6 +491,12 @@ type Story struct {
 	ShowLocation bool              `json:"show_location"`
 }
 
+type StoryHashtag struct {
+	StoryID   uuid.UUID `json:"story_id"`
+	Tag       string    `json:"tag"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type StoryMention struct {
 	ID              uuid.UUID `json:"id"`
 	StoryID         uuid.UUID `json:"story_id"`
@@ -423,14 +532,14 @@ type User struct {
 	TrustLevel             int32           `json:"trust_level"`
 	IsVerified             bool            `json:"is_verified"`
 	IsShadowBanned         bool            `json:"is_shadow_banned"`
-	LastActiveAt           sql.NullTime    `json:"last_active_at"`
+	LastActiveAt           util.NullTime   `json:"last_active_at"`
 	CreatedAt              time.Time       `json:"created_at"`
 	IsGhostMode            bool            `json:"is_ghost_mode"`
 	ActivityStreak         sql.NullInt32   `json:"activity_streak"`
-	StreakUpdatedAt        sql.NullTime    `json:"streak_updated_at"`
+	StreakUpdatedAt        util.NullTime   `json:"streak_updated_at"`
 	IsPremium              sql.NullBool    `json:"is_premium"`
 	StreakFreezesRemaining int32           `json:"streak_freezes_remaining"`
-	BoostExpiresAt         sql.NullTime    `json:"boost_expires_at"`
+	BoostExpiresAt         util.NullTime   `json:"boost_expires_at"`
 	BannerUrl              sql.NullString  `json:"banner_url"`
 	Theme                  sql.NullString  `json:"theme"`
 	ProfileVisibility      sql.NullString  `json:"profile_visibility"`
@@ -439,6 +548,11 @@ type User struct {
 	Links                  json.RawMessage `json:"links"`
 	GoogleID               sql.NullString  `json:"google_id"`
 	PasswordResetToken     sql.NullString  `json:"password_reset_token"`
-	PasswordResetExpiresAt sql.NullTime    `json:"password_reset_expires_at"`
-	GhostModeExpiresAt     sql.NullTime    `json:"ghost_mode_expires_at"`
+	PasswordResetExpiresAt util.NullTime   `json:"password_reset_expires_at"`
+	GhostModeExpiresAt     util.NullTime   `json:"ghost_mode_expires_at"`
+	LastSeenAt             util.NullTime   `json:"last_seen_at"`
+	PhoneVerified          bool            `json:"phone_verified"`
+	BanType                sql.NullString  `json:"ban_type"`
+	BanReason              sql.NullString  `json:"ban_reason"`
+	BanExpiresAt           util.NullTime   `json:"ban_expires_at"`
 }
@@ -34,12 +34,15 @@ const upsertPrivacySettings = `-- name: UpsertPrivacySettings :one
 INSERT INTO privacy_settings (
     user_id, who_can_message, who_can_see_stories, show_location
 ) VALUES (
-    $1, $2, $3, $4
+    $1,
+    COALESCE($2, 'connections'),
+    COALESCE($3, 'connections'),
+    COALESCE($4, true)
 ) ON CONFLICT (user_id) DO UPDATE
 SET 
-    who_can_message = EXCLUDED.who_can_message,
-    who_can_see_stories = EXCLUDED.who_can_see_stories,
-    show_location = EXCLUDED.show_location,
+    who_can_message = COALESCE($2, privacy_settings.who_can_message),
+    who_can_see_stories = COALESCE($3, privacy_settings.who_can_see_stories),
+    show_location = COALESCE($4, privacy_settings.show_location),
     updated_at = NOW()
 RETURNING user_id, who_can_message, who_can_see_stories, show_location, created_at, updated_at
 `
@@ -51,6 +54,7 @@ type UpsertPrivacySettingsParams struct {
 	ShowLocation     sql.NullBool   `json:"show_location"`
 }
 
+// Settings passed as null keep their current value, or the default on a new row
 func (q *Queries) UpsertPrivacySettings(ctx context.Context, arg UpsertPrivacySettingsParams) (PrivacySetting, error) {
 	row := q.db.QueryRowContext(ctx, upsertPrivacySettings,
 		arg.UserID,
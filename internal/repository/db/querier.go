@@ -7,36 +7,80 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
+	// Adding someone already on the list is a no-op
+	AddCloseFriend(ctx context.Context, arg AddCloseFriendParams) error
 	AddGroupMember(ctx context.Context, arg AddGroupMemberParams) (GroupMember, error)
 	ArchiveStory(ctx context.Context, arg ArchiveStoryParams) (ArchivedStory, error)
+	// BanUser sets or clears a user's ban; a NULL ban_type lifts it
 	BanUser(ctx context.Context, arg BanUserParams) (User, error)
+	// Blocks one of a user's sessions even if it already is, so logging out twice succeeds
+	BlockSession(ctx context.Context, arg BlockSessionParams) (int64, error)
+	// Blocking someone already blocked returns the existing block
 	BlockUser(ctx context.Context, arg BlockUserParams) (BlockedUser, error)
+	BlockUserSessions(ctx context.Context, userID uuid.UUID) (int64, error)
 	BoostUser(ctx context.Context, arg BoostUserParams) (User, error)
 	CheckGroupMembership(ctx context.Context, arg CheckGroupMembershipParams) (bool, error)
+	// Removes a due message so exactly one sender delivers it. No row means it was
+	// cancelled, postponed or already sent.
+	ClaimScheduledMessage(ctx context.Context, arg ClaimScheduledMessageParams) (ScheduledMessage, error)
+	// Detaches media older than the retention policy from messages that outlive it,
+	// unless the media is on hold. The media GC then purges the object.
+	ClearExpiredMessageMedia(ctx context.Context, createdBefore time.Time) (int64, error)
 	ClearPasswordResetToken(ctx context.Context, id uuid.UUID) error
+	CountAdminAuditLog(ctx context.Context) (int64, error)
+	// How many users SearchAdminUsers matches across all pages
+	CountAdminUsers(ctx context.Context, arg CountAdminUsersParams) (int64, error)
+	CountAnonymousStoriesSince(ctx context.Context, arg CountAnonymousStoriesSinceParams) (int64, error)
 	CountArchivedStories(ctx context.Context, userID uuid.UUID) (int64, error)
+	CountConnectionRecommendations(ctx context.Context, userID uuid.UUID) (int64, error)
 	CountConnectionRequestsToday(ctx context.Context, requesterID uuid.UUID) (int64, error)
 	CountCrossingsToday(ctx context.Context, userID1 uuid.UUID) (int64, error)
+	// How many messages SearchDirectMessages matches across all pages
+	CountDirectMessageMatches(ctx context.Context, arg CountDirectMessageMatchesParams) (int64, error)
+	// How many messages SearchGroupMessages matches across all pages
+	CountGroupMessageMatches(ctx context.Context, arg CountGroupMessageMatchesParams) (int64, error)
+	// How many replies ListMessageReplies returns across all pages
+	CountMessageReplies(ctx context.Context, messageID uuid.UUID) (int64, error)
+	// Distinct reporters with an open report on a story
+	CountOpenStoryReports(ctx context.Context, targetStoryID uuid.NullUUID) (int64, error)
+	// Admin: Count reports for pagination
+	CountReports(ctx context.Context, isResolved bool) (int64, error)
+	CountStories(ctx context.Context) (int64, error)
 	CountStoryReactions(ctx context.Context, storyID uuid.UUID) (int64, error)
 	CountStoryViews(ctx context.Context, storyID uuid.UUID) (int64, error)
 	CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error)
-	CountUsers(ctx context.Context) (int64, error)
+	CreateAdminAuditLog(ctx context.Context, arg CreateAdminAuditLogParams) error
+	// Inserts a batch read from the event stream. Entries already ingested and
+	// events of accounts deleted in the meantime are skipped.
+	CreateAnalyticsEvents(ctx context.Context, arg CreateAnalyticsEventsParams) (int64, error)
 	CreateConnectionRequest(ctx context.Context, arg CreateConnectionRequestParams) (Connection, error)
 	CreateCrossing(ctx context.Context, arg CreateCrossingParams) (Crossing, error)
 	CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error)
 	CreateLocation(ctx context.Context, arg CreateLocationParams) (Location, error)
 	CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error)
-	CreateMessageReaction(ctx context.Context, arg CreateMessageReactionParams) (MessageReaction, error)
+	// Stores a message's attachments in the order given
+	CreateMessageAttachments(ctx context.Context, arg CreateMessageAttachmentsParams) error
+	// Toggles one emoji of a user's reactions: adds it, or removes it if the user
+	// already reacted with it. Different emoji on the same message are kept apart.
+	CreateMessageReaction(ctx context.Context, arg CreateMessageReactionParams) (CreateMessageReactionRow, error)
 	CreateNotification(ctx context.Context, arg CreateNotificationParams) (Notification, error)
 	CreateReport(ctx context.Context, arg CreateReportParams) (Report, error)
+	CreateScheduledMessage(ctx context.Context, arg CreateScheduledMessageParams) (ScheduledMessage, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateStory(ctx context.Context, arg CreateStoryParams) (CreateStoryRow, error)
+	CreateStoryHashtag(ctx context.Context, arg CreateStoryHashtagParams) error
 	CreateStoryMention(ctx context.Context, arg CreateStoryMentionParams) (StoryMention, error)
+	// Mentions the given users in a story and returns the ones newly mentioned.
+	// The author, anyone on either side of a block with them and anyone outside
+	// the story's audience are skipped.
+	CreateStoryMentions(ctx context.Context, arg CreateStoryMentionsParams) ([]uuid.UUID, error)
+	CreateStoryModerationHold(ctx context.Context, arg CreateStoryModerationHoldParams) error
 	// Story Reactions
 	CreateStoryReaction(ctx context.Context, arg CreateStoryReactionParams) (StoryReaction, error)
 	// Story Views
@@ -44,8 +88,11 @@ type Querier interface {
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	// Used for panic mode - deletes all user data
 	DeleteAllUserData(ctx context.Context, id uuid.UUID) error
+	DeleteAnalyticsEventsBefore(ctx context.Context, before time.Time) (int64, error)
 	DeleteArchivedStory(ctx context.Context, arg DeleteArchivedStoryParams) error
+	// Close friends are a subset of connections, so disconnecting drops them both ways
 	DeleteConnection(ctx context.Context, arg DeleteConnectionParams) error
+	DeleteConnectionRecommendations(ctx context.Context, userID uuid.UUID) error
 	DeleteConversation(ctx context.Context, arg DeleteConversationParams) error
 	DeleteExpiredLocations(ctx context.Context) error
 	DeleteExpiredMessages(ctx context.Context) error
@@ -56,34 +103,59 @@ type Querier interface {
 	DeleteOldMessages(ctx context.Context) error
 	// Delete notifications older than 30 days
 	DeleteOldNotifications(ctx context.Context) error
+	// Only recent days feed the activity summary
+	DeleteOldUserActivityDays(ctx context.Context) error
+	DeleteScheduledMessage(ctx context.Context, arg DeleteScheduledMessageParams) (int64, error)
 	// Admin: Delete story
 	DeleteStory(ctx context.Context, id uuid.UUID) error
+	DeleteStoryHashtags(ctx context.Context, storyID uuid.UUID) error
 	DeleteStoryMentions(ctx context.Context, storyID uuid.UUID) error
 	DeleteStoryReaction(ctx context.Context, arg DeleteStoryReactionParams) error
+	// Only deletes if still unreferenced and not on hold, so a concurrent reuse or hold wins
+	DeleteUnreferencedMediaObject(ctx context.Context, hash string) (int64, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	// Deletes the groups a user created that have no other members
+	DeleteUserSoleGroups(ctx context.Context, createdBy uuid.UUID) error
 	// Block Logic
 	FindPotentialCrossings(ctx context.Context, arg FindPotentialCrossingsParams) ([]FindPotentialCrossingsRow, error)
+	// One row per emoji on a message, in the order they were first used
+	GetAggregatedReactions(ctx context.Context, arg GetAggregatedReactionsParams) ([]GetAggregatedReactionsRow, error)
+	GetAnalyticsTotalsSince(ctx context.Context, since time.Time) ([]GetAnalyticsTotalsSinceRow, error)
 	GetArchivedStories(ctx context.Context, arg GetArchivedStoriesParams) ([]ArchivedStory, error)
 	GetArchivedStory(ctx context.Context, arg GetArchivedStoryParams) (ArchivedStory, error)
 	GetBlockedUsers(ctx context.Context, blockerID uuid.UUID) ([]GetBlockedUsersRow, error)
 	GetConnection(ctx context.Context, arg GetConnectionParams) (Connection, error)
+	// Re-checks connections, blocks and visibility so stale rows never leak
+	GetConnectionRecommendations(ctx context.Context, arg GetConnectionRecommendationsParams) ([]GetConnectionRecommendationsRow, error)
 	// Get stories from connected users (not limited by radius)
 	GetConnectionStories(ctx context.Context, userID uuid.UUID) ([]GetConnectionStoriesRow, error)
 	GetConversationList(ctx context.Context, receiverID uuid.NullUUID) ([]GetConversationListRow, error)
-	GetConversionStats(ctx context.Context) (GetConversionStatsRow, error)
+	// The pair's settings, whichever order the two users are given in
+	GetConversationSettings(ctx context.Context, arg GetConversationSettingsParams) (ConversationSetting, error)
 	GetCrossingsForUser(ctx context.Context, userID1 uuid.UUID) ([]Crossing, error)
-	GetEngagementStats(ctx context.Context) (GetEngagementStatsRow, error)
 	GetGroupByID(ctx context.Context, id uuid.UUID) (Group, error)
 	GetGroupMembers(ctx context.Context, groupID uuid.UUID) ([]GetGroupMembersRow, error)
 	GetGroupMessages(ctx context.Context, groupID uuid.NullUUID) ([]GetGroupMessagesRow, error)
-	GetHeatmapData(ctx context.Context) ([]GetHeatmapDataRow, error)
+	// Distinct users per geohash cell of the given precision inside a box over the last hour
+	GetHeatmapCells(ctx context.Context, arg GetHeatmapCellsParams) ([]GetHeatmapCellsRow, error)
+	GetMediaObjectByHash(ctx context.Context, hash string) (MediaObject, error)
 	GetMessage(ctx context.Context, id uuid.UUID) (Message, error)
 	GetMessageReactions(ctx context.Context, messageID uuid.UUID) ([]GetMessageReactionsRow, error)
+	// What a reply shows of the message it quotes; no row once that message is
+	// deleted or expired
+	GetMessageReplyPreview(ctx context.Context, id uuid.UUID) (GetMessageReplyPreviewRow, error)
+	// Delivery and read state of messages the user sent or received, so a
+	// reconnecting client can catch up on receipts it missed
+	GetMessageStatus(ctx context.Context, arg GetMessageStatusParams) ([]GetMessageStatusRow, error)
 	GetMyProfileViews(ctx context.Context, viewerID uuid.UUID) ([]GetMyProfileViewsRow, error)
 	GetPrivacySettings(ctx context.Context, userID uuid.UUID) (PrivacySetting, error)
 	GetProfileViewCount(ctx context.Context, viewedUserID uuid.UUID) (int64, error)
 	GetRecentProfileVisitors(ctx context.Context, viewedUserID uuid.UUID) ([]GetRecentProfileVisitorsRow, error)
+	GetScheduledMessage(ctx context.Context, arg GetScheduledMessageParams) (ScheduledMessage, error)
 	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
+	// Of the users who signed up in [cohort_start, cohort_end), how many did
+	// anything else since active_since
+	GetSignupRetention(ctx context.Context, arg GetSignupRetentionParams) (GetSignupRetentionRow, error)
 	// Get stories within a bounding box for map view
 	// AND DATE(u.last_active_at) >= CURRENT_DATE - INTERVAL '1 day'
 	GetStoriesInBounds(ctx context.Context, arg GetStoriesInBoundsParams) ([]GetStoriesInBoundsRow, error)
@@ -95,9 +167,12 @@ type Querier interface {
 	GetStoryStats(ctx context.Context) (GetStoryStatsRow, error)
 	// Only accessible by story owner
 	GetStoryViewers(ctx context.Context, storyID uuid.UUID) ([]GetStoryViewersRow, error)
-	GetStreakRetentionStats(ctx context.Context) (GetStreakRetentionStatsRow, error)
 	GetSuggestedConnections(ctx context.Context, arg GetSuggestedConnectionsParams) ([]GetSuggestedConnectionsRow, error)
 	GetSystemStats(ctx context.Context) (GetSystemStatsRow, error)
+	// Tags on live stories within the radius that the viewer could see in their
+	// feed, fastest-rising first
+	GetTrendingHashtags(ctx context.Context, arg GetTrendingHashtagsParams) ([]GetTrendingHashtagsRow, error)
+	// Messages from senders the receiver muted don't count toward the badge
 	GetUnreadMessageCount(ctx context.Context, receiverID uuid.NullUUID) (int64, error)
 	// Get user's activity status and visibility
 	GetUserActivityStatus(ctx context.Context, id uuid.UUID) (GetUserActivityStatusRow, error)
@@ -110,44 +185,135 @@ type Querier interface {
 	GetUserEngagementStats(ctx context.Context, userID uuid.UUID) (GetUserEngagementStatsRow, error)
 	GetUserGroups(ctx context.Context, userID uuid.UUID) ([]Group, error)
 	GetUserMentions(ctx context.Context, arg GetUserMentionsParams) ([]GetUserMentionsRow, error)
+	GetUserPresence(ctx context.Context, id uuid.UUID) (GetUserPresenceRow, error)
 	GetUserProfile(ctx context.Context, id uuid.UUID) (GetUserProfileRow, error)
+	// Matches case-insensitively, as mentions are parsed lowercased
+	GetUsersByUsernames(ctx context.Context, usernames []string) ([]GetUsersByUsernamesRow, error)
+	// Whether sender_id already replied to the story
+	HasStoryReply(ctx context.Context, arg HasStoryReplyParams) (bool, error)
 	HasValidStory(ctx context.Context, userID uuid.UUID) (bool, error)
+	// Scores non-connected users by mutual connections (weighted 3) and recent crossings.
+	// Blocks in either direction and non-public profiles are excluded.
+	InsertConnectionRecommendations(ctx context.Context, arg InsertConnectionRecommendationsParams) error
+	IsCloseFriend(ctx context.Context, arg IsCloseFriendParams) (bool, error)
+	IsConversationMuted(ctx context.Context, arg IsConversationMutedParams) (bool, error)
+	IsStoryHeld(ctx context.Context, storyID uuid.UUID) (bool, error)
 	IsUserBlocked(ctx context.Context, arg IsUserBlockedParams) (bool, error)
+	// LiftExpiredBans clears suspensions whose end has passed
+	LiftExpiredBans(ctx context.Context) (int64, error)
+	// Users locked out by a hard ban or a suspension that hasn't ended
+	ListActiveLockouts(ctx context.Context) ([]ListActiveLockoutsRow, error)
+	ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	ListActiveUserIDs(ctx context.Context, activeSince time.Time) ([]uuid.UUID, error)
+	ListAdminAuditLog(ctx context.Context, arg ListAdminAuditLogParams) ([]AdminAuditLog, error)
 	// Admin: List all stories
 	ListAllStories(ctx context.Context, arg ListAllStoriesParams) ([]ListAllStoriesRow, error)
+	ListCloseFriends(ctx context.Context, userID uuid.UUID) ([]ListCloseFriendsRow, error)
+	// Live stories of the viewer's connections with the viewer's seen state, ordered
+	// as a stories tray: authors with unseen stories first, then by their latest
+	// story; each author's stories oldest first. Anonymous stories are left out
+	// because a ring names its author.
+	ListConnectionStoryRings(ctx context.Context, userID uuid.UUID) ([]ListConnectionStoryRingsRow, error)
 	ListConnections(ctx context.Context, requesterID uuid.UUID) ([]ListConnectionsRow, error)
+	// Relationships seen from the given user: direction tells incoming from outgoing
+	ListConnectionsByStatus(ctx context.Context, arg ListConnectionsByStatusParams) ([]ListConnectionsByStatusRow, error)
+	ListDueScheduledMessages(ctx context.Context, arg ListDueScheduledMessagesParams) ([]ScheduledMessage, error)
+	// Members who muted the group, so deliveries to them can skip the unread badge
+	ListGroupMuters(ctx context.Context, groupID uuid.NullUUID) ([]uuid.UUID, error)
+	// Moderation queue: live stories waiting for review, oldest first. Shows the
+	// real author even for anonymous stories.
+	ListHeldStories(ctx context.Context, arg ListHeldStoriesParams) ([]ListHeldStoriesRow, error)
+	ListMessageAttachments(ctx context.Context, messageID uuid.UUID) ([]MessageAttachment, error)
+	// Unexpired replies to a message, oldest first. Deleted replies are gone
+	// from the table, so they drop out on their own.
+	ListMessageReplies(ctx context.Context, arg ListMessageRepliesParams) ([]ListMessageRepliesRow, error)
 	ListMessages(ctx context.Context, arg ListMessagesParams) ([]ListMessagesRow, error)
+	// The owner's live stories plus archived (highlighted) stories whose original has expired, newest first
+	ListMyStories(ctx context.Context, arg ListMyStoriesParams) ([]ListMyStoriesRow, error)
+	// The users among user_ids that viewer_id may discover nearby: not ghosted or
+	// shadow-banned, no block either way, sharing their location, and with a
+	// profile the viewer can see
+	ListNearbyUsers(ctx context.Context, arg ListNearbyUsersParams) ([]ListNearbyUsersRow, error)
 	ListNotifications(ctx context.Context, arg ListNotificationsParams) ([]Notification, error)
 	ListPendingRequests(ctx context.Context, targetID uuid.UUID) ([]ListPendingRequestsRow, error)
 	// Admin: List all reports
 	ListReports(ctx context.Context, arg ListReportsParams) ([]ListReportsRow, error)
+	ListScheduledMessages(ctx context.Context, senderID uuid.UUID) ([]ScheduledMessage, error)
 	ListSentConnectionRequests(ctx context.Context, requesterID uuid.UUID) ([]ListSentConnectionRequestsRow, error)
-	// Admin Queries
-	ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error)
+	// Media nobody references, idle past the grace period, past its retention and not on hold
+	ListUnreferencedMediaObjects(ctx context.Context, arg ListUnreferencedMediaObjectsParams) ([]MediaObject, error)
+	ListUserActivityDays(ctx context.Context, arg ListUserActivityDaysParams) ([]time.Time, error)
+	// Users whose cached data may include this user: connections in any state
+	// and direct message partners
+	ListUserContacts(ctx context.Context, targetID uuid.UUID) ([]uuid.UUID, error)
+	// Where a user's stories were posted, to expire the feeds they appear in
+	ListUserStoryGeohashes(ctx context.Context, userID uuid.UUID) ([]string, error)
 	MarkAllNotificationsAsRead(ctx context.Context, userID uuid.UUID) error
-	MarkConversationRead(ctx context.Context, arg MarkConversationReadParams) error
+	// Returns the messages this call marked, for per-message read receipts
+	MarkConversationRead(ctx context.Context, arg MarkConversationReadParams) ([]MarkConversationReadRow, error)
 	MarkMessageRead(ctx context.Context, arg MarkMessageReadParams) (Message, error)
+	// Records that the receiver's client got these messages. Only the first
+	// receipt counts, so already-delivered messages are not returned again.
+	MarkMessagesDelivered(ctx context.Context, arg MarkMessagesDeliveredParams) ([]MarkMessagesDeliveredRow, error)
 	MarkNotificationAsRead(ctx context.Context, arg MarkNotificationAsReadParams) (Notification, error)
+	// MarkPhoneVerified records that the user with this phone confirmed it with a one-time code
+	MarkPhoneVerified(ctx context.Context, phone string) (uuid.UUID, error)
+	// Muting an already muted group is a no-op
+	MuteGroupConversation(ctx context.Context, arg MuteGroupConversationParams) error
+	// Muting an already muted conversation is a no-op
+	MuteUserConversation(ctx context.Context, arg MuteUserConversationParams) error
+	// PromoteUserToAdmin grants the admin role; it affects no rows when the user doesn't exist
+	PromoteUserToAdmin(ctx context.Context, id uuid.UUID) (int64, error)
+	ReleaseStoryModerationHold(ctx context.Context, storyID uuid.UUID) (int64, error)
+	RemoveCloseFriend(ctx context.Context, arg RemoveCloseFriendParams) error
 	RemoveGroupMember(ctx context.Context, arg RemoveGroupMemberParams) error
 	// Admin: Resolve report
 	ResolveReport(ctx context.Context, id uuid.UUID) (Report, error)
+	// Keeps the newest max_sessions active sessions and revokes the rest
+	RevokeExcessSessions(ctx context.Context, arg RevokeExcessSessionsParams) (int64, error)
+	RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error)
+	// Recomputes the daily rollups of every day from from_day on
+	RollupAnalyticsDaily(ctx context.Context, fromDay time.Time) error
 	SaveMessage(ctx context.Context, id uuid.UUID) (Message, error)
-	SearchUsers(ctx context.Context, query string) ([]SearchUsersRow, error)
+	// Users whose username, email or phone contains query (any user when it's
+	// NULL), newest first. status is all, banned or active; a temporary ban that
+	// has run out counts as active.
+	SearchAdminUsers(ctx context.Context, arg SearchAdminUsersParams) ([]User, error)
+	// Unexpired messages between two users whose text contains query, newest
+	// first. query is a LIKE pattern body, with % _ and \ escaped by the caller.
+	SearchDirectMessages(ctx context.Context, arg SearchDirectMessagesParams) ([]SearchDirectMessagesRow, error)
+	// Unexpired messages in a group whose text contains query, newest first
+	SearchGroupMessages(ctx context.Context, arg SearchGroupMessagesParams) ([]SearchGroupMessagesRow, error)
+	// Users matching the query, leaving out anyone the viewer blocked or was
+	// blocked by
+	SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error)
+	SetMediaObjectHold(ctx context.Context, arg SetMediaObjectHoldParams) (int64, error)
 	SetPasswordResetToken(ctx context.Context, arg SetPasswordResetTokenParams) (User, error)
 	// Privacy Features
 	ToggleGhostMode(ctx context.Context, arg ToggleGhostModeParams) (User, error)
 	TrackProfileView(ctx context.Context, arg TrackProfileViewParams) (ProfileView, error)
+	// Hands each group a user created to its longest-standing other member, who
+	// becomes an admin
+	TransferUserGroups(ctx context.Context, userID uuid.UUID) error
 	UnblockUser(ctx context.Context, arg UnblockUserParams) error
+	// target_id is either the other user of a direct chat or a group
+	UnmuteConversation(ctx context.Context, arg UnmuteConversationParams) error
 	UpdateConnectionStatus(ctx context.Context, arg UpdateConnectionStatusParams) (Connection, error)
 	UpdateMessage(ctx context.Context, arg UpdateMessageParams) (Message, error)
+	UpdateScheduledMessage(ctx context.Context, arg UpdateScheduledMessageParams) (ScheduledMessage, error)
 	UpdateStory(ctx context.Context, arg UpdateStoryParams) (UpdateStoryRow, error)
-	// Updates last_active_at and calculates activity streak
+	// Updates last_active_at and calculates activity streak, recording today as an active day
 	UpdateUserActivity(ctx context.Context, id uuid.UUID) (User, error)
 	UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (UpdateUserEmailRow, error)
 	UpdateUserGoogleID(ctx context.Context, arg UpdateUserGoogleIDParams) (User, error)
+	UpdateUserLastSeen(ctx context.Context, id uuid.UUID) error
 	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
 	UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (UpdateUserProfileRow, error)
 	UpdateUserTrust(ctx context.Context, arg UpdateUserTrustParams) (User, error)
+	UpsertConversationSettings(ctx context.Context, arg UpsertConversationSettingsParams) (ConversationSetting, error)
+	// A re-upload keeps the longer of the two retention windows
+	UpsertMediaObject(ctx context.Context, arg UpsertMediaObjectParams) (MediaObject, error)
+	// Settings passed as null keep their current value, or the default on a new row
 	UpsertPrivacySettings(ctx context.Context, arg UpsertPrivacySettingsParams) (PrivacySetting, error)
 }
 
@@ -13,24 +13,52 @@ import (
 	"github.com/google/uuid"
 )
 
+const countOpenStoryReports = `-- name: CountOpenStoryReports :one
+SELECT COUNT(DISTINCT reporter_id) FROM reports
+WHERE target_story_id = $1 AND is_resolved = false
+`
+
+// Distinct reporters with an open report on a story
+func (q *Queries) CountOpenStoryReports(ctx context.Context, targetStoryID uuid.NullUUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOpenStoryReports, targetStoryID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countReports = `-- name: CountReports :one
+SELECT COUNT(*) FROM reports
+WHERE is_resolved = $1
+`
+
+// Admin: Count reports for pagination
+func (q *Queries) CountReports(ctx context.Context, isResolved bool) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countReports, isResolved)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createReport = `-- name: CreateReport :one
 INSERT INTO reports (
   reporter_id,
   target_user_id,
   target_story_id,
+  target_message_id,
   reason,
   description
 ) VALUES (
-  $1, $2, $3, $4, $5
-) RETURNING id, reporter_id, target_user_id, target_story_id, reason, description, is_resolved, created_at
+  $1, $2, $3, $4, $5, $6
+) RETURNING id, reporter_id, target_user_id, target_story_id, reason, description, is_resolved, created_at, target_message_id
 `
 
 type CreateReportParams struct {
-	ReporterID    uuid.UUID      `json:"reporter_id"`
-	TargetUserID  uuid.NullUUID  `json:"target_user_id"`
-	TargetStoryID uuid.NullUUID  `json:"target_story_id"`
-	Reason        ReportReason   `json:"reason"`
-	Description   sql.NullString `json:"description"`
+	ReporterID      uuid.UUID      `json:"reporter_id"`
+	TargetUserID    uuid.NullUUID  `json:"target_user_id"`
+	TargetStoryID   uuid.NullUUID  `json:"target_story_id"`
+	TargetMessageID uuid.NullUUID  `json:"target_message_id"`
+	Reason          ReportReason   `json:"reason"`
+	Description     sql.NullString `json:"description"`
 }
 
 func (q *Queries) CreateReport(ctx context.Context, arg CreateReportParams) (Report, error) {
@@ -38,6 +66,7 @@ func (q *Queries) CreateReport(ctx context.Context, arg CreateReportParams) (Rep
 		arg.ReporterID,
 		arg.TargetUserID,
 		arg.TargetStoryID,
+		arg.TargetMessageID,
 		arg.Reason,
 		arg.Description,
 	)
@@ -51,12 +80,13 @@ func (q *Queries) CreateReport(ctx context.Context, arg CreateReportParams) (Rep
 		&i.Description,
 		&i.IsResolved,
 		&i.CreatedAt,
+		&i.TargetMessageID,
 	)
 	return i, err
 }
 
 const listReports = `-- name: ListReports :many
-SELECT r.id, r.reporter_id, r.target_user_id, r.target_story_id, r.reason, r.description, r.is_resolved, r.created_at, 
+SELECT r.id, r.reporter_id, r.target_user_id, r.target_story_id, r.reason, r.description, r.is_resolved, r.created_at, r.target_message_id, 
   u1.username as reporter_username,
   u2.username as target_username
 FROM reports r
@@ -82,6 +112,7 @@ type ListReportsRow struct {
 	Description      sql.NullString `json:"description"`
 	IsResolved       bool           `json:"is_resolved"`
 	CreatedAt        time.Time      `json:"created_at"`
+	TargetMessageID  uuid.NullUUID  `json:"target_message_id"`
 	ReporterUsername sql.NullString `json:"reporter_username"`
 	TargetUsername   sql.NullString `json:"target_username"`
 }
@@ -105,6 +136,7 @@ func (q *Queries) ListReports(ctx context.Context, arg ListReportsParams) ([]Lis
 			&i.Description,
 			&i.IsResolved,
 			&i.CreatedAt,
+			&i.TargetMessageID,
 			&i.ReporterUsername,
 			&i.TargetUsername,
 		); err != nil {
@@ -125,7 +157,7 @@ const resolveReport = `-- name: ResolveReport :one
 UPDATE reports
 SET is_resolved = true
 WHERE id = $1
-RETURNING id, reporter_id, target_user_id, target_story_id, reason, description, is_resolved, created_at
+RETURNING id, reporter_id, target_user_id, target_story_id, reason, description, is_resolved, created_at, target_message_id
 `
 
 // Admin: Resolve report
@@ -141,6 +173,7 @@ func (q *Queries) ResolveReport(ctx context.Context, id uuid.UUID) (Report, erro
 		&i.Description,
 		&i.IsResolved,
 		&i.CreatedAt,
+		&i.TargetMessageID,
 	)
 	return i, err
 }
@@ -12,6 +12,40 @@ import (
 	"github.com/google/uuid"
 )
 
+const blockSession = `-- name: BlockSession :execrows
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1 AND user_id = $2
+`
+
+type BlockSessionParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// Blocks one of a user's sessions even if it already is, so logging out twice succeeds
+func (q *Queries) BlockSession(ctx context.Context, arg BlockSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, blockSession, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const blockUserSessions = `-- name: BlockUserSessions :execrows
+UPDATE sessions
+SET is_blocked = true
+WHERE user_id = $1 AND is_blocked = false
+`
+
+func (q *Queries) BlockUserSessions(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, blockUserSessions, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createSession = `-- name: CreateSession :one
 INSERT INTO sessions (
   id,
@@ -20,10 +54,12 @@ INSERT INTO sessions (
   user_agent,
   client_ip,
   is_blocked,
-  expires_at
+  expires_at,
+  device_name,
+  platform
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7
-) RETURNING id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at, device_name, platform
 `
 
 type CreateSessionParams struct {
@@ -34,6 +70,8 @@ type CreateSessionParams struct {
 	ClientIp     string    `json:"client_ip"`
 	IsBlocked    bool      `json:"is_blocked"`
 	ExpiresAt    time.Time `json:"expires_at"`
+	DeviceName   string    `json:"device_name"`
+	Platform     string    `json:"platform"`
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
@@ -45,6 +83,8 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		arg.ClientIp,
 		arg.IsBlocked,
 		arg.ExpiresAt,
+		arg.DeviceName,
+		arg.Platform,
 	)
 	var i Session
 	err := row.Scan(
@@ -56,12 +96,14 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.IsBlocked,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.DeviceName,
+		&i.Platform,
 	)
 	return i, err
 }
 
 const getSession = `-- name: GetSession :one
-SELECT id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at FROM sessions
+SELECT id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at, device_name, platform FROM sessions
 WHERE id = $1 LIMIT 1
 `
 
@@ -77,6 +119,99 @@ func (q *Queries) GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 		&i.IsBlocked,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.DeviceName,
+		&i.Platform,
 	)
 	return i, err
 }
+
+const listActiveSessions = `-- name: ListActiveSessions :many
+SELECT id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at, created_at, device_name, platform FROM sessions
+WHERE user_id = $1
+  AND is_blocked = false
+  AND expires_at > now()
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Session
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.RefreshToken,
+			&i.UserAgent,
+			&i.ClientIp,
+			&i.IsBlocked,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+			&i.DeviceName,
+			&i.Platform,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeExcessSessions = `-- name: RevokeExcessSessions :execrows
+UPDATE sessions
+SET is_blocked = true
+WHERE user_id = $1
+  AND is_blocked = false
+  AND expires_at > now()
+  AND id NOT IN (
+    SELECT s.id FROM sessions s
+    WHERE s.user_id = $1
+      AND s.is_blocked = false
+      AND s.expires_at > now()
+    ORDER BY s.created_at DESC
+    LIMIT $2
+  )
+`
+
+type RevokeExcessSessionsParams struct {
+	UserID      uuid.UUID `json:"user_id"`
+	MaxSessions int32     `json:"max_sessions"`
+}
+
+// Keeps the newest max_sessions active sessions and revokes the rest
+func (q *Queries) RevokeExcessSessions(ctx context.Context, arg RevokeExcessSessionsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeExcessSessions, arg.UserID, arg.MaxSessions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeSession = `-- name: RevokeSession :execrows
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1 AND user_id = $2 AND is_blocked = false
+`
+
+type RevokeSessionParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeSession, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
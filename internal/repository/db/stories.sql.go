@@ -11,8 +11,20 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"privacy-social-backend/internal/util"
 )
 
+const countStories = `-- name: CountStories :one
+SELECT COUNT(*) FROM stories
+`
+
+func (q *Queries) CountStories(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countStories)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createStory = `-- name: CreateStory :one
 INSERT INTO stories (
   user_id,
@@ -24,24 +36,26 @@ INSERT INTO stories (
   is_anonymous,
   show_location,
   is_premium,
-  expires_at
+  expires_at,
+  visibility
 ) VALUES (
-  $1, $2, $3, $4, $5, ST_SetSRID(ST_MakePoint($6::float8, $7::float8), 4326), $8, $9, $10, $11
+  $1, $2, $3, $4, $5, ST_SetSRID(ST_MakePoint($6::float8, $7::float8), 4326), $8, $9, $10, $11, $12
 ) RETURNING id, user_id, media_url, media_type, thumbnail_url, caption, geohash, geom, visibility, expires_at, created_at, is_anonymous, is_premium, show_location, ST_Y(geom::geometry) as lat, ST_X(geom::geometry) as lng
 `
 
 type CreateStoryParams struct {
-	UserID       uuid.UUID      `json:"user_id"`
-	MediaUrl     string         `json:"media_url"`
-	MediaType    string         `json:"media_type"`
-	Caption      sql.NullString `json:"caption"`
-	Geohash      string         `json:"geohash"`
-	Lng          float64        `json:"lng"`
-	Lat          float64        `json:"lat"`
-	IsAnonymous  bool           `json:"is_anonymous"`
-	ShowLocation bool           `json:"show_location"`
-	IsPremium    sql.NullBool   `json:"is_premium"`
-	ExpiresAt    time.Time      `json:"expires_at"`
+	UserID       uuid.UUID         `json:"user_id"`
+	MediaUrl     string            `json:"media_url"`
+	MediaType    string            `json:"media_type"`
+	Caption      sql.NullString    `json:"caption"`
+	Geohash      string            `json:"geohash"`
+	Lng          float64           `json:"lng"`
+	Lat          float64           `json:"lat"`
+	IsAnonymous  bool              `json:"is_anonymous"`
+	ShowLocation bool              `json:"show_location"`
+	IsPremium    sql.NullBool      `json:"is_premium"`
+	ExpiresAt    time.Time         `json:"expires_at"`
+	Visibility   StoryAvailability `json:"visibility"`
 }
 
 type CreateStoryRow struct {
@@ -76,6 +90,7 @@ func (q *Queries) CreateStory(ctx context.Context, arg CreateStoryParams) (Creat
 		arg.ShowLocation,
 		arg.IsPremium,
 		arg.ExpiresAt,
+		arg.Visibility,
 	)
 	var i CreateStoryRow
 	err := row.Scan(
@@ -122,7 +137,8 @@ func (q *Queries) DeleteStory(ctx context.Context, id uuid.UUID) error {
 
 const getConnectionStories = `-- name: GetConnectionStories :many
 SELECT s.id, s.user_id, s.media_url, s.media_type, s.thumbnail_url, s.caption, s.geohash, s.geom, s.visibility, s.expires_at, s.created_at, s.is_anonymous, s.is_premium, s.show_location, u.username, u.avatar_url, u.is_premium,
-       ST_Y(s.geom::geometry) as lat, ST_X(s.geom::geometry) as lng
+       ST_Y(s.geom::geometry) as lat, ST_X(s.geom::geometry) as lng,
+       (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = s.id)::bigint AS reaction_count
 FROM stories s
 JOIN users u ON s.user_id = u.id
 JOIN connections c ON 
@@ -133,6 +149,10 @@ WHERE
   AND s.expires_at > now()
   AND u.is_shadow_banned = false
   AND u.is_shadow_banned = false
+  -- Stories held for moderation stay out of feeds until released
+  AND NOT EXISTS (
+    SELECT 1 FROM story_moderation_holds smh WHERE smh.story_id = s.id
+  )
   -- strict streak rule (DISABLED)
   -- AND DATE(u.last_active_at) >= CURRENT_DATE - INTERVAL '1 day'
   -- Block Logic: Exclude if blocked by either party
@@ -141,29 +161,34 @@ WHERE
     WHERE (bu.blocker_id = $1 AND bu.blocked_id = s.user_id)
        OR (bu.blocker_id = s.user_id AND bu.blocked_id = $1)
   )
+  -- Close-friends stories only reach the author's close friends
+  AND (s.visibility <> 'close_friends' OR EXISTS (
+    SELECT 1 FROM close_friends cf WHERE cf.user_id = s.user_id AND cf.friend_id = $1
+  ))
 ORDER BY s.created_at DESC
 `
 
 type GetConnectionStoriesRow struct {
-	ID           uuid.UUID         `json:"id"`
-	UserID       uuid.UUID         `json:"user_id"`
-	MediaUrl     string            `json:"media_url"`
-	MediaType    string            `json:"media_type"`
-	ThumbnailUrl sql.NullString    `json:"thumbnail_url"`
-	Caption      sql.NullString    `json:"caption"`
-	Geohash      string            `json:"geohash"`
-	Geom         interface{}       `json:"geom"`
-	Visibility   StoryAvailability `json:"visibility"`
-	ExpiresAt    time.Time         `json:"expires_at"`
-	CreatedAt    time.Time         `json:"created_at"`
-	IsAnonymous  bool              `json:"is_anonymous"`
-	IsPremium    sql.NullBool      `json:"is_premium"`
-	ShowLocation bool              `json:"show_location"`
-	Username     string            `json:"username"`
-	AvatarUrl    sql.NullString    `json:"avatar_url"`
-	IsPremium_2  sql.NullBool      `json:"is_premium_2"`
-	Lat          interface{}       `json:"lat"`
-	Lng          interface{}       `json:"lng"`
+	ID            uuid.UUID         `json:"id"`
+	UserID        uuid.UUID         `json:"user_id"`
+	MediaUrl      string            `json:"media_url"`
+	MediaType     string            `json:"media_type"`
+	ThumbnailUrl  sql.NullString    `json:"thumbnail_url"`
+	Caption       sql.NullString    `json:"caption"`
+	Geohash       string            `json:"geohash"`
+	Geom          interface{}       `json:"geom"`
+	Visibility    StoryAvailability `json:"visibility"`
+	ExpiresAt     time.Time         `json:"expires_at"`
+	CreatedAt     time.Time         `json:"created_at"`
+	IsAnonymous   bool              `json:"is_anonymous"`
+	IsPremium     sql.NullBool      `json:"is_premium"`
+	ShowLocation  bool              `json:"show_location"`
+	Username      string            `json:"username"`
+	AvatarUrl     sql.NullString    `json:"avatar_url"`
+	IsPremium_2   sql.NullBool      `json:"is_premium_2"`
+	Lat           interface{}       `json:"lat"`
+	Lng           interface{}       `json:"lng"`
+	ReactionCount int64             `json:"reaction_count"`
 }
 
 // Get stories from connected users (not limited by radius)
@@ -196,6 +221,7 @@ func (q *Queries) GetConnectionStories(ctx context.Context, userID uuid.UUID) ([
 			&i.IsPremium_2,
 			&i.Lat,
 			&i.Lng,
+			&i.ReactionCount,
 		); err != nil {
 			return nil, err
 		}
@@ -212,18 +238,35 @@ func (q *Queries) GetConnectionStories(ctx context.Context, userID uuid.UUID) ([
 
 const getStoriesInBounds = `-- name: GetStoriesInBounds :many
 SELECT s.id, s.user_id, s.media_url, s.media_type, s.thumbnail_url, s.caption, s.geohash, s.geom, s.visibility, s.expires_at, s.created_at, s.is_anonymous, s.is_premium, s.show_location, u.username, u.avatar_url,
-       ST_Y(s.geom::geometry) as lat, ST_X(s.geom::geometry) as lng
+       ST_Y(s.geom::geometry) as lat, ST_X(s.geom::geometry) as lng,
+       (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = s.id)::bigint AS reaction_count
 FROM stories s
 JOIN users u ON s.user_id = u.id
 WHERE s.geom && ST_MakeEnvelope($1::float8, $2::float8, $3::float8, $4::float8, 4326)
 AND s.expires_at > now()
 AND u.is_shadow_banned = false
 AND u.is_ghost_mode = false
+AND (s.user_id = $5 OR NOT EXISTS (
+    SELECT 1 FROM story_moderation_holds smh WHERE smh.story_id = s.id
+))
 AND NOT EXISTS (
     SELECT 1 FROM blocked_users bu 
     WHERE (bu.blocker_id = $5 AND bu.blocked_id = s.user_id)
        OR (bu.blocker_id = s.user_id AND bu.blocked_id = $5)
 )
+-- Story audience: connections and close_friends stories stay within that audience
+AND (
+  s.visibility = 'public'
+  OR s.user_id = $5
+  OR (s.visibility = 'connections' AND EXISTS (
+    SELECT 1 FROM connections c
+    WHERE (c.requester_id = $5 AND c.target_id = s.user_id OR c.requester_id = s.user_id AND c.target_id = $5)
+    AND c.status = 'accepted'
+  ))
+  OR (s.visibility = 'close_friends' AND EXISTS (
+    SELECT 1 FROM close_friends cf WHERE cf.user_id = s.user_id AND cf.friend_id = $5
+  ))
+)
 AND (
     s.user_id = $5
     OR
@@ -258,24 +301,25 @@ type GetStoriesInBoundsParams struct {
 }
 
 type GetStoriesInBoundsRow struct {
-	ID           uuid.UUID         `json:"id"`
-	UserID       uuid.UUID         `json:"user_id"`
-	MediaUrl     string            `json:"media_url"`
-	MediaType    string            `json:"media_type"`
-	ThumbnailUrl sql.NullString    `json:"thumbnail_url"`
-	Caption      sql.NullString    `json:"caption"`
-	Geohash      string            `json:"geohash"`
-	Geom         interface{}       `json:"geom"`
-	Visibility   StoryAvailability `json:"visibility"`
-	ExpiresAt    time.Time         `json:"expires_at"`
-	CreatedAt    time.Time         `json:"created_at"`
-	IsAnonymous  bool              `json:"is_anonymous"`
-	IsPremium    sql.NullBool      `json:"is_premium"`
-	ShowLocation bool              `json:"show_location"`
-	Username     string            `json:"username"`
-	AvatarUrl    sql.NullString    `json:"avatar_url"`
-	Lat          interface{}       `json:"lat"`
-	Lng          interface{}       `json:"lng"`
+	ID            uuid.UUID         `json:"id"`
+	UserID        uuid.UUID         `json:"user_id"`
+	MediaUrl      string            `json:"media_url"`
+	MediaType     string            `json:"media_type"`
+	ThumbnailUrl  sql.NullString    `json:"thumbnail_url"`
+	Caption       sql.NullString    `json:"caption"`
+	Geohash       string            `json:"geohash"`
+	Geom          interface{}       `json:"geom"`
+	Visibility    StoryAvailability `json:"visibility"`
+	ExpiresAt     time.Time         `json:"expires_at"`
+	CreatedAt     time.Time         `json:"created_at"`
+	IsAnonymous   bool              `json:"is_anonymous"`
+	IsPremium     sql.NullBool      `json:"is_premium"`
+	ShowLocation  bool              `json:"show_location"`
+	Username      string            `json:"username"`
+	AvatarUrl     sql.NullString    `json:"avatar_url"`
+	Lat           interface{}       `json:"lat"`
+	Lng           interface{}       `json:"lng"`
+	ReactionCount int64             `json:"reaction_count"`
 }
 
 // Get stories within a bounding box for map view
@@ -314,6 +358,7 @@ func (q *Queries) GetStoriesInBounds(ctx context.Context, arg GetStoriesInBounds
 			&i.AvatarUrl,
 			&i.Lat,
 			&i.Lng,
+			&i.ReactionCount,
 		); err != nil {
 			return nil, err
 		}
@@ -330,7 +375,10 @@ func (q *Queries) GetStoriesInBounds(ctx context.Context, arg GetStoriesInBounds
 
 const getStoriesWithinRadius = `-- name: GetStoriesWithinRadius :many
 SELECT s.id, s.user_id, s.media_url, s.media_type, s.thumbnail_url, s.caption, s.geohash, s.geom, s.visibility, s.expires_at, s.created_at, s.is_anonymous, s.is_premium, s.show_location, u.username, u.avatar_url, u.is_premium,
-       ST_Y(s.geom::geometry) as lat, ST_X(s.geom::geometry) as lng
+       ST_Y(s.geom::geometry) as lat, ST_X(s.geom::geometry) as lng,
+       (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = s.id)::bigint AS reaction_count,
+       -- Matches before the LIMIT, so clients can tell when the feed was capped
+       COUNT(*) OVER() AS total_count
 FROM stories s
 JOIN users u ON s.user_id = u.id
 WHERE 
@@ -339,23 +387,47 @@ WHERE
     ST_MakePoint($1::float8, $2::float8)::geography,
     $3
   )
-  AND s.expires_at > now()
+  AND s.expires_at > $4::timestamptz
+  -- Incremental refresh: only stories posted after the client's last fetch
+  AND ($8::timestamptz IS NULL OR s.created_at > $8::timestamptz)
+  -- Hashtag discovery: only stories tagged with the requested hashtag
+  AND ($9::text IS NULL OR EXISTS (
+    SELECT 1 FROM story_hashtags sh
+    WHERE sh.story_id = s.id AND sh.tag = $9::text
+  ))
   -- Allow anonymous stories (handled in presentation)
   -- AND (s.is_anonymous = false OR s.user_id = @user_id)
   AND u.is_shadow_banned = false
   AND u.is_ghost_mode = false
+  -- Stories held for moderation are only visible to their author
+  AND (s.user_id = $5 OR NOT EXISTS (
+    SELECT 1 FROM story_moderation_holds smh WHERE smh.story_id = s.id
+  ))
   -- Strict Streak Rule (DISABLED)
   -- AND DATE(u.last_active_at) >= CURRENT_DATE - INTERVAL '1 day'
   -- Block Logic: Exclude if blocked by either party (using blocked_users table)
   AND NOT EXISTS (
     SELECT 1 FROM blocked_users bu 
-    WHERE (bu.blocker_id = $4 AND bu.blocked_id = s.user_id)
-       OR (bu.blocker_id = s.user_id AND bu.blocked_id = $4)
+    WHERE (bu.blocker_id = $5 AND bu.blocked_id = s.user_id)
+       OR (bu.blocker_id = s.user_id AND bu.blocked_id = $5)
+  )
+  -- Story audience: connections and close_friends stories stay within that audience
+  AND (
+    s.visibility = 'public'
+    OR s.user_id = $5
+    OR (s.visibility = 'connections' AND EXISTS (
+      SELECT 1 FROM connections c
+      WHERE (c.requester_id = $5 AND c.target_id = s.user_id OR c.requester_id = s.user_id AND c.target_id = $5)
+      AND c.status = 'accepted'
+    ))
+    OR (s.visibility = 'close_friends' AND EXISTS (
+      SELECT 1 FROM close_friends cf WHERE cf.user_id = s.user_id AND cf.friend_id = $5
+    ))
   )
   -- Privacy Settings Logic --
   AND (
     -- Case 1: My own stories (always visible)
-    s.user_id = $4
+    s.user_id = $5
     OR
     (
       -- Case 2: User is NOT in Ghost Mode (using privacy_settings)
@@ -369,7 +441,7 @@ WHERE
           OR
           (ps.who_can_see_stories = 'connections' AND EXISTS (
              SELECT 1 FROM connections c 
-             WHERE (c.requester_id = $4 AND c.target_id = s.user_id OR c.requester_id = s.user_id AND c.target_id = $4)
+             WHERE (c.requester_id = $5 AND c.target_id = s.user_id OR c.requester_id = s.user_id AND c.target_id = $5)
              AND c.status = 'accepted'
           ))
         )
@@ -379,38 +451,62 @@ WHERE
        NOT EXISTS (SELECT 1 FROM privacy_settings ps WHERE ps.user_id = s.user_id)
     )
   )
+  -- Connection-gated deployments: strangers only appear when they opted into a public audience
+  AND (
+    NOT $6::boolean
+    OR s.user_id = $5
+    OR EXISTS (
+      SELECT 1 FROM connections c
+      WHERE (c.requester_id = $5 AND c.target_id = s.user_id OR c.requester_id = s.user_id AND c.target_id = $5)
+      AND c.status = 'accepted'
+    )
+    OR (
+      s.visibility = 'public'
+      AND EXISTS (
+        SELECT 1 FROM privacy_settings ps
+        WHERE ps.user_id = s.user_id AND ps.who_can_see_stories = 'everyone'
+      )
+    )
+  )
 ORDER BY 
   s.geom <-> ST_SetSRID(ST_MakePoint($1::float8, $2::float8), 4326)
-LIMIT 50
+LIMIT $7
 `
 
 type GetStoriesWithinRadiusParams struct {
-	Lng          float64     `json:"lng"`
-	Lat          float64     `json:"lat"`
-	RadiusMeters interface{} `json:"radius_meters"`
-	UserID       uuid.UUID   `json:"user_id"`
+	Lng             float64        `json:"lng"`
+	Lat             float64        `json:"lat"`
+	RadiusMeters    interface{}    `json:"radius_meters"`
+	Now             time.Time      `json:"now"`
+	UserID          uuid.UUID      `json:"user_id"`
+	ConnectionsOnly bool           `json:"connections_only"`
+	ResultLimit     int32          `json:"result_limit"`
+	Since           util.NullTime  `json:"since"`
+	Hashtag         sql.NullString `json:"hashtag"`
 }
 
 type GetStoriesWithinRadiusRow struct {
-	ID           uuid.UUID         `json:"id"`
-	UserID       uuid.UUID         `json:"user_id"`
-	MediaUrl     string            `json:"media_url"`
-	MediaType    string            `json:"media_type"`
-	ThumbnailUrl sql.NullString    `json:"thumbnail_url"`
-	Caption      sql.NullString    `json:"caption"`
-	Geohash      string            `json:"geohash"`
-	Geom         interface{}       `json:"geom"`
-	Visibility   StoryAvailability `json:"visibility"`
-	ExpiresAt    time.Time         `json:"expires_at"`
-	CreatedAt    time.Time         `json:"created_at"`
-	IsAnonymous  bool              `json:"is_anonymous"`
-	IsPremium    sql.NullBool      `json:"is_premium"`
-	ShowLocation bool              `json:"show_location"`
-	Username     string            `json:"username"`
-	AvatarUrl    sql.NullString    `json:"avatar_url"`
-	IsPremium_2  sql.NullBool      `json:"is_premium_2"`
-	Lat          interface{}       `json:"lat"`
-	Lng          interface{}       `json:"lng"`
+	ID            uuid.UUID         `json:"id"`
+	UserID        uuid.UUID         `json:"user_id"`
+	MediaUrl      string            `json:"media_url"`
+	MediaType     string            `json:"media_type"`
+	ThumbnailUrl  sql.NullString    `json:"thumbnail_url"`
+	Caption       sql.NullString    `json:"caption"`
+	Geohash       string            `json:"geohash"`
+	Geom          interface{}       `json:"geom"`
+	Visibility    StoryAvailability `json:"visibility"`
+	ExpiresAt     time.Time         `json:"expires_at"`
+	CreatedAt     time.Time         `json:"created_at"`
+	IsAnonymous   bool              `json:"is_anonymous"`
+	IsPremium     sql.NullBool      `json:"is_premium"`
+	ShowLocation  bool              `json:"show_location"`
+	Username      string            `json:"username"`
+	AvatarUrl     sql.NullString    `json:"avatar_url"`
+	IsPremium_2   sql.NullBool      `json:"is_premium_2"`
+	Lat           interface{}       `json:"lat"`
+	Lng           interface{}       `json:"lng"`
+	ReactionCount int64             `json:"reaction_count"`
+	TotalCount    int64             `json:"total_count"`
 }
 
 func (q *Queries) GetStoriesWithinRadius(ctx context.Context, arg GetStoriesWithinRadiusParams) ([]GetStoriesWithinRadiusRow, error) {
@@ -418,7 +514,12 @@ func (q *Queries) GetStoriesWithinRadius(ctx context.Context, arg GetStoriesWith
 		arg.Lng,
 		arg.Lat,
 		arg.RadiusMeters,
+		arg.Now,
 		arg.UserID,
+		arg.ConnectionsOnly,
+		arg.ResultLimit,
+		arg.Since,
+		arg.Hashtag,
 	)
 	if err != nil {
 		return nil, err
@@ -447,6 +548,8 @@ func (q *Queries) GetStoriesWithinRadius(ctx context.Context, arg GetStoriesWith
 			&i.IsPremium_2,
 			&i.Lat,
 			&i.Lng,
+			&i.ReactionCount,
+			&i.TotalCount,
 		); err != nil {
 			return nil, err
 		}
@@ -462,27 +565,30 @@ func (q *Queries) GetStoriesWithinRadius(ctx context.Context, arg GetStoriesWith
 }
 
 const getStoryByID = `-- name: GetStoryByID :one
-SELECT id, user_id, media_url, media_type, thumbnail_url, caption, geohash, geom, visibility, expires_at, created_at, is_anonymous, is_premium, show_location, ST_Y(geom::geometry) as lat, ST_X(geom::geometry) as lng FROM stories
+SELECT id, user_id, media_url, media_type, thumbnail_url, caption, geohash, geom, visibility, expires_at, created_at, is_anonymous, is_premium, show_location, ST_Y(geom::geometry) as lat, ST_X(geom::geometry) as lng,
+       (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = stories.id)::bigint AS reaction_count
+FROM stories
 WHERE id = $1 LIMIT 1
 `
 
 type GetStoryByIDRow struct {
-	ID           uuid.UUID         `json:"id"`
-	UserID       uuid.UUID         `json:"user_id"`
-	MediaUrl     string            `json:"media_url"`
-	MediaType    string            `json:"media_type"`
-	ThumbnailUrl sql.NullString    `json:"thumbnail_url"`
-	Caption      sql.NullString    `json:"caption"`
-	Geohash      string            `json:"geohash"`
-	Geom         interface{}       `json:"geom"`
-	Visibility   StoryAvailability `json:"visibility"`
-	ExpiresAt    time.Time         `json:"expires_at"`
-	CreatedAt    time.Time         `json:"created_at"`
-	IsAnonymous  bool              `json:"is_anonymous"`
-	IsPremium    sql.NullBool      `json:"is_premium"`
-	ShowLocation bool              `json:"show_location"`
-	Lat          interface{}       `json:"lat"`
-	Lng          interface{}       `json:"lng"`
+	ID            uuid.UUID         `json:"id"`
+	UserID        uuid.UUID         `json:"user_id"`
+	MediaUrl      string            `json:"media_url"`
+	MediaType     string            `json:"media_type"`
+	ThumbnailUrl  sql.NullString    `json:"thumbnail_url"`
+	Caption       sql.NullString    `json:"caption"`
+	Geohash       string            `json:"geohash"`
+	Geom          interface{}       `json:"geom"`
+	Visibility    StoryAvailability `json:"visibility"`
+	ExpiresAt     time.Time         `json:"expires_at"`
+	CreatedAt     time.Time         `json:"created_at"`
+	IsAnonymous   bool              `json:"is_anonymous"`
+	IsPremium     sql.NullBool      `json:"is_premium"`
+	ShowLocation  bool              `json:"show_location"`
+	Lat           interface{}       `json:"lat"`
+	Lng           interface{}       `json:"lng"`
+	ReactionCount int64             `json:"reaction_count"`
 }
 
 func (q *Queries) GetStoryByID(ctx context.Context, id uuid.UUID) (GetStoryByIDRow, error) {
@@ -505,6 +611,7 @@ func (q *Queries) GetStoryByID(ctx context.Context, id uuid.UUID) (GetStoryByIDR
 		&i.ShowLocation,
 		&i.Lat,
 		&i.Lng,
+		&i.ReactionCount,
 	)
 	return i, err
 }
@@ -617,6 +724,255 @@ func (q *Queries) ListAllStories(ctx context.Context, arg ListAllStoriesParams)
 	return items, nil
 }
 
+const listConnectionStoryRings = `-- name: ListConnectionStoryRings :many
+SELECT s.id, s.user_id, s.media_url, s.media_type, s.thumbnail_url, s.caption, s.geohash,
+       s.visibility, s.expires_at, s.created_at, s.is_anonymous, s.show_location,
+       u.username, u.avatar_url, u.is_premium,
+       ST_Y(s.geom::geometry) as lat, ST_X(s.geom::geometry) as lng,
+       (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = s.id)::bigint AS reaction_count,
+       (sv.id IS NOT NULL)::bool AS seen,
+       bool_or(sv.id IS NULL) OVER (PARTITION BY s.user_id)::bool AS author_has_unseen,
+       max(s.created_at) OVER (PARTITION BY s.user_id)::timestamptz AS author_latest_at
+FROM stories s
+JOIN users u ON s.user_id = u.id
+JOIN connections c ON
+  (c.requester_id = $1 AND c.target_id = s.user_id) OR
+  (c.target_id = $1 AND c.requester_id = s.user_id)
+LEFT JOIN story_views sv ON sv.story_id = s.id AND sv.user_id = $1
+WHERE
+  c.status = 'accepted'
+  AND s.expires_at > now()
+  AND s.is_anonymous = false
+  AND u.is_shadow_banned = false
+  AND NOT EXISTS (
+    SELECT 1 FROM story_moderation_holds smh WHERE smh.story_id = s.id
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM blocked_users bu
+    WHERE (bu.blocker_id = $1 AND bu.blocked_id = s.user_id)
+       OR (bu.blocker_id = s.user_id AND bu.blocked_id = $1)
+  )
+  -- Close-friends stories only reach the author's close friends
+  AND (s.visibility <> 'close_friends' OR EXISTS (
+    SELECT 1 FROM close_friends cf WHERE cf.user_id = s.user_id AND cf.friend_id = $1
+  ))
+ORDER BY author_has_unseen DESC, author_latest_at DESC, s.user_id, s.created_at ASC
+`
+
+type ListConnectionStoryRingsRow struct {
+	ID              uuid.UUID         `json:"id"`
+	UserID          uuid.UUID         `json:"user_id"`
+	MediaUrl        string            `json:"media_url"`
+	MediaType       string            `json:"media_type"`
+	ThumbnailUrl    sql.NullString    `json:"thumbnail_url"`
+	Caption         sql.NullString    `json:"caption"`
+	Geohash         string            `json:"geohash"`
+	Visibility      StoryAvailability `json:"visibility"`
+	ExpiresAt       time.Time         `json:"expires_at"`
+	CreatedAt       time.Time         `json:"created_at"`
+	IsAnonymous     bool              `json:"is_anonymous"`
+	ShowLocation    bool              `json:"show_location"`
+	Username        string            `json:"username"`
+	AvatarUrl       sql.NullString    `json:"avatar_url"`
+	IsPremium       sql.NullBool      `json:"is_premium"`
+	Lat             interface{}       `json:"lat"`
+	Lng             interface{}       `json:"lng"`
+	ReactionCount   int64             `json:"reaction_count"`
+	Seen            bool              `json:"seen"`
+	AuthorHasUnseen bool              `json:"author_has_unseen"`
+	AuthorLatestAt  time.Time         `json:"author_latest_at"`
+}
+
+// Live stories of the viewer's connections with the viewer's seen state, ordered
+// as a stories tray: authors with unseen stories first, then by their latest
+// story; each author's stories oldest first. Anonymous stories are left out
+// because a ring names its author.
+func (q *Queries) ListConnectionStoryRings(ctx context.Context, userID uuid.UUID) ([]ListConnectionStoryRingsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listConnectionStoryRings, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListConnectionStoryRingsRow
+	for rows.Next() {
+		var i ListConnectionStoryRingsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.MediaUrl,
+			&i.MediaType,
+			&i.ThumbnailUrl,
+			&i.Caption,
+			&i.Geohash,
+			&i.Visibility,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+			&i.IsAnonymous,
+			&i.ShowLocation,
+			&i.Username,
+			&i.AvatarUrl,
+			&i.IsPremium,
+			&i.Lat,
+			&i.Lng,
+			&i.ReactionCount,
+			&i.Seen,
+			&i.AuthorHasUnseen,
+			&i.AuthorLatestAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMyStories = `-- name: ListMyStories :many
+SELECT story_id, archive_id, media_url, media_type, thumbnail_url, caption, is_anonymous, show_location, created_at, expires_at, is_highlight, view_count, reaction_count, COUNT(*) OVER() AS total_count FROM (
+  SELECT s.id AS story_id,
+         NULL::uuid AS archive_id,
+         s.media_url,
+         s.media_type,
+         s.thumbnail_url,
+         s.caption,
+         s.is_anonymous,
+         s.show_location,
+         s.created_at,
+         s.expires_at::timestamptz AS expires_at,
+         false AS is_highlight,
+         (SELECT COUNT(*) FROM story_views sv WHERE sv.story_id = s.id)::bigint AS view_count,
+         (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = s.id)::bigint AS reaction_count
+  FROM stories s
+  WHERE s.user_id = $1
+    AND s.expires_at > $2::timestamptz
+  UNION ALL
+  SELECT a.story_id,
+         a.id,
+         a.media_url,
+         a.media_type,
+         NULL::text,
+         a.caption,
+         COALESCE(a.is_anonymous, false),
+         COALESCE(a.show_location, true),
+         a.original_created_at,
+         NULL::timestamptz,
+         true,
+         (SELECT COUNT(*) FROM story_views sv WHERE sv.story_id = a.story_id)::bigint,
+         (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = a.story_id)::bigint
+  FROM archived_stories a
+  WHERE a.user_id = $1
+    -- A story archived while still live is listed once, as the live story
+    AND NOT EXISTS (
+      SELECT 1 FROM stories s
+      WHERE s.id = a.story_id AND s.expires_at > $2::timestamptz
+    )
+) mine
+ORDER BY created_at DESC
+LIMIT $3 OFFSET $4;
+`
+
+type ListMyStoriesParams struct {
+	UserID     uuid.UUID `json:"user_id"`
+	Now        time.Time `json:"now"`
+	PageLimit  int32     `json:"page_limit"`
+	PageOffset int32     `json:"page_offset"`
+}
+
+type ListMyStoriesRow struct {
+	StoryID       uuid.UUID      `json:"story_id"`
+	ArchiveID     uuid.NullUUID  `json:"archive_id"`
+	MediaUrl      string         `json:"media_url"`
+	MediaType     string         `json:"media_type"`
+	ThumbnailUrl  sql.NullString `json:"thumbnail_url"`
+	Caption       sql.NullString `json:"caption"`
+	IsAnonymous   bool           `json:"is_anonymous"`
+	ShowLocation  bool           `json:"show_location"`
+	CreatedAt     time.Time      `json:"created_at"`
+	ExpiresAt     util.NullTime  `json:"expires_at"`
+	IsHighlight   bool           `json:"is_highlight"`
+	ViewCount     int64          `json:"view_count"`
+	ReactionCount int64          `json:"reaction_count"`
+	TotalCount    int64          `json:"total_count"`
+}
+
+// The owner's live stories plus archived (highlighted) stories whose original has expired, newest first
+func (q *Queries) ListMyStories(ctx context.Context, arg ListMyStoriesParams) ([]ListMyStoriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listMyStories,
+		arg.UserID,
+		arg.Now,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMyStoriesRow
+	for rows.Next() {
+		var i ListMyStoriesRow
+		if err := rows.Scan(
+			&i.StoryID,
+			&i.ArchiveID,
+			&i.MediaUrl,
+			&i.MediaType,
+			&i.ThumbnailUrl,
+			&i.Caption,
+			&i.IsAnonymous,
+			&i.ShowLocation,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.IsHighlight,
+			&i.ViewCount,
+			&i.ReactionCount,
+			&i.TotalCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserStoryGeohashes = `-- name: ListUserStoryGeohashes :many
+SELECT DISTINCT geohash FROM stories
+WHERE user_id = $1
+`
+
+// Where a user's stories were posted, to expire the feeds they appear in
+func (q *Queries) ListUserStoryGeohashes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listUserStoryGeohashes, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var geohash string
+		if err := rows.Scan(&geohash); err != nil {
+			return nil, err
+		}
+		items = append(items, geohash)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateStory = `-- name: UpdateStory :one
 UPDATE stories
 SET 
@@ -627,7 +983,8 @@ WHERE id = $1
   AND user_id = $2
   AND created_at > NOW() - INTERVAL '15 minutes'
   AND expires_at > NOW()
-RETURNING id, user_id, media_url, media_type, thumbnail_url, caption, geohash, geom, visibility, expires_at, created_at, is_anonymous, is_premium, show_location, ST_Y(geom::geometry) as lat, ST_X(geom::geometry) as lng
+RETURNING id, user_id, media_url, media_type, thumbnail_url, caption, geohash, geom, visibility, expires_at, created_at, is_anonymous, is_premium, show_location, ST_Y(geom::geometry) as lat, ST_X(geom::geometry) as lng,
+  (SELECT COUNT(*) FROM story_reactions sr WHERE sr.story_id = stories.id)::bigint AS reaction_count
 `
 
 type UpdateStoryParams struct {
@@ -639,22 +996,23 @@ type UpdateStoryParams struct {
 }
 
 type UpdateStoryRow struct {
-	ID           uuid.UUID         `json:"id"`
-	UserID       uuid.UUID         `json:"user_id"`
-	MediaUrl     string            `json:"media_url"`
-	MediaType    string            `json:"media_type"`
-	ThumbnailUrl sql.NullString    `json:"thumbnail_url"`
-	Caption      sql.NullString    `json:"caption"`
-	Geohash      string            `json:"geohash"`
-	Geom         interface{}       `json:"geom"`
-	Visibility   StoryAvailability `json:"visibility"`
-	ExpiresAt    time.Time         `json:"expires_at"`
-	CreatedAt    time.Time         `json:"created_at"`
-	IsAnonymous  bool              `json:"is_anonymous"`
-	IsPremium    sql.NullBool      `json:"is_premium"`
-	ShowLocation bool              `json:"show_location"`
-	Lat          interface{}       `json:"lat"`
-	Lng          interface{}       `json:"lng"`
+	ID            uuid.UUID         `json:"id"`
+	UserID        uuid.UUID         `json:"user_id"`
+	MediaUrl      string            `json:"media_url"`
+	MediaType     string            `json:"media_type"`
+	ThumbnailUrl  sql.NullString    `json:"thumbnail_url"`
+	Caption       sql.NullString    `json:"caption"`
+	Geohash       string            `json:"geohash"`
+	Geom          interface{}       `json:"geom"`
+	Visibility    StoryAvailability `json:"visibility"`
+	ExpiresAt     time.Time         `json:"expires_at"`
+	CreatedAt     time.Time         `json:"created_at"`
+	IsAnonymous   bool              `json:"is_anonymous"`
+	IsPremium     sql.NullBool      `json:"is_premium"`
+	ShowLocation  bool              `json:"show_location"`
+	Lat           interface{}       `json:"lat"`
+	Lng           interface{}       `json:"lng"`
+	ReactionCount int64             `json:"reaction_count"`
 }
 
 func (q *Queries) UpdateStory(ctx context.Context, arg UpdateStoryParams) (UpdateStoryRow, error) {
@@ -683,6 +1041,7 @@ func (q *Queries) UpdateStory(ctx context.Context, arg UpdateStoryParams) (Updat
 		&i.ShowLocation,
 		&i.Lat,
 		&i.Lng,
+		&i.ReactionCount,
 	)
 	return i, err
 }
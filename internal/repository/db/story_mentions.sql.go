@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createStoryMention = `-- name: CreateStoryMention :one
@@ -40,6 +41,66 @@ func (q *Queries) CreateStoryMention(ctx context.Context, arg CreateStoryMention
 	return i, err
 }
 
+const createStoryMentions = `-- name: CreateStoryMentions :many
+INSERT INTO story_mentions (
+  story_id,
+  mentioned_user_id
+)
+SELECT s.id, m.user_id
+FROM stories s, unnest($1::uuid[]) AS m(user_id)
+WHERE s.id = $2
+  AND m.user_id <> s.user_id
+  AND NOT EXISTS (
+    SELECT 1 FROM blocked_users b
+    WHERE (b.blocker_id = m.user_id AND b.blocked_id = s.user_id)
+       OR (b.blocker_id = s.user_id AND b.blocked_id = m.user_id)
+  )
+  AND (
+    s.visibility = 'public'
+    OR (s.visibility = 'connections' AND EXISTS (
+      SELECT 1 FROM connections c
+      WHERE (c.requester_id = m.user_id AND c.target_id = s.user_id OR c.requester_id = s.user_id AND c.target_id = m.user_id)
+      AND c.status = 'accepted'
+    ))
+    OR (s.visibility = 'close_friends' AND EXISTS (
+      SELECT 1 FROM close_friends cf WHERE cf.user_id = s.user_id AND cf.friend_id = m.user_id
+    ))
+  )
+ON CONFLICT (story_id, mentioned_user_id) DO NOTHING
+RETURNING mentioned_user_id
+`
+
+type CreateStoryMentionsParams struct {
+	MentionedUserIds []uuid.UUID `json:"mentioned_user_ids"`
+	StoryID          uuid.UUID   `json:"story_id"`
+}
+
+// Mentions the given users in a story and returns the ones newly mentioned.
+// The author, anyone on either side of a block with them and anyone outside
+// the story's audience are skipped.
+func (q *Queries) CreateStoryMentions(ctx context.Context, arg CreateStoryMentionsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, createStoryMentions, pq.Array(arg.MentionedUserIds), arg.StoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var mentioned_user_id uuid.UUID
+		if err := rows.Scan(&mentioned_user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, mentioned_user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const deleteStoryMentions = `-- name: DeleteStoryMentions :exec
 DELETE FROM story_mentions
 WHERE story_id = $1
@@ -12,23 +12,38 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/sqlc-dev/pqtype"
+	"privacy-social-backend/internal/util"
 )
 
 const banUser = `-- name: BanUser :one
 UPDATE users
-SET is_shadow_banned = $2
+SET is_shadow_banned = $2,
+  ban_type = $3,
+  ban_reason = $4,
+  ban_expires_at = $5
 WHERE id = $1
-RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at
+RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at
 `
 
 type BanUserParams struct {
-	ID             uuid.UUID `json:"id"`
-	IsShadowBanned bool      `json:"is_shadow_banned"`
+	ID             uuid.UUID      `json:"id"`
+	IsShadowBanned bool           `json:"is_shadow_banned"`
+	BanType        sql.NullString `json:"ban_type"`
+	BanReason      sql.NullString `json:"ban_reason"`
+	BanExpiresAt   util.NullTime  `json:"ban_expires_at"`
 }
 
+// BanUser sets or clears a user's ban; a NULL ban_type lifts it
 func (q *Queries) BanUser(ctx context.Context, arg BanUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, banUser, arg.ID, arg.IsShadowBanned)
+	row := q.db.QueryRowContext(ctx, banUser,
+		arg.ID,
+		arg.IsShadowBanned,
+		arg.BanType,
+		arg.BanReason,
+		arg.BanExpiresAt,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -60,6 +75,11 @@ func (q *Queries) BanUser(ctx context.Context, arg BanUserParams) (User, error)
 		&i.PasswordResetToken,
 		&i.PasswordResetExpiresAt,
 		&i.GhostModeExpiresAt,
+		&i.LastSeenAt,
+		&i.PhoneVerified,
+		&i.BanType,
+		&i.BanReason,
+		&i.BanExpiresAt,
 	)
 	return i, err
 }
@@ -68,12 +88,12 @@ const boostUser = `-- name: BoostUser :one
 UPDATE users
 SET boost_expires_at = $2
 WHERE id = $1
-RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at
+RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at
 `
 
 type BoostUserParams struct {
-	ID             uuid.UUID    `json:"id"`
-	BoostExpiresAt sql.NullTime `json:"boost_expires_at"`
+	ID             uuid.UUID     `json:"id"`
+	BoostExpiresAt util.NullTime `json:"boost_expires_at"`
 }
 
 func (q *Queries) BoostUser(ctx context.Context, arg BoostUserParams) (User, error) {
@@ -109,6 +129,11 @@ func (q *Queries) BoostUser(ctx context.Context, arg BoostUserParams) (User, err
 		&i.PasswordResetToken,
 		&i.PasswordResetExpiresAt,
 		&i.GhostModeExpiresAt,
+		&i.LastSeenAt,
+		&i.PhoneVerified,
+		&i.BanType,
+		&i.BanReason,
+		&i.BanExpiresAt,
 	)
 	return i, err
 }
@@ -126,12 +151,26 @@ func (q *Queries) ClearPasswordResetToken(ctx context.Context, id uuid.UUID) err
 	return err
 }
 
-const countUsers = `-- name: CountUsers :one
+const countAdminUsers = `-- name: CountAdminUsers :one
 SELECT COUNT(*) FROM users
+WHERE ($1::text IS NULL
+    OR username ILIKE '%' || $1::text || '%'
+    OR email ILIKE '%' || $1::text || '%'
+    OR phone ILIKE '%' || $1::text || '%')
+  AND ($2::text = 'all'
+    OR ($2::text = 'banned') = (
+      is_shadow_banned OR (ban_type IS NOT NULL AND (ban_type <> 'temp' OR ban_expires_at > now()))
+    ))
 `
 
-func (q *Queries) CountUsers(ctx context.Context) (int64, error) {
-	row := q.db.QueryRowContext(ctx, countUsers)
+type CountAdminUsersParams struct {
+	Query  sql.NullString `json:"query"`
+	Status string         `json:"status"`
+}
+
+// How many users SearchAdminUsers matches across all pages
+func (q *Queries) CountAdminUsers(ctx context.Context, arg CountAdminUsersParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAdminUsers, arg.Query, arg.Status)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
@@ -145,7 +184,7 @@ INSERT INTO users (
   full_name
 ) VALUES (
   $1, $2, $3, $4
-) RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at
+) RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at
 `
 
 type CreateUserParams struct {
@@ -193,6 +232,11 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.PasswordResetToken,
 		&i.PasswordResetExpiresAt,
 		&i.GhostModeExpiresAt,
+		&i.LastSeenAt,
+		&i.PhoneVerified,
+		&i.BanType,
+		&i.BanReason,
+		&i.BanExpiresAt,
 	)
 	return i, err
 }
@@ -261,12 +305,12 @@ WHERE id = $1
 `
 
 type GetUserActivityStatusRow struct {
-	ID               uuid.UUID    `json:"id"`
-	Username         string       `json:"username"`
-	LastActiveAt     sql.NullTime `json:"last_active_at"`
-	ActivityStreak   interface{}  `json:"activity_streak"`
-	VisibilityStatus string       `json:"visibility_status"`
-	IsVisible        bool         `json:"is_visible"`
+	ID               uuid.UUID     `json:"id"`
+	Username         string        `json:"username"`
+	LastActiveAt     util.NullTime `json:"last_active_at"`
+	ActivityStreak   interface{}   `json:"activity_streak"`
+	VisibilityStatus string        `json:"visibility_status"`
+	IsVisible        bool          `json:"is_visible"`
 }
 
 // Get user's activity status and visibility
@@ -285,7 +329,7 @@ func (q *Queries) GetUserActivityStatus(ctx context.Context, id uuid.UUID) (GetU
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at FROM users
+SELECT id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at FROM users
 WHERE email = $1 LIMIT 1
 `
 
@@ -322,12 +366,17 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email sql.NullString) (Use
 		&i.PasswordResetToken,
 		&i.PasswordResetExpiresAt,
 		&i.GhostModeExpiresAt,
+		&i.LastSeenAt,
+		&i.PhoneVerified,
+		&i.BanType,
+		&i.BanReason,
+		&i.BanExpiresAt,
 	)
 	return i, err
 }
 
 const getUserByGoogleID = `-- name: GetUserByGoogleID :one
-SELECT id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at FROM users
+SELECT id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at FROM users
 WHERE google_id = $1 LIMIT 1
 `
 
@@ -364,12 +413,17 @@ func (q *Queries) GetUserByGoogleID(ctx context.Context, googleID sql.NullString
 		&i.PasswordResetToken,
 		&i.PasswordResetExpiresAt,
 		&i.GhostModeExpiresAt,
+		&i.LastSeenAt,
+		&i.PhoneVerified,
+		&i.BanType,
+		&i.BanReason,
+		&i.BanExpiresAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at FROM users
+SELECT id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at FROM users
 WHERE id = $1 LIMIT 1
 `
 
@@ -406,12 +460,17 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.PasswordResetToken,
 		&i.PasswordResetExpiresAt,
 		&i.GhostModeExpiresAt,
+		&i.LastSeenAt,
+		&i.PhoneVerified,
+		&i.BanType,
+		&i.BanReason,
+		&i.BanExpiresAt,
 	)
 	return i, err
 }
 
 const getUserByPhone = `-- name: GetUserByPhone :one
-SELECT id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at FROM users
+SELECT id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at FROM users
 WHERE phone = $1 LIMIT 1
 `
 
@@ -448,12 +507,17 @@ func (q *Queries) GetUserByPhone(ctx context.Context, phone string) (User, error
 		&i.PasswordResetToken,
 		&i.PasswordResetExpiresAt,
 		&i.GhostModeExpiresAt,
+		&i.LastSeenAt,
+		&i.PhoneVerified,
+		&i.BanType,
+		&i.BanReason,
+		&i.BanExpiresAt,
 	)
 	return i, err
 }
 
 const getUserByResetToken = `-- name: GetUserByResetToken :one
-SELECT id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at FROM users
+SELECT id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at FROM users
 WHERE password_reset_token = $1 
 AND password_reset_expires_at > now()
 LIMIT 1
@@ -492,12 +556,17 @@ func (q *Queries) GetUserByResetToken(ctx context.Context, passwordResetToken sq
 		&i.PasswordResetToken,
 		&i.PasswordResetExpiresAt,
 		&i.GhostModeExpiresAt,
+		&i.LastSeenAt,
+		&i.PhoneVerified,
+		&i.BanType,
+		&i.BanReason,
+		&i.BanExpiresAt,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at FROM users
+SELECT id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at FROM users
 WHERE username = $1 LIMIT 1
 `
 
@@ -534,6 +603,11 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.PasswordResetToken,
 		&i.PasswordResetExpiresAt,
 		&i.GhostModeExpiresAt,
+		&i.LastSeenAt,
+		&i.PhoneVerified,
+		&i.BanType,
+		&i.BanReason,
+		&i.BanExpiresAt,
 	)
 	return i, err
 }
@@ -565,6 +639,24 @@ func (q *Queries) GetUserEngagementStats(ctx context.Context, userID uuid.UUID)
 	return i, err
 }
 
+const getUserPresence = `-- name: GetUserPresence :one
+SELECT is_ghost_mode, ghost_mode_expires_at, last_seen_at FROM users
+WHERE id = $1
+`
+
+type GetUserPresenceRow struct {
+	IsGhostMode        bool          `json:"is_ghost_mode"`
+	GhostModeExpiresAt util.NullTime `json:"ghost_mode_expires_at"`
+	LastSeenAt         util.NullTime `json:"last_seen_at"`
+}
+
+func (q *Queries) GetUserPresence(ctx context.Context, id uuid.UUID) (GetUserPresenceRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserPresence, id)
+	var i GetUserPresenceRow
+	err := row.Scan(&i.IsGhostMode, &i.GhostModeExpiresAt, &i.LastSeenAt)
+	return i, err
+}
+
 const getUserProfile = `-- name: GetUserProfile :one
 SELECT 
   u.id, u.username, u.full_name, u.avatar_url, u.bio, u.banner_url, u.theme, u.profile_visibility, u.email, u.is_ghost_mode, u.website_url, u.links, u.created_at, u.is_premium, u.last_active_at,
@@ -597,7 +689,7 @@ type GetUserProfileRow struct {
 	Links             json.RawMessage `json:"links"`
 	CreatedAt         time.Time       `json:"created_at"`
 	IsPremium         sql.NullBool    `json:"is_premium"`
-	LastActiveAt      sql.NullTime    `json:"last_active_at"`
+	LastActiveAt      util.NullTime   `json:"last_active_at"`
 	StoryCount        int64           `json:"story_count"`
 	ConnectionCount   int64           `json:"connection_count"`
 	ActivityStreak    interface{}     `json:"activity_streak"`
@@ -631,21 +723,219 @@ func (q *Queries) GetUserProfile(ctx context.Context, id uuid.UUID) (GetUserProf
 	return i, err
 }
 
-const listUsers = `-- name: ListUsers :many
+const getUsersByUsernames = `-- name: GetUsersByUsernames :many
+SELECT id, username FROM users
+WHERE lower(username) = ANY($1::text[])
+`
+
+type GetUsersByUsernamesRow struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+}
 
-SELECT id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at FROM users
+// Matches case-insensitively, as mentions are parsed lowercased
+func (q *Queries) GetUsersByUsernames(ctx context.Context, usernames []string) ([]GetUsersByUsernamesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByUsernames, pq.Array(usernames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsersByUsernamesRow
+	for rows.Next() {
+		var i GetUsersByUsernamesRow
+		if err := rows.Scan(&i.ID, &i.Username); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const liftExpiredBans = `-- name: LiftExpiredBans :execrows
+UPDATE users
+SET ban_type = NULL,
+  ban_reason = NULL,
+  ban_expires_at = NULL
+WHERE ban_type = 'temp' AND ban_expires_at <= now()
+`
+
+// LiftExpiredBans clears suspensions whose end has passed
+func (q *Queries) LiftExpiredBans(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, liftExpiredBans)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listActiveLockouts = `-- name: ListActiveLockouts :many
+SELECT id, ban_type, ban_expires_at FROM users
+WHERE ban_type = 'hard'
+  OR (ban_type = 'temp' AND ban_expires_at > now())
+`
+
+type ListActiveLockoutsRow struct {
+	ID           uuid.UUID      `json:"id"`
+	BanType      sql.NullString `json:"ban_type"`
+	BanExpiresAt util.NullTime  `json:"ban_expires_at"`
+}
+
+// Users locked out by a hard ban or a suspension that hasn't ended
+func (q *Queries) ListActiveLockouts(ctx context.Context) ([]ListActiveLockoutsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveLockouts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListActiveLockoutsRow
+	for rows.Next() {
+		var i ListActiveLockoutsRow
+		if err := rows.Scan(&i.ID, &i.BanType, &i.BanExpiresAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listActiveUserIDs = `-- name: ListActiveUserIDs :many
+SELECT id FROM users
+WHERE last_active_at >= $1::timestamptz
+  AND is_shadow_banned = false
+ORDER BY id
+`
+
+func (q *Queries) ListActiveUserIDs(ctx context.Context, activeSince time.Time) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveUserIDs, activeSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserContacts = `-- name: ListUserContacts :many
+SELECT requester_id AS contact_id FROM connections WHERE target_id = $1
+UNION
+SELECT target_id FROM connections WHERE requester_id = $1
+UNION
+SELECT receiver_id FROM messages WHERE sender_id = $1 AND receiver_id IS NOT NULL
+UNION
+SELECT sender_id FROM messages WHERE receiver_id = $1
+`
+
+// Users whose cached data may include this user: connections in any state
+// and direct message partners
+func (q *Queries) ListUserContacts(ctx context.Context, targetID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listUserContacts, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var contact_id uuid.UUID
+		if err := rows.Scan(&contact_id); err != nil {
+			return nil, err
+		}
+		items = append(items, contact_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markPhoneVerified = `-- name: MarkPhoneVerified :one
+UPDATE users
+SET phone_verified = true
+WHERE phone = $1
+RETURNING id
+`
+
+// MarkPhoneVerified records that the user with this phone confirmed it with a one-time code
+func (q *Queries) MarkPhoneVerified(ctx context.Context, phone string) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, markPhoneVerified, phone)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const promoteUserToAdmin = `-- name: PromoteUserToAdmin :execrows
+UPDATE users
+SET role = 'admin'
+WHERE id = $1
+`
+
+// PromoteUserToAdmin grants the admin role; it affects no rows when the user doesn't exist
+func (q *Queries) PromoteUserToAdmin(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, promoteUserToAdmin, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const searchAdminUsers = `-- name: SearchAdminUsers :many
+SELECT id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at FROM users
+WHERE ($1::text IS NULL
+    OR username ILIKE '%' || $1::text || '%'
+    OR email ILIKE '%' || $1::text || '%'
+    OR phone ILIKE '%' || $1::text || '%')
+  AND ($2::text = 'all'
+    OR ($2::text = 'banned') = (
+      is_shadow_banned OR (ban_type IS NOT NULL AND (ban_type <> 'temp' OR ban_expires_at > now()))
+    ))
 ORDER BY created_at DESC
-LIMIT $1 OFFSET $2
+LIMIT $3 OFFSET $4
 `
 
-type ListUsersParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+type SearchAdminUsersParams struct {
+	Query  sql.NullString `json:"query"`
+	Status string         `json:"status"`
+	Limit  int32          `json:"limit"`
+	Offset int32          `json:"offset"`
 }
 
-// Admin Queries
-func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
-	rows, err := q.db.QueryContext(ctx, listUsers, arg.Limit, arg.Offset)
+// Users whose username, email or phone contains query (any user when it's
+// NULL), newest first. status is all, banned or active; a temporary ban that
+// has run out counts as active.
+func (q *Queries) SearchAdminUsers(ctx context.Context, arg SearchAdminUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, searchAdminUsers,
+		arg.Query,
+		arg.Status,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -683,6 +973,11 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 			&i.PasswordResetToken,
 			&i.PasswordResetExpiresAt,
 			&i.GhostModeExpiresAt,
+			&i.LastSeenAt,
+			&i.PhoneVerified,
+			&i.BanType,
+			&i.BanReason,
+			&i.BanExpiresAt,
 		); err != nil {
 			return nil, err
 		}
@@ -710,9 +1005,19 @@ FROM users
 WHERE 
   (username ILIKE '%' || $1::text || '%' OR full_name ILIKE '%' || $1::text || '%')
   AND is_shadow_banned = false
+  AND NOT EXISTS (
+    SELECT 1 FROM blocked_users bu
+    WHERE (bu.blocker_id = $2 AND bu.blocked_id = users.id)
+       OR (bu.blocker_id = users.id AND bu.blocked_id = $2)
+  )
 LIMIT 20
 `
 
+type SearchUsersParams struct {
+	Query    string    `json:"query"`
+	ViewerID uuid.UUID `json:"viewer_id"`
+}
+
 type SearchUsersRow struct {
 	ID         uuid.UUID      `json:"id"`
 	Username   string         `json:"username"`
@@ -723,8 +1028,10 @@ type SearchUsersRow struct {
 	CreatedAt  time.Time      `json:"created_at"`
 }
 
-func (q *Queries) SearchUsers(ctx context.Context, query string) ([]SearchUsersRow, error) {
-	rows, err := q.db.QueryContext(ctx, searchUsers, query)
+// Users matching the query, leaving out anyone the viewer blocked or was
+// blocked by
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchUsers, arg.Query, arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -760,13 +1067,13 @@ SET
     password_reset_token = $2,
     password_reset_expires_at = $3
 WHERE email = $1
-RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at
+RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at
 `
 
 type SetPasswordResetTokenParams struct {
 	Email                  sql.NullString `json:"email"`
 	PasswordResetToken     sql.NullString `json:"password_reset_token"`
-	PasswordResetExpiresAt sql.NullTime   `json:"password_reset_expires_at"`
+	PasswordResetExpiresAt util.NullTime  `json:"password_reset_expires_at"`
 }
 
 func (q *Queries) SetPasswordResetToken(ctx context.Context, arg SetPasswordResetTokenParams) (User, error) {
@@ -802,6 +1109,11 @@ func (q *Queries) SetPasswordResetToken(ctx context.Context, arg SetPasswordRese
 		&i.PasswordResetToken,
 		&i.PasswordResetExpiresAt,
 		&i.GhostModeExpiresAt,
+		&i.LastSeenAt,
+		&i.PhoneVerified,
+		&i.BanType,
+		&i.BanReason,
+		&i.BanExpiresAt,
 	)
 	return i, err
 }
@@ -812,13 +1124,13 @@ UPDATE users
 SET is_ghost_mode = $2,
     ghost_mode_expires_at = $3
 WHERE id = $1
-RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at
+RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at
 `
 
 type ToggleGhostModeParams struct {
-	ID                 uuid.UUID    `json:"id"`
-	IsGhostMode        bool         `json:"is_ghost_mode"`
-	GhostModeExpiresAt sql.NullTime `json:"ghost_mode_expires_at"`
+	ID                 uuid.UUID     `json:"id"`
+	IsGhostMode        bool          `json:"is_ghost_mode"`
+	GhostModeExpiresAt util.NullTime `json:"ghost_mode_expires_at"`
 }
 
 // Privacy Features
@@ -855,11 +1167,21 @@ func (q *Queries) ToggleGhostMode(ctx context.Context, arg ToggleGhostModeParams
 		&i.PasswordResetToken,
 		&i.PasswordResetExpiresAt,
 		&i.GhostModeExpiresAt,
+		&i.LastSeenAt,
+		&i.PhoneVerified,
+		&i.BanType,
+		&i.BanReason,
+		&i.BanExpiresAt,
 	)
 	return i, err
 }
 
 const updateUserActivity = `-- name: UpdateUserActivity :one
+WITH active_day AS (
+  INSERT INTO user_activity_days (user_id, day)
+  VALUES ($1, CURRENT_DATE)
+  ON CONFLICT DO NOTHING
+)
 UPDATE users
 SET 
   last_active_at = now(),
@@ -880,10 +1202,10 @@ SET
   END,
   streak_updated_at = now()
 WHERE id = $1
-RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at
+RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at
 `
 
-// Updates last_active_at and calculates activity streak
+// Updates last_active_at and calculates activity streak, recording today as an active day
 func (q *Queries) UpdateUserActivity(ctx context.Context, id uuid.UUID) (User, error) {
 	row := q.db.QueryRowContext(ctx, updateUserActivity, id)
 	var i User
@@ -917,6 +1239,11 @@ func (q *Queries) UpdateUserActivity(ctx context.Context, id uuid.UUID) (User, e
 		&i.PasswordResetToken,
 		&i.PasswordResetExpiresAt,
 		&i.GhostModeExpiresAt,
+		&i.LastSeenAt,
+		&i.PhoneVerified,
+		&i.BanType,
+		&i.BanReason,
+		&i.BanExpiresAt,
 	)
 	return i, err
 }
@@ -956,7 +1283,7 @@ const updateUserGoogleID = `-- name: UpdateUserGoogleID :one
 UPDATE users
 SET google_id = $2
 WHERE id = $1
-RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at
+RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at
 `
 
 type UpdateUserGoogleIDParams struct {
@@ -997,10 +1324,26 @@ func (q *Queries) UpdateUserGoogleID(ctx context.Context, arg UpdateUserGoogleID
 		&i.PasswordResetToken,
 		&i.PasswordResetExpiresAt,
 		&i.GhostModeExpiresAt,
+		&i.LastSeenAt,
+		&i.PhoneVerified,
+		&i.BanType,
+		&i.BanReason,
+		&i.BanExpiresAt,
 	)
 	return i, err
 }
 
+const updateUserLastSeen = `-- name: UpdateUserLastSeen :exec
+UPDATE users
+SET last_seen_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) UpdateUserLastSeen(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, updateUserLastSeen, id)
+	return err
+}
+
 const updateUserPassword = `-- name: UpdateUserPassword :exec
 UPDATE users
 SET password_hash = $2
@@ -1094,7 +1437,7 @@ const updateUserTrust = `-- name: UpdateUserTrust :one
 UPDATE users
 SET trust_level = $2
 WHERE id = $1
-RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at
+RETURNING id, phone, password_hash, username, full_name, avatar_url, bio, role, trust_level, is_verified, is_shadow_banned, last_active_at, created_at, is_ghost_mode, activity_streak, streak_updated_at, is_premium, streak_freezes_remaining, boost_expires_at, banner_url, theme, profile_visibility, email, website_url, links, google_id, password_reset_token, password_reset_expires_at, ghost_mode_expires_at, last_seen_at, phone_verified, ban_type, ban_reason, ban_expires_at
 `
 
 type UpdateUserTrustParams struct {
@@ -1135,6 +1478,11 @@ func (q *Queries) UpdateUserTrust(ctx context.Context, arg UpdateUserTrustParams
 		&i.PasswordResetToken,
 		&i.PasswordResetExpiresAt,
 		&i.GhostModeExpiresAt,
+		&i.LastSeenAt,
+		&i.PhoneVerified,
+		&i.BanType,
+		&i.BanReason,
+		&i.BanExpiresAt,
 	)
 	return i, err
 }
@@ -14,6 +14,7 @@ import (
 	sql "database/sql"
 	db "privacy-social-backend/internal/repository/db"
 	reflect "reflect"
+	time "time"
 
 	uuid "github.com/google/uuid"
 	gomock "go.uber.org/mock/gomock"
@@ -43,6 +44,20 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// AddCloseFriend mocks base method.
+func (m *MockStore) AddCloseFriend(ctx context.Context, arg db.AddCloseFriendParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddCloseFriend", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddCloseFriend indicates an expected call of AddCloseFriend.
+func (mr *MockStoreMockRecorder) AddCloseFriend(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddCloseFriend", reflect.TypeOf((*MockStore)(nil).AddCloseFriend), ctx, arg)
+}
+
 // AddGroupMember mocks base method.
 func (m *MockStore) AddGroupMember(ctx context.Context, arg db.AddGroupMemberParams) (db.GroupMember, error) {
 	m.ctrl.T.Helper()
@@ -88,6 +103,21 @@ func (mr *MockStoreMockRecorder) BanUser(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BanUser", reflect.TypeOf((*MockStore)(nil).BanUser), ctx, arg)
 }
 
+// BlockSession mocks base method.
+func (m *MockStore) BlockSession(ctx context.Context, arg db.BlockSessionParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockSession", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BlockSession indicates an expected call of BlockSession.
+func (mr *MockStoreMockRecorder) BlockSession(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockSession", reflect.TypeOf((*MockStore)(nil).BlockSession), ctx, arg)
+}
+
 // BlockUser mocks base method.
 func (m *MockStore) BlockUser(ctx context.Context, arg db.BlockUserParams) (db.BlockedUser, error) {
 	m.ctrl.T.Helper()
@@ -103,6 +133,21 @@ func (mr *MockStoreMockRecorder) BlockUser(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockUser", reflect.TypeOf((*MockStore)(nil).BlockUser), ctx, arg)
 }
 
+// BlockUserSessions mocks base method.
+func (m *MockStore) BlockUserSessions(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockUserSessions", ctx, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BlockUserSessions indicates an expected call of BlockUserSessions.
+func (mr *MockStoreMockRecorder) BlockUserSessions(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockUserSessions", reflect.TypeOf((*MockStore)(nil).BlockUserSessions), ctx, userID)
+}
+
 // BoostUser mocks base method.
 func (m *MockStore) BoostUser(ctx context.Context, arg db.BoostUserParams) (db.User, error) {
 	m.ctrl.T.Helper()
@@ -133,6 +178,36 @@ func (mr *MockStoreMockRecorder) CheckGroupMembership(ctx, arg any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckGroupMembership", reflect.TypeOf((*MockStore)(nil).CheckGroupMembership), ctx, arg)
 }
 
+// ClaimScheduledMessage mocks base method.
+func (m *MockStore) ClaimScheduledMessage(ctx context.Context, arg db.ClaimScheduledMessageParams) (db.ScheduledMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimScheduledMessage", ctx, arg)
+	ret0, _ := ret[0].(db.ScheduledMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimScheduledMessage indicates an expected call of ClaimScheduledMessage.
+func (mr *MockStoreMockRecorder) ClaimScheduledMessage(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimScheduledMessage", reflect.TypeOf((*MockStore)(nil).ClaimScheduledMessage), ctx, arg)
+}
+
+// ClearExpiredMessageMedia mocks base method.
+func (m *MockStore) ClearExpiredMessageMedia(ctx context.Context, createdBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearExpiredMessageMedia", ctx, createdBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClearExpiredMessageMedia indicates an expected call of ClearExpiredMessageMedia.
+func (mr *MockStoreMockRecorder) ClearExpiredMessageMedia(ctx, createdBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearExpiredMessageMedia", reflect.TypeOf((*MockStore)(nil).ClearExpiredMessageMedia), ctx, createdBefore)
+}
+
 // ClearPasswordResetToken mocks base method.
 func (m *MockStore) ClearPasswordResetToken(ctx context.Context, id uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -147,6 +222,51 @@ func (mr *MockStoreMockRecorder) ClearPasswordResetToken(ctx, id any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearPasswordResetToken", reflect.TypeOf((*MockStore)(nil).ClearPasswordResetToken), ctx, id)
 }
 
+// CountAdminAuditLog mocks base method.
+func (m *MockStore) CountAdminAuditLog(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountAdminAuditLog", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountAdminAuditLog indicates an expected call of CountAdminAuditLog.
+func (mr *MockStoreMockRecorder) CountAdminAuditLog(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountAdminAuditLog", reflect.TypeOf((*MockStore)(nil).CountAdminAuditLog), ctx)
+}
+
+// CountAdminUsers mocks base method.
+func (m *MockStore) CountAdminUsers(ctx context.Context, arg db.CountAdminUsersParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountAdminUsers", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountAdminUsers indicates an expected call of CountAdminUsers.
+func (mr *MockStoreMockRecorder) CountAdminUsers(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountAdminUsers", reflect.TypeOf((*MockStore)(nil).CountAdminUsers), ctx, arg)
+}
+
+// CountAnonymousStoriesSince mocks base method.
+func (m *MockStore) CountAnonymousStoriesSince(ctx context.Context, arg db.CountAnonymousStoriesSinceParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountAnonymousStoriesSince", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountAnonymousStoriesSince indicates an expected call of CountAnonymousStoriesSince.
+func (mr *MockStoreMockRecorder) CountAnonymousStoriesSince(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountAnonymousStoriesSince", reflect.TypeOf((*MockStore)(nil).CountAnonymousStoriesSince), ctx, arg)
+}
+
 // CountArchivedStories mocks base method.
 func (m *MockStore) CountArchivedStories(ctx context.Context, userID uuid.UUID) (int64, error) {
 	m.ctrl.T.Helper()
@@ -162,6 +282,21 @@ func (mr *MockStoreMockRecorder) CountArchivedStories(ctx, userID any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountArchivedStories", reflect.TypeOf((*MockStore)(nil).CountArchivedStories), ctx, userID)
 }
 
+// CountConnectionRecommendations mocks base method.
+func (m *MockStore) CountConnectionRecommendations(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountConnectionRecommendations", ctx, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountConnectionRecommendations indicates an expected call of CountConnectionRecommendations.
+func (mr *MockStoreMockRecorder) CountConnectionRecommendations(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountConnectionRecommendations", reflect.TypeOf((*MockStore)(nil).CountConnectionRecommendations), ctx, userID)
+}
+
 // CountConnectionRequestsToday mocks base method.
 func (m *MockStore) CountConnectionRequestsToday(ctx context.Context, requesterID uuid.UUID) (int64, error) {
 	m.ctrl.T.Helper()
@@ -192,6 +327,96 @@ func (mr *MockStoreMockRecorder) CountCrossingsToday(ctx, userID1 any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountCrossingsToday", reflect.TypeOf((*MockStore)(nil).CountCrossingsToday), ctx, userID1)
 }
 
+// CountDirectMessageMatches mocks base method.
+func (m *MockStore) CountDirectMessageMatches(ctx context.Context, arg db.CountDirectMessageMatchesParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountDirectMessageMatches", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountDirectMessageMatches indicates an expected call of CountDirectMessageMatches.
+func (mr *MockStoreMockRecorder) CountDirectMessageMatches(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountDirectMessageMatches", reflect.TypeOf((*MockStore)(nil).CountDirectMessageMatches), ctx, arg)
+}
+
+// CountGroupMessageMatches mocks base method.
+func (m *MockStore) CountGroupMessageMatches(ctx context.Context, arg db.CountGroupMessageMatchesParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountGroupMessageMatches", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountGroupMessageMatches indicates an expected call of CountGroupMessageMatches.
+func (mr *MockStoreMockRecorder) CountGroupMessageMatches(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountGroupMessageMatches", reflect.TypeOf((*MockStore)(nil).CountGroupMessageMatches), ctx, arg)
+}
+
+// CountMessageReplies mocks base method.
+func (m *MockStore) CountMessageReplies(ctx context.Context, messageID uuid.UUID) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountMessageReplies", ctx, messageID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountMessageReplies indicates an expected call of CountMessageReplies.
+func (mr *MockStoreMockRecorder) CountMessageReplies(ctx, messageID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountMessageReplies", reflect.TypeOf((*MockStore)(nil).CountMessageReplies), ctx, messageID)
+}
+
+// CountOpenStoryReports mocks base method.
+func (m *MockStore) CountOpenStoryReports(ctx context.Context, targetStoryID uuid.NullUUID) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountOpenStoryReports", ctx, targetStoryID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountOpenStoryReports indicates an expected call of CountOpenStoryReports.
+func (mr *MockStoreMockRecorder) CountOpenStoryReports(ctx, targetStoryID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountOpenStoryReports", reflect.TypeOf((*MockStore)(nil).CountOpenStoryReports), ctx, targetStoryID)
+}
+
+// CountReports mocks base method.
+func (m *MockStore) CountReports(ctx context.Context, isResolved bool) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountReports", ctx, isResolved)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountReports indicates an expected call of CountReports.
+func (mr *MockStoreMockRecorder) CountReports(ctx, isResolved any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountReports", reflect.TypeOf((*MockStore)(nil).CountReports), ctx, isResolved)
+}
+
+// CountStories mocks base method.
+func (m *MockStore) CountStories(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountStories", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountStories indicates an expected call of CountStories.
+func (mr *MockStoreMockRecorder) CountStories(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountStories", reflect.TypeOf((*MockStore)(nil).CountStories), ctx)
+}
+
 // CountStoryReactions mocks base method.
 func (m *MockStore) CountStoryReactions(ctx context.Context, storyID uuid.UUID) (int64, error) {
 	m.ctrl.T.Helper()
@@ -237,19 +462,33 @@ func (mr *MockStoreMockRecorder) CountUnreadNotifications(ctx, userID any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUnreadNotifications", reflect.TypeOf((*MockStore)(nil).CountUnreadNotifications), ctx, userID)
 }
 
-// CountUsers mocks base method.
-func (m *MockStore) CountUsers(ctx context.Context) (int64, error) {
+// CreateAdminAuditLog mocks base method.
+func (m *MockStore) CreateAdminAuditLog(ctx context.Context, arg db.CreateAdminAuditLogParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAdminAuditLog", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAdminAuditLog indicates an expected call of CreateAdminAuditLog.
+func (mr *MockStoreMockRecorder) CreateAdminAuditLog(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAdminAuditLog", reflect.TypeOf((*MockStore)(nil).CreateAdminAuditLog), ctx, arg)
+}
+
+// CreateAnalyticsEvents mocks base method.
+func (m *MockStore) CreateAnalyticsEvents(ctx context.Context, arg db.CreateAnalyticsEventsParams) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CountUsers", ctx)
+	ret := m.ctrl.Call(m, "CreateAnalyticsEvents", ctx, arg)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CountUsers indicates an expected call of CountUsers.
-func (mr *MockStoreMockRecorder) CountUsers(ctx any) *gomock.Call {
+// CreateAnalyticsEvents indicates an expected call of CreateAnalyticsEvents.
+func (mr *MockStoreMockRecorder) CreateAnalyticsEvents(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsers", reflect.TypeOf((*MockStore)(nil).CountUsers), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAnalyticsEvents", reflect.TypeOf((*MockStore)(nil).CreateAnalyticsEvents), ctx, arg)
 }
 
 // CreateConnectionRequest mocks base method.
@@ -327,11 +566,25 @@ func (mr *MockStoreMockRecorder) CreateMessage(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMessage", reflect.TypeOf((*MockStore)(nil).CreateMessage), ctx, arg)
 }
 
+// CreateMessageAttachments mocks base method.
+func (m *MockStore) CreateMessageAttachments(ctx context.Context, arg db.CreateMessageAttachmentsParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateMessageAttachments", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateMessageAttachments indicates an expected call of CreateMessageAttachments.
+func (mr *MockStoreMockRecorder) CreateMessageAttachments(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMessageAttachments", reflect.TypeOf((*MockStore)(nil).CreateMessageAttachments), ctx, arg)
+}
+
 // CreateMessageReaction mocks base method.
-func (m *MockStore) CreateMessageReaction(ctx context.Context, arg db.CreateMessageReactionParams) (db.MessageReaction, error) {
+func (m *MockStore) CreateMessageReaction(ctx context.Context, arg db.CreateMessageReactionParams) (db.CreateMessageReactionRow, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateMessageReaction", ctx, arg)
-	ret0, _ := ret[0].(db.MessageReaction)
+	ret0, _ := ret[0].(db.CreateMessageReactionRow)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -372,6 +625,21 @@ func (mr *MockStoreMockRecorder) CreateReport(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReport", reflect.TypeOf((*MockStore)(nil).CreateReport), ctx, arg)
 }
 
+// CreateScheduledMessage mocks base method.
+func (m *MockStore) CreateScheduledMessage(ctx context.Context, arg db.CreateScheduledMessageParams) (db.ScheduledMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateScheduledMessage", ctx, arg)
+	ret0, _ := ret[0].(db.ScheduledMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateScheduledMessage indicates an expected call of CreateScheduledMessage.
+func (mr *MockStoreMockRecorder) CreateScheduledMessage(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateScheduledMessage", reflect.TypeOf((*MockStore)(nil).CreateScheduledMessage), ctx, arg)
+}
+
 // CreateSession mocks base method.
 func (m *MockStore) CreateSession(ctx context.Context, arg db.CreateSessionParams) (db.Session, error) {
 	m.ctrl.T.Helper()
@@ -402,6 +670,20 @@ func (mr *MockStoreMockRecorder) CreateStory(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStory", reflect.TypeOf((*MockStore)(nil).CreateStory), ctx, arg)
 }
 
+// CreateStoryHashtag mocks base method.
+func (m *MockStore) CreateStoryHashtag(ctx context.Context, arg db.CreateStoryHashtagParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateStoryHashtag", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateStoryHashtag indicates an expected call of CreateStoryHashtag.
+func (mr *MockStoreMockRecorder) CreateStoryHashtag(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStoryHashtag", reflect.TypeOf((*MockStore)(nil).CreateStoryHashtag), ctx, arg)
+}
+
 // CreateStoryMention mocks base method.
 func (m *MockStore) CreateStoryMention(ctx context.Context, arg db.CreateStoryMentionParams) (db.StoryMention, error) {
 	m.ctrl.T.Helper()
@@ -417,6 +699,35 @@ func (mr *MockStoreMockRecorder) CreateStoryMention(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStoryMention", reflect.TypeOf((*MockStore)(nil).CreateStoryMention), ctx, arg)
 }
 
+// CreateStoryMentions mocks base method.
+func (m *MockStore) CreateStoryMentions(ctx context.Context, arg db.CreateStoryMentionsParams) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateStoryMentions", ctx, arg)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateStoryMentions indicates an expected call of CreateStoryMentions.
+func (mr *MockStoreMockRecorder) CreateStoryMentions(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStoryMentions", reflect.TypeOf((*MockStore)(nil).CreateStoryMentions), ctx, arg)
+}
+
+// CreateStoryModerationHold mocks base method.
+func (m *MockStore) CreateStoryModerationHold(ctx context.Context, arg db.CreateStoryModerationHoldParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateStoryModerationHold", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateStoryModerationHold indicates an expected call of CreateStoryModerationHold.
+func (mr *MockStoreMockRecorder) CreateStoryModerationHold(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStoryModerationHold", reflect.TypeOf((*MockStore)(nil).CreateStoryModerationHold), ctx, arg)
+}
+
 // CreateStoryReaction mocks base method.
 func (m *MockStore) CreateStoryReaction(ctx context.Context, arg db.CreateStoryReactionParams) (db.StoryReaction, error) {
 	m.ctrl.T.Helper()
@@ -476,6 +787,21 @@ func (mr *MockStoreMockRecorder) DeleteAllUserData(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAllUserData", reflect.TypeOf((*MockStore)(nil).DeleteAllUserData), ctx, id)
 }
 
+// DeleteAnalyticsEventsBefore mocks base method.
+func (m *MockStore) DeleteAnalyticsEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAnalyticsEventsBefore", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteAnalyticsEventsBefore indicates an expected call of DeleteAnalyticsEventsBefore.
+func (mr *MockStoreMockRecorder) DeleteAnalyticsEventsBefore(ctx, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAnalyticsEventsBefore", reflect.TypeOf((*MockStore)(nil).DeleteAnalyticsEventsBefore), ctx, before)
+}
+
 // DeleteArchivedStory mocks base method.
 func (m *MockStore) DeleteArchivedStory(ctx context.Context, arg db.DeleteArchivedStoryParams) error {
 	m.ctrl.T.Helper()
@@ -504,6 +830,20 @@ func (mr *MockStoreMockRecorder) DeleteConnection(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteConnection", reflect.TypeOf((*MockStore)(nil).DeleteConnection), ctx, arg)
 }
 
+// DeleteConnectionRecommendations mocks base method.
+func (m *MockStore) DeleteConnectionRecommendations(ctx context.Context, userID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteConnectionRecommendations", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteConnectionRecommendations indicates an expected call of DeleteConnectionRecommendations.
+func (mr *MockStoreMockRecorder) DeleteConnectionRecommendations(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteConnectionRecommendations", reflect.TypeOf((*MockStore)(nil).DeleteConnectionRecommendations), ctx, userID)
+}
+
 // DeleteConversation mocks base method.
 func (m *MockStore) DeleteConversation(ctx context.Context, arg db.DeleteConversationParams) error {
 	m.ctrl.T.Helper()
@@ -616,6 +956,35 @@ func (mr *MockStoreMockRecorder) DeleteOldNotifications(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOldNotifications", reflect.TypeOf((*MockStore)(nil).DeleteOldNotifications), ctx)
 }
 
+// DeleteOldUserActivityDays mocks base method.
+func (m *MockStore) DeleteOldUserActivityDays(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOldUserActivityDays", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOldUserActivityDays indicates an expected call of DeleteOldUserActivityDays.
+func (mr *MockStoreMockRecorder) DeleteOldUserActivityDays(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOldUserActivityDays", reflect.TypeOf((*MockStore)(nil).DeleteOldUserActivityDays), ctx)
+}
+
+// DeleteScheduledMessage mocks base method.
+func (m *MockStore) DeleteScheduledMessage(ctx context.Context, arg db.DeleteScheduledMessageParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteScheduledMessage", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteScheduledMessage indicates an expected call of DeleteScheduledMessage.
+func (mr *MockStoreMockRecorder) DeleteScheduledMessage(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteScheduledMessage", reflect.TypeOf((*MockStore)(nil).DeleteScheduledMessage), ctx, arg)
+}
+
 // DeleteStory mocks base method.
 func (m *MockStore) DeleteStory(ctx context.Context, id uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -630,6 +999,20 @@ func (mr *MockStoreMockRecorder) DeleteStory(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStory", reflect.TypeOf((*MockStore)(nil).DeleteStory), ctx, id)
 }
 
+// DeleteStoryHashtags mocks base method.
+func (m *MockStore) DeleteStoryHashtags(ctx context.Context, storyID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteStoryHashtags", ctx, storyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteStoryHashtags indicates an expected call of DeleteStoryHashtags.
+func (mr *MockStoreMockRecorder) DeleteStoryHashtags(ctx, storyID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStoryHashtags", reflect.TypeOf((*MockStore)(nil).DeleteStoryHashtags), ctx, storyID)
+}
+
 // DeleteStoryMentions mocks base method.
 func (m *MockStore) DeleteStoryMentions(ctx context.Context, storyID uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -658,6 +1041,21 @@ func (mr *MockStoreMockRecorder) DeleteStoryReaction(ctx, arg any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStoryReaction", reflect.TypeOf((*MockStore)(nil).DeleteStoryReaction), ctx, arg)
 }
 
+// DeleteUnreferencedMediaObject mocks base method.
+func (m *MockStore) DeleteUnreferencedMediaObject(ctx context.Context, hash string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUnreferencedMediaObject", ctx, hash)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteUnreferencedMediaObject indicates an expected call of DeleteUnreferencedMediaObject.
+func (mr *MockStoreMockRecorder) DeleteUnreferencedMediaObject(ctx, hash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUnreferencedMediaObject", reflect.TypeOf((*MockStore)(nil).DeleteUnreferencedMediaObject), ctx, hash)
+}
+
 // DeleteUser mocks base method.
 func (m *MockStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -672,6 +1070,20 @@ func (mr *MockStoreMockRecorder) DeleteUser(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockStore)(nil).DeleteUser), ctx, id)
 }
 
+// DeleteUserSoleGroups mocks base method.
+func (m *MockStore) DeleteUserSoleGroups(ctx context.Context, createdBy uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserSoleGroups", ctx, createdBy)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserSoleGroups indicates an expected call of DeleteUserSoleGroups.
+func (mr *MockStoreMockRecorder) DeleteUserSoleGroups(ctx, createdBy any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserSoleGroups", reflect.TypeOf((*MockStore)(nil).DeleteUserSoleGroups), ctx, createdBy)
+}
+
 // ExecTx mocks base method.
 func (m *MockStore) ExecTx(ctx context.Context, fn func(*db.Queries) error) error {
 	m.ctrl.T.Helper()
@@ -701,6 +1113,36 @@ func (mr *MockStoreMockRecorder) FindPotentialCrossings(ctx, arg any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPotentialCrossings", reflect.TypeOf((*MockStore)(nil).FindPotentialCrossings), ctx, arg)
 }
 
+// GetAggregatedReactions mocks base method.
+func (m *MockStore) GetAggregatedReactions(ctx context.Context, arg db.GetAggregatedReactionsParams) ([]db.GetAggregatedReactionsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAggregatedReactions", ctx, arg)
+	ret0, _ := ret[0].([]db.GetAggregatedReactionsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAggregatedReactions indicates an expected call of GetAggregatedReactions.
+func (mr *MockStoreMockRecorder) GetAggregatedReactions(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAggregatedReactions", reflect.TypeOf((*MockStore)(nil).GetAggregatedReactions), ctx, arg)
+}
+
+// GetAnalyticsTotalsSince mocks base method.
+func (m *MockStore) GetAnalyticsTotalsSince(ctx context.Context, since time.Time) ([]db.GetAnalyticsTotalsSinceRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAnalyticsTotalsSince", ctx, since)
+	ret0, _ := ret[0].([]db.GetAnalyticsTotalsSinceRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAnalyticsTotalsSince indicates an expected call of GetAnalyticsTotalsSince.
+func (mr *MockStoreMockRecorder) GetAnalyticsTotalsSince(ctx, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAnalyticsTotalsSince", reflect.TypeOf((*MockStore)(nil).GetAnalyticsTotalsSince), ctx, since)
+}
+
 // GetArchivedStories mocks base method.
 func (m *MockStore) GetArchivedStories(ctx context.Context, arg db.GetArchivedStoriesParams) ([]db.ArchivedStory, error) {
 	m.ctrl.T.Helper()
@@ -761,6 +1203,21 @@ func (mr *MockStoreMockRecorder) GetConnection(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConnection", reflect.TypeOf((*MockStore)(nil).GetConnection), ctx, arg)
 }
 
+// GetConnectionRecommendations mocks base method.
+func (m *MockStore) GetConnectionRecommendations(ctx context.Context, arg db.GetConnectionRecommendationsParams) ([]db.GetConnectionRecommendationsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConnectionRecommendations", ctx, arg)
+	ret0, _ := ret[0].([]db.GetConnectionRecommendationsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConnectionRecommendations indicates an expected call of GetConnectionRecommendations.
+func (mr *MockStoreMockRecorder) GetConnectionRecommendations(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConnectionRecommendations", reflect.TypeOf((*MockStore)(nil).GetConnectionRecommendations), ctx, arg)
+}
+
 // GetConnectionStories mocks base method.
 func (m *MockStore) GetConnectionStories(ctx context.Context, userID uuid.UUID) ([]db.GetConnectionStoriesRow, error) {
 	m.ctrl.T.Helper()
@@ -791,19 +1248,19 @@ func (mr *MockStoreMockRecorder) GetConversationList(ctx, receiverID any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConversationList", reflect.TypeOf((*MockStore)(nil).GetConversationList), ctx, receiverID)
 }
 
-// GetConversionStats mocks base method.
-func (m *MockStore) GetConversionStats(ctx context.Context) (db.GetConversionStatsRow, error) {
+// GetConversationSettings mocks base method.
+func (m *MockStore) GetConversationSettings(ctx context.Context, arg db.GetConversationSettingsParams) (db.ConversationSetting, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetConversionStats", ctx)
-	ret0, _ := ret[0].(db.GetConversionStatsRow)
+	ret := m.ctrl.Call(m, "GetConversationSettings", ctx, arg)
+	ret0, _ := ret[0].(db.ConversationSetting)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetConversionStats indicates an expected call of GetConversionStats.
-func (mr *MockStoreMockRecorder) GetConversionStats(ctx any) *gomock.Call {
+// GetConversationSettings indicates an expected call of GetConversationSettings.
+func (mr *MockStoreMockRecorder) GetConversationSettings(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConversionStats", reflect.TypeOf((*MockStore)(nil).GetConversionStats), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConversationSettings", reflect.TypeOf((*MockStore)(nil).GetConversationSettings), ctx, arg)
 }
 
 // GetCrossingsForUser mocks base method.
@@ -821,21 +1278,6 @@ func (mr *MockStoreMockRecorder) GetCrossingsForUser(ctx, userID1 any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCrossingsForUser", reflect.TypeOf((*MockStore)(nil).GetCrossingsForUser), ctx, userID1)
 }
 
-// GetEngagementStats mocks base method.
-func (m *MockStore) GetEngagementStats(ctx context.Context) (db.GetEngagementStatsRow, error) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetEngagementStats", ctx)
-	ret0, _ := ret[0].(db.GetEngagementStatsRow)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
-}
-
-// GetEngagementStats indicates an expected call of GetEngagementStats.
-func (mr *MockStoreMockRecorder) GetEngagementStats(ctx any) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEngagementStats", reflect.TypeOf((*MockStore)(nil).GetEngagementStats), ctx)
-}
-
 // GetGroupByID mocks base method.
 func (m *MockStore) GetGroupByID(ctx context.Context, id uuid.UUID) (db.Group, error) {
 	m.ctrl.T.Helper()
@@ -881,19 +1323,34 @@ func (mr *MockStoreMockRecorder) GetGroupMessages(ctx, groupID any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroupMessages", reflect.TypeOf((*MockStore)(nil).GetGroupMessages), ctx, groupID)
 }
 
-// GetHeatmapData mocks base method.
-func (m *MockStore) GetHeatmapData(ctx context.Context) ([]db.GetHeatmapDataRow, error) {
+// GetHeatmapCells mocks base method.
+func (m *MockStore) GetHeatmapCells(ctx context.Context, arg db.GetHeatmapCellsParams) ([]db.GetHeatmapCellsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHeatmapCells", ctx, arg)
+	ret0, _ := ret[0].([]db.GetHeatmapCellsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHeatmapCells indicates an expected call of GetHeatmapCells.
+func (mr *MockStoreMockRecorder) GetHeatmapCells(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHeatmapCells", reflect.TypeOf((*MockStore)(nil).GetHeatmapCells), ctx, arg)
+}
+
+// GetMediaObjectByHash mocks base method.
+func (m *MockStore) GetMediaObjectByHash(ctx context.Context, hash string) (db.MediaObject, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetHeatmapData", ctx)
-	ret0, _ := ret[0].([]db.GetHeatmapDataRow)
+	ret := m.ctrl.Call(m, "GetMediaObjectByHash", ctx, hash)
+	ret0, _ := ret[0].(db.MediaObject)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetHeatmapData indicates an expected call of GetHeatmapData.
-func (mr *MockStoreMockRecorder) GetHeatmapData(ctx any) *gomock.Call {
+// GetMediaObjectByHash indicates an expected call of GetMediaObjectByHash.
+func (mr *MockStoreMockRecorder) GetMediaObjectByHash(ctx, hash any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHeatmapData", reflect.TypeOf((*MockStore)(nil).GetHeatmapData), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMediaObjectByHash", reflect.TypeOf((*MockStore)(nil).GetMediaObjectByHash), ctx, hash)
 }
 
 // GetMessage mocks base method.
@@ -926,6 +1383,36 @@ func (mr *MockStoreMockRecorder) GetMessageReactions(ctx, messageID any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessageReactions", reflect.TypeOf((*MockStore)(nil).GetMessageReactions), ctx, messageID)
 }
 
+// GetMessageReplyPreview mocks base method.
+func (m *MockStore) GetMessageReplyPreview(ctx context.Context, id uuid.UUID) (db.GetMessageReplyPreviewRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessageReplyPreview", ctx, id)
+	ret0, _ := ret[0].(db.GetMessageReplyPreviewRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessageReplyPreview indicates an expected call of GetMessageReplyPreview.
+func (mr *MockStoreMockRecorder) GetMessageReplyPreview(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessageReplyPreview", reflect.TypeOf((*MockStore)(nil).GetMessageReplyPreview), ctx, id)
+}
+
+// GetMessageStatus mocks base method.
+func (m *MockStore) GetMessageStatus(ctx context.Context, arg db.GetMessageStatusParams) ([]db.GetMessageStatusRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessageStatus", ctx, arg)
+	ret0, _ := ret[0].([]db.GetMessageStatusRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessageStatus indicates an expected call of GetMessageStatus.
+func (mr *MockStoreMockRecorder) GetMessageStatus(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessageStatus", reflect.TypeOf((*MockStore)(nil).GetMessageStatus), ctx, arg)
+}
+
 // GetMyProfileViews mocks base method.
 func (m *MockStore) GetMyProfileViews(ctx context.Context, viewerID uuid.UUID) ([]db.GetMyProfileViewsRow, error) {
 	m.ctrl.T.Helper()
@@ -986,6 +1473,21 @@ func (mr *MockStoreMockRecorder) GetRecentProfileVisitors(ctx, viewedUserID any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRecentProfileVisitors", reflect.TypeOf((*MockStore)(nil).GetRecentProfileVisitors), ctx, viewedUserID)
 }
 
+// GetScheduledMessage mocks base method.
+func (m *MockStore) GetScheduledMessage(ctx context.Context, arg db.GetScheduledMessageParams) (db.ScheduledMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScheduledMessage", ctx, arg)
+	ret0, _ := ret[0].(db.ScheduledMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledMessage indicates an expected call of GetScheduledMessage.
+func (mr *MockStoreMockRecorder) GetScheduledMessage(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledMessage", reflect.TypeOf((*MockStore)(nil).GetScheduledMessage), ctx, arg)
+}
+
 // GetSession mocks base method.
 func (m *MockStore) GetSession(ctx context.Context, id uuid.UUID) (db.Session, error) {
 	m.ctrl.T.Helper()
@@ -1001,6 +1503,21 @@ func (mr *MockStoreMockRecorder) GetSession(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSession", reflect.TypeOf((*MockStore)(nil).GetSession), ctx, id)
 }
 
+// GetSignupRetention mocks base method.
+func (m *MockStore) GetSignupRetention(ctx context.Context, arg db.GetSignupRetentionParams) (db.GetSignupRetentionRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSignupRetention", ctx, arg)
+	ret0, _ := ret[0].(db.GetSignupRetentionRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSignupRetention indicates an expected call of GetSignupRetention.
+func (mr *MockStoreMockRecorder) GetSignupRetention(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSignupRetention", reflect.TypeOf((*MockStore)(nil).GetSignupRetention), ctx, arg)
+}
+
 // GetStoriesInBounds mocks base method.
 func (m *MockStore) GetStoriesInBounds(ctx context.Context, arg db.GetStoriesInBoundsParams) ([]db.GetStoriesInBoundsRow, error) {
 	m.ctrl.T.Helper()
@@ -1106,21 +1623,6 @@ func (mr *MockStoreMockRecorder) GetStoryViewers(ctx, storyID any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStoryViewers", reflect.TypeOf((*MockStore)(nil).GetStoryViewers), ctx, storyID)
 }
 
-// GetStreakRetentionStats mocks base method.
-func (m *MockStore) GetStreakRetentionStats(ctx context.Context) (db.GetStreakRetentionStatsRow, error) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetStreakRetentionStats", ctx)
-	ret0, _ := ret[0].(db.GetStreakRetentionStatsRow)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
-}
-
-// GetStreakRetentionStats indicates an expected call of GetStreakRetentionStats.
-func (mr *MockStoreMockRecorder) GetStreakRetentionStats(ctx any) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStreakRetentionStats", reflect.TypeOf((*MockStore)(nil).GetStreakRetentionStats), ctx)
-}
-
 // GetSuggestedConnections mocks base method.
 func (m *MockStore) GetSuggestedConnections(ctx context.Context, arg db.GetSuggestedConnectionsParams) ([]db.GetSuggestedConnectionsRow, error) {
 	m.ctrl.T.Helper()
@@ -1151,6 +1653,21 @@ func (mr *MockStoreMockRecorder) GetSystemStats(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSystemStats", reflect.TypeOf((*MockStore)(nil).GetSystemStats), ctx)
 }
 
+// GetTrendingHashtags mocks base method.
+func (m *MockStore) GetTrendingHashtags(ctx context.Context, arg db.GetTrendingHashtagsParams) ([]db.GetTrendingHashtagsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTrendingHashtags", ctx, arg)
+	ret0, _ := ret[0].([]db.GetTrendingHashtagsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTrendingHashtags indicates an expected call of GetTrendingHashtags.
+func (mr *MockStoreMockRecorder) GetTrendingHashtags(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTrendingHashtags", reflect.TypeOf((*MockStore)(nil).GetTrendingHashtags), ctx, arg)
+}
+
 // GetUnreadMessageCount mocks base method.
 func (m *MockStore) GetUnreadMessageCount(ctx context.Context, receiverID uuid.NullUUID) (int64, error) {
 	m.ctrl.T.Helper()
@@ -1316,6 +1833,21 @@ func (mr *MockStoreMockRecorder) GetUserMentions(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserMentions", reflect.TypeOf((*MockStore)(nil).GetUserMentions), ctx, arg)
 }
 
+// GetUserPresence mocks base method.
+func (m *MockStore) GetUserPresence(ctx context.Context, id uuid.UUID) (db.GetUserPresenceRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserPresence", ctx, id)
+	ret0, _ := ret[0].(db.GetUserPresenceRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserPresence indicates an expected call of GetUserPresence.
+func (mr *MockStoreMockRecorder) GetUserPresence(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserPresence", reflect.TypeOf((*MockStore)(nil).GetUserPresence), ctx, id)
+}
+
 // GetUserProfile mocks base method.
 func (m *MockStore) GetUserProfile(ctx context.Context, id uuid.UUID) (db.GetUserProfileRow, error) {
 	m.ctrl.T.Helper()
@@ -1331,6 +1863,36 @@ func (mr *MockStoreMockRecorder) GetUserProfile(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserProfile", reflect.TypeOf((*MockStore)(nil).GetUserProfile), ctx, id)
 }
 
+// GetUsersByUsernames mocks base method.
+func (m *MockStore) GetUsersByUsernames(ctx context.Context, usernames []string) ([]db.GetUsersByUsernamesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsersByUsernames", ctx, usernames)
+	ret0, _ := ret[0].([]db.GetUsersByUsernamesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsersByUsernames indicates an expected call of GetUsersByUsernames.
+func (mr *MockStoreMockRecorder) GetUsersByUsernames(ctx, usernames any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersByUsernames", reflect.TypeOf((*MockStore)(nil).GetUsersByUsernames), ctx, usernames)
+}
+
+// HasStoryReply mocks base method.
+func (m *MockStore) HasStoryReply(ctx context.Context, arg db.HasStoryReplyParams) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasStoryReply", ctx, arg)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasStoryReply indicates an expected call of HasStoryReply.
+func (mr *MockStoreMockRecorder) HasStoryReply(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasStoryReply", reflect.TypeOf((*MockStore)(nil).HasStoryReply), ctx, arg)
+}
+
 // HasValidStory mocks base method.
 func (m *MockStore) HasValidStory(ctx context.Context, userID uuid.UUID) (bool, error) {
 	m.ctrl.T.Helper()
@@ -1346,6 +1908,65 @@ func (mr *MockStoreMockRecorder) HasValidStory(ctx, userID any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasValidStory", reflect.TypeOf((*MockStore)(nil).HasValidStory), ctx, userID)
 }
 
+// InsertConnectionRecommendations mocks base method.
+func (m *MockStore) InsertConnectionRecommendations(ctx context.Context, arg db.InsertConnectionRecommendationsParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertConnectionRecommendations", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertConnectionRecommendations indicates an expected call of InsertConnectionRecommendations.
+func (mr *MockStoreMockRecorder) InsertConnectionRecommendations(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertConnectionRecommendations", reflect.TypeOf((*MockStore)(nil).InsertConnectionRecommendations), ctx, arg)
+}
+
+// IsCloseFriend mocks base method.
+func (m *MockStore) IsCloseFriend(ctx context.Context, arg db.IsCloseFriendParams) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsCloseFriend", ctx, arg)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsCloseFriend indicates an expected call of IsCloseFriend.
+func (mr *MockStoreMockRecorder) IsCloseFriend(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsCloseFriend", reflect.TypeOf((*MockStore)(nil).IsCloseFriend), ctx, arg)
+}
+
+// IsConversationMuted mocks base method.
+func (m *MockStore) IsConversationMuted(ctx context.Context, arg db.IsConversationMutedParams) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsConversationMuted", ctx, arg)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsConversationMuted indicates an expected call of IsConversationMuted.
+func (mr *MockStoreMockRecorder) IsConversationMuted(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsConversationMuted", reflect.TypeOf((*MockStore)(nil).IsConversationMuted), ctx, arg)
+}
+
+// IsStoryHeld mocks base method.
+func (m *MockStore) IsStoryHeld(ctx context.Context, storyID uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsStoryHeld", ctx, storyID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsStoryHeld indicates an expected call of IsStoryHeld.
+func (mr *MockStoreMockRecorder) IsStoryHeld(ctx, storyID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsStoryHeld", reflect.TypeOf((*MockStore)(nil).IsStoryHeld), ctx, storyID)
+}
+
 // IsUserBlocked mocks base method.
 func (m *MockStore) IsUserBlocked(ctx context.Context, arg db.IsUserBlockedParams) (bool, error) {
 	m.ctrl.T.Helper()
@@ -1361,6 +1982,81 @@ func (mr *MockStoreMockRecorder) IsUserBlocked(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsUserBlocked", reflect.TypeOf((*MockStore)(nil).IsUserBlocked), ctx, arg)
 }
 
+// LiftExpiredBans mocks base method.
+func (m *MockStore) LiftExpiredBans(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LiftExpiredBans", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LiftExpiredBans indicates an expected call of LiftExpiredBans.
+func (mr *MockStoreMockRecorder) LiftExpiredBans(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LiftExpiredBans", reflect.TypeOf((*MockStore)(nil).LiftExpiredBans), ctx)
+}
+
+// ListActiveLockouts mocks base method.
+func (m *MockStore) ListActiveLockouts(ctx context.Context) ([]db.ListActiveLockoutsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListActiveLockouts", ctx)
+	ret0, _ := ret[0].([]db.ListActiveLockoutsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListActiveLockouts indicates an expected call of ListActiveLockouts.
+func (mr *MockStoreMockRecorder) ListActiveLockouts(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActiveLockouts", reflect.TypeOf((*MockStore)(nil).ListActiveLockouts), ctx)
+}
+
+// ListActiveSessions mocks base method.
+func (m *MockStore) ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListActiveSessions", ctx, userID)
+	ret0, _ := ret[0].([]db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListActiveSessions indicates an expected call of ListActiveSessions.
+func (mr *MockStoreMockRecorder) ListActiveSessions(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActiveSessions", reflect.TypeOf((*MockStore)(nil).ListActiveSessions), ctx, userID)
+}
+
+// ListActiveUserIDs mocks base method.
+func (m *MockStore) ListActiveUserIDs(ctx context.Context, activeSince time.Time) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListActiveUserIDs", ctx, activeSince)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListActiveUserIDs indicates an expected call of ListActiveUserIDs.
+func (mr *MockStoreMockRecorder) ListActiveUserIDs(ctx, activeSince any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActiveUserIDs", reflect.TypeOf((*MockStore)(nil).ListActiveUserIDs), ctx, activeSince)
+}
+
+// ListAdminAuditLog mocks base method.
+func (m *MockStore) ListAdminAuditLog(ctx context.Context, arg db.ListAdminAuditLogParams) ([]db.AdminAuditLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAdminAuditLog", ctx, arg)
+	ret0, _ := ret[0].([]db.AdminAuditLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAdminAuditLog indicates an expected call of ListAdminAuditLog.
+func (mr *MockStoreMockRecorder) ListAdminAuditLog(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAdminAuditLog", reflect.TypeOf((*MockStore)(nil).ListAdminAuditLog), ctx, arg)
+}
+
 // ListAllStories mocks base method.
 func (m *MockStore) ListAllStories(ctx context.Context, arg db.ListAllStoriesParams) ([]db.ListAllStoriesRow, error) {
 	m.ctrl.T.Helper()
@@ -1376,6 +2072,36 @@ func (mr *MockStoreMockRecorder) ListAllStories(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAllStories", reflect.TypeOf((*MockStore)(nil).ListAllStories), ctx, arg)
 }
 
+// ListCloseFriends mocks base method.
+func (m *MockStore) ListCloseFriends(ctx context.Context, userID uuid.UUID) ([]db.ListCloseFriendsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCloseFriends", ctx, userID)
+	ret0, _ := ret[0].([]db.ListCloseFriendsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCloseFriends indicates an expected call of ListCloseFriends.
+func (mr *MockStoreMockRecorder) ListCloseFriends(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCloseFriends", reflect.TypeOf((*MockStore)(nil).ListCloseFriends), ctx, userID)
+}
+
+// ListConnectionStoryRings mocks base method.
+func (m *MockStore) ListConnectionStoryRings(ctx context.Context, userID uuid.UUID) ([]db.ListConnectionStoryRingsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListConnectionStoryRings", ctx, userID)
+	ret0, _ := ret[0].([]db.ListConnectionStoryRingsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListConnectionStoryRings indicates an expected call of ListConnectionStoryRings.
+func (mr *MockStoreMockRecorder) ListConnectionStoryRings(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListConnectionStoryRings", reflect.TypeOf((*MockStore)(nil).ListConnectionStoryRings), ctx, userID)
+}
+
 // ListConnections mocks base method.
 func (m *MockStore) ListConnections(ctx context.Context, requesterID uuid.UUID) ([]db.ListConnectionsRow, error) {
 	m.ctrl.T.Helper()
@@ -1391,6 +2117,96 @@ func (mr *MockStoreMockRecorder) ListConnections(ctx, requesterID any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListConnections", reflect.TypeOf((*MockStore)(nil).ListConnections), ctx, requesterID)
 }
 
+// ListConnectionsByStatus mocks base method.
+func (m *MockStore) ListConnectionsByStatus(ctx context.Context, arg db.ListConnectionsByStatusParams) ([]db.ListConnectionsByStatusRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListConnectionsByStatus", ctx, arg)
+	ret0, _ := ret[0].([]db.ListConnectionsByStatusRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListConnectionsByStatus indicates an expected call of ListConnectionsByStatus.
+func (mr *MockStoreMockRecorder) ListConnectionsByStatus(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListConnectionsByStatus", reflect.TypeOf((*MockStore)(nil).ListConnectionsByStatus), ctx, arg)
+}
+
+// ListDueScheduledMessages mocks base method.
+func (m *MockStore) ListDueScheduledMessages(ctx context.Context, arg db.ListDueScheduledMessagesParams) ([]db.ScheduledMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDueScheduledMessages", ctx, arg)
+	ret0, _ := ret[0].([]db.ScheduledMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDueScheduledMessages indicates an expected call of ListDueScheduledMessages.
+func (mr *MockStoreMockRecorder) ListDueScheduledMessages(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDueScheduledMessages", reflect.TypeOf((*MockStore)(nil).ListDueScheduledMessages), ctx, arg)
+}
+
+// ListGroupMuters mocks base method.
+func (m *MockStore) ListGroupMuters(ctx context.Context, groupID uuid.NullUUID) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGroupMuters", ctx, groupID)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGroupMuters indicates an expected call of ListGroupMuters.
+func (mr *MockStoreMockRecorder) ListGroupMuters(ctx, groupID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGroupMuters", reflect.TypeOf((*MockStore)(nil).ListGroupMuters), ctx, groupID)
+}
+
+// ListHeldStories mocks base method.
+func (m *MockStore) ListHeldStories(ctx context.Context, arg db.ListHeldStoriesParams) ([]db.ListHeldStoriesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListHeldStories", ctx, arg)
+	ret0, _ := ret[0].([]db.ListHeldStoriesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListHeldStories indicates an expected call of ListHeldStories.
+func (mr *MockStoreMockRecorder) ListHeldStories(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListHeldStories", reflect.TypeOf((*MockStore)(nil).ListHeldStories), ctx, arg)
+}
+
+// ListMessageAttachments mocks base method.
+func (m *MockStore) ListMessageAttachments(ctx context.Context, messageID uuid.UUID) ([]db.MessageAttachment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMessageAttachments", ctx, messageID)
+	ret0, _ := ret[0].([]db.MessageAttachment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMessageAttachments indicates an expected call of ListMessageAttachments.
+func (mr *MockStoreMockRecorder) ListMessageAttachments(ctx, messageID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMessageAttachments", reflect.TypeOf((*MockStore)(nil).ListMessageAttachments), ctx, messageID)
+}
+
+// ListMessageReplies mocks base method.
+func (m *MockStore) ListMessageReplies(ctx context.Context, arg db.ListMessageRepliesParams) ([]db.ListMessageRepliesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMessageReplies", ctx, arg)
+	ret0, _ := ret[0].([]db.ListMessageRepliesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMessageReplies indicates an expected call of ListMessageReplies.
+func (mr *MockStoreMockRecorder) ListMessageReplies(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMessageReplies", reflect.TypeOf((*MockStore)(nil).ListMessageReplies), ctx, arg)
+}
+
 // ListMessages mocks base method.
 func (m *MockStore) ListMessages(ctx context.Context, arg db.ListMessagesParams) ([]db.ListMessagesRow, error) {
 	m.ctrl.T.Helper()
@@ -1406,6 +2222,36 @@ func (mr *MockStoreMockRecorder) ListMessages(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMessages", reflect.TypeOf((*MockStore)(nil).ListMessages), ctx, arg)
 }
 
+// ListMyStories mocks base method.
+func (m *MockStore) ListMyStories(ctx context.Context, arg db.ListMyStoriesParams) ([]db.ListMyStoriesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMyStories", ctx, arg)
+	ret0, _ := ret[0].([]db.ListMyStoriesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMyStories indicates an expected call of ListMyStories.
+func (mr *MockStoreMockRecorder) ListMyStories(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMyStories", reflect.TypeOf((*MockStore)(nil).ListMyStories), ctx, arg)
+}
+
+// ListNearbyUsers mocks base method.
+func (m *MockStore) ListNearbyUsers(ctx context.Context, arg db.ListNearbyUsersParams) ([]db.ListNearbyUsersRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNearbyUsers", ctx, arg)
+	ret0, _ := ret[0].([]db.ListNearbyUsersRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNearbyUsers indicates an expected call of ListNearbyUsers.
+func (mr *MockStoreMockRecorder) ListNearbyUsers(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNearbyUsers", reflect.TypeOf((*MockStore)(nil).ListNearbyUsers), ctx, arg)
+}
+
 // ListNotifications mocks base method.
 func (m *MockStore) ListNotifications(ctx context.Context, arg db.ListNotificationsParams) ([]db.Notification, error) {
 	m.ctrl.T.Helper()
@@ -1451,6 +2297,21 @@ func (mr *MockStoreMockRecorder) ListReports(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReports", reflect.TypeOf((*MockStore)(nil).ListReports), ctx, arg)
 }
 
+// ListScheduledMessages mocks base method.
+func (m *MockStore) ListScheduledMessages(ctx context.Context, senderID uuid.UUID) ([]db.ScheduledMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListScheduledMessages", ctx, senderID)
+	ret0, _ := ret[0].([]db.ScheduledMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListScheduledMessages indicates an expected call of ListScheduledMessages.
+func (mr *MockStoreMockRecorder) ListScheduledMessages(ctx, senderID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListScheduledMessages", reflect.TypeOf((*MockStore)(nil).ListScheduledMessages), ctx, senderID)
+}
+
 // ListSentConnectionRequests mocks base method.
 func (m *MockStore) ListSentConnectionRequests(ctx context.Context, requesterID uuid.UUID) ([]db.ListSentConnectionRequestsRow, error) {
 	m.ctrl.T.Helper()
@@ -1466,19 +2327,64 @@ func (mr *MockStoreMockRecorder) ListSentConnectionRequests(ctx, requesterID any
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSentConnectionRequests", reflect.TypeOf((*MockStore)(nil).ListSentConnectionRequests), ctx, requesterID)
 }
 
-// ListUsers mocks base method.
-func (m *MockStore) ListUsers(ctx context.Context, arg db.ListUsersParams) ([]db.User, error) {
+// ListUnreferencedMediaObjects mocks base method.
+func (m *MockStore) ListUnreferencedMediaObjects(ctx context.Context, arg db.ListUnreferencedMediaObjectsParams) ([]db.MediaObject, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListUsers", ctx, arg)
-	ret0, _ := ret[0].([]db.User)
+	ret := m.ctrl.Call(m, "ListUnreferencedMediaObjects", ctx, arg)
+	ret0, _ := ret[0].([]db.MediaObject)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListUsers indicates an expected call of ListUsers.
-func (mr *MockStoreMockRecorder) ListUsers(ctx, arg any) *gomock.Call {
+// ListUnreferencedMediaObjects indicates an expected call of ListUnreferencedMediaObjects.
+func (mr *MockStoreMockRecorder) ListUnreferencedMediaObjects(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockStore)(nil).ListUsers), ctx, arg)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUnreferencedMediaObjects", reflect.TypeOf((*MockStore)(nil).ListUnreferencedMediaObjects), ctx, arg)
+}
+
+// ListUserActivityDays mocks base method.
+func (m *MockStore) ListUserActivityDays(ctx context.Context, arg db.ListUserActivityDaysParams) ([]time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUserActivityDays", ctx, arg)
+	ret0, _ := ret[0].([]time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUserActivityDays indicates an expected call of ListUserActivityDays.
+func (mr *MockStoreMockRecorder) ListUserActivityDays(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUserActivityDays", reflect.TypeOf((*MockStore)(nil).ListUserActivityDays), ctx, arg)
+}
+
+// ListUserContacts mocks base method.
+func (m *MockStore) ListUserContacts(ctx context.Context, targetID uuid.UUID) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUserContacts", ctx, targetID)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUserContacts indicates an expected call of ListUserContacts.
+func (mr *MockStoreMockRecorder) ListUserContacts(ctx, targetID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUserContacts", reflect.TypeOf((*MockStore)(nil).ListUserContacts), ctx, targetID)
+}
+
+// ListUserStoryGeohashes mocks base method.
+func (m *MockStore) ListUserStoryGeohashes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUserStoryGeohashes", ctx, userID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUserStoryGeohashes indicates an expected call of ListUserStoryGeohashes.
+func (mr *MockStoreMockRecorder) ListUserStoryGeohashes(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUserStoryGeohashes", reflect.TypeOf((*MockStore)(nil).ListUserStoryGeohashes), ctx, userID)
 }
 
 // MarkAllNotificationsAsRead mocks base method.
@@ -1496,11 +2402,12 @@ func (mr *MockStoreMockRecorder) MarkAllNotificationsAsRead(ctx, userID any) *go
 }
 
 // MarkConversationRead mocks base method.
-func (m *MockStore) MarkConversationRead(ctx context.Context, arg db.MarkConversationReadParams) error {
+func (m *MockStore) MarkConversationRead(ctx context.Context, arg db.MarkConversationReadParams) ([]db.MarkConversationReadRow, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "MarkConversationRead", ctx, arg)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].([]db.MarkConversationReadRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // MarkConversationRead indicates an expected call of MarkConversationRead.
@@ -1524,6 +2431,21 @@ func (mr *MockStoreMockRecorder) MarkMessageRead(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkMessageRead", reflect.TypeOf((*MockStore)(nil).MarkMessageRead), ctx, arg)
 }
 
+// MarkMessagesDelivered mocks base method.
+func (m *MockStore) MarkMessagesDelivered(ctx context.Context, arg db.MarkMessagesDeliveredParams) ([]db.MarkMessagesDeliveredRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkMessagesDelivered", ctx, arg)
+	ret0, _ := ret[0].([]db.MarkMessagesDeliveredRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkMessagesDelivered indicates an expected call of MarkMessagesDelivered.
+func (mr *MockStoreMockRecorder) MarkMessagesDelivered(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkMessagesDelivered", reflect.TypeOf((*MockStore)(nil).MarkMessagesDelivered), ctx, arg)
+}
+
 // MarkNotificationAsRead mocks base method.
 func (m *MockStore) MarkNotificationAsRead(ctx context.Context, arg db.MarkNotificationAsReadParams) (db.Notification, error) {
 	m.ctrl.T.Helper()
@@ -1539,6 +2461,107 @@ func (mr *MockStoreMockRecorder) MarkNotificationAsRead(ctx, arg any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkNotificationAsRead", reflect.TypeOf((*MockStore)(nil).MarkNotificationAsRead), ctx, arg)
 }
 
+// MarkPhoneVerified mocks base method.
+func (m *MockStore) MarkPhoneVerified(ctx context.Context, phone string) (uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkPhoneVerified", ctx, phone)
+	ret0, _ := ret[0].(uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkPhoneVerified indicates an expected call of MarkPhoneVerified.
+func (mr *MockStoreMockRecorder) MarkPhoneVerified(ctx, phone any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkPhoneVerified", reflect.TypeOf((*MockStore)(nil).MarkPhoneVerified), ctx, phone)
+}
+
+// MuteGroupConversation mocks base method.
+func (m *MockStore) MuteGroupConversation(ctx context.Context, arg db.MuteGroupConversationParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MuteGroupConversation", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MuteGroupConversation indicates an expected call of MuteGroupConversation.
+func (mr *MockStoreMockRecorder) MuteGroupConversation(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MuteGroupConversation", reflect.TypeOf((*MockStore)(nil).MuteGroupConversation), ctx, arg)
+}
+
+// MuteUserConversation mocks base method.
+func (m *MockStore) MuteUserConversation(ctx context.Context, arg db.MuteUserConversationParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MuteUserConversation", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MuteUserConversation indicates an expected call of MuteUserConversation.
+func (mr *MockStoreMockRecorder) MuteUserConversation(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MuteUserConversation", reflect.TypeOf((*MockStore)(nil).MuteUserConversation), ctx, arg)
+}
+
+// Ping mocks base method.
+func (m *MockStore) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockStoreMockRecorder) Ping(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStore)(nil).Ping), ctx)
+}
+
+// PromoteUserToAdmin mocks base method.
+func (m *MockStore) PromoteUserToAdmin(ctx context.Context, id uuid.UUID) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PromoteUserToAdmin", ctx, id)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PromoteUserToAdmin indicates an expected call of PromoteUserToAdmin.
+func (mr *MockStoreMockRecorder) PromoteUserToAdmin(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PromoteUserToAdmin", reflect.TypeOf((*MockStore)(nil).PromoteUserToAdmin), ctx, id)
+}
+
+// ReleaseStoryModerationHold mocks base method.
+func (m *MockStore) ReleaseStoryModerationHold(ctx context.Context, storyID uuid.UUID) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseStoryModerationHold", ctx, storyID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseStoryModerationHold indicates an expected call of ReleaseStoryModerationHold.
+func (mr *MockStoreMockRecorder) ReleaseStoryModerationHold(ctx, storyID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseStoryModerationHold", reflect.TypeOf((*MockStore)(nil).ReleaseStoryModerationHold), ctx, storyID)
+}
+
+// RemoveCloseFriend mocks base method.
+func (m *MockStore) RemoveCloseFriend(ctx context.Context, arg db.RemoveCloseFriendParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveCloseFriend", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveCloseFriend indicates an expected call of RemoveCloseFriend.
+func (mr *MockStoreMockRecorder) RemoveCloseFriend(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveCloseFriend", reflect.TypeOf((*MockStore)(nil).RemoveCloseFriend), ctx, arg)
+}
+
 // RemoveGroupMember mocks base method.
 func (m *MockStore) RemoveGroupMember(ctx context.Context, arg db.RemoveGroupMemberParams) error {
 	m.ctrl.T.Helper()
@@ -1568,6 +2591,50 @@ func (mr *MockStoreMockRecorder) ResolveReport(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveReport", reflect.TypeOf((*MockStore)(nil).ResolveReport), ctx, id)
 }
 
+// RevokeExcessSessions mocks base method.
+func (m *MockStore) RevokeExcessSessions(ctx context.Context, arg db.RevokeExcessSessionsParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeExcessSessions", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeExcessSessions indicates an expected call of RevokeExcessSessions.
+func (mr *MockStoreMockRecorder) RevokeExcessSessions(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeExcessSessions", reflect.TypeOf((*MockStore)(nil).RevokeExcessSessions), ctx, arg)
+}
+
+// RevokeSession mocks base method.
+func (m *MockStore) RevokeSession(ctx context.Context, arg db.RevokeSessionParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockStoreMockRecorder) RevokeSession(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockStore)(nil).RevokeSession), ctx, arg)
+}
+
+// RollupAnalyticsDaily mocks base method.
+func (m *MockStore) RollupAnalyticsDaily(ctx context.Context, fromDay time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RollupAnalyticsDaily", ctx, fromDay)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RollupAnalyticsDaily indicates an expected call of RollupAnalyticsDaily.
+func (mr *MockStoreMockRecorder) RollupAnalyticsDaily(ctx, fromDay any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RollupAnalyticsDaily", reflect.TypeOf((*MockStore)(nil).RollupAnalyticsDaily), ctx, fromDay)
+}
+
 // SaveMessage mocks base method.
 func (m *MockStore) SaveMessage(ctx context.Context, id uuid.UUID) (db.Message, error) {
 	m.ctrl.T.Helper()
@@ -1583,19 +2650,79 @@ func (mr *MockStoreMockRecorder) SaveMessage(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveMessage", reflect.TypeOf((*MockStore)(nil).SaveMessage), ctx, id)
 }
 
+// SearchAdminUsers mocks base method.
+func (m *MockStore) SearchAdminUsers(ctx context.Context, arg db.SearchAdminUsersParams) ([]db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchAdminUsers", ctx, arg)
+	ret0, _ := ret[0].([]db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchAdminUsers indicates an expected call of SearchAdminUsers.
+func (mr *MockStoreMockRecorder) SearchAdminUsers(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchAdminUsers", reflect.TypeOf((*MockStore)(nil).SearchAdminUsers), ctx, arg)
+}
+
+// SearchDirectMessages mocks base method.
+func (m *MockStore) SearchDirectMessages(ctx context.Context, arg db.SearchDirectMessagesParams) ([]db.SearchDirectMessagesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchDirectMessages", ctx, arg)
+	ret0, _ := ret[0].([]db.SearchDirectMessagesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchDirectMessages indicates an expected call of SearchDirectMessages.
+func (mr *MockStoreMockRecorder) SearchDirectMessages(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchDirectMessages", reflect.TypeOf((*MockStore)(nil).SearchDirectMessages), ctx, arg)
+}
+
+// SearchGroupMessages mocks base method.
+func (m *MockStore) SearchGroupMessages(ctx context.Context, arg db.SearchGroupMessagesParams) ([]db.SearchGroupMessagesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchGroupMessages", ctx, arg)
+	ret0, _ := ret[0].([]db.SearchGroupMessagesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchGroupMessages indicates an expected call of SearchGroupMessages.
+func (mr *MockStoreMockRecorder) SearchGroupMessages(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchGroupMessages", reflect.TypeOf((*MockStore)(nil).SearchGroupMessages), ctx, arg)
+}
+
 // SearchUsers mocks base method.
-func (m *MockStore) SearchUsers(ctx context.Context, query string) ([]db.SearchUsersRow, error) {
+func (m *MockStore) SearchUsers(ctx context.Context, arg db.SearchUsersParams) ([]db.SearchUsersRow, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SearchUsers", ctx, query)
+	ret := m.ctrl.Call(m, "SearchUsers", ctx, arg)
 	ret0, _ := ret[0].([]db.SearchUsersRow)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SearchUsers indicates an expected call of SearchUsers.
-func (mr *MockStoreMockRecorder) SearchUsers(ctx, query any) *gomock.Call {
+func (mr *MockStoreMockRecorder) SearchUsers(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockStore)(nil).SearchUsers), ctx, query)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockStore)(nil).SearchUsers), ctx, arg)
+}
+
+// SetMediaObjectHold mocks base method.
+func (m *MockStore) SetMediaObjectHold(ctx context.Context, arg db.SetMediaObjectHoldParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMediaObjectHold", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetMediaObjectHold indicates an expected call of SetMediaObjectHold.
+func (mr *MockStoreMockRecorder) SetMediaObjectHold(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMediaObjectHold", reflect.TypeOf((*MockStore)(nil).SetMediaObjectHold), ctx, arg)
 }
 
 // SetPasswordResetToken mocks base method.
@@ -1643,6 +2770,20 @@ func (mr *MockStoreMockRecorder) TrackProfileView(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrackProfileView", reflect.TypeOf((*MockStore)(nil).TrackProfileView), ctx, arg)
 }
 
+// TransferUserGroups mocks base method.
+func (m *MockStore) TransferUserGroups(ctx context.Context, userID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferUserGroups", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TransferUserGroups indicates an expected call of TransferUserGroups.
+func (mr *MockStoreMockRecorder) TransferUserGroups(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferUserGroups", reflect.TypeOf((*MockStore)(nil).TransferUserGroups), ctx, userID)
+}
+
 // UnblockUser mocks base method.
 func (m *MockStore) UnblockUser(ctx context.Context, arg db.UnblockUserParams) error {
 	m.ctrl.T.Helper()
@@ -1657,6 +2798,20 @@ func (mr *MockStoreMockRecorder) UnblockUser(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnblockUser", reflect.TypeOf((*MockStore)(nil).UnblockUser), ctx, arg)
 }
 
+// UnmuteConversation mocks base method.
+func (m *MockStore) UnmuteConversation(ctx context.Context, arg db.UnmuteConversationParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnmuteConversation", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnmuteConversation indicates an expected call of UnmuteConversation.
+func (mr *MockStoreMockRecorder) UnmuteConversation(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnmuteConversation", reflect.TypeOf((*MockStore)(nil).UnmuteConversation), ctx, arg)
+}
+
 // UpdateConnectionStatus mocks base method.
 func (m *MockStore) UpdateConnectionStatus(ctx context.Context, arg db.UpdateConnectionStatusParams) (db.Connection, error) {
 	m.ctrl.T.Helper()
@@ -1687,6 +2842,21 @@ func (mr *MockStoreMockRecorder) UpdateMessage(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMessage", reflect.TypeOf((*MockStore)(nil).UpdateMessage), ctx, arg)
 }
 
+// UpdateScheduledMessage mocks base method.
+func (m *MockStore) UpdateScheduledMessage(ctx context.Context, arg db.UpdateScheduledMessageParams) (db.ScheduledMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateScheduledMessage", ctx, arg)
+	ret0, _ := ret[0].(db.ScheduledMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateScheduledMessage indicates an expected call of UpdateScheduledMessage.
+func (mr *MockStoreMockRecorder) UpdateScheduledMessage(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateScheduledMessage", reflect.TypeOf((*MockStore)(nil).UpdateScheduledMessage), ctx, arg)
+}
+
 // UpdateStory mocks base method.
 func (m *MockStore) UpdateStory(ctx context.Context, arg db.UpdateStoryParams) (db.UpdateStoryRow, error) {
 	m.ctrl.T.Helper()
@@ -1747,6 +2917,20 @@ func (mr *MockStoreMockRecorder) UpdateUserGoogleID(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserGoogleID", reflect.TypeOf((*MockStore)(nil).UpdateUserGoogleID), ctx, arg)
 }
 
+// UpdateUserLastSeen mocks base method.
+func (m *MockStore) UpdateUserLastSeen(ctx context.Context, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserLastSeen", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserLastSeen indicates an expected call of UpdateUserLastSeen.
+func (mr *MockStoreMockRecorder) UpdateUserLastSeen(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserLastSeen", reflect.TypeOf((*MockStore)(nil).UpdateUserLastSeen), ctx, id)
+}
+
 // UpdateUserPassword mocks base method.
 func (m *MockStore) UpdateUserPassword(ctx context.Context, arg db.UpdateUserPasswordParams) error {
 	m.ctrl.T.Helper()
@@ -1791,6 +2975,36 @@ func (mr *MockStoreMockRecorder) UpdateUserTrust(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserTrust", reflect.TypeOf((*MockStore)(nil).UpdateUserTrust), ctx, arg)
 }
 
+// UpsertConversationSettings mocks base method.
+func (m *MockStore) UpsertConversationSettings(ctx context.Context, arg db.UpsertConversationSettingsParams) (db.ConversationSetting, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertConversationSettings", ctx, arg)
+	ret0, _ := ret[0].(db.ConversationSetting)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertConversationSettings indicates an expected call of UpsertConversationSettings.
+func (mr *MockStoreMockRecorder) UpsertConversationSettings(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertConversationSettings", reflect.TypeOf((*MockStore)(nil).UpsertConversationSettings), ctx, arg)
+}
+
+// UpsertMediaObject mocks base method.
+func (m *MockStore) UpsertMediaObject(ctx context.Context, arg db.UpsertMediaObjectParams) (db.MediaObject, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertMediaObject", ctx, arg)
+	ret0, _ := ret[0].(db.MediaObject)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertMediaObject indicates an expected call of UpsertMediaObject.
+func (mr *MockStoreMockRecorder) UpsertMediaObject(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertMediaObject", reflect.TypeOf((*MockStore)(nil).UpsertMediaObject), ctx, arg)
+}
+
 // UpsertPrivacySettings mocks base method.
 func (m *MockStore) UpsertPrivacySettings(ctx context.Context, arg db.UpsertPrivacySettingsParams) (db.PrivacySetting, error) {
 	m.ctrl.T.Helper()
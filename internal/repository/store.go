@@ -13,6 +13,8 @@ type Store interface {
 	db.Querier
 	// Add transaction methods here later if needed
 	ExecTx(ctx context.Context, fn func(*db.Queries) error) error
+	// Ping checks the database can still be reached
+	Ping(ctx context.Context) error
 }
 
 // SQLStore provides all functions to execute SQL queries and transactions
@@ -47,3 +49,8 @@ func (store *SQLStore) ExecTx(ctx context.Context, fn func(*db.Queries) error) e
 
 	return tx.Commit()
 }
+
+// Ping checks the database can still be reached
+func (store *SQLStore) Ping(ctx context.Context) error {
+	return store.db.PingContext(ctx)
+}
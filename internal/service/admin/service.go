@@ -2,7 +2,9 @@ package admin
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,6 +13,8 @@ import (
 
 	"privacy-social-backend/internal/repository"
 	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/analytics"
+	"privacy-social-backend/internal/util"
 )
 
 const (
@@ -21,11 +25,48 @@ const (
 type ListUsersParams struct {
 	PageID   int32
 	PageSize int32
+	// Query matches part of a username, email or phone; empty matches everyone
+	Query string
+	// Status is UserStatusAll (the default), UserStatusBanned or UserStatusActive
+	Status string
 }
 
+// User status filters for ListUsers. A temporary ban that has run out counts as active.
+const (
+	UserStatusAll    = "all"
+	UserStatusBanned = "banned"
+	UserStatusActive = "active"
+)
+
+// ErrMediaNotFound is returned when a hold targets media the server never stored
+var ErrMediaNotFound = errors.New("media not found")
+
+// ErrStoryNotHeld is returned when releasing a story that isn't awaiting moderation
+var ErrStoryNotHeld = errors.New("story is not held for moderation")
+
+// Ban types. A shadow ban hides the user's content without telling them, a
+// hard ban locks them out and a temporary ban locks them out until it expires.
+const (
+	BanTypeShadow = "shadow"
+	BanTypeHard   = "hard"
+	BanTypeTemp   = "temp"
+)
+
+var (
+	// ErrBanExpiryRequired is returned when a temporary ban has no end in the future
+	ErrBanExpiryRequired = errors.New("a temporary ban needs an expiry in the future")
+	// ErrBanExpiryNotAllowed is returned when a shadow or hard ban is given an expiry
+	ErrBanExpiryNotAllowed = errors.New("only temporary bans can expire")
+)
+
+// BanUserParams bans a user, or lifts their ban when Ban is false. Type
+// defaults to a shadow ban.
 type BanUserParams struct {
-	UserID string
-	Ban    bool
+	UserID    string
+	Ban       bool
+	Type      string
+	Reason    string
+	ExpiresAt *time.Time
 }
 
 type Service interface {
@@ -33,10 +74,14 @@ type Service interface {
 	ListUsers(ctx context.Context, params ListUsersParams) ([]db.User, int64, error)
 	BanUser(ctx context.Context, params BanUserParams) (db.User, error)
 	DeleteUser(ctx context.Context, userID string) error
-	ListReports(ctx context.Context, resolved bool, pageID, pageSize int32) ([]db.ListReportsRow, error)
+	ListReports(ctx context.Context, resolved bool, pageID, pageSize int32) ([]db.ListReportsRow, int64, error)
 	ResolveReport(ctx context.Context, reportID string) (db.Report, error)
 	DeleteStory(ctx context.Context, storyID string) error
-	ListAllStories(ctx context.Context, pageID, pageSize int32) ([]db.ListAllStoriesRow, error)
+	ListAllStories(ctx context.Context, pageID, pageSize int32) ([]db.ListAllStoriesRow, int64, error)
+	SetMediaHold(ctx context.Context, url string, hold bool) error
+	ListHeldStories(ctx context.Context, pageID, pageSize int32) ([]db.ListHeldStoriesRow, error)
+	ReleaseStoryHold(ctx context.Context, storyID string) error
+	ListAuditLog(ctx context.Context, pageID, pageSize int32) ([]db.AdminAuditLog, int64, error)
 }
 
 type ServiceImpl struct {
@@ -53,7 +98,7 @@ func NewService(store repository.Store, redis *redis.Client) Service {
 
 func (s *ServiceImpl) GetStats(ctx context.Context) (map[string]interface{}, bool, error) {
 	// Try cache first
-	cachedData, err := s.redis.Get(ctx, statsCacheKey).Result()
+	cachedData, err := s.redis.Get(ctx, util.RedisKey(statsCacheKey)).Result()
 	if err == nil && cachedData != "" {
 		var response map[string]interface{}
 		if err := json.Unmarshal([]byte(cachedData), &response); err == nil {
@@ -72,43 +117,95 @@ func (s *ServiceImpl) GetStats(ctx context.Context) (map[string]interface{}, boo
 		return nil, false, err
 	}
 
-	// Fetch Analytics (North Star)
-	retention, err := s.store.GetStreakRetentionStats(ctx)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to get retention stats")
-		retention = db.GetStreakRetentionStatsRow{}
-	}
-	engagement, err := s.store.GetEngagementStats(ctx)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to get engagement stats")
-		engagement = db.GetEngagementStatsRow{}
-	}
-	conversion, err := s.store.GetConversionStats(ctx)
+	// Fetch Analytics (North Star) from the event store, not the OLTP tables
+	summary, err := s.analyticsSummary(ctx, userStats.TotalUsers)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to get conversion stats")
-		conversion = db.GetConversionStatsRow{}
+		log.Error().Err(err).Msg("failed to get analytics")
+		summary = map[string]interface{}{}
 	}
 
 	response := map[string]interface{}{
-		"users":   userStats,
-		"stories": storyStats,
-		"analytics": map[string]interface{}{
-			"retention_rate_3d":        retention.RetentionRate,
-			"retained_users_count":     retention.RetainedUsersCount,
-			"weekly_stories_per_user":  engagement.AvgStoriesPerUserWeekly,
-			"crossing_conversion_rate": conversion.CrossingConversionRate,
-		},
+		"users":     userStats,
+		"stories":   storyStats,
+		"analytics": summary,
 	}
 
 	// Cache for 1 minute
 	responseJSON, _ := json.Marshal(response)
-	s.redis.Set(ctx, statsCacheKey, responseJSON, statsCacheTTL)
+	s.redis.Set(ctx, util.RedisKey(statsCacheKey), responseJSON, statsCacheTTL)
 
 	return response, false, nil
 }
 
+// analyticsSummary summarises the ingested product events: D7 retention of last week's
+// signup cohort, weekly engagement and crossing-to-connection conversion
+func (s *ServiceImpl) analyticsSummary(ctx context.Context, totalUsers int64) (map[string]interface{}, error) {
+	now := util.Now()
+	weekAgo := now.AddDate(0, 0, -7)
+
+	weekly, err := s.eventTotals(ctx, weekAgo)
+	if err != nil {
+		return nil, err
+	}
+	monthly, err := s.eventTotals(ctx, now.AddDate(0, 0, -30))
+	if err != nil {
+		return nil, err
+	}
+	retention, err := s.store.GetSignupRetention(ctx, db.GetSignupRetentionParams{
+		ActiveSince: weekAgo,
+		CohortStart: now.AddDate(0, 0, -14),
+		CohortEnd:   weekAgo,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"retention_rate_7d":        percent(retention.RetainedCount, retention.CohortSize),
+		"retained_users_count":     retention.RetainedCount,
+		"signup_cohort_size":       retention.CohortSize,
+		"weekly_stories_per_user":  ratio(weekly[string(analytics.EventStoryPosted)], totalUsers),
+		"crossing_conversion_rate": percent(monthly[string(analytics.EventConnectionAccepted)], monthly[string(analytics.EventCrossing)]),
+		"events_7d":                weekly,
+	}, nil
+}
+
+// eventTotals counts each event type on the daily rollups since the given day
+func (s *ServiceImpl) eventTotals(ctx context.Context, since time.Time) (map[string]int64, error) {
+	rows, err := s.store.GetAnalyticsTotalsSince(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		totals[row.EventType] = row.EventCount
+	}
+	return totals, nil
+}
+
+func ratio(n, d int64) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+func percent(n, d int64) float64 {
+	return ratio(n, d) * 100
+}
+
+// ListUsers returns a page of the users matching the filters, newest first,
+// and how many match in all
 func (s *ServiceImpl) ListUsers(ctx context.Context, params ListUsersParams) ([]db.User, int64, error) {
-	users, err := s.store.ListUsers(ctx, db.ListUsersParams{
+	query := sql.NullString{String: params.Query, Valid: params.Query != ""}
+	status := params.Status
+	if status == "" {
+		status = UserStatusAll
+	}
+
+	users, err := s.store.SearchAdminUsers(ctx, db.SearchAdminUsersParams{
+		Query:  query,
+		Status: status,
 		Limit:  params.PageSize,
 		Offset: (params.PageID - 1) * params.PageSize,
 	})
@@ -116,7 +213,10 @@ func (s *ServiceImpl) ListUsers(ctx context.Context, params ListUsersParams) ([]
 		return nil, 0, err
 	}
 
-	count, err := s.store.CountUsers(ctx)
+	count, err := s.store.CountAdminUsers(ctx, db.CountAdminUsersParams{
+		Query:  query,
+		Status: status,
+	})
 	if err != nil {
 		return nil, 0, err
 	}
@@ -124,15 +224,54 @@ func (s *ServiceImpl) ListUsers(ctx context.Context, params ListUsersParams) ([]
 	return users, count, nil
 }
 
+// ListAuditLog returns a page of the admin audit log, newest first, and the total number of entries
+func (s *ServiceImpl) ListAuditLog(ctx context.Context, pageID, pageSize int32) ([]db.AdminAuditLog, int64, error) {
+	entries, err := s.store.ListAdminAuditLog(ctx, db.ListAdminAuditLogParams{
+		Limit:  pageSize,
+		Offset: (pageID - 1) * pageSize,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := s.store.CountAdminAuditLog(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, count, nil
+}
+
 func (s *ServiceImpl) BanUser(ctx context.Context, params BanUserParams) (db.User, error) {
 	userID, err := uuid.Parse(params.UserID)
 	if err != nil {
 		return db.User{}, err
 	}
 
+	if !params.Ban {
+		return s.store.BanUser(ctx, db.BanUserParams{ID: userID})
+	}
+
+	banType := params.Type
+	if banType == "" {
+		banType = BanTypeShadow
+	}
+	var expiresAt util.NullTime
+	if banType == BanTypeTemp {
+		if params.ExpiresAt == nil || !params.ExpiresAt.After(util.Now()) {
+			return db.User{}, ErrBanExpiryRequired
+		}
+		expiresAt = util.NullTime{Time: *params.ExpiresAt, Valid: true}
+	} else if params.ExpiresAt != nil {
+		return db.User{}, ErrBanExpiryNotAllowed
+	}
+
 	return s.store.BanUser(ctx, db.BanUserParams{
 		ID:             userID,
-		IsShadowBanned: params.Ban,
+		IsShadowBanned: banType == BanTypeShadow,
+		BanType:        sql.NullString{String: banType, Valid: true},
+		BanReason:      sql.NullString{String: params.Reason, Valid: params.Reason != ""},
+		BanExpiresAt:   expiresAt,
 	})
 }
 
@@ -144,12 +283,23 @@ func (s *ServiceImpl) DeleteUser(ctx context.Context, userID string) error {
 	return s.store.DeleteUser(ctx, id)
 }
 
-func (s *ServiceImpl) ListReports(ctx context.Context, resolved bool, pageID, pageSize int32) ([]db.ListReportsRow, error) {
-	return s.store.ListReports(ctx, db.ListReportsParams{
+// ListReports returns a page of resolved or open reports, newest first, and how many there are in all
+func (s *ServiceImpl) ListReports(ctx context.Context, resolved bool, pageID, pageSize int32) ([]db.ListReportsRow, int64, error) {
+	reports, err := s.store.ListReports(ctx, db.ListReportsParams{
 		IsResolved: resolved,
 		Limit:      pageSize,
 		Offset:     (pageID - 1) * pageSize,
 	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := s.store.CountReports(ctx, resolved)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return reports, count, nil
 }
 
 func (s *ServiceImpl) ResolveReport(ctx context.Context, reportID string) (db.Report, error) {
@@ -171,16 +321,72 @@ func (s *ServiceImpl) DeleteStory(ctx context.Context, storyID string) error {
 	}
 
 	// Invalidate feed cache
-	keys, err := s.redis.Keys(ctx, "feed:*").Result()
+	keys, err := s.redis.Keys(ctx, util.RedisKey("feed:*")).Result()
 	if err == nil && len(keys) > 0 {
 		s.redis.Del(ctx, keys...)
 	}
 	return nil
 }
 
-func (s *ServiceImpl) ListAllStories(ctx context.Context, pageID, pageSize int32) ([]db.ListAllStoriesRow, error) {
-	return s.store.ListAllStories(ctx, db.ListAllStoriesParams{
+// ListAllStories returns a page of all stories, newest first, and how many there are in all
+func (s *ServiceImpl) ListAllStories(ctx context.Context, pageID, pageSize int32) ([]db.ListAllStoriesRow, int64, error) {
+	stories, err := s.store.ListAllStories(ctx, db.ListAllStoriesParams{
 		Limit:  pageSize,
 		Offset: (pageID - 1) * pageSize,
 	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := s.store.CountStories(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return stories, count, nil
+}
+
+// SetMediaHold places or releases a moderation hold; held media is never garbage collected
+func (s *ServiceImpl) SetMediaHold(ctx context.Context, url string, hold bool) error {
+	rows, err := s.store.SetMediaObjectHold(ctx, db.SetMediaObjectHoldParams{
+		Url:    url,
+		OnHold: hold,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrMediaNotFound
+	}
+	return nil
+}
+
+// ListHeldStories is the moderation queue, oldest hold first
+func (s *ServiceImpl) ListHeldStories(ctx context.Context, pageID, pageSize int32) ([]db.ListHeldStoriesRow, error) {
+	return s.store.ListHeldStories(ctx, db.ListHeldStoriesParams{
+		Limit:  pageSize,
+		Offset: (pageID - 1) * pageSize,
+	})
+}
+
+// ReleaseStoryHold approves a held story so it appears in feeds
+func (s *ServiceImpl) ReleaseStoryHold(ctx context.Context, storyID string) error {
+	id, err := uuid.Parse(storyID)
+	if err != nil {
+		return err
+	}
+	rows, err := s.store.ReleaseStoryModerationHold(ctx, id)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrStoryNotHeld
+	}
+
+	// Invalidate feed cache
+	keys, err := s.redis.Keys(ctx, util.RedisKey("feed:*")).Result()
+	if err == nil && len(keys) > 0 {
+		s.redis.Del(ctx, keys...)
+	}
+	return nil
 }
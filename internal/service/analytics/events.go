@@ -0,0 +1,62 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/util"
+)
+
+// EventType is what happened. Values are stored, so never rename one.
+type EventType string
+
+const (
+	EventSignup             EventType = "signup" // GetSignupRetention matches this literal
+	EventStoryPosted        EventType = "story_posted"
+	EventCrossing           EventType = "crossing"
+	EventConnectionAccepted EventType = "connection_accepted"
+	EventMessageSent        EventType = "message_sent"
+)
+
+const (
+	// streamKey is the Redis stream events are appended to until the worker ingests them
+	streamKey = "analytics:events"
+	// streamMaxLen bounds the stream if ingestion stops; the oldest entries are dropped first
+	streamMaxLen = 200000
+)
+
+// Emitter appends product events to a Redis stream. Emitting is one XADD, so it
+// is cheap enough for request paths, and it never fails the request: analytics
+// may lose an event but a user action never fails because of analytics.
+type Emitter struct {
+	redis *redis.Client
+	clock util.Clock
+}
+
+func NewEmitter(rdb *redis.Client) *Emitter {
+	return &Emitter{redis: rdb, clock: util.SystemClock}
+}
+
+// Emit records that userID did eventType now. A nil Emitter drops the event.
+func (e *Emitter) Emit(ctx context.Context, eventType EventType, userID uuid.UUID) {
+	if e == nil || e.redis == nil {
+		return
+	}
+	err := e.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: util.RedisKey(streamKey),
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"type":    string(eventType),
+			"user_id": userID.String(),
+			"at":      e.clock.Now().UTC().Format(time.RFC3339Nano),
+		},
+	}).Err()
+	if err != nil {
+		log.Warn().Err(err).Str("event", string(eventType)).Msg("failed to emit analytics event")
+	}
+}
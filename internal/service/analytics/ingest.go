@@ -0,0 +1,126 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/repository"
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// consumerGroup lets several API instances share ingestion without double work
+	consumerGroup = "analytics-ingest"
+	// ingestBatchSize is how many stream entries are written per insert
+	ingestBatchSize = 500
+	// maxIngestBatches bounds one run; anything left waits for the next tick
+	maxIngestBatches = 20
+
+	// EventRetention is how long raw events are kept; daily rollups are kept forever
+	EventRetention = 90 * 24 * time.Hour
+)
+
+// Ingest moves emitted events from the Redis stream into analytics_events and
+// refreshes the daily rollups of the days they fall on. Entries are acknowledged
+// only after they are stored, so a failed run leaves them pending for this
+// consumer and the next run retries them first. It returns how many entries
+// were consumed.
+func Ingest(ctx context.Context, store repository.Store, rdb *redis.Client, consumer string) (int, error) {
+	key := util.RedisKey(streamKey)
+	err := rdb.XGroupCreateMkStream(ctx, key, consumerGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return 0, err
+	}
+
+	consumed := 0
+	var fromDay time.Time
+	// "0" re-reads entries this consumer read but never acknowledged, ">" reads new ones
+	for _, start := range []string{"0", ">"} {
+		for batch := 0; batch < maxIngestBatches; batch++ {
+			streams, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+				Group:    consumerGroup,
+				Consumer: consumer,
+				Streams:  []string{key, start},
+				Count:    ingestBatchSize,
+				Block:    -1,
+			}).Result()
+			if errors.Is(err, redis.Nil) {
+				break
+			}
+			if err != nil {
+				return consumed, err
+			}
+			if len(streams) == 0 || len(streams[0].Messages) == 0 {
+				break
+			}
+
+			messages := streams[0].Messages
+			arg, earliest := eventBatch(messages)
+			if len(arg.StreamIds) > 0 {
+				if _, err := store.CreateAnalyticsEvents(ctx, arg); err != nil {
+					return consumed, err
+				}
+				if fromDay.IsZero() || earliest.Before(fromDay) {
+					fromDay = earliest
+				}
+			}
+
+			ids := make([]string, len(messages))
+			for i, msg := range messages {
+				ids[i] = msg.ID
+			}
+			if err := rdb.XAck(ctx, key, consumerGroup, ids...).Err(); err != nil {
+				return consumed, err
+			}
+			consumed += len(messages)
+		}
+	}
+
+	if !fromDay.IsZero() {
+		day := fromDay.UTC().Truncate(24 * time.Hour)
+		if err := store.RollupAnalyticsDaily(ctx, day); err != nil {
+			return consumed, err
+		}
+	}
+	return consumed, nil
+}
+
+// eventBatch converts stream entries into insert params and reports the earliest
+// event time. Malformed entries are left out; they are still acknowledged.
+func eventBatch(messages []redis.XMessage) (db.CreateAnalyticsEventsParams, time.Time) {
+	var arg db.CreateAnalyticsEventsParams
+	var earliest time.Time
+	for _, msg := range messages {
+		eventType, _ := msg.Values["type"].(string)
+		rawUserID, _ := msg.Values["user_id"].(string)
+		rawAt, _ := msg.Values["at"].(string)
+
+		userID, err := uuid.Parse(rawUserID)
+		at, atErr := time.Parse(time.RFC3339Nano, rawAt)
+		if eventType == "" || err != nil || atErr != nil {
+			log.Warn().Str("stream_id", msg.ID).Msg("skipping malformed analytics event")
+			continue
+		}
+
+		arg.StreamIds = append(arg.StreamIds, msg.ID)
+		arg.EventTypes = append(arg.EventTypes, eventType)
+		arg.UserIds = append(arg.UserIds, userID)
+		arg.OccurredAts = append(arg.OccurredAts, at)
+		if earliest.IsZero() || at.Before(earliest) {
+			earliest = at
+		}
+	}
+	return arg, earliest
+}
+
+// Prune deletes raw events older than EventRetention and reports how many
+func Prune(ctx context.Context, store repository.Store, now time.Time) (int64, error) {
+	return store.DeleteAnalyticsEventsBefore(ctx, now.Add(-EventRetention))
+}
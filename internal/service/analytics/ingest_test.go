@@ -0,0 +1,120 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+var ingestNow = time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+
+type ingestHarness struct {
+	store   *mockdb.MockStore
+	redis   *redis.Client
+	emitter *Emitter
+}
+
+func newIngestHarness(t *testing.T) *ingestHarness {
+	ctrl := gomock.NewController(t)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	emitter := NewEmitter(rdb)
+	emitter.clock = util.FixedClock{T: ingestNow}
+	return &ingestHarness{store: mockdb.NewMockStore(ctrl), redis: rdb, emitter: emitter}
+}
+
+func (h *ingestHarness) pending(t *testing.T) int64 {
+	pending, err := h.redis.XPending(context.Background(), util.RedisKey(streamKey), consumerGroup).Result()
+	require.NoError(t, err)
+	return pending.Count
+}
+
+func TestIngestStoresEventsAndRollsUp(t *testing.T) {
+	h := newIngestHarness(t)
+	ctx := context.Background()
+	alice, bob := uuid.New(), uuid.New()
+
+	h.emitter.Emit(ctx, EventSignup, alice)
+	h.emitter.Emit(ctx, EventStoryPosted, alice)
+	h.emitter.Emit(ctx, EventMessageSent, bob)
+
+	var stored db.CreateAnalyticsEventsParams
+	h.store.EXPECT().CreateAnalyticsEvents(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, arg db.CreateAnalyticsEventsParams) (int64, error) {
+			stored = arg
+			return int64(len(arg.StreamIds)), nil
+		})
+	h.store.EXPECT().RollupAnalyticsDaily(gomock.Any(), time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)).Return(nil)
+
+	consumed, err := Ingest(ctx, h.store, h.redis, "test")
+	require.NoError(t, err)
+	require.Equal(t, 3, consumed)
+	require.Equal(t, []string{"signup", "story_posted", "message_sent"}, stored.EventTypes)
+	require.Equal(t, []uuid.UUID{alice, alice, bob}, stored.UserIds)
+	require.Equal(t, []time.Time{ingestNow, ingestNow, ingestNow}, stored.OccurredAts)
+	require.Len(t, stored.StreamIds, 3)
+	require.Zero(t, h.pending(t))
+
+	// Nothing new: no writes at all
+	consumed, err = Ingest(ctx, h.store, h.redis, "test")
+	require.NoError(t, err)
+	require.Zero(t, consumed)
+}
+
+func TestIngestRetriesAfterFailedInsert(t *testing.T) {
+	h := newIngestHarness(t)
+	ctx := context.Background()
+	h.emitter.Emit(ctx, EventCrossing, uuid.New())
+
+	h.store.EXPECT().CreateAnalyticsEvents(gomock.Any(), gomock.Any()).Return(int64(0), errors.New("db down"))
+	_, err := Ingest(ctx, h.store, h.redis, "test")
+	require.Error(t, err)
+	require.Equal(t, int64(1), h.pending(t))
+
+	// The unacknowledged entry is read again before anything new
+	var retried db.CreateAnalyticsEventsParams
+	h.store.EXPECT().CreateAnalyticsEvents(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, arg db.CreateAnalyticsEventsParams) (int64, error) {
+			retried = arg
+			return 1, nil
+		})
+	h.store.EXPECT().RollupAnalyticsDaily(gomock.Any(), gomock.Any()).Return(nil)
+
+	consumed, err := Ingest(ctx, h.store, h.redis, "test")
+	require.NoError(t, err)
+	require.Equal(t, 1, consumed)
+	require.Equal(t, []string{"crossing"}, retried.EventTypes)
+	require.Zero(t, h.pending(t))
+}
+
+func TestIngestAcknowledgesMalformedEntries(t *testing.T) {
+	h := newIngestHarness(t)
+	ctx := context.Background()
+	require.NoError(t, h.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: util.RedisKey(streamKey),
+		Values: map[string]interface{}{"type": "signup", "user_id": "not-a-uuid"},
+	}).Err())
+
+	consumed, err := Ingest(ctx, h.store, h.redis, "test")
+	require.NoError(t, err)
+	require.Equal(t, 1, consumed)
+	require.Zero(t, h.pending(t))
+}
+
+func TestEmitWithoutEmitterIsNoop(t *testing.T) {
+	var emitter *Emitter
+	emitter.Emit(context.Background(), EventSignup, uuid.New())
+}
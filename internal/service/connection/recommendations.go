@@ -0,0 +1,57 @@
+package connection
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/repository"
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// RecommendationCrossingWindow is how far back crossings count towards a recommendation
+	RecommendationCrossingWindow = 30 * 24 * time.Hour
+	// RecommendationActiveWindow selects which users get their recommendations precomputed
+	RecommendationActiveWindow = 7 * 24 * time.Hour
+	// MaxStoredRecommendations caps the candidates kept per user
+	MaxStoredRecommendations = 50
+)
+
+// RefreshRecommendations recomputes "people you may know" for one user, replacing
+// whatever was stored before
+func RefreshRecommendations(ctx context.Context, store repository.Store, userID uuid.UUID) error {
+	return store.ExecTx(ctx, func(q *db.Queries) error {
+		if err := q.DeleteConnectionRecommendations(ctx, userID); err != nil {
+			return err
+		}
+		return q.InsertConnectionRecommendations(ctx, db.InsertConnectionRecommendationsParams{
+			UserID:       userID,
+			CrossedSince: util.Now().Add(-RecommendationCrossingWindow),
+			ResultLimit:  MaxStoredRecommendations,
+		})
+	})
+}
+
+// RefreshActiveUsers recomputes recommendations for everyone active recently.
+// It keeps going past per-user failures and reports how many users were refreshed.
+func RefreshActiveUsers(ctx context.Context, store repository.Store) (int, error) {
+	userIDs, err := store.ListActiveUserIDs(ctx, util.Now().Add(-RecommendationActiveWindow))
+	if err != nil {
+		return 0, err
+	}
+
+	refreshed := 0
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return refreshed, ctx.Err()
+		}
+		if err := RefreshRecommendations(ctx, store, userID); err != nil {
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
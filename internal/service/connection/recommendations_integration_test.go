@@ -0,0 +1,147 @@
+//go:build integration
+
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"privacy-social-backend/internal/repository"
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/testutil"
+	"privacy-social-backend/internal/util"
+)
+
+// These tests run against a freshly migrated PostGIS database per test:
+//
+//	make test-env-up test-integration
+
+func newIntegrationStore(t *testing.T) repository.Store {
+	return repository.NewStore(testutil.NewTestDB(t))
+}
+
+func createTestUser(t *testing.T, store repository.Store) db.User {
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		Phone:        "+91" + util.RandomString(10),
+		PasswordHash: "x",
+		Username:     "rec_" + util.RandomString(10),
+		FullName:     "Recommendation Test",
+	})
+	require.NoError(t, err)
+	return user
+}
+
+func connect(t *testing.T, store repository.Store, a, b uuid.UUID) {
+	ctx := context.Background()
+	_, err := store.CreateConnectionRequest(ctx, db.CreateConnectionRequestParams{RequesterID: a, TargetID: b})
+	require.NoError(t, err)
+	_, err = store.UpdateConnectionStatus(ctx, db.UpdateConnectionStatusParams{RequesterID: a, TargetID: b, Status: db.ConnectionStatusAccepted})
+	require.NoError(t, err)
+}
+
+func cross(t *testing.T, store repository.Store, a, b uuid.UUID) {
+	_, err := store.CreateCrossing(context.Background(), db.CreateCrossingParams{
+		UserID1:        a,
+		UserID2:        b,
+		LocationCenter: "tdr1v",
+		OccurredAt:     util.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+}
+
+func block(t *testing.T, store repository.Store, blocker, blocked uuid.UUID) {
+	_, err := store.BlockUser(context.Background(), db.BlockUserParams{BlockerID: blocker, BlockedID: blocked})
+	require.NoError(t, err)
+}
+
+func setProfileVisibility(t *testing.T, store repository.Store, userID uuid.UUID, visibility string) {
+	_, err := store.UpdateUserProfile(context.Background(), db.UpdateUserProfileParams{
+		ID:                userID,
+		ProfileVisibility: sql.NullString{String: visibility, Valid: true},
+	})
+	require.NoError(t, err)
+}
+
+func recommendedIDs(t *testing.T, store repository.Store, userID uuid.UUID) []uuid.UUID {
+	rows, err := store.GetConnectionRecommendations(context.Background(), db.GetConnectionRecommendationsParams{
+		UserID:      userID,
+		ResultLimit: MaxStoredRecommendations,
+	})
+	require.NoError(t, err)
+	ids := make([]uuid.UUID, len(rows))
+	for i, r := range rows {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func TestRefreshRecommendationsExcludesHiddenCandidates(t *testing.T) {
+	ctx := context.Background()
+	store := newIntegrationStore(t)
+
+	viewer := createTestUser(t, store)
+	friend := createTestUser(t, store)
+	connect(t, store, viewer.ID, friend.ID)
+
+	mutual := createTestUser(t, store)
+	connect(t, store, friend.ID, mutual.ID)
+	crossed := createTestUser(t, store)
+	cross(t, store, viewer.ID, crossed.ID)
+
+	blockedByViewer := createTestUser(t, store)
+	cross(t, store, viewer.ID, blockedByViewer.ID)
+	block(t, store, viewer.ID, blockedByViewer.ID)
+
+	blockedViewer := createTestUser(t, store)
+	connect(t, store, friend.ID, blockedViewer.ID)
+	block(t, store, blockedViewer.ID, viewer.ID)
+
+	private := createTestUser(t, store)
+	cross(t, store, private.ID, viewer.ID)
+	setProfileVisibility(t, store, private.ID, "connections")
+
+	require.NoError(t, RefreshRecommendations(ctx, store, viewer.ID))
+
+	// Mutual connections outrank a single crossing; existing connections aren't suggested
+	require.Equal(t, []uuid.UUID{mutual.ID, crossed.ID}, recommendedIDs(t, store, viewer.ID))
+
+	// The hidden candidates were never stored, not just filtered on the way out
+	stored, err := store.CountConnectionRecommendations(ctx, viewer.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), stored)
+}
+
+func TestGetConnectionRecommendationsRechecksCandidates(t *testing.T) {
+	ctx := context.Background()
+	store := newIntegrationStore(t)
+
+	viewer := createTestUser(t, store)
+	blocked := createTestUser(t, store)
+	blocker := createTestUser(t, store)
+	private := createTestUser(t, store)
+	connected := createTestUser(t, store)
+	kept := createTestUser(t, store)
+	for _, candidate := range []db.User{blocked, blocker, private, connected, kept} {
+		cross(t, store, viewer.ID, candidate.ID)
+	}
+
+	require.NoError(t, RefreshRecommendations(ctx, store, viewer.ID))
+	require.Len(t, recommendedIDs(t, store, viewer.ID), 5)
+
+	// Everything that changes after the precompute still applies on read
+	block(t, store, viewer.ID, blocked.ID)
+	block(t, store, blocker.ID, viewer.ID)
+	setProfileVisibility(t, store, private.ID, "connections")
+	connect(t, store, connected.ID, viewer.ID)
+
+	require.Equal(t, []uuid.UUID{kept.ID}, recommendedIDs(t, store, viewer.ID))
+
+	stored, err := store.CountConnectionRecommendations(ctx, viewer.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), stored)
+}
@@ -0,0 +1,54 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestRefreshActiveUsers(t *testing.T) {
+	t.Run("SkipsFailures", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().ListActiveUserIDs(gomock.Any(), gomock.Any()).Times(1).
+			Return([]uuid.UUID{uuid.New(), uuid.New(), uuid.New()}, nil)
+		gomock.InOrder(
+			store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).Return(nil),
+			store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).Return(errors.New("deadlock")),
+			store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).Return(nil),
+		)
+
+		refreshed, err := RefreshActiveUsers(context.Background(), store)
+		require.NoError(t, err)
+		require.Equal(t, 2, refreshed)
+	})
+
+	t.Run("ListFails", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().ListActiveUserIDs(gomock.Any(), gomock.Any()).Times(1).Return(nil, errors.New("boom"))
+		store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+
+		_, err := RefreshActiveUsers(context.Background(), store)
+		require.Error(t, err)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().ListActiveUserIDs(gomock.Any(), gomock.Any()).Times(1).Return([]uuid.UUID{uuid.New()}, nil)
+		store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		refreshed, err := RefreshActiveUsers(ctx, store)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Zero(t, refreshed)
+	})
+}
@@ -0,0 +1,140 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"privacy-social-backend/internal/util"
+)
+
+// maxActiveNearbyScan caps how many nearby members one count looks at; callers
+// only ever show a coarse bucket, so anything past it reads as "lots"
+const maxActiveNearbyScan = 1000
+
+// maxNearbyUsers caps how many users one nearby search returns, nearest first
+const maxNearbyUsers = 200
+
+// ErrNoRecentLocation means a user hasn't pinged their location recently enough
+// to search around it
+var ErrNoRecentLocation = errors.New("no recent location; share your location first")
+
+// NearbyUser is a user found near another and how far apart they are
+type NearbyUser struct {
+	UserID         uuid.UUID
+	DistanceMeters float64
+}
+
+// CountActiveNearby counts users whose last ping was within radiusMeters of the
+// point and no earlier than since
+func (s *RedisLocationService) CountActiveNearby(ctx context.Context, lat, lng, radiusMeters float64, since time.Time) (int, error) {
+	matches, err := s.redis.GeoRadius(ctx, util.RedisKey(userLocationsKey), lng, lat, &redis.GeoRadiusQuery{
+		Radius: radiusMeters,
+		Unit:   "m",
+		Count:  maxActiveNearbyScan,
+		Sort:   "ASC",
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+	if len(matches) == 0 {
+		return 0, nil
+	}
+
+	members := make([]string, len(matches))
+	for i, match := range matches {
+		members[i] = match.Name
+	}
+	// Members that never pinged since this was added score 0 and never count
+	lastSeen, err := s.redis.ZMScore(ctx, util.RedisKey(userLastSeenKey), members...).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := float64(since.Unix())
+	count := 0
+	for _, seen := range lastSeen {
+		if seen >= cutoff {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// FindNearbyUsers returns the users whose last ping was within radiusMeters of
+// userID's own last position and no earlier than since, nearest first. userID
+// must have pinged since then too, or ErrNoRecentLocation is returned.
+func (s *RedisLocationService) FindNearbyUsers(ctx context.Context, userID uuid.UUID, radiusMeters float64, since time.Time) ([]NearbyUser, error) {
+	member := userID.String()
+	cutoff := float64(since.Unix())
+
+	// Ghost mode drops a user from the index, so they can't search either
+	seen, err := s.redis.ZScore(ctx, util.RedisKey(userLastSeenKey), member).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNoRecentLocation
+	}
+	if err != nil {
+		return nil, err
+	}
+	if seen < cutoff {
+		return nil, ErrNoRecentLocation
+	}
+
+	matches, err := s.redis.GeoSearchLocation(ctx, util.RedisKey(userLocationsKey), &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: redis.GeoSearchQuery{
+			Member:     member,
+			Radius:     radiusMeters,
+			RadiusUnit: "m",
+			Sort:       "ASC",
+			Count:      maxNearbyUsers + 1, // the caller is always among them
+		},
+		WithDist: true,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	others := make([]redis.GeoLocation, 0, len(matches))
+	for _, match := range matches {
+		if match.Name != member {
+			others = append(others, match)
+		}
+	}
+	if len(others) == 0 {
+		return nil, nil
+	}
+
+	members := make([]string, len(others))
+	for i, match := range others {
+		members[i] = match.Name
+	}
+	lastSeen, err := s.redis.ZMScore(ctx, util.RedisKey(userLastSeenKey), members...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var users []NearbyUser
+	for i, match := range others {
+		if lastSeen[i] < cutoff {
+			continue
+		}
+		id, err := uuid.Parse(match.Name)
+		if err != nil {
+			continue
+		}
+		users = append(users, NearbyUser{UserID: id, DistanceMeters: match.Dist})
+	}
+	return users, nil
+}
+
+// RemoveUserLocation drops a user from the live location index, e.g. when they go ghost
+func (s *RedisLocationService) RemoveUserLocation(ctx context.Context, userID uuid.UUID) error {
+	pipe := s.redis.TxPipeline()
+	pipe.ZRem(ctx, util.RedisKey(userLocationsKey), userID.String())
+	pipe.ZRem(ctx, util.RedisKey(userLastSeenKey), userID.String())
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,68 @@
+package location
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountActiveNearby(t *testing.T) {
+	// A tiny crossing radius keeps crossing detection out of the way
+	h := newCrossingHarness(t, CrossingConfig{RadiusMeters: 1})
+	ctx := context.Background()
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+
+	h.ping(t, alice, lat1, lng1)
+	h.advance(10 * time.Minute)
+	h.ping(t, bob, lat1+0.003, lng1) // ~330m north
+	h.ping(t, carol, lat1+0.1, lng1) // ~11km north
+
+	count := func(since time.Time) int {
+		n, err := h.service.CountActiveNearby(ctx, lat1, lng1, 1000, since)
+		require.NoError(t, err)
+		return n
+	}
+
+	now := h.service.clock.Now()
+	require.Equal(t, 2, count(now.Add(-15*time.Minute)))
+	// Alice's ping is too old for a 5 minute window
+	require.Equal(t, 1, count(now.Add(-5*time.Minute)))
+
+	// Going ghost drops a user right away
+	require.NoError(t, h.service.RemoveUserLocation(ctx, bob))
+	require.Equal(t, 1, count(now.Add(-15*time.Minute)))
+}
+
+func TestFindNearbyUsers(t *testing.T) {
+	h := newCrossingHarness(t, CrossingConfig{RadiusMeters: 1})
+	ctx := context.Background()
+	alice, bob, carol, dave := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	h.ping(t, dave, lat1+0.001, lng1) // ~110m north, but stale by the search
+	h.advance(20 * time.Minute)
+	h.ping(t, alice, lat1, lng1)
+	h.ping(t, bob, lat1+0.003, lng1) // ~330m north
+	h.ping(t, carol, lat1+0.1, lng1) // ~11km north
+
+	since := h.service.clock.Now().Add(-15 * time.Minute)
+	users, err := h.service.FindNearbyUsers(ctx, alice, 1000, since)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, bob, users[0].UserID)
+	require.InDelta(t, 333, users[0].DistanceMeters, 10)
+
+	// Dave's last ping is too old to search around
+	_, err = h.service.FindNearbyUsers(ctx, dave, 1000, since)
+	require.ErrorIs(t, err, ErrNoRecentLocation)
+
+	// Going ghost drops a user from results and stops them searching
+	require.NoError(t, h.service.RemoveUserLocation(ctx, bob))
+	users, err = h.service.FindNearbyUsers(ctx, alice, 1000, since)
+	require.NoError(t, err)
+	require.Empty(t, users)
+	_, err = h.service.FindNearbyUsers(ctx, bob, 1000, since)
+	require.ErrorIs(t, err, ErrNoRecentLocation)
+}
@@ -0,0 +1,130 @@
+package location
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// DefaultMinUsers is the k every aggregate location response is held to when
+// LOCATION_MIN_USERS is unset or too low to protect anyone
+const DefaultMinUsers = 5
+
+// MinUsers returns the configured k, falling back to DefaultMinUsers below 2:
+// with k = 1 a cell or count could stand for a single person.
+func MinUsers(configured int) int {
+	if configured < 2 {
+		return DefaultMinUsers
+	}
+	return configured
+}
+
+// Cell is one geohash cell of an aggregate location response: the distinct
+// users it stands for and the items (stories, pings) it reports.
+type Cell[T any] struct {
+	Geohash string
+	Members []uuid.UUID
+	Items   []T
+}
+
+// AnonymizeCells makes every cell stand for at least k distinct users. Cells
+// already at k are kept as they are. The rest are merged into their parent cell,
+// one geohash character shorter, together with their small siblings, until they
+// reach k; whatever is still short of k at minPrecision is dropped. Cells come
+// back sorted by geohash.
+func AnonymizeCells[T any](cells []Cell[T], k, minPrecision int) []Cell[T] {
+	var kept []Cell[T]
+	pending := cells
+	for len(pending) > 0 {
+		parents := make(map[string]*Cell[T])
+		for _, cell := range pending {
+			cell.Members = distinctMembers(cell.Members)
+			if len(cell.Members) >= k {
+				kept = append(kept, cell)
+				continue
+			}
+			if len(cell.Geohash) <= minPrecision {
+				continue
+			}
+
+			hash := cell.Geohash[:len(cell.Geohash)-1]
+			parent, ok := parents[hash]
+			if !ok {
+				parent = &Cell[T]{Geohash: hash}
+				parents[hash] = parent
+			}
+			parent.Members = append(parent.Members, cell.Members...)
+			parent.Items = append(parent.Items, cell.Items...)
+		}
+
+		pending = pending[:0:0]
+		for _, parent := range parents {
+			pending = append(pending, *parent)
+		}
+	}
+
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Geohash < kept[j].Geohash })
+	return kept
+}
+
+// CoarsenCells merges the finest cells into their parents, one geohash
+// character at a time, until there are at most maxCells of them or the finest
+// left are at minPrecision. Merging only ever adds users to a cell, so cells
+// that passed AnonymizeCells still do. Cells come back sorted by geohash.
+func CoarsenCells[T any](cells []Cell[T], maxCells, minPrecision int) []Cell[T] {
+	for len(cells) > maxCells {
+		finest := 0
+		for _, cell := range cells {
+			finest = max(finest, len(cell.Geohash))
+		}
+		if finest <= minPrecision {
+			break
+		}
+
+		merged := make(map[string]*Cell[T], len(cells))
+		for _, cell := range cells {
+			hash := cell.Geohash
+			if len(hash) == finest {
+				hash = hash[:finest-1]
+			}
+			parent, ok := merged[hash]
+			if !ok {
+				parent = &Cell[T]{Geohash: hash}
+				merged[hash] = parent
+			}
+			parent.Members = append(parent.Members, cell.Members...)
+			parent.Items = append(parent.Items, cell.Items...)
+		}
+
+		cells = cells[:0:0]
+		for _, cell := range merged {
+			cell.Members = distinctMembers(cell.Members)
+			cells = append(cells, *cell)
+		}
+	}
+
+	sort.Slice(cells, func(i, j int) bool { return cells[i].Geohash < cells[j].Geohash })
+	return cells
+}
+
+// AnonymizeCount returns count when it covers at least k users and 0 otherwise,
+// for responses that are a single number rather than cells
+func AnonymizeCount(count, k int) int {
+	if count < k {
+		return 0
+	}
+	return count
+}
+
+func distinctMembers(members []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]struct{}, len(members))
+	distinct := members[:0:0]
+	for _, member := range members {
+		if _, ok := seen[member]; ok {
+			continue
+		}
+		seen[member] = struct{}{}
+		distinct = append(distinct, member)
+	}
+	return distinct
+}
@@ -0,0 +1,82 @@
+package location
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func users(n int) []uuid.UUID {
+	ids := make([]uuid.UUID, n)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	return ids
+}
+
+func TestAnonymizeCellsMergesSmallCellsUpward(t *testing.T) {
+	busy := users(3)
+	alice, bob := uuid.New(), uuid.New()
+
+	cells := []Cell[string]{
+		{Geohash: "tdr1w", Members: busy, Items: []string{"a", "b", "c"}},
+		// Alone in their cells; together with a sibling they still fall short
+		{Geohash: "tdr1x", Members: []uuid.UUID{alice, alice}, Items: []string{"d", "e"}},
+		{Geohash: "tdr1y", Members: []uuid.UUID{bob}, Items: []string{"f"}},
+		// Two levels up they meet the rest of tdr1's small cells
+		{Geohash: "tdr2a", Members: users(1), Items: []string{"g"}},
+		// Nothing to merge with before the minimum precision
+		{Geohash: "tdq9z", Members: users(1), Items: []string{"h"}},
+	}
+
+	anonymized := AnonymizeCells(cells, 3, 3)
+	require.Len(t, anonymized, 2)
+
+	require.Equal(t, "tdr", anonymized[0].Geohash)
+	require.Len(t, anonymized[0].Members, 3)
+	require.ElementsMatch(t, []string{"d", "e", "f", "g"}, anonymized[0].Items)
+
+	require.Equal(t, "tdr1w", anonymized[1].Geohash)
+	require.Equal(t, busy, anonymized[1].Members)
+}
+
+func TestAnonymizeCellsCountsDistinctUsers(t *testing.T) {
+	alice := uuid.New()
+	cells := []Cell[int]{{Geohash: "tdr1w", Members: []uuid.UUID{alice, alice, alice}}}
+
+	require.Empty(t, AnonymizeCells(cells, 2, 5))
+}
+
+func TestCoarsenCells(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	cells := []Cell[int]{
+		{Geohash: "tdr1w", Members: []uuid.UUID{alice}},
+		{Geohash: "tdr1x", Members: []uuid.UUID{alice, bob}},
+		// A coarser cell the finer ones fold into
+		{Geohash: "tdr1", Members: users(2)},
+		{Geohash: "tdr2", Members: users(1)},
+	}
+
+	coarsened := CoarsenCells(cells, 2, 3)
+	require.Len(t, coarsened, 2)
+	require.Equal(t, "tdr1", coarsened[0].Geohash)
+	require.Len(t, coarsened[0].Members, 4)
+	require.Equal(t, "tdr2", coarsened[1].Geohash)
+
+	// Nothing is merged past the minimum precision, even over the limit
+	require.Len(t, CoarsenCells(cells, 1, 4), 2)
+	// Under the limit the cells are left alone
+	require.Len(t, CoarsenCells(cells, 10, 3), 4)
+}
+
+func TestAnonymizeCount(t *testing.T) {
+	require.Equal(t, 0, AnonymizeCount(4, 5))
+	require.Equal(t, 5, AnonymizeCount(5, 5))
+}
+
+func TestMinUsers(t *testing.T) {
+	require.Equal(t, DefaultMinUsers, MinUsers(0))
+	require.Equal(t, DefaultMinUsers, MinUsers(1))
+	require.Equal(t, 2, MinUsers(2))
+}
@@ -3,6 +3,8 @@ package location
 import (
 	"context"
 	"fmt"
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +14,7 @@ import (
 
 	"privacy-social-backend/internal/repository"
 	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/util"
 )
 
 const (
@@ -20,34 +23,111 @@ const (
 	// Member: UserID
 	userLocationsKey = "users:locations"
 
-	// Key prefix for daily crossing duplications
+	// Key for when each user last pinged
+	// Type: Sorted Set
+	// Member: UserID, Score: unix seconds
+	userLastSeenKey = "users:last_seen"
+
+	// Key prefix for crossing cooldowns
 	// Type: String (with TTL)
 	// Key: crossing:<uid1>:<uid2>
 	crossingKeyPrefix = "crossing:"
 
-	// Crossing TTL (Don't trigger same crossing for 24h)
-	crossingTTL = 24 * time.Hour
+	// Key prefix for encounters in progress
+	// Type: Hash (first_seen, handled) with TTL refreshed on every sighting
+	// Key: crossing:encounter:<uid1>:<uid2>
+	encounterKeyPrefix = "crossing:encounter:"
+
+	// An encounter ends once the pair hasn't been seen together for this long
+	encounterGap = 10 * time.Minute
+
+	// CrossingDetectedType is the notification and WebSocket event type for a new crossing
+	CrossingDetectedType = "crossing_detected"
 
-	// Radius for "crossing paths" (approx 76m to match Geohash precision)
-	crossingRadiusMeters = 80.0
+	// crossingDistanceStep is what the distance sent with a crossing is rounded
+	// to, so it doesn't pinpoint the other user
+	crossingDistanceStep = 10
 )
 
+// CrossingConfig tunes real-time crossing detection
+type CrossingConfig struct {
+	// RadiusMeters is how close two users must be to count as crossing paths
+	RadiusMeters float64
+	// MinDwell is how long they must stay within the radius before it counts
+	MinDwell time.Duration
+	// Cooldown is the minimum time between two crossings of the same pair
+	Cooldown time.Duration
+}
+
+const (
+	// Approx 76m to match Geohash precision
+	DefaultCrossingRadiusMeters = 80.0
+	// Don't trigger the same crossing for 24h
+	DefaultCrossingCooldown = 24 * time.Hour
+)
+
+func (c CrossingConfig) withDefaults() CrossingConfig {
+	if c.RadiusMeters <= 0 {
+		c.RadiusMeters = DefaultCrossingRadiusMeters
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = DefaultCrossingCooldown
+	}
+	if c.MinDwell < 0 {
+		c.MinDwell = 0
+	}
+	return c
+}
+
+// CrossingEvent is a detected crossing as one of its users is told about it
+type CrossingEvent struct {
+	Crossing db.Crossing
+	// CrossedWith is the other user of the pair
+	CrossedWith         uuid.UUID
+	CrossedWithUsername string
+	// DistanceMeters is roughly how far apart they were, in crossingDistanceStep steps
+	DistanceMeters int
+}
+
+// CrossingNotifier pushes a detected crossing to a user in real time
+type CrossingNotifier func(recipient uuid.UUID, event CrossingEvent)
+
+// approximateDistance rounds meters to the nearest crossingDistanceStep, never
+// below one step
+func approximateDistance(meters float64) int {
+	steps := int(math.Round(meters / crossingDistanceStep))
+	if steps < 1 {
+		steps = 1
+	}
+	return steps * crossingDistanceStep
+}
+
 type RedisLocationService struct {
-	redis *redis.Client
-	store repository.Store
+	redis    *redis.Client
+	store    repository.Store
+	config   CrossingConfig
+	clock    util.Clock
+	notifier CrossingNotifier
 }
 
-func NewRedisLocationService(redis *redis.Client, store repository.Store) *RedisLocationService {
+func NewRedisLocationService(redis *redis.Client, store repository.Store, config CrossingConfig) *RedisLocationService {
 	return &RedisLocationService{
-		redis: redis,
-		store: store,
+		redis:  redis,
+		store:  store,
+		config: config.withDefaults(),
+		clock:  util.SystemClock,
 	}
 }
 
+// SetCrossingNotifier registers where crossings are pushed besides the persisted notification
+func (s *RedisLocationService) SetCrossingNotifier(notifier CrossingNotifier) {
+	s.notifier = notifier
+}
+
 // UpdateUserLocation updates user position in Redis and triggers real-time crossing detection
 func (s *RedisLocationService) UpdateUserLocation(ctx context.Context, userID uuid.UUID, lat, lng float64) error {
 	// 1. Update Geo Index
-	err := s.redis.GeoAdd(ctx, userLocationsKey, &redis.GeoLocation{
+	err := s.redis.GeoAdd(ctx, util.RedisKey(userLocationsKey), &redis.GeoLocation{
 		Name:      userID.String(),
 		Longitude: lng,
 		Latitude:  lat,
@@ -55,11 +135,18 @@ func (s *RedisLocationService) UpdateUserLocation(ctx context.Context, userID uu
 	if err != nil {
 		return fmt.Errorf("failed to update geo location: %w", err)
 	}
+	err = s.redis.ZAdd(ctx, util.RedisKey(userLastSeenKey), redis.Z{
+		Score:  float64(s.clock.Now().Unix()),
+		Member: userID.String(),
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to update last seen: %w", err)
+	}
 
 	// 2. Find nearby users (Real-time Crossing Detection)
 	// look for users within specific radius
-	matches, err := s.redis.GeoRadius(ctx, userLocationsKey, lng, lat, &redis.GeoRadiusQuery{
-		Radius:    crossingRadiusMeters,
+	matches, err := s.redis.GeoRadius(ctx, util.RedisKey(userLocationsKey), lng, lat, &redis.GeoRadiusQuery{
+		Radius:    s.config.RadiusMeters,
 		Unit:      "m",
 		WithDist:  true,
 		WithCoord: true,
@@ -95,27 +182,34 @@ func (s *RedisLocationService) processCrossings(ctx context.Context, userID uuid
 		if u1.String() > u2.String() {
 			u1, u2 = u2, u1
 		}
+		pair := u1.String() + ":" + u2.String()
 
-		// 4. Check De-duplication Cache (Redis)
-		dedupKey := fmt.Sprintf("%s%s:%s", crossingKeyPrefix, u1.String(), u2.String())
-		exists, err := s.redis.Exists(ctx, dedupKey).Result()
-		if err == nil && exists > 0 {
-			// Already crossed recently, skip
+		// 4. Track the encounter; a pair lingering together only ever yields one crossing
+		encounterKey := util.RedisKey(encounterKeyPrefix + pair)
+		ready, err := s.observeEncounter(ctx, encounterKey)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to track encounter")
+			continue
+		}
+		if !ready {
 			continue
 		}
 
-		// 5. Verify Database Constraints (Block, Ghost Mode, etc) - Optional optimization:
-		// We could do this BEFORE DB insert, but `FindPotentialCrossings` query was complex.
-		// For now, let's assume if they are both "active" (pinging), they are candidates.
-		// We'll let the user decide if we need deep validation queries here or if we just insert.
-		// Detailed validation is safer.
-
-		// Check privacy/ghost mode for BOTH users
-		// We can reuse the extensive WHERE clause from the original SQL if we want,
-		// but checking individual user status is faster.
+		// 5. Check the cooldown between separate encounters
+		cooldownKey := util.RedisKey(crossingKeyPrefix + pair)
+		exists, err := s.redis.Exists(ctx, cooldownKey).Result()
+		if err == nil && exists > 0 {
+			s.markEncounterHandled(ctx, encounterKey)
+			continue
+		}
 
-		valid, err := s.validateCrossingPrivacy(ctx, userID, targetUserID)
-		if err != nil || !valid {
+		// Check blocks and privacy/ghost mode for BOTH users
+		user, target, valid, err := s.crossingUsers(ctx, userID, targetUserID)
+		if err != nil {
+			continue
+		}
+		s.markEncounterHandled(ctx, encounterKey)
+		if !valid {
 			continue
 		}
 
@@ -128,54 +222,85 @@ func (s *RedisLocationService) processCrossings(ctx context.Context, userID uuid
 			UserID1:        u1,
 			UserID2:        u2,
 			LocationCenter: centerHash,
-			OccurredAt:     time.Now().UTC(),
+			OccurredAt:     s.clock.Now(),
 		})
 		if err != nil {
 			log.Error().Err(err).Msg("failed to persist crossing")
 			continue
 		}
 
-		// 7. Send Notification
-		// Only notify the OTHER person "You crossed with current_user"
-		// Actually, usually both get notified or it is symmetric.
-		// Original logic notified User2 about User1.
-
-		// Notify User 1
-		s.createNotification(ctx, userID, targetUserID, crossing.ID)
-		// Notify User 2
-		s.createNotification(ctx, targetUserID, userID, crossing.ID)
+		// 7. Notify both users
+		distance := approximateDistance(match.Dist)
+		s.createNotification(ctx, userID, CrossingEvent{
+			Crossing:            crossing,
+			CrossedWith:         targetUserID,
+			CrossedWithUsername: target.Username,
+			DistanceMeters:      distance,
+		})
+		s.createNotification(ctx, targetUserID, CrossingEvent{
+			Crossing:            crossing,
+			CrossedWith:         userID,
+			CrossedWithUsername: user.Username,
+			DistanceMeters:      distance,
+		})
 
 		// 8. Invalidate crossings cache for both users
 		s.invalidateCrossingsCache(ctx, userID)
 		s.invalidateCrossingsCache(ctx, targetUserID)
 
-		// 9. Set Dedup Key
-		s.redis.Set(ctx, dedupKey, "1", crossingTTL)
+		// 9. Start the cooldown
+		s.redis.Set(ctx, cooldownKey, "1", s.config.Cooldown)
+	}
+}
+
+// observeEncounter records a sighting of a pair within the radius and reports
+// whether they have now been together for MinDwell without a crossing yet
+func (s *RedisLocationService) observeEncounter(ctx context.Context, key string) (bool, error) {
+	now := s.clock.Now()
+
+	pipe := s.redis.TxPipeline()
+	pipe.HSetNX(ctx, key, "first_seen", now.UnixNano())
+	fields := pipe.HGetAll(ctx, key)
+	pipe.Expire(ctx, key, encounterGap)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+
+	encounter := fields.Val()
+	if encounter["handled"] == "1" {
+		return false, nil
+	}
+
+	firstSeen, err := strconv.ParseInt(encounter["first_seen"], 10, 64)
+	if err != nil {
+		return false, err
 	}
+	return now.Sub(time.Unix(0, firstSeen)) >= s.config.MinDwell, nil
 }
 
-func (s *RedisLocationService) validateCrossingPrivacy(ctx context.Context, u1, u2 uuid.UUID) (bool, error) {
+// markEncounterHandled stops the rest of an encounter from being considered again
+func (s *RedisLocationService) markEncounterHandled(ctx context.Context, key string) {
+	s.redis.HSet(ctx, key, "handled", "1")
+}
+
+// crossingUsers loads both users of a possible crossing and reports whether
+// they may cross: neither blocks the other and neither is hidden
+func (s *RedisLocationService) crossingUsers(ctx context.Context, u1, u2 uuid.UUID) (db.User, db.User, bool, error) {
 	// Check blocks
 	blocked, err := s.store.IsUserBlocked(ctx, db.IsUserBlockedParams{
 		BlockerID: u1,
 		BlockedID: u2,
 	})
-	if err != nil {
-		return false, err
-	}
-	if blocked {
-		return false, nil
+	if err != nil || blocked {
+		return db.User{}, db.User{}, false, err
 	}
 
 	blockedReverse, err := s.store.IsUserBlocked(ctx, db.IsUserBlockedParams{
 		BlockerID: u2,
 		BlockedID: u1,
 	})
-	if err != nil {
-		return false, err
-	}
-	if blockedReverse {
-		return false, nil
+	if err != nil || blockedReverse {
+		return db.User{}, db.User{}, false, err
 	}
 
 	// Check Ghost Mode (using User model)
@@ -183,40 +308,43 @@ func (s *RedisLocationService) validateCrossingPrivacy(ctx context.Context, u1,
 	// For now, fetching user is safest.
 	user1, err := s.store.GetUserByID(ctx, u1)
 	if err != nil {
-		return false, err
+		return db.User{}, db.User{}, false, err
 	}
 	if user1.IsGhostMode || user1.IsShadowBanned {
-		return false, nil
+		return user1, db.User{}, false, nil
 	}
 
 	user2, err := s.store.GetUserByID(ctx, u2)
 	if err != nil {
-		return false, err
+		return db.User{}, db.User{}, false, err
 	}
 	if user2.IsGhostMode || user2.IsShadowBanned {
-		return false, nil
+		return user1, user2, false, nil
 	}
 
-	return true, nil
+	return user1, user2, true, nil
 }
 
-func (s *RedisLocationService) createNotification(ctx context.Context, recipient, crossedWith uuid.UUID, crossingID uuid.UUID) {
+func (s *RedisLocationService) createNotification(ctx context.Context, recipient uuid.UUID, event CrossingEvent) {
 	_, err := s.store.CreateNotification(ctx, db.CreateNotificationParams{
 		UserID:            recipient,
-		Type:              "crossing_detected",
+		Type:              CrossingDetectedType,
 		Title:             "Path Crossed!",
 		Message:           "You crossed paths with someone nearby",
-		RelatedUserID:     uuid.NullUUID{UUID: crossedWith, Valid: true},
-		RelatedCrossingID: uuid.NullUUID{UUID: crossingID, Valid: true},
+		RelatedUserID:     uuid.NullUUID{UUID: event.CrossedWith, Valid: true},
+		RelatedCrossingID: uuid.NullUUID{UUID: event.Crossing.ID, Valid: true},
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("failed to create notification for crossing")
 	}
-	// Note: We could trigger WebSocket here if we had access to hub
+
+	if s.notifier != nil {
+		s.notifier(recipient, event)
+	}
 }
 
 // invalidateCrossingsCache removes the cached crossings for a user
 func (s *RedisLocationService) invalidateCrossingsCache(ctx context.Context, userID uuid.UUID) {
-	cacheKey := "crossings:v3:" + userID.String()
+	cacheKey := util.RedisKey("crossings:v3:" + userID.String())
 	s.redis.Del(ctx, cacheKey)
 }
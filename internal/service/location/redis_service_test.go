@@ -0,0 +1,205 @@
+package location
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/util"
+)
+
+var crossingNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// About 20m apart
+const (
+	lat1, lng1 = 12.9716, 77.5946
+	lat2, lng2 = 12.9717, 77.5947
+)
+
+type crossingHarness struct {
+	service  *RedisLocationService
+	store    *mockdb.MockStore
+	redis    *miniredis.Miniredis
+	notified []uuid.UUID
+	events   map[uuid.UUID]CrossingEvent
+}
+
+func newCrossingHarness(t *testing.T, config CrossingConfig) *crossingHarness {
+	ctrl := gomock.NewController(t)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	h := &crossingHarness{
+		store:  mockdb.NewMockStore(ctrl),
+		redis:  mr,
+		events: make(map[uuid.UUID]CrossingEvent),
+	}
+	h.service = NewRedisLocationService(rdb, h.store, config)
+	h.service.SetCrossingNotifier(func(recipient uuid.UUID, event CrossingEvent) {
+		h.notified = append(h.notified, recipient)
+		h.events[recipient] = event
+	})
+	h.setNow(crossingNow)
+	return h
+}
+
+func (h *crossingHarness) setNow(now time.Time) {
+	h.service.clock = util.FixedClock{T: now}
+}
+
+// advance moves both the service clock and Redis TTLs forward
+func (h *crossingHarness) advance(d time.Duration) {
+	h.setNow(h.service.clock.Now().Add(d))
+	h.redis.FastForward(d)
+}
+
+func (h *crossingHarness) ping(t *testing.T, userID uuid.UUID, lat, lng float64) {
+	require.NoError(t, h.service.UpdateUserLocation(context.Background(), userID, lat, lng))
+}
+
+// expectEligible stubs the block and ghost-mode checks for a pair that may cross
+func (h *crossingHarness) expectEligible(user1, user2 uuid.UUID) {
+	h.store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).Times(2).Return(false, nil)
+	h.store.EXPECT().GetUserByID(gomock.Any(), user1).Return(db.User{ID: user1, Username: user1.String()[:8]}, nil)
+	h.store.EXPECT().GetUserByID(gomock.Any(), user2).Return(db.User{ID: user2, Username: user2.String()[:8]}, nil)
+}
+
+func (h *crossingHarness) expectCrossing() {
+	h.store.EXPECT().CreateCrossing(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, arg db.CreateCrossingParams) (db.Crossing, error) {
+			return db.Crossing{ID: uuid.New(), UserID1: arg.UserID1, UserID2: arg.UserID2, OccurredAt: arg.OccurredAt}, nil
+		})
+	h.store.EXPECT().CreateNotification(gomock.Any(), gomock.Any()).Times(2).Return(db.Notification{}, nil)
+}
+
+func TestCrossingDetectedOncePerEncounter(t *testing.T) {
+	h := newCrossingHarness(t, CrossingConfig{})
+	alice, bob := uuid.New(), uuid.New()
+
+	h.ping(t, alice, lat1, lng1)
+
+	h.expectEligible(bob, alice)
+	h.expectCrossing()
+	h.ping(t, bob, lat2, lng2)
+	require.ElementsMatch(t, []uuid.UUID{alice, bob}, h.notified)
+
+	// Lingering together doesn't produce more crossings
+	for i := 0; i < 3; i++ {
+		h.advance(time.Minute)
+		h.ping(t, alice, lat1, lng1)
+		h.ping(t, bob, lat2, lng2)
+	}
+	require.Len(t, h.notified, 2)
+}
+
+func TestCrossingEventDescribesTheOtherUser(t *testing.T) {
+	h := newCrossingHarness(t, CrossingConfig{})
+	alice, bob := uuid.New(), uuid.New()
+
+	h.ping(t, alice, lat1, lng1)
+	h.expectEligible(bob, alice)
+	h.expectCrossing()
+	h.ping(t, bob, lat2, lng2)
+
+	toAlice, toBob := h.events[alice], h.events[bob]
+	require.Equal(t, bob, toAlice.CrossedWith)
+	require.Equal(t, bob.String()[:8], toAlice.CrossedWithUsername)
+	require.Equal(t, alice, toBob.CrossedWith)
+	require.Equal(t, alice.String()[:8], toBob.CrossedWithUsername)
+	require.Equal(t, toAlice.Crossing.ID, toBob.Crossing.ID)
+
+	// ~15m apart, reported in whole steps
+	require.Equal(t, 20, toAlice.DistanceMeters)
+	require.Equal(t, toAlice.DistanceMeters, toBob.DistanceMeters)
+}
+
+func TestApproximateDistance(t *testing.T) {
+	require.Equal(t, 10, approximateDistance(0))
+	require.Equal(t, 10, approximateDistance(14.9))
+	require.Equal(t, 20, approximateDistance(15))
+	require.Equal(t, 80, approximateDistance(78))
+}
+
+func TestCrossingRequiresMinDwell(t *testing.T) {
+	h := newCrossingHarness(t, CrossingConfig{MinDwell: 2 * time.Minute})
+	alice, bob := uuid.New(), uuid.New()
+
+	h.ping(t, alice, lat1, lng1)
+	h.ping(t, bob, lat2, lng2)
+
+	h.advance(time.Minute)
+	h.ping(t, bob, lat2, lng2)
+	require.Empty(t, h.notified)
+
+	h.advance(time.Minute)
+	h.expectEligible(bob, alice)
+	h.expectCrossing()
+	h.ping(t, bob, lat2, lng2)
+	require.Len(t, h.notified, 2)
+}
+
+func TestCrossingOutsideRadius(t *testing.T) {
+	h := newCrossingHarness(t, CrossingConfig{RadiusMeters: 10})
+	alice, bob := uuid.New(), uuid.New()
+
+	h.ping(t, alice, lat1, lng1)
+	h.ping(t, bob, lat2, lng2)
+	require.Empty(t, h.notified)
+}
+
+func TestCrossingHonorsBlocks(t *testing.T) {
+	h := newCrossingHarness(t, CrossingConfig{})
+	alice, bob := uuid.New(), uuid.New()
+
+	h.ping(t, alice, lat1, lng1)
+
+	// Checked once per encounter, never persisted or notified
+	h.store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).Times(1).Return(true, nil)
+	h.ping(t, bob, lat2, lng2)
+	h.ping(t, bob, lat2, lng2)
+	require.Empty(t, h.notified)
+}
+
+func TestCrossingHonorsGhostMode(t *testing.T) {
+	h := newCrossingHarness(t, CrossingConfig{})
+	alice, bob := uuid.New(), uuid.New()
+
+	h.ping(t, alice, lat1, lng1)
+
+	h.store.EXPECT().IsUserBlocked(gomock.Any(), gomock.Any()).Times(2).Return(false, nil)
+	h.store.EXPECT().GetUserByID(gomock.Any(), bob).Return(db.User{ID: bob}, nil)
+	h.store.EXPECT().GetUserByID(gomock.Any(), alice).Return(db.User{ID: alice, IsGhostMode: true}, nil)
+	h.ping(t, bob, lat2, lng2)
+	require.Empty(t, h.notified)
+}
+
+func TestCrossingCooldownBetweenEncounters(t *testing.T) {
+	h := newCrossingHarness(t, CrossingConfig{Cooldown: time.Hour})
+	alice, bob := uuid.New(), uuid.New()
+
+	h.ping(t, alice, lat1, lng1)
+	h.expectEligible(bob, alice)
+	h.expectCrossing()
+	h.ping(t, bob, lat2, lng2)
+
+	// They part and meet again while the cooldown is running
+	h.advance(encounterGap + time.Minute)
+	h.ping(t, bob, lat2, lng2)
+	require.Len(t, h.notified, 2)
+
+	// A new encounter after the cooldown counts again
+	h.advance(time.Hour)
+	h.expectEligible(bob, alice)
+	h.expectCrossing()
+	h.ping(t, bob, lat2, lng2)
+	require.Len(t, h.notified, 4)
+}
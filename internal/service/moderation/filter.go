@@ -0,0 +1,176 @@
+package moderation
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Mode is what happens to a story caption with a blocked word in it
+type Mode string
+
+const (
+	// ModeReject refuses the caption
+	ModeReject Mode = "reject"
+	// ModeMask stars out the blocked words and keeps the rest
+	ModeMask Mode = "mask"
+)
+
+var ErrBlockedContent = errors.New("content contains blocked words")
+
+// Config says where the blocked word list comes from
+type Config struct {
+	// Words is a comma-separated word list
+	Words string
+	// WordsFile is a file with one word per line; blank lines and # comments are skipped
+	WordsFile string
+	Mode      Mode
+}
+
+// leet maps the usual character substitutions back to the letter they stand for
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+}
+
+// Filter finds blocked words in user text. Words match whole, case-insensitively
+// and with leetspeak undone, so "Sh1t" and "$hit" both match "shit".
+type Filter struct {
+	config Config
+
+	mu    sync.RWMutex
+	words map[string]struct{}
+}
+
+// NewFilter loads the word list described by config
+func NewFilter(config Config) (*Filter, error) {
+	if config.Mode != ModeMask {
+		config.Mode = ModeReject
+	}
+	f := &Filter{config: config}
+	if _, err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Mode is what should happen to captions with blocked words
+func (f *Filter) Mode() Mode {
+	return f.config.Mode
+}
+
+// Reload reads the word list again and returns how many words it has. On error
+// the previous list stays in use.
+func (f *Filter) Reload() (int, error) {
+	words := make(map[string]struct{})
+	for _, word := range strings.Split(f.config.Words, ",") {
+		addWord(words, word)
+	}
+
+	if f.config.WordsFile != "" {
+		file, err := os.Open(f.config.WordsFile)
+		if err != nil {
+			return 0, fmt.Errorf("cannot open moderation word list: %w", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+			addWord(words, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return 0, fmt.Errorf("cannot read moderation word list: %w", err)
+		}
+	}
+
+	f.mu.Lock()
+	f.words = words
+	f.mu.Unlock()
+	return len(words), nil
+}
+
+// FilterText reports whether content has a blocked word and returns it with
+// every blocked word replaced by asterisks
+func (f *Filter) FilterText(content string) (clean string, blocked bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if len(f.words) == 0 {
+		return content, false
+	}
+
+	runes := []rune(content)
+	for start := 0; start < len(runes); {
+		if !isWordRune(runes[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+
+		// A leading @ or # is a mention or hashtag and a trailing ! is punctuation
+		from, to := start, end
+		for from < to-1 && (runes[from] == '@' || runes[from] == '#') {
+			from++
+		}
+		for to > from+1 && runes[to-1] == '!' {
+			to--
+		}
+
+		if _, ok := f.words[normalize(runes[from:to])]; ok {
+			blocked = true
+			for i := from; i < to; i++ {
+				runes[i] = '*'
+			}
+		}
+		start = end
+	}
+	if !blocked {
+		return content, false
+	}
+	return string(runes), true
+}
+
+func addWord(words map[string]struct{}, word string) {
+	word = strings.TrimSpace(word)
+	if word != "" {
+		words[normalize([]rune(word))] = struct{}{}
+	}
+}
+
+func isWordRune(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '#' {
+		return true
+	}
+	_, ok := leet[r]
+	return ok
+}
+
+func normalize(word []rune) string {
+	var b strings.Builder
+	for _, r := range word {
+		r = unicode.ToLower(r)
+		if letter, ok := leet[r]; ok {
+			r = letter
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
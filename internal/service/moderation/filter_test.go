@@ -0,0 +1,67 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterText(t *testing.T) {
+	f, err := NewFilter(Config{Words: "darn, heck"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		content string
+		clean   string
+		blocked bool
+	}{
+		{"have a nice day", "have a nice day", false},
+		{"Darn it", "**** it", true},
+		{"what the H3CK!", "what the ****!", true},
+		{"d4rn, d@rn and #darn", "****, **** and #****", true},
+		// Only whole words match
+		{"darning socks", "darning socks", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.content, func(t *testing.T) {
+			clean, blocked := f.FilterText(tc.content)
+			require.Equal(t, tc.blocked, blocked)
+			require.Equal(t, tc.clean, clean)
+		})
+	}
+}
+
+func TestFilterReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# blocked words\ndarn\n\n"), 0o600))
+
+	f, err := NewFilter(Config{WordsFile: path})
+	require.NoError(t, err)
+	require.Equal(t, ModeReject, f.Mode())
+
+	_, blocked := f.FilterText("heck")
+	require.False(t, blocked)
+
+	require.NoError(t, os.WriteFile(path, []byte("darn\nheck\n"), 0o600))
+	count, err := f.Reload()
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	_, blocked = f.FilterText("heck")
+	require.True(t, blocked)
+
+	// A broken reload keeps the words already loaded
+	require.NoError(t, os.Remove(path))
+	_, err = f.Reload()
+	require.Error(t, err)
+	_, blocked = f.FilterText("heck")
+	require.True(t, blocked)
+}
+
+func TestNewFilterMissingFile(t *testing.T) {
+	_, err := NewFilter(Config{WordsFile: filepath.Join(t.TempDir(), "missing.txt")})
+	require.Error(t, err)
+}
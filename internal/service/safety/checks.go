@@ -0,0 +1,142 @@
+package safety
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// DefaultMaxSpeedKmH is 1000 km/h (approx jet speed). Anything faster is definitely fake.
+	DefaultMaxSpeedKmH = 1000.0
+
+	// DefaultTeleportWindow is how soon after the last ping a jump counts as a teleport
+	DefaultTeleportWindow = 2 * time.Minute
+	// DefaultTeleportMaxJumpKm is how far a user may move within the teleport window
+	DefaultTeleportMaxJumpKm = 150.0
+
+	// DefaultMaxPostsPerHour is how many stories a user may post an hour before it looks automated
+	DefaultMaxPostsPerHour = 20
+
+	// Key prefix for recent post counters
+	postCountKeyPrefix = "safety:posts:"
+)
+
+// SpeedCheck flags movement faster than MaxSpeedKmH since the last ping:
+//
+//	speed = haversine(previous, current) km / hours between the pings
+//
+// Pings with no time between them are left to TeleportCheck.
+type SpeedCheck struct {
+	MaxSpeedKmH float64
+	Weight      float64
+}
+
+func (c SpeedCheck) Name() string { return "speed" }
+
+func (c SpeedCheck) Evaluate(_ context.Context, event Event) (Risk, error) {
+	if event.Previous == nil {
+		return Risk{}, nil
+	}
+	hours := event.At.Sub(event.Previous.At).Hours()
+	if hours <= 0 {
+		// Same timestamp or clock skew; TeleportCheck covers instant jumps
+		return Risk{}, nil
+	}
+
+	speed := haversineKm(event.Previous.Lat, event.Previous.Lng, event.Lat, event.Lng) / hours
+	if speed <= c.MaxSpeedKmH {
+		return Risk{}, nil
+	}
+	return Risk{
+		Score:  c.Weight,
+		Reason: "Speed limit exceeded (" + formatFloat(speed) + " km/h)",
+	}, nil
+}
+
+// TeleportCheck flags a jump of more than MaxJumpKm within Window of the last
+// ping, including jumps with no time in between that SpeedCheck can't measure:
+//
+//	elapsed <= Window and haversine(previous, current) km > MaxJumpKm
+type TeleportCheck struct {
+	Window    time.Duration
+	MaxJumpKm float64
+	Weight    float64
+}
+
+func (c TeleportCheck) Name() string { return "teleport" }
+
+func (c TeleportCheck) Evaluate(_ context.Context, event Event) (Risk, error) {
+	if event.Previous == nil || event.At.Sub(event.Previous.At) > c.Window {
+		return Risk{}, nil
+	}
+
+	distance := haversineKm(event.Previous.Lat, event.Previous.Lng, event.Lat, event.Lng)
+	if distance <= c.MaxJumpKm {
+		return Risk{}, nil
+	}
+	return Risk{
+		Score:  c.Weight,
+		Reason: "Location jumped " + formatFloat(distance) + " km within " + event.At.Sub(event.Previous.At).String(),
+	}, nil
+}
+
+// SpoofHintCheck flags devices whose app reports a mock location provider or
+// known GPS spoofing apps
+type SpoofHintCheck struct {
+	Weight float64
+}
+
+func (c SpoofHintCheck) Name() string { return "spoof_hints" }
+
+func (c SpoofHintCheck) Evaluate(_ context.Context, event Event) (Risk, error) {
+	switch {
+	case event.Client.IsMockLocation:
+		return Risk{Score: c.Weight, Reason: "Client reported a mock location"}, nil
+	case len(event.Client.SpoofApps) > 0:
+		return Risk{Score: c.Weight, Reason: "Spoofing apps installed: " + strings.Join(event.Client.SpoofApps, ", ")}, nil
+	}
+	return Risk{}, nil
+}
+
+// RapidPostingCheck flags users posting more than Max stories in Window. Every
+// evaluated post counts, including refused ones, so retrying doesn't help.
+type RapidPostingCheck struct {
+	redis  *redis.Client
+	Max    int
+	Window time.Duration
+	Weight float64
+}
+
+func NewRapidPostingCheck(rdb *redis.Client, max int, window time.Duration, weight float64) RapidPostingCheck {
+	return RapidPostingCheck{redis: rdb, Max: max, Window: window, Weight: weight}
+}
+
+func (c RapidPostingCheck) Name() string { return "rapid_posting" }
+
+func (c RapidPostingCheck) Evaluate(ctx context.Context, event Event) (Risk, error) {
+	if event.Action != ActionPostStory {
+		return Risk{}, nil
+	}
+
+	key := util.RedisKey(postCountKeyPrefix + event.UserID)
+	count, err := c.redis.Incr(ctx, key).Result()
+	if err != nil {
+		return Risk{}, err
+	}
+	if count == 1 {
+		c.redis.Expire(ctx, key, c.Window)
+	}
+	if count <= int64(c.Max) {
+		return Risk{}, nil
+	}
+	return Risk{
+		Score:  c.Weight,
+		Reason: "Posted " + strconv.FormatInt(count, 10) + " stories within " + c.Window.String(),
+	}, nil
+}
@@ -0,0 +1,73 @@
+package safety
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/repository"
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/admin"
+)
+
+// AuditActionBan is the admin audit log action for bans placed by the safety checks
+const AuditActionBan = "safety.ban"
+
+// SystemActorID stands in for the admin on audit entries the server writes by itself
+var SystemActorID = uuid.Nil
+
+// Enforcer carries out the bans the Monitor calls for. With enforcement off,
+// as on staging, bans are only logged.
+type Enforcer struct {
+	store   repository.Store
+	enforce bool
+}
+
+func NewEnforcer(store repository.Store, enforce bool) *Enforcer {
+	return &Enforcer{store: store, enforce: enforce}
+}
+
+// Apply shadow-bans userID when result calls for a ban, and records it in the
+// admin audit log. It reports whether a ban was placed. A nil Enforcer never bans.
+func (e *Enforcer) Apply(ctx context.Context, userID uuid.UUID, action Action, result ValidationResult) bool {
+	if e == nil || !result.ShouldBan {
+		return false
+	}
+
+	logger := log.With().
+		Str("user_id", userID.String()).
+		Str("action", string(action)).
+		Float64("score", result.Score).
+		Str("reason", result.Reason).
+		Logger()
+	if !e.enforce {
+		logger.Warn().Msg("safety ban not enforced")
+		return false
+	}
+
+	_, err := e.store.BanUser(ctx, db.BanUserParams{
+		ID:             userID,
+		IsShadowBanned: true,
+		BanType:        sql.NullString{String: admin.BanTypeShadow, Valid: true},
+		BanReason:      sql.NullString{String: result.Reason, Valid: result.Reason != ""},
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to place safety ban")
+		return false
+	}
+	logger.Warn().Msg("user shadow-banned by safety checks")
+
+	err = e.store.CreateAdminAuditLog(ctx, db.CreateAdminAuditLogParams{
+		AdminID:      SystemActorID,
+		TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+		TargetID:     uuid.NullUUID{UUID: userID, Valid: true},
+		Action:       AuditActionBan,
+		Reason:       sql.NullString{String: string(action) + ": " + result.Reason, Valid: true},
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to write admin audit log")
+	}
+	return true
+}
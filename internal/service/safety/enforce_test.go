@@ -0,0 +1,83 @@
+package safety
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+)
+
+func TestEnforcerApply(t *testing.T) {
+	userID := uuid.New()
+	ban := ValidationResult{ShouldBan: true, Score: 1.5, Reason: "Speed limit exceeded (1750.00 km/h)"}
+
+	testCases := []struct {
+		name       string
+		enforce    bool
+		result     ValidationResult
+		buildStubs func(store *mockdb.MockStore)
+		wantBanned bool
+	}{
+		{
+			name:    "Bans",
+			enforce: true,
+			result:  ban,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BanUser(gomock.Any(), db.BanUserParams{
+						ID:             userID,
+						IsShadowBanned: true,
+						BanType:        sql.NullString{String: "shadow", Valid: true},
+						BanReason:      sql.NullString{String: ban.Reason, Valid: true},
+					}).
+					Times(1).
+					Return(db.User{ID: userID}, nil)
+				store.EXPECT().
+					CreateAdminAuditLog(gomock.Any(), db.CreateAdminAuditLogParams{
+						AdminID:      SystemActorID,
+						TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+						TargetID:     uuid.NullUUID{UUID: userID, Valid: true},
+						Action:       AuditActionBan,
+						Reason:       sql.NullString{String: "post_story: " + ban.Reason, Valid: true},
+					}).
+					Times(1).
+					Return(nil)
+			},
+			wantBanned: true,
+		},
+		{
+			name:   "NotEnforced",
+			result: ban,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BanUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateAdminAuditLog(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:    "NoBanCalledFor",
+			enforce: true,
+			result:  ValidationResult{Score: 1.5, Strikes: 1},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BanUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			enforcer := NewEnforcer(store, tc.enforce)
+			banned := enforcer.Apply(context.Background(), userID, ActionPostStory, tc.result)
+			require.Equal(t, tc.wantBanned, banned)
+		})
+	}
+}
@@ -5,84 +5,273 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/util"
 )
 
 const (
-	// MaxSpeedKmH is 1000 km/h (approx jet speed). Anything faster is definitely fake.
-	MaxSpeedKmH = 1000.0
 	// Key prefix for last location
 	lastLocationKeyPrefix = "safety:last_loc:"
+	lastLocationTTL       = 24 * time.Hour
+
+	// DefaultBlockThreshold is the combined risk at which an action is refused
+	DefaultBlockThreshold = 1.0
+	// DefaultBanThreshold is the combined risk at which an event counts as a ban strike
+	DefaultBanThreshold = 1.5
+	// DefaultBanStrikes is how many ban strikes within BanStrikeWindow get a user
+	// banned, so a single noisy GPS fix doesn't
+	DefaultBanStrikes = 3
+	// BanStrikeWindow is how long a ban strike counts against a user
+	BanStrikeWindow = 24 * time.Hour
+
+	// Key prefix for ban strike counters
+	banStrikesKeyPrefix = "safety:strikes:"
+	// Key prefix for users exempt from the safety checks
+	exemptKeyPrefix = "safety:exempt:"
+)
+
+// Action is what the user is doing when the checks run
+type Action string
+
+const (
+	ActionLocationUpdate Action = "location_update"
+	ActionPostStory      Action = "post_story"
 )
 
+// ClientHints is what the app reports about the device alongside a location.
+// It is self-reported, so it only ever adds risk.
+type ClientHints struct {
+	// IsMockLocation is set when the OS flags the fix as coming from a mock provider
+	IsMockLocation bool `json:"is_mock_location"`
+	// SpoofApps lists installed packages known to fake GPS
+	SpoofApps []string `json:"spoof_apps"`
+}
+
+// LastLocation is the user's previous accepted position
+type LastLocation struct {
+	Lat float64
+	Lng float64
+	At  time.Time
+}
+
+// Event is one location-bearing action to evaluate
+type Event struct {
+	UserID string
+	Action Action
+	Lat    float64
+	Lng    float64
+	At     time.Time
+	Client ClientHints
+	// Previous is filled in by the Monitor; nil on a user's first ping
+	Previous *LastLocation
+}
+
+// Risk is one check's verdict. Score is already weighted; zero means nothing suspicious.
+type Risk struct {
+	Check  string
+	Score  float64
+	Reason string
+}
+
+// SafetyCheck is one anti-abuse signal in the Monitor's pipeline
+type SafetyCheck interface {
+	Name() string
+	Evaluate(ctx context.Context, event Event) (Risk, error)
+}
+
 type ValidationResult struct {
-	Allowed   bool
-	Reason    string
+	Allowed bool
+	Reason  string
+	// ShouldBan is set on the event that brings the user to BanStrikes strikes
 	ShouldBan bool
+	// Strikes counts the user's ban-level events within BanStrikeWindow, this one included
+	Strikes int64
+	// Score is the sum of every check's risk
+	Score float64
+	// Risks are the checks that flagged this event
+	Risks []Risk
+}
+
+// MonitorConfig sets how combined risk turns into a decision
+type MonitorConfig struct {
+	// BlockThreshold refuses the action at or above this score
+	BlockThreshold float64
+	// BanThreshold counts a ban strike against the user at or above this score
+	BanThreshold float64
+	// BanStrikes is how many strikes within BanStrikeWindow call for a ban; zero uses DefaultBanStrikes
+	BanStrikes int
+	// MaxPostsPerHour feeds the rapid-posting check; zero uses DefaultMaxPostsPerHour
+	MaxPostsPerHour int
+	// MaxSpeedKmH is the fastest plausible movement between pings; zero uses DefaultMaxSpeedKmH
+	MaxSpeedKmH float64
+	// TeleportWindow is how soon after the last ping a long jump counts as a
+	// teleport; zero uses DefaultTeleportWindow
+	TeleportWindow time.Duration
+	// TeleportMaxJumpKm is how far a user may move within TeleportWindow; zero
+	// uses DefaultTeleportMaxJumpKm
+	TeleportMaxJumpKm float64
 }
 
-// Monitor handles safety checks like Fake GPS
+func (c MonitorConfig) withDefaults() MonitorConfig {
+	if c.BlockThreshold <= 0 {
+		c.BlockThreshold = DefaultBlockThreshold
+	}
+	if c.BanThreshold <= 0 {
+		c.BanThreshold = DefaultBanThreshold
+	}
+	if c.BanStrikes <= 0 {
+		c.BanStrikes = DefaultBanStrikes
+	}
+	if c.MaxPostsPerHour <= 0 {
+		c.MaxPostsPerHour = DefaultMaxPostsPerHour
+	}
+	if c.MaxSpeedKmH <= 0 {
+		c.MaxSpeedKmH = DefaultMaxSpeedKmH
+	}
+	if c.TeleportWindow <= 0 {
+		c.TeleportWindow = DefaultTeleportWindow
+	}
+	if c.TeleportMaxJumpKm <= 0 {
+		c.TeleportMaxJumpKm = DefaultTeleportMaxJumpKm
+	}
+	return c
+}
+
+// Monitor runs safety checks like Fake GPS and combines their risk into a decision
 type Monitor struct {
-	redis *redis.Client
+	redis  *redis.Client
+	config MonitorConfig
+	clock  util.Clock
+	checks []SafetyCheck
 }
 
-func NewMonitor(rdb *redis.Client) *Monitor {
-	return &Monitor{redis: rdb}
+// NewMonitor creates a Monitor with the default checks: speed, teleport, spoofing
+// hints from the client and rapid posting
+func NewMonitor(rdb *redis.Client, config MonitorConfig) *Monitor {
+	config = config.withDefaults()
+	m := &Monitor{
+		redis:  rdb,
+		config: config,
+		clock:  util.SystemClock,
+	}
+	m.Register(
+		SpeedCheck{MaxSpeedKmH: config.MaxSpeedKmH, Weight: 1.5},
+		TeleportCheck{Window: config.TeleportWindow, MaxJumpKm: config.TeleportMaxJumpKm, Weight: 1.0},
+		SpoofHintCheck{Weight: 0.6},
+		NewRapidPostingCheck(rdb, config.MaxPostsPerHour, time.Hour, 1.0),
+	)
+	return m
 }
 
-// ValidateUserMovement checks if the user moved typically fast
-func (s *Monitor) ValidateUserMovement(ctx context.Context, userID string, newLat, newLng float64) ValidationResult {
-	key := lastLocationKeyPrefix + userID
+// Register adds checks to the pipeline; they run in registration order
+func (s *Monitor) Register(checks ...SafetyCheck) {
+	s.checks = append(s.checks, checks...)
+}
 
-	// Get last location
-	res, err := s.redis.HGetAll(ctx, key).Result()
-	if err != nil || len(res) == 0 {
-		// First ping or expired, just save new location
-		s.saveLastLocation(ctx, key, newLat, newLng)
+// Evaluate runs every check against the event. The location is only remembered
+// when the action is allowed, so a faked position never becomes the baseline.
+// Exempt users are always allowed.
+func (s *Monitor) Evaluate(ctx context.Context, event Event) ValidationResult {
+	if event.At.IsZero() {
+		event.At = s.clock.Now()
+	}
+	key := util.RedisKey(lastLocationKeyPrefix + event.UserID)
+	if s.IsExempt(ctx, event.UserID) {
+		s.saveLastLocation(ctx, key, event)
 		return ValidationResult{Allowed: true}
 	}
+	event.Previous = s.lastLocation(ctx, key)
 
-	// Parse last location
-	lastLat := parseFloat(res["lat"])
-	lastLng := parseFloat(res["lng"])
-	lastTime, _ := time.Parse(time.RFC3339, res["time"])
+	var result ValidationResult
+	reasons := make([]string, 0)
+	for _, check := range s.checks {
+		risk, err := check.Evaluate(ctx, event)
+		if err != nil {
+			// A broken signal shouldn't lock everyone out
+			log.Error().Err(err).Str("check", check.Name()).Msg("safety check failed")
+			continue
+		}
+		if risk.Score <= 0 {
+			continue
+		}
+		risk.Check = check.Name()
+		result.Risks = append(result.Risks, risk)
+		result.Score += risk.Score
+		reasons = append(reasons, risk.Reason)
+	}
 
-	now := time.Now()
+	result.Allowed = result.Score < s.config.BlockThreshold
+	result.Reason = strings.Join(reasons, "; ")
+	if result.Score >= s.config.BanThreshold {
+		result.Strikes = s.addBanStrike(ctx, event.UserID)
+		// Only the strike that reaches the limit bans, so a ban fires once per window
+		result.ShouldBan = result.Strikes == int64(s.config.BanStrikes)
+	}
 
-	// Calculate distance (Haversine)
-	distKm := haversineKm(lastLat, lastLng, newLat, newLng)
-	timeDiffHours := now.Sub(lastTime).Hours()
+	if result.Allowed {
+		s.saveLastLocation(ctx, key, event)
+	}
+	return result
+}
 
-	if timeDiffHours <= 0 {
-		// Same timestamp or clock skew? Allow but update
-		s.saveLastLocation(ctx, key, newLat, newLng)
-		return ValidationResult{Allowed: true}
+// SetExempt exempts a user from the safety checks, or ends their exemption.
+// It is for users the checks keep flagging wrongly, such as frequent flyers.
+func (s *Monitor) SetExempt(ctx context.Context, userID string, exempt bool) error {
+	key := util.RedisKey(exemptKeyPrefix + userID)
+	if !exempt {
+		return s.redis.Del(ctx, key).Err()
 	}
+	return s.redis.Set(ctx, key, "1", 0).Err()
+}
 
-	speed := distKm / timeDiffHours
+// IsExempt reports whether a user is exempt from the safety checks. Redis
+// errors count as not exempt.
+func (s *Monitor) IsExempt(ctx context.Context, userID string) bool {
+	exempt, err := s.redis.Exists(ctx, util.RedisKey(exemptKeyPrefix+userID)).Result()
+	return err == nil && exempt > 0
+}
 
-	if speed > MaxSpeedKmH {
-		return ValidationResult{
-			Allowed:   false,
-			Reason:    "Speed limit exceeded (" + formatFloat(speed) + " km/h)",
-			ShouldBan: true,
-		}
+// addBanStrike counts a ban-level event against the user and returns their
+// strikes within BanStrikeWindow. Redis errors count as no strikes.
+func (s *Monitor) addBanStrike(ctx context.Context, userID string) int64 {
+	key := util.RedisKey(banStrikesKeyPrefix + userID)
+	strikes, err := s.redis.Incr(ctx, key).Result()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to count safety ban strike")
+		return 0
 	}
+	if strikes == 1 {
+		s.redis.Expire(ctx, key, BanStrikeWindow)
+	}
+	return strikes
+}
 
-	// Valid, update last location
-	s.saveLastLocation(ctx, key, newLat, newLng)
-	return ValidationResult{Allowed: true}
+func (s *Monitor) lastLocation(ctx context.Context, key string) *LastLocation {
+	res, err := s.redis.HGetAll(ctx, key).Result()
+	if err != nil || len(res) == 0 {
+		// First ping or expired
+		return nil
+	}
+	at, _ := time.Parse(time.RFC3339, res["time"])
+	return &LastLocation{
+		Lat: parseFloat(res["lat"]),
+		Lng: parseFloat(res["lng"]),
+		At:  at,
+	}
 }
 
-func (s *Monitor) saveLastLocation(ctx context.Context, key string, lat, lng float64) {
+func (s *Monitor) saveLastLocation(ctx context.Context, key string, event Event) {
 	s.redis.HSet(ctx, key, map[string]interface{}{
-		"lat":  lat,
-		"lng":  lng,
-		"time": time.Now().Format(time.RFC3339),
+		"lat":  event.Lat,
+		"lng":  event.Lng,
+		"time": event.At.Format(time.RFC3339),
 	})
-	s.redis.Expire(ctx, key, 24*time.Hour)
+	s.redis.Expire(ctx, key, lastLocationTTL)
 }
 
 // -- Helpers --
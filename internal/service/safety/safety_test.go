@@ -0,0 +1,191 @@
+package safety
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"privacy-social-backend/internal/util"
+)
+
+var monitorNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// Bengaluru and Delhi, ~1750km apart
+const (
+	blrLat, blrLng = 12.9716, 77.5946
+	delLat, delLng = 28.6139, 77.2090
+)
+
+func newTestMonitor(t *testing.T, config MonitorConfig) *Monitor {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	m := NewMonitor(rdb, config)
+	m.clock = util.FixedClock{T: monitorNow}
+	return m
+}
+
+func ping(m *Monitor, at time.Time, lat, lng float64) ValidationResult {
+	return m.Evaluate(context.Background(), Event{
+		UserID: "user",
+		Action: ActionLocationUpdate,
+		Lat:    lat,
+		Lng:    lng,
+		At:     at,
+	})
+}
+
+func riskNames(result ValidationResult) []string {
+	names := make([]string, len(result.Risks))
+	for i, r := range result.Risks {
+		names[i] = r.Check
+	}
+	return names
+}
+
+func TestMonitorImpossibleSpeedBans(t *testing.T) {
+	m := newTestMonitor(t, MonitorConfig{BanStrikes: 1})
+
+	require.True(t, ping(m, monitorNow, blrLat, blrLng).Allowed)
+
+	// Delhi an hour later is ~1750 km/h
+	result := ping(m, monitorNow.Add(time.Hour), delLat, delLng)
+	require.False(t, result.Allowed)
+	require.True(t, result.ShouldBan)
+	require.Equal(t, []string{"speed"}, riskNames(result))
+
+	// The rejected position isn't remembered, so a plausible ping still passes
+	require.True(t, ping(m, monitorNow.Add(2*time.Hour), blrLat+0.01, blrLng).Allowed)
+}
+
+func TestMonitorTeleportWithoutElapsedTime(t *testing.T) {
+	m := newTestMonitor(t, MonitorConfig{})
+
+	require.True(t, ping(m, monitorNow, blrLat, blrLng).Allowed)
+
+	result := ping(m, monitorNow, delLat, delLng)
+	require.False(t, result.Allowed)
+	require.False(t, result.ShouldBan)
+	require.Equal(t, []string{"teleport"}, riskNames(result))
+}
+
+func TestMonitorSpoofHintsCombine(t *testing.T) {
+	m := newTestMonitor(t, MonitorConfig{BanStrikes: 1})
+
+	// A hint on its own is suspicious but not enough to refuse
+	result := m.Evaluate(context.Background(), Event{
+		UserID: "user", Action: ActionLocationUpdate, Lat: blrLat, Lng: blrLng, At: monitorNow,
+		Client: ClientHints{IsMockLocation: true},
+	})
+	require.True(t, result.Allowed)
+	require.Equal(t, 0.6, result.Score)
+
+	// Together with a teleport it crosses the ban threshold
+	result = m.Evaluate(context.Background(), Event{
+		UserID: "user", Action: ActionLocationUpdate, Lat: delLat, Lng: delLng, At: monitorNow,
+		Client: ClientHints{SpoofApps: []string{"com.lexa.fakegps"}},
+	})
+	require.False(t, result.Allowed)
+	require.True(t, result.ShouldBan)
+	require.Equal(t, []string{"teleport", "spoof_hints"}, riskNames(result))
+}
+
+func TestMonitorBanGrace(t *testing.T) {
+	m := newTestMonitor(t, MonitorConfig{})
+	require.True(t, ping(m, monitorNow, blrLat, blrLng).Allowed)
+
+	// Each jump to Delhi is refused, but only the third in a day bans
+	for strike := int64(1); strike <= DefaultBanStrikes; strike++ {
+		result := ping(m, monitorNow.Add(time.Hour), delLat, delLng)
+		require.False(t, result.Allowed)
+		require.Equal(t, strike, result.Strikes)
+		require.Equal(t, strike == DefaultBanStrikes, result.ShouldBan)
+	}
+
+	// The ban fires once, not on every strike after it
+	result := ping(m, monitorNow.Add(time.Hour), delLat, delLng)
+	require.False(t, result.ShouldBan)
+}
+
+func TestMonitorMovementIsConfigurable(t *testing.T) {
+	// Bengaluru to Delhi in two hours is ~875 km/h, a flight
+	flight := func(m *Monitor) ValidationResult {
+		require.True(t, ping(m, monitorNow, blrLat, blrLng).Allowed)
+		return ping(m, monitorNow.Add(2*time.Hour), delLat, delLng)
+	}
+	require.True(t, flight(newTestMonitor(t, MonitorConfig{})).Allowed)
+
+	result := flight(newTestMonitor(t, MonitorConfig{MaxSpeedKmH: 300}))
+	require.False(t, result.Allowed)
+	require.Equal(t, []string{"speed"}, riskNames(result))
+}
+
+func TestMonitorTeleportTolerance(t *testing.T) {
+	m := newTestMonitor(t, MonitorConfig{})
+
+	// 100km within the window is under the default tolerance
+	require.True(t, ping(m, monitorNow, blrLat, blrLng).Allowed)
+	result := ping(m, monitorNow, blrLat+0.9, blrLng)
+	require.True(t, result.Allowed)
+	require.Empty(t, result.Risks)
+
+	m = newTestMonitor(t, MonitorConfig{TeleportMaxJumpKm: 50})
+	require.True(t, ping(m, monitorNow, blrLat, blrLng).Allowed)
+	result = ping(m, monitorNow, blrLat+0.9, blrLng)
+	require.False(t, result.Allowed)
+	require.Equal(t, []string{"teleport"}, riskNames(result))
+
+	// Outside the window the same jump is left to the speed check
+	require.True(t, ping(m, monitorNow.Add(time.Hour), blrLat+0.9, blrLng).Allowed)
+}
+
+func TestMonitorExemptUser(t *testing.T) {
+	m := newTestMonitor(t, MonitorConfig{BanStrikes: 1})
+	ctx := context.Background()
+	require.NoError(t, m.SetExempt(ctx, "user", true))
+	require.True(t, m.IsExempt(ctx, "user"))
+
+	require.True(t, ping(m, monitorNow, blrLat, blrLng).Allowed)
+	result := ping(m, monitorNow, delLat, delLng)
+	require.True(t, result.Allowed)
+	require.False(t, result.ShouldBan)
+
+	// Delhi was remembered, so flying back is judged from there once checks resume
+	require.NoError(t, m.SetExempt(ctx, "user", false))
+	require.False(t, ping(m, monitorNow, blrLat, blrLng).Allowed)
+}
+
+func TestMonitorRapidPosting(t *testing.T) {
+	m := newTestMonitor(t, MonitorConfig{MaxPostsPerHour: 2})
+	post := func() ValidationResult {
+		return m.Evaluate(context.Background(), Event{
+			UserID: "user", Action: ActionPostStory, Lat: blrLat, Lng: blrLng, At: monitorNow,
+		})
+	}
+
+	require.True(t, post().Allowed)
+	require.True(t, post().Allowed)
+	result := post()
+	require.False(t, result.Allowed)
+	require.False(t, result.ShouldBan)
+	require.Equal(t, []string{"rapid_posting"}, riskNames(result))
+
+	// Location pings don't count as posts
+	require.True(t, ping(m, monitorNow, blrLat, blrLng).Allowed)
+}
+
+func TestMonitorThresholdsAreConfigurable(t *testing.T) {
+	m := newTestMonitor(t, MonitorConfig{BlockThreshold: 0.5, BanThreshold: 10})
+
+	result := m.Evaluate(context.Background(), Event{
+		UserID: "user", Action: ActionLocationUpdate, Lat: blrLat, Lng: blrLng, At: monitorNow,
+		Client: ClientHints{IsMockLocation: true},
+	})
+	require.False(t, result.Allowed)
+	require.False(t, result.ShouldBan)
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rs/zerolog/log"
+
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// privateRefPrefix marks a stored media reference to an object in a private bucket.
+	// Rows keep the reference; clients only ever see presigned URLs.
+	privateRefPrefix = "r2:"
+
+	// DefaultMediaURLExpiry is how long a presigned media URL stays valid
+	DefaultMediaURLExpiry = time.Hour
+
+	// maxCachedMediaURLs bounds the signature cache; expired entries are dropped first
+	maxCachedMediaURLs = 10000
+)
+
+// PrivateRef is the stored reference for a private object key
+func PrivateRef(key string) string {
+	return privateRefPrefix + key
+}
+
+// PrivateKey returns the object key of a private media reference. Public URLs
+// and anything else stored before private media existed report false.
+func PrivateKey(ref string) (string, bool) {
+	if !strings.HasPrefix(ref, privateRefPrefix) {
+		return "", false
+	}
+	key := strings.TrimPrefix(ref, privateRefPrefix)
+	return key, key != ""
+}
+
+// PresignedGetURL returns a time-limited URL for reading a private object
+func (s *S3Service) PresignedGetURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	req, err := s.presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign get: %w", err)
+	}
+	return req.URL, nil
+}
+
+// URLSigner presigns reads of private objects; Service satisfies it
+type URLSigner interface {
+	PresignedGetURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+type signedURL struct {
+	url       string
+	expiresAt time.Time
+}
+
+// MediaResolver turns stored media references into URLs clients can fetch.
+// Signatures are cached and reused for the first half of their lifetime, so
+// clients see stable URLs (and their image caches hit) and a cached response
+// holding one still has at least half the expiry left when it is served.
+type MediaResolver struct {
+	signer URLSigner
+	expiry time.Duration
+	clock  util.Clock
+
+	mu    sync.Mutex
+	cache map[string]signedURL
+}
+
+// NewMediaResolver creates a resolver. A nil signer leaves every reference as is,
+// for deployments without object storage.
+func NewMediaResolver(signer URLSigner, expiry time.Duration) *MediaResolver {
+	if expiry <= 0 {
+		expiry = DefaultMediaURLExpiry
+	}
+	return &MediaResolver{
+		signer: signer,
+		expiry: expiry,
+		clock:  util.SystemClock,
+		cache:  make(map[string]signedURL),
+	}
+}
+
+// Resolve returns the URL to show for a stored media reference. Public URLs pass
+// through; private references get a presigned URL, or "" if signing fails so a
+// client never receives an unusable reference.
+func (r *MediaResolver) Resolve(ctx context.Context, ref string) string {
+	key, ok := PrivateKey(ref)
+	if !ok || r == nil || r.signer == nil {
+		return ref
+	}
+
+	now := r.clock.Now()
+	r.mu.Lock()
+	cached, hit := r.cache[key]
+	r.mu.Unlock()
+	if hit && now.Before(cached.expiresAt.Add(-r.expiry/2)) {
+		return cached.url
+	}
+
+	url, err := r.signer.PresignedGetURL(ctx, key, r.expiry)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("failed to presign media URL")
+		return ""
+	}
+
+	r.mu.Lock()
+	if len(r.cache) >= maxCachedMediaURLs {
+		r.evict(now)
+	}
+	r.cache[key] = signedURL{url: url, expiresAt: now.Add(r.expiry)}
+	r.mu.Unlock()
+	return url
+}
+
+// evict drops expired signatures, or everything if none have expired yet. The
+// caller holds r.mu.
+func (r *MediaResolver) evict(now time.Time) {
+	for key, cached := range r.cache {
+		if !now.Before(cached.expiresAt) {
+			delete(r.cache, key)
+		}
+	}
+	if len(r.cache) >= maxCachedMediaURLs {
+		r.cache = make(map[string]signedURL)
+	}
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"privacy-social-backend/internal/util"
+)
+
+type fakeSigner struct {
+	calls int
+	err   error
+}
+
+func (f *fakeSigner) PresignedGetURL(_ context.Context, key string, expiry time.Duration) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	f.calls++
+	return fmt.Sprintf("https://signed.example.com/%s?exp=%s&n=%d", key, expiry, f.calls), nil
+}
+
+func TestPrivateRef(t *testing.T) {
+	key, ok := PrivateKey(PrivateRef("media/abc.jpg"))
+	require.True(t, ok)
+	require.Equal(t, "media/abc.jpg", key)
+
+	_, ok = PrivateKey("https://bucket.r2.dev/media/abc.jpg")
+	require.False(t, ok)
+	_, ok = PrivateKey(privateRefPrefix)
+	require.False(t, ok)
+}
+
+func TestMediaResolver(t *testing.T) {
+	signer := &fakeSigner{}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := NewMediaResolver(signer, time.Hour)
+	r.clock = util.FixedClock{T: now}
+	ctx := context.Background()
+
+	// Public URLs pass through untouched
+	require.Equal(t, "https://bucket.r2.dev/a.jpg", r.Resolve(ctx, "https://bucket.r2.dev/a.jpg"))
+	require.Zero(t, signer.calls)
+
+	ref := PrivateRef("media/a.jpg")
+	first := r.Resolve(ctx, ref)
+	require.Contains(t, first, "media/a.jpg")
+
+	// Reused for the first half of its lifetime
+	r.clock = util.FixedClock{T: now.Add(29 * time.Minute)}
+	require.Equal(t, first, r.Resolve(ctx, ref))
+	require.Equal(t, 1, signer.calls)
+
+	// Then re-signed so a cached response never holds a nearly expired URL
+	r.clock = util.FixedClock{T: now.Add(31 * time.Minute)}
+	require.NotEqual(t, first, r.Resolve(ctx, ref))
+	require.Equal(t, 2, signer.calls)
+}
+
+func TestMediaResolverWithoutStorage(t *testing.T) {
+	var r *MediaResolver
+	require.Equal(t, PrivateRef("k"), r.Resolve(context.Background(), PrivateRef("k")))
+
+	failing := NewMediaResolver(&fakeSigner{err: errors.New("boom")}, time.Hour)
+	require.Empty(t, failing.Resolve(context.Background(), PrivateRef("k")))
+}
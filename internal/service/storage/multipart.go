@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/util"
+)
+
+const (
+	// partURLExpiry is how long a presigned part URL stays valid
+	partURLExpiry = 30 * time.Minute
+	// MaxUploadParts is the S3 limit on parts per multipart upload
+	MaxUploadParts = 10000
+)
+
+// MultipartUpload identifies an in-progress multipart upload
+type MultipartUpload struct {
+	Key      string `json:"key"`
+	UploadID string `json:"upload_id"`
+}
+
+// CompletedPart is a part the client has finished uploading
+type CompletedPart struct {
+	PartNumber int32  `json:"part_number" binding:"required,min=1,max=10000"`
+	ETag       string `json:"etag" binding:"required"`
+}
+
+// PresignedPart is a URL the client PUTs a single part's bytes to
+type PresignedPart struct {
+	PartNumber int32     `json:"part_number"`
+	URL        string    `json:"url"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// CreateMultipartUpload starts a multipart upload under keyPrefix and returns its identifiers
+func (s *S3Service) CreateMultipartUpload(ctx context.Context, keyPrefix, filename, contentType string) (MultipartUpload, error) {
+	key := fmt.Sprintf("%s/%s%s", keyPrefix, uuid.New().String(), filepath.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	var out *s3.CreateMultipartUploadOutput
+	err := s.withRetry(ctx, "create multipart upload", func(ctx context.Context) error {
+		var err error
+		out, err = s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:      aws.String(s.bucketName),
+			Key:         aws.String(key),
+			ContentType: aws.String(contentType),
+		})
+		return err
+	})
+	if err != nil {
+		return MultipartUpload{}, fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+
+	return MultipartUpload{Key: key, UploadID: aws.ToString(out.UploadId)}, nil
+}
+
+// PresignUploadParts returns presigned PUT URLs for the given part numbers
+func (s *S3Service) PresignUploadParts(ctx context.Context, upload MultipartUpload, partNumbers []int32) ([]PresignedPart, error) {
+	parts := make([]PresignedPart, 0, len(partNumbers))
+	for _, n := range partNumbers {
+		if n < 1 || n > MaxUploadParts {
+			return nil, fmt.Errorf("invalid part number %d", n)
+		}
+
+		req, err := s.presigner.PresignUploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(s.bucketName),
+			Key:        aws.String(upload.Key),
+			UploadId:   aws.String(upload.UploadID),
+			PartNumber: aws.Int32(n),
+		}, s3.WithPresignExpires(partURLExpiry))
+		if err != nil {
+			return nil, fmt.Errorf("failed to presign part %d: %w", n, err)
+		}
+
+		parts = append(parts, PresignedPart{
+			PartNumber: n,
+			URL:        req.URL,
+			ExpiresAt:  util.Now().Add(partURLExpiry),
+		})
+	}
+	return parts, nil
+}
+
+// CompleteMultipartUpload assembles the uploaded parts and describes the
+// stored object as StatObject does. Its URL is the public URL, or the private
+// reference for a private bucket.
+func (s *S3Service) CompleteMultipartUpload(ctx context.Context, upload MultipartUpload, parts []CompletedPart) (UploadResult, error) {
+	completed := make([]types.CompletedPart, len(parts))
+	for i, p := range parts {
+		completed[i] = types.CompletedPart{
+			PartNumber: aws.Int32(p.PartNumber),
+			ETag:       aws.String(p.ETag),
+		}
+	}
+
+	err := s.withRetry(ctx, "complete multipart upload", func(ctx context.Context) error {
+		_, err := s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(s.bucketName),
+			Key:             aws.String(upload.Key),
+			UploadId:        aws.String(upload.UploadID),
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+		})
+		return err
+	})
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	result, err := s.StatObject(ctx, upload.Key)
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("failed to read completed upload: %w", err)
+	}
+	return result, nil
+}
+
+// ReadObjectHead returns up to the first n bytes of an object, enough to
+// sniff its type
+func (s *S3Service) ReadObjectHead(ctx context.Context, key string, n int64) ([]byte, error) {
+	var data []byte
+	err := s.withRetry(ctx, "get object range", func(ctx context.Context) error {
+		out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucketName),
+			Key:    aws.String(key),
+			Range:  aws.String(fmt.Sprintf("bytes=0-%d", n-1)),
+		})
+		if err != nil {
+			return err
+		}
+		defer out.Body.Close()
+		data, err = io.ReadAll(io.LimitReader(out.Body, n))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return data, nil
+}
+
+// AbortMultipartUpload discards an upload and any parts already stored for it
+func (s *S3Service) AbortMultipartUpload(ctx context.Context, upload MultipartUpload) error {
+	err := s.withRetry(ctx, "abort multipart upload", func(ctx context.Context) error {
+		_, err := s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(s.bucketName),
+			Key:      aws.String(upload.Key),
+			UploadId: aws.String(upload.UploadID),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+	return nil
+}
+
+// AbortStaleMultipartUploads aborts every incomplete upload initiated more than olderThan ago
+func (s *S3Service) AbortStaleMultipartUploads(ctx context.Context, olderThan time.Duration) (int, error) {
+	cutoff := time.Now().Add(-olderThan)
+	aborted := 0
+
+	var keyMarker, uploadIDMarker *string
+	for {
+		var out *s3.ListMultipartUploadsOutput
+		err := s.withRetry(ctx, "list multipart uploads", func(ctx context.Context) error {
+			var err error
+			out, err = s.client.ListMultipartUploads(ctx, &s3.ListMultipartUploadsInput{
+				Bucket:         aws.String(s.bucketName),
+				KeyMarker:      keyMarker,
+				UploadIdMarker: uploadIDMarker,
+			})
+			return err
+		})
+		if err != nil {
+			return aborted, fmt.Errorf("failed to list multipart uploads: %w", err)
+		}
+
+		for _, u := range out.Uploads {
+			if u.Initiated == nil || u.Initiated.After(cutoff) {
+				continue
+			}
+			err := s.AbortMultipartUpload(ctx, MultipartUpload{
+				Key:      aws.ToString(u.Key),
+				UploadID: aws.ToString(u.UploadId),
+			})
+			if err != nil {
+				return aborted, err
+			}
+			aborted++
+		}
+
+		if !aws.ToBool(out.IsTruncated) {
+			return aborted, nil
+		}
+		keyMarker, uploadIDMarker = out.NextKeyMarker, out.NextUploadIdMarker
+	}
+}
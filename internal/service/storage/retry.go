@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RetryConfig controls how R2 operations are retried on transient failures
+type RetryConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryConfig is used when the service is created without an explicit config
+var DefaultRetryConfig = RetryConfig{
+	MaxAttempts: 4,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// ErrRetriesExhausted is returned when an operation kept failing with retryable errors
+var ErrRetriesExhausted = errors.New("storage operation failed after retries")
+
+// withRetry runs fn until it succeeds, fails with a non-retryable error,
+// runs out of attempts, or the context deadline would pass before the next attempt.
+func (s *S3Service) withRetry(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	cfg := s.retry
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < cfg.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			delay := backoffDelay(cfg, attempt)
+
+			// Don't start a sleep we know will outlive the caller
+			if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+				break
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("%s: %w (last error: %v)", op, ctx.Err(), lastErr)
+			case <-timer.C:
+			}
+		}
+
+		lastErr = fn(ctx)
+		if lastErr == nil {
+			return nil
+		}
+		if !isRetryable(lastErr) {
+			return lastErr
+		}
+
+		log.Warn().
+			Err(lastErr).
+			Str("op", op).
+			Int("attempt", attempt+1).
+			Int("max_attempts", cfg.MaxAttempts).
+			Msg("retryable storage error")
+	}
+
+	return fmt.Errorf("%s: %w: %v", op, ErrRetriesExhausted, lastErr)
+}
+
+// backoffDelay returns an exponential delay with full jitter for the given attempt (1-based)
+func backoffDelay(cfg RetryConfig, attempt int) time.Duration {
+	ceiling := cfg.BaseDelay << uint(attempt-1)
+	if ceiling <= 0 || ceiling > cfg.MaxDelay {
+		ceiling = cfg.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)) + 1)
+}
+
+// isRetryable reports whether err is worth retrying: 5xx, 429, network timeouts and
+// dropped connections are, 4xx responses and cancelled contexts are not.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var respErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &respErr) {
+		code := respErr.HTTPStatusCode()
+		return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests
+	}
+
+	// Connection resets and similar transport failures have no status code.
+	// They're checked before net.Error, which *net.OpError and *url.Error also
+	// implement but which only reports them retryable on a timeout.
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type statusError struct {
+	code int
+}
+
+func (e statusError) Error() string       { return "status error" }
+func (e statusError) HTTPStatusCode() int { return e.code }
+
+func TestIsRetryable(t *testing.T) {
+	require.True(t, isRetryable(statusError{code: 500}))
+	require.True(t, isRetryable(statusError{code: 503}))
+	require.True(t, isRetryable(statusError{code: 429}))
+	require.False(t, isRetryable(statusError{code: 403}))
+	require.False(t, isRetryable(statusError{code: 404}))
+	require.False(t, isRetryable(context.Canceled))
+	require.False(t, isRetryable(errors.New("boom")))
+
+	// A reset isn't a timeout, but the request never got an answer
+	reset := &url.Error{Op: "Put", URL: "https://r2.example/obj", Err: &net.OpError{
+		Op:  "read",
+		Net: "tcp",
+		Err: os.NewSyscallError("read", syscall.ECONNRESET),
+	}}
+	require.True(t, isRetryable(fmt.Errorf("upload: %w", reset)))
+	require.True(t, isRetryable(fmt.Errorf("upload: %w", io.ErrUnexpectedEOF)))
+	require.True(t, isRetryable(&url.Error{Op: "Put", URL: "https://r2.example/obj", Err: timeoutError{}}))
+	require.False(t, isRetryable(&url.Error{Op: "Put", URL: "https://r2.example/obj", Err: errors.New("bad url")}))
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestWithRetry(t *testing.T) {
+	s := &S3Service{retry: RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}}
+
+	calls := 0
+	err := s.withRetry(context.Background(), "test", func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return statusError{code: 502}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	calls = 0
+	err = s.withRetry(context.Background(), "test", func(ctx context.Context) error {
+		calls++
+		return statusError{code: 400}
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+
+	calls = 0
+	err = s.withRetry(context.Background(), "test", func(ctx context.Context) error {
+		calls++
+		return statusError{code: 500}
+	})
+	require.ErrorIs(t, err, ErrRetriesExhausted)
+	require.Equal(t, 3, calls)
+}
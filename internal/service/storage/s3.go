@@ -2,29 +2,60 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
-	"path/filepath"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/google/uuid"
 )
 
+// DefaultUploadURLExpiry is how long a presigned upload URL stays valid
+const DefaultUploadURLExpiry = 15 * time.Minute
+
 type Service interface {
-	UploadFile(ctx context.Context, file multipart.File, fileHeader *multipart.FileHeader) (string, error)
+	UploadFile(ctx context.Context, file multipart.File, fileHeader *multipart.FileHeader) (UploadResult, error)
+	GeneratePresignedUploadURL(ctx context.Context, keyPrefix, contentType, ext string) (uploadURL, publicURL string, key string, err error)
+	DeleteObject(ctx context.Context, key string) error
+	CreateMultipartUpload(ctx context.Context, keyPrefix, filename, contentType string) (MultipartUpload, error)
+	PresignUploadParts(ctx context.Context, upload MultipartUpload, partNumbers []int32) ([]PresignedPart, error)
+	CompleteMultipartUpload(ctx context.Context, upload MultipartUpload, parts []CompletedPart) (UploadResult, error)
+	ReadObjectHead(ctx context.Context, key string, n int64) ([]byte, error)
+	StatObject(ctx context.Context, key string) (UploadResult, error)
+	AbortMultipartUpload(ctx context.Context, upload MultipartUpload) error
+	AbortStaleMultipartUploads(ctx context.Context, olderThan time.Duration) (int, error)
+	PresignedGetURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+	UploadThumbnail(ctx context.Context, key string, data []byte, contentType string) (string, error)
 }
 
 type S3Service struct {
 	client     *s3.Client
+	presigner  *s3.PresignClient
 	bucketName string
 	endpoint   string
-	baseURL    string // Optional: custom domain for public access
+	baseURL    string // Public base URL such as a CDN domain; empty uses r2.dev
+	retry      RetryConfig
+	// private buckets store references (see PrivateRef) instead of public URLs
+	private bool
+	// uploadURLExpiry is how long URLs from GeneratePresignedUploadURL stay valid
+	uploadURLExpiry time.Duration
 }
 
-func NewS3Service(ctx context.Context, accountID, accessKey, secretKey, bucketName string) (Service, error) {
+// NewS3Service connects to an R2 bucket. Public URLs are built on
+// publicBaseURL, or https://<bucket>.r2.dev when it is empty. With private
+// set, uploads return private references that are presigned at response time.
+// A zero uploadURLExpiry uses DefaultUploadURLExpiry.
+func NewS3Service(ctx context.Context, accountID, accessKey, secretKey, bucketName, publicBaseURL string, private bool, uploadURLExpiry time.Duration) (Service, error) {
 	// R2 Endpoint: https://<accountid>.r2.cloudflarestorage.com
 	r2Endpoint := fmt.Sprintf("https://%s.r2.cloudflarestorage.com", accountID)
 
@@ -38,20 +69,55 @@ func NewS3Service(ctx context.Context, accountID, accessKey, secretKey, bucketNa
 
 	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
 		o.BaseEndpoint = aws.String(r2Endpoint)
+		// Retries are handled by withRetry so attempts don't multiply with the SDK's own retryer
+		o.Retryer = aws.NopRetryer{}
 	})
 
+	if uploadURLExpiry <= 0 {
+		uploadURLExpiry = DefaultUploadURLExpiry
+	}
+
 	return &S3Service{
-		client:     client,
-		bucketName: bucketName,
-		endpoint:   r2Endpoint,
+		client:          client,
+		presigner:       s3.NewPresignClient(client),
+		bucketName:      bucketName,
+		endpoint:        r2Endpoint,
+		baseURL:         strings.TrimRight(publicBaseURL, "/"),
+		retry:           DefaultRetryConfig,
+		private:         private,
+		uploadURLExpiry: uploadURLExpiry,
 	}, nil
 }
 
-// UploadFile uploads a multipart file to R2 and returns the public URL
-func (s *S3Service) UploadFile(ctx context.Context, file multipart.File, fileHeader *multipart.FileHeader) (string, error) {
-	// Generate unique filename
-	extension := filepath.Ext(fileHeader.Filename)
-	key := fmt.Sprintf("%s%s", uuid.New().String(), extension)
+// UploadResult describes a stored object
+type UploadResult struct {
+	URL         string
+	Key         string
+	Hash        string
+	Size        int64
+	ContentType string
+	// Deduplicated is true when identical content already existed and no bytes were sent
+	Deduplicated bool
+}
+
+// ContentKey is where UploadFile stores content with the given SHA-256
+func ContentKey(hash string) string {
+	return "media/" + hash
+}
+
+// UploadFile uploads a multipart file to R2 and returns the public URL.
+// Objects are keyed by the SHA-256 of their content, so identical files share one object.
+func (s *S3Service) UploadFile(ctx context.Context, file multipart.File, fileHeader *multipart.FileHeader) (UploadResult, error) {
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("failed to hash file: %w", err)
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	// The key is the hash alone: media_objects tracks content by hash, so the
+	// same bytes sent under another file name must land on the same object
+	key := ContentKey(hash)
 
 	// Determine Content-Type
 	contentType := fileHeader.Header.Get("Content-Type")
@@ -59,37 +125,155 @@ func (s *S3Service) UploadFile(ctx context.Context, file multipart.File, fileHea
 		contentType = "application/octet-stream"
 	}
 
-	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+	result := UploadResult{
+		URL:         s.mediaRef(key),
+		Key:         key,
+		Hash:        hash,
+		Size:        size,
+		ContentType: contentType,
+	}
+
+	exists, err := s.objectExists(ctx, key)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	if exists {
+		result.Deduplicated = true
+		return result, nil
+	}
+
+	err = s.withRetry(ctx, "put object", func(ctx context.Context) error {
+		// Rewind so a retried attempt sends the whole file again
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to rewind file: %w", err)
+		}
+		_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(s.bucketName),
+			Key:         aws.String(key),
+			Body:        file,
+			ContentType: aws.String(contentType),
+			// ACL is often not supported or needed for R2 depending on bucket settings, but public-read is common for S3
+			// ACL: types.ObjectCannedACLPublicRead,
+		})
+		return err
+	})
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("failed to upload file to S3: %w", err)
+	}
+
+	return result, nil
+}
+
+// GeneratePresignedUploadURL returns a URL the client PUTs a file to directly,
+// with contentType as its Content-Type, so the bytes never pass through this
+// server. The key is under keyPrefix, like a multipart upload's. publicURL is
+// what to send as media_url once the PUT succeeds: the public URL, or a
+// private reference for a private bucket.
+func (s *S3Service) GeneratePresignedUploadURL(ctx context.Context, keyPrefix, contentType, ext string) (uploadURL, publicURL string, key string, err error) {
+	// The content isn't known yet, so unlike UploadFile the key can't be its hash
+	key = fmt.Sprintf("%s/%s%s", keyPrefix, uuid.New().String(), strings.ToLower(ext))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	req, err := s.presigner.PresignPutObject(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(s.bucketName),
 		Key:         aws.String(key),
-		Body:        file,
 		ContentType: aws.String(contentType),
-		// ACL is often not supported or needed for R2 depending on bucket settings, but public-read is common for S3
-		// ACL: types.ObjectCannedACLPublicRead,
-	})
+	}, s3.WithPresignExpires(s.uploadURLExpiry))
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to presign upload: %w", err)
+	}
+
+	return req.URL, s.mediaRef(key), key, nil
+}
+
+// objectExists reports whether key is already stored in the bucket
+// ErrObjectNotFound is returned by StatObject for a key with no object
+var ErrObjectNotFound = errors.New("object not found")
 
+// StatObject describes an object written straight to the bucket, by a
+// presigned PUT or a multipart upload. Such objects live under a random key
+// rather than their content hash, so Hash is the key and they are never
+// deduplicated.
+func (s *S3Service) StatObject(ctx context.Context, key string) (UploadResult, error) {
+	var head *s3.HeadObjectOutput
+	err := s.withRetry(ctx, "head object", func(ctx context.Context) error {
+		var err error
+		head, err = s.client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(s.bucketName),
+			Key:    aws.String(key),
+		})
+		return err
+	})
+	if isNotFound(err) {
+		return UploadResult{}, ErrObjectNotFound
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to upload file to S3: %w", err)
+		return UploadResult{}, fmt.Errorf("failed to read object: %w", err)
 	}
 
-	// Construct public URL
-	// If the bucket allows public access via custom domain: https://<custom_domain>/<key>
-	// Or raw R2 dev URL (usually needs worker or public bucket setup)
-	// For now, we assume a public bucket url pattern or return the key.
-	// Let's assume standard R2 format or a configured public domain.
-	// Users usually configure a public domain like `media.locolive.com`.
-	// We'll return a relative path or full URL if ENV is set.
-	// Since we don't have the public domain env yet, let's construct a standard R2 public URL format if possible,
-	// OR better: use a placeholder domain that the user replaces.
+	return UploadResult{
+		URL:         s.mediaRef(key),
+		Key:         key,
+		Hash:        key,
+		Size:        aws.ToInt64(head.ContentLength),
+		ContentType: aws.ToString(head.ContentType),
+	}, nil
+}
 
-	// Default to R2.dev URL if testing, but ideally should be custom domain
-	// https://pub-<hash>.r2.dev/<key>
-	// Since we don't have the pub- hash, we'll return a format that the frontend can use or just the key?
-	// The frontend expects a full URL.
-	// Let's assume a generic one that needs config.
+func (s *S3Service) objectExists(ctx context.Context, key string) (bool, error) {
+	err := s.withRetry(ctx, "head object", func(ctx context.Context) error {
+		_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(s.bucketName),
+			Key:    aws.String(key),
+		})
+		return err
+	})
+	if err == nil {
+		return true, nil
+	}
+	if isNotFound(err) {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to check object: %w", err)
+}
+
+// isNotFound reports whether a HEAD failed because there is no such object
+func isNotFound(err error) bool {
+	var notFound *types.NotFound
+	var respErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &notFound) || (errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound)
+}
+
+// DeleteObject removes an object from the bucket
+func (s *S3Service) DeleteObject(ctx context.Context, key string) error {
+	err := s.withRetry(ctx, "delete object", func(ctx context.Context) error {
+		_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucketName),
+			Key:    aws.String(key),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	return nil
+}
 
-	// FIX: We will depend on an environment variable for PUBLIC_URL_BASE in real app.
-	// For this code, I will return `https://<bucket>.r2.dev/<key>` as a sensible default assumption for R2 public buckets.
+// publicURL returns the public URL for an object key
+func (s *S3Service) publicURL(key string) string {
+	if s.baseURL != "" {
+		return s.baseURL + "/" + key
+	}
+	return fmt.Sprintf("https://%s.r2.dev/%s", s.bucketName, key)
+}
 
-	return fmt.Sprintf("https://%s.r2.dev/%s", s.bucketName, key), nil
+// mediaRef is what rows store for an object: its public URL, or a private
+// reference when the bucket is private
+func (s *S3Service) mediaRef(key string) string {
+	if s.private {
+		return PrivateRef(key)
+	}
+	return s.publicURL(key)
 }
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePresignedUploadURL(t *testing.T) {
+	service, err := NewS3Service(context.Background(), "account", "access", "secret", "bucket", "", false, 0)
+	require.NoError(t, err)
+
+	uploadURL, publicURL, key, err := service.GeneratePresignedUploadURL(context.Background(), "user-1", "video/mp4", ".MP4")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(key, "user-1/"))
+	require.True(t, strings.HasSuffix(key, ".mp4"))
+	require.Equal(t, "https://bucket.r2.dev/"+key, publicURL)
+
+	parsed, err := url.Parse(uploadURL)
+	require.NoError(t, err)
+	require.Contains(t, parsed.Path, key)
+	require.Equal(t, "900", parsed.Query().Get("X-Amz-Expires"))
+
+	private, err := NewS3Service(context.Background(), "account", "access", "secret", "bucket", "", true, 0)
+	require.NoError(t, err)
+	_, ref, key, err := private.GeneratePresignedUploadURL(context.Background(), "user-1", "image/jpeg", ".jpg")
+	require.NoError(t, err)
+	require.Equal(t, PrivateRef(key), ref)
+}
+
+func TestPublicBaseURL(t *testing.T) {
+	testCases := []struct {
+		name    string
+		baseURL string
+		want    string
+	}{
+		{name: "Default", want: "https://bucket.r2.dev/media/abc.jpg"},
+		{name: "CustomDomain", baseURL: "https://media.example.com", want: "https://media.example.com/media/abc.jpg"},
+		{name: "TrailingSlash", baseURL: "https://cdn.example.com/locolive/", want: "https://cdn.example.com/locolive/media/abc.jpg"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, err := NewS3Service(context.Background(), "account", "access", "secret", "bucket", tc.baseURL, false, 0)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, service.(*S3Service).mediaRef("media/abc.jpg"))
+		})
+	}
+}
+
+func TestContentKey(t *testing.T) {
+	// No extension: the same bytes uploaded as photo.jpg and photo.png share one key
+	require.Equal(t, "media/abc123", ContentKey("abc123"))
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers webp with image.Decode; uploads allow it
+)
+
+const (
+	// ThumbnailWidth is the width thumbnails are scaled down to, keeping the aspect ratio
+	ThumbnailWidth = 400
+
+	thumbnailJPEGQuality = 80
+	// maxThumbnailPixels stops a small, highly compressed file from being
+	// decoded into a bitmap big enough to exhaust memory
+	maxThumbnailPixels = 50_000_000
+)
+
+var ErrImageTooLarge = errors.New("image is too large to thumbnail")
+
+// ThumbnailKey is where the thumbnail of the object at key is stored
+func ThumbnailKey(key string) string {
+	return "thumb/" + key
+}
+
+// MakeThumbnail decodes the image in r and scales it to ThumbnailWidth wide.
+// Images already that narrow are re-encoded as they are, never scaled up.
+// PNGs stay PNG to keep their transparency; everything else becomes JPEG.
+func MakeThumbnail(r io.ReadSeeker) (data []byte, contentType string, err error) {
+	cfg, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxThumbnailPixels {
+		return nil, "", ErrImageTooLarge
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, "", fmt.Errorf("failed to rewind image: %w", err)
+	}
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	thumb := src
+	if bounds := src.Bounds(); bounds.Dx() > ThumbnailWidth {
+		height := max(1, bounds.Dy()*ThumbnailWidth/bounds.Dx())
+		scaled := image.NewRGBA(image.Rect(0, 0, ThumbnailWidth, height))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), src, bounds, draw.Src, nil)
+		thumb = scaled
+	}
+
+	var buf bytes.Buffer
+	if format == "png" {
+		err = png.Encode(&buf, thumb)
+		contentType = "image/png"
+	} else {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: thumbnailJPEGQuality})
+		contentType = "image/jpeg"
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+// UploadThumbnail stores data as the thumbnail of the object at key and returns
+// its media reference. Objects are keyed by content hash, so a thumbnail that
+// is already stored is left as it is.
+func (s *S3Service) UploadThumbnail(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	thumbKey := ThumbnailKey(key)
+
+	exists, err := s.objectExists(ctx, thumbKey)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return s.mediaRef(thumbKey), nil
+	}
+
+	err = s.withRetry(ctx, "put thumbnail", func(ctx context.Context) error {
+		_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(s.bucketName),
+			Key:         aws.String(thumbKey),
+			Body:        bytes.NewReader(data),
+			ContentType: aws.String(contentType),
+		})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload thumbnail to S3: %w", err)
+	}
+	return s.mediaRef(thumbKey), nil
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeThumbnail(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 1000, 250))
+
+	var pngBuf, jpegBuf bytes.Buffer
+	require.NoError(t, png.Encode(&pngBuf, src))
+	require.NoError(t, jpeg.Encode(&jpegBuf, src, nil))
+
+	testCases := []struct {
+		name     string
+		input    []byte
+		wantType string
+	}{
+		{name: "PNGStaysPNG", input: pngBuf.Bytes(), wantType: "image/png"},
+		{name: "JPEG", input: jpegBuf.Bytes(), wantType: "image/jpeg"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, contentType, err := MakeThumbnail(bytes.NewReader(tc.input))
+			require.NoError(t, err)
+			require.Equal(t, tc.wantType, contentType)
+
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Equal(t, ThumbnailWidth, cfg.Width)
+			require.Equal(t, 100, cfg.Height)
+		})
+	}
+
+	_, _, err := MakeThumbnail(bytes.NewReader([]byte("not an image")))
+	require.Error(t, err)
+
+	require.Equal(t, "thumb/media/abc.png", ThumbnailKey("media/abc.png"))
+}
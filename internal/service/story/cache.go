@@ -0,0 +1,72 @@
+package story
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"privacy-social-backend/internal/util"
+)
+
+// Feed results are personalised (blocks, own stories, audience), so they are cached
+// per user within a geohash cell. Rather than hunting down every user's key when a
+// cell or a user changes, each carries a generation counter that is part of the
+// cache key: bumping it orphans the old entries, which then age out via their TTL.
+
+// FeedCellGenerationKey holds the generation counter for a geohash cell
+func FeedCellGenerationKey(geohash string) string {
+	return util.RedisKey("feed:gen:cell:" + geohash)
+}
+
+// FeedUserGenerationKey holds the generation counter for a user's feeds
+func FeedUserGenerationKey(userID uuid.UUID) string {
+	return util.RedisKey("feed:gen:user:" + userID.String())
+}
+
+// FeedCacheKey returns the current cache key for userID's feed of radius meters in a geohash cell
+func FeedCacheKey(ctx context.Context, rdb *redis.Client, geohash string, radius float64, userID uuid.UUID) (string, error) {
+	gens, err := feedGenerations(ctx, rdb, geohash, userID)
+	if err != nil {
+		return "", err
+	}
+	return util.RedisKey(fmt.Sprintf("feed:%s:%.0f:%s:%s", geohash, radius, userID, gens)), nil
+}
+
+// HashtagCacheKey returns the current cache key for userID's hashtag results in a
+// geohash cell. It shares the feed generations, so anything that expires the feed
+// expires tag results too.
+func HashtagCacheKey(ctx context.Context, rdb *redis.Client, tag, geohash string, userID uuid.UUID) (string, error) {
+	gens, err := feedGenerations(ctx, rdb, geohash, userID)
+	if err != nil {
+		return "", err
+	}
+	return util.RedisKey(fmt.Sprintf("feed:tag:%s:%s:%s:%s", tag, geohash, userID, gens)), nil
+}
+
+// feedGenerations renders the cell and user generation counters for a cache key
+func feedGenerations(ctx context.Context, rdb *redis.Client, geohash string, userID uuid.UUID) (string, error) {
+	gens, err := rdb.MGet(ctx, FeedCellGenerationKey(geohash), FeedUserGenerationKey(userID)).Result()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v:%v", generation(gens[0]), generation(gens[1])), nil
+}
+
+// InvalidateFeedCell expires every cached feed for a geohash cell
+func InvalidateFeedCell(ctx context.Context, rdb *redis.Client, geohash string) {
+	rdb.Incr(ctx, FeedCellGenerationKey(geohash))
+}
+
+// InvalidateUserFeeds expires every cached feed for a user
+func InvalidateUserFeeds(ctx context.Context, rdb *redis.Client, userID uuid.UUID) {
+	rdb.Incr(ctx, FeedUserGenerationKey(userID))
+}
+
+func generation(v interface{}) interface{} {
+	if v == nil {
+		return "0"
+	}
+	return v
+}
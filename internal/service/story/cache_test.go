@@ -0,0 +1,132 @@
+package story
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/mmcloughlin/geohash"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"privacy-social-backend/internal/repository/db"
+	mockdb "privacy-social-backend/internal/repository/mock"
+	"privacy-social-backend/internal/service/safety"
+)
+
+func newTestRedis(t *testing.T) *redis.Client {
+	return redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+}
+
+func mustFeedCacheKey(t *testing.T, rdb *redis.Client, cell string, userID uuid.UUID) string {
+	key, err := FeedCacheKey(context.Background(), rdb, cell, 3000, userID)
+	require.NoError(t, err)
+	return key
+}
+
+func TestFeedCacheKeyPerUser(t *testing.T) {
+	rdb := newTestRedis(t)
+	alice, bob := uuid.New(), uuid.New()
+
+	// Feeds are personalised, so two users in one cell never share an entry
+	require.NotEqual(t, mustFeedCacheKey(t, rdb, "tdr1v", alice), mustFeedCacheKey(t, rdb, "tdr1v", bob))
+
+	// The key is stable until something bumps a generation
+	require.Equal(t, mustFeedCacheKey(t, rdb, "tdr1v", alice), mustFeedCacheKey(t, rdb, "tdr1v", alice))
+}
+
+func TestInvalidateUserFeeds(t *testing.T) {
+	ctx := context.Background()
+	rdb := newTestRedis(t)
+	alice, bob := uuid.New(), uuid.New()
+
+	aliceBefore := mustFeedCacheKey(t, rdb, "tdr1v", alice)
+	aliceElsewhere := mustFeedCacheKey(t, rdb, "u4pru", alice)
+	bobBefore := mustFeedCacheKey(t, rdb, "tdr1v", bob)
+
+	InvalidateUserFeeds(ctx, rdb, alice)
+
+	// Every cell's entry for the user moves on, other users' entries don't
+	require.NotEqual(t, aliceBefore, mustFeedCacheKey(t, rdb, "tdr1v", alice))
+	require.NotEqual(t, aliceElsewhere, mustFeedCacheKey(t, rdb, "u4pru", alice))
+	require.Equal(t, bobBefore, mustFeedCacheKey(t, rdb, "tdr1v", bob))
+}
+
+func TestInvalidateFeedCell(t *testing.T) {
+	ctx := context.Background()
+	rdb := newTestRedis(t)
+	alice, bob := uuid.New(), uuid.New()
+
+	aliceBefore := mustFeedCacheKey(t, rdb, "tdr1v", alice)
+	bobBefore := mustFeedCacheKey(t, rdb, "tdr1v", bob)
+	otherCell := mustFeedCacheKey(t, rdb, "u4pru", alice)
+
+	InvalidateFeedCell(ctx, rdb, "tdr1v")
+
+	// Every user's entry for the cell moves on, other cells' entries don't
+	require.NotEqual(t, aliceBefore, mustFeedCacheKey(t, rdb, "tdr1v", alice))
+	require.NotEqual(t, bobBefore, mustFeedCacheKey(t, rdb, "tdr1v", bob))
+	require.Equal(t, otherCell, mustFeedCacheKey(t, rdb, "u4pru", alice))
+}
+
+func TestStoryChangesInvalidateFeedCell(t *testing.T) {
+	const lat, lng = 12.9716, 77.5946
+	cell := geohash.Encode(lat, lng)[:5]
+	authorID := uuid.New()
+	viewerID := uuid.New()
+	storyID := uuid.New()
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		change     func(svc Service) error
+	}{
+		{
+			name: "Create",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserByID(gomock.Any(), authorID).Times(1).Return(db.User{ID: authorID}, nil)
+				store.EXPECT().CreateStory(gomock.Any(), gomock.Any()).Times(1).Return(db.CreateStoryRow{ID: storyID}, nil)
+				store.EXPECT().UpdateUserActivity(gomock.Any(), authorID).Times(1).Return(db.User{}, nil)
+				store.EXPECT().DeleteStoryHashtags(gomock.Any(), storyID).Times(1).Return(nil)
+			},
+			change: func(svc Service) error {
+				_, err := svc.CreateStory(context.Background(), CreateStoryParams{
+					UserID:    authorID,
+					MediaURL:  "https://cdn.example.com/media/abc",
+					MediaType: "image",
+					Latitude:  lat,
+					Longitude: lng,
+				})
+				return err
+			},
+		},
+		{
+			name: "Delete",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStoryByID(gomock.Any(), storyID).Times(1).
+					Return(db.GetStoryByIDRow{ID: storyID, UserID: authorID, Geohash: geohash.Encode(lat, lng)}, nil)
+				store.EXPECT().DeleteStory(gomock.Any(), storyID).Times(1).Return(nil)
+			},
+			change: func(svc Service) error {
+				return svc.DeleteStory(context.Background(), storyID, authorID)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			rdb := newTestRedis(t)
+			svc := NewService(store, rdb, safety.NewMonitor(rdb, safety.MonitorConfig{}), nil, FeedConfig{}, AnonymousConfig{})
+
+			before := mustFeedCacheKey(t, rdb, cell, viewerID)
+			require.NoError(t, tc.change(svc))
+			require.NotEqual(t, before, mustFeedCacheKey(t, rdb, cell, viewerID))
+		})
+	}
+}
@@ -0,0 +1,407 @@
+//go:build integration
+
+package story
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcloughlin/geohash"
+	"github.com/stretchr/testify/require"
+
+	"privacy-social-backend/internal/repository"
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/testutil"
+	"privacy-social-backend/internal/util"
+)
+
+// These tests run against a freshly migrated PostGIS database per test:
+//
+//	make test-env-up test-integration
+
+// fixedNow is the reference time every feed query in these tests uses
+var fixedNow = time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func newIntegrationStore(t *testing.T) repository.Store {
+	return repository.NewStore(testutil.NewTestDB(t))
+}
+
+// testOrigin is a random spot per test so rows from other runs never fall inside the radius
+type testOrigin struct {
+	lat, lng float64
+}
+
+func newTestOrigin() testOrigin {
+	return testOrigin{lat: -60 + rand.Float64()*120, lng: -170 + rand.Float64()*340}
+}
+
+func createTestUser(t *testing.T, store repository.Store) db.User {
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		Phone:        "+91" + util.RandomString(10),
+		PasswordHash: "x",
+		Username:     "feed_" + util.RandomString(10),
+		FullName:     "Feed Test",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { store.DeleteUser(context.Background(), user.ID) })
+	return user
+}
+
+// createTestStory places a public story northOffsetMeters north of origin, expiring at expiresAt
+func createTestStory(t *testing.T, store repository.Store, origin testOrigin, userID uuid.UUID, northOffsetMeters float64, expiresAt time.Time) uuid.UUID {
+	return createAudienceTestStory(t, store, origin, userID, northOffsetMeters, expiresAt, db.StoryAvailabilityPublic)
+}
+
+func createAudienceTestStory(t *testing.T, store repository.Store, origin testOrigin, userID uuid.UUID, northOffsetMeters float64, expiresAt time.Time, audience db.StoryAvailability) uuid.UUID {
+	lat := origin.lat + northOffsetMeters/111320.0
+	story, err := store.CreateStory(context.Background(), db.CreateStoryParams{
+		UserID:       userID,
+		MediaUrl:     "https://example.com/" + util.RandomString(8) + ".jpg",
+		MediaType:    "image",
+		Geohash:      geohash.Encode(lat, origin.lng),
+		Lat:          lat,
+		Lng:          origin.lng,
+		ShowLocation: true,
+		IsPremium:    sql.NullBool{Valid: true},
+		ExpiresAt:    expiresAt,
+		Visibility:   audience,
+	})
+	require.NoError(t, err)
+	return story.ID
+}
+
+func newFeedService(store repository.Store) Service {
+	return NewService(store, nil, nil, nil, FeedConfig{Now: func() time.Time { return fixedNow }, RadiusStep: 500}, AnonymousConfig{})
+}
+
+func feedIDs(feed *FeedResult) []uuid.UUID {
+	ids := make([]uuid.UUID, len(feed.Stories))
+	for i, r := range feed.Stories {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func TestGetFeedOrderingAndRadius(t *testing.T) {
+	store := newIntegrationStore(t)
+	svc := newFeedService(store)
+	origin := newTestOrigin()
+
+	viewer := createTestUser(t, store)
+	author := createTestUser(t, store)
+
+	live := fixedNow.Add(time.Hour)
+	near := createTestStory(t, store, origin, author.ID, 100, live)
+	mid := createTestStory(t, store, origin, author.ID, 1500, live)
+	far := createTestStory(t, store, origin, author.ID, 8000, live)
+
+	feed, err := svc.GetFeed(context.Background(), GetFeedParams{
+		UserID:       viewer.ID,
+		Latitude:     origin.lat,
+		Longitude:    origin.lng,
+		RadiusMeters: 2000,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2000.0, feed.Radius)
+	require.Equal(t, []uuid.UUID{near, mid}, feedIDs(feed))
+
+	// Widening the radius brings the far story in, still nearest first
+	feed, err = svc.GetFeed(context.Background(), GetFeedParams{
+		UserID:       viewer.ID,
+		Latitude:     origin.lat,
+		Longitude:    origin.lng,
+		RadiusMeters: 10000,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{near, mid, far}, feedIDs(feed))
+}
+
+func TestGetFeedExpiry(t *testing.T) {
+	store := newIntegrationStore(t)
+	svc := newFeedService(store)
+	origin := newTestOrigin()
+
+	viewer := createTestUser(t, store)
+	author := createTestUser(t, store)
+
+	live := createTestStory(t, store, origin, author.ID, 50, fixedNow.Add(time.Minute))
+	expired := createTestStory(t, store, origin, author.ID, 60, fixedNow.Add(-time.Minute))
+
+	feed, err := svc.GetFeed(context.Background(), GetFeedParams{
+		UserID:       viewer.ID,
+		Latitude:     origin.lat,
+		Longitude:    origin.lng,
+		RadiusMeters: 500,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{live}, feedIDs(feed))
+
+	// An explicit reference time overrides the service clock
+	feed, err = svc.GetFeed(context.Background(), GetFeedParams{
+		UserID:       viewer.ID,
+		Latitude:     origin.lat,
+		Longitude:    origin.lng,
+		RadiusMeters: 500,
+		Now:          fixedNow.Add(-time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{live, expired}, feedIDs(feed))
+}
+
+func TestGetFeedExcludesBlocked(t *testing.T) {
+	store := newIntegrationStore(t)
+	svc := newFeedService(store)
+	origin := newTestOrigin()
+
+	viewer := createTestUser(t, store)
+	blocked := createTestUser(t, store)
+	blocker := createTestUser(t, store)
+	other := createTestUser(t, store)
+
+	live := fixedNow.Add(time.Hour)
+	createTestStory(t, store, origin, blocked.ID, 10, live)
+	createTestStory(t, store, origin, blocker.ID, 20, live)
+	visible := createTestStory(t, store, origin, other.ID, 30, live)
+
+	_, err := store.BlockUser(context.Background(), db.BlockUserParams{BlockerID: viewer.ID, BlockedID: blocked.ID})
+	require.NoError(t, err)
+	_, err = store.BlockUser(context.Background(), db.BlockUserParams{BlockerID: blocker.ID, BlockedID: viewer.ID})
+	require.NoError(t, err)
+
+	feed, err := svc.GetFeed(context.Background(), GetFeedParams{
+		UserID:       viewer.ID,
+		Latitude:     origin.lat,
+		Longitude:    origin.lng,
+		RadiusMeters: 500,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{visible}, feedIDs(feed))
+}
+
+func TestGetFeedStoryAudience(t *testing.T) {
+	store := newIntegrationStore(t)
+	svc := newFeedService(store)
+	origin := newTestOrigin()
+	ctx := context.Background()
+
+	author := createTestUser(t, store)
+	closeFriend := createTestUser(t, store)
+	connection := createTestUser(t, store)
+	stranger := createTestUser(t, store)
+
+	for _, userID := range []uuid.UUID{closeFriend.ID, connection.ID} {
+		_, err := store.CreateConnectionRequest(ctx, db.CreateConnectionRequestParams{RequesterID: author.ID, TargetID: userID})
+		require.NoError(t, err)
+		_, err = store.UpdateConnectionStatus(ctx, db.UpdateConnectionStatusParams{
+			RequesterID: author.ID,
+			TargetID:    userID,
+			Status:      db.ConnectionStatusAccepted,
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, store.AddCloseFriend(ctx, db.AddCloseFriendParams{UserID: author.ID, FriendID: closeFriend.ID}))
+
+	live := fixedNow.Add(time.Hour)
+	public := createTestStory(t, store, origin, author.ID, 10, live)
+	connections := createAudienceTestStory(t, store, origin, author.ID, 20, live, db.StoryAvailabilityConnections)
+	closeFriends := createAudienceTestStory(t, store, origin, author.ID, 30, live, db.StoryAvailabilityCloseFriends)
+
+	feedFor := func(userID uuid.UUID) []uuid.UUID {
+		feed, err := svc.GetFeed(ctx, GetFeedParams{
+			UserID:       userID,
+			Latitude:     origin.lat,
+			Longitude:    origin.lng,
+			RadiusMeters: 500,
+		})
+		require.NoError(t, err)
+		return feedIDs(feed)
+	}
+
+	require.Equal(t, []uuid.UUID{public, connections, closeFriends}, feedFor(author.ID))
+	require.Equal(t, []uuid.UUID{public, connections, closeFriends}, feedFor(closeFriend.ID))
+	require.Equal(t, []uuid.UUID{public, connections}, feedFor(connection.ID))
+	require.Equal(t, []uuid.UUID{public}, feedFor(stranger.ID))
+
+	// Disconnecting takes the friend off the close friends list too
+	require.NoError(t, store.DeleteConnection(ctx, db.DeleteConnectionParams{RequesterID: closeFriend.ID, TargetID: author.ID}))
+	require.Equal(t, []uuid.UUID{public}, feedFor(closeFriend.ID))
+}
+
+func TestGetFeedHidesHeldStories(t *testing.T) {
+	store := newIntegrationStore(t)
+	svc := newFeedService(store)
+	origin := newTestOrigin()
+	ctx := context.Background()
+
+	viewer := createTestUser(t, store)
+	author := createTestUser(t, store)
+
+	held := createTestStory(t, store, origin, author.ID, 10, fixedNow.Add(time.Hour))
+	require.NoError(t, store.CreateStoryModerationHold(ctx, db.CreateStoryModerationHoldParams{
+		StoryID: held,
+		Reason:  AnonymousHoldReason,
+	}))
+
+	feedFor := func(userID uuid.UUID) []uuid.UUID {
+		feed, err := svc.GetFeed(ctx, GetFeedParams{
+			UserID:       userID,
+			Latitude:     origin.lat,
+			Longitude:    origin.lng,
+			RadiusMeters: 500,
+		})
+		require.NoError(t, err)
+		return feedIDs(feed)
+	}
+
+	// Only the author sees a held story until it is released
+	require.Empty(t, feedFor(viewer.ID))
+	require.Equal(t, []uuid.UUID{held}, feedFor(author.ID))
+
+	released, err := store.ReleaseStoryModerationHold(ctx, held)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, released)
+	require.Equal(t, []uuid.UUID{held}, feedFor(viewer.ID))
+}
+
+func TestGetFeedSince(t *testing.T) {
+	store := newIntegrationStore(t)
+	svc := newFeedService(store)
+	origin := newTestOrigin()
+
+	viewer := createTestUser(t, store)
+	author := createTestUser(t, store)
+
+	live := fixedNow.Add(time.Hour)
+	seen := createTestStory(t, store, origin, author.ID, 10, live)
+	fresh := createTestStory(t, store, origin, author.ID, 20, live)
+
+	seenStory, err := store.GetStoryByID(context.Background(), seen)
+	require.NoError(t, err)
+
+	// Only stories posted after the cursor come back
+	feed, err := svc.GetFeed(context.Background(), GetFeedParams{
+		UserID:       viewer.ID,
+		Latitude:     origin.lat,
+		Longitude:    origin.lng,
+		RadiusMeters: 500,
+		Since:        seenStory.CreatedAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{fresh}, feedIDs(feed))
+	require.Equal(t, fixedNow, feed.AsOf)
+}
+
+func TestGetFeedHashtag(t *testing.T) {
+	store := newIntegrationStore(t)
+	svc := newFeedService(store)
+	origin := newTestOrigin()
+
+	viewer := createTestUser(t, store)
+	author := createTestUser(t, store)
+
+	live := fixedNow.Add(time.Hour)
+	tagged := createTestStory(t, store, origin, author.ID, 10, live)
+	createTestStory(t, store, origin, author.ID, 20, live)
+
+	tag := "feed" + util.RandomString(8)
+	require.NoError(t, svc.IndexHashtags(context.Background(), tagged, "golden hour #"+tag))
+
+	feed, err := svc.GetFeed(context.Background(), GetFeedParams{
+		UserID:       viewer.ID,
+		Latitude:     origin.lat,
+		Longitude:    origin.lng,
+		RadiusMeters: 500,
+		Hashtag:      tag,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{tagged}, feedIDs(feed))
+}
+
+func TestTrendingHashtags(t *testing.T) {
+	store := newIntegrationStore(t)
+	svc := newFeedService(store)
+	origin := newTestOrigin()
+
+	author := createTestUser(t, store)
+
+	live := fixedNow.Add(time.Hour)
+	first := createTestStory(t, store, origin, author.ID, 10, live)
+	second := createTestStory(t, store, origin, author.ID, 20, live)
+	expired := createTestStory(t, store, origin, author.ID, 30, fixedNow.Add(-time.Minute))
+
+	popular := "pop" + util.RandomString(8)
+	niche := "niche" + util.RandomString(8)
+	require.NoError(t, svc.IndexHashtags(context.Background(), first, "#"+popular+" #"+niche))
+	require.NoError(t, svc.IndexHashtags(context.Background(), second, "#"+popular))
+	require.NoError(t, svc.IndexHashtags(context.Background(), expired, "#"+niche))
+
+	trending, err := svc.TrendingHashtags(context.Background(), TrendingHashtagsParams{
+		UserID:       author.ID,
+		Latitude:     origin.lat,
+		Longitude:    origin.lng,
+		RadiusMeters: 500,
+	})
+	require.NoError(t, err)
+	require.Len(t, trending, 2)
+	require.Equal(t, popular, trending[0].Tag)
+	require.Equal(t, int64(2), trending[0].StoryCount)
+	require.Equal(t, niche, trending[1].Tag)
+	require.Equal(t, int64(1), trending[1].StoryCount)
+}
+
+func TestTrendingHashtagsFollowsFeedRules(t *testing.T) {
+	store := newIntegrationStore(t)
+	svc := newFeedService(store)
+	origin := newTestOrigin()
+	ctx := context.Background()
+
+	viewer := createTestUser(t, store)
+	blocked := createTestUser(t, store)
+	author := createTestUser(t, store)
+	friend := createTestUser(t, store)
+
+	_, err := store.BlockUser(ctx, db.BlockUserParams{BlockerID: viewer.ID, BlockedID: blocked.ID})
+	require.NoError(t, err)
+	_, err = store.CreateConnectionRequest(ctx, db.CreateConnectionRequestParams{RequesterID: author.ID, TargetID: friend.ID})
+	require.NoError(t, err)
+	_, err = store.UpdateConnectionStatus(ctx, db.UpdateConnectionStatusParams{
+		RequesterID: author.ID,
+		TargetID:    friend.ID,
+		Status:      db.ConnectionStatusAccepted,
+	})
+	require.NoError(t, err)
+
+	live := fixedNow.Add(time.Hour)
+	public := createTestStory(t, store, origin, author.ID, 10, live)
+	connections := createAudienceTestStory(t, store, origin, author.ID, 20, live, db.StoryAvailabilityConnections)
+	fromBlocked := createTestStory(t, store, origin, blocked.ID, 30, live)
+
+	open := "open" + util.RandomString(8)
+	inner := "inner" + util.RandomString(8)
+	hidden := "hidden" + util.RandomString(8)
+	require.NoError(t, svc.IndexHashtags(ctx, public, "#"+open))
+	require.NoError(t, svc.IndexHashtags(ctx, connections, "#"+inner))
+	require.NoError(t, svc.IndexHashtags(ctx, fromBlocked, "#"+hidden))
+
+	tagsFor := func(userID uuid.UUID) []string {
+		trending, err := svc.TrendingHashtags(ctx, TrendingHashtagsParams{
+			UserID:       userID,
+			Latitude:     origin.lat,
+			Longitude:    origin.lng,
+			RadiusMeters: 500,
+		})
+		require.NoError(t, err)
+		tags := make([]string, len(trending))
+		for i, tag := range trending {
+			tags[i] = tag.Tag
+		}
+		return tags
+	}
+
+	require.ElementsMatch(t, []string{open}, tagsFor(viewer.ID))
+	require.ElementsMatch(t, []string{open, inner, hidden}, tagsFor(friend.ID))
+}
@@ -0,0 +1,40 @@
+package story
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedRadius(t *testing.T) {
+	svc := NewService(nil, nil, nil, nil, FeedConfig{DefaultRadius: 3000, MaxRadius: 20000, RadiusStep: 2000}, AnonymousConfig{})
+
+	testCases := []struct {
+		name      string
+		requested float64
+		want      float64
+	}{
+		{name: "Default", requested: 0, want: 3000},
+		{name: "OnStep", requested: 4000, want: 4000},
+		{name: "RoundsUp", requested: 4100, want: 6000},
+		{name: "Max", requested: 20000, want: 20000},
+		{name: "BelowDefault", requested: 500, want: 3000},
+		{name: "AboveMax", requested: 20001, want: 20000},
+		{name: "FarAboveMax", requested: 1e9, want: 20000},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, svc.FeedRadius(tc.requested))
+		})
+	}
+}
+
+func TestFeedRadiusDefaultCappedAtMax(t *testing.T) {
+	svc := NewService(nil, nil, nil, nil, FeedConfig{MaxRadius: 2000}, AnonymousConfig{})
+
+	require.Equal(t, 2000.0, svc.FeedRadius(0))
+
+	// A step larger than the max never rounds past it
+	require.Equal(t, 2000.0, svc.FeedRadius(1200))
+}
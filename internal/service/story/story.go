@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,6 +16,7 @@ import (
 	"privacy-social-backend/internal/repository"
 	"privacy-social-backend/internal/repository/db"
 	"privacy-social-backend/internal/service/safety"
+	"privacy-social-backend/internal/util"
 )
 
 type CreateStoryParams struct {
@@ -26,48 +28,173 @@ type CreateStoryParams struct {
 	Caption      string
 	IsAnonymous  bool
 	ShowLocation bool
+	// Audience limits who sees the story; empty means public
+	Audience db.StoryAvailability
+	Client   safety.ClientHints
 }
 
 type GetFeedParams struct {
 	UserID    uuid.UUID
 	Latitude  float64
 	Longitude float64
+	// Now is the reference time for expiry; zero uses the service clock
+	Now time.Time
+	// RadiusMeters overrides the search radius; zero uses the configured default.
+	// It is rounded up to the configured step and may not exceed the maximum.
+	RadiusMeters float64
+	// Since limits the feed to stories posted after it; zero returns the full feed
+	Since time.Time
+	// Hashtag limits the feed to stories tagged with it (normalized, without "#")
+	Hashtag string
+}
+
+const (
+	// DefaultFeedRadius is the feed search radius when none is requested
+	DefaultFeedRadius = 50000.0 // 50km
+	// DefaultFeedMaxRadius is the largest radius a client may request
+	DefaultFeedMaxRadius = 50000.0 // 50km hard cap
+	// DefaultFeedRadiusStep is what requested radii are rounded up to, so nearby
+	// requests share cached results
+	DefaultFeedRadiusStep = 5000.0 // 5km
+	// DefaultFeedResultLimit caps how many stories a single feed request returns
+	DefaultFeedResultLimit = 50
+)
+
+// FeedResult is one page of nearby stories
+type FeedResult struct {
+	Stories []db.GetStoriesWithinRadiusRow
+	Message string
+	Radius  float64
+	// AsOf is the reference time of this result; clients pass it back as Since
+	AsOf time.Time
+	// Total is how many stories matched before the result limit was applied
+	Total int64
+	// Truncated is true when Total exceeds len(Stories)
+	Truncated bool
+}
+
+// FeedConfig controls which nearby stories the feed returns
+type FeedConfig struct {
+	// ConnectionsOnly limits the feed to the user's own stories, accepted
+	// connections and authors who opted into a public audience
+	ConnectionsOnly bool
+	// ResultLimit caps stories per request; zero uses DefaultFeedResultLimit
+	ResultLimit int
+	// DefaultRadius applies when a request has no radius; zero uses DefaultFeedRadius.
+	// It is capped at MaxRadius.
+	DefaultRadius float64
+	// MaxRadius is the largest radius a request gets; larger ones are clamped to
+	// it. Zero uses DefaultFeedMaxRadius.
+	MaxRadius float64
+	// RadiusStep rounds requested radii up to a multiple of it; zero uses
+	// DefaultFeedRadiusStep. Cached feeds are shared within a 5-char geohash cell
+	// (~4.9km across), so a viewer can be that far from the point a cached feed was
+	// computed for: radii much smaller than the cell trade precision for cache hits.
+	RadiusStep float64
+	// Now is the service clock; nil uses util.Now (UTC). Tests inject a fixed time.
+	Now func() time.Time
+}
+
+const (
+	// DefaultAnonymousDailyLimit caps anonymous stories per author when none is configured
+	DefaultAnonymousDailyLimit = 3
+	// AnonymousHoldReason marks holds placed on every new anonymous story
+	AnonymousHoldReason = "anonymous"
+)
+
+var (
+	ErrAnonymousStoryLimit = errors.New("anonymous story limit reached, try again later")
+	ErrAnonymousStoryLinks = errors.New("anonymous stories can't contain links")
+	// ErrAnonymousStoryAudience is returned for anonymous stories with a narrower
+	// audience than public: a small audience would all but name the author
+	ErrAnonymousStoryAudience = errors.New("anonymous stories must be public")
+	// ErrSafetyRejected is deliberately vague so it doesn't teach abusers which signal fired
+	ErrSafetyRejected = errors.New("story could not be posted right now")
+)
+
+// AnonymousConfig limits anonymous posting. Limits apply to the real author, so
+// they hold even though readers never see who posted.
+type AnonymousConfig struct {
+	// DailyLimit caps anonymous stories per author in any 24 hours; zero uses DefaultAnonymousDailyLimit
+	DailyLimit int
 }
 
 type Service interface {
 	CreateStory(ctx context.Context, params CreateStoryParams) (*db.CreateStoryRow, error)
-	GetFeed(ctx context.Context, params GetFeedParams) ([]db.GetStoriesWithinRadiusRow, string, float64, error)
+	CheckAnonymousStory(ctx context.Context, userID uuid.UUID, caption string) error
+	HoldAnonymousStory(ctx context.Context, storyID uuid.UUID) error
+	GetFeed(ctx context.Context, params GetFeedParams) (*FeedResult, error)
+	FeedRadius(requested float64) float64
 	DeleteStory(ctx context.Context, storyID uuid.UUID, userID uuid.UUID) error
+	IndexHashtags(ctx context.Context, storyID uuid.UUID, caption string) error
+	TrendingHashtags(ctx context.Context, params TrendingHashtagsParams) ([]TrendingHashtag, error)
 }
 
 type ServiceImpl struct {
-	store  repository.Store
-	redis  *redis.Client
-	safety *safety.Monitor
+	store     repository.Store
+	redis     *redis.Client
+	safety    *safety.Monitor
+	enforcer  *safety.Enforcer
+	feed      FeedConfig
+	anonymous AnonymousConfig
 }
 
-func NewService(store repository.Store, rdb *redis.Client, safety *safety.Monitor) Service {
+func NewService(store repository.Store, rdb *redis.Client, safety *safety.Monitor, enforcer *safety.Enforcer, feed FeedConfig, anonymous AnonymousConfig) Service {
+	if feed.Now == nil {
+		feed.Now = util.Now
+	}
+	if feed.ResultLimit <= 0 {
+		feed.ResultLimit = DefaultFeedResultLimit
+	}
+	if feed.MaxRadius <= 0 {
+		feed.MaxRadius = DefaultFeedMaxRadius
+	}
+	if feed.RadiusStep <= 0 {
+		feed.RadiusStep = DefaultFeedRadiusStep
+	}
+	if feed.DefaultRadius <= 0 {
+		feed.DefaultRadius = DefaultFeedRadius
+	}
+	feed.DefaultRadius = math.Min(feed.DefaultRadius, feed.MaxRadius)
+	if anonymous.DailyLimit <= 0 {
+		anonymous.DailyLimit = DefaultAnonymousDailyLimit
+	}
 	return &ServiceImpl{
-		store:  store,
-		redis:  rdb,
-		safety: safety,
+		store:     store,
+		redis:     rdb,
+		safety:    safety,
+		enforcer:  enforcer,
+		feed:      feed,
+		anonymous: anonymous,
 	}
 }
 
 func (s *ServiceImpl) CreateStory(ctx context.Context, req CreateStoryParams) (*db.CreateStoryRow, error) {
+	if req.Audience == "" {
+		req.Audience = db.StoryAvailabilityPublic
+	}
+	if req.IsAnonymous {
+		if req.Audience != db.StoryAvailabilityPublic {
+			return nil, ErrAnonymousStoryAudience
+		}
+		if err := s.CheckAnonymousStory(ctx, req.UserID, req.Caption); err != nil {
+			return nil, err
+		}
+	}
+
 	hash := geohash.Encode(req.Latitude, req.Longitude)
 
-	// Safety Check: Fake GPS
-	val := s.safety.ValidateUserMovement(ctx, req.UserID.String(), req.Latitude, req.Longitude)
+	// Safety Check: Fake GPS, spoofing hints, rapid posting
+	val := s.safety.Evaluate(ctx, safety.Event{
+		UserID: req.UserID.String(),
+		Action: safety.ActionPostStory,
+		Lat:    req.Latitude,
+		Lng:    req.Longitude,
+		Client: req.Client,
+	})
 	if !val.Allowed {
-		if val.ShouldBan {
-			log.Warn().
-				Str("user_id", req.UserID.String()).
-				Float64("lat", req.Latitude).
-				Float64("lng", req.Longitude).
-				Msg("Fake GPS detected (Dev Bypass: User not banned)")
-			// Logic for banning can be added here if needed
-		}
+		s.enforcer.Apply(ctx, req.UserID, safety.ActionPostStory, val)
+		return nil, ErrSafetyRejected
 	}
 
 	// Get user to check premium status
@@ -83,14 +210,14 @@ func (s *ServiceImpl) CreateStory(ctx context.Context, req CreateStoryParams) (*
 		expiryDuration = 48 * time.Hour
 		isPremium = true
 	}
-	expiresAt := time.Now().UTC().Add(expiryDuration)
+	expiresAt := s.feed.Now().UTC().Add(expiryDuration)
 
 	var captionNull sql.NullString
 	if req.Caption != "" {
 		captionNull = sql.NullString{String: req.Caption, Valid: true}
 	}
 
-	story, err := s.store.CreateStory(ctx, db.CreateStoryParams{
+	arg := db.CreateStoryParams{
 		UserID:       req.UserID,
 		MediaUrl:     req.MediaURL,
 		MediaType:    req.MediaType,
@@ -102,7 +229,25 @@ func (s *ServiceImpl) CreateStory(ctx context.Context, req CreateStoryParams) (*
 		ShowLocation: req.ShowLocation,
 		IsPremium:    sql.NullBool{Bool: isPremium, Valid: true},
 		ExpiresAt:    expiresAt,
-	})
+		Visibility:   req.Audience,
+	}
+
+	var story db.CreateStoryRow
+	if req.IsAnonymous {
+		// The hold goes in with the story so it is never visible unreviewed
+		err = s.store.ExecTx(ctx, func(q *db.Queries) error {
+			story, err = q.CreateStory(ctx, arg)
+			if err != nil {
+				return err
+			}
+			return q.CreateStoryModerationHold(ctx, db.CreateStoryModerationHoldParams{
+				StoryID: story.ID,
+				Reason:  AnonymousHoldReason,
+			})
+		})
+	} else {
+		story, err = s.store.CreateStory(ctx, arg)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -113,11 +258,11 @@ func (s *ServiceImpl) CreateStory(ctx context.Context, req CreateStoryParams) (*
 		log.Error().Err(err).Msg("Failed to update user activity")
 	}
 
-	// Create mentions if caption has @username
-	// NOTE: In a real service, this might be async or handled by another service method
-	// keeping it simple here, skipping the async mentions creation for now or assuming handler handles it?
-	// The original code did `go server.createStoryMentions`. We should probably expose that or do it here.
-	// For now, let's omit the async mention part to keep this pure or add a placeholder.
+	// @username mentions are created by the API handler, which can push them over WebSocket
+
+	if err := s.IndexHashtags(ctx, story.ID, req.Caption); err != nil {
+		log.Error().Err(err).Str("story_id", story.ID.String()).Msg("Failed to index story hashtags")
+	}
 
 	// Invalidate feed cache for the area
 	userGeohash := hash
@@ -129,7 +274,36 @@ func (s *ServiceImpl) CreateStory(ctx context.Context, req CreateStoryParams) (*
 	return &story, nil
 }
 
-func (s *ServiceImpl) GetFeed(ctx context.Context, params GetFeedParams) ([]db.GetStoriesWithinRadiusRow, string, float64, error) {
+// CheckAnonymousStory applies the content and rate rules for anonymous posting
+// to a story userID is about to post, or make anonymous, with caption
+func (s *ServiceImpl) CheckAnonymousStory(ctx context.Context, userID uuid.UUID, caption string) error {
+	if util.ContainsLink(caption) {
+		return ErrAnonymousStoryLinks
+	}
+
+	count, err := s.store.CountAnonymousStoriesSince(ctx, db.CountAnonymousStoriesSinceParams{
+		UserID: userID,
+		Since:  s.feed.Now().UTC().Add(-24 * time.Hour),
+	})
+	if err != nil {
+		return err
+	}
+	if count >= int64(s.anonymous.DailyLimit) {
+		return ErrAnonymousStoryLimit
+	}
+	return nil
+}
+
+// HoldAnonymousStory keeps an existing story that was just made anonymous out of
+// feeds until a moderator releases it
+func (s *ServiceImpl) HoldAnonymousStory(ctx context.Context, storyID uuid.UUID) error {
+	return s.store.CreateStoryModerationHold(ctx, db.CreateStoryModerationHoldParams{
+		StoryID: storyID,
+		Reason:  AnonymousHoldReason,
+	})
+}
+
+func (s *ServiceImpl) GetFeed(ctx context.Context, params GetFeedParams) (*FeedResult, error) {
 	// Create cache key based on user's geohash (5 chars = ~2.4km precision)
 	// Cache logic currently disabled in service layer
 	// userGeohash := geohash.Encode(params.Latitude, params.Longitude)
@@ -152,26 +326,47 @@ func (s *ServiceImpl) GetFeed(ctx context.Context, params GetFeedParams) ([]db.G
 
 	// Let's implement the DB logic loop (the one we want to optimize later).
 
-	// Optimized: Single query with K-NN (Limit 50 relevant stories within 50km)
+	// Optimized: Single query with K-NN (nearest ResultLimit stories within the radius)
 	// The database query now uses <-> operator for efficient nearest-neighbor search
-	const maxRadius = 50000.0 // 50km hard cap
+	now := params.Now
+	if now.IsZero() {
+		now = s.feed.Now()
+	}
+	radius := s.FeedRadius(params.RadiusMeters)
 
 	stories, err := s.store.GetStoriesWithinRadius(ctx, db.GetStoriesWithinRadiusParams{
-		Lng:          params.Longitude,
-		Lat:          params.Latitude,
-		RadiusMeters: maxRadius,
-		UserID:       params.UserID,
+		Lng:             params.Longitude,
+		Lat:             params.Latitude,
+		RadiusMeters:    radius,
+		Now:             now,
+		UserID:          params.UserID,
+		ConnectionsOnly: s.feed.ConnectionsOnly,
+		ResultLimit:     int32(s.feed.ResultLimit),
+		Since:           util.NullTime{Time: params.Since, Valid: !params.Since.IsZero()},
+		Hashtag:         sql.NullString{String: params.Hashtag, Valid: params.Hashtag != ""},
 	})
 	if err != nil {
-		return nil, "", 0, err
+		return nil, err
 	}
 
-	message := "Stories found nearby"
+	result := &FeedResult{
+		Stories: stories,
+		Message: "Stories found nearby",
+		Radius:  radius,
+		AsOf:    now,
+	}
 	if len(stories) == 0 {
-		message = "No stories found within 50km"
+		result.Message = fmt.Sprintf("No stories found within %.0fkm", radius/1000)
+		if !params.Since.IsZero() {
+			result.Message = fmt.Sprintf("No new stories within %.0fkm", radius/1000)
+		}
+	} else {
+		// Every row carries the same window count
+		result.Total = stories[0].TotalCount
+		result.Truncated = result.Total > int64(len(stories))
 	}
 
-	return stories, message, maxRadius, nil
+	return result, nil
 }
 
 func (s *ServiceImpl) DeleteStory(ctx context.Context, storyID uuid.UUID, userID uuid.UUID) error {
@@ -202,9 +397,36 @@ func (s *ServiceImpl) DeleteStory(ctx context.Context, storyID uuid.UUID, userID
 	return nil
 }
 
+// IndexHashtags replaces a story's hashtag index with the tags in caption
+func (s *ServiceImpl) IndexHashtags(ctx context.Context, storyID uuid.UUID, caption string) error {
+	if err := s.store.DeleteStoryHashtags(ctx, storyID); err != nil {
+		return err
+	}
+
+	for _, tag := range util.ParseHashtags(caption) {
+		err := s.store.CreateStoryHashtag(ctx, db.CreateStoryHashtagParams{
+			StoryID: storyID,
+			Tag:     tag,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FeedRadius resolves a requested feed radius: zero means the default, anything
+// else is rounded up to the radius step and clamped into [default, max]
+func (s *ServiceImpl) FeedRadius(requested float64) float64 {
+	if requested <= 0 {
+		return s.feed.DefaultRadius
+	}
+	radius := math.Ceil(requested/s.feed.RadiusStep) * s.feed.RadiusStep
+	return math.Min(math.Max(radius, s.feed.DefaultRadius), s.feed.MaxRadius)
+}
+
 func (s *ServiceImpl) invalidateFeedCache(ctx context.Context, geohash string) {
-	cacheKey := "feed:" + geohash
-	s.redis.Del(ctx, cacheKey)
+	InvalidateFeedCell(ctx, s.redis, geohash)
 }
 
 // Helper to replace cached JSON logic?
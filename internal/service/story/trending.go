@@ -0,0 +1,72 @@
+package story
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/repository/db"
+)
+
+const (
+	// DefaultTrendingRadius is the trending search radius when none is requested
+	DefaultTrendingRadius = 10000.0 // 10km
+	// TrendingWindow is the "recent" slice of a story's lifetime that drives velocity
+	TrendingWindow = time.Hour
+	// DefaultTrendingLimit caps how many hashtags are returned
+	DefaultTrendingLimit = 20
+)
+
+type TrendingHashtagsParams struct {
+	// UserID is the viewer; only stories they could see in their feed count
+	UserID    uuid.UUID
+	Latitude  float64
+	Longitude float64
+	// RadiusMeters overrides the search radius; zero uses DefaultTrendingRadius
+	RadiusMeters float64
+}
+
+// TrendingHashtag is a tag's usage on live stories in the area
+type TrendingHashtag struct {
+	Tag string `json:"tag"`
+	// StoryCount is how many live stories in the area carry the tag
+	StoryCount int64 `json:"story_count"`
+	// RecentCount is how many of those were tagged within TrendingWindow
+	RecentCount int64 `json:"recent_count"`
+	// Velocity is RecentCount per hour
+	Velocity float64 `json:"velocity"`
+}
+
+// TrendingHashtags ranks hashtags on live stories around a point by how fast they're being used
+func (s *ServiceImpl) TrendingHashtags(ctx context.Context, params TrendingHashtagsParams) ([]TrendingHashtag, error) {
+	radius := params.RadiusMeters
+	if radius <= 0 {
+		radius = DefaultTrendingRadius
+	}
+	now := s.feed.Now()
+
+	rows, err := s.store.GetTrendingHashtags(ctx, db.GetTrendingHashtagsParams{
+		RecentSince:  now.Add(-TrendingWindow),
+		Now:          now,
+		Lng:          params.Longitude,
+		Lat:          params.Latitude,
+		RadiusMeters: radius,
+		UserID:       params.UserID,
+		ResultLimit:  DefaultTrendingLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	trending := make([]TrendingHashtag, len(rows))
+	for i, row := range rows {
+		trending[i] = TrendingHashtag{
+			Tag:         row.Tag,
+			StoryCount:  row.StoryCount,
+			RecentCount: row.RecentCount,
+			Velocity:    float64(row.RecentCount) / TrendingWindow.Hours(),
+		}
+	}
+	return trending, nil
+}
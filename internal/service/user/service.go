@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,10 +23,17 @@ type CreateUserParams struct {
 }
 
 type LoginUserParams struct {
-	Phone     string
-	Password  string
-	UserAgent string
-	ClientIP  string
+	Phone    string
+	Password string
+	Device   SessionDevice
+}
+
+// SessionDevice describes the client a session was created from
+type SessionDevice struct {
+	UserAgent  string
+	ClientIP   string
+	DeviceName string
+	Platform   string
 }
 
 type LoginUserResult struct {
@@ -37,6 +45,30 @@ type LoginUserResult struct {
 	User                  db.User
 }
 
+// RenewAccessTokenResult is a fresh access token issued for a session
+type RenewAccessTokenResult struct {
+	AccessToken          string
+	AccessTokenExpiresAt time.Time
+}
+
+// DeletedAccount is what a deleted account leaves behind in other users'
+// cached data
+type DeletedAccount struct {
+	// ContactIDs are the users it was connected to or messaged with
+	ContactIDs []uuid.UUID
+	// StoryGeohashes are where its stories were posted
+	StoryGeohashes []string
+}
+
+// AccountReauth is how a user proves it's them before deleting their account:
+// their password, or for a Google sign-in account the subject of a Google ID
+// token the caller has just verified. Google accounts are created with a random
+// password nobody knows, so they have no other way.
+type AccountReauth struct {
+	Password  string
+	GoogleSub string
+}
+
 type UpdateEmailParams struct {
 	UserID uuid.UUID
 	Email  string
@@ -45,10 +77,17 @@ type UpdateEmailParams struct {
 type Service interface {
 	CreateUser(ctx context.Context, params CreateUserParams) (db.User, error)
 	LoginUser(ctx context.Context, params LoginUserParams) (*LoginUserResult, error)
+	StartSession(ctx context.Context, user db.User, device SessionDevice) (*LoginUserResult, error)
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]db.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	Logout(ctx context.Context, userID, sessionID uuid.UUID) error
+	LogoutAll(ctx context.Context, userID uuid.UUID) (int64, error)
+	RenewAccessToken(ctx context.Context, refreshToken string) (*RenewAccessTokenResult, error)
 	UpdateEmail(ctx context.Context, params UpdateEmailParams) (db.User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (db.User, error)
 	UpdatePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string) error
-	SearchUsers(ctx context.Context, query string) ([]db.SearchUsersRow, error)
+	DeleteAccount(ctx context.Context, userID uuid.UUID, reauth AccountReauth) (*DeletedAccount, error)
+	SearchUsers(ctx context.Context, viewerID uuid.UUID, query string) ([]db.SearchUsersRow, error)
 }
 
 type ServiceImpl struct {
@@ -60,8 +99,22 @@ type ServiceImpl struct {
 type TokenConfig struct {
 	AccessTokenDuration  time.Duration
 	RefreshTokenDuration time.Duration
+	// MaxSessionsPerUser caps concurrent sessions; the oldest are revoked beyond it.
+	// Zero or negative leaves sessions unlimited.
+	MaxSessionsPerUser int
 }
 
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionBlocked  = errors.New("session blocked")
+	ErrSessionExpired  = errors.New("session expired")
+	ErrTokenMismatch   = errors.New("token mismatch")
+	// ErrInvalidRefreshToken wraps whatever made a refresh token fail verification
+	ErrInvalidRefreshToken   = errors.New("invalid refresh token")
+	ErrIncorrectPassword     = errors.New("incorrect password")
+	ErrGoogleAccountMismatch = errors.New("google account does not match this account")
+)
+
 func NewService(store repository.Store, tokenMaker token.Maker, config TokenConfig) Service {
 	return &ServiceImpl{
 		store:      store,
@@ -105,6 +158,12 @@ func (s *ServiceImpl) LoginUser(ctx context.Context, req LoginUserParams) (*Logi
 		return nil, errors.New("incorrect password")
 	}
 
+	return s.StartSession(ctx, user, req.Device)
+}
+
+// StartSession issues tokens for user and records the session, revoking the
+// user's oldest sessions when that takes them over MaxSessionsPerUser
+func (s *ServiceImpl) StartSession(ctx context.Context, user db.User, device SessionDevice) (*LoginUserResult, error) {
 	accessToken, accessPayload, err := s.tokenMaker.CreateToken(user.Username, user.ID, s.config.AccessTokenDuration)
 	if err != nil {
 		return nil, err
@@ -115,14 +174,33 @@ func (s *ServiceImpl) LoginUser(ctx context.Context, req LoginUserParams) (*Logi
 		return nil, err
 	}
 
-	session, err := s.store.CreateSession(ctx, db.CreateSessionParams{
-		ID:           refreshPayload.ID,
-		UserID:       user.ID,
-		RefreshToken: refreshToken,
-		UserAgent:    req.UserAgent,
-		ClientIp:     req.ClientIP,
-		IsBlocked:    false,
-		ExpiresAt:    refreshPayload.ExpiredAt,
+	var session db.Session
+	// One transaction so concurrent logins can't both slip under the cap
+	err = s.store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		session, err = q.CreateSession(ctx, db.CreateSessionParams{
+			ID:           refreshPayload.ID,
+			UserID:       user.ID,
+			RefreshToken: refreshToken,
+			UserAgent:    device.UserAgent,
+			ClientIp:     device.ClientIP,
+			IsBlocked:    false,
+			ExpiresAt:    refreshPayload.ExpiredAt,
+			DeviceName:   device.DeviceName,
+			Platform:     device.Platform,
+		})
+		if err != nil {
+			return err
+		}
+
+		if s.config.MaxSessionsPerUser <= 0 {
+			return nil
+		}
+		_, err = q.RevokeExcessSessions(ctx, db.RevokeExcessSessionsParams{
+			UserID:      user.ID,
+			MaxSessions: int32(s.config.MaxSessionsPerUser),
+		})
+		return err
 	})
 	if err != nil {
 		return nil, err
@@ -138,6 +216,85 @@ func (s *ServiceImpl) LoginUser(ctx context.Context, req LoginUserParams) (*Logi
 	}, nil
 }
 
+// ListSessions returns the user's active sessions, newest first
+func (s *ServiceImpl) ListSessions(ctx context.Context, userID uuid.UUID) ([]db.Session, error) {
+	return s.store.ListActiveSessions(ctx, userID)
+}
+
+// RevokeSession ends one of the user's sessions
+func (s *ServiceImpl) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	n, err := s.store.RevokeSession(ctx, db.RevokeSessionParams{
+		ID:     sessionID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// Logout blocks one of the user's sessions so its refresh token stops working.
+// Logging out of an already blocked session succeeds.
+func (s *ServiceImpl) Logout(ctx context.Context, userID, sessionID uuid.UUID) error {
+	n, err := s.store.BlockSession(ctx, db.BlockSessionParams{
+		ID:     sessionID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// LogoutAll blocks every session of the user and returns how many were still active
+func (s *ServiceImpl) LogoutAll(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.store.BlockUserSessions(ctx, userID)
+}
+
+// RenewAccessToken issues a new access token for the session a refresh token
+// belongs to, as long as the session is still active and the token is the one
+// it was created with
+func (s *ServiceImpl) RenewAccessToken(ctx context.Context, refreshToken string) (*RenewAccessTokenResult, error) {
+	refreshPayload, err := s.tokenMaker.VerifyToken(refreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRefreshToken, err)
+	}
+
+	// A session's ID is the ID of its refresh token
+	session, err := s.store.GetSession(ctx, refreshPayload.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	if session.IsBlocked {
+		return nil, ErrSessionBlocked
+	}
+	if !util.Now().Before(session.ExpiresAt) {
+		return nil, ErrSessionExpired
+	}
+	if session.UserID != refreshPayload.UserID || session.RefreshToken != refreshToken {
+		return nil, ErrTokenMismatch
+	}
+
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(refreshPayload.Username, session.UserID, s.config.AccessTokenDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RenewAccessTokenResult{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+	}, nil
+}
+
 func (s *ServiceImpl) UpdateEmail(ctx context.Context, req UpdateEmailParams) (db.User, error) {
 	_, err := s.store.UpdateUserEmail(ctx, db.UpdateUserEmailParams{
 		ID:    req.UserID,
@@ -186,6 +343,54 @@ func (s *ServiceImpl) UpdatePassword(ctx context.Context, userID uuid.UUID, curr
 	return err
 }
 
-func (s *ServiceImpl) SearchUsers(ctx context.Context, query string) ([]db.SearchUsersRow, error) {
-	return s.store.SearchUsers(ctx, query)
+// DeleteAccount deletes a user once reauth checks out. It runs in one
+// transaction: groups the user created pass to another member (or go, if they
+// have none), then deleting the user cascades to their stories, messages,
+// locations, sessions, reactions and group memberships. Deleting the sessions
+// stops their refresh tokens working straight away.
+func (s *ServiceImpl) DeleteAccount(ctx context.Context, userID uuid.UUID, reauth AccountReauth) (*DeletedAccount, error) {
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if reauth.GoogleSub != "" {
+		if !user.GoogleID.Valid || user.GoogleID.String != reauth.GoogleSub {
+			return nil, ErrGoogleAccountMismatch
+		}
+	} else if err := util.CheckPassword(reauth.Password, user.PasswordHash); err != nil {
+		return nil, ErrIncorrectPassword
+	}
+
+	var deleted DeletedAccount
+	err = s.store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		deleted.ContactIDs, err = q.ListUserContacts(ctx, userID)
+		if err != nil {
+			return err
+		}
+		deleted.StoryGeohashes, err = q.ListUserStoryGeohashes(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := q.DeleteUserSoleGroups(ctx, userID); err != nil {
+			return err
+		}
+		if err := q.TransferUserGroups(ctx, userID); err != nil {
+			return err
+		}
+		return q.DeleteUser(ctx, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &deleted, nil
+}
+
+// SearchUsers finds users by username or name, hiding those the viewer
+// blocked or was blocked by
+func (s *ServiceImpl) SearchUsers(ctx context.Context, viewerID uuid.UUID, query string) ([]db.SearchUsersRow, error) {
+	return s.store.SearchUsers(ctx, db.SearchUsersParams{
+		Query:    query,
+		ViewerID: viewerID,
+	})
 }
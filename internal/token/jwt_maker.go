@@ -29,14 +29,32 @@ func (maker *JWTMaker) CreateToken(username string, userID uuid.UUID, duration t
 	if err != nil {
 		return "", payload, err
 	}
+	return maker.signPayload(payload)
+}
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+// CreateImpersonationToken creates a read-only token letting impersonatorID act as userID
+func (maker *JWTMaker) CreateImpersonationToken(username string, userID, impersonatorID uuid.UUID, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, userID, duration)
+	if err != nil {
+		return "", payload, err
+	}
+	payload.ImpersonatorID = impersonatorID
+	return maker.signPayload(payload)
+}
+
+func (maker *JWTMaker) signPayload(payload *Payload) (string, *Payload, error) {
+	claims := jwt.MapClaims{
 		"id":         payload.ID.String(),
 		"user_id":    payload.UserID.String(),
 		"username":   payload.Username,
 		"issued_at":  payload.IssuedAt.Format(time.RFC3339Nano),
 		"expired_at": payload.ExpiredAt.Format(time.RFC3339Nano),
-	})
+	}
+	if payload.IsImpersonation() {
+		claims["impersonator_id"] = payload.ImpersonatorID.String()
+	}
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	token, err := jwtToken.SignedString([]byte(maker.secretKey))
 	return token, payload, err
@@ -108,12 +126,26 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
+	// Parse impersonator_id (only present on impersonation tokens)
+	var impersonatorID uuid.UUID
+	if impersonatorStr, present := claims["impersonator_id"]; present {
+		impersonatorIDStr, ok := impersonatorStr.(string)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		impersonatorID, err = uuid.Parse(impersonatorIDStr)
+		if err != nil || impersonatorID == uuid.Nil {
+			return nil, ErrInvalidToken
+		}
+	}
+
 	payload := &Payload{
-		ID:        id,
-		UserID:    userID,
-		Username:  username,
-		IssuedAt:  issuedAt,
-		ExpiredAt: expiredAt,
+		ID:             id,
+		UserID:         userID,
+		Username:       username,
+		IssuedAt:       issuedAt,
+		ExpiredAt:      expiredAt,
+		ImpersonatorID: impersonatorID,
 	}
 
 	// Check if token is expired
@@ -31,6 +31,31 @@ func TestJWTMaker(t *testing.T) {
 	require.WithinDuration(t, payload.ExpiredAt, payload2.ExpiredAt, time.Second)
 }
 
+func TestJWTMakerImpersonation(t *testing.T) {
+	maker, err := NewJWTMaker("12345678901234567890123456789012")
+	require.NoError(t, err)
+
+	userID := uuid.New()
+	adminID := uuid.New()
+	token, payload, err := maker.CreateImpersonationToken("testuser", userID, adminID, time.Minute)
+	require.NoError(t, err)
+	require.True(t, payload.IsImpersonation())
+
+	payload2, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, userID, payload2.UserID)
+	require.Equal(t, adminID, payload2.ImpersonatorID)
+	require.True(t, payload2.IsImpersonation())
+
+	// Regular tokens carry no impersonation claim
+	token, _, err = maker.CreateToken("testuser", userID, time.Minute)
+	require.NoError(t, err)
+
+	payload3, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.False(t, payload3.IsImpersonation())
+}
+
 func TestExpiredJWTToken(t *testing.T) {
 	maker, err := NewJWTMaker("12345678901234567890123456789012")
 	require.NoError(t, err)
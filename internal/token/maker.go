@@ -11,6 +11,9 @@ type Maker interface {
 	// CreateToken creates a new token for a specific username and duration
 	CreateToken(username string, userID uuid.UUID, duration time.Duration) (string, *Payload, error)
 
+	// CreateImpersonationToken creates a read-only token letting impersonatorID act as userID
+	CreateImpersonationToken(username string, userID, impersonatorID uuid.UUID, duration time.Duration) (string, *Payload, error)
+
 	// VerifyToken checks if the token is valid or not
 	VerifyToken(token string) (*Payload, error)
 }
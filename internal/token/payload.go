@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"privacy-social-backend/internal/util"
 )
 
 // Different types of error returned by the VerifyToken function
@@ -20,10 +22,17 @@ type Payload struct {
 	Username  string    `json:"username"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
+	// ImpersonatorID is the admin acting as UserID; set only on read-only support tokens
+	ImpersonatorID uuid.UUID `json:"impersonator_id"`
 }
 
 // NewPayload creates a new token payload with a specific username and duration
 func NewPayload(username string, userID uuid.UUID, duration time.Duration) (*Payload, error) {
+	return newPayloadAt(username, userID, duration, util.Now())
+}
+
+// newPayloadAt creates a payload issued at now; expiry is an absolute UTC instant
+func newPayloadAt(username string, userID uuid.UUID, duration time.Duration, now time.Time) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -33,15 +42,24 @@ func NewPayload(username string, userID uuid.UUID, duration time.Duration) (*Pay
 		ID:        tokenID,
 		UserID:    userID,
 		Username:  username,
-		IssuedAt:  time.Now(),
-		ExpiredAt: time.Now().Add(duration),
+		IssuedAt:  now.UTC(),
+		ExpiredAt: now.UTC().Add(duration),
 	}
 	return payload, nil
 }
 
+// IsImpersonation reports whether the token was minted for an admin viewing as the user
+func (payload *Payload) IsImpersonation() bool {
+	return payload.ImpersonatorID != uuid.Nil
+}
+
 // Valid checks if the token payload is valid or not
 func (payload *Payload) Valid() error {
-	if time.Now().After(payload.ExpiredAt) {
+	return payload.validAt(util.Now())
+}
+
+func (payload *Payload) validAt(now time.Time) error {
+	if now.After(payload.ExpiredAt) {
 		return ErrExpiredToken
 	}
 	return nil
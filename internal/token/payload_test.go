@@ -0,0 +1,35 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadExpiryAcrossTimezoneBoundary(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// 01:50 EST, ten minutes before clocks jump to 03:00 EDT
+	issued := time.Date(2024, 3, 10, 1, 50, 0, 0, newYork)
+	payload, err := newPayloadAt("testuser", uuid.New(), 15*time.Minute, issued)
+	require.NoError(t, err)
+
+	// Expiry is the absolute instant 15 minutes later, stored in UTC
+	require.Equal(t, time.UTC, payload.IssuedAt.Location())
+	require.Equal(t, time.UTC, payload.ExpiredAt.Location())
+	require.True(t, payload.ExpiredAt.Equal(time.Date(2024, 3, 10, 7, 5, 0, 0, time.UTC)))
+	// Wall clock in New York reads 03:05, not 02:05
+	require.Equal(t, 3, payload.ExpiredAt.In(newYork).Hour())
+
+	require.NoError(t, payload.validAt(issued.Add(14*time.Minute)))
+	require.NoError(t, payload.validAt(issued.Add(15*time.Minute)))
+	require.ErrorIs(t, payload.validAt(issued.Add(15*time.Minute+time.Second)), ErrExpiredToken)
+
+	// A clock reporting the same instant in another zone agrees
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+	require.ErrorIs(t, payload.validAt(issued.Add(16*time.Minute).In(kolkata)), ErrExpiredToken)
+}
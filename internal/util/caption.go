@@ -0,0 +1,97 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MaxHashtagLength bounds what ends up in story_hashtags.tag
+	MaxHashtagLength = 50
+	// MaxHashtagsPerCaption stops one caption from flooding the hashtag index
+	MaxHashtagsPerCaption = 10
+)
+
+var (
+	mentionPattern = regexp.MustCompile(`@(\w+)`)
+	hashtagPattern = regexp.MustCompile(`#([\p{L}\p{N}_]+)`)
+	// hashtagBodyPattern matches a whole tag without its "#"
+	hashtagBodyPattern = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+	// linkPattern matches URLs with a scheme, "www." hosts and bare domains such as example.com/x
+	linkPattern = regexp.MustCompile(`(?i)\b(?:[a-z][a-z0-9+.-]*://|www\.)\S+|\b[a-z0-9-]+(?:\.[a-z0-9-]+)*\.(?:[a-z]{2,24})\b(?:/\S*)?`)
+	// linkTLDs are the top-level domains a bare "name.tld" must end in to count as a link
+	linkTLDs = map[string]bool{
+		"com": true, "net": true, "org": true, "io": true, "co": true, "me": true, "ly": true,
+		"app": true, "xyz": true, "info": true, "biz": true, "link": true, "site": true,
+		"online": true, "gg": true, "tv": true, "in": true, "ru": true, "to": true, "cc": true,
+	}
+)
+
+// ParseMentions extracts the distinct @username mentions from a caption, lowercased
+func ParseMentions(text string) []string {
+	return parseCaptionTokens(mentionPattern, text, 0, nil)
+}
+
+// ParseHashtags extracts the distinct #hashtags from a caption, lowercased and
+// without the "#". Purely numeric or overlong tags are ignored.
+func ParseHashtags(text string) []string {
+	return parseCaptionTokens(hashtagPattern, text, MaxHashtagsPerCaption, validHashtag)
+}
+
+// NormalizeHashtag turns user input such as "#Sunset" into the indexed form,
+// returning "" when it isn't a valid hashtag
+func NormalizeHashtag(tag string) string {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if !hashtagBodyPattern.MatchString(tag) || !validHashtag(tag) {
+		return ""
+	}
+	return tag
+}
+
+// ContainsLink reports whether text holds something that looks like an external
+// link: a URL with a scheme, a "www." host or a bare domain such as bit.ly/x
+func ContainsLink(text string) bool {
+	for _, match := range linkPattern.FindAllString(text, -1) {
+		lower := strings.ToLower(match)
+		if strings.Contains(lower, "://") || strings.HasPrefix(lower, "www.") {
+			return true
+		}
+		host, _, _ := strings.Cut(lower, "/")
+		if linkTLDs[host[strings.LastIndex(host, ".")+1:]] {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCaptionTokens(re *regexp.Regexp, text string, limit int, valid func(string) bool) []string {
+	tokens := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, match := range re.FindAllStringSubmatch(text, -1) {
+		token := strings.ToLower(match[1])
+		if seen[token] || (valid != nil && !valid(token)) {
+			continue
+		}
+		seen[token] = true
+		tokens = append(tokens, token)
+		if limit > 0 && len(tokens) == limit {
+			break
+		}
+	}
+
+	return tokens
+}
+
+func validHashtag(tag string) bool {
+	if len([]rune(tag)) > MaxHashtagLength {
+		return false
+	}
+	for _, r := range tag {
+		if !unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMentions(t *testing.T) {
+	require.Equal(t, []string{"alice", "bob"}, ParseMentions("hi @Alice and @bob, also @alice"))
+	require.Empty(t, ParseMentions("no mentions here"))
+}
+
+func TestParseHashtags(t *testing.T) {
+	require.Equal(t, []string{"sunset", "goa_beach", "café"}, ParseHashtags("#Sunset at #goa_beach #SUNSET #café"))
+	require.Equal(t, []string{"day1"}, ParseHashtags("#2024 #day1"))
+	require.Empty(t, ParseHashtags("#"+strings.Repeat("a", MaxHashtagLength+1)))
+
+	var many []string
+	for i := 0; i < MaxHashtagsPerCaption+5; i++ {
+		many = append(many, "#tag"+RandomString(6))
+	}
+	require.Len(t, ParseHashtags(strings.Join(many, " ")), MaxHashtagsPerCaption)
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	require.Equal(t, "sunset", NormalizeHashtag("#Sunset"))
+	require.Equal(t, "sunset", NormalizeHashtag(" sunset "))
+	require.Equal(t, "", NormalizeHashtag("sun set"))
+	require.Equal(t, "", NormalizeHashtag("123"))
+	require.Equal(t, "", NormalizeHashtag(""))
+}
+
+func TestContainsLink(t *testing.T) {
+	require.True(t, ContainsLink("see https://example.com/x"))
+	require.True(t, ContainsLink("WWW.Example.org"))
+	require.True(t, ContainsLink("dm me at bit.ly/abc"))
+	require.True(t, ContainsLink("join t.me/somegroup"))
+	require.False(t, ContainsLink("sunset at the beach... so good"))
+	require.False(t, ContainsLink("3.5 stars #goa"))
+	require.False(t, ContainsLink("end of sentence.next one"))
+}
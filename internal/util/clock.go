@@ -0,0 +1,61 @@
+package util
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Clock supplies the current time. Expiry and timestamps go through a Clock so
+// they are always UTC, whatever the host or database timezone.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// SystemClock is the real clock, in UTC
+var SystemClock Clock = systemClock{}
+
+// FixedClock always returns T (in UTC); useful for tests
+type FixedClock struct {
+	T time.Time
+}
+
+func (c FixedClock) Now() time.Time {
+	return c.T.UTC()
+}
+
+// Now returns the current time in UTC
+func Now() time.Time {
+	return SystemClock.Now()
+}
+
+// IsExpired reports whether t has passed at now. A NULL time never expires.
+// Times are compared as absolute instants, so their zones don't matter.
+func IsExpired(t NullTime, now time.Time) bool {
+	return t.Valid && !now.Before(t.Time)
+}
+
+// WithUTCSession makes a Postgres connection string request a UTC session
+// timezone, so now() and timestamptz values come back in UTC. An explicitly
+// configured timezone is left alone.
+func WithUTCSession(dsn string) string {
+	if strings.Contains(strings.ToLower(dsn), "timezone") {
+		return dsn
+	}
+
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		q := u.Query()
+		q.Set("timezone", "UTC")
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+
+	// key=value form
+	return strings.TrimSpace(dsn) + " timezone=UTC"
+}
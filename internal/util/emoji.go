@@ -0,0 +1,123 @@
+package util
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+const (
+	// MaxReactionRunes covers the longest ZWJ sequences (e.g. families with skin tones)
+	MaxReactionRunes = 16
+	// MaxReactionBytes bounds what can end up in the reactions column
+	MaxReactionBytes = 64
+)
+
+var (
+	ErrReactionEmpty    = errors.New("reaction is empty")
+	ErrReactionTooLong  = errors.New("reaction is too long")
+	ErrReactionNotEmoji = errors.New("reaction must be a single emoji")
+)
+
+// ValidateReaction checks that s is exactly one emoji, including modifier,
+// keycap, flag and zero-width-joiner sequences.
+func ValidateReaction(s string) error {
+	if s == "" {
+		return ErrReactionEmpty
+	}
+	if len(s) > MaxReactionBytes || utf8.RuneCountInString(s) > MaxReactionRunes {
+		return ErrReactionTooLong
+	}
+	if !utf8.ValidString(s) {
+		return ErrReactionNotEmoji
+	}
+
+	runes := []rune(s)
+
+	// Keycaps: digit, # or * followed by optional VS16 and the combining keycap
+	if isKeycapBase(runes[0]) {
+		rest := runes[1:]
+		if len(rest) > 0 && rest[0] == variationSelector16 {
+			rest = rest[1:]
+		}
+		if len(rest) == 1 && rest[0] == combiningKeycap {
+			return nil
+		}
+		return ErrReactionNotEmoji
+	}
+
+	// Flags: exactly two regional indicators
+	if isRegionalIndicator(runes[0]) {
+		if len(runes) == 2 && isRegionalIndicator(runes[1]) {
+			return nil
+		}
+		return ErrReactionNotEmoji
+	}
+
+	// Everything else: one pictograph, optionally joined to more pictographs with ZWJ
+	expectBase := true
+	for _, r := range runes {
+		switch {
+		case expectBase:
+			if !isPictograph(r) {
+				return ErrReactionNotEmoji
+			}
+			expectBase = false
+		case r == zeroWidthJoiner:
+			expectBase = true
+		case r == variationSelector16 || isSkinTone(r) || isTag(r):
+			// Modifiers attach to the preceding pictograph
+		default:
+			return ErrReactionNotEmoji
+		}
+	}
+	if expectBase {
+		// Trailing ZWJ
+		return ErrReactionNotEmoji
+	}
+	return nil
+}
+
+const (
+	zeroWidthJoiner     = '\u200d'
+	variationSelector16 = '\ufe0f'
+	combiningKeycap     = '\u20e3'
+)
+
+func isKeycapBase(r rune) bool {
+	return (r >= '0' && r <= '9') || r == '#' || r == '*'
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+func isSkinTone(r rune) bool {
+	return r >= 0x1F3FB && r <= 0x1F3FF
+}
+
+func isTag(r rune) bool {
+	return r >= 0xE0020 && r <= 0xE007F
+}
+
+func isPictograph(r rune) bool {
+	switch {
+	case r >= 0x1F300 && r <= 0x1F5FF: // Misc symbols and pictographs
+		return !isSkinTone(r)
+	case r >= 0x1F600 && r <= 0x1F64F, // Emoticons
+		r >= 0x1F680 && r <= 0x1F6FF, // Transport and map
+		r >= 0x1F900 && r <= 0x1F9FF, // Supplemental symbols and pictographs
+		r >= 0x1FA70 && r <= 0x1FAFF, // Symbols and pictographs extended-A
+		r >= 0x2600 && r <= 0x27BF,   // Misc symbols and dingbats
+		r >= 0x2B00 && r <= 0x2BFF,   // Arrows, stars, squares
+		r >= 0x1F000 && r <= 0x1F0FF, // Mahjong and playing cards
+		r >= 0x1F200 && r <= 0x1F2FF, // Enclosed ideographic supplement
+		r == 0x00A9, r == 0x00AE, r == 0x203C, r == 0x2049, r == 0x2122, r == 0x2139,
+		r >= 0x2194 && r <= 0x21AA,
+		r >= 0x231A && r <= 0x23FF,
+		r == 0x24C2, r == 0x25AA, r == 0x25AB, r == 0x25B6, r == 0x25C0,
+		r >= 0x25FB && r <= 0x25FE,
+		r == 0x3030, r == 0x303D, r == 0x3297, r == 0x3299:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateReaction(t *testing.T) {
+	valid := []string{
+		"👍",
+		"❤️",
+		"😂",
+		"👍🏽",
+		"👨‍👩‍👧‍👦",
+		"🏳️‍🌈",
+		"🇮🇳",
+		"1️⃣",
+		"🔥",
+	}
+	for _, s := range valid {
+		require.NoError(t, ValidateReaction(s), s)
+	}
+
+	require.ErrorIs(t, ValidateReaction(""), ErrReactionEmpty)
+	require.ErrorIs(t, ValidateReaction(strings.Repeat("😂", 20)), ErrReactionTooLong)
+
+	invalid := []string{
+		"lol",
+		"👍👍",
+		"a👍",
+		"👍 ",
+		"🇮",
+		"👨‍",
+		"1",
+	}
+	for _, s := range invalid {
+		require.ErrorIs(t, ValidateReaction(s), ErrReactionNotEmoji, s)
+	}
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// NullTime is a nullable timestamp. Unlike sql.NullTime, which marshals to a
+// {"Time", "Valid"} object, it marshals to null or an RFC 3339 string, the same
+// as time.Time. sqlc maps nullable timestamp columns to it (see sqlc.yaml).
+type NullTime sql.NullTime
+
+// ValidTime wraps a time that is always set
+func ValidTime(t time.Time) NullTime {
+	return NullTime{Time: t, Valid: true}
+}
+
+// Ptr returns the time, or nil when NULL
+func (nt NullTime) Ptr() *time.Time {
+	if !nt.Valid {
+		return nil
+	}
+	t := nt.Time
+	return &t
+}
+
+// Scan implements the sql.Scanner interface
+func (nt *NullTime) Scan(value interface{}) error {
+	return (*sql.NullTime)(nt).Scan(value)
+}
+
+// Value implements the driver.Valuer interface
+func (nt NullTime) Value() (driver.Value, error) {
+	return sql.NullTime(nt).Value()
+}
+
+func (nt NullTime) MarshalJSON() ([]byte, error) {
+	if !nt.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(nt.Time)
+}
+
+func (nt *NullTime) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*nt = NullTime{}
+		return nil
+	}
+	var t time.Time
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+	*nt = NullTime{Time: t, Valid: true}
+	return nil
+}
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNullTimeJSON(t *testing.T) {
+	ts := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	var payload struct {
+		ReadAt    NullTime `json:"read_at"`
+		ExpiresAt NullTime `json:"expires_at"`
+	}
+	payload.ExpiresAt = ValidTime(ts)
+
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"read_at":null,"expires_at":"2026-03-01T12:30:00Z"}`, string(data))
+
+	payload.ReadAt = ValidTime(ts)
+	payload.ExpiresAt = NullTime{}
+	require.NoError(t, json.Unmarshal(data, &payload))
+	require.False(t, payload.ReadAt.Valid)
+	require.True(t, payload.ExpiresAt.Valid)
+	require.True(t, ts.Equal(payload.ExpiresAt.Time))
+}
+
+func TestNullTimeScan(t *testing.T) {
+	ts := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	var nt NullTime
+	require.NoError(t, nt.Scan(ts))
+	require.Equal(t, ValidTime(ts), nt)
+
+	value, err := nt.Value()
+	require.NoError(t, err)
+	require.Equal(t, ts, value)
+
+	require.NoError(t, nt.Scan(nil))
+	require.False(t, nt.Valid)
+	require.Nil(t, nt.Ptr())
+}
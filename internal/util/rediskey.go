@@ -0,0 +1,31 @@
+package util
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// redisKeyPrefix namespaces every Redis key so environments can share one instance
+var redisKeyPrefix atomic.Value
+
+// SetRedisKeyPrefix sets the namespace prepended to all Redis keys. A trailing
+// ":" is added when missing; an empty prefix leaves keys unchanged. Call it once
+// at startup, before any key is built.
+func SetRedisKeyPrefix(prefix string) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix != "" && !strings.HasSuffix(prefix, ":") {
+		prefix += ":"
+	}
+	redisKeyPrefix.Store(prefix)
+}
+
+// RedisKeyPrefix returns the current namespace (with trailing ":"), or ""
+func RedisKeyPrefix() string {
+	prefix, _ := redisKeyPrefix.Load().(string)
+	return prefix
+}
+
+// RedisKey namespaces key (or a key pattern such as "feed:*") with the configured prefix
+func RedisKey(key string) string {
+	return RedisKeyPrefix() + key
+}
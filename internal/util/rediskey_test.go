@@ -0,0 +1,21 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisKey(t *testing.T) {
+	t.Cleanup(func() { SetRedisKeyPrefix("") })
+
+	SetRedisKeyPrefix("")
+	require.Equal(t, "feed:abc", RedisKey("feed:abc"))
+
+	SetRedisKeyPrefix("staging")
+	require.Equal(t, "staging:feed:abc", RedisKey("feed:abc"))
+	require.Equal(t, "staging:feed:*", RedisKey("feed:*"))
+
+	SetRedisKeyPrefix("prod:")
+	require.Equal(t, "prod:unread_count:1", RedisKey("unread_count:1"))
+}
@@ -65,6 +65,22 @@ func (worker *CleanupWorker) cleanup() {
 		log.Info().Msg("Expired messages deleted")
 	}
 
+	// Activity days only matter for the recent activity summary
+	err = worker.store.DeleteOldUserActivityDays(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to delete old activity days")
+	} else {
+		log.Info().Msg("Old activity days deleted")
+	}
+
+	// Lift suspensions that have run out; their lockouts expire from Redis on their own
+	lifted, err := worker.store.LiftExpiredBans(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to lift expired bans")
+	} else {
+		log.Info().Int64("users", lifted).Msg("Expired bans lifted")
+	}
+
 	// Cleanup old notifications (30+ days)
 	err = worker.store.DeleteOldNotifications(ctx)
 	if err != nil {
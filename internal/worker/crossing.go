@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/util"
 )
 
 func (worker *CleanupWorker) StartCrossingDetector() {
@@ -25,7 +26,7 @@ func (worker *CleanupWorker) detectCrossings() {
 	defer cancel()
 
 	// Look back at the last 10-15 minutes
-	now := time.Now().UTC()
+	now := util.Now()
 	maxTime := now
 	minTime := now.Add(-15 * time.Minute)
 
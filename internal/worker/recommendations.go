@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"privacy-social-backend/internal/repository"
+	"privacy-social-backend/internal/service/connection"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RecommendationWorker precomputes connection recommendations for active users,
+// since ranking by mutual connections and crossings is too expensive per request.
+type RecommendationWorker struct {
+	store repository.Store
+}
+
+func NewRecommendationWorker(store repository.Store) *RecommendationWorker {
+	return &RecommendationWorker{
+		store: store,
+	}
+}
+
+func (worker *RecommendationWorker) Start() {
+	ticker := time.NewTicker(1 * time.Hour)
+	go func() {
+		for {
+			<-ticker.C
+			worker.refresh()
+		}
+	}()
+}
+
+func (worker *RecommendationWorker) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	refreshed, err := connection.RefreshActiveUsers(ctx, worker.store)
+	if err != nil {
+		log.Error().Err(err).Int("refreshed", refreshed).Msg("failed to refresh connection recommendations")
+		return
+	}
+	log.Info().Int("refreshed", refreshed).Msg("Connection recommendations refreshed")
+}
@@ -0,0 +1,119 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"privacy-social-backend/internal/repository"
+	"privacy-social-backend/internal/repository/db"
+	"privacy-social-backend/internal/service/storage"
+	"privacy-social-backend/internal/util"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// staleUploadAge is how long a multipart upload may stay incomplete before it is aborted
+	staleUploadAge = 24 * time.Hour
+	// unreferencedMediaGrace gives clients time to attach a fresh upload to a story or message
+	unreferencedMediaGrace = 24 * time.Hour
+	mediaGCBatchSize       = 100
+)
+
+// UploadCleanupWorker aborts multipart uploads that clients never completed and
+// deletes media objects no story or message references any more, so neither
+// keeps accruing storage costs. Media on moderation hold is never deleted.
+type UploadCleanupWorker struct {
+	store   repository.Store
+	storage storage.Service
+	// mediaRetention detaches message media older than this, independently of
+	// when the message itself expires. Zero leaves message media alone.
+	mediaRetention time.Duration
+}
+
+func NewUploadCleanupWorker(store repository.Store, storage storage.Service, mediaRetention time.Duration) *UploadCleanupWorker {
+	return &UploadCleanupWorker{
+		store:          store,
+		storage:        storage,
+		mediaRetention: mediaRetention,
+	}
+}
+
+func (worker *UploadCleanupWorker) Start() {
+	ticker := time.NewTicker(1 * time.Hour)
+	go func() {
+		for {
+			<-ticker.C
+			worker.cleanup()
+		}
+	}()
+}
+
+func (worker *UploadCleanupWorker) cleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	aborted, err := worker.storage.AbortStaleMultipartUploads(ctx, staleUploadAge)
+	if err != nil {
+		log.Error().Err(err).Int("aborted", aborted).Msg("failed to abort stale multipart uploads")
+	} else {
+		log.Info().Int("aborted", aborted).Msg("Stale multipart uploads aborted")
+	}
+
+	worker.expireMessageMedia(ctx)
+	worker.collectUnreferencedMedia(ctx)
+}
+
+// expireMessageMedia drops the media reference from messages past the retention
+// policy; the object itself goes once collectUnreferencedMedia finds it unused.
+func (worker *UploadCleanupWorker) expireMessageMedia(ctx context.Context) {
+	if worker.mediaRetention <= 0 {
+		return
+	}
+
+	cleared, err := worker.store.ClearExpiredMessageMedia(ctx, util.Now().Add(-worker.mediaRetention))
+	if err != nil {
+		log.Error().Err(err).Msg("failed to expire message media")
+		return
+	}
+	log.Info().Int64("cleared", cleared).Msg("Expired message media detached")
+}
+
+// collectUnreferencedMedia deletes objects whose reference count has dropped to zero
+// once their retention window has passed, skipping anything on hold.
+// The row is removed first (only while still unreferenced) so media reused in the
+// meantime is never deleted from the bucket.
+func (worker *UploadCleanupWorker) collectUnreferencedMedia(ctx context.Context) {
+	now := util.Now()
+	objects, err := worker.store.ListUnreferencedMediaObjects(ctx, db.ListUnreferencedMediaObjectsParams{
+		IdleBefore: now.Add(-unreferencedMediaGrace),
+		Now:        now,
+		BatchSize:  mediaGCBatchSize,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to list unreferenced media")
+		return
+	}
+
+	deleted := 0
+	for _, obj := range objects {
+		rows, err := worker.store.DeleteUnreferencedMediaObject(ctx, obj.Hash)
+		if err != nil {
+			log.Error().Err(err).Str("hash", obj.Hash).Msg("failed to delete media record")
+			continue
+		}
+		if rows == 0 {
+			continue
+		}
+		if err := worker.storage.DeleteObject(ctx, obj.ObjectKey); err != nil {
+			log.Error().Err(err).Str("key", obj.ObjectKey).Msg("failed to delete media object")
+			continue
+		}
+		// Not every object has a thumbnail, and deleting a missing key succeeds
+		if err := worker.storage.DeleteObject(ctx, storage.ThumbnailKey(obj.ObjectKey)); err != nil {
+			log.Warn().Err(err).Str("key", obj.ObjectKey).Msg("failed to delete media thumbnail")
+		}
+		deleted++
+	}
+	log.Info().Int("deleted", deleted).Msg("Unreferenced media collected")
+}